@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// AccessListConfig is the JSON format of access list file and admin API
+type AccessListConfig struct {
+	WhitelistPkhs     []string `json:"whitelist_pkhs"`      // BCH PKHs, hex
+	WhitelistEvmAddrs []string `json:"whitelist_evm_addrs"` // sBCH addresses, hex
+	BlacklistPkhs     []string `json:"blacklist_pkhs"`      // BCH PKHs, hex
+	BlacklistEvmAddrs []string `json:"blacklist_evm_addrs"` // sBCH addresses, hex
+}
+
+// AccessList decides whether bot should act on a deposit:
+// blacklisted senders are always rejected, and if the whitelist
+// is not empty, only whitelisted senders are accepted.
+type AccessList struct {
+	mu       sync.RWMutex
+	file     string // optional
+	cfg      AccessListConfig
+	allowed  map[string]bool
+	rejected map[string]bool
+}
+
+func newAccessList(file string) (*AccessList, error) {
+	al := &AccessList{file: file}
+	if file == "" {
+		return al, al.set(AccessListConfig{}, false)
+	}
+	return al, al.reload()
+}
+
+// reload access list from file
+func (al *AccessList) reload() error {
+	if al.file == "" {
+		return fmt.Errorf("no access list file")
+	}
+	bz, err := os.ReadFile(al.file)
+	if err != nil {
+		return err
+	}
+	var cfg AccessListConfig
+	if err = json.Unmarshal(bz, &cfg); err != nil {
+		return err
+	}
+	return al.set(cfg, false)
+}
+
+// replace access list, and save it to file if persist is true
+func (al *AccessList) set(cfg AccessListConfig, persist bool) error {
+	allowed := map[string]bool{}
+	rejected := map[string]bool{}
+	for _, group := range []struct {
+		addrs []string
+		m     map[string]bool
+	}{
+		{cfg.WhitelistPkhs, allowed},
+		{cfg.WhitelistEvmAddrs, allowed},
+		{cfg.BlacklistPkhs, rejected},
+		{cfg.BlacklistEvmAddrs, rejected},
+	} {
+		for _, addr := range group.addrs {
+			bz := gethcmn.FromHex(strings.TrimSpace(addr))
+			if len(bz) != 20 {
+				return fmt.Errorf("invalid PKH or address: %s", addr)
+			}
+			group.m[toHex(bz)] = true
+		}
+	}
+
+	if persist && al.file != "" {
+		bz, _ := json.MarshalIndent(cfg, "", "  ")
+		if err := os.WriteFile(al.file, bz, 0600); err != nil {
+			return err
+		}
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.cfg = cfg
+	al.allowed = allowed
+	al.rejected = rejected
+	log.Infof("access list updated, whitelist: %d, blacklist: %d", len(allowed), len(rejected))
+	return nil
+}
+
+func (al *AccessList) get() AccessListConfig {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+	return al.cfg
+}
+
+// pkh and evmAddr are both optional
+func (al *AccessList) isAllowed(pkh, evmAddr []byte) bool {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+
+	pkhHex := toHex(pkh)
+	evmAddrHex := toHex(evmAddr)
+	if (len(pkh) > 0 && al.rejected[pkhHex]) ||
+		(len(evmAddr) > 0 && al.rejected[evmAddrHex]) {
+		return false
+	}
+	if len(al.allowed) == 0 {
+		return true
+	}
+	return (len(pkh) > 0 && al.allowed[pkhHex]) ||
+		(len(evmAddr) > 0 && al.allowed[evmAddrHex])
+}
+
+func (bot *MarketMakerBot) isSenderAllowed(pkh, evmAddr []byte) bool {
+	if bot.accessList == nil {
+		return true
+	}
+	return bot.accessList.isAllowed(pkh, evmAddr)
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessList(t *testing.T) {
+	pkh1 := gethAddrBytes("pkh1")
+	pkh2 := gethAddrBytes("pkh2")
+	evm1 := gethAddrBytes("evm1")
+	evm2 := gethAddrBytes("evm2")
+
+	al, err := newAccessList("")
+	require.NoError(t, err)
+	require.True(t, al.isAllowed(pkh1, evm1))
+
+	// blacklist only
+	require.NoError(t, al.set(AccessListConfig{
+		BlacklistPkhs:     []string{toHex(pkh1)},
+		BlacklistEvmAddrs: []string{"0x" + toHex(evm2)},
+	}, false))
+	require.False(t, al.isAllowed(pkh1, evm1))
+	require.False(t, al.isAllowed(pkh2, evm2))
+	require.True(t, al.isAllowed(pkh2, evm1))
+	require.True(t, al.isAllowed(nil, evm1))
+
+	// whitelist
+	require.NoError(t, al.set(AccessListConfig{
+		WhitelistPkhs: []string{toHex(pkh2)},
+		BlacklistPkhs: []string{toHex(pkh1)},
+	}, false))
+	require.True(t, al.isAllowed(pkh2, evm1))
+	require.False(t, al.isAllowed(pkh1, evm1))
+	require.False(t, al.isAllowed(gethAddrBytes("pkh3"), evm1))
+
+	require.ErrorContains(t, al.set(AccessListConfig{BlacklistPkhs: []string{"1234"}}, false),
+		"invalid PKH or address")
+	require.Equal(t, []string{toHex(pkh2)}, al.get().WhitelistPkhs)
+}
+
+func TestAccessList_file(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "access-list.json")
+	_, err := newAccessList(file)
+	require.Error(t, err)
+
+	pkh1 := gethAddrBytes("pkh1")
+	require.NoError(t, os.WriteFile(file, []byte(`{"blacklist_pkhs":["`+toHex(pkh1)+`"]}`), 0600))
+	al, err := newAccessList(file)
+	require.NoError(t, err)
+	require.False(t, al.isAllowed(pkh1, nil))
+
+	// persist
+	require.NoError(t, al.set(AccessListConfig{}, true))
+	require.True(t, al.isAllowed(pkh1, nil))
+	require.NoError(t, al.reload())
+	require.True(t, al.isAllowed(pkh1, nil))
+}
+
+func TestAdminAccessListAPI(t *testing.T) {
+	al, err := newAccessList("")
+	require.NoError(t, err)
+	_bot := &MarketMakerBot{accessList: al}
+	mux := _bot.createHttpHandlers()
+
+	body := `{"blacklist_pkhs":["` + toHex(gethAddrBytes("pkh1")) + `"]}`
+	req := httptest.NewRequest(http.MethodPost, "/admin/access-list", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Contains(t, w.Body.String(), "admin API is disabled")
+
+	_bot.adminToken = "secret"
+	req = httptest.NewRequest(http.MethodPost, "/admin/access-list", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer wrong")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/access-list", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Contains(t, w.Body.String(), `"success":true`)
+	require.False(t, _bot.isSenderAllowed(gethAddrBytes("pkh1"), nil))
+}
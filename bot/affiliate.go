@@ -0,0 +1,278 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcmath"
+)
+
+const (
+	AffiliateMemoPrefix = "ref:" // memo of the form ref:<code>[:<user reference>]
+	maxAffiliateCodeLen = 32
+)
+
+// AffiliateConfig registers an integrator who originates swaps by tagging their memos
+// with its code, and earns a share of the service fees of them
+type AffiliateConfig struct {
+	Code     string `json:"code"`      // [0-9A-Za-z_-], at most 32 chars
+	BchAddr  string `json:"bch_addr"`  // P2PKH address BCH shares are paid to, empty means not paid
+	SbchAddr string `json:"sbch_addr"` // EOA or contract sBCH shares are paid to, empty means not paid
+	ShareBPS uint16 `json:"share_bps"` // share of service fees, in BPS
+}
+
+type Affiliate struct {
+	code     string
+	bchPkh   []byte
+	sbchAddr gethcmn.Address
+	shareBPS uint16
+}
+
+// Affiliates are registered affiliates by code
+type Affiliates map[string]*Affiliate
+
+func newAffiliates(cfgs []AffiliateConfig, bchNet *htlcbch.ChainParams) (Affiliates, error) {
+	affiliates := make(Affiliates, len(cfgs))
+	for _, cfg := range cfgs {
+		if !isValidAffiliateCode(cfg.Code) {
+			return nil, fmt.Errorf("invalid affiliate code: %q", cfg.Code)
+		}
+		if affiliates[cfg.Code] != nil {
+			return nil, fmt.Errorf("duplicated affiliate code: %s", cfg.Code)
+		}
+		if cfg.ShareBPS == 0 || cfg.ShareBPS > htlcmath.MaxBPS {
+			return nil, fmt.Errorf("share BPS of affiliate %s not in [1, %d]: %d",
+				cfg.Code, htlcmath.MaxBPS, cfg.ShareBPS)
+		}
+		if cfg.BchAddr == "" && cfg.SbchAddr == "" {
+			return nil, fmt.Errorf("affiliate %s has no payout address", cfg.Code)
+		}
+		affiliate := &Affiliate{code: cfg.Code, shareBPS: cfg.ShareBPS}
+		if cfg.BchAddr != "" {
+			addr, err := bchNet.DecodeP2PKHAddress(cfg.BchAddr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BCH address of affiliate %s: %w", cfg.Code, err)
+			}
+			affiliate.bchPkh = addr.ScriptAddress()
+		}
+		if cfg.SbchAddr != "" {
+			if !gethcmn.IsHexAddress(cfg.SbchAddr) {
+				return nil, fmt.Errorf("invalid sBCH address of affiliate %s: %s", cfg.Code, cfg.SbchAddr)
+			}
+			affiliate.sbchAddr = gethcmn.HexToAddress(cfg.SbchAddr)
+		}
+		affiliates[cfg.Code] = affiliate
+	}
+	return affiliates, nil
+}
+
+func isValidAffiliateCode(code string) bool {
+	if len(code) == 0 || len(code) > maxAffiliateCodeLen {
+		return false
+	}
+	for _, c := range code {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// return the code of the registered affiliate tagged by memo, or empty
+func (affiliates Affiliates) getCodeOfMemo(memo string) string {
+	if !strings.HasPrefix(memo, AffiliateMemoPrefix) {
+		return ""
+	}
+	code, _, _ := strings.Cut(memo[len(AffiliateMemoPrefix):], ":")
+	if affiliates[code] == nil {
+		return ""
+	}
+	return code
+}
+
+// the share of affiliate in fee, rounded down
+func (affiliate *Affiliate) getShare(fee uint64) uint64 {
+	share, err := htlcmath.ApplyBPS(fee, affiliate.shareBPS)
+	if err != nil {
+		return 0
+	}
+	return share
+}
+
+// record fee revenue of a swap, minus the share of its affiliate, which is accrued to it
+func (bot *MarketMakerBot) recordFeeRevenue(hashLock, affiliateCode, asset string, amount uint64) {
+	if affiliate := bot.affiliates[affiliateCode]; affiliate != nil {
+		share := affiliate.getShare(amount)
+		amount -= share
+		if share > 0 {
+			err := bot.db.addAffiliateAccrual(&AffiliateAccrual{
+				HashLock: hashLock,
+				Code:     affiliateCode,
+				Asset:    asset,
+				Amount:   share,
+			})
+			if err != nil {
+				bot.logError("DB error, failed to save affiliate accrual: ", err)
+			}
+		}
+	}
+	if amount == 0 {
+		return
+	}
+	err := bot.db.addFeeRevenue(&FeeRevenue{
+		HashLock: hashLock,
+		Asset:    asset,
+		Amount:   amount,
+	})
+	if err != nil {
+		bot.logError("DB error, failed to save fee revenue: ", err)
+	}
+}
+
+// periodically pay unpaid accruals to affiliates
+func (bot *MarketMakerBot) payAffiliates() {
+	if bot.affiliatePayoutInterval == 0 || len(bot.affiliates) == 0 || bot.isSlaveMode || !bot.canSign() {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastAffiliatesPaidAt < int64(bot.affiliatePayoutInterval) {
+		return
+	}
+	bot.lastAffiliatesPaidAt = now
+
+	for _, affiliate := range bot.affiliates {
+		affiliate := affiliate
+		if affiliate.bchPkh != nil {
+			bot.payAffiliate(affiliate.code, FeeAssetBch, func(amount uint64) (string, error) {
+				return bot.sendBchTo(affiliate.bchPkh, amount)
+			})
+		}
+		if affiliate.sbchAddr != (gethcmn.Address{}) {
+			bot.payAffiliate(affiliate.code, FeeAssetSbch, func(amount uint64) (string, error) {
+				return bot.sendSbchTo(affiliate.sbchAddr, amount)
+			})
+		}
+	}
+}
+
+func (bot *MarketMakerBot) payAffiliate(code, asset string, pay func(amount uint64) (string, error)) {
+	accruals, err := bot.db.getUnpaidAffiliateAccruals(code, asset, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get affiliate accruals: ", err)
+		return
+	}
+	var amount uint64
+	ids := make([]uint, len(accruals))
+	for i, accrual := range accruals {
+		amount += accrual.Amount
+		ids[i] = accrual.ID
+	}
+	if amount < minSweepVal {
+		return
+	}
+
+	txHash, err := pay(amount)
+	if err != nil {
+		bot.logError("failed to pay affiliate: ", err)
+		return
+	}
+	if err = bot.db.setAffiliateAccrualsPaid(ids, txHash); err != nil {
+		bot.logError("DB error, failed to mark affiliate accruals as paid: ", err)
+	}
+	bot.logWarnf("paid %d sats %s to affiliate %s for %d swaps, tx hash: %s",
+		amount, asset, code, len(ids), txHash)
+}
+
+// AffiliateStatement lists the accruals of an affiliate in a period, amounts are in sats by asset
+type AffiliateStatement struct {
+	Code     string                  `json:"code"`
+	ShareBPS uint16                  `json:"share_bps"`
+	Swaps    int                     `json:"swaps"`
+	Accrued  map[string]uint64       `json:"accrued"`
+	Paid     map[string]uint64       `json:"paid"`
+	Unpaid   map[string]uint64       `json:"unpaid"`
+	Payouts  []*AffiliatePayoutInfo  `json:"payouts"`
+	Accruals []*AffiliateAccrualInfo `json:"accruals"`
+}
+
+type AffiliatePayoutInfo struct {
+	TxHash string `json:"tx_hash"`
+	Asset  string `json:"asset"`
+	Amount uint64 `json:"amount"` // in sats
+	Swaps  int    `json:"swaps"`
+}
+
+type AffiliateAccrualInfo struct {
+	HashLock string `json:"hash_lock"`
+	Asset    string `json:"asset"`
+	Amount   uint64 `json:"amount"`              // in sats
+	PayoutTx string `json:"payout_tx,omitempty"` // empty means not paid yet
+	Time     int64  `json:"time"`                // unix timestamp
+}
+
+// return the statement of an affiliate in [from, to)
+func (bot *MarketMakerBot) getAffiliateStatement(code string, from, to int64) (*AffiliateStatement, error) {
+	affiliate := bot.affiliates[code]
+	if affiliate == nil {
+		return nil, fmt.Errorf("unknown affiliate: %s", code)
+	}
+	accruals, err := bot.db.getAffiliateAccruals(code, time.Unix(from, 0), time.Unix(to, 0))
+	if err != nil {
+		return nil, err
+	}
+	statement := &AffiliateStatement{
+		Code:     code,
+		ShareBPS: affiliate.shareBPS,
+		Swaps:    len(accruals),
+		Accrued:  map[string]uint64{},
+		Paid:     map[string]uint64{},
+		Unpaid:   map[string]uint64{},
+		Payouts:  []*AffiliatePayoutInfo{},
+		Accruals: make([]*AffiliateAccrualInfo, len(accruals)),
+	}
+	payouts := map[string]*AffiliatePayoutInfo{}
+	for i, accrual := range accruals {
+		statement.Accrued[accrual.Asset] += accrual.Amount
+		if accrual.PayoutTx == "" {
+			statement.Unpaid[accrual.Asset] += accrual.Amount
+		} else {
+			statement.Paid[accrual.Asset] += accrual.Amount
+			payout := payouts[accrual.PayoutTx]
+			if payout == nil {
+				payout = &AffiliatePayoutInfo{TxHash: accrual.PayoutTx, Asset: accrual.Asset}
+				payouts[accrual.PayoutTx] = payout
+				statement.Payouts = append(statement.Payouts, payout)
+			}
+			payout.Amount += accrual.Amount
+			payout.Swaps++
+		}
+		statement.Accruals[i] = &AffiliateAccrualInfo{
+			HashLock: accrual.HashLock,
+			Asset:    accrual.Asset,
+			Amount:   accrual.Amount,
+			PayoutTx: accrual.PayoutTx,
+			Time:     accrual.CreatedAt.Unix(),
+		}
+	}
+	return statement, nil
+}
+
+// return the statement of an affiliate, of the last 30 days by default
+func (bot *MarketMakerBot) handleAffiliateStatement(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleReader, false) {
+		return
+	}
+	to := int64(getIntQueryParam(r, "to", int(time.Now().Unix()+1)))
+	from := int64(getIntQueryParam(r, "from", int(to-30*24*3600)))
+	statement, err := bot.getAffiliateStatement(r.URL.Query().Get("code"), from, to)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(statement).WriteTo(w)
+}
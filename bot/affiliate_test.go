@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestNewAffiliates(t *testing.T) {
+	bchAddr, err := htlcbch.MainNet.NewP2PKHAddress(gethAddrBytes("aff"))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		cfg AffiliateConfig
+		err string
+	}{
+		{AffiliateConfig{Code: "", SbchAddr: "0x1234", ShareBPS: 100}, "invalid affiliate code"},
+		{AffiliateConfig{Code: "a:b", SbchAddr: "0x1234", ShareBPS: 100}, "invalid affiliate code"},
+		{AffiliateConfig{Code: "acme", SbchAddr: gethAddr("aff").String()}, "share BPS of affiliate acme not in [1, 10000]: 0"},
+		{AffiliateConfig{Code: "acme", ShareBPS: 100}, "affiliate acme has no payout address"},
+		{AffiliateConfig{Code: "acme", SbchAddr: "0x1234", ShareBPS: 100}, "invalid sBCH address of affiliate acme"},
+		{AffiliateConfig{Code: "acme", BchAddr: gethAddr("aff").String(), ShareBPS: 100}, "invalid BCH address of affiliate acme"},
+	} {
+		_, err = newAffiliates([]AffiliateConfig{tc.cfg}, htlcbch.MainNet)
+		require.ErrorContains(t, err, tc.err)
+	}
+
+	cfg := AffiliateConfig{Code: "acme", BchAddr: bchAddr.String(), ShareBPS: 2000}
+	_, err = newAffiliates([]AffiliateConfig{cfg, cfg}, htlcbch.MainNet)
+	require.ErrorContains(t, err, "duplicated affiliate code: acme")
+
+	affiliates, err := newAffiliates([]AffiliateConfig{cfg}, htlcbch.MainNet)
+	require.NoError(t, err)
+	require.Equal(t, gethAddrBytes("aff"), affiliates["acme"].bchPkh)
+	require.Equal(t, gethcmn.Address{}, affiliates["acme"].sbchAddr)
+	require.Equal(t, uint64(200), affiliates["acme"].getShare(1000))
+
+	require.Equal(t, "acme", affiliates.getCodeOfMemo("ref:acme"))
+	require.Equal(t, "acme", affiliates.getCodeOfMemo("ref:acme:order-42"))
+	require.Equal(t, "", affiliates.getCodeOfMemo("ref:foo"))
+	require.Equal(t, "", affiliates.getCodeOfMemo("acme"))
+	require.Equal(t, "", affiliates.getCodeOfMemo(""))
+	require.Equal(t, "", Affiliates(nil).getCodeOfMemo("ref:acme"))
+}
+
+func TestPayAffiliates(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 125)
+	_bchCli.utxos = []btcjson.ListUnspentResult{
+		{TxID: gethcmn.Hash{'u', '1'}.String(), Vout: 0, Amount: 1.5},
+	}
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	affSbchAddr := gethAddr("affsbch")
+	_bot := &MarketMakerBot{
+		db:                  _db,
+		bchCli:              _bchCli,
+		sbchCli:             _sbchCli,
+		bchPrivKey:          testBchPrivKey,
+		bchPkh:              testBchPkh,
+		bchLockMinerFeeRate: 2,
+		dbQueryLimit:        100,
+		affiliates: Affiliates{
+			"acme": {code: "acme", bchPkh: gethAddrBytes("affbch"), shareBPS: 5000},
+			"sbch": {code: "sbch", sbchAddr: affSbchAddr, shareBPS: 2000},
+		},
+		affiliatePayoutInterval: 3600,
+		errLogQueue:             newErrLogQueue(100),
+	}
+
+	// price 0.999: the fee is 0.1% of the value
+	_bot.recordBch2SbchFee(&Bch2SbchRecord{HashLock: "h1", Value: 2e8, BchPrice: 0.999e8, Affiliate: "acme"})
+	_bot.recordBch2SbchFee(&Bch2SbchRecord{HashLock: "h2", Value: 1e8, BchPrice: 0.999e8})
+	_bot.recordSbch2BchFee(&Sbch2BchRecord{HashLock: "h3", Value: 1e8, SbchPrice: 0.999e8, Affiliate: "acme"})
+	_bot.recordSbch2BchFee(&Sbch2BchRecord{HashLock: "h4", Value: 1e8, SbchPrice: 0.999e8, Affiliate: "sbch"})
+	_bot.recordSbch2BchFee(&Sbch2BchRecord{HashLock: "h5", Value: 1e8, SbchPrice: 0.999e8, Affiliate: "gone"})
+
+	revenues, err := _db.getUnsweptFeeRevenues(FeeAssetBch, 100)
+	require.NoError(t, err)
+	require.Len(t, revenues, 2)
+	require.Equal(t, uint64(100000), revenues[0].Amount)
+	require.Equal(t, uint64(100000), revenues[1].Amount)
+	revenues, err = _db.getUnsweptFeeRevenues(FeeAssetSbch, 100)
+	require.NoError(t, err)
+	require.Len(t, revenues, 3)
+	require.Equal(t, uint64(50000), revenues[0].Amount)
+	require.Equal(t, uint64(80000), revenues[1].Amount)
+	require.Equal(t, uint64(100000), revenues[2].Amount)
+
+	// acme: 100000 BCH sats are paid, 50000 sBCH sats are not (no address)
+	// sbch: 20000 sBCH sats are too small to pay
+	_bot.payAffiliates()
+	require.Len(t, _bchCli.sentTxs, 1)
+	affOut := findTxOut(_bchCli.sentTxs[0], gethAddrBytes("affbch"))
+	require.NotNil(t, affOut)
+	require.Less(t, affOut.Value, int64(100000))
+	require.Greater(t, affOut.Value, int64(100000-1000))
+	require.Nil(t, _sbchCli.sent[affSbchAddr])
+	require.Len(t, _bot.errLogQueue.removeErrLogs(10), 1) // alert
+
+	// not paid again within the interval
+	_bot.recordBch2SbchFee(&Bch2SbchRecord{HashLock: "h6", Value: 2e8, BchPrice: 0.999e8, Affiliate: "acme"})
+	_bot.payAffiliates()
+	require.Len(t, _bchCli.sentTxs, 1)
+
+	now := time.Now().Unix()
+	statement, err := _bot.getAffiliateStatement("acme", now-60, now+60)
+	require.NoError(t, err)
+	require.Equal(t, 3, statement.Swaps)
+	require.Equal(t, uint16(5000), statement.ShareBPS)
+	require.Equal(t, map[string]uint64{FeeAssetBch: 200000, FeeAssetSbch: 50000}, statement.Accrued)
+	require.Equal(t, map[string]uint64{FeeAssetBch: 100000}, statement.Paid)
+	require.Equal(t, map[string]uint64{FeeAssetBch: 100000, FeeAssetSbch: 50000}, statement.Unpaid)
+	require.Len(t, statement.Payouts, 1)
+	require.Equal(t, _bchCli.sentTxs[0].TxHash().String(), statement.Payouts[0].TxHash)
+	require.Equal(t, uint64(100000), statement.Payouts[0].Amount)
+	require.Equal(t, 1, statement.Payouts[0].Swaps)
+	require.Len(t, statement.Accruals, 3)
+	require.Equal(t, "h3", statement.Accruals[1].HashLock)
+	require.Equal(t, "", statement.Accruals[1].PayoutTx)
+
+	statement, err = _bot.getAffiliateStatement("acme", now+60, now+120)
+	require.NoError(t, err)
+	require.Equal(t, 0, statement.Swaps)
+	_, err = _bot.getAffiliateStatement("gone", now-60, now+60)
+	require.ErrorContains(t, err, "unknown affiliate: gone")
+}
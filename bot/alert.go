@@ -0,0 +1,305 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Operator alerts (logError, logWarnf and logCriticalf of the bot) are pushed to providers, such as
+// Twilio SMS and generic webhooks, each subscribing alerts of a min severity. Every provider has its
+// own queue and goroutine, so a slow or failing provider does not delay the others, and failed sends
+// are retried with backoff. Repeated warnings and errors (e.g. an RPC error logged every round) are
+// throttled per provider, critical alerts never are.
+
+const (
+	AlertSeverityWarning  = "warning"
+	AlertSeverityError    = "error"
+	AlertSeverityCritical = "critical"
+
+	AlertProviderTwilio  = "twilio"
+	AlertProviderWebhook = "webhook"
+
+	alertQueueSize      = 1000
+	alertTimeout        = 10 * time.Second
+	alertMaxAttempts    = 8
+	alertBaseBackoff    = time.Second // doubled after each failed attempt
+	alertThrottle       = 600         // in seconds, identical non-critical alerts are sent once in it
+	maxSmsLength        = 320
+	defaultTwilioApiUrl = "https://api.twilio.com"
+)
+
+var alertSeverityLevels = map[string]int{
+	AlertSeverityWarning:  1,
+	AlertSeverityError:    2,
+	AlertSeverityCritical: 3,
+}
+
+// AlertProviderConfig is a channel operator alerts are pushed to
+type AlertProviderConfig struct {
+	Type        string `json:"type"`         // twilio|webhook
+	MinSeverity string `json:"min_severity"` // warning|error|critical, default critical
+
+	// twilio
+	AccountSid string   `json:"account_sid"` //
+	AuthToken  string   `json:"auth_token"`  //
+	From       string   `json:"from"`        // sender phone number
+	To         []string `json:"to"`          // phone numbers of operators
+
+	// webhook, POSTed an Alert
+	Url    string `json:"url"`    //
+	Secret string `json:"secret"` // optional, HMAC-SHA256 key of X-Webhook-Signature, same as webhooks
+}
+
+// Alert is the JSON body POSTed to webhook providers
+type Alert struct {
+	Severity string `json:"severity"` // warning|error|critical
+	Msg      string `json:"msg"`      //
+	Source   string `json:"source"`   // sBCH address of the bot
+	Time     int64  `json:"time"`     // unix seconds
+}
+
+// AlertProvider sends an alert to a channel, it is called by one goroutine only
+type AlertProvider interface {
+	Name() string
+	Send(alert *Alert) error
+}
+
+type alertWorker struct {
+	provider    AlertProvider
+	minSeverity int
+	queue       chan *Alert
+	lastSent    map[string]int64 // message => unix seconds, for throttling
+	mu          sync.Mutex
+}
+
+type AlertDispatcher struct {
+	source  string
+	workers []*alertWorker
+	backoff time.Duration
+	errLog  *ErrLogQueue
+}
+
+// returns nil if no provider is configured
+func newAlertDispatcher(cfgs []AlertProviderConfig, source string, errLog *ErrLogQueue) (*AlertDispatcher, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	d := &AlertDispatcher{source: source, backoff: alertBaseBackoff, errLog: errLog}
+	client := &http.Client{Timeout: alertTimeout}
+	for i, cfg := range cfgs {
+		if cfg.MinSeverity == "" {
+			cfg.MinSeverity = AlertSeverityCritical
+		}
+		minSeverity := alertSeverityLevels[cfg.MinSeverity]
+		if minSeverity == 0 {
+			return nil, fmt.Errorf("alerts[%d]: invalid min_severity: %s", i, cfg.MinSeverity)
+		}
+		var providers []AlertProvider
+		var err error
+		switch cfg.Type {
+		case AlertProviderTwilio:
+			providers, err = newTwilioSmsProviders(&cfg, client)
+		case AlertProviderWebhook:
+			var provider *WebhookPushProvider
+			provider, err = newWebhookPushProvider(&cfg, client)
+			providers = []AlertProvider{provider}
+		default:
+			err = fmt.Errorf("invalid type: %s", cfg.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("alerts[%d]: %w", i, err)
+		}
+		for _, provider := range providers {
+			d.workers = append(d.workers, &alertWorker{
+				provider:    provider,
+				minSeverity: minSeverity,
+				queue:       make(chan *Alert, alertQueueSize),
+				lastSent:    map[string]int64{},
+			})
+		}
+	}
+	return d, nil
+}
+
+func (d *AlertDispatcher) run() {
+	for _, w := range d.workers {
+		go d.runWorker(w)
+	}
+}
+
+// queue the alert to providers subscribing its severity, never blocks
+func (d *AlertDispatcher) alert(severity, msg string) {
+	if d == nil {
+		return
+	}
+	alert := &Alert{Severity: severity, Msg: msg, Source: d.source, Time: time.Now().Unix()}
+	for _, w := range d.workers {
+		if alertSeverityLevels[severity] < w.minSeverity || w.isThrottled(alert) {
+			continue
+		}
+		select {
+		case w.queue <- alert:
+		default:
+			log.Errorf("alert queue of %s is full, alert dropped: %s", w.provider.Name(), msg)
+		}
+	}
+}
+
+func (w *alertWorker) isThrottled(alert *Alert) bool {
+	if alert.Severity == AlertSeverityCritical {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if alert.Time < w.lastSent[alert.Msg]+alertThrottle {
+		return true
+	}
+	for msg, ts := range w.lastSent {
+		if alert.Time >= ts+alertThrottle {
+			delete(w.lastSent, msg)
+		}
+	}
+	w.lastSent[alert.Msg] = alert.Time
+	return false
+}
+
+func (d *AlertDispatcher) runWorker(w *alertWorker) {
+	for alert := range w.queue {
+		d.send(w.provider, alert)
+	}
+}
+
+// retry with backoff, 1s, 2s, 4s ... about 2 minutes in total
+func (d *AlertDispatcher) send(provider AlertProvider, alert *Alert) {
+	backoff := d.backoff
+	for attempts := 1; ; attempts++ {
+		err := provider.Send(alert)
+		if err == nil {
+			log.Infof("%s alert sent by %s", alert.Severity, provider.Name())
+			return
+		}
+		if attempts >= alertMaxAttempts {
+			// not alerted again, which could loop
+			msg := fmt.Sprintf("failed to send %s alert by %s, attempts: %d, alert: %s",
+				alert.Severity, provider.Name(), attempts, alert.Msg)
+			log.Error(msg, ": ", err)
+			d.errLog.recordErrLog("error", fmt.Sprintf("%s: %s", msg, err))
+			return
+		}
+		log.Infof("failed to send alert by %s, attempts: %d, error: %s", provider.Name(), attempts, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// TwilioSmsProvider texts alerts to an operator by the Messages API of Twilio
+type TwilioSmsProvider struct {
+	apiUrl     string
+	accountSid string
+	authToken  string
+	from       string
+	to         string
+	client     *http.Client
+}
+
+// one provider per phone number, so that a failed number is retried alone
+func newTwilioSmsProviders(cfg *AlertProviderConfig, client *http.Client) ([]AlertProvider, error) {
+	if cfg.AccountSid == "" || cfg.AuthToken == "" {
+		return nil, fmt.Errorf("missing account_sid or auth_token")
+	}
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("missing from or to")
+	}
+	var providers []AlertProvider
+	for _, to := range cfg.To {
+		providers = append(providers, &TwilioSmsProvider{
+			apiUrl:     defaultTwilioApiUrl,
+			accountSid: cfg.AccountSid,
+			authToken:  cfg.AuthToken,
+			from:       cfg.From,
+			to:         to,
+			client:     client,
+		})
+	}
+	return providers, nil
+}
+
+func (p *TwilioSmsProvider) Name() string {
+	return "twilio " + p.to
+}
+
+func (p *TwilioSmsProvider) Send(alert *Alert) error {
+	body := fmt.Sprintf("[%s] %s", strings.ToUpper(alert.Severity), alert.Msg)
+	if len(body) > maxSmsLength {
+		body = body[:maxSmsLength]
+	}
+	apiUrl := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", p.apiUrl, p.accountSid)
+	form := url.Values{"From": {p.from}, "To": {p.to}, "Body": {body}}
+	req, err := http.NewRequest(http.MethodPost, apiUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.accountSid, p.authToken)
+	return doAlertRequest(p.client, req)
+}
+
+// WebhookPushProvider POSTs alerts to push gateways, chat bots, paging services, ...
+type WebhookPushProvider struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func newWebhookPushProvider(cfg *AlertProviderConfig, client *http.Client) (*WebhookPushProvider, error) {
+	u, err := url.Parse(cfg.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url: %s", cfg.Url)
+	}
+	return &WebhookPushProvider{url: cfg.Url, secret: cfg.Secret, client: client}, nil
+}
+
+func (p *WebhookPushProvider) Name() string {
+	return "webhook " + p.url
+}
+
+func (p *WebhookPushProvider) Send(alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Webhook-Timestamp", timestamp)
+		req.Header.Set("X-Webhook-Signature", signWebhookPayload(p.secret, timestamp, body))
+	}
+	return doAlertRequest(p.client, req)
+}
+
+func doAlertRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAlertDispatcher(t *testing.T) {
+	d, err := newAlertDispatcher(nil, "", nil)
+	require.NoError(t, err)
+	require.Nil(t, d)
+	d.alert(AlertSeverityCritical, "ignored") // nil safe
+
+	_, err = newAlertDispatcher([]AlertProviderConfig{{Type: "pager"}}, "", nil)
+	require.EqualError(t, err, "alerts[0]: invalid type: pager")
+	_, err = newAlertDispatcher([]AlertProviderConfig{{Type: "webhook", Url: "https://a.io", MinSeverity: "info"}}, "", nil)
+	require.EqualError(t, err, "alerts[0]: invalid min_severity: info")
+	_, err = newAlertDispatcher([]AlertProviderConfig{{Type: "webhook", Url: "a.io"}}, "", nil)
+	require.EqualError(t, err, "alerts[0]: invalid url: a.io")
+	_, err = newAlertDispatcher([]AlertProviderConfig{{Type: "twilio", AccountSid: "AC1", AuthToken: "t"}}, "", nil)
+	require.EqualError(t, err, "alerts[0]: missing from or to")
+
+	d, err = newAlertDispatcher([]AlertProviderConfig{
+		{Type: "twilio", AccountSid: "AC1", AuthToken: "t", From: "+100", To: []string{"+101", "+102"}},
+		{Type: "webhook", Url: "https://a.io/push", MinSeverity: "warning"},
+	}, "", nil)
+	require.NoError(t, err)
+	require.Len(t, d.workers, 3)
+	require.Equal(t, "twilio +102", d.workers[1].provider.Name())
+	require.Equal(t, alertSeverityLevels[AlertSeverityCritical], d.workers[1].minSeverity)
+	require.Equal(t, "webhook https://a.io/push", d.workers[2].provider.Name())
+}
+
+func TestAlertDispatcher(t *testing.T) {
+	var mu sync.Mutex
+	var sms []url.Values
+	var pushes []*Alert
+	failing := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing > 0 {
+			failing--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/push" {
+			ts := r.Header.Get("X-Webhook-Timestamp")
+			require.Equal(t, signWebhookPayload("secret", ts, body), r.Header.Get("X-Webhook-Signature"))
+			alert := &Alert{}
+			require.NoError(t, json.Unmarshal(body, alert))
+			pushes = append(pushes, alert)
+			return
+		}
+		require.Equal(t, "/2010-04-01/Accounts/AC1/Messages.json", r.URL.Path)
+		user, pass, _ := r.BasicAuth()
+		require.Equal(t, "AC1:token", user+":"+pass)
+		form, err := url.ParseQuery(string(body))
+		require.NoError(t, err)
+		sms = append(sms, form)
+	}))
+	defer server.Close()
+
+	errLogQueue := newErrLogQueue(100)
+	d, err := newAlertDispatcher([]AlertProviderConfig{
+		{Type: "twilio", AccountSid: "AC1", AuthToken: "token", From: "+100", To: []string{"+101"}},
+		{Type: "webhook", Url: server.URL + "/push", Secret: "secret", MinSeverity: "warning"},
+	}, "0xbot", errLogQueue)
+	require.NoError(t, err)
+	d.workers[0].provider.(*TwilioSmsProvider).apiUrl = server.URL
+	d.backoff = time.Millisecond
+	d.run()
+	waitFor := func(cond func() bool) {
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return cond()
+		}, 5*time.Second, time.Millisecond)
+	}
+
+	// by severity
+	d.alert(AlertSeverityWarning, "low BCH inventory")
+	d.alert(AlertSeverityCritical, "BCH not unlocked")
+	waitFor(func() bool { return len(pushes) == 2 && len(sms) == 1 })
+	require.Equal(t, "+101", sms[0].Get("To"))
+	require.Equal(t, "+100", sms[0].Get("From"))
+	require.Equal(t, "[CRITICAL] BCH not unlocked", sms[0].Get("Body"))
+	require.Equal(t, "low BCH inventory", pushes[0].Msg)
+	require.Equal(t, AlertSeverityWarning, pushes[0].Severity)
+	require.Equal(t, "0xbot", pushes[0].Source)
+
+	// identical warnings are throttled, critical alerts are not
+	d.alert(AlertSeverityWarning, "low BCH inventory")
+	d.alert(AlertSeverityCritical, "BCH not unlocked")
+	waitFor(func() bool { return len(pushes) == 3 && len(sms) == 2 })
+	require.Equal(t, AlertSeverityCritical, pushes[2].Severity)
+
+	// retried
+	mu.Lock()
+	failing = 3
+	mu.Unlock()
+	d.alert(AlertSeverityCritical, "frozen")
+	waitFor(func() bool { return len(pushes) == 4 && len(sms) == 3 })
+	require.Len(t, errLogQueue.peekErrLogs(10), 0)
+
+	// given up
+	mu.Lock()
+	failing = 1000
+	mu.Unlock()
+	d.alert(AlertSeverityError, "RPC error")
+	require.Eventually(t, func() bool { return len(errLogQueue.peekErrLogs(10)) == 1 }, 5*time.Second, time.Millisecond)
+	require.Contains(t, errLogQueue.peekErrLogs(10)[0].Msg, "failed to send error alert by webhook "+server.URL+"/push, attempts: 8")
+}
@@ -0,0 +1,280 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RoleReader   = "reader"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+
+	maxSignatureAge = 60 // in seconds
+	rateLimitWindow = 60 // in seconds
+)
+
+var roleLevels = map[string]int{
+	RoleReader:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ApiKeyConfig describes a client of admin API
+type ApiKeyConfig struct {
+	Name      string `json:"name"`
+	Key       string `json:"key"`        // sent as bearer token, also used to sign sensitive requests
+	Role      string `json:"role"`       // reader|operator|admin
+	RateLimit uint32 `json:"rate_limit"` // requests per minute, 0 means unlimited
+}
+
+// JwtClaims are the claims of HS256 JWTs accepted by admin API
+type JwtClaims struct {
+	Sub  string `json:"sub"`
+	Role string `json:"role"`
+	Exp  int64  `json:"exp"` // required
+}
+
+type rateWindow struct {
+	start int64
+	count uint32
+}
+
+// ApiAuth authenticates admin API requests by API key or JWT,
+// checks roles, signatures of sensitive requests and per-key rate limits.
+type ApiAuth struct {
+	mu        sync.Mutex
+	keys      map[string]ApiKeyConfig // key => config
+	jwtSecret []byte
+	windows   map[string]*rateWindow // key name => current window
+}
+
+type apiCaller struct {
+	name string
+	role string
+	key  *ApiKeyConfig // nil for JWT
+}
+
+func newApiAuth(keys []ApiKeyConfig, jwtSecret string) (*ApiAuth, error) {
+	auth := &ApiAuth{windows: map[string]*rateWindow{}}
+	return auth, auth.set(keys, jwtSecret)
+}
+
+func (auth *ApiAuth) set(keys []ApiKeyConfig, jwtSecret string) error {
+	m := map[string]ApiKeyConfig{}
+	names := map[string]bool{}
+	for _, key := range keys {
+		if key.Name == "" || key.Key == "" {
+			return fmt.Errorf("missing name or key of API key")
+		}
+		if roleLevels[key.Role] == 0 {
+			return fmt.Errorf("invalid role of API key %s: %s", key.Name, key.Role)
+		}
+		if names[key.Name] || m[key.Key].Name != "" {
+			return fmt.Errorf("duplicated API key: %s", key.Name)
+		}
+		names[key.Name] = true
+		m[key.Key] = key
+	}
+
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	auth.keys = m
+	auth.jwtSecret = []byte(jwtSecret)
+	return nil
+}
+
+func (auth *ApiAuth) isEnabled() bool {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	return len(auth.keys) > 0 || len(auth.jwtSecret) > 0
+}
+
+// find the caller by bearer token, which is either an API key or a JWT, nil means unknown
+func (auth *ApiAuth) getCaller(token string, now int64) (*apiCaller, error) {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	for k, key := range auth.keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(k)) == 1 {
+			key := key
+			return &apiCaller{name: key.Name, role: key.Role, key: &key}, nil
+		}
+	}
+	if len(auth.jwtSecret) > 0 && strings.Count(token, ".") == 2 {
+		claims, err := verifyJWT(token, auth.jwtSecret, now)
+		if err != nil {
+			return nil, err
+		}
+		return &apiCaller{name: "jwt:" + claims.Sub, role: claims.Role}, nil
+	}
+	return nil, nil
+}
+
+// count a request of the key, return false if it exceeds rate limit
+func (auth *ApiAuth) allow(key *ApiKeyConfig, now int64) bool {
+	if key.RateLimit == 0 {
+		return true
+	}
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	w := auth.windows[key.Name]
+	if w == nil || now-w.start >= rateLimitWindow {
+		w = &rateWindow{start: now}
+		auth.windows[key.Name] = w
+	}
+	w.count++
+	return w.count <= key.RateLimit
+}
+
+func verifyJWT(token string, secret []byte, now int64) (*JwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWT")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported JWT alg")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT signature")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, fmt.Errorf("invalid JWT signature")
+	}
+
+	var claims JwtClaims
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed JWT claims")
+	}
+	if claims.Exp == 0 || now >= claims.Exp {
+		return nil, fmt.Errorf("JWT expired")
+	}
+	if roleLevels[claims.Role] == 0 {
+		return nil, fmt.Errorf("invalid role in JWT: %s", claims.Role)
+	}
+	return &claims, nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	bz, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bz, v)
+}
+
+// hex(HMAC-SHA256(key, timestamp + method + requestURI + body))
+func signApiRequest(key, timestamp, method, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + method + uri))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sensitive requests must be signed by the API key, see signApiRequest()
+func checkApiSignature(r *http.Request, key string, now int64) error {
+	timestamp := r.Header.Get("X-Api-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid X-Api-Timestamp")
+	}
+	if now-ts > maxSignatureAge || ts-now > maxSignatureAge {
+		return fmt.Errorf("request expired")
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	sig := signApiRequest(key, timestamp, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(sig), []byte(strings.ToLower(r.Header.Get("X-Api-Signature")))) {
+		return fmt.Errorf("invalid X-Api-Signature")
+	}
+	return nil
+}
+
+// admin API requires "Authorization: Bearer <admin token|API key|JWT>",
+// the caller must have the role, and sensitive requests must be signed by API key
+func (bot *MarketMakerBot) checkApiAuth(w http.ResponseWriter, r *http.Request, role string, sensitive bool) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	now := time.Now().Unix()
+	caller, httpStatus, err := bot.authorizeApiCaller(token, role, now)
+	if err != nil {
+		if httpStatus != http.StatusOK {
+			w.WriteHeader(httpStatus)
+		}
+		NewErrResp(err.Error()).WriteTo(w)
+		return false
+	}
+	if caller == nil {
+		return true // the legacy admin token has admin role and needs no signature
+	}
+	if sensitive {
+		if caller.key == nil {
+			w.WriteHeader(http.StatusForbidden)
+			NewErrResp("sensitive request must be signed by API key").WriteTo(w)
+			return false
+		}
+		if err = checkApiSignature(r, caller.key.Key, now); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			NewErrResp(err.Error()).WriteTo(w)
+			return false
+		}
+	}
+	log.Infof("admin API %s %s called by %s", r.Method, r.URL.Path, caller.name)
+	return true
+}
+
+// check the bearer token, role and rate limit of an admin API caller,
+// returns nil caller for the legacy admin token, or an error with the HTTP status to reply
+func (bot *MarketMakerBot) authorizeApiCaller(token, role string, now int64) (*apiCaller, int, error) {
+	authEnabled := bot.apiAuth != nil && bot.apiAuth.isEnabled()
+	if bot.adminToken == "" && !authEnabled {
+		return nil, http.StatusOK, fmt.Errorf("admin API is disabled")
+	}
+
+	if bot.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(bot.adminToken)) == 1 {
+		return nil, http.StatusOK, nil
+	}
+
+	var caller *apiCaller
+	var err error
+	if authEnabled {
+		caller, err = bot.apiAuth.getCaller(token, now)
+	}
+	if err != nil {
+		return nil, http.StatusUnauthorized, fmt.Errorf("unauthorized: %w", err)
+	}
+	if caller == nil {
+		return nil, http.StatusUnauthorized, fmt.Errorf("unauthorized")
+	}
+	if caller.key != nil && !bot.apiAuth.allow(caller.key, now) {
+		return nil, http.StatusTooManyRequests, fmt.Errorf("rate limit exceeded")
+	}
+	if roleLevels[caller.role] < roleLevels[role] {
+		return nil, http.StatusForbidden, fmt.Errorf("%s role required", role)
+	}
+	return caller, http.StatusOK, nil
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func makeTestJWT(secret, claims string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestNewApiAuth(t *testing.T) {
+	_, err := newApiAuth([]ApiKeyConfig{{Name: "a", Key: "k", Role: "root"}}, "")
+	require.ErrorContains(t, err, "invalid role of API key a")
+	_, err = newApiAuth([]ApiKeyConfig{{Name: "a", Role: RoleAdmin}}, "")
+	require.ErrorContains(t, err, "missing name or key")
+	_, err = newApiAuth([]ApiKeyConfig{
+		{Name: "a", Key: "k1", Role: RoleAdmin},
+		{Name: "b", Key: "k1", Role: RoleReader},
+	}, "")
+	require.ErrorContains(t, err, "duplicated API key: b")
+
+	auth, err := newApiAuth(nil, "")
+	require.NoError(t, err)
+	require.False(t, auth.isEnabled())
+	require.NoError(t, auth.set(nil, "secret"))
+	require.True(t, auth.isEnabled())
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Now().Unix()
+	token := makeTestJWT("secret", fmt.Sprintf(`{"sub":"alice","role":"operator","exp":%d}`, now+60))
+	claims, err := verifyJWT(token, []byte("secret"), now)
+	require.NoError(t, err)
+	require.Equal(t, "alice", claims.Sub)
+	require.Equal(t, RoleOperator, claims.Role)
+
+	_, err = verifyJWT(token, []byte("wrong"), now)
+	require.ErrorContains(t, err, "invalid JWT signature")
+	_, err = verifyJWT(token, []byte("secret"), now+60)
+	require.ErrorContains(t, err, "JWT expired")
+	_, err = verifyJWT(makeTestJWT("secret", `{"sub":"alice","role":"admin"}`), []byte("secret"), now)
+	require.ErrorContains(t, err, "JWT expired")
+	_, err = verifyJWT(makeTestJWT("secret", fmt.Sprintf(`{"role":"root","exp":%d}`, now+60)), []byte("secret"), now)
+	require.ErrorContains(t, err, "invalid role")
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	_, err = verifyJWT(none+"."+strings.Split(token, ".")[1]+".", []byte("secret"), now)
+	require.ErrorContains(t, err, "unsupported JWT alg")
+}
+
+func TestAdminApiRoles(t *testing.T) {
+	al, err := newAccessList("")
+	require.NoError(t, err)
+	auth, err := newApiAuth([]ApiKeyConfig{
+		{Name: "r", Key: "rkey", Role: RoleReader, RateLimit: 2},
+		{Name: "a", Key: "akey", Role: RoleAdmin},
+	}, "jwtsecret")
+	require.NoError(t, err)
+	_bot := &MarketMakerBot{accessList: al, apiAuth: auth}
+	mux := _bot.createHttpHandlers()
+
+	call := func(method, token string, body string, signKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/access-list", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if signKey != "" {
+			ts := fmt.Sprintf("%d", time.Now().Unix())
+			req.Header.Set("X-Api-Timestamp", ts)
+			req.Header.Set("X-Api-Signature", signApiRequest(signKey, ts, method, "/admin/access-list", []byte(body)))
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"blacklist_pkhs":["` + toHex(gethAddrBytes("pkh1")) + `"]}`
+	require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "wrong", "", "").Code)
+	require.Contains(t, call(http.MethodGet, "rkey", "", "").Body.String(), `"success":true`)
+
+	// reader can not write
+	w := call(http.MethodPost, "rkey", body, "rkey")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), "admin role required")
+
+	// rate limited
+	require.Equal(t, http.StatusTooManyRequests, call(http.MethodGet, "rkey", "", "").Code)
+
+	// admin must sign
+	w = call(http.MethodPost, "akey", body, "")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), "X-Api-Timestamp")
+	w = call(http.MethodPost, "akey", body, "rkey")
+	require.Contains(t, w.Body.String(), "invalid X-Api-Signature")
+	w = call(http.MethodPost, "akey", body, "akey")
+	require.Contains(t, w.Body.String(), `"success":true`)
+	require.False(t, _bot.isSenderAllowed(gethAddrBytes("pkh1"), nil))
+
+	// JWT can not do sensitive actions
+	jwt := makeTestJWT("jwtsecret", fmt.Sprintf(`{"sub":"bob","role":"admin","exp":%d}`, time.Now().Unix()+60))
+	require.Contains(t, call(http.MethodGet, jwt, "", "").Body.String(), `"success":true`)
+	w = call(http.MethodPost, jwt, body, "")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), "must be signed by API key")
+	jwt = makeTestJWT("wrong", fmt.Sprintf(`{"sub":"bob","role":"admin","exp":%d}`, time.Now().Unix()+60))
+	require.Contains(t, call(http.MethodGet, jwt, "", "").Body.String(), "invalid JWT signature")
+}
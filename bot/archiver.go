@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const archiveInterval = 3600 // 1h
+
+// periodically move finished swaps older than retention days to archive tables,
+// and prune expired quotes, confirmed BCH txs, handled events, suspect and SBAS txs, to keep hot tables small
+func (bot *MarketMakerBot) archiveSwaps() {
+	if bot.retentionDays == 0 {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastArchivedAt < archiveInterval {
+		return
+	}
+	bot.lastArchivedAt = now
+
+	log.Info("archive finished swaps ...")
+	before := time.Unix(now, 0).AddDate(0, 0, -int(bot.retentionDays))
+	for {
+		n, err := bot.db.archiveBch2SbchRecords(before, bot.dbQueryLimit)
+		if err != nil {
+			bot.logError("DB error, failed to archive BCH2SBCH records: ", err)
+			return
+		}
+		log.Info("archived BCH2SBCH records: ", n)
+		if n == 0 || n < bot.dbQueryLimit {
+			break
+		}
+	}
+	for {
+		n, err := bot.db.archiveSbch2BchRecords(before, bot.dbQueryLimit)
+		if err != nil {
+			bot.logError("DB error, failed to archive SBCH2BCH records: ", err)
+			return
+		}
+		log.Info("archived SBCH2BCH records: ", n)
+		if n == 0 || n < bot.dbQueryLimit {
+			break
+		}
+	}
+
+	n, err := bot.db.pruneQuotes(before)
+	if err != nil {
+		bot.logError("DB error, failed to prune quotes: ", err)
+		return
+	}
+	log.Info("pruned quotes: ", n)
+	n, err = bot.db.prunePendingBchTxs(before)
+	if err != nil {
+		bot.logError("DB error, failed to prune pending BCH txs: ", err)
+		return
+	}
+	log.Info("pruned pending BCH txs: ", n)
+	n, err = bot.db.pruneSwapEvents(before)
+	if err != nil {
+		bot.logError("DB error, failed to prune events: ", err)
+		return
+	}
+	log.Info("pruned events: ", n)
+	n, err = bot.db.pruneSuspectBchTxs(before)
+	if err != nil {
+		bot.logError("DB error, failed to prune suspect txs: ", err)
+		return
+	}
+	log.Info("pruned suspect txs: ", n)
+	n, err = bot.db.pruneSbasBchTxs(before)
+	if err != nil {
+		bot.logError("DB error, failed to prune SBAS txs: ", err)
+		return
+	}
+	log.Info("pruned SBAS txs: ", n)
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestArchiveSwaps(t *testing.T) {
+	old := time.Now().AddDate(0, 0, -40)
+	_db := initDB(t, 123, 456)
+	for i, x := range []struct {
+		status      Bch2SbchStatus
+		filledValue uint64
+		updatedAt   time.Time
+	}{
+		{Bch2SbchStatusBchUnlocked, 0, old},           // archived
+		{Bch2SbchStatusBchUnlocked, 1000, old},        // remainder not refunded
+		{Bch2SbchStatusSbchRefunded, 0, time.Now()},   // too new
+		{Bch2SbchStatusSbchLocked, 0, old},            // not finished
+		{Bch2SbchStatusRemainderRefunded, 1000, old},  // archived
+		{Bch2SbchStatusTooLateToLockSbch, 0, old},     // archived
+		{Bch2SbchStatusSecretRevealed, 0, time.Now()}, // not finished
+	} {
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			Model:          gorm.Model{CreatedAt: x.updatedAt, UpdatedAt: x.updatedAt},
+			BchLockHeight:  122,
+			BchLockTxHash:  toHex(gethHash32Bytes("bchlock" + string(rune('0'+i)))),
+			Value:          12345678,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(gethAddrBytes("user")),
+			HashLock:       toHex(gethHash32Bytes("b2s" + string(rune('0'+i)))),
+			TimeLock:       100,
+			SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+			FilledValue:    x.filledValue,
+			Status:         x.status,
+		}))
+	}
+	for i, x := range []struct {
+		status    Sbch2BchStatus
+		updatedAt time.Time
+	}{
+		{Sbch2BchStatusSbchUnlocked, old},            // archived
+		{Sbch2BchStatusBchLocked, old},               // not finished
+		{Sbch2BchStatusBchRefunded, old},             // archived
+		{Sbch2BchStatusUnprofitable, old},            // archived
+		{Sbch2BchStatusPriceChanged, old},            // archived
+		{Sbch2BchStatusNew, time.Now()},              // not finished
+		{Sbch2BchStatusTooLateToLockBch, time.Now()}, // too new
+	} {
+		require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+			Model:           gorm.Model{CreatedAt: x.updatedAt, UpdatedAt: x.updatedAt},
+			SbchLockTime:    1234567890,
+			SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock" + string(rune('0'+i)))),
+			Value:           12345678,
+			SbchPrice:       1e8,
+			SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+			BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+			HashLock:        toHex(gethHash32Bytes("s2b" + string(rune('0'+i)))),
+			TimeLock:        72000,
+			HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+			Status:          x.status,
+		}))
+	}
+	require.NoError(t, _db.addQuote(&Quote{
+		HashLock: "q1", Direction: DirectionBch2Sbch, Value: 1e8, Price: 1e8, ValidUntil: old.Unix()}, time.Now().Unix()))
+	require.NoError(t, _db.addQuote(&Quote{
+		HashLock: "q2", Direction: DirectionBch2Sbch, Value: 1e8, Price: 1e8, ValidUntil: time.Now().Unix()}, time.Now().Unix()))
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 2, // archived in batches
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	// disabled
+	_bot.archiveSwaps()
+	b2sRecords, err := _db.GetAllBch2SbchRecords()
+	require.NoError(t, err)
+	require.Len(t, b2sRecords, 7)
+
+	_bot.retentionDays = 30
+	_bot.archiveSwaps()
+	require.NotZero(t, _bot.lastArchivedAt)
+
+	b2sRecords, err = _db.GetAllBch2SbchRecords()
+	require.NoError(t, err)
+	require.Len(t, b2sRecords, 4)
+	s2bRecords, err := _db.GetAllSbch2BchRecords()
+	require.NoError(t, err)
+	require.Len(t, s2bRecords, 3)
+
+	require.True(t, _db.isBch2SbchRecordArchived(toHex(gethHash32Bytes("b2s0"))))
+	require.False(t, _db.isBch2SbchRecordArchived(toHex(gethHash32Bytes("b2s1"))))
+	require.True(t, _db.isSbch2BchRecordArchived(toHex(gethHash32Bytes("s2b3"))))
+	require.False(t, _db.isSbch2BchRecordArchived(toHex(gethHash32Bytes("s2b5"))))
+
+	_, err = _db.getQuoteByHashLock("q1")
+	require.Error(t, err)
+	_, err = _db.getQuoteByHashLock("q2")
+	require.NoError(t, err)
+
+	// archived records are still counted by stats
+	b2sRecords, err = _db.getBch2SbchRecordsCreatedSince(old.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, b2sRecords, 7)
+	s2bRecords, err = _db.getSbch2BchRecordsCreatedSince(old.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, s2bRecords, 7)
+}
@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// a batch tx of this many inputs is about 80KB, below the 100KB limit of standard txs
+const maxBatchInputs = 250
+
+func checkBatchMaxInputs(n uint32) error {
+	if n < 2 || n > maxBatchInputs {
+		return fmt.Errorf("batch max inputs not in [2, %d]: %d", maxBatchInputs, n)
+	}
+	return nil
+}
+
+// Deposits to batchable covenants (quoted while batchReceipts is on) are claimed in batch txs
+// of up to batchMaxInputs inputs, input#i paying output#i to bot, so that the miner fee of the
+// tx overhead is shared. Every deposit still has its own chain job, which saves the batch tx before
+// it is broadcast, so re-claimers resend it one by one like the unlock tx of a single deposit.
+// Return the records left to be unlocked one by one.
+func (bot *MarketMakerBot) unlockBchUserDepositsInBatches(records []*Bch2SbchRecord, now time.Time,
+) (left []*Bch2SbchRecord) {
+
+	if !bot.batchReceipts {
+		return records
+	}
+
+	var batchable []*Bch2SbchRecord
+	for _, record := range records {
+		if bot.canBatchUnlock(record) {
+			batchable = append(batchable, record)
+		} else {
+			left = append(left, record)
+		}
+	}
+	if len(batchable) < 2 {
+		return records
+	}
+
+	for start := 0; start < len(batchable); start += int(bot.batchMaxInputs) {
+		end := start + int(bot.batchMaxInputs)
+		if end > len(batchable) {
+			end = len(batchable)
+		}
+		bot.unlockBchUserDepositsInBatch(batchable[start:end], now)
+	}
+	return
+}
+
+func (bot *MarketMakerBot) canBatchUnlock(record *Bch2SbchRecord) bool {
+	if !bot.getChainAdapter().CanBatchClaim(newBch2SbchHtlcSpec(record)) {
+		return false
+	}
+	// the tx saved by a previous attempt is resent as is
+	job := bot.getUnfinishedChainJob(record.HashLock, SwapLegBchUnlock)
+	return job == nil || job.RawTx == ""
+}
+
+func (bot *MarketMakerBot) unlockBchUserDepositsInBatch(records []*Bch2SbchRecord, now time.Time) {
+	var claimed []*Bch2SbchRecord
+	var jobs []*ChainJob
+	for _, record := range records {
+		log.Info("record: ", toJSON(record))
+		job := bot.claimBchUnlockJob(record, now)
+		if job == nil {
+			continue
+		}
+		if job.RawTx != "" {
+			// saved by another worker after canBatchUnlock()
+			bot.unlockBchUserDepositWithJob(record, job)
+			continue
+		}
+		if bot.idempotencyGuard {
+			spent, err := bot.isBchDepositSpent(record)
+			if err != nil {
+				bot.logError("failed to look up BCH deposit on chain: ", err)
+				bot.releaseChainJob(job, err)
+				continue
+			}
+			if spent {
+				bot.logWarnf("BCH deposit is spent, not unlocking again, hashLock: %s", record.HashLock)
+				bot.saveUnlockedBchRecord(record, job, "?")
+				continue
+			}
+		}
+		claimed = append(claimed, record)
+		jobs = append(jobs, job)
+	}
+	if len(claimed) == 0 {
+		return
+	}
+	if len(claimed) == 1 {
+		bot.unlockBchUserDepositWithJob(claimed[0], jobs[0])
+		return
+	}
+
+	claims := make([]htlcbch.HtlcClaim, len(claimed))
+	for i, record := range claimed {
+		claims[i] = htlcbch.HtlcClaim{
+			Htlc:   newBch2SbchHtlcSpec(record),
+			TxID:   gethcmn.FromHex(record.BchLockTxHash),
+			Vout:   record.BchLockVout,
+			InAmt:  int64(record.Value),
+			Secret: gethcmn.FromHex(record.Secret),
+		}
+	}
+	tx, err := bot.getChainAdapter().BuildBatchClaim(claims, bot.bchUnlockMinerFeeRate, bot.getBchTxLockTime())
+	if err != nil {
+		// e.g. an output below dust limit, unlock them one by one
+		bot.logError("failed to create batch unlock tx: ", err)
+		for i, record := range claimed {
+			bot.unlockBchUserDepositWithJob(record, jobs[i])
+		}
+		return
+	}
+	log.Info("batch tx: ", htlcbch.MsgTxToHex(tx))
+
+	txHash, rawTx := tx.TxHash().String(), htlcbch.MsgTxToHex(tx)
+	for i, job := range jobs {
+		if err = bot.db.setChainJobTx(job.ID, txHash, rawTx); err != nil {
+			bot.logError("DB error, failed to save tx of chain job: ", err)
+			for _, job := range jobs[:i] {
+				if err := bot.db.setChainJobTx(job.ID, "", ""); err != nil {
+					bot.logError("DB error, failed to clear tx of chain job: ", err)
+				}
+			}
+			for _, job := range jobs {
+				bot.releaseChainJob(job, err)
+			}
+			return
+		}
+		job.TxHash, job.RawTx = txHash, rawTx
+	}
+
+	if _, err = bot.bchCli.SendTx(tx); err != nil {
+		bot.logError("failed to send batch unlock tx: ", err)
+		if !isUtxoSpentErr(err) {
+			// the tx may still be mined, it is resent by the next attempts
+			for _, job := range jobs {
+				bot.releaseChainJob(job, err)
+			}
+			return
+		}
+		// some deposits are spent by others, unlock them one by one
+		for i, record := range claimed {
+			if err := bot.db.setChainJobTx(jobs[i].ID, "", ""); err != nil {
+				bot.logError("DB error, failed to clear tx of chain job: ", err)
+				bot.releaseChainJob(jobs[i], err)
+				continue
+			}
+			jobs[i].TxHash, jobs[i].RawTx = "", ""
+			bot.unlockBchUserDepositWithJob(record, jobs[i])
+		}
+		return
+	}
+	log.Info("BCH batch unlock tx sent, hash: ", txHash, ", deposits: ", len(claimed))
+
+	for i, record := range claimed {
+		bot.saveBchSwapMsgTx(record.HashLock, SwapLegBchUnlock, tx)
+		if fee := int64(record.Value) - tx.TxOut[i].Value; fee > 0 {
+			if err := bot.db.addSwapBchMinerFee(record.HashLock, uint64(fee)); err != nil {
+				bot.logError("DB error, failed to update swap cost: ", err)
+			}
+		}
+	}
+	fwdTx := bot.forwardBchToTreasury(claimed[0].HashLock, tx, bot.bchUnlockMinerFeeRate)
+	if fwdTx != nil {
+		for _, record := range claimed[1:] {
+			bot.saveBchSwapMsgTx(record.HashLock, SwapLegBchForward, fwdTx)
+		}
+	}
+	bot.watchBchTx(claimed[0].HashLock, SwapLegBchUnlock, tx, fwdTx)
+	for i, record := range claimed {
+		bot.saveUnlockedBchRecord(record, jobs[i], txHash)
+	}
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func addSecretRevealedBch2SbchRecord(t *testing.T, _db DB, i int, batchable bool) {
+	_secret := gethHash32Bytes(fmt.Sprintf("secret%d", i))
+	_hashLock := sha256.Sum256(_secret)
+	covenantHash := ""
+	if batchable {
+		covenantHash = htlcbch.BatchableCovenantTemplate().Hash()
+	}
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes(fmt.Sprintf("bchlock%d", i))),
+		Value:          12345678,
+		BchPrice:       1e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(_hashLock[:]),
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		CovenantHash:   covenantHash,
+		SbchLockTxHash: toHex(gethHash32Bytes(fmt.Sprintf("sbchlock%d", i))),
+		Secret:         toHex(_secret),
+		Status:         Bch2SbchStatusSecretRevealed,
+	}))
+}
+
+func TestBch2Sbch_botUnlockBch_batch(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i := 0; i < 3; i++ {
+		addSecretRevealedBch2SbchRecord(t, _db, i, true)
+	}
+	addSecretRevealedBch2SbchRecord(t, _db, 3, false) // HTLC4, not batchable
+
+	_bchCli := newMockBchClient(124, 130)
+	_bot := &MarketMakerBot{
+		db:             _db,
+		dbQueryLimit:   100,
+		bchCli:         _bchCli,
+		bchPrivKey:     testBchPrivKey,
+		bchPkh:         testBchPkh,
+		bchAddr:        testBchAddr,
+		batchReceipts:  true,
+		batchMaxInputs: 2,
+		errLogQueue:    newErrLogQueue(100),
+
+		bchUnlockMinerFeeRate: 2,
+	}
+	_bot.unlockBchUserDeposits()
+
+	// a batch of 2 deposits, the rest are unlocked one by one
+	require.Len(t, _bchCli.sentTxs, 3)
+	batchTx := _bchCli.sentTxs[0]
+	require.Len(t, batchTx.TxIn, 2)
+	require.Len(t, batchTx.TxOut, 2)
+	require.Len(t, _bchCli.sentTxs[1].TxIn, 1)
+	require.Len(t, _bchCli.sentTxs[2].TxIn, 1)
+
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusBchUnlocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	inBatch := 0
+	for _, record := range records {
+		if record.BchUnlockTxHash == batchTx.TxHash().String() {
+			inBatch++
+			require.Equal(t, htlcbch.BatchableCovenantTemplate().Hash(), record.CovenantHash)
+			require.Equal(t, batchTx.TxOut[0].Value, batchTx.TxOut[1].Value)
+			require.Less(t, batchTx.TxOut[0].Value, int64(record.Value))
+		}
+	}
+	require.Equal(t, 2, inBatch)
+}
+
+func TestBch2Sbch_botUnlockBch_batchOff(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i := 0; i < 2; i++ {
+		addSecretRevealedBch2SbchRecord(t, _db, i, true)
+	}
+
+	_bchCli := newMockBchClient(124, 130)
+	_bot := &MarketMakerBot{
+		db:             _db,
+		dbQueryLimit:   100,
+		bchCli:         _bchCli,
+		bchPrivKey:     testBchPrivKey,
+		bchPkh:         testBchPkh,
+		bchAddr:        testBchAddr,
+		batchReceipts:  false,
+		batchMaxInputs: 20,
+		errLogQueue:    newErrLogQueue(100),
+	}
+
+	// deposits of batchable covenants are unlocked one by one if batching is turned off
+	_bot.unlockBchUserDeposits()
+	require.Len(t, _bchCli.sentTxs, 2)
+	require.Len(t, _bchCli.sentTxs[0].TxIn, 1)
+	require.Len(t, _bchCli.sentTxs[1].TxIn, 1)
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusBchUnlocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	// a batch of one deposit is a single unlock tx
+	_bot.batchReceipts = true
+	addSecretRevealedBch2SbchRecord(t, _db, 2, true)
+	_bot.unlockBchUserDeposits()
+	require.Len(t, _bchCli.sentTxs, 3)
+	require.Len(t, _bchCli.sentTxs[2].TxIn, 1)
+}
+
+func TestBch2Sbch_botUnlockBch_batchNotSent(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i := 0; i < 2; i++ {
+		addSecretRevealedBch2SbchRecord(t, _db, i, true)
+	}
+
+	_bchCli := newMockBchClient(124, 130)
+	_bchCli.sendErr = errors.New("timeout")
+	_bot := &MarketMakerBot{
+		db:             _db,
+		dbQueryLimit:   100,
+		bchCli:         _bchCli,
+		bchPrivKey:     testBchPrivKey,
+		bchPkh:         testBchPkh,
+		bchAddr:        testBchAddr,
+		batchReceipts:  true,
+		batchMaxInputs: 20,
+		errLogQueue:    newErrLogQueue(100),
+	}
+	_bot.unlockBchUserDeposits()
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusSecretRevealed, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	// the batch tx may still be mined, so it is resent by the job of each deposit
+	_bchCli.sendErr = nil
+	_bot.unlockBchUserDeposits()
+	require.Len(t, _bchCli.sentTxs, 2)
+	require.Len(t, _bchCli.sentTxs[0].TxIn, 2)
+	require.Equal(t, _bchCli.sentTxs[0].TxHash(), _bchCli.sentTxs[1].TxHash())
+	records, err = _db.getBch2SbchRecordsByStatus(Bch2SbchStatusBchUnlocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, _bchCli.sentTxs[0].TxHash().String(), records[0].BchUnlockTxHash)
+	require.Equal(t, _bchCli.sentTxs[0].TxHash().String(), records[1].BchUnlockTxHash)
+
+	// a batch of one deposit is a single unlock tx
+	addSecretRevealedBch2SbchRecord(t, _db, 2, true)
+	_bot.unlockBchUserDeposits()
+	require.Len(t, _bchCli.sentTxs, 3)
+	require.Len(t, _bchCli.sentTxs[2].TxIn, 1)
+}
+
+func TestCheckBatchMaxInputs(t *testing.T) {
+	require.NoError(t, checkBatchMaxInputs(2))
+	require.NoError(t, checkBatchMaxInputs(maxBatchInputs))
+	require.ErrorContains(t, checkBatchMaxInputs(1), "batch max inputs not in [2, 250]: 1")
+	require.Error(t, checkBatchMaxInputs(maxBatchInputs+1))
+}
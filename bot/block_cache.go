@@ -0,0 +1,253 @@
+package bot
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcash/bchd/btcjson"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// bumped when cached parse results change, older entries are treated as missing
+const blockCacheVersion = 1
+
+// CachedBchBlock is the parse result of a BCH block, cached by its hash
+type CachedBchBlock struct {
+	Version  int                       `json:"version"`
+	Height   int64                     `json:"height"`
+	Hash     string                    `json:"hash"`
+	Filter   string                    `json:"filter"` // chain and deposit filter the block was scanned with
+	Deposits []*htlcbch.HtlcLockInfo   `json:"deposits"`
+	Receipts []*htlcbch.HtlcUnlockInfo `json:"receipts"`
+	Spends   []*CachedBchSpend         `json:"spends"` // txs other than receipts, refunds are found among them
+}
+
+// CachedBchSpend is a tx with the txs whose outputs it spends
+type CachedBchSpend struct {
+	TxHash       string   `json:"tx"`
+	PrevTxHashes []string `json:"prev"`
+}
+
+func newCachedBchBlock(h int64, block *btcjson.GetBlockVerboseTxResult, scan *htlcbch.BlockScan,
+	filter string) *CachedBchBlock {
+
+	cached := &CachedBchBlock{
+		Version:  blockCacheVersion,
+		Height:   h,
+		Hash:     block.Hash,
+		Filter:   filter,
+		Deposits: scan.Deposits,
+		Receipts: scan.Receipts,
+	}
+	receiptTxs := map[string]bool{}
+	for _, receipt := range scan.Receipts {
+		receiptTxs[receipt.TxHash] = true
+	}
+	for _, tx := range block.Tx {
+		if receiptTxs[tx.Txid] {
+			continue
+		}
+		spend := &CachedBchSpend{TxHash: tx.Txid}
+		for _, vin := range tx.Vin {
+			if vin.Txid != "" { // not coinbase
+				spend.PrevTxHashes = append(spend.PrevTxHashes, vin.Txid)
+			}
+		}
+		if len(spend.PrevTxHashes) > 0 {
+			cached.Spends = append(cached.Spends, spend)
+		}
+	}
+	return cached
+}
+
+// BlockCache keeps parse results of BCH blocks in a dir, one file per block hash, so that
+// rescans and reorg revalidations skip fetching and parsing unchanged blocks. The least
+// recently used files are deleted once the total size exceeds the cap; file modification
+// times keep the LRU order across restarts.
+type BlockCache struct {
+	dir     string
+	maxSize int64 // in bytes
+
+	mu    sync.Mutex
+	lru   *list.List               // of *blockCacheFile, the front is the most recently used
+	files map[string]*list.Element // block hash => element of lru
+	size  int64
+}
+
+type blockCacheFile struct {
+	hash string
+	size int64
+}
+
+func newBlockCache(dir string, maxSize int64) (*BlockCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create block cache dir: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block cache dir: %w", err)
+	}
+
+	var infos []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read block cache dir: %w", err)
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+
+	c := &BlockCache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		files:   map[string]*list.Element{},
+	}
+	for _, info := range infos {
+		hash := strings.TrimSuffix(info.Name(), ".json")
+		c.files[hash] = c.lru.PushBack(&blockCacheFile{hash: hash, size: info.Size()})
+		c.size += info.Size()
+	}
+	c.evict()
+	log.Infof("block cache: %d blocks, %d bytes", c.lru.Len(), c.size)
+	return c, nil
+}
+
+// get returns nil if the block is not cached, or cached by another version or filter
+func (c *BlockCache) get(hash, filter string) *CachedBchBlock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem := c.files[hash]
+	if elem == nil {
+		return nil
+	}
+	bz, err := os.ReadFile(c.filePath(hash))
+	if err != nil {
+		log.Warn("block cache: failed to read block ", hash, ": ", err)
+		c.remove(elem)
+		return nil
+	}
+	var cached CachedBchBlock
+	if err = json.Unmarshal(bz, &cached); err != nil {
+		log.Warn("block cache: failed to parse block ", hash, ": ", err)
+		_ = os.Remove(c.filePath(hash))
+		c.remove(elem)
+		return nil
+	}
+	if cached.Version != blockCacheVersion || cached.Filter != filter || cached.Hash != hash {
+		return nil
+	}
+
+	c.lru.MoveToFront(elem)
+	now := time.Now()
+	_ = os.Chtimes(c.filePath(hash), now, now)
+	return &cached
+}
+
+func (c *BlockCache) put(cached *CachedBchBlock) error {
+	if cached.Hash == "" {
+		return nil
+	}
+	bz, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err = os.WriteFile(c.filePath(cached.Hash), bz, 0644); err != nil {
+		return err
+	}
+	if elem := c.files[cached.Hash]; elem != nil {
+		file := elem.Value.(*blockCacheFile)
+		c.size += int64(len(bz)) - file.size
+		file.size = int64(len(bz))
+		c.lru.MoveToFront(elem)
+	} else {
+		c.files[cached.Hash] = c.lru.PushFront(&blockCacheFile{hash: cached.Hash, size: int64(len(bz))})
+		c.size += int64(len(bz))
+	}
+	c.evict()
+	return nil
+}
+
+// delete the least recently used files until the size is within the cap
+func (c *BlockCache) evict() {
+	for c.size > c.maxSize && c.lru.Len() > 0 {
+		elem := c.lru.Back()
+		if err := os.Remove(c.filePath(elem.Value.(*blockCacheFile).hash)); err != nil && !os.IsNotExist(err) {
+			log.Warn("block cache: failed to evict block: ", err)
+			return
+		}
+		c.remove(elem)
+	}
+}
+
+func (c *BlockCache) remove(elem *list.Element) {
+	file := c.lru.Remove(elem).(*blockCacheFile)
+	delete(c.files, file.hash)
+	c.size -= file.size
+}
+
+func (c *BlockCache) filePath(hash string) string {
+	return filepath.Join(c.dir, hash+".json")
+}
+
+// the deposit filter changes parse results, SbasTxs of DepositFilter.Diagnose are not cached
+func (bot *MarketMakerBot) getBlockCacheFilter() string {
+	filter := bot.getDepositFilter()
+	floor := filter.Floor
+	if floor == nil {
+		floor = &htlcbch.DepositFloor{}
+	}
+	return fmt.Sprintf("%s/%d/%d/%d/%v", bot.getChainAdapter().ChainName(),
+		floor.MinValue, floor.UnlockFeeRate, floor.RefundFeeRate, filter.AnyOutputOrder)
+}
+
+func (bot *MarketMakerBot) cacheBchBlock(h int64, block *btcjson.GetBlockVerboseTxResult,
+	scan *htlcbch.BlockScan) *CachedBchBlock {
+
+	cached := newCachedBchBlock(h, block, scan, bot.getBlockCacheFilter())
+	if bot.blockCache != nil {
+		if err := bot.blockCache.put(cached); err != nil {
+			log.Warn("block cache: failed to cache BCH block#", h, ": ", err)
+		}
+	}
+	return cached
+}
+
+// returns the parse result of BCH block#h from cache if its hash is unchanged, or fetches and parses it
+func (bot *MarketMakerBot) getBchBlockScan(h int64) (*CachedBchBlock, error) {
+	if bot.blockCache != nil {
+		hash, err := bot.bchCli.GetBlockHash(h)
+		if err != nil {
+			return nil, err
+		}
+		if cached := bot.blockCache.get(hash.String(), bot.getBlockCacheFilter()); cached != nil {
+			return cached, nil
+		}
+	}
+	block, err := bot.bchCli.GetBlock(h)
+	if err != nil {
+		return nil, err
+	}
+	scan := bot.getChainAdapter().ScanBlock(block, bot.getDepositFilter())
+	return bot.cacheBchBlock(h, block, scan), nil
+}
@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestBlockCache(t *testing.T) {
+	dir := t.TempDir()
+	newBlock := func(h int64) *CachedBchBlock {
+		return &CachedBchBlock{
+			Version: blockCacheVersion,
+			Height:  h,
+			Hash:    strings.Repeat(string(rune('a'+h)), 64),
+			Filter:  "f",
+			Spends:  []*CachedBchSpend{{TxHash: "tx", PrevTxHashes: []string{"prev"}}},
+		}
+	}
+
+	cache, err := newBlockCache(dir, 1000)
+	require.NoError(t, err)
+	require.Nil(t, cache.get(newBlock(1).Hash, "f"))
+	require.NoError(t, cache.put(newBlock(1)))
+	require.NoError(t, cache.put(newBlock(2)))
+	require.NoError(t, cache.put(newBlock(3)))
+	require.Equal(t, newBlock(1), cache.get(newBlock(1).Hash, "f"))
+	require.Nil(t, cache.get(newBlock(1).Hash, "g"))
+
+	// block#2 is the least recently used
+	fileSize := cache.size / 3
+	cache.maxSize = fileSize * 3
+	require.NoError(t, cache.put(newBlock(4)))
+	require.Nil(t, cache.get(newBlock(2).Hash, "f"))
+	require.NotNil(t, cache.get(newBlock(3).Hash, "f"))
+	require.NotNil(t, cache.get(newBlock(1).Hash, "f"))
+	_, err = os.Stat(filepath.Join(dir, newBlock(2).Hash+".json"))
+	require.True(t, os.IsNotExist(err))
+
+	// LRU order is kept by modification times
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, newBlock(4).Hash+".json"), past, past))
+	cache, err = newBlockCache(dir, fileSize*2)
+	require.NoError(t, err)
+	require.Nil(t, cache.get(newBlock(4).Hash, "f"))
+	require.NotNil(t, cache.get(newBlock(3).Hash, "f"))
+	require.NotNil(t, cache.get(newBlock(1).Hash, "f"))
+
+	// broken files are dropped
+	require.NoError(t, os.WriteFile(filepath.Join(dir, newBlock(3).Hash+".json"), []byte("{"), 0644))
+	require.Nil(t, cache.get(newBlock(3).Hash, "f"))
+	require.NotContains(t, cache.files, newBlock(3).Hash)
+}
+
+func TestRescan_bch_blockCache(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_timeLock := uint16(100)
+	_penaltyBPS := uint16(500)
+	_evmAddr := gethAddrBytes("evm")
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, testBchPkh, _hashLock, _timeLock, _penaltyBPS)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+
+	_db := initDB(t, 200, 456)
+	_bchCli := newMockBchClient(124, 128)
+	cache, err := newBlockCache(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		bchPkh:       testBchPkh,
+		bchTimeLock:  _timeLock,
+		penaltyRatio: _penaltyBPS,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+		errLogQueue:  newErrLogQueue(100),
+		blockCache:   cache,
+	}
+
+	issues, err := _bot.Rescan(RescanChainBch, 124, 128, false)
+	require.NoError(t, err)
+	require.Len(t, issues, 0)
+	require.Equal(t, 5, _bchCli.getBlockCalls)
+
+	// unchanged blocks are not fetched again
+	issues, err = _bot.Rescan(RescanChainBch, 124, 128, false)
+	require.NoError(t, err)
+	require.Len(t, issues, 0)
+	require.Equal(t, 5, _bchCli.getBlockCalls)
+
+	// reorg
+	_bchCli.blocks[125] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+					{PkScript: newHtlcDepositOpRet(testBchPkh, _userPkh, _hashLock, _timeLock, _penaltyBPS, _evmAddr, 1e8)},
+				},
+			},
+		},
+	}
+	issues, err = _bot.Rescan(RescanChainBch, 124, 128, false)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, uint64(125), issues[0].Height)
+	require.Equal(t, "BCH2SBCH record not found", issues[0].Issue)
+	require.Equal(t, 6, _bchCli.getBlockCalls)
+
+	// cached deposits are found as well
+	issues, err = _bot.Rescan(RescanChainBch, 124, 128, false)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, toHex(_hashLock), issues[0].HashLock)
+	require.Equal(t, 6, _bchCli.getBlockCalls)
+
+	// scanned blocks are cached
+	_bchCli.hTo = 129
+	_bchCli.blocks[129] = &wire.MsgBlock{}
+	require.True(t, _bot.handleBchBlock(129))
+	require.Equal(t, 7, _bchCli.getBlockCalls)
+	_, err = _bot.Rescan(RescanChainBch, 129, 129, false)
+	require.NoError(t, err)
+	require.Equal(t, 7, _bchCli.getBlockCalls)
+}
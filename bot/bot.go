@@ -6,10 +6,13 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	gethcmn "github.com/ethereum/go-ethereum/common"
@@ -17,7 +20,7 @@ import (
 	gethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/gcash/bchd/bchec"
 	"github.com/gcash/bchd/btcjson"
-	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/wire"
 	"github.com/gcash/bchutil"
 	log "github.com/sirupsen/logrus"
 	"github.com/smartbch/atomic-swap-bot/htlcbch"
@@ -183,16 +186,22 @@ const (
 )
 
 type MarketMakerBot struct {
-	db          DB            // thread safe
-	bchCli      IBchClient    // thread safe
-	sbchCli     ISbchClient   // not thread safe
-	sbchCliRO   *SbchClientRO // not thread safe
-	errLogQueue *ErrLogQueue  // thread safe
+	db          DB             // thread safe
+	bchCli      IBchClient     // thread safe
+	sbchCli     ISbchClient    // thread safe, sends are serialized by the nonce manager
+	sbchCliRO   *SbchClientRO  // thread safe, read only
+	chaos       *chaosInjector // optional, injects faults into bchCli & sbchCli
+	errLogQueue *ErrLogQueue   // thread safe
 
 	// BCH key
 	bchPrivKey *bchec.PrivateKey
 	bchPkh     []byte
 	bchAddr    bchutil.Address // P2PKH
+	hdPkhs     *HdPkhs         // optional, derived receive PKHs
+
+	// BCH network
+	bchNet       *htlcbch.ChainParams
+	chainAdapter htlcbch.ChainAdapter
 
 	// sBCH key
 	sbchPrivKey *ecdsa.PrivateKey
@@ -209,61 +218,373 @@ type MarketMakerBot struct {
 	minSwapVal            uint64 // in sats
 	maxSwapVal            uint64 // in sats
 	bchConfirmations      uint8
+	sbchConfirmations     uint8
+	sbchLockConfs         uint8  // 0 means sbchConfirmations
+	sbchUnlockConfs       uint8  // 0 means sbchConfirmations
+	sbchLatestHeight      uint64 // got by the last scan
 	bchLockMinerFeeRate   uint64 // sats/byte
 	bchUnlockMinerFeeRate uint64 // sats/byte
 	bchRefundMinerFeeRate uint64 // sats/byte
+	bchDepositFloor       uint64 // in sats, 0 means disabled
+	strictDepositOutputs  bool   // see getDepositFilter()
+	bchCompactFilters     bool   // skip BCH blocks by compact filters, see mayBchBlockBeSkipped()
+	bchCFilterUnsupported bool   // compact filters are not served by the BCH node
+	bootstrap             bool   // index swaps of all users since the first SBAS block, see swap_index.go
+	bootstrapSbchHeight   uint64 // first sBCH block scanned in bootstrap mode
+	explorer              bool   // index swaps of all users for the explorer API, see explorer.go
+	sbasDiagnostics       string // off|log|save
+	bchSigType            htlcbch.SigType
+	antiFeeSniping        bool // see getBchTxLockTime()
 	dbQueryLimit          int
 	isSlaveMode           bool
+	isObserverMode        bool // never sign or broadcast anything
 	lazyMaster            bool // debug only
 
 	// internal state
 	lastPricesUpdatedAt int64
+	lastReconciledAt    int64
+	lastArchivedAt      int64
+	lastGaugesSampledAt int64
+	bchScannedAt        int64 // when BCH scanner caught up with chain tip
+	sbchScannedAt       int64 // when sBCH scanner caught up with chain tip
+
+	// quotes
+	quoteValidity uint32 // in seconds
+
+	// quote hashes committed on chain
+	quoteCommitment       bool
+	lastQuotesCommittedAt int64
+
+	idempotencyGuard bool // look up chains before locking or claiming, see findBchLockOnChain()
+
+	// stuck BCH txs
+	stuckTxBlocks   uint16 // 0 means disabled
+	stuckTxStrategy string // alert|rebroadcast|cpfp
+
+	// batch receipts of BCH deposits, see batch_receipt.go
+	batchReceipts  bool
+	batchMaxInputs uint32
+
+	// API
+	paused        atomic.Bool // set by admin API, see isPaused()
+	frozen        atomic.Bool // kill switch saved in DB, see isFrozen()
+	statsCache    *StatsCache
+	gauges        *Gauges
+	latencies     *SwapLatencyHistograms
+	gaugeInterval uint32 // in seconds, 0 means disabled
+
+	// dead man's switch, see heartbeat.go
+	heartbeatUrl      string // empty means disabled
+	heartbeatInterval uint32 // in seconds
+	heartbeatMaxLag   uint32 // in seconds, longer scan lag is pinged as failure, 0 means disabled
+	lastHeartbeatAt   int64
+
+	// BCH block notification
+	bchZmq *ZmqListener // optional, wakes up the loop on new BCH blocks
+
+	// BCH covenant watcher
+	fulcrumCli        IFulcrumClient  // optional, receipts are found in blocks if not set
+	watchedCovenants  map[string]bool // scripthash => true
+	lastFulcrumPingAt int64
+
+	// chain watchers => swap state machine
+	eventBus *EventBus // created on first use
+
+	// custom swap policies
+	swapHooks  []SwapHook
+	hookVetoed map[string]bool // hookName:hashLock => true
+
+	// hot/standby
+	leader *LeaderElector // optional
+
+	// reconciler
+	reconcileInterval uint32 // in seconds, 0 means disabled
+
+	// archiver
+	retentionDays uint32 // 0 means disabled
+
+	// timeout alerts
+	refundAlertBlocks uint16          // 0 means disabled
+	refundAlerted     map[string]bool // hashLock => true
+
+	// startup rescan
+	startupRescanBch  uint32 // in blocks, 0 means disabled
+	startupRescanSbch uint32 // in blocks, 0 means disabled
+	startupRescanned  bool
+
+	// cold storage
+	coldWallets *ColdWallets // optional
+	lastSweptAt int64
+
+	// fee revenue
+	feeWallets     *FeeWallets // optional
+	lastFeeSweptAt int64
+
+	referral *Referral // optional, paid by split covenants
+
+	negotiation *NegotiationConfig // optional, see /negotiate
+
+	// affiliates tagged by memos, see AffiliateConfig
+	affiliates              Affiliates
+	affiliatePayoutInterval uint32 // in seconds, 0 means disabled
+	lastAffiliatesPaidAt    int64
+
+	// multisig treasury
+	bchTreasury   *htlcbch.MultisigTreasury // optional, spends are approved by co-signers via admin API
+	treasuryLocks treasuryProposalLocks
+
+	// fee budget
+	sbchGasPrice      uint64 // in wei
+	profitabilityGate bool   // skip swaps whose estimated cost exceeds service fee
+
+	// partial fill
+	partialFill bool // lock what sBCH inventory allows and pay back the rest in BCH
+
+	// inventory reservations
+	reserveInventory bool // quotes and deposits reserve the counter-asset until it is locked or they expire
+
+	// see checkCounterpartyRisk()
+	maxRiskScore            uint32 // 0 means disabled
+	maxCounterpartyExposure uint64 // in sats, 0 means disabled
+
+	// concurrent swap handling
+	swapWorkers *SwapWorkers
+	bchWalletMu sync.Mutex // held while UTXOs of bot are selected and spent
+	cancelGuard swapCancelGuard
+	jobOwner    string // claims chain jobs, see ChainJob
+
+	// SEP20 tokens
+	tokens map[string]*Token // symbol => token
+
+	// other EVM chains
+	evmPeers []*EvmPeer
+
+	// admin
+	accessList *AccessList // optional
+	adminToken string      // legacy admin token, admin API is disabled if neither it nor apiAuth is set
+	apiAuth    *ApiAuth    // API keys and JWT
+
+	// public API
+	httpPolicy *HttpPolicy // CORS, trusted proxies and rate limit
+
+	webhooks   *WebhookDispatcher // POSTs swap status changes to integrators
+	emails     *EmailNotifier     // optional, emails users who registered with quotes
+	alerts     *AlertDispatcher   // optional, pushes operator alerts to SMS and push providers
+	deadlines  *DeadlineWatchdog  // fires persistent timers of in-flight swaps
+	recorder   *BlockRecorder     // optional, archives scanned blocks for replay
+	blockCache *BlockCache        // optional, parse results of BCH blocks by hash for rescans
+
+	// config
+	cfg      *Config
+	cfgFile  string // optional, for hot reload
+	reloadCh chan *Config
 }
 
-func NewBot(
-	dbFile string,
-	bchPrivKeyWIF, sbchPrivKeyHex string, // master mode
-	bchMasterAddr, sbchMasterAddr string, // slave mode
-	bchRpcUrl, sbchRpcUrl string,
-	sbchHtlcAddr gethcmn.Address,
-	sbchGasPrice *big.Int,
-	bchConfirmations uint8,
-	bchLockMinerFeeRate, bchUnlockMinerFeeRate, bchRefundMinerFeeRate uint64,
-	dbQueryLimit int,
-	debugMode bool,
-	slaveMode bool,
-	lazyMaster bool, // debug only
-) (*MarketMakerBot, error) {
-
-	// load BCH key
+func NewBot(cfg *Config) (*MarketMakerBot, error) {
+
+	bchNet, err := getBchChainParams(cfg.BchNet, cfg.DebugMode)
+	if err != nil {
+		return nil, err
+	}
+	bchSigType, err := getBchSigType(cfg.BchSigType, cfg.BchSigHashType)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.StuckTxBlocks > 0 {
+		if err = checkStuckTxStrategy(cfg.StuckTxStrategy); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.BchBatchReceipts {
+		if err = checkBatchMaxInputs(cfg.BchBatchMaxInputs); err != nil {
+			return nil, err
+		}
+	}
+	if err = checkSbasDiagnostics(cfg.SbasDiagnostics); err != nil {
+		return nil, err
+	}
+	if err = checkNegotiationConfig(cfg.Negotiation); err != nil {
+		return nil, err
+	}
+	if err = checkChaosConfig(cfg.Chaos, bchNet); err != nil {
+		return nil, err
+	}
+
+	// load BCH key, observer follows master like slave
 	bchPrivKey, bchPbk, bchPkh, bchAddr, err := loadBchKey(
-		bchPrivKeyWIF, bchMasterAddr, debugMode, slaveMode)
+		cfg.BchPrivKeyWIF, cfg.BchMasterAddr, bchNet, cfg.SlaveMode || cfg.ObserverMode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load BCH private key: %w", err)
 	}
 
-	// load sBCH key
-	sbchPrivKey, sbchAddr, err := loadSbchKey(sbchPrivKeyHex, sbchMasterAddr, slaveMode)
+	// load sBCH key, observer has no key at all
+	var sbchPrivKey *ecdsa.PrivateKey
+	var sbchAddr gethcmn.Address
+	if cfg.ObserverMode {
+		sbchAddr, err = loadSbchMasterAddr(cfg.SbchMasterAddr)
+	} else {
+		sbchPrivKey, sbchAddr, err = loadSbchKey(cfg.SbchPrivKeyHex, cfg.SbchMasterAddr, cfg.SlaveMode)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load sBCH private key: %w", err)
 	}
 
+	// load BCH xpub
+	var hdPkhs *HdPkhs
+	if cfg.BchXPub != "" {
+		hdPkhs, err = newHdPkhs(cfg.BchXPub, cfg.BchXPubLookahead)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load BCH xpub: %w", err)
+		}
+	}
+
+	// load SEP20 tokens
+	tokens, err := newTokens(cfg.Tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokens: %w", err)
+	}
+
+	// load access list
+	accessList, err := newAccessList(cfg.AccessListFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load access list: %w", err)
+	}
+
+	// load API keys
+	apiAuth, err := newApiAuth(cfg.ApiKeys, cfg.JwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API keys: %w", err)
+	}
+	if (cfg.TlsCertFile == "") != (cfg.TlsKeyFile == "") {
+		return nil, fmt.Errorf("both TLS cert and key files are required")
+	}
+	httpPolicy, err := newHttpPolicy(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HTTP policy: %w", err)
+	}
+	bchTreasury, err := newTreasury(cfg, bchNet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load treasury: %w", err)
+	}
+	coldWallets, err := newColdWallets(cfg, bchNet, bchTreasury)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cold wallets: %w", err)
+	}
+	feeWallets, err := newFeeWallets(cfg, bchNet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fee wallets: %w", err)
+	}
+	referral, err := newReferral(cfg, bchNet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load referral: %w", err)
+	}
+	affiliates, err := newAffiliates(cfg.Affiliates, bchNet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load affiliates: %w", err)
+	}
+
+	// load swap hooks, screening goes first
+	swapHooks := make([]SwapHook, 0, len(cfg.Plugins)+1)
+	if cfg.ScreeningUrl != "" {
+		screeningHook, err := newScreeningHook(cfg.ScreeningUrl)
+		if err != nil {
+			return nil, err
+		}
+		swapHooks = append(swapHooks, screeningHook)
+	}
+	for _, path := range cfg.Plugins {
+		hook, err := LoadSwapHookPlugin(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load plugin: %w", err)
+		}
+		swapHooks = append(swapHooks, hook)
+	}
+
+	var bchZmq *ZmqListener
+	if cfg.BchZmqUrl != "" {
+		bchZmq, err = newZmqListener(cfg.BchZmqUrl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var fulcrumCli IFulcrumClient
+	if cfg.BchFulcrumUrl != "" {
+		fulcrumCli, err = NewFulcrumClient(cfg.BchFulcrumUrl, 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// open DB
+	db, err := OpenDB(cfg.DbFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DB file: %w", err)
+	}
+
+	var leader *LeaderElector
+	jobOwner := getDefaultJobOwner()
+	if cfg.LeaderId != "" {
+		leader = newLeaderElector(db, cfg.LeaderId, cfg.LeaderTTL)
+		jobOwner = cfg.LeaderId
+	}
+
+	errLogQueue := newErrLogQueue(5000)
+	webhooks, err := newWebhookDispatcher(db, cfg.Webhooks, errLogQueue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	emails, err := newEmailNotifier(db, cfg.Email, errLogQueue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email: %w", err)
+	}
+	alerts, err := newAlertDispatcher(cfg.Alerts, sbchAddr.String(), errLogQueue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alerts: %w", err)
+	}
+	heartbeatInterval := cfg.HeartbeatInterval
+	if heartbeatInterval == 0 {
+		heartbeatInterval = defaultHeartbeatInterval
+	}
+
 	// create RPC clients
-	bchCli, err := NewBchClient(bchRpcUrl, bchAddr)
+	bchCli, err := NewBchClient(cfg.BchRpcUrl, bchAddr)
 	if err != nil {
 		return nil, fmt.Errorf("faield to create BCH RPC client: %w", err)
 	}
-	sbchCli, err := newSbchClient(sbchRpcUrl, 5*time.Second, sbchPrivKey, sbchHtlcAddr,
-		sbchGasPrice)
+	evmChain, err := cfg.getEvmChain()
+	if err != nil {
+		return nil, err
+	}
+	sbchCli, err := newSbchClient(cfg.SbchRpcUrl, 5*time.Second, sbchPrivKey, cfg.getSbchHtlcAddr(),
+		cfg.getTokenHtlcAddrs(), evmChain, db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sBCH RPC client: %w", err)
 	}
-	sbchCliRO, err := newSbchClientRO(sbchRpcUrl, 5*time.Second, sbchAddr)
+	if evmChain.ChainId != nil {
+		if _, err = sbchCli.getChainId(); err != nil {
+			return nil, fmt.Errorf("failed to check EVM chain ID: %w", err)
+		}
+	}
+	sbchCliRO, err := newSbchClientRO(cfg.SbchRpcUrl, 5*time.Second, sbchAddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sBCH RPC client (RO): %w", err)
 	}
+	var bchCliI IBchClient = bchCli
+	var sbchCliI ISbchClient = sbchCli
+	var chaos *chaosInjector
+	if cfg.Chaos != nil {
+		chaos = newChaosInjector(cfg.Chaos)
+		bchCliI, sbchCliI = newChaosBchClient(bchCli, chaos), newChaosSbchClient(sbchCli, chaos)
+	}
+
+	evmPeers, err := newEvmPeers(cfg.EvmPeers, sbchCliI, cfg.getSbchHtlcAddr(), sbchPrivKey, db)
+	if err != nil {
+		return nil, err
+	}
 
-	botInfo, err := sbchCli.getMarketMakerInfo(sbchAddr)
+	botInfo, err := sbchCliI.getMarketMakerInfo(sbchAddr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query bot info: %w", err)
 	}
@@ -273,50 +594,150 @@ func NewBot(
 			toHex(bchPkh), toHex(botInfo.BchPkh[:]))
 	}
 
-	// open DB
-	db, err := OpenDB(dbFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open DB file: %w", err)
+	var recorder *BlockRecorder
+	if cfg.RecordDir != "" {
+		recorder, err = newBlockRecorder(cfg.RecordDir, cfg.RecordAllTxs)
+		if err != nil {
+			return nil, err
+		}
+		err = recorder.recordBotInfo(&RecordedBotInfo{
+			BchNet:       cfg.BchNet,
+			BchPkh:       toHex(bchPkh),
+			SbchAddr:     sbchAddr.Hex(),
+			BchTimeLock:  botInfo.BchLockTime,
+			SbchTimeLock: botInfo.SbchLockTime,
+			PenaltyBPS:   botInfo.PenaltyBPS,
+			BchPrice:     weiToSats(botInfo.BchPrice),
+			SbchPrice:    weiToSats(botInfo.SbchPrice),
+			MinSwapVal:   weiToSats(botInfo.MinSwapAmt),
+			MaxSwapVal:   weiToSats(botInfo.MaxSwapAmt),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record bot info: %w", err)
+		}
+	}
+
+	var blockCache *BlockCache
+	if cfg.BlockCacheDir != "" {
+		blockCache, err = newBlockCache(cfg.BlockCacheDir, int64(cfg.BlockCacheSize)<<20)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// print bot info
+	if cfg.ObserverMode {
+		log.Info("observer mode, never sign or broadcast txs")
+	}
+	if cfg.Bootstrap {
+		log.Info("bootstrap mode, index all swaps since BCH block#", bchNet.FirstSbasHeight)
+	}
+	log.Info("BCH network : ", bchNet.Name)
 	log.Info("BCH pubkey  : ", "0x"+hex.EncodeToString(bchPbk))
 	log.Info("BCH PKH     : ", "0x"+hex.EncodeToString(bchPkh))
 	log.Info("BCH address : ", bchAddr.String())
 	log.Info("sBCH address: ", sbchAddr.String())
 
 	return &MarketMakerBot{
-		db:                    db,
-		bchCli:                bchCli,
-		bchPrivKey:            bchPrivKey,
-		bchPkh:                bchPkh,
-		bchAddr:               bchAddr,
-		sbchCli:               sbchCli,
-		sbchCliRO:             sbchCliRO,
-		sbchPrivKey:           sbchPrivKey,
-		sbchAddr:              sbchAddr,
-		bchTimeLock:           botInfo.BchLockTime,
-		sbchTimeLock:          botInfo.SbchLockTime,
-		penaltyRatio:          botInfo.PenaltyBPS,
-		bchPrice:              weiToSats(botInfo.BchPrice),
-		sbchPrice:             weiToSats(botInfo.SbchPrice),
-		minSwapVal:            weiToSats(botInfo.MinSwapAmt),
-		maxSwapVal:            weiToSats(botInfo.MaxSwapAmt),
-		bchLockMinerFeeRate:   bchLockMinerFeeRate,
-		bchUnlockMinerFeeRate: bchUnlockMinerFeeRate,
-		bchRefundMinerFeeRate: bchRefundMinerFeeRate,
-		bchConfirmations:      bchConfirmations,
-		dbQueryLimit:          dbQueryLimit,
-		isSlaveMode:           slaveMode,
-		lazyMaster:            debugMode && lazyMaster,
-		errLogQueue:           newErrLogQueue(5000),
+		db:                      db,
+		bchCli:                  bchCliI,
+		bchPrivKey:              bchPrivKey,
+		bchPkh:                  bchPkh,
+		bchAddr:                 bchAddr,
+		hdPkhs:                  hdPkhs,
+		bchNet:                  bchNet,
+		sbchCli:                 sbchCliI,
+		sbchCliRO:               sbchCliRO,
+		chaos:                   chaos,
+		sbchPrivKey:             sbchPrivKey,
+		sbchAddr:                sbchAddr,
+		bchTimeLock:             botInfo.BchLockTime,
+		sbchTimeLock:            botInfo.SbchLockTime,
+		penaltyRatio:            botInfo.PenaltyBPS,
+		bchPrice:                weiToSats(botInfo.BchPrice),
+		sbchPrice:               weiToSats(botInfo.SbchPrice),
+		minSwapVal:              weiToSats(botInfo.MinSwapAmt),
+		maxSwapVal:              weiToSats(botInfo.MaxSwapAmt),
+		bchLockMinerFeeRate:     cfg.BchLockFeeRate,
+		bchUnlockMinerFeeRate:   cfg.BchUnlockFeeRate,
+		bchRefundMinerFeeRate:   cfg.BchRefundFeeRate,
+		bchDepositFloor:         cfg.BchDepositFloor,
+		strictDepositOutputs:    cfg.StrictDepositOutputs,
+		bchCompactFilters:       cfg.BchCompactFilters,
+		bootstrap:               cfg.Bootstrap,
+		bootstrapSbchHeight:     cfg.BootstrapSbchHeight,
+		explorer:                cfg.Explorer,
+		heartbeatUrl:            cfg.HeartbeatUrl,
+		heartbeatInterval:       heartbeatInterval,
+		heartbeatMaxLag:         cfg.HeartbeatMaxLag,
+		sbasDiagnostics:         cfg.SbasDiagnostics,
+		bchSigType:              bchSigType,
+		antiFeeSniping:          cfg.AntiFeeSniping,
+		bchConfirmations:        cfg.BchConfirmations,
+		sbchConfirmations:       cfg.SbchConfirmations,
+		sbchLockConfs:           cfg.SbchLockConfs,
+		sbchUnlockConfs:         cfg.SbchUnlockConfs,
+		dbQueryLimit:            cfg.DbQueryLimit,
+		isSlaveMode:             cfg.SlaveMode || cfg.ObserverMode,
+		isObserverMode:          cfg.ObserverMode,
+		lazyMaster:              cfg.DebugMode && cfg.LazyMaster,
+		quoteValidity:           cfg.QuoteValidity,
+		quoteCommitment:         cfg.QuoteCommitment,
+		idempotencyGuard:        cfg.IdempotencyGuard,
+		stuckTxBlocks:           cfg.StuckTxBlocks,
+		stuckTxStrategy:         cfg.StuckTxStrategy,
+		batchReceipts:           cfg.BchBatchReceipts,
+		batchMaxInputs:          cfg.BchBatchMaxInputs,
+		reconcileInterval:       cfg.ReconcileInterval,
+		retentionDays:           cfg.RetentionDays,
+		refundAlertBlocks:       cfg.RefundAlertBlocks,
+		startupRescanBch:        cfg.StartupRescanBch,
+		startupRescanSbch:       cfg.StartupRescanSbch,
+		coldWallets:             coldWallets,
+		feeWallets:              feeWallets,
+		referral:                referral,
+		affiliates:              affiliates,
+		negotiation:             cfg.Negotiation,
+		affiliatePayoutInterval: cfg.AffiliatePayoutInterval,
+		bchTreasury:             bchTreasury,
+		bchZmq:                  bchZmq,
+		fulcrumCli:              fulcrumCli,
+		watchedCovenants:        map[string]bool{},
+		swapHooks:               swapHooks,
+		leader:                  leader,
+		statsCache:              newStatsCache(),
+		gauges:                  newGauges(),
+		latencies:               newSwapLatencyHistograms(),
+		gaugeInterval:           cfg.GaugeInterval,
+		sbchGasPrice:            cfg.getSbchGasPrice().Uint64(),
+		profitabilityGate:       cfg.ProfitabilityGate,
+		partialFill:             cfg.PartialFill,
+		reserveInventory:        cfg.ReserveInventory,
+		maxRiskScore:            cfg.MaxRiskScore,
+		maxCounterpartyExposure: cfg.MaxCounterpartyExposure,
+		swapWorkers:             newSwapWorkers(int(cfg.SwapWorkers)),
+		jobOwner:                jobOwner,
+		tokens:                  tokens,
+		evmPeers:                evmPeers,
+		accessList:              accessList,
+		adminToken:              cfg.AdminToken,
+		apiAuth:                 apiAuth,
+		httpPolicy:              httpPolicy,
+		webhooks:                webhooks,
+		emails:                  emails,
+		alerts:                  alerts,
+		deadlines:               newDeadlineWatchdog(db),
+		recorder:                recorder,
+		blockCache:              blockCache,
+		cfg:                     cfg,
+		reloadCh:                make(chan *Config, 1),
+		errLogQueue:             errLogQueue,
 	}, nil
 }
 
-func loadBchKey(privKeyWIF, masterAddr string, debugMode, slaveMode bool,
+func loadBchKey(privKeyWIF, masterAddr string, net *htlcbch.ChainParams, slaveMode bool,
 ) (privKey *bchec.PrivateKey, pubKey, pkh []byte, addr *bchutil.AddressPubKeyHash, err error) {
 
-	params := getBchParams(debugMode)
 	if !slaveMode {
 		// master mode
 
@@ -326,11 +747,15 @@ func loadBchKey(privKeyWIF, masterAddr string, debugMode, slaveMode bool,
 			err = fmt.Errorf("failed to decode WIF: %w", err)
 			return
 		}
+		if !wif.IsForNet(net.Net) {
+			err = fmt.Errorf("WIF is not for %s", net.Name)
+			return
+		}
 
 		privKey = wif.PrivKey
 		pubKey = privKey.PubKey().SerializeCompressed()
 		pkh = bchutil.Hash160(pubKey)
-		addr, err = bchutil.NewAddressPubKeyHash(pkh, params)
+		addr, err = net.NewP2PKHAddress(pkh)
 		if err != nil {
 			err = fmt.Errorf("failed to derive BCH address: %w", err)
 		}
@@ -344,16 +769,9 @@ func loadBchKey(privKeyWIF, masterAddr string, debugMode, slaveMode bool,
 		return
 	}
 
-	_addr, _err := bchutil.DecodeAddress(masterAddr, params)
-	if _err != nil {
-		err = fmt.Errorf("failed to decode master address: %w", _err)
-		return
-	}
-
-	ok := false
-	addr, ok = _addr.(*bchutil.AddressPubKeyHash)
-	if !ok {
-		err = fmt.Errorf("failed to cast master address")
+	addr, err = net.DecodeP2PKHAddress(masterAddr)
+	if err != nil {
+		err = fmt.Errorf("failed to decode master address: %w", err)
 		return
 	}
 
@@ -380,7 +798,11 @@ func loadSbchKey(privKeyHex, masterAddr string, slaveMode bool,
 	}
 
 	// slave mode
+	addr, err = loadSbchMasterAddr(masterAddr)
+	return
+}
 
+func loadSbchMasterAddr(masterAddr string) (addr gethcmn.Address, err error) {
 	if masterAddr == "" {
 		err = fmt.Errorf("missing sbchMasterAddr")
 		return
@@ -390,34 +812,154 @@ func loadSbchKey(privKeyHex, masterAddr string, slaveMode bool,
 	return
 }
 
-func getBchParams(debugMode bool) *chaincfg.Params {
-	if debugMode {
-		return &chaincfg.TestNet3Params
+// use testnet3 in debug mode if network is not specified
+func getBchChainParams(netName string, debugMode bool) (*htlcbch.ChainParams, error) {
+	if netName == "" {
+		if debugMode {
+			return htlcbch.TestNet3, nil
+		}
+		return htlcbch.MainNet, nil
+	}
+	return htlcbch.GetChainParams(netName)
+}
+
+// the signature algorithm of P2PKH inputs, flagged with the sighash type
+func getBchSigType(sigTypeName, sigHashTypeName string) (htlcbch.SigType, error) {
+	sigType, err := htlcbch.ParseSigType(sigTypeName)
+	if err != nil {
+		return 0, err
+	}
+	sigHashType, err := htlcbch.ParseSigHashType(sigHashTypeName)
+	if err != nil {
+		return 0, err
+	}
+	return sigType.WithSigHashType(sigHashType)
+}
+
+func (bot *MarketMakerBot) getBchNet() *htlcbch.ChainParams {
+	if bot.bchNet == nil {
+		return htlcbch.MainNet
+	}
+	return bot.bchNet
+}
+
+// the swap engine scans blocks and builds HTLC txs through it, defaults to the BCH network
+func (bot *MarketMakerBot) getChainAdapter() htlcbch.ChainAdapter {
+	if bot.chainAdapter == nil {
+		return bot.getBchNet()
+	}
+	return bot.chainAdapter
+}
+
+// anti-fee-sniping: BCH txs made by bot can only be mined after the current tip, so miners gain
+// nothing by reorging it to take their fees. 0 (no lock time) if disabled or the height is unknown.
+func (bot *MarketMakerBot) getBchTxLockTime() uint32 {
+	if !bot.antiFeeSniping {
+		return 0
+	}
+	h, err := bot.bchCli.GetBlockCount()
+	if err != nil {
+		bot.logError("RPC error, failed to get BCH height for lock time: ", err)
+		return 0
+	}
+	return uint32(h)
+}
+
+// the HTLC of a bch2sbch swap, locked by user
+func newBch2SbchHtlcSpec(record *Bch2SbchRecord) *htlcbch.HtlcSpec {
+	return &htlcbch.HtlcSpec{
+		SenderPkh:    gethcmn.FromHex(record.SenderPkh),
+		RecipientPkh: gethcmn.FromHex(record.RecipientPkh),
+		HashLock:     gethcmn.FromHex(record.HashLock),
+		Expiration:   uint16(record.TimeLock),
+		ExpiresAt:    record.BchExpiresAt,
+		PenaltyBPS:   record.PenaltyBPS,
+		ReferralPkh:  gethcmn.FromHex(record.ReferralPkh),
+		ReferralBPS:  record.ReferralBPS,
+		Template:     getCovenantVersion(record.CovenantHash),
+	}
+}
+
+// the HTLC of a sbch2bch swap, locked by bot with half of the sBCH time lock
+func (bot *MarketMakerBot) newSbch2BchHtlcSpec(record *Sbch2BchRecord) *htlcbch.HtlcSpec {
+	return &htlcbch.HtlcSpec{
+		SenderPkh:    bot.bchPkh,
+		RecipientPkh: gethcmn.FromHex(record.BchRecipientPkh),
+		HashLock:     gethcmn.FromHex(record.HashLock),
+		Expiration:   sbchTimeLockToBlocks(record.TimeLock) / 2,
+		ReferralPkh:  gethcmn.FromHex(record.ReferralPkh),
+		ReferralBPS:  record.ReferralBPS,
+		Template:     getCovenantVersion(record.CovenantHash),
+	}
+}
+
+// nil if disabled, fees are estimated at bot's own unlock|refund fee rates
+func (bot *MarketMakerBot) getDepositFloor() *htlcbch.DepositFloor {
+	if bot.bchDepositFloor == 0 {
+		return nil
+	}
+	return &htlcbch.DepositFloor{
+		MinValue:      bot.bchDepositFloor,
+		UnlockFeeRate: bot.bchUnlockMinerFeeRate,
+		RefundFeeRate: bot.bchRefundMinerFeeRate,
+	}
+}
+
+// deposits are searched in any output order, unless the strict (output#0 + output#1) layout is required
+func (bot *MarketMakerBot) getDepositFilter() *htlcbch.DepositFilter {
+	return &htlcbch.DepositFilter{
+		Floor:          bot.getDepositFloor(),
+		AnyOutputOrder: !bot.strictDepositOutputs,
+		Diagnose:       bot.sbasDiagnosticsEnabled(),
 	}
-	return &chaincfg.MainNetParams
 }
 
 func (bot *MarketMakerBot) logError(msg string, err error) {
 	log.Error(msg, err)
 	bot.errLogQueue.recordErrLog("error", fmt.Sprintf("%s: %s", msg, err))
+	bot.alerts.alert(AlertSeverityError, fmt.Sprintf("%s%s", msg, err))
 }
 func (bot *MarketMakerBot) logWarnf(format string, args ...any) {
 	log.Warnf(format, args...)
 	bot.errLogQueue.recordErrLog("warning", fmt.Sprintf(format, args...))
+	bot.alerts.alert(AlertSeverityWarning, fmt.Sprintf(format, args...))
+}
+
+// value is at risk or the bot stops working, the operator must act now
+func (bot *MarketMakerBot) logCriticalf(format string, args ...any) {
+	log.Errorf(format, args...)
+	bot.errLogQueue.recordErrLog("critical", fmt.Sprintf(format, args...))
+	bot.alerts.alert(AlertSeverityCritical, fmt.Sprintf(format, args...))
 }
 
 func (bot *MarketMakerBot) PrepareDB() {
 	_, err := bot.db.getLastHeights()
-	if err == nil || !strings.HasPrefix(err.Error(), "no such table") {
-		return
+	if err != nil && strings.HasPrefix(err.Error(), "no such table") {
+		log.Info("init DB, sync schemas ...")
+		if err = bot.db.syncSchemas(); err != nil {
+			log.Fatal(err)
+		}
+		log.Info("init last BCH & sBCH heights ...")
+		if err = bot.db.initLastHeights(0, 0); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		// migrate new tables & columns
+		if err = bot.db.syncSchemas(); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	log.Info("init DB, sync schemas ...")
-	if err = bot.db.syncSchemas(); err != nil {
+	if err = bot.loadHdReceivePkhs(); err != nil {
+		log.Fatal(err)
+	}
+	if err = bot.armInFlightSwapTimers(); err != nil {
 		log.Fatal(err)
 	}
-	log.Info("init last BCH & sBCH heights ...")
-	if err = bot.db.initLastHeights(0, 0); err != nil {
+	if err = bot.syncFreeze(); err != nil {
+		log.Fatal(err)
+	}
+	if err = bot.checkCovenantUpgrade(); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -427,18 +969,74 @@ func (bot *MarketMakerBot) GetUTXOs() ([]btcjson.ListUnspentResult, error) {
 }
 
 func (bot *MarketMakerBot) Loop() {
+	if bot.bchZmq != nil {
+		go bot.bchZmq.Run()
+	}
+	if bot.webhooks != nil {
+		go bot.webhooks.run()
+	}
+	if bot.emails != nil {
+		go bot.emails.run()
+	}
+	if bot.alerts != nil {
+		bot.alerts.run()
+	}
+	if bot.deadlines != nil {
+		go bot.deadlines.run()
+	}
 	for {
 		log.Info("---------- ", time.Now(), "' ----------")
+		bot.applyPendingConfig()
+		if err := bot.syncFreeze(); err != nil {
+			bot.logError("DB error, failed to load freeze: ", err)
+		}
+		if !bot.keepLeadership() {
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		bot.handleEvents() // replay events left unhandled by last run
+		bot.handleDeadlines()
+		bot.rescanOnStartup()
 		bot.updatePrices()
 		bot.refundLockedSbch()
 		gotNewBlocks := bot.scanBchBlocks()
 		bot.refundLockedBCH(gotNewBlocks)
+		if gotNewBlocks {
+			bot.checkStuckBchTxs()
+			bot.checkSwapTimeouts()
+		}
+		bot.watchBchCovenants()
 		bot.handleBchUserDeposits()
 		bot.unlockBchUserDeposits()
+		bot.refundRemainders()
 		bot.scanSbchEvents()
 		bot.handleSbchUserDeposits()
 		bot.unlockSbchUserDeposits()
+		bot.scanEvmPeers()
+		bot.handleEvmSwaps()
+		bot.reconcile()
+		bot.archiveSwaps()
+		bot.sweepToCold()
+		bot.sweepFees()
+		bot.payAffiliates()
+		bot.commitQuotes()
+		bot.dispatchWebhooks()
+		bot.sampleGauges()
+		bot.sendHeartbeat()
+		bot.waitForNextRound()
+	}
+}
+
+// sleep until the next poll, or wake up early if a new BCH block is notified
+func (bot *MarketMakerBot) waitForNextRound() {
+	if bot.bchZmq == nil {
 		time.Sleep(2 * time.Second)
+		return
+	}
+	select {
+	case <-bot.bchZmq.NewBlocks():
+		log.Info("new BCH block notified by ZMQ")
+	case <-time.After(2 * time.Second):
 	}
 }
 
@@ -449,6 +1047,7 @@ func (bot *MarketMakerBot) updatePrices() {
 	}
 
 	bot.lastPricesUpdatedAt = now
+	bot.updateTokenPrices()
 	log.Info("update BCH/sBCH prices ...")
 	botInfo, err := bot.sbchCli.getMarketMakerInfo(bot.sbchAddr)
 	if err != nil {
@@ -485,14 +1084,24 @@ func (bot *MarketMakerBot) scanBchBlocks() (gotNewBlocks bool) {
 	}
 
 	if lastBlockNum == 0 {
-		lastBlockNum = uint64(safeNewBlockNum) - 1
+		if bot.bootstrap {
+			lastBlockNum = bot.getBootstrapLastBchHeight()
+		} else {
+			lastBlockNum = uint64(safeNewBlockNum) - 1
+		}
 		log.Info("init last BCH height: ", lastBlockNum)
 	}
 
+	caughtUp := true
 	for h := int64(lastBlockNum) + 1; h <= safeNewBlockNum; h++ {
 		if !bot.handleBchBlock(h) {
+			caughtUp = false
 			break
 		}
+		bot.handleDeadlines()
+	}
+	if caughtUp {
+		bot.bchScannedAt = time.Now().Unix()
 	}
 
 	gotNewBlocks = safeNewBlockNum > int64(lastBlockNum)
@@ -502,6 +1111,15 @@ func (bot *MarketMakerBot) scanBchBlocks() (gotNewBlocks bool) {
 // handle BCH lock|unlock|refund txs
 func (bot *MarketMakerBot) handleBchBlock(h int64) bool {
 	//log.Info("get BCH block#", h, " ...")
+	if bot.mayBchBlockBeSkipped(h) {
+		log.Info("skipped BCH block#", h, " by compact filter")
+		if err := bot.db.setLastBchHeight(uint64(h)); err != nil {
+			log.Fatal("DB error, failed to update last BCH height: ", err)
+		}
+		bot.handleEvents()
+		return true
+	}
+
 	block, err := bot.bchCli.GetBlock(h)
 	if err != nil {
 		bot.logError(fmt.Sprintf("RPC error, failed to get BCH block#%d: ", h), err)
@@ -509,44 +1127,89 @@ func (bot *MarketMakerBot) handleBchBlock(h int64) bool {
 	}
 	log.Info("got BCH block#", h)
 
-	bot.handleBchDepositTxs(uint64(h), block)
-	bot.handleBchReceiptTxs(block)
+	scan := bot.getChainAdapter().ScanBlock(block, bot.getDepositFilter())
+	if bot.blockCache != nil {
+		bot.cacheBchBlock(h, block, scan)
+	}
+	if bot.recorder != nil {
+		if err = bot.recorder.recordBchBlock(h, block, scan); err != nil {
+			bot.logError(fmt.Sprintf("failed to record BCH block#%d: ", h), err)
+		}
+	}
+	if bot.isSwapIndexEnabled() {
+		if err = bot.indexBchBlock(uint64(h), block, scan); err != nil {
+			bot.logError(fmt.Sprintf("DB error, failed to index BCH block#%d: ", h), err)
+			return false
+		}
+	}
+	if !bot.publishBchDepositTxs(uint64(h), scan.Deposits) {
+		return false
+	}
+	if bot.fulcrumCli == nil && !bot.publishBchReceiptTxs(uint64(h), scan.Receipts) {
+		return false
+	}
+	if !bot.saveSuspectBchTxs(uint64(h), scan.Suspects) {
+		return false
+	}
+	if !bot.logSbasTxs(uint64(h), scan.SbasTxs) {
+		return false
+	}
 
 	err = bot.db.setLastBchHeight(uint64(h))
 	if err != nil {
 		log.Fatal("DB error, failed to update last BCH height: ", err)
 	}
 
+	bot.handleEvents()
 	return true
 }
 
-// find and handle BCH lock txs
-func (bot *MarketMakerBot) handleBchDepositTxs(h uint64, block *btcjson.GetBlockVerboseTxResult) {
-	deposits := htlcbch.GetHtlcLocksInfo(block)
+// find BCH lock txs and publish them to event bus
+func (bot *MarketMakerBot) publishBchDepositTxs(h uint64, deposits []*htlcbch.HtlcLockInfo) bool {
 	log.Info("HTLC deposits: ", len(deposits))
 	for _, deposit := range deposits {
 		log.Info("HTLC deposit: ", toJSON(deposit))
-		bot.handleBchDepositTxB2S(h, deposit)
-		bot.handleBchDepositTxS2B(h, deposit)
+		if !bot.publishEvent(EventBchDeposit, deposit.TxHash, h, deposit) {
+			return false
+		}
 	}
+	return true
 }
 
 // create bch2sbch records (status=new)
 func (bot *MarketMakerBot) handleBchDepositTxB2S(h uint64, deposit *htlcbch.HtlcLockInfo) {
 	log.Info("handleBchDepositTxB2S")
-	if !bytes.Equal(deposit.RecipientPkh, bot.bchPkh) {
+	if !bot.isMyReceivePkh(deposit.RecipientPkh) {
 		log.Info("not send to me, recipientPkh: ",
 			toHex(deposit.RecipientPkh))
 		return
 	}
-	if deposit.Expiration != bot.bchTimeLock {
-		log.Infof("invalid expiration: %d != %d",
-			deposit.Expiration, bot.bchTimeLock)
+	if deposit.HashType != htlcbch.HashTypeSha256 {
+		// sBCH HTLC contract only supports SHA256 hash locks
+		log.Info("unsupported hash type: ", deposit.HashType)
+		return
+	}
+	timeLock := uint32(deposit.Expiration)
+	switch deposit.ExpirationType {
+	case htlcbch.ExpirationTypeBlocks:
+		if !bot.areTermsAccepted(DirectionBch2Sbch, toHex(deposit.HashLock), deposit.Value,
+			timeLock, deposit.PenaltyBPS, time.Now()) {
+			return
+		}
+	case htlcbch.ExpirationTypeTimestamp:
+		var ok bool
+		timeLock, ok = bot.areExpiresAtTermsAccepted(toHex(deposit.HashLock), deposit.Value,
+			deposit.ExpiresAt, deposit.PenaltyBPS, time.Now())
+		if !ok {
+			return
+		}
+	default:
+		// time locks of swaps are in BCH blocks or absolute, see areTermsAccepted()
+		log.Info("unsupported expiration type: ", deposit.ExpirationType)
 		return
 	}
-	if deposit.PenaltyBPS != bot.penaltyRatio {
-		log.Infof("invalid penaltyRatio: %d != %d",
-			deposit.PenaltyBPS, bot.penaltyRatio)
+	if !bot.referral.matchesDeposit(deposit) {
+		log.Infof("invalid referral: %s, %d", toHex(deposit.ReferralPkh), deposit.ReferralBPS)
 		return
 	}
 	if deposit.Value < bot.minSwapVal ||
@@ -556,28 +1219,55 @@ func (bot *MarketMakerBot) handleBchDepositTxB2S(h uint64, deposit *htlcbch.Htlc
 			deposit.Value, bot.minSwapVal, bot.maxSwapVal)
 		return
 	}
-	if deposit.ExpectedPrice > bot.bchPrice {
+	if !bot.isSenderAllowed(deposit.SenderPkh, deposit.SenderEvmAddr) {
+		log.Infof("sender not allowed, senderPkh: %s, senderEvmAddr: %s",
+			toHex(deposit.SenderPkh), toHex(deposit.SenderEvmAddr))
+		return
+	}
+	// the BCH covenant does not commit to the token, so it is asked for by quote
+	token, err := bot.getQuotedToken(toHex(deposit.HashLock))
+	if err != nil {
+		log.Info("invalid quote: ", err)
+		return
+	}
+	if bchPrice := bot.getBchPriceFor(token, toHex(deposit.HashLock), deposit.Value, time.Now()); deposit.ExpectedPrice > bchPrice {
 		log.Infof("expected BCH price is too high: %d > %d",
-			deposit.ExpectedPrice, bot.bchPrice)
+			deposit.ExpectedPrice, bchPrice)
 		return
 	}
 
-	err := bot.db.addBch2SbchRecord(&Bch2SbchRecord{
+	record := &Bch2SbchRecord{
 		BchLockHeight:  h,
 		BchLockTxHash:  deposit.TxHash,
+		BchLockVout:    deposit.Vout,
 		Value:          deposit.Value,
 		BchPrice:       deposit.ExpectedPrice,
 		RecipientPkh:   toHex(deposit.RecipientPkh),
 		SenderPkh:      toHex(deposit.SenderPkh),
 		HashLock:       toHex(deposit.HashLock),
-		TimeLock:       uint32(deposit.Expiration),
+		TimeLock:       timeLock,
+		BchExpiresAt:   deposit.ExpiresAt,
 		PenaltyBPS:     deposit.PenaltyBPS,
 		SenderEvmAddr:  toHex(deposit.SenderEvmAddr),
 		HtlcScriptHash: toHex(deposit.ScriptHash),
-	})
+		Token:          token.getSymbol(),
+		Memo:           deposit.Memo,
+		Affiliate:      bot.affiliates.getCodeOfMemo(deposit.Memo),
+		ReferralPkh:    toHex(deposit.ReferralPkh),
+		ReferralBPS:    deposit.ReferralBPS,
+		CovenantHash:   getCovenantHash(deposit.Template),
+	}
+	if bot.callSwapHooks(HookOnDepositDetected, newBch2SbchAction(record)) != nil {
+		return
+	}
+
+	err = bot.db.addBch2SbchRecord(record)
 	if err != nil {
 		bot.logError("DB error, failed to save BCH2SBCH record: ", err)
+		return
 	}
+	bot.markHdReceivePkhUsed(deposit.RecipientPkh, toHex(deposit.HashLock))
+	bot.saveBchSwapTx(toHex(deposit.HashLock), SwapLegBchLock, deposit.TxHash, deposit.RawTx)
 }
 
 // for sbch2bch record, change status from New to BchLocked
@@ -598,25 +1288,44 @@ func (bot *MarketMakerBot) handleBchDepositTxS2B(h uint64, deposit *htlcbch.Htlc
 	record, err := bot.db.getSbch2BchRecordByHashLock(hashLock)
 	if err != nil {
 		log.Info("DB error, Sbch2BchRecord not found, hashLock: ", hashLock)
+		return
+	}
+
+	if record.Status != Sbch2BchStatusNew {
+		log.Info("wrong status: ", record.Status)
+		return
 	}
 
-	// TODO: add more checks
+	// the BCH lock tx must mirror the sBCH lock
+	if toHex(deposit.ScriptHash) != record.HtlcScriptHash {
+		bot.logWarnf("HTLC script hash not match! BCH lock tx: %s, script hash: %s, DB script hash: %s",
+			deposit.TxHash, toHex(deposit.ScriptHash), record.HtlcScriptHash)
+		return
+	}
+	bchVal := mulByPrice(record.Value, record.SbchPrice)
+	if err = bot.getChainAdapter().VerifyLock(deposit, bot.newSbch2BchHtlcSpec(record), bchVal); err != nil {
+		bot.logWarnf("BCH lock tx not verified! BCH lock tx: %s, error: %s", deposit.TxHash, err)
+		return
+	}
 
 	record.UpdateStatusToBchLocked(deposit.TxHash)
 	err = bot.db.updateSbch2BchRecord(record)
 	if err != nil {
 		bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
 	}
+	bot.saveBchSwapTx(hashLock, SwapLegBchLock, deposit.TxHash, deposit.RawTx)
 }
 
-// find and handle BCH unlock txs
-func (bot *MarketMakerBot) handleBchReceiptTxs(block *btcjson.GetBlockVerboseTxResult) {
-	receipts := htlcbch.GetHtlcUnlocksInfo(block)
+// find BCH unlock txs and publish them to event bus
+func (bot *MarketMakerBot) publishBchReceiptTxs(h uint64, receipts []*htlcbch.HtlcUnlockInfo) bool {
 	log.Info("HTLC receipts: ", len(receipts))
 	for _, receipt := range receipts {
 		log.Info("HTLC receipt:", toJSON(receipt))
-		bot.handleBchReceiptTx(receipt)
+		if !bot.publishEvent(EventBchReceipt, receipt.TxHash, h, receipt) {
+			return false
+		}
 	}
+	return true
 }
 
 // for sbch2bch records, change status from BchLocked to SecretRevealed
@@ -647,6 +1356,7 @@ func (bot *MarketMakerBot) handleBchReceiptTx(receipt *htlcbch.HtlcUnlockInfo) {
 	if err != nil {
 		bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
 	}
+	bot.saveBchSwapTx(record.HashLock, SwapLegBchUnlock, receipt.TxHash, receipt.RawTx)
 }
 
 func (bot *MarketMakerBot) scanSbchEvents() {
@@ -664,9 +1374,19 @@ func (bot *MarketMakerBot) scanSbchEvents() {
 		return
 	}
 	log.Info("latest sBCH height: ", newBlockNum)
+	bot.sbchLatestHeight = newBlockNum
+	scanConfirmations := bot.getSbchScanConfirmations()
+	if newBlockNum < scanConfirmations {
+		return
+	}
+	newBlockNum -= scanConfirmations
 
 	if lastBlockNum == 0 {
-		lastBlockNum = newBlockNum - 1
+		if bot.bootstrap {
+			lastBlockNum = bot.getBootstrapLastSbchHeight()
+		} else {
+			lastBlockNum = newBlockNum - 1
+		}
 		log.Info("init last sBCH height: ", lastBlockNum)
 	}
 
@@ -677,9 +1397,11 @@ func (bot *MarketMakerBot) scanSbchEvents() {
 			toH = newBlockNum
 		}
 		if !bot.handleSbchEvents(fromH, toH) {
-			break
+			return
 		}
+		bot.handleDeadlines()
 	}
+	bot.sbchScannedAt = time.Now().Unix()
 }
 
 func (bot *MarketMakerBot) handleSbchEvents(fromH, toH uint64) bool {
@@ -690,15 +1412,33 @@ func (bot *MarketMakerBot) handleSbchEvents(fromH, toH uint64) bool {
 	}
 	log.Infof("sBCH logs (block#%d ~ block#%d): %d",
 		fromH, toH, len(logs))
+	if bot.recorder != nil {
+		err = bot.recorder.recordSbchLogs(logs, func(ethLog gethtypes.Log) (uint64, error) {
+			return bot.sbchCli.getTxTime(ethLog.TxHash)
+		})
+		if err != nil {
+			bot.logError("failed to record sBCH logs: ", err)
+		}
+	}
+	if bot.isSwapIndexEnabled() {
+		if err = bot.indexSbchLogs(logs); err != nil {
+			bot.logError("DB error, failed to index sBCH logs: ", err)
+			return false
+		}
+	}
 
 	for _, ethLog := range logs {
 		log.Info("sBCH log: ", toJSON(ethLog))
+		key := fmt.Sprintf("%s:%d", ethLog.TxHash.Hex(), ethLog.Index)
+		published := true
 		switch ethLog.Topics[0] {
 		case htlcsbch.LockEventId:
-			bot.handleSbchLockEventS2B(ethLog)
-			bot.handleSbchLockEventB2S(ethLog)
+			published = bot.publishEvent(EventSbchLock, key, ethLog.BlockNumber, ethLog)
 		case htlcsbch.UnlockEventId:
-			bot.handleSbchUnlockEvent(ethLog)
+			published = bot.publishEvent(EventSbchUnlock, key, ethLog.BlockNumber, ethLog)
+		}
+		if !published {
+			return false
 		}
 	}
 
@@ -707,6 +1447,7 @@ func (bot *MarketMakerBot) handleSbchEvents(fromH, toH uint64) bool {
 		log.Fatal("DB error, failed to update last sBCH height: ", err)
 	}
 
+	bot.handleEvents()
 	return true
 }
 
@@ -731,52 +1472,43 @@ func (bot *MarketMakerBot) handleSbchLockEventS2B(ethLog gethtypes.Log) {
 		return
 	}
 
-	penaltyBPS := lockLog.PenaltyBPS
-	if penaltyBPS != bot.penaltyRatio {
-		log.Infof("invalid penaltyRatio: %d != %d",
-			penaltyBPS, bot.penaltyRatio)
+	if !bot.isSenderAllowed(lockLog.BchRecipientPkh[:], lockLog.LockerAddr[:]) {
+		log.Infof("sender not allowed, lockerAddr: %s, bchRecipientPkh: %s",
+			lockLog.LockerAddr.String(), toHex(lockLog.BchRecipientPkh[:]))
 		return
 	}
 
+	penaltyBPS := lockLog.PenaltyBPS
 	sbchTimeLock := uint32(lockLog.UnlockTime - lockLog.CreatedTime)
-	if sbchTimeLock != bot.sbchTimeLock {
-		log.Infof("invalid TimeLock: %d != %d",
-			sbchTimeLock, bot.sbchTimeLock)
+	token, valSats := bot.getLogValue(ethLog.Address, lockLog.Value)
+	if !bot.areTermsAccepted(DirectionSbch2Bch, toHex(lockLog.HashLock[:]), valSats,
+		sbchTimeLock, penaltyBPS, time.Now()) {
 		return
 	}
 
-	valSats := weiToSats(lockLog.Value)
-	if valSats < bot.minSwapVal ||
-		(bot.maxSwapVal > 0 && valSats > bot.maxSwapVal) {
+	swapVal := valSats
+	if token != nil {
+		// swap value is always checked in sats
+		swapVal = mulByPrice(valSats, token.TokenPrice)
+	}
+	if swapVal < bot.minSwapVal ||
+		(bot.maxSwapVal > 0 && swapVal > bot.maxSwapVal) {
 
 		log.Infof("value out of range: %d ∉ [%d, %d]",
-			valSats, bot.minSwapVal, bot.maxSwapVal)
+			swapVal, bot.minSwapVal, bot.maxSwapVal)
 		return
 	}
 
 	expectedPrice := weiToSats(lockLog.ExpectedPrice)
-	if expectedPrice > bot.sbchPrice {
+	sbchPrice := bot.getSbchPriceFor(token, toHex(lockLog.HashLock[:]), valSats, time.Now())
+	if expectedPrice > sbchPrice {
 		log.Infof("expected sBCH price is too high: %d > %d",
-			expectedPrice, bot.sbchPrice)
+			expectedPrice, sbchPrice)
 		return
 	}
 
 	log.Info("got a sBCH Lock log: ", toJSON(lockLog))
-	bchTimeLock := sbchTimeLockToBlocks(sbchTimeLock) / 2
-	covenant, err := htlcbch.NewMainnetCovenant(bot.bchPkh,
-		lockLog.BchRecipientPkh[:], lockLog.HashLock[:], bchTimeLock, 0)
-	if err != nil {
-		bot.logError("failed to create HTLC covenant: ", err)
-		return
-	}
-
-	scriptHash, err := covenant.GetRedeemScriptHash()
-	if err != nil {
-		bot.logError("failed to get script hash: ", err)
-		return
-	}
-
-	err = bot.db.addSbch2BchRecord(&Sbch2BchRecord{
+	record := &Sbch2BchRecord{
 		SbchLockTime:    lockLog.CreatedTime,
 		SbchLockTxHash:  toHex(ethLog.TxHash[:]),
 		Value:           valSats,
@@ -786,10 +1518,36 @@ func (bot *MarketMakerBot) handleSbchLockEventS2B(ethLog gethtypes.Log) {
 		HashLock:        toHex(lockLog.HashLock[:]),
 		TimeLock:        sbchTimeLock,
 		PenaltyBPS:      penaltyBPS,
-		HtlcScriptHash:  toHex(scriptHash),
-	})
+		Token:           token.getSymbol(),
+		ReferralPkh:     bot.referral.getPkh(),
+		ReferralBPS:     bot.referral.getBPS(),
+		CovenantHash:    htlcbch.TemplateHash(),
+	}
+	scriptHash, err := bot.getChainAdapter().ScriptHash(bot.newSbch2BchHtlcSpec(record))
+	if err != nil {
+		bot.logError("failed to get script hash: ", err)
+		return
+	}
+	record.HtlcScriptHash = toHex(scriptHash)
+	// the raw tx is also saved as the sBCH lock leg, the swap is taken without memo if it can not be got
+	rawTx, err := bot.sbchCli.getRawTx(ethLog.TxHash)
+	if err != nil {
+		bot.logError("RPC error, failed to get raw sBCH tx: ", err)
+	} else {
+		record.Memo = getSbchLockMemo(rawTx)
+		record.Affiliate = bot.affiliates.getCodeOfMemo(record.Memo)
+	}
+	if bot.callSwapHooks(HookOnDepositDetected, newSbch2BchAction(record)) != nil {
+		return
+	}
+
+	err = bot.db.addSbch2BchRecord(record)
 	if err != nil {
 		bot.logError("DB error, failed to save SBCH2BCH record: ", err)
+		return
+	}
+	if rawTx != nil {
+		bot.saveSwapTx(record.HashLock, SwapLegSbchLock, record.SbchLockTxHash, toHex(rawTx))
 	}
 }
 
@@ -823,6 +1581,16 @@ func (bot *MarketMakerBot) handleSbchLockEventB2S(ethLog gethtypes.Log) {
 	if record.Status != Bch2SbchStatusNew {
 		return
 	}
+	token, lockedVal := bot.getLogValue(ethLog.Address, lockLog.Value)
+	if token.getSymbol() != record.Token {
+		bot.logWarnf("token not match! hashLock: %s, locked token: %s, DB token: %s",
+			record.HashLock, token.getSymbol(), record.Token)
+		return
+	}
+	if lockedVal < mulByPrice(record.Value, record.BchPrice) {
+		record.FilledValue = divByPrice(lockedVal, record.BchPrice)
+		log.Info("partially filled by master, filled value: ", record.FilledValue)
+	}
 
 	txTime, err := bot.sbchCli.getTxTime(ethLog.TxHash)
 	if err != nil {
@@ -835,6 +1603,7 @@ func (bot *MarketMakerBot) handleSbchLockEventB2S(ethLog gethtypes.Log) {
 	if err != nil {
 		bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
 	}
+	bot.saveSbchSwapTx(record.HashLock, SwapLegSbchLock, ethLog.TxHash)
 }
 
 // bch2sbch records: SbchLocked => SecretRevealed
@@ -870,11 +1639,12 @@ func (bot *MarketMakerBot) handleSbchUnlockEvent(ethLog gethtypes.Log) {
 		bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
 		return
 	}
+	bot.saveSbchSwapTx(hashLock, SwapLegSbchUnlock, unlockLog.TxHash)
 }
 
 // bch2sbch records: New => SbchLocked|TooLateToLockSbch
 func (bot *MarketMakerBot) handleBchUserDeposits() {
-	if bot.isSlaveMode {
+	if bot.isSlaveMode || !bot.canSign() || bot.isPaused() {
 		return
 	}
 
@@ -886,79 +1656,220 @@ func (bot *MarketMakerBot) handleBchUserDeposits() {
 	}
 	log.Info("unhandled BCH user deposits: ", len(records))
 
-	for _, record := range records {
-		log.Info("handle BCH user deposit: ", toJSON(record))
+	jobs := make([]swapJob, len(records))
+	for i, record := range records {
+		record := record
+		jobs[i] = swapJob{hashLock: record.HashLock, action: func() { bot.handleBchUserDeposit(record) }}
+	}
+	bot.swapWorkers.run(jobs)
+}
 
-		if record.BchPrice > bot.bchPrice {
-			log.Infof("BCH price changed, expected price: %d, current price: %d",
-				record.BchPrice, bot.bchPrice)
-			record.Status = Bch2SbchStatusPriceChanged
-			err = bot.db.updateBch2SbchRecord(record)
-			if err != nil {
-				bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
-			}
-			continue
+// too late if a third of the BCH time lock is passed, or if less than two thirds of it
+// (in seconds) is left before the expiry of a deposit of absolute expiration
+func (bot *MarketMakerBot) isTooLateToLockSbch(record *Bch2SbchRecord) (bool, error) {
+	if record.BchExpiresAt != 0 {
+		secondsLeft := int64(record.BchExpiresAt) - time.Now().Unix()
+		if secondsLeft < int64(bchTimeLockToSeconds(record.TimeLock))*2/3 {
+			log.Info("too late to lock sBCH",
+				", seconds left: ", secondsLeft,
+				", timeLock: ", record.TimeLock)
+			return true, nil
 		}
+		return false, nil
+	}
+
+	//confirmations := currBlockNum - int64(record.BchLockHeight) + 1
+	confirmations, err := bot.bchCli.GetTxConfirmations(record.BchLockTxHash)
+	if err != nil {
+		return false, err
+	}
+	if confirmations > int64(record.TimeLock)/3 {
+		log.Info("too late to lock sBCH",
+			", confirmations: ", confirmations,
+			", timeLock: ", record.TimeLock)
+		return true, nil
+	}
+	return false, nil
+}
+
+// lock sBCH for the BCH deposit
+func (bot *MarketMakerBot) handleBchUserDeposit(record *Bch2SbchRecord) {
+	log.Info("handle BCH user deposit: ", toJSON(record))
 
-		//confirmations := currBlockNum - int64(record.BchLockHeight) + 1
-		confirmations, err := bot.bchCli.GetTxConfirmations(record.BchLockTxHash)
+	token, err := bot.getToken(record.Token)
+	if err != nil {
+		bot.logError("failed to lock token: ", err)
+		return
+	}
+	if (bot.idempotencyGuard || bot.getUnfinishedChainJob(record.HashLock, SwapLegSbchLock) != nil) &&
+		bot.isSbchLockedForBchDeposit(record) {
+		// locked by a previous attempt, which did not update the record
+		log.Info("resume sBCH lock, hashLock: ", record.HashLock)
+		bot.lockSbchForBchDeposit(record, token, mulByPrice(record.GetFilledValue(), record.BchPrice), false)
+		return
+	}
+	if token.isPriceStale(time.Now().Unix()) {
+		log.Info("token price is stale, wait oracle: ", token.Symbol)
+		return
+	}
+
+	if bchPrice := bot.getBchPriceFor(token, record.HashLock, record.Value, record.CreatedAt); record.BchPrice > bchPrice {
+		log.Infof("BCH price changed, expected price: %d, current price: %d",
+			record.BchPrice, bchPrice)
+		record.Status = Bch2SbchStatusPriceChanged
+		err = bot.db.updateBch2SbchRecord(record)
 		if err != nil {
-			bot.logError("RPC error, failed to get tx confirmations: ", err)
-			continue
+			bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
 		}
+		return
+	}
 
-		// do not send sBCH to user if it's too late!
-		if confirmations > int64(bot.bchTimeLock)/3 {
-			log.Info("too late to lock sBCH",
-				", confirmations: ", confirmations,
-				", timeLock: ", record.TimeLock)
-			record.Status = Bch2SbchStatusTooLateToLockSbch
-			err = bot.db.updateBch2SbchRecord(record)
-			if err != nil {
-				bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
-			}
+	if !bot.checkProfitability(record.HashLock, DirectionBch2Sbch,
+		bot.getSwapServiceFee(record.Token, DirectionBch2Sbch, record.Value, record.BchPrice),
+		bot.estimateBch2SbchCost()) {
 
-			continue
+		record.Status = Bch2SbchStatusUnprofitable
+		err = bot.db.updateBch2SbchRecord(record)
+		if err != nil {
+			bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
 		}
+		return
+	}
 
-		sbchTimeLock := bchTimeLockToSeconds(record.TimeLock) / 2
-		// val * bchPrice / 1e8
-		sbchVal := mulByPrice(record.Value, record.BchPrice)
-		log.Info("sbchTimeLock: ", sbchTimeLock,
-			" , bchPrice: ", bot.bchPrice, " , sbchVal: ", sbchVal)
-
-		txHash, err := bot.sbchCli.lockSbchToHtlc(
-			gethcmn.HexToAddress(record.SenderEvmAddr),
-			gethcmn.HexToHash(record.HashLock),
-			sbchTimeLock,
-			satsToWei(sbchVal),
-		)
+	// do not send sBCH to user if it's too late!
+	tooLate, err := bot.isTooLateToLockSbch(record)
+	if err != nil {
+		bot.logError("RPC error, failed to get tx confirmations: ", err)
+		return
+	}
+	if tooLate {
+		record.Status = Bch2SbchStatusTooLateToLockSbch
+		err = bot.db.updateBch2SbchRecord(record)
 		if err != nil {
-			bot.logError("RPC error, failed to lock sBCH to HTLC: ", err)
-			continue
+			bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
 		}
 
-		log.Info("lock sBCH successful",
-			", hashLock: ", record.HashLock,
-			", txHash: ", txHash.String())
+		return
+	}
 
-		txTime, err := bot.sbchCli.getTxTime(*txHash)
+	if bot.partialFill && token == nil {
+		filledVal, err := bot.getFillableValue(record)
 		if err != nil {
-			bot.logError("RPC error, failed to get sBCH tx time:", err)
-			txTime = uint64(time.Now().Unix())
+			bot.logError("failed to get fillable value: ", err)
+			return
+		}
+		if filledVal == 0 {
+			log.Info("not enough sBCH to fill the deposit, hashLock: ", record.HashLock)
+			return
+		}
+		if filledVal < record.Value {
+			log.Infof("partially fill the deposit, hashLock: %s, value: %d, filled: %d",
+				record.HashLock, record.Value, filledVal)
+			record.FilledValue = filledVal
 		}
+	}
 
-		record.UpdateStatusToSbchLocked(toHex(txHash[:]), txTime)
+	action := newBch2SbchAction(record)
+	if err = bot.checkCounterpartyRisk(action); err == nil {
+		err = bot.callSwapHooks(HookBeforeLock, action)
+	}
+	if err != nil {
+		if errors.Is(err, ErrSwapHookRetry) {
+			return
+		}
+		record.Status = Bch2SbchStatusRejected
 		err = bot.db.updateBch2SbchRecord(record)
 		if err != nil {
 			bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
 		}
+		return
 	}
+
+	// val * bchPrice / 1e8
+	sbchVal := mulByPrice(record.GetFilledValue(), record.BchPrice)
+	bot.lockSbchForBchDeposit(record, token, sbchVal, true)
+}
+
+// lock sbchVal to the user and update status of the record to SbchLocked,
+// sBCH is reserved first if reserve is true (not when resuming a lock)
+func (bot *MarketMakerBot) lockSbchForBchDeposit(record *Bch2SbchRecord, token *Token, sbchVal uint64, reserve bool) {
+	if !bot.beginLocking(DirectionBch2Sbch, record.HashLock) {
+		return
+	}
+	defer bot.endLocking(record.HashLock)
+
+	// reserved after beginLocking(), otherwise a cancellation in between releases
+	// the reservation before it is made, and the reservation is never released
+	if reserve && !bot.reserveForBch2SbchRecord(record, sbchVal) {
+		return
+	}
+
+	job := bot.claimChainJob(record.HashLock, SwapLegSbchLock)
+	if job == nil {
+		return
+	}
+
+	sbchTimeLock := bchTimeLockToSeconds(record.TimeLock) / 2
+	log.Info("sbchTimeLock: ", sbchTimeLock,
+		" , bchPrice: ", bot.bchPrice, " , sbchVal: ", sbchVal)
+
+	txHashStr, sent, err := bot.sendSbchJobTx(job,
+		func() bool { return bot.isSbchLockedForBchDeposit(record) },
+		func() (*gethcmn.Hash, error) {
+			return bot.lockToHtlc(
+				token,
+				gethcmn.HexToAddress(record.SenderEvmAddr),
+				gethcmn.HexToHash(record.HashLock),
+				sbchTimeLock,
+				sbchVal,
+			)
+		},
+	)
+	if err != nil {
+		bot.logError("RPC error, failed to lock sBCH to HTLC: ", err)
+		bot.releaseChainJob(job, err)
+		return
+	}
+
+	log.Info("lock sBCH successful",
+		", hashLock: ", record.HashLock,
+		", txHash: ", txHashStr)
+
+	txTime := uint64(time.Now().Unix())
+	if txHashStr != "?" {
+		if t, err := bot.sbchCli.getTxTime(gethcmn.HexToHash(txHashStr)); err == nil {
+			txTime = t
+		} else {
+			bot.logError("RPC error, failed to get sBCH tx time:", err)
+		}
+	}
+
+	record.UpdateStatusToSbchLocked(txHashStr, txTime)
+	err = bot.db.updateBch2SbchRecord(record)
+	if err != nil {
+		bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
+	} else {
+		bot.finishChainJob(job)
+	}
+	if sent {
+		bot.saveSbchSwapTx(record.HashLock, SwapLegSbchLock, gethcmn.HexToHash(txHashStr))
+		bot.recordSbchGasFee(record.HashLock, sbchLockGas)
+	}
+}
+
+// the sBCH lock of bot for the BCH deposit is on chain, maybe unlocked or refunded already
+func (bot *MarketMakerBot) isSbchLockedForBchDeposit(record *Bch2SbchRecord) bool {
+	sbchCli, err := bot.sbchCliFor(record.Token)
+	if err != nil {
+		return false
+	}
+	state, err := sbchCli.getSwapState(bot.sbchAddr, gethcmn.HexToHash(record.HashLock))
+	return err == nil && state != SwapInvalid
 }
 
 // sbch2bch records: New => BchLocked|TooLateToLockSbch
 func (bot *MarketMakerBot) handleSbchUserDeposits() {
-	if bot.isSlaveMode {
+	if bot.isSlaveMode || !bot.canSign() || bot.isPaused() {
 		return
 	}
 
@@ -978,26 +1889,141 @@ func (bot *MarketMakerBot) handleSbchUserDeposits() {
 	}
 	log.Info("unhandled sBCH user deposits: ", len(records))
 
-	for _, record := range records {
-		log.Info("SBCH2BCH record: ", toJSON(record))
+	jobs := make([]swapJob, len(records))
+	for i, record := range records {
+		record := record
+		jobs[i] = swapJob{hashLock: record.HashLock, action: func() { bot.handleSbchUserDeposit(record) }}
+	}
+	bot.swapWorkers.run(jobs)
+}
 
-		if record.SbchPrice > bot.sbchPrice {
-			log.Infof("sBCH price changed, expected price: %d, current price: %d",
-				record.SbchPrice, bot.sbchPrice)
-			record.Status = Sbch2BchStatusPriceChanged
-			err = bot.db.updateSbch2BchRecord(record)
-			if err != nil {
-				bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
-			}
-			continue
+// lock BCH for the sBCH deposit
+func (bot *MarketMakerBot) handleSbchUserDeposit(record *Sbch2BchRecord) {
+	log.Info("SBCH2BCH record: ", toJSON(record))
+
+	token, err := bot.getToken(record.Token)
+	if err != nil {
+		bot.logError("failed to lock BCH: ", err)
+		return
+	}
+	if job := bot.getUnfinishedChainJob(record.HashLock, SwapLegBchLock); job != nil && job.RawTx != "" {
+		// the tx of a previous attempt may be on chain, it must be resent or replaced
+		log.Info("resume BCH lock, hashLock: ", record.HashLock)
+		bot.lockBchForSbchDeposit(record, false)
+		return
+	}
+	if token.isPriceStale(time.Now().Unix()) {
+		log.Info("token price is stale, wait oracle: ", token.Symbol)
+		return
+	}
+
+	if sbchPrice := bot.getSbchPriceFor(token, record.HashLock, record.Value, record.CreatedAt); record.SbchPrice > sbchPrice {
+		log.Infof("sBCH price changed, expected price: %d, current price: %d",
+			record.SbchPrice, sbchPrice)
+		record.Status = Sbch2BchStatusPriceChanged
+		err = bot.db.updateSbch2BchRecord(record)
+		if err != nil {
+			bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		}
+		return
+	}
+
+	if !bot.checkProfitability(record.HashLock, DirectionSbch2Bch,
+		bot.getSwapServiceFee(record.Token, DirectionSbch2Bch, record.Value, record.SbchPrice),
+		bot.estimateSbch2BchCost()) {
+
+		record.Status = Sbch2BchStatusUnprofitable
+		err = bot.db.updateSbch2BchRecord(record)
+		if err != nil {
+			bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		}
+		return
+	}
+
+	currTime, err := bot.sbchCli.getBlockTimeLatest()
+	if err != nil {
+		bot.logError("RPC error, failed to get sBCH time: ", err)
+		return
+	}
+
+	// do not send BCH to user if its too late!
+	timeElapsed := currTime - record.SbchLockTime
+	if uint32(timeElapsed) > record.TimeLock/3 {
+		log.Info("too late to lock BCH, time elapsed: ", timeElapsed, ", timeLock: ", record.TimeLock)
+		record.Status = Sbch2BchStatusTooLateToLockBch
+		err = bot.db.updateSbch2BchRecord(record)
+		if err != nil {
+			bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		}
+
+		return
+	} else {
+		log.Info("time elapsed: ", timeElapsed, ", timeLock: ", record.TimeLock)
+	}
+
+	action := newSbch2BchAction(record)
+	if err = bot.checkCounterpartyRisk(action); err == nil {
+		err = bot.callSwapHooks(HookBeforeLock, action)
+	}
+	if err != nil {
+		if errors.Is(err, ErrSwapHookRetry) {
+			return
 		}
+		record.Status = Sbch2BchStatusRejected
+		err = bot.db.updateSbch2BchRecord(record)
+		if err != nil {
+			bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		}
+		return
+	}
+
+	bot.lockBchForSbchDeposit(record, true)
+}
 
-		// val * sbchPrice / 1e8
-		bchVal := int64(mulByPrice(record.Value, record.SbchPrice))
+// lock BCH to the user and update status of the record to BchLocked,
+// BCH is reserved first if reserve is true (not when resuming a lock)
+func (bot *MarketMakerBot) lockBchForSbchDeposit(record *Sbch2BchRecord, reserve bool) {
+	if !bot.beginLocking(DirectionSbch2Bch, record.HashLock) {
+		return
+	}
+	defer bot.endLocking(record.HashLock)
+
+	// reserved after beginLocking(), see lockSbchForBchDeposit()
+	if reserve && !bot.reserveForSbch2BchRecord(record) {
+		return
+	}
+
+	job := bot.claimChainJob(record.HashLock, SwapLegBchLock)
+	if job == nil {
+		return
+	}
+
+	// UTXOs of bot must not be selected by concurrent locks
+	bot.bchWalletMu.Lock()
+	defer bot.bchWalletMu.Unlock()
+
+	if bot.idempotencyGuard && job.RawTx == "" {
+		txHash, err := bot.findBchLockOnChain(record)
+		if err != nil {
+			bot.logError("failed to look up BCH lock on chain: ", err)
+			bot.releaseChainJob(job, err)
+			return
+		}
+		if txHash != "" {
+			bot.logWarnf("BCH lock tx found on chain, not locking again, hashLock: %s, txHash: %s",
+				record.HashLock, txHash)
+			bot.saveLockedBchRecord(record, job, txHash)
+			return
+		}
+	}
+
+	// val * sbchPrice / 1e8
+	bchVal := int64(mulByPrice(record.Value, record.SbchPrice))
+	var inAmt int64
+	tx, built, err := bot.sendBchJobTx(job, func() (*wire.MsgTx, error) {
 		utxos, err := bot.bchCli.GetUTXOs(bchVal+5000, 10)
 		if err != nil {
-			bot.logError("failed to get UTXOs: ", err)
-			continue
+			return nil, fmt.Errorf("failed to get UTXOs: %w", err)
 		}
 		log.Info("sBCH price: ", bot.sbchPrice,
 			", bchVal: ", bchVal, ", UTXOs:", toJSON(utxos))
@@ -1009,78 +2035,68 @@ func (bot *MarketMakerBot) handleSbchUserDeposits() {
 				Vout:   utxo.Vout,
 				Amount: utxoAmtToSats(utxo.Amount),
 			}
-		}
-
-		currTime, err := bot.sbchCli.getBlockTimeLatest()
-		if err != nil {
-			bot.logError("RPC error, failed to get sBCH time: ", err)
-			continue
-		}
-
-		// do not send BCH to user if its too late!
-		timeElapsed := currTime - record.SbchLockTime
-		if uint32(timeElapsed) > bot.sbchTimeLock/3 {
-			log.Info("too late to lock BCH, time elapsed: ", timeElapsed, ", timeLock: ", record.TimeLock)
-			record.Status = Sbch2BchStatusTooLateToLockBch
-			err = bot.db.updateSbch2BchRecord(record)
-			if err != nil {
-				bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
-			}
-
-			continue
-		} else {
-			log.Info("time elapsed: ", timeElapsed, ", timeLock: ", record.TimeLock)
+			inAmt += inputs[i].Amount
 		}
 
 		bchTimeLock := sbchTimeLockToBlocks(record.TimeLock) / 2
 		log.Info("BCH timeLock: ", bchTimeLock)
 
-		covenant, err := htlcbch.NewMainnetCovenant(
-			bot.bchPkh,
-			gethcmn.FromHex(record.BchRecipientPkh),
-			gethcmn.FromHex(record.HashLock),
-			bchTimeLock,
-			0,
-		)
+		changeAddr, err := bot.getBchChangeAddr()
 		if err != nil {
-			bot.logError("failed to create HTLC covenant: ", err)
-			continue
+			return nil, fmt.Errorf("failed to get treasury address: %w", err)
 		}
-
-		tx, err := covenant.MakeLockTx(
+		tx, err := bot.getChainAdapter().BuildLock(
+			bot.newSbch2BchHtlcSpec(record),
 			bot.bchPrivKey,
 			inputs,
 			bchVal,
+			changeAddr,
 			bot.bchLockMinerFeeRate,
+			bot.bchSigType,
+			bot.getBchTxLockTime(),
 		)
 		if err != nil {
-			bot.logError("failed to create BCH tx: ", err)
-			continue
+			return nil, fmt.Errorf("failed to create BCH tx: %w", err)
 		}
 		log.Info("BCH tx hex: ", htlcbch.MsgTxToHex(tx))
+		return tx, nil
+	})
+	if err != nil {
+		bot.logError("failed to send BCH tx: ", err)
+		bot.releaseChainJob(job, err)
 
-		txHash, err := bot.bchCli.SendTx(tx)
-		if err != nil {
-			bot.logError("failed to send BCH tx: ", err)
+		// more debug info
+		//prevPkScript, _ := htlcbch.PayToPubKeyHashPkScript(bot.bchPkh)
+		//log.Infof("meep debug --tx=%s --idx=%d --amt=%d --pkscript=%s",
+		//	htlcbch.MsgTxToHex(tx), 0, utxoAmtToSats(utxo.Amount), toHex(prevPkScript))
+		return
+	}
+	txHash := tx.TxHash()
+	log.Info("BCH tx sent, hash: ", txHash.String())
 
-			// more debug info
-			//prevPkScript, _ := htlcbch.PayToPubKeyHashPkScript(bot.bchPkh)
-			//log.Infof("meep debug --tx=%s --idx=%d --amt=%d --pkscript=%s",
-			//	htlcbch.MsgTxToHex(tx), 0, utxoAmtToSats(utxo.Amount), toHex(prevPkScript))
-			continue
-		}
-		log.Info("BCH tx sent, hash: ", txHash.String())
+	bot.saveLockedBchRecord(record, job, txHash.String())
+	if built {
+		bot.saveBchSwapMsgTx(record.HashLock, SwapLegBchLock, tx)
+		bot.recordBchMinerFee(record.HashLock, inAmt, tx)
+	}
+}
 
-		record.UpdateStatusToBchLocked(txHash.String())
-		err = bot.db.updateSbch2BchRecord(record)
-		if err != nil {
-			bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
-		}
+func (bot *MarketMakerBot) saveLockedBchRecord(record *Sbch2BchRecord, job *ChainJob, txHash string) {
+	record.UpdateStatusToBchLocked(txHash)
+	err := bot.db.updateSbch2BchRecord(record)
+	if err != nil {
+		bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		return
 	}
+	bot.finishChainJob(job)
 }
 
 // bch2sbch records: SecretRevealed => BchUnlocked
 func (bot *MarketMakerBot) unlockBchUserDeposits() {
+	if !bot.canSign() {
+		return
+	}
+
 	log.Info("unlock BCH user deposits ...")
 	records, err := bot.db.getBch2SbchRecordsByStatus(Bch2SbchStatusSecretRevealed, bot.dbQueryLimit)
 	if err != nil {
@@ -1090,72 +2106,119 @@ func (bot *MarketMakerBot) unlockBchUserDeposits() {
 	log.Info("secret-revealed BCH user deposits: ", len(records))
 
 	now := time.Now()
-	for _, record := range records {
-		log.Info("record: ", toJSON(record))
-		if bot.isSlaveMode {
-			if now.Sub(record.UpdatedAt).Seconds() < slaveDelaySeconds {
-				// give master some time to handle it
-				log.Info("wait master")
-				continue
-			}
-		} else if bot.lazyMaster {
-			if now.Sub(record.UpdatedAt).Seconds() < slaveDelaySeconds*2 {
-				// give slave some time to handle it
-				log.Info("wait slave")
-				continue
-			}
+	records = bot.unlockBchUserDepositsInBatches(records, now)
+	jobs := make([]swapJob, len(records))
+	for i, record := range records {
+		record := record
+		jobs[i] = swapJob{hashLock: record.HashLock, action: func() { bot.unlockBchUserDeposit(record, now) }}
+	}
+	bot.swapWorkers.run(jobs)
+}
+
+// return true if the status is changed to BchUnlocked
+func (bot *MarketMakerBot) unlockBchUserDeposit(record *Bch2SbchRecord, now time.Time) bool {
+	log.Info("record: ", toJSON(record))
+	job := bot.claimBchUnlockJob(record, now)
+	if job == nil {
+		return false
+	}
+	return bot.unlockBchUserDepositWithJob(record, job)
+}
+
+// claim the job to unlock the BCH deposit, return nil if it is not unlocked this round
+func (bot *MarketMakerBot) claimBchUnlockJob(record *Bch2SbchRecord, now time.Time) *ChainJob {
+	if bot.isSlaveMode {
+		if now.Sub(record.UpdatedAt).Seconds() < slaveDelaySeconds {
+			// give master some time to handle it
+			log.Info("wait master")
+			return nil
+		}
+	} else if bot.lazyMaster {
+		if now.Sub(record.UpdatedAt).Seconds() < slaveDelaySeconds*2 {
+			// give slave some time to handle it
+			log.Info("wait slave")
+			return nil
 		}
+	}
 
-		covenant, err := htlcbch.NewMainnetCovenant(
-			gethcmn.FromHex(record.SenderPkh),
-			gethcmn.FromHex(record.RecipientPkh),
-			gethcmn.FromHex(record.HashLock),
-			uint16(record.TimeLock),
-			record.PenaltyBPS,
-		)
+	if bot.callSwapHooks(HookBeforeRedeem, newBch2SbchAction(record)) != nil {
+		return nil
+	}
+	return bot.claimChainJob(record.HashLock, SwapLegBchUnlock)
+}
+
+// return true if the status is changed to BchUnlocked
+func (bot *MarketMakerBot) unlockBchUserDepositWithJob(record *Bch2SbchRecord, job *ChainJob) bool {
+	if bot.idempotencyGuard && job.RawTx == "" {
+		spent, err := bot.isBchDepositSpent(record)
 		if err != nil {
-			bot.logError("failed to create HTLC covenant: ", err)
-			continue
+			bot.logError("failed to look up BCH deposit on chain: ", err)
+			bot.releaseChainJob(job, err)
+			return false
 		}
-		p2shAddr, _ := covenant.GetP2SHAddress()
-		log.Info("covenant: ", p2shAddr)
+		if spent {
+			bot.logWarnf("BCH deposit is spent, not unlocking again, hashLock: %s", record.HashLock)
+			return bot.saveUnlockedBchRecord(record, job, "?")
+		}
+	}
 
-		tx, err := covenant.MakeUnlockTx(
+	log.Info("HTLC script hash: ", record.HtlcScriptHash)
+	txHashStr := "?"
+	if tx, built, err := bot.sendBchJobTx(job, func() (*wire.MsgTx, error) {
+		tx, err := bot.getChainAdapter().BuildClaim(
+			newBch2SbchHtlcSpec(record),
 			gethcmn.FromHex(record.BchLockTxHash),
-			0,
+			record.BchLockVout,
 			int64(record.Value),
 			bot.bchUnlockMinerFeeRate,
 			gethcmn.FromHex(record.Secret),
+			bot.getBchTxLockTime(),
 		)
 		if err != nil {
-			bot.logError("failed to create unlock tx: ", err)
-			continue
+			return nil, fmt.Errorf("failed to create unlock tx: %w", err)
 		}
 		log.Info("tx: ", htlcbch.MsgTxToHex(tx))
-
-		txHashStr := "?"
-		if txHash, err := bot.bchCli.SendTx(tx); err == nil {
-			log.Info("BCH unlock tx sent, hash: ", txHash.String())
-			txHashStr = txHash.String()
+		return tx, nil
+	}); err == nil {
+		log.Info("BCH unlock tx sent, hash: ", tx.TxHash().String())
+		txHashStr = tx.TxHash().String()
+		if built {
+			bot.saveBchSwapMsgTx(record.HashLock, SwapLegBchUnlock, tx)
+			fwdTx := bot.forwardBchToTreasury(record.HashLock, tx, bot.bchUnlockMinerFeeRate)
+			bot.watchBchTx(record.HashLock, SwapLegBchUnlock, tx, fwdTx)
+			bot.recordBchMinerFee(record.HashLock, int64(record.Value), tx)
+		}
+	} else {
+		bot.logError("failed to unlock BCH: ", err)
+		if isUtxoSpentErr(err) {
+			log.Info("UTXO is spent by others")
 		} else {
-			bot.logError("failed to unlock BCH: ", err)
-			if isUtxoSpentErr(err) {
-				log.Info("UTXO is spent by others")
-			} else {
-				continue
-			}
+			bot.releaseChainJob(job, err)
+			return false
 		}
+	}
 
-		record.UpdateStatusToBchUnlocked(txHashStr)
-		err = bot.db.updateBch2SbchRecord(record)
-		if err != nil {
-			bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
-		}
+	return bot.saveUnlockedBchRecord(record, job, txHashStr)
+}
+
+func (bot *MarketMakerBot) saveUnlockedBchRecord(record *Bch2SbchRecord, job *ChainJob, txHash string) bool {
+	record.UpdateStatusToBchUnlocked(txHash)
+	err := bot.db.updateBch2SbchRecord(record)
+	if err != nil {
+		bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
+		return false
 	}
+	bot.finishChainJob(job)
+	bot.recordBch2SbchFee(record)
+	return true
 }
 
 // sbch2bch: SecretRevealed => SbchUnlocked
 func (bot *MarketMakerBot) unlockSbchUserDeposits() {
+	if !bot.canSign() {
+		return
+	}
+
 	log.Info("unlock sBCH user deposits ...")
 	records, err := bot.db.getSbch2BchRecordsByStatus(Sbch2BchStatusSecretRevealed, bot.dbQueryLimit)
 	if err != nil {
@@ -1165,52 +2228,89 @@ func (bot *MarketMakerBot) unlockSbchUserDeposits() {
 	log.Info("secret-revealed sBCH user deposits: ", len(records))
 
 	now := time.Now()
-	for _, record := range records {
-		log.Info("SBCH2BCH record: ", toJSON(record))
-		if bot.isSlaveMode {
-			if now.Sub(record.UpdatedAt).Seconds() < slaveDelaySeconds {
-				// give master some time to handle it
-				log.Info("wait master")
-				continue
-			}
-		} else if bot.lazyMaster {
-			if now.Sub(record.UpdatedAt).Seconds() < slaveDelaySeconds*2 {
-				// give slave some time to handle it
-				log.Info("wait slave")
-				continue
-			}
+	jobs := make([]swapJob, len(records))
+	for i, record := range records {
+		record := record
+		jobs[i] = swapJob{hashLock: record.HashLock, action: func() { bot.unlockSbchUserDeposit(record, now) }}
+	}
+	bot.swapWorkers.run(jobs)
+}
+
+// return true if the status is changed to SbchUnlocked
+func (bot *MarketMakerBot) unlockSbchUserDeposit(record *Sbch2BchRecord, now time.Time) bool {
+	log.Info("SBCH2BCH record: ", toJSON(record))
+	if bot.isSlaveMode {
+		if now.Sub(record.UpdatedAt).Seconds() < slaveDelaySeconds {
+			// give master some time to handle it
+			log.Info("wait master")
+			return false
+		}
+	} else if bot.lazyMaster {
+		if now.Sub(record.UpdatedAt).Seconds() < slaveDelaySeconds*2 {
+			// give slave some time to handle it
+			log.Info("wait slave")
+			return false
 		}
+	}
 
-		sender := gethcmn.HexToAddress(record.SbchSenderAddr)
-		hashLock := gethcmn.HexToHash(record.HashLock)
-		secret := gethcmn.HexToHash(record.Secret)
+	if bot.callSwapHooks(HookBeforeRedeem, newSbch2BchAction(record)) != nil {
+		return false
+	}
 
-		txHashStr := "?"
-		if txHash, err := bot.sbchCli.unlockSbchFromHtlc(sender, hashLock, secret); err == nil {
-			txHashStr = toHex(txHash[:])
-			log.Info("sBCH unlock tx sent, hash: ", txHashStr)
-		} else {
-			bot.logError("RPC error, failed to unlock sBCH: ", err)
+	sbchCli, err := bot.sbchCliFor(record.Token)
+	if err != nil {
+		bot.logError("failed to unlock sBCH: ", err)
+		return false
+	}
 
-			state, _ := bot.sbchCli.getSwapState(sender, hashLock)
-			if state == SwapUnlocked {
-				log.Info("swap is unlockd")
-			} else {
-				continue
-			}
+	sender := gethcmn.HexToAddress(record.SbchSenderAddr)
+	hashLock := gethcmn.HexToHash(record.HashLock)
+	secret := gethcmn.HexToHash(record.Secret)
+
+	job := bot.claimChainJob(record.HashLock, SwapLegSbchUnlock)
+	if job == nil {
+		return false
+	}
+	isUnlocked := func() bool {
+		state, _ := sbchCli.getSwapState(sender, hashLock)
+		return state == SwapUnlocked
+	}
+
+	txHashStr := "?"
+	if txHash, sent, err := bot.sendSbchJobTx(job, isUnlocked, func() (*gethcmn.Hash, error) {
+		return sbchCli.unlockSbchFromHtlc(sender, hashLock, secret)
+	}); err == nil {
+		txHashStr = txHash
+		log.Info("sBCH unlock tx sent, hash: ", txHashStr)
+		if sent {
+			bot.saveSbchSwapTx(record.HashLock, SwapLegSbchUnlock, gethcmn.HexToHash(txHash))
+			bot.recordSbchGasFee(record.HashLock, sbchUnlockGas)
 		}
+	} else {
+		bot.logError("RPC error, failed to unlock sBCH: ", err)
 
-		record.UpdateStatusToSbchUnlocked(txHashStr)
-		err = bot.db.updateSbch2BchRecord(record)
-		if err != nil {
-			bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		if isUnlocked() {
+			log.Info("swap is unlockd")
+		} else {
+			bot.releaseChainJob(job, err)
+			return false
 		}
 	}
+
+	record.UpdateStatusToSbchUnlocked(txHashStr)
+	err = bot.db.updateSbch2BchRecord(record)
+	if err != nil {
+		bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		return false
+	}
+	bot.finishChainJob(job)
+	bot.recordSbch2BchFee(record)
+	return true
 }
 
 // sbch2bch records: BchLocked => BchRefunded
 func (bot *MarketMakerBot) refundLockedBCH(gotNewBlocks bool) {
-	if !gotNewBlocks {
+	if !gotNewBlocks || !bot.canSign() {
 		return
 	}
 
@@ -1223,81 +2323,100 @@ func (bot *MarketMakerBot) refundLockedBCH(gotNewBlocks bool) {
 	}
 	log.Info("BchLocked SBCH2BCH records: ", len(records))
 
-	for _, record := range records {
-		log.Info("record: ", record.ID, ", txHash: ", record.BchLockTxHash)
-		bchTimeLock := sbchTimeLockToBlocks(record.TimeLock) / 2
-		//log.Info("BCH timeLock: ", bchTimeLock)
+	jobs := make([]swapJob, len(records))
+	for i, record := range records {
+		record := record
+		jobs[i] = swapJob{hashLock: record.HashLock, action: func() { bot.refundLockedBchRecord(record) }}
+	}
+	bot.swapWorkers.run(jobs)
+}
 
-		requiredConfirmations := bchTimeLock
-		if bot.isSlaveMode {
-			// give master some time to handle it
-			requiredConfirmations += slaveDelayBchBlocks
-		} else if bot.lazyMaster {
-			// give slave some time to handle it
-			requiredConfirmations += slaveDelayBchBlocks * 2
-		}
+// return true if the status is changed to BchRefunded
+func (bot *MarketMakerBot) refundLockedBchRecord(record *Sbch2BchRecord) bool {
+	log.Info("record: ", record.ID, ", txHash: ", record.BchLockTxHash)
+	bchTimeLock := sbchTimeLockToBlocks(record.TimeLock) / 2
+	//log.Info("BCH timeLock: ", bchTimeLock)
 
-		confirmations, err := bot.bchCli.GetTxConfirmations(record.BchLockTxHash)
-		if err != nil {
-			bot.logError("RPC error, failed to get tx confirmations: ", err)
-			continue
-		}
+	requiredConfirmations := bchTimeLock
+	if bot.isSlaveMode {
+		// give master some time to handle it
+		requiredConfirmations += slaveDelayBchBlocks
+	} else if bot.lazyMaster {
+		// give slave some time to handle it
+		requiredConfirmations += slaveDelayBchBlocks * 2
+	}
 
-		log.Info("confirmations: ", confirmations, " , bchTimeLock: ", bchTimeLock)
-		if confirmations <= int64(requiredConfirmations) {
-			continue
-		}
+	confirmations, err := bot.bchCli.GetTxConfirmations(record.BchLockTxHash)
+	if err != nil {
+		bot.logError("RPC error, failed to get tx confirmations: ", err)
+		return false
+	}
 
-		covenant, err := htlcbch.NewMainnetCovenant(
-			bot.bchPkh,
-			gethcmn.FromHex(record.BchRecipientPkh),
-			gethcmn.FromHex(record.HashLock),
-			bchTimeLock,
-			0,
-		)
-		if err != nil {
-			bot.logError("failed to create HTLC covenant: ", err)
-			log.Info("record:", toJSON(record))
-			continue
-		}
+	log.Info("confirmations: ", confirmations, " , bchTimeLock: ", bchTimeLock)
+	if confirmations <= int64(requiredConfirmations) {
+		return false
+	}
+	if bot.callSwapHooks(HookBeforeRefund, newSbch2BchAction(record)) != nil {
+		return false
+	}
 
-		// val * sbchPrice / 1e8
-		bchVal := int64(mulByPrice(record.Value, record.SbchPrice))
-		tx, err := covenant.MakeRefundTx(
+	job := bot.claimChainJob(record.HashLock, SwapLegBchRefund)
+	if job == nil {
+		return false
+	}
+
+	// val * sbchPrice / 1e8
+	bchVal := int64(mulByPrice(record.Value, record.SbchPrice))
+	txHashStr := "?"
+	if tx, built, err := bot.sendBchJobTx(job, func() (*wire.MsgTx, error) {
+		tx, err := bot.getChainAdapter().BuildRefund(
+			bot.newSbch2BchHtlcSpec(record),
 			gethcmn.FromHex(record.BchLockTxHash),
 			0,
 			bchVal,
 			bot.bchRefundMinerFeeRate,
+			bot.getBchTxLockTime(),
 		)
 		if err != nil {
-			bot.logError("failed to make refund tx: ", err)
-			continue
+			return nil, fmt.Errorf("failed to make refund tx: %w", err)
 		}
 		log.Info("refund tx: ", htlcbch.MsgTxToHex(tx))
-
-		txHashStr := "?"
-		if txHash, err := bot.bchCli.SendTx(tx); err == nil {
-			log.Info("BCH refund tx sent, hash: ", txHash.String())
-			txHashStr = txHash.String()
+		return tx, nil
+	}); err == nil {
+		log.Info("BCH refund tx sent, hash: ", tx.TxHash().String())
+		txHashStr = tx.TxHash().String()
+		if built {
+			bot.saveBchSwapMsgTx(record.HashLock, SwapLegBchRefund, tx)
+			fwdTx := bot.forwardBchToTreasury(record.HashLock, tx, bot.bchRefundMinerFeeRate)
+			bot.watchBchTx(record.HashLock, SwapLegBchRefund, tx, fwdTx)
+			bot.recordBchMinerFee(record.HashLock, bchVal, tx)
+		}
+	} else {
+		bot.logError("failed to refund BCH: ", err)
+		if isUtxoSpentErr(err) {
+			log.Info("UTXO is spent by others")
 		} else {
-			bot.logError("failed to refund BCH: ", err)
-			if isUtxoSpentErr(err) {
-				log.Info("UTXO is spent by others")
-			} else {
-				continue
-			}
+			bot.releaseChainJob(job, err)
+			return false
 		}
+	}
 
-		record.UpdateStatusToBchRefunded(txHashStr)
-		err = bot.db.updateSbch2BchRecord(record)
-		if err != nil {
-			bot.logError("DB error, failed to save SBCH2BCH record: ", err)
-		}
+	record.UpdateStatusToBchRefunded(txHashStr)
+	err = bot.db.updateSbch2BchRecord(record)
+	if err != nil {
+		bot.logError("DB error, failed to save SBCH2BCH record: ", err)
+		return false
 	}
+	bot.finishChainJob(job)
+	return true
 }
 
 // bch2sbch records: SbchLocked => SbchRefunded
 func (bot *MarketMakerBot) refundLockedSbch() {
+	if !bot.canSign() {
+		return
+	}
+
 	log.Info("handle sBCH refunds ...")
 
 	records, err := bot.db.getBch2SbchRecordsByStatus(Bch2SbchStatusSbchLocked, bot.dbQueryLimit)
@@ -1318,49 +2437,84 @@ func (bot *MarketMakerBot) refundLockedSbch() {
 	}
 	log.Info("sbchNow: ", sbchNow)
 
-	for _, record := range records {
-		log.Info("record: ", record.ID,
-			" , SbchLockTxHash: ", record.SbchLockTxHash,
-			" , SbchLockTxTime: ", record.SbchLockTxTime)
-		txTime := record.SbchLockTxTime
-		sbchTimeLock := bchTimeLockToSeconds(record.TimeLock) / 2
-		unlockableTime := txTime + uint64(sbchTimeLock)
-		if bot.isSlaveMode {
-			// give master some time to handle it
-			unlockableTime += slaveDelaySeconds
-		} else if bot.lazyMaster {
-			// give slave some time to handle it
-			unlockableTime += slaveDelaySeconds * 2
-		}
+	jobs := make([]swapJob, len(records))
+	for i, record := range records {
+		record := record
+		jobs[i] = swapJob{hashLock: record.HashLock, action: func() { bot.refundLockedSbchRecord(record, sbchNow) }}
+	}
+	bot.swapWorkers.run(jobs)
+}
 
-		if sbchNow <= unlockableTime {
-			log.Info("txTime: ", txTime, " unlockableTime: ", unlockableTime)
-			continue
-		}
+// return true if the status is changed to SbchRefunded
+func (bot *MarketMakerBot) refundLockedSbchRecord(record *Bch2SbchRecord, sbchNow uint64) bool {
+	log.Info("record: ", record.ID,
+		" , SbchLockTxHash: ", record.SbchLockTxHash,
+		" , SbchLockTxTime: ", record.SbchLockTxTime)
+	txTime := record.SbchLockTxTime
+	sbchTimeLock := bchTimeLockToSeconds(record.TimeLock) / 2
+	unlockableTime := txTime + uint64(sbchTimeLock)
+	if bot.isSlaveMode {
+		// give master some time to handle it
+		unlockableTime += slaveDelaySeconds
+	} else if bot.lazyMaster {
+		// give slave some time to handle it
+		unlockableTime += slaveDelaySeconds * 2
+	}
 
-		hashLock := gethcmn.HexToHash(record.HashLock)
+	if sbchNow <= unlockableTime {
+		log.Info("txTime: ", txTime, " unlockableTime: ", unlockableTime)
+		return false
+	}
+	if bot.callSwapHooks(HookBeforeRefund, newBch2SbchAction(record)) != nil {
+		return false
+	}
 
-		txHashStr := "?"
-		if txHash, err := bot.sbchCli.refundSbchFromHtlc(bot.sbchAddr, hashLock); err == nil {
-			txHashStr = toHex(txHash.Bytes())
-			log.Info("sBCH refund tx sent, hash: ", txHashStr)
-		} else {
-			bot.logError("RPC error, failed to refund sBCH: ", err)
+	sbchCli, err := bot.sbchCliFor(record.Token)
+	if err != nil {
+		bot.logError("failed to refund sBCH: ", err)
+		return false
+	}
 
-			state, _ := bot.sbchCli.getSwapState(bot.sbchAddr, hashLock)
-			if state == SwapRefunded {
-				log.Info("swap is refunded")
-			} else {
-				continue
-			}
+	hashLock := gethcmn.HexToHash(record.HashLock)
+
+	job := bot.claimChainJob(record.HashLock, SwapLegSbchRefund)
+	if job == nil {
+		return false
+	}
+	isRefunded := func() bool {
+		state, _ := sbchCli.getSwapState(bot.sbchAddr, hashLock)
+		return state == SwapRefunded
+	}
+
+	txHashStr := "?"
+	if txHash, sent, err := bot.sendSbchJobTx(job, isRefunded, func() (*gethcmn.Hash, error) {
+		return sbchCli.refundSbchFromHtlc(bot.sbchAddr, hashLock)
+	}); err == nil {
+		txHashStr = txHash
+		log.Info("sBCH refund tx sent, hash: ", txHashStr)
+		if sent {
+			bot.saveSbchSwapTx(record.HashLock, SwapLegSbchRefund, gethcmn.HexToHash(txHash))
+			bot.recordSbchGasFee(record.HashLock, sbchRefundGas)
 		}
+	} else {
+		bot.logError("RPC error, failed to refund sBCH: ", err)
 
-		record.UpdateStatusToSbchRefunded(txHashStr)
-		err = bot.db.updateBch2SbchRecord(record)
-		if err != nil {
-			bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
+		if isRefunded() {
+			log.Info("swap is refunded")
+		} else {
+			bot.releaseChainJob(job, err)
+			return false
 		}
 	}
+
+	record.UpdateStatusToSbchRefunded(txHashStr)
+	err = bot.db.updateBch2SbchRecord(record)
+	if err != nil {
+		bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
+		return false
+	}
+	bot.finishChainJob(job)
+	return true
 }
 
 func secretToHashLock(secret []byte) string {
@@ -1395,6 +2549,12 @@ func mulByPrice(amt, price uint64) uint64 {
 	return big.NewInt(0).Div(prod, big.NewInt(1e8)).Uint64()
 }
 
+// amt * 1e8 / price
+func divByPrice(amt, price uint64) uint64 {
+	prod := big.NewInt(0).Mul(big.NewInt(int64(amt)), big.NewInt(1e8))
+	return big.NewInt(0).Div(prod, big.NewInt(int64(price))).Uint64()
+}
+
 func toHex(bs []byte) string {
 	return hex.EncodeToString(bs)
 }
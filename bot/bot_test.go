@@ -2,6 +2,7 @@ package bot
 
 import (
 	"crypto/sha256"
+	"encoding/json"
 	"strconv"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/gcash/bchd/bchec"
 	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/txscript"
 	"github.com/gcash/bchd/wire"
 	"github.com/gcash/bchutil"
 
@@ -129,6 +131,212 @@ func TestBch2Sbch_userLockBch(t *testing.T) {
 	require.Equal(t, "", record0.Secret)
 	require.Equal(t, "", record0.BchUnlockTxHash)
 	require.Equal(t, Bch2SbchStatusNew, record0.Status)
+
+	swapTxs, err := _db.getSwapTxsByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Len(t, swapTxs, 1)
+	require.Equal(t, SwapLegBchLock, swapTxs[0].Leg)
+	require.Equal(t, record0.BchLockTxHash, swapTxs[0].TxHash)
+	require.Equal(t, htlcbch.MsgTxToHex(_bchCli.blocks[126].Transactions[0]), swapTxs[0].RawTx)
+}
+
+func TestBch2Sbch_userLockBch_memo(t *testing.T) {
+	_botPkh := testBchPkh
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_evmAddr := gethAddrBytes("evm")
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, _botPkh, _hashLock, 100, 500)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+	opRet, err := covenant.BuildOpRetPkScriptWithMemo(_evmAddr, 1e8, "order-123")
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 128)
+	_bchCli.blocks[126] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+					{PkScript: opRet},
+				},
+			},
+		},
+	}
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		bchPrivKey:   testBchPrivKey,
+		bchPkh:       _botPkh,
+		bchTimeLock:  100,
+		penaltyRatio: 500,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+	}
+	_bot.scanBchBlocks()
+
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "order-123", records[0].Memo)
+
+	list, err := _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, "order-123", list.Swaps[0].Memo)
+	event := &WebhookEvent{}
+	require.NoError(t, json.Unmarshal([]byte(newBch2SbchStatusChange(records[0]).Payload), event))
+	require.Equal(t, "order-123", event.Memo)
+}
+
+func TestBch2Sbch_userLockBch_anyOutputOrder(t *testing.T) {
+	_botPkh := testBchPkh
+	_userPkh := gethAddrBytes("user")
+	_secret := gethHash32Bytes("secret")
+	_hashLock := sha256.Sum256(_secret)
+	_evmAddr := gethAddrBytes("evm")
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, _botPkh, _hashLock[:], 100, 500)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+	opRet, err := covenant.BuildOpRetPkScript(_evmAddr, 1e8)
+	require.NoError(t, err)
+	otherOpRet, err := txscript.NullDataScript([]byte("hello"))
+	require.NoError(t, err)
+
+	// change, other OP_RETURN, SBAS OP_RETURN, deposit
+	newBchCli := func() *MockBchClient {
+		_bchCli := newMockBchClient(124, 128)
+		_bchCli.blocks[126] = &wire.MsgBlock{
+			Transactions: []*wire.MsgTx{
+				{
+					TxIn: []*wire.TxIn{},
+					TxOut: []*wire.TxOut{
+						{Value: 5000, PkScript: newP2SHPkScript(gethAddrBytes("change"))},
+						{PkScript: otherOpRet},
+						{PkScript: opRet},
+						{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+					},
+				},
+			},
+		}
+		return _bchCli
+	}
+	newBot := func(strict bool) *MarketMakerBot {
+		return &MarketMakerBot{
+			db:                   initDB(t, 123, 456),
+			dbQueryLimit:         100,
+			bchCli:               newBchCli(),
+			bchPrivKey:           testBchPrivKey,
+			bchPkh:               _botPkh,
+			bchTimeLock:          100,
+			penaltyRatio:         500,
+			bchPrice:             1e8,
+			sbchPrice:            1e8,
+			strictDepositOutputs: strict,
+		}
+	}
+
+	_bot := newBot(true)
+	_bot.scanBchBlocks()
+	records, err := _bot.db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 0)
+
+	_bot = newBot(false)
+	_bot.scanBchBlocks()
+	records, err = _bot.db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, uint32(3), records[0].BchLockVout)
+	require.Equal(t, uint64(12345678), records[0].Value)
+
+	// the bot unlocks output#3
+	records[0].SbchLockTxHash = toHex(gethHash32Bytes("sbchlock"))
+	records[0].SbchUnlockTxHash = toHex(gethHash32Bytes("sbchunlock"))
+	records[0].Secret = toHex(_secret)
+	records[0].Status = Bch2SbchStatusSecretRevealed
+	require.NoError(t, _bot.db.updateBch2SbchRecord(records[0]))
+	_bot.unlockBchUserDeposits()
+	_bchCli := _bot.bchCli.(*MockBchClient)
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Equal(t, uint32(3), _bchCli.sentTxs[0].TxIn[0].PreviousOutPoint.Index)
+}
+
+func TestBch2Sbch_userLockBch_referral(t *testing.T) {
+	_botPkh := testBchPkh
+	_userPkh := gethAddrBytes("user")
+	_secret := gethHash32Bytes("secret")
+	_hashLock := sha256.Sum256(_secret)
+	_evmAddr := gethAddrBytes("evm")
+	_referral := &Referral{pkh: gethAddrBytes("referral"), bps: 100}
+
+	newDepositTx := func(hashLock, referralPkh []byte) *wire.MsgTx {
+		covenant, err := htlcbch.NewMainnetCovenant(_userPkh, _botPkh, hashLock, 100, 500)
+		require.NoError(t, err)
+		covenant, err = covenant.WithReferral(referralPkh, 100)
+		require.NoError(t, err)
+		scriptHash, err := covenant.GetRedeemScriptHash()
+		require.NoError(t, err)
+		opRet, err := covenant.BuildOpRetPkScript(_evmAddr, 1e8)
+		require.NoError(t, err)
+		return &wire.MsgTx{
+			TxIn: []*wire.TxIn{},
+			TxOut: []*wire.TxOut{
+				{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+				{PkScript: opRet},
+			},
+		}
+	}
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 128)
+	_bchCli.blocks[126] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			newDepositTx(_hashLock[:], _referral.pkh),
+			newDepositTx(gethHash32Bytes("hash2"), gethAddrBytes("other")),
+		},
+	}
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		bchPrivKey:   testBchPrivKey,
+		bchPkh:       _botPkh,
+		bchTimeLock:  100,
+		penaltyRatio: 500,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+		referral:     _referral,
+	}
+	_bot.scanBchBlocks()
+
+	// only the configured referral is accepted
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, toHex(_hashLock[:]), records[0].HashLock)
+	require.Equal(t, toHex(_referral.pkh), records[0].ReferralPkh)
+	require.Equal(t, uint16(100), records[0].ReferralBPS)
+
+	// the bot pays the referral when unlocking BCH
+	records[0].SbchLockTxHash = toHex(gethHash32Bytes("sbchlock"))
+	records[0].SbchUnlockTxHash = toHex(gethHash32Bytes("sbchunlock"))
+	records[0].Secret = toHex(_secret)
+	records[0].Status = Bch2SbchStatusSecretRevealed
+	require.NoError(t, _db.updateBch2SbchRecord(records[0]))
+	_bot.unlockBchUserDeposits()
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Len(t, _bchCli.sentTxs[0].TxOut, 2)
+	require.Equal(t, int64(123456), _bchCli.sentTxs[0].TxOut[1].Value)
+
+	// the referral fee is not revenue
+	require.Equal(t, uint64(0), getReferralFee(12345678, 0))
+	require.Equal(t, uint64(123456), getReferralFee(12345678, 100))
 }
 
 func TestBch2Sbch_userLockBch_invalidParams(t *testing.T) {
@@ -349,6 +557,66 @@ func TestBch2Sbch_botLockSbch_priceChanged(t *testing.T) {
 	require.Len(t, handled, 1)
 }
 
+func TestBch2Sbch_botLockSbch_unprofitable(t *testing.T) {
+	_val := uint64(12345678)
+	_txHash := gethHash32Bytes("bchlock")
+	_botPkh := gethAddrBytes("bot")
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_timeLock := uint32(100)
+	_evmAddr := gethAddrBytes("evm")
+	_scriptHash := gethAddrBytes("htlc")
+	_botBchPrice := uint64(1e8)
+	_botSbchPrice := uint64(1e8)
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  123,
+		BchLockTxHash:  toHex(_txHash),
+		Value:          _val,
+		BchPrice:       _botBchPrice,
+		RecipientPkh:   toHex(_botPkh),
+		SenderPkh:      toHex(_userPkh),
+		HashLock:       toHex(_hashLock),
+		TimeLock:       _timeLock,
+		SenderEvmAddr:  toHex(_evmAddr),
+		HtlcScriptHash: toHex(_scriptHash),
+		Status:         Bch2SbchStatusNew,
+	}))
+
+	_bchCli := newMockBchClient(124, 125)
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_bot := &MarketMakerBot{
+		db:                    _db,
+		dbQueryLimit:          100,
+		bchCli:                _bchCli,
+		sbchCli:               _sbchCli,
+		bchPrivKey:            testBchPrivKey,
+		bchPkh:                _botPkh,
+		bchTimeLock:           72,
+		bchPrice:              _botBchPrice,
+		sbchPrice:             _botSbchPrice,
+		bchUnlockMinerFeeRate: 2,
+		sbchGasPrice:          1e10,
+		profitabilityGate:     true,
+	}
+	_bot.handleBchUserDeposits()
+
+	unhandled, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, unhandled, 0)
+
+	handled, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusUnprofitable, 100)
+	require.NoError(t, err)
+	require.Len(t, handled, 1)
+
+	cost, err := _db.getSwapCost(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), cost.ServiceFee)
+	require.Equal(t, uint64(sbchLockGas+bchUnlockTxSize*2), cost.EstimatedCost)
+	require.Equal(t, uint64(0), cost.SbchGasFee)
+}
+
 func TestBch2Sbch_botLockSbch_notConfirmed(t *testing.T) {
 	_val := uint64(12345678)
 	_txHash := gethHash32Bytes("bchlock")
@@ -585,6 +853,47 @@ func TestBch2Sbch_botUnlockBch(t *testing.T) {
 	require.Equal(t, Bch2SbchStatusBchUnlocked, record0.Status)
 }
 
+func TestBch2Sbch_botUnlockBch_antiFeeSniping(t *testing.T) {
+	_secret := gethHash32Bytes("secret")
+	_hashLock := sha256.Sum256(_secret)
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock")),
+		Value:          12345678,
+		BchPrice:       1e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(_hashLock[:]),
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		SbchLockTxHash: toHex(gethHash32Bytes("sbchlock")),
+		Secret:         toHex(_secret),
+		Status:         Bch2SbchStatusSecretRevealed,
+	}))
+
+	_bchCli := newMockBchClient(124, 130)
+	_bot := &MarketMakerBot{
+		db:             _db,
+		dbQueryLimit:   100,
+		bchCli:         _bchCli,
+		bchPrivKey:     testBchPrivKey,
+		bchPkh:         testBchPkh,
+		bchAddr:        testBchAddr,
+		antiFeeSniping: true,
+	}
+	_bot.unlockBchUserDeposits()
+
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Equal(t, uint32(130), _bchCli.sentTxs[0].LockTime)
+	require.Equal(t, uint32(wire.MaxTxInSequenceNum-1), _bchCli.sentTxs[0].TxIn[0].Sequence)
+
+	_bot.antiFeeSniping = false
+	require.Equal(t, uint32(0), _bot.getBchTxLockTime())
+}
+
 func TestBch2Sbch_botRefundSbch(t *testing.T) {
 	_val := uint64(12345678)
 	_secret := gethHash32("secret")
@@ -803,6 +1112,126 @@ func TestSbch2Bch_userLockSbch(t *testing.T) {
 	require.Equal(t, Sbch2BchStatusNew, record0.Status)
 }
 
+func TestSbch2Bch_userLockSbch_memo(t *testing.T) {
+	_sbchLockTxHash := gethHash32("sbchlocktx")
+	_userEvmAddr := gethAddr("uevm")
+	_hashLock := gethHash32("hashlock")
+	_userBchPkh := gethAddrBytes("ubch")
+
+	calldata, err := htlcsbch.PackLock(testEvmAddr, _hashLock, 12*3600, gethcmn.BytesToAddress(_userBchPkh))
+	require.NoError(t, err)
+	rawTx, err := gethtypes.NewTx(&gethtypes.LegacyTx{Data: append(calldata, "order-123"...)}).MarshalBinary()
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_sbchCli.rawTxs[_sbchLockTxHash] = rawTx
+	_sbchCli.logs[459] = []gethtypes.Log{
+		{
+			BlockNumber: 459,
+			TxHash:      _sbchLockTxHash,
+			Topics: []gethcmn.Hash{
+				htlcsbch.LockEventId,
+				gethAddrToHash32(_userEvmAddr),
+				gethAddrToHash32(testEvmAddr),
+			},
+			Data: joinBytes(_hashLock.Bytes(), int64ToBytes32(987600000+12*3600), satsToWeiBytes32(12345678),
+				rightPad0(_userBchPkh, 12), int64ToBytes32(987600000), int64ToBytes32(500), satsToWeiBytes32(1e8)),
+		},
+	}
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		sbchCli:      _sbchCli,
+		sbchAddr:     testEvmAddr,
+		bchPkh:       testBchPkh,
+		sbchTimeLock: 12 * 3600,
+		penaltyRatio: 500,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+	}
+	_bot.scanSbchEvents()
+
+	records, err := _db.getSbch2BchRecordsByStatus(Sbch2BchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "order-123", records[0].Memo)
+
+	swapTxs, err := _db.getSwapTxsByHashLock(toHex(_hashLock[:]))
+	require.NoError(t, err)
+	require.Len(t, swapTxs, 1)
+	require.Equal(t, toHex(rawTx), swapTxs[0].RawTx)
+
+	list, err := _bot.listSwaps(DirectionSbch2Bch, "", "en", &SwapFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, "order-123", list.Swaps[0].Memo)
+	event := &WebhookEvent{}
+	require.NoError(t, json.Unmarshal([]byte(newSbch2BchStatusChange(records[0]).Payload), event))
+	require.Equal(t, "order-123", event.Memo)
+
+	// invalid memos are ignored
+	require.Equal(t, "", getSbchLockMemo(rawTx[:len(rawTx)-1]))
+	rawTx, err = gethtypes.NewTx(&gethtypes.LegacyTx{Data: append(calldata, "\x00"...)}).MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, "", getSbchLockMemo(rawTx))
+}
+
+func TestSbch2Bch_userLockSbch_referral(t *testing.T) {
+	_sbchLockTxHash := gethHash32("sbchlocktx")
+	_userEvmAddr := gethAddr("uevm")
+	_hashLock := gethHash32("hashlock")
+	_userBchPkh := gethAddrBytes("ubch")
+	_referral := &Referral{pkh: gethAddrBytes("referral"), bps: 100}
+
+	_db := initDB(t, 123, 456)
+	_sbchCli := newMockSbchClient(457, 999, 987600000+60)
+	_sbchCli.logs[459] = []gethtypes.Log{
+		{
+			BlockNumber: 459,
+			TxHash:      _sbchLockTxHash,
+			Topics: []gethcmn.Hash{
+				htlcsbch.LockEventId,
+				gethAddrToHash32(_userEvmAddr),
+				gethAddrToHash32(testEvmAddr),
+			},
+			Data: joinBytes(_hashLock.Bytes(), int64ToBytes32(987600000+12*3600), satsToWeiBytes32(12345678),
+				rightPad0(_userBchPkh, 12), int64ToBytes32(987600000), int64ToBytes32(500), satsToWeiBytes32(1e8)),
+		},
+	}
+	_bchCli := newMockBchClient(124, 128)
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		bchPrivKey:   testBchPrivKey,
+		bchPkh:       testBchPkh,
+		sbchCli:      _sbchCli,
+		sbchAddr:     testEvmAddr,
+		sbchTimeLock: 12 * 3600,
+		penaltyRatio: 500,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+		referral:     _referral,
+	}
+	_bot.scanSbchEvents()
+
+	records, err := _db.getSbch2BchRecordsByStatus(Sbch2BchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, toHex(_referral.pkh), records[0].ReferralPkh)
+	require.Equal(t, uint16(100), records[0].ReferralBPS)
+
+	// the BCH covenant locked by bot is split and announced by OP_RETURN
+	_bot.handleSbchUserDeposits()
+	require.Len(t, _bchCli.sentTxs, 1)
+	lockTx := _bchCli.sentTxs[0]
+	require.Equal(t, records[0].HtlcScriptHash, toHex(lockTx.TxOut[0].PkScript[2:22]))
+	deposit := htlcbch.MainNet.GetHtlcLockInfo(msgTxToVerbose(lockTx))
+	require.NotNil(t, deposit)
+	require.Equal(t, _referral.pkh, []byte(deposit.ReferralPkh))
+	require.Equal(t, uint16(100), deposit.ReferralBPS)
+}
+
 func TestSbch2Bch_userLockSbch_invalidParams(t *testing.T) {
 	_sbchLockTxHash := gethHash32("sbchlocktx")
 	_userEvmAddr := gethAddr("uevm")
@@ -1339,10 +1768,21 @@ func TestSbch2Bch_handleBchDepositTxS2B(t *testing.T) {
 	_hashLock := gethHash32Bytes("hashlock")
 	_lockTime := uint64(time.Now().Unix())
 	_sbchTimeLock := uint32(36000)
-	_bchTimeLock := uint16(60)
+	_bchTimeLock := uint16(30)
 	_userBchPkh := gethAddrBytes("ubch")
-	_scriptHash := gethAddrBytes("htlc")
-	_penaltyBPS := uint16(500)
+	_penaltyBPS := uint16(0)
+
+	covenant, err := htlcbch.NewMainnetCovenant(_botPkh, _userBchPkh, _hashLock, _bchTimeLock, _penaltyBPS)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+	opRet, _ := covenant.BuildOpRetPkScript(_userEvmAddr[:], 1e8)
+
+	covenant2, err := htlcbch.NewMainnetCovenant(_botPkh, _userPkh, _hashLock, _bchTimeLock, _penaltyBPS)
+	require.NoError(t, err)
+	scriptHash2, err := covenant2.GetRedeemScriptHash()
+	require.NoError(t, err)
+	opRet2, _ := covenant2.BuildOpRetPkScript(_userEvmAddr[:], 1e8)
 
 	_db := initDB(t, 123, 456)
 	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
@@ -1354,21 +1794,43 @@ func TestSbch2Bch_handleBchDepositTxS2B(t *testing.T) {
 		BchRecipientPkh:  toHex(_userBchPkh),
 		HashLock:         toHex(_hashLock),
 		TimeLock:         _sbchTimeLock,
-		HtlcScriptHash:   toHex(_scriptHash),
+		HtlcScriptHash:   toHex(scriptHash),
 		BchLockTxHash:    "",
 		Secret:           "",
 		SbchUnlockTxHash: "",
 		Status:           Sbch2BchStatusNew,
 	}))
 
-	covenant, err := htlcbch.NewMainnetCovenant(_botPkh, _userPkh, _hashLock, _bchTimeLock, _penaltyBPS)
-	require.NoError(t, err)
-	scriptHash, err := covenant.GetRedeemScriptHash()
-	require.NoError(t, err)
-	opRet, _ := covenant.BuildOpRetPkScript(_userEvmAddr[:], 1e8)
-
 	_bchCli := newMockBchClient(122, 222)
 	_bchCli.blocks[126] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{ // wrong recipient
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{
+						Value:    12345678,
+						PkScript: newP2SHPkScript(scriptHash2),
+					},
+					{
+						PkScript: opRet2,
+					},
+				},
+			},
+			{ // wrong value
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{
+						Value:    12345677,
+						PkScript: newP2SHPkScript(scriptHash),
+					},
+					{
+						PkScript: opRet,
+					},
+				},
+			},
+		},
+	}
+	_bchCli.blocks[127] = &wire.MsgBlock{
 		Transactions: []*wire.MsgTx{
 			{
 				TxIn: []*wire.TxIn{},
@@ -1396,6 +1858,7 @@ func TestSbch2Bch_handleBchDepositTxS2B(t *testing.T) {
 		isSlaveMode:  true,
 		bchPrice:     1e8,
 		sbchPrice:    1e8,
+		errLogQueue:  newErrLogQueue(100),
 	}
 
 	_bot.scanBchBlocks()
@@ -1407,4 +1870,54 @@ func TestSbch2Bch_handleBchDepositTxS2B(t *testing.T) {
 	bchLockedRecords, err := _db.getSbch2BchRecordsByStatus(Sbch2BchStatusBchLocked, 100)
 	require.NoError(t, err)
 	require.Len(t, bchLockedRecords, 1)
+	require.Equal(t, _bchCli.blocks[127].Transactions[0].TxHash().String(), bchLockedRecords[0].BchLockTxHash)
+}
+
+func TestObserverMode(t *testing.T) {
+	_secret := gethHash32Bytes("secret")
+	_hashLock := sha256.Sum256(_secret)
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock")),
+		Value:          12345678,
+		BchPrice:       1e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(_hashLock[:]),
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		SbchLockTxHash: toHex(gethHash32Bytes("sbchlock")),
+		Secret:         toHex(_secret),
+		Status:         Bch2SbchStatusSecretRevealed,
+	}))
+
+	_bchCli := newMockBchClient(122, 129)
+	_bot := &MarketMakerBot{
+		db:             _db,
+		dbQueryLimit:   100,
+		bchCli:         _bchCli,
+		sbchCli:        newMockSbchClient(457, 999, 0),
+		bchPkh:         testBchPkh,
+		bchAddr:        testBchAddr,
+		isSlaveMode:    true,
+		isObserverMode: true,
+	}
+	_bot.unlockBchUserDeposits()
+	_bot.unlockSbchUserDeposits()
+	_bot.refundLockedBCH(true)
+	_bot.refundLockedSbch()
+	require.Len(t, _bchCli.sentTxs, 0)
+
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusSecretRevealed, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	// no key, no signing
+	_sbchCli, err := newSbchClient("http://127.0.0.1:8545", time.Second, nil, gethcmn.Address{}, nil, &EvmChain{}, _db)
+	require.NoError(t, err)
+	_, err = _sbchCli.refundSbchFromHtlc(testEvmAddr, gethcmn.Hash(_hashLock))
+	require.ErrorIs(t, err, errNoSbchPrivKey)
 }
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchutil"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrSwapBeingLocked = errors.New("the bot is locking for the swap")
+
+// CancelReq asks the bot not to act on a detected swap, signed by the sender of the deposit
+type CancelReq struct {
+	Direction string `json:"direction"` // bch2sbch|sbch2bch
+	HashLock  string `json:"hash_lock"` // hex
+	Signature string `json:"signature"` // of getCancelMsg(), see verifyCancelSig()
+}
+
+// swapCancelGuard makes cancellations and locks by bot of New swaps mutually exclusive,
+// the zero value is ready to use
+type swapCancelGuard struct {
+	mu      sync.Mutex
+	locking map[string]bool // hashLock => true
+}
+
+// called by bot before locking for a swap, see MarketMakerBot.beginLocking()
+func (g *swapCancelGuard) beginLocking(hashLock string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.locking == nil {
+		g.locking = map[string]bool{}
+	}
+	g.locking[hashLock] = true
+}
+
+func (g *swapCancelGuard) endLocking(hashLock string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.locking, hashLock)
+}
+
+// run cancel unless bot is locking for the swap
+func (g *swapCancelGuard) cancel(hashLock string, cancel func() error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.locking[hashLock] {
+		return ErrSwapBeingLocked
+	}
+	return cancel()
+}
+
+// the text message signed by users to cancel a swap
+func getCancelMsg(direction, hashLock string) string {
+	return fmt.Sprintf("cancel %s swap %s", direction, hashLock)
+}
+
+// bch2sbch: base64 signature of BCH wallets ("signmessage") by the key of senderPkh,
+// sbch2bch: hex personal_sign signature of the EVM sender
+func verifyCancelSig(direction, hashLock, sig, sender string) error {
+	msg := []byte(getCancelMsg(direction, hashLock))
+	if direction == DirectionBch2Sbch {
+		bchSig, err := base64.StdEncoding.DecodeString(sig)
+		if err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
+		}
+		pubKey, compressed, err := bchec.RecoverCompact(bchec.S256(), bchSig, getBchSignedMsgHash(msg))
+		if err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
+		}
+		pubKeyBytes := pubKey.SerializeUncompressed()
+		if compressed {
+			pubKeyBytes = pubKey.SerializeCompressed()
+		}
+		if toHex(bchutil.Hash160(pubKeyBytes)) != sender {
+			return fmt.Errorf("not signed by the sender of the swap")
+		}
+		return nil
+	}
+
+	evmSig := gethcmn.FromHex(sig)
+	if len(evmSig) != 65 {
+		return fmt.Errorf("invalid signature length: %d", len(evmSig))
+	}
+	if evmSig[64] >= 27 { // wallets sign with v in {27, 28}
+		evmSig[64] -= 27
+	}
+	pubKey, err := gethcrypto.SigToPub(accounts.TextHash(msg), evmSig)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if gethcrypto.PubkeyToAddress(*pubKey) != gethcmn.HexToAddress(sender) {
+		return fmt.Errorf("not signed by the sender of the swap")
+	}
+	return nil
+}
+
+// mark a New swap as Cancelled if req is signed by its sender,
+// the bot will not lock for it, and the reserved inventory is released
+func (bot *MarketMakerBot) cancelSwap(req *CancelReq) error {
+	hashLock := toHex(gethcmn.FromHex(req.HashLock))
+	switch req.Direction {
+	case DirectionBch2Sbch:
+		record, err := bot.db.getBch2SbchRecordByHashLock(hashLock)
+		if err != nil {
+			return fmt.Errorf("swap not found: %s", hashLock)
+		}
+		if err = verifyCancelSig(req.Direction, hashLock, req.Signature, record.SenderPkh); err != nil {
+			return err
+		}
+		return bot.cancelGuard.cancel(hashLock, func() error {
+			// re-read, the status may be changed by the main loop
+			record, err = bot.db.getBch2SbchRecordByHashLock(hashLock)
+			if err != nil {
+				return err
+			}
+			if record.Status != Bch2SbchStatusNew {
+				return fmt.Errorf("swap can not be cancelled in status %s", record.Status)
+			}
+			record.Status = Bch2SbchStatusCancelled
+			return bot.db.updateBch2SbchRecord(record)
+		})
+	case DirectionSbch2Bch:
+		record, err := bot.db.getSbch2BchRecordByHashLock(hashLock)
+		if err != nil {
+			return fmt.Errorf("swap not found: %s", hashLock)
+		}
+		if err = verifyCancelSig(req.Direction, hashLock, req.Signature, record.SbchSenderAddr); err != nil {
+			return err
+		}
+		return bot.cancelGuard.cancel(hashLock, func() error {
+			record, err = bot.db.getSbch2BchRecordByHashLock(hashLock)
+			if err != nil {
+				return err
+			}
+			if record.Status != Sbch2BchStatusNew {
+				return fmt.Errorf("swap can not be cancelled in status %s", record.Status)
+			}
+			record.Status = Sbch2BchStatusCancelled
+			return bot.db.updateSbch2BchRecord(record)
+		})
+	default:
+		return fmt.Errorf("invalid direction: %s", req.Direction)
+	}
+}
+
+// called by the main loop right before locking for a New swap, false if it has been cancelled,
+// otherwise endLocking() must be called after the lock is saved or given up
+func (bot *MarketMakerBot) beginLocking(direction, hashLock string) bool {
+	bot.cancelGuard.beginLocking(hashLock)
+	var cancelled bool
+	if direction == DirectionBch2Sbch {
+		record, err := bot.db.getBch2SbchRecordByHashLock(hashLock)
+		cancelled = err == nil && record.Status == Bch2SbchStatusCancelled
+	} else {
+		record, err := bot.db.getSbch2BchRecordByHashLock(hashLock)
+		cancelled = err == nil && record.Status == Sbch2BchStatusCancelled
+	}
+	if cancelled {
+		bot.cancelGuard.endLocking(hashLock)
+		log.Info("swap is cancelled by user, hashLock: ", hashLock)
+		return false
+	}
+	return true
+}
+
+func (bot *MarketMakerBot) endLocking(hashLock string) {
+	bot.cancelGuard.endLocking(hashLock)
+}
+
+// cancel a detected swap the bot has not locked for, signed by the sender of the deposit
+func (bot *MarketMakerBot) handleCancelSwap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		NewErrResp("POST only").WriteTo(w)
+		return
+	}
+	var req CancelReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		NewErrResp("invalid request: " + err.Error()).WriteTo(w)
+		return
+	}
+	if err := bot.cancelSwap(&req); err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp("cancelled").WriteTo(w)
+}
@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestCancelSwap_bch2sbch(t *testing.T) {
+	userKey, err := bchec.NewPrivateKey(bchec.S256())
+	require.NoError(t, err)
+	otherKey, err := bchec.NewPrivateKey(bchec.S256())
+	require.NoError(t, err)
+	signCancel := func(key *bchec.PrivateKey, hashLock string) string {
+		msg := []byte(getCancelMsg(DirectionBch2Sbch, hashLock))
+		sig, err := bchec.SignCompact(bchec.S256(), key, getBchSignedMsgHash(msg), true)
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:               _db,
+		sbchCli:          newMockSbchClient(457, 459, 0),
+		reserveInventory: true,
+		bchTimeLock:      72,
+	}
+	hashLock := toHex(gethHash32Bytes("b2s"))
+	record := &Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock")),
+		Value:          1e8,
+		BchPrice:       0.99e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(bchutil.Hash160(userKey.PubKey().SerializeCompressed())),
+		HashLock:       hashLock,
+		TimeLock:       72,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+	}
+	require.NoError(t, _db.addBch2SbchRecord(record))
+	require.True(t, _bot.reserveForBch2SbchRecord(record, 0.99e8))
+
+	req := &CancelReq{Direction: DirectionBch2Sbch, HashLock: hashLock}
+	req.Signature = signCancel(otherKey, hashLock)
+	require.ErrorContains(t, _bot.cancelSwap(req), "not signed by the sender of the swap")
+	req.Signature = "not base64"
+	require.ErrorContains(t, _bot.cancelSwap(req), "invalid signature")
+	req.Signature = signCancel(userKey, toHex(gethHash32Bytes("other")))
+	require.ErrorContains(t, _bot.cancelSwap(req), "not signed by the sender of the swap")
+
+	// not cancelled while bot is locking
+	req.Signature = signCancel(userKey, hashLock)
+	require.True(t, _bot.beginLocking(DirectionBch2Sbch, hashLock))
+	require.ErrorIs(t, _bot.cancelSwap(req), ErrSwapBeingLocked)
+	_bot.endLocking(hashLock)
+
+	staleRecord := *record // loaded by the main loop before the cancellation
+	require.NoError(t, _bot.cancelSwap(req))
+	record, err = _db.getBch2SbchRecordByHashLock(hashLock)
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusCancelled, record.Status)
+	require.True(t, isBch2SbchRefundable(record.Status))
+	reserved, err := _db.getReservedInventory(AssetSbch, time.Now().Unix())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), reserved)
+
+	// bot does not lock or reserve for it, and it can not be cancelled again
+	require.False(t, _bot.beginLocking(DirectionBch2Sbch, hashLock))
+	_bot.lockSbchForBchDeposit(&staleRecord, nil, 0.99e8, true)
+	reserved, err = _db.getReservedInventory(AssetSbch, time.Now().Unix())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), reserved)
+	require.ErrorContains(t, _bot.cancelSwap(req), "swap can not be cancelled in status Cancelled")
+
+	req.HashLock = toHex(gethHash32Bytes("unknown"))
+	require.ErrorContains(t, _bot.cancelSwap(req), "swap not found")
+	req.Direction = "foo"
+	require.ErrorContains(t, _bot.cancelSwap(req), "invalid direction: foo")
+}
+
+func TestCancelSwap_sbch2bch(t *testing.T) {
+	userKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	signCancel := func(hashLock string, v byte) string {
+		msg := []byte(getCancelMsg(DirectionSbch2Bch, hashLock))
+		sig, err := gethcrypto.Sign(accounts.TextHash(msg), userKey)
+		require.NoError(t, err)
+		sig[64] += v
+		return toHex(sig)
+	}
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(100, 101)
+	_bchCli.utxos = []btcjson.ListUnspentResult{{Amount: 10}}
+	_bot := &MarketMakerBot{db: _db, reserveInventory: true, bchCli: _bchCli, sbchTimeLock: 36000}
+	hashLock := toHex(gethHash32Bytes("s2b"))
+	record := &Sbch2BchRecord{
+		SbchLockTime:    987600000,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock")),
+		Value:           1e8,
+		SbchPrice:       0.99e8,
+		SbchSenderAddr:  gethcrypto.PubkeyToAddress(userKey.PublicKey).String(),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        hashLock,
+		TimeLock:        36000,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+	}
+	require.NoError(t, _db.addSbch2BchRecord(record))
+
+	req := &CancelReq{Direction: DirectionSbch2Bch, HashLock: "0x" + hashLock}
+	req.Signature = "0x1234"
+	require.ErrorContains(t, _bot.cancelSwap(req), "invalid signature length: 2")
+	req.Signature = signCancel(toHex(gethHash32Bytes("other")), 0)
+	require.ErrorContains(t, _bot.cancelSwap(req), "not signed by the sender of the swap")
+
+	// signed by wallet, v is 27 or 28
+	req.Signature = signCancel(hashLock, 27)
+	staleRecord := *record
+	staleRecord.SbchLockTime = uint64(time.Now().Unix()) // not expired reservation
+	require.NoError(t, _bot.cancelSwap(req))
+	record, err = _db.getSbch2BchRecordByHashLock(hashLock)
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusCancelled, record.Status)
+	require.False(t, _bot.beginLocking(DirectionSbch2Bch, hashLock))
+	_bot.lockBchForSbchDeposit(&staleRecord, true)
+	reserved, err := _db.getReservedInventory(AssetBch, time.Now().Unix())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), reserved)
+}
+
+func TestCancelSwap_beforeLock(t *testing.T) {
+	userKey, err := bchec.NewPrivateKey(bchec.S256())
+	require.NoError(t, err)
+	_userPkh := bchutil.Hash160(userKey.PubKey().SerializeCompressed())
+	_hashLock := gethHash32Bytes("b2s")
+
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:               _db,
+		sbchCli:          newMockSbchClient(457, 459, 0),
+		reserveInventory: true,
+		bchPkh:           testBchPkh,
+		bchTimeLock:      72,
+		penaltyRatio:     500,
+		bchPrice:         1e8,
+		sbchPrice:        0.98e8,
+		minSwapVal:       1000,
+		errLogQueue:      newErrLogQueue(100),
+	}
+	_bot.handleBchDepositTxB2S(124, &htlcbch.HtlcLockInfo{
+		TxHash:        toHex(gethHash32Bytes("bchlock")),
+		RecipientPkh:  testBchPkh,
+		SenderPkh:     _userPkh,
+		HashLock:      _hashLock,
+		Expiration:    72,
+		PenaltyBPS:    500,
+		Value:         1e8,
+		SenderEvmAddr: gethAddrBytes("evm"),
+		ExpectedPrice: 1e8,
+		ScriptHash:    gethAddrBytes("htlc"),
+	})
+	record, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusNew, record.Status)
+
+	// nothing is reserved until bot locks for the deposit
+	reserved, err := _db.getReservedInventory(AssetSbch, time.Now().Unix())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), reserved)
+
+	msg := []byte(getCancelMsg(DirectionBch2Sbch, record.HashLock))
+	sig, err := bchec.SignCompact(bchec.S256(), userKey, getBchSignedMsgHash(msg), true)
+	require.NoError(t, err)
+	require.NoError(t, _bot.cancelSwap(&CancelReq{Direction: DirectionBch2Sbch, HashLock: record.HashLock,
+		Signature: base64.StdEncoding.EncodeToString(sig)}))
+
+	_bot.lockSbchForBchDeposit(record, nil, 1e8, true) // loaded before the cancellation
+	reserved, err = _db.getReservedInventory(AssetSbch, time.Now().Unix())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), reserved)
+	record, err = _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusCancelled, record.Status)
+}
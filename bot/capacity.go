@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// keep some BCH to pay miner fee of the lock tx, same as handleSbchUserDeposits()
+const bchLockFeeReserve = 5000 // in sats
+
+// CapacityInfo is the max value of a swap which the bot can fill right now, in each direction.
+// Amounts which will be locked by bot for deposits it has not handled yet (and quotes, if
+// inventory reservation is enabled) are reserved.
+type CapacityInfo struct {
+	Bch2Sbch     uint64 `json:"bch2sbch"`      // in sats, max BCH user can lock, 0 means none
+	Sbch2Bch     uint64 `json:"sbch2bch"`      // in sats, max sBCH user can lock, 0 means none
+	FreeBch      uint64 `json:"free_bch"`      // in sats
+	FreeSbch     uint64 `json:"free_sbch"`     // in sats
+	ReservedBch  uint64 `json:"reserved_bch"`  // in sats, to be locked for sbch2bch deposits
+	ReservedSbch uint64 `json:"reserved_sbch"` // in sats, to be locked for bch2sbch deposits
+	UpdatedAt    int64  `json:"updated_at"`    // unix timestamp
+}
+
+// return max swap values the bot can serve, computed on every request
+func (bot *MarketMakerBot) handleCapacity(w http.ResponseWriter, r *http.Request) {
+	capacity, err := bot.getCapacity()
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(capacity).WriteTo(w)
+}
+
+func (bot *MarketMakerBot) getCapacity() (*CapacityInfo, error) {
+	freeBch, err := bot.getFreeBch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query UTXOs: %w", err)
+	}
+	freeSbch, err := bot.sbchCliRO.getBotBalance()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sBCH balance: %w", err)
+	}
+	return bot.computeCapacity(uint64(utxoAmtToSats(freeBch)), weiToSats(freeSbch))
+}
+
+func (bot *MarketMakerBot) computeCapacity(freeBch, freeSbch uint64) (*CapacityInfo, error) {
+	capacity := &CapacityInfo{
+		FreeBch:   freeBch,
+		FreeSbch:  freeSbch,
+		UpdatedAt: time.Now().Unix(),
+	}
+
+	// sBCH to be locked for BCH deposits (tokens are not counted)
+	b2sRecords, err := bot.db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 500)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DB: %w", err)
+	}
+	for _, record := range b2sRecords {
+		if record.Token == "" {
+			capacity.ReservedSbch += mulByPrice(record.Value, record.BchPrice)
+		}
+	}
+
+	// BCH to be locked for sBCH and token deposits
+	s2bRecords, err := bot.db.getSbch2BchRecordsByStatus(Sbch2BchStatusNew, 500)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DB: %w", err)
+	}
+	for _, record := range s2bRecords {
+		capacity.ReservedBch += mulByPrice(record.Value, record.SbchPrice)
+	}
+
+	// quotes are reserved too, and deposits which can not be filled are not
+	if bot.reserveInventory {
+		if capacity.ReservedSbch, err = bot.db.getReservedInventory(AssetSbch, capacity.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to query DB: %w", err)
+		}
+		if capacity.ReservedBch, err = bot.db.getReservedInventory(AssetBch, capacity.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to query DB: %w", err)
+		}
+	}
+
+	availSbch := subOrZero(freeSbch, capacity.ReservedSbch+bot.getSbchGasFee(sbchLockGas))
+	availBch := subOrZero(freeBch, capacity.ReservedBch+bchLockFeeReserve*uint64(len(s2bRecords)+1))
+	if bot.bchPrice > 0 {
+		capacity.Bch2Sbch = bot.capSwapVal(divByPrice(availSbch, bot.bchPrice))
+	}
+	if bot.sbchPrice > 0 {
+		capacity.Sbch2Bch = bot.capSwapVal(divByPrice(availBch, bot.sbchPrice))
+	}
+	return capacity, nil
+}
+
+// clamp the value to the swap value range, 0 means it is too small
+func (bot *MarketMakerBot) capSwapVal(val uint64) uint64 {
+	if bot.maxSwapVal > 0 && val > bot.maxSwapVal {
+		val = bot.maxSwapVal
+	}
+	if val < bot.minSwapVal {
+		return 0
+	}
+	return val
+}
+
+func subOrZero(a, b uint64) uint64 {
+	if a <= b {
+		return 0
+	}
+	return a - b
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeCapacity(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock1")),
+		Value:          1e8,
+		BchPrice:       0.99e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(gethHash32Bytes("b2s1")),
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc1")),
+		Status:         Bch2SbchStatusNew,
+	}))
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock2")),
+		Value:          5e8,
+		BchPrice:       0.99e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(gethHash32Bytes("b2s2")),
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc2")),
+		Status:         Bch2SbchStatusSbchLocked,
+	}))
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    1234567890,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock1")),
+		Value:           2e8,
+		SbchPrice:       0.98e8,
+		SbchSenderAddr:  toHex(gethAddrBytes("user")),
+		BchRecipientPkh: toHex(gethAddrBytes("user")),
+		HashLock:        toHex(gethHash32Bytes("s2b1")),
+		TimeLock:        3600,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc3")),
+		Status:          Sbch2BchStatusNew,
+	}))
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		bchPrice:     0.99e8,
+		sbchPrice:    0.98e8,
+		sbchGasPrice: 1e10,
+		minSwapVal:   0.1e8,
+		maxSwapVal:   10e8,
+	}
+	capacity, err := _bot.computeCapacity(5e8, 3e8)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0.99e8), capacity.ReservedSbch)
+	require.Equal(t, uint64(1.96e8), capacity.ReservedBch)
+	sbchGasFee := _bot.getSbchGasFee(sbchLockGas)
+	require.Equal(t, divByPrice(3e8-0.99e8-sbchGasFee, 0.99e8), capacity.Bch2Sbch)
+	require.Equal(t, divByPrice(5e8-1.96e8-2*bchLockFeeReserve, 0.98e8), capacity.Sbch2Bch)
+
+	// clamped to the swap value range
+	capacity, err = _bot.computeCapacity(100e8, 1e8)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10e8), capacity.Sbch2Bch)
+	require.Equal(t, uint64(0), capacity.Bch2Sbch)
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/chaincfg/chainhash"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+	"github.com/gcash/bchutil/gcs"
+	"github.com/gcash/bchutil/gcs/builder"
+	log "github.com/sirupsen/logrus"
+)
+
+// Compact filters (BIP158 style basic filters of bchd, made of the outpoints spent and the output
+// scripts of a block) only answer whether exact items may be in a block, so BCH blocks are skipped
+// only if they can contain none of the items the bot is waiting for:
+//   - the covenant scripts of unexpired bch2sbch quotes (OP_RETURN outputs are not in basic filters),
+//     deposits made without a quote can not be matched, so users must get a quote first
+//   - the covenant scripts of sbch2bch swaps not locked yet, locked by master in slave mode
+//   - the covenant outputs of sbch2bch swaps locked by bot, spent by receipts and refunds
+//
+// False positives (about 1/2^19 per item) only cost a block download.
+
+// quotes may be deposited right before they expire, and mined later
+const quoteDepositGrace = 6 * 3600
+
+// returns true if BCH block#h can be skipped, it is never skipped on errors
+func (bot *MarketMakerBot) mayBchBlockBeSkipped(h int64) bool {
+	if !bot.bchCompactFilters || bot.bchCFilterUnsupported {
+		return false
+	}
+	if bot.isSwapIndexEnabled() || bot.sbasDiagnosticsEnabled() || bot.recorder != nil && bot.recorder.allTxs {
+		return false // all txs are wanted
+	}
+	blockHash, err := bot.bchCli.GetBlockHash(h)
+	if err != nil {
+		bot.logError(fmt.Sprintf("RPC error, failed to get hash of BCH block#%d: ", h), err)
+		return false
+	}
+	filterData, err := bot.bchCli.GetCFilter(blockHash)
+	if err != nil {
+		if isCFilterUnsupportedErr(err) {
+			bot.bchCFilterUnsupported = true
+			bot.logWarnf("compact filters are not supported by the BCH node, blocks are always downloaded: %s", err)
+		} else {
+			log.Warn("failed to get compact filter of BCH block#", h, ": ", err)
+		}
+		return false
+	}
+	filter, err := gcs.FromNBytes(builder.DefaultP, builder.DefaultM, filterData)
+	if err != nil {
+		log.Warn("failed to parse compact filter of BCH block#", h, ": ", err)
+		return false
+	}
+
+	items, err := bot.getBchWatchItems()
+	if err != nil {
+		bot.logError("DB error, failed to get watched BCH items: ", err)
+		return false
+	}
+	if len(items) == 0 || filter.N() == 0 {
+		return true
+	}
+	matched, err := filter.MatchAny(builder.DeriveKey(blockHash), items)
+	if err != nil {
+		log.Warn("failed to match compact filter of BCH block#", h, ": ", err)
+		return false
+	}
+	return !matched
+}
+
+func isCFilterUnsupportedErr(err error) bool {
+	var rpcErr *btcjson.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code == btcjson.ErrRPCMethodNotFound.Code ||
+			strings.Contains(rpcErr.Message, "CF index")
+	}
+	return false
+}
+
+// scripts and serialized outpoints which make a block worth downloading
+func (bot *MarketMakerBot) getBchWatchItems() (items [][]byte, err error) {
+	quotes, err := bot.db.getValidQuotes(DirectionBch2Sbch, time.Now().Unix()-quoteDepositGrace)
+	if err != nil {
+		return nil, err
+	}
+	for _, quote := range quotes {
+		covenantAddr, _, err := parsePaymentUri(quote.PaymentUri)
+		if err != nil {
+			log.Warn("invalid payment URI of quote ", quote.HashLock, ": ", err)
+			continue
+		}
+		if pkScript := bot.getP2SHPkScriptOfAddr(covenantAddr); pkScript != nil {
+			items = append(items, pkScript)
+		}
+	}
+
+	// -1 means no limit
+	records, err := bot.db.getSbch2BchRecordsByStatus(Sbch2BchStatusNew, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if scriptHash := gethcmn.FromHex(record.HtlcScriptHash); len(scriptHash) == 20 {
+			items = append(items, newP2SHPkScript(scriptHash))
+		}
+	}
+
+	records, err = bot.db.getSbch2BchRecordsByStatus(Sbch2BchStatusBchLocked, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		txHash, err := chainhash.NewHashFromStr(record.BchLockTxHash)
+		if err != nil {
+			continue
+		}
+		var buf bytes.Buffer
+		_ = wire.NewOutPoint(txHash, 0).Serialize(&buf) // covenant is output#0 of lock txs
+		items = append(items, buf.Bytes())
+	}
+	return items, nil
+}
+
+func (bot *MarketMakerBot) getP2SHPkScriptOfAddr(addrStr string) []byte {
+	addr, err := bchutil.DecodeAddress(addrStr, bot.getBchNet().Net)
+	if err != nil {
+		return nil
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil
+	}
+	return pkScript
+}
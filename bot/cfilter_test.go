@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestIsCFilterUnsupportedErr(t *testing.T) {
+	require.True(t, isCFilterUnsupportedErr(&btcjson.RPCError{Code: btcjson.ErrRPCNoCFIndex, Message: "The CF index must be enabled for this command"}))
+	require.True(t, isCFilterUnsupportedErr(btcjson.ErrRPCMethodNotFound))
+	require.False(t, isCFilterUnsupportedErr(&btcjson.RPCError{Code: btcjson.ErrRPCBlockNotFound, Message: "Block not found"}))
+	require.False(t, isCFilterUnsupportedErr(errors.New("timeout")))
+}
+
+func TestScanBchBlocks_compactFilters(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_timeLock := uint16(100)
+	_penaltyBPS := uint16(500)
+	_evmAddr := gethAddrBytes("evm")
+	_bchLockTxHash := bchHash32("bchlocktx")
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, testBchPkh, _hashLock, _timeLock, _penaltyBPS)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+	covenantAddr, err := covenant.GetP2SHAddress()
+	require.NoError(t, err)
+	opRet := newHtlcDepositOpRet(testBchPkh, _userPkh, _hashLock, _timeLock, _penaltyBPS, _evmAddr, 1e8)
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 130)
+	_bchCli.cfilters = true
+	// quoted deposit
+	_bchCli.blocks[126] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+					{PkScript: opRet},
+				},
+			},
+		},
+	}
+	// spends the covenant locked by bot
+	_bchCli.blocks[128] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{TxIn: []*wire.TxIn{}, TxOut: []*wire.TxOut{{Value: 1, PkScript: []byte{0x51}}}}, // coinbase
+			{
+				TxIn:  []*wire.TxIn{{PreviousOutPoint: wire.OutPoint{Hash: _bchLockTxHash}}},
+				TxOut: []*wire.TxOut{{Value: 12345000, PkScript: []byte{0x76}}},
+			},
+		},
+	}
+	// other txs
+	_bchCli.blocks[129] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{TxIn: []*wire.TxIn{}, TxOut: []*wire.TxOut{{Value: 1, PkScript: []byte{0x51}}}}, // coinbase
+			{
+				TxIn:  []*wire.TxIn{{PreviousOutPoint: wire.OutPoint{Hash: bchHash32("othertx")}}},
+				TxOut: []*wire.TxOut{{Value: 12345000, PkScript: []byte{0x76}}},
+			},
+		},
+	}
+
+	require.NoError(t, _db.addQuote(&Quote{
+		HashLock:   toHex(_hashLock),
+		Direction:  DirectionBch2Sbch,
+		Value:      12345678,
+		Price:      1e8,
+		ValidUntil: time.Now().Unix() + 600,
+		PaymentUri: makePaymentUri(covenantAddr, 12345678, toHex(_hashLock), opRet),
+	}, time.Now().Unix()))
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    1234567890,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlocktx")),
+		Value:           12345678,
+		SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+		TimeLock:        72000,
+		SbchPrice:       1e8,
+		BchRecipientPkh: toHex(_userPkh),
+		HashLock:        toHex(gethHash32Bytes("hash2")),
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		BchLockTxHash:   _bchLockTxHash.String(),
+		Status:          Sbch2BchStatusBchLocked,
+	}))
+
+	_bot := &MarketMakerBot{
+		db:                _db,
+		dbQueryLimit:      100,
+		bchCli:            _bchCli,
+		bchPkh:            testBchPkh,
+		bchTimeLock:       _timeLock,
+		penaltyRatio:      _penaltyBPS,
+		bchPrice:          1e8,
+		sbchPrice:         1e8,
+		errLogQueue:       newErrLogQueue(100),
+		bchCompactFilters: true,
+	}
+	items, err := _bot.getBchWatchItems()
+	require.NoError(t, err)
+	require.Len(t, items, 2) // OP_RETURN scripts are not in basic filters
+	require.NotContains(t, items, opRet)
+
+	_bot.scanBchBlocks()
+	newH, err := _db.getLastBchHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(130), newH)
+	require.Equal(t, 2, _bchCli.getBlockCalls) // 126 & 128
+	require.False(t, _bot.bchCFilterUnsupported)
+
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, uint64(126), records[0].BchLockHeight)
+
+	// node without compact filters
+	_bchCli.cfilters = false
+	_bchCli.hTo = 132
+	_bchCli.blocks[131] = &wire.MsgBlock{}
+	_bchCli.blocks[132] = &wire.MsgBlock{}
+	_bot.scanBchBlocks()
+	newH, err = _db.getLastBchHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(132), newH)
+	require.Equal(t, 4, _bchCli.getBlockCalls)
+	require.True(t, _bot.bchCFilterUnsupported)
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/wire"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// a claimed job not finished or released within it is considered abandoned (e.g. the worker crashed),
+// and can be taken over by other workers
+const chainJobVisibilityTimeout = 300 // in seconds
+
+// Every broadcast of a swap leg is a ChainJob persisted in DB before the tx is sent:
+//   - the job is claimed by one worker at a time, the claim expires after chainJobVisibilityTimeout
+//   - the job of a leg is unique by its idempotency key, and is done once the record is updated
+//   - a BCH tx is saved in the job before it is broadcast, and re-claimers resend it instead of
+//     building a new one, so that bot never pays twice from its wallet
+//   - re-claimers of sBCH jobs check the swap state first, and skip the broadcast if it is done
+
+// idempotency key of the job of a swap leg
+func getChainJobKey(leg, hashLock string) string {
+	return leg + ":" + hashLock
+}
+
+func getDefaultJobOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// claim the job of a swap leg, return nil if it is done or claimed by another worker, or bot is frozen
+func (bot *MarketMakerBot) claimChainJob(hashLock, leg string) *ChainJob {
+	if bot.isFrozen() {
+		// frozen while the swap is being handled
+		log.Info("frozen, chain job is not claimed, leg: ", leg, ", hashLock: ", hashLock)
+		return nil
+	}
+	now := time.Now().Unix()
+	job, err := bot.db.claimChainJob(getChainJobKey(leg, hashLock), hashLock, leg,
+		bot.jobOwner, now, now+chainJobVisibilityTimeout)
+	if err != nil {
+		bot.logError("DB error, failed to claim chain job: ", err)
+		return nil
+	}
+	if job == nil {
+		log.Info("chain job is done or claimed by others, leg: ", leg, ", hashLock: ", hashLock)
+	} else if job.Attempts > 1 {
+		log.Info("chain job re-claimed, leg: ", leg, ", hashLock: ", hashLock, ", attempts: ", job.Attempts)
+	}
+	return job
+}
+
+// called after the record is updated
+func (bot *MarketMakerBot) finishChainJob(job *ChainJob) {
+	if err := bot.db.finishChainJob(job.ID); err != nil {
+		bot.logError("DB error, failed to finish chain job: ", err)
+	}
+}
+
+// called when the action is given up this round, err may be nil
+func (bot *MarketMakerBot) releaseChainJob(job *ChainJob, err error) {
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+	}
+	if err = bot.db.releaseChainJob(job.ID, lastError); err != nil {
+		bot.logError("DB error, failed to release chain job: ", err)
+	}
+}
+
+// broadcast the BCH tx of job, the tx saved by a previous attempt is resent if there is one,
+// otherwise a new one is built and saved before being broadcast, built is false if the tx is resent
+func (bot *MarketMakerBot) sendBchJobTx(job *ChainJob,
+	build func() (*wire.MsgTx, error)) (tx *wire.MsgTx, built bool, err error) {
+
+	if job.RawTx != "" {
+		tx, err = htlcbch.MsgTxFromBytes(gethcmn.FromHex(job.RawTx))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid raw tx of chain job: %w", err)
+		}
+		log.Info("resend BCH tx of chain job: ", job.TxHash)
+		_, err = bot.bchCli.SendTx(tx)
+		if err == nil {
+			return tx, false, nil
+		}
+		if _, err2 := bot.bchCli.GetTxConfirmations(job.TxHash); err2 == nil {
+			log.Info("BCH tx of chain job is already known: ", job.TxHash)
+			return tx, false, nil
+		}
+		if !isUtxoSpentErr(err) {
+			return nil, false, err // the tx may still be mined, so a new one is not built
+		}
+		// inputs are spent by another tx, the saved tx can never be mined
+		log.Info("inputs of BCH tx of chain job are spent, build a new one")
+		if err = bot.db.setChainJobTx(job.ID, "", ""); err != nil {
+			return nil, false, err
+		}
+		job.TxHash, job.RawTx = "", ""
+	}
+
+	tx, err = build()
+	if err != nil {
+		return nil, false, err
+	}
+	txHash, rawTx := tx.TxHash().String(), htlcbch.MsgTxToHex(tx)
+	if err = bot.db.setChainJobTx(job.ID, txHash, rawTx); err != nil {
+		return nil, false, fmt.Errorf("failed to save tx of chain job: %w", err)
+	}
+	job.TxHash, job.RawTx = txHash, rawTx
+	if _, err = bot.bchCli.SendTx(tx); err != nil {
+		return nil, false, err
+	}
+	return tx, true, nil
+}
+
+// broadcast the sBCH tx of job unless a previous attempt (or any, with the idempotency guard)
+// has taken effect, which is checked by isDone,
+// return the tx hash, which is "?" if the tx of a previous attempt is unknown, sent is false if skipped
+func (bot *MarketMakerBot) sendSbchJobTx(job *ChainJob,
+	isDone func() bool, send func() (*gethcmn.Hash, error)) (txHash string, sent bool, err error) {
+
+	if (job.Attempts > 1 || job.TxHash != "" || bot.idempotencyGuard) && isDone() {
+		log.Info("sBCH tx of chain job has taken effect, leg: ", job.Leg, ", hashLock: ", job.HashLock)
+		if job.TxHash != "" {
+			return job.TxHash, false, nil
+		}
+		return "?", false, nil
+	}
+
+	hash, err := send()
+	if err != nil {
+		return "", false, err
+	}
+	job.TxHash = toHex(hash[:])
+	if err = bot.db.setChainJobTx(job.ID, job.TxHash, ""); err != nil {
+		bot.logError("DB error, failed to save tx of chain job: ", err)
+	}
+	return job.TxHash, true, nil
+}
+
+// return the job of a swap leg which is not done, or nil
+func (bot *MarketMakerBot) getUnfinishedChainJob(hashLock, leg string) *ChainJob {
+	job, err := bot.db.getChainJob(getChainJobKey(leg, hashLock))
+	if err != nil || job.Done {
+		return nil
+	}
+	return job
+}
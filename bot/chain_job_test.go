@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimChainJob(t *testing.T) {
+	_db := initDB(t, 123, 456)
+
+	job, err := _db.claimChainJob("sbch_lock:h1", "h1", SwapLegSbchLock, "a", 100, 400)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), job.Attempts)
+	require.Equal(t, "a", job.Owner)
+
+	// claimed by a
+	job, err = _db.claimChainJob("sbch_lock:h1", "h1", SwapLegSbchLock, "b", 200, 500)
+	require.NoError(t, err)
+	require.Nil(t, job)
+
+	// re-claimed by a
+	job, err = _db.claimChainJob("sbch_lock:h1", "h1", SwapLegSbchLock, "a", 200, 500)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), job.Attempts)
+
+	// the claim of a expires
+	job, err = _db.claimChainJob("sbch_lock:h1", "h1", SwapLegSbchLock, "b", 501, 801)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), job.Attempts)
+	require.Equal(t, "b", job.Owner)
+
+	// released by b
+	require.NoError(t, _db.releaseChainJob(job.ID, "oops"))
+	job, err = _db.claimChainJob("sbch_lock:h1", "h1", SwapLegSbchLock, "a", 600, 900)
+	require.NoError(t, err)
+	require.Equal(t, "oops", job.LastError)
+
+	// other jobs are not affected
+	job2, err := _db.claimChainJob("sbch_refund:h1", "h1", SwapLegSbchRefund, "b", 600, 900)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), job2.Attempts)
+
+	require.NoError(t, _db.finishChainJob(job.ID))
+	job, err = _db.claimChainJob("sbch_lock:h1", "h1", SwapLegSbchLock, "a", 2000, 2300)
+	require.NoError(t, err)
+	require.Nil(t, job)
+}
+
+func TestSbch2Bch_botLockBch_resumed(t *testing.T) {
+	_hashLock := gethHash32Bytes("hashlock")
+	_lockTime := uint64(1683248875)
+	_timeLock := uint32(36000)
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    _lockTime,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlocktx")),
+		Value:           12345678,
+		SbchPrice:       8e7 - 1,
+		SbchSenderAddr:  gethAddr("uevm").String(),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        toHex(_hashLock),
+		TimeLock:        _timeLock,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		Status:          Sbch2BchStatusNew,
+	}))
+
+	_bchCli := &MockBchClient{sendErr: errors.New("connection refused")}
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		bchPrivKey:   testBchPrivKey,
+		bchPkh:       testBchPkh,
+		sbchCli:      newMockSbchClient(457, 500, _lockTime+60),
+		sbchAddr:     testEvmAddr,
+		sbchTimeLock: _timeLock,
+		bchPrice:     1e8,
+		sbchPrice:    8e7,
+		jobOwner:     "a",
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	// the tx is saved before broadcast
+	_bot.handleSbchUserDeposits()
+	require.Len(t, _bchCli.sentTxs, 0)
+	job, err := _db.getChainJob(getChainJobKey(SwapLegBchLock, toHex(_hashLock)))
+	require.NoError(t, err)
+	require.NotEmpty(t, job.RawTx)
+	require.Equal(t, "connection refused", job.LastError)
+	require.False(t, job.Done)
+
+	// the saved tx is resent, even if price is changed or a new tx would be different
+	_bchCli.sendErr = nil
+	_bot.sbchPrice = 8e7 - 2
+	_bot.bchLockMinerFeeRate = 5
+	_bot.handleSbchUserDeposits()
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Equal(t, job.TxHash, _bchCli.sentTxs[0].TxHash().String())
+
+	record, err := _db.getSbch2BchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusBchLocked, record.Status)
+	require.Equal(t, job.TxHash, record.BchLockTxHash)
+	job, err = _db.getChainJob(job.IdemKey)
+	require.NoError(t, err)
+	require.True(t, job.Done)
+	require.Equal(t, uint32(2), job.Attempts)
+	require.Nil(t, _bot.claimChainJob(toHex(_hashLock), SwapLegBchLock))
+}
+
+func TestSbch2Bch_botUnlockSbch_duplicated(t *testing.T) {
+	_hashLock := gethHash32Bytes("hashlock")
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    uint64(time.Now().Unix()),
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlocktx")),
+		Value:           12345678,
+		SbchPrice:       1e8,
+		SbchSenderAddr:  gethAddr("uevm").String(),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        toHex(_hashLock),
+		TimeLock:        888,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		Secret:          toHex(gethHash32Bytes("secret")),
+		Status:          Sbch2BchStatusSecretRevealed,
+	}))
+	record, err := _db.getSbch2BchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+
+	_sbchCli := newMockSbchClient(457, 500, 0)
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		sbchCli:      _sbchCli,
+		jobOwner:     "a",
+	}
+	other := &MarketMakerBot{
+		db:       _db,
+		sbchCli:  _sbchCli,
+		jobOwner: "b",
+	}
+
+	// a claims the job, sends the tx and crashes
+	require.NotNil(t, _bot.claimChainJob(record.HashLock, SwapLegSbchUnlock))
+	_sbchCli.states[gethcmn.BytesToHash(_hashLock)] = SwapUnlocked
+
+	// the job is still claimed by a
+	require.False(t, other.unlockSbchUserDeposit(record, time.Now()))
+
+	// a restarts and finds the swap unlocked, so the tx is not sent again
+	require.True(t, _bot.unlockSbchUserDeposit(record, time.Now()))
+	record, err = _db.getSbch2BchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusSbchUnlocked, record.Status)
+	require.Equal(t, "?", record.SbchUnlockTxHash)
+	txs, err := _db.getSwapTxsByHashLock(record.HashLock)
+	require.NoError(t, err)
+	require.Len(t, txs, 0)
+}
@@ -0,0 +1,422 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/chaincfg/chainhash"
+	"github.com/gcash/bchd/wire"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+var errChaosDropped = errors.New("chaos: RPC dropped")
+
+// ChaosConfig injects faults into BCH and sBCH RPC calls of the bot, to soak test its retries,
+// failovers and swap state machines. Each call is delayed, dropped and corrupted independently
+// with the given probabilities in [0, 1]:
+//   - a dropped call fails without reaching the node
+//   - a corrupted read returns stale or partial data, e.g. a lagging tip, a block missing a tx,
+//     fewer confirmations, a missing UTXO or log
+//   - a corrupted write reaches the node but its response is lost, so the call fails
+//
+// It is refused on mainnet.
+type ChaosConfig struct {
+	Seed        int64   `json:"seed"` // 0 means seeded by time
+	DelayProb   float64 `json:"delay_prob"`
+	MaxDelay    uint32  `json:"max_delay"` // in milliseconds
+	DropProb    float64 `json:"drop_prob"`
+	CorruptProb float64 `json:"corrupt_prob"`
+}
+
+func checkChaosConfig(c *ChaosConfig, bchNet *htlcbch.ChainParams) error {
+	if c == nil {
+		return nil
+	}
+	if bchNet == htlcbch.MainNet {
+		return fmt.Errorf("chaos is not allowed on %s", bchNet.Name)
+	}
+	for _, prob := range []float64{c.DelayProb, c.DropProb, c.CorruptProb} {
+		if prob < 0 || prob > 1 {
+			return fmt.Errorf("invalid chaos probability: %v", prob)
+		}
+	}
+	if c.DelayProb > 0 && c.MaxDelay == 0 {
+		return fmt.Errorf("chaos delay probability is set without max delay")
+	}
+	return nil
+}
+
+// ChaosStats counts injected faults
+type ChaosStats struct {
+	Delayed   uint64 `json:"delayed"`
+	Dropped   uint64 `json:"dropped"`
+	Corrupted uint64 `json:"corrupted"`
+}
+
+type chaosInjector struct {
+	cfg   ChaosConfig
+	mu    sync.Mutex
+	rng   *rand.Rand
+	stats ChaosStats
+}
+
+func newChaosInjector(cfg *ChaosConfig) *chaosInjector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Warnf("chaos: RPC faults are injected, seed: %d", seed)
+	return &chaosInjector{cfg: *cfg, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (inj *chaosInjector) getStats() ChaosStats {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	return inj.stats
+}
+
+// intn returns a random int in [0, n)
+func (inj *chaosInjector) intn(n int) int {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	return inj.rng.Intn(n)
+}
+
+// inject delays the call of method, then returns errChaosDropped if it is dropped,
+// or whether its response should be corrupted
+func (inj *chaosInjector) inject(method string) (corrupt bool, err error) {
+	inj.mu.Lock()
+	var delay time.Duration
+	if inj.rng.Float64() < inj.cfg.DelayProb {
+		delay = time.Duration(inj.rng.Int63n(int64(inj.cfg.MaxDelay)+1)) * time.Millisecond
+		inj.stats.Delayed++
+	}
+	drop := inj.rng.Float64() < inj.cfg.DropProb
+	if drop {
+		inj.stats.Dropped++
+	} else {
+		corrupt = inj.rng.Float64() < inj.cfg.CorruptProb
+		if corrupt {
+			inj.stats.Corrupted++
+		}
+	}
+	inj.mu.Unlock()
+
+	if delay > 0 {
+		log.Debugf("chaos: %s delayed by %s", method, delay)
+		time.Sleep(delay)
+	}
+	if drop {
+		log.Debugf("chaos: %s dropped", method)
+		return false, fmt.Errorf("%s: %w", method, errChaosDropped)
+	}
+	if corrupt {
+		log.Debugf("chaos: %s corrupted", method)
+	}
+	return corrupt, nil
+}
+
+// chaosRead calls read through the injector, corrupted results are mangled by corrupt
+func chaosRead[T any](inj *chaosInjector, method string, read func() (T, error), corrupt func(T) T) (T, error) {
+	var zero T
+	corrupted, err := inj.inject(method)
+	if err != nil {
+		return zero, err
+	}
+	result, err := read()
+	if err != nil || !corrupted {
+		return result, err
+	}
+	return corrupt(result), nil
+}
+
+// chaosWrite calls write through the injector, corrupted results are lost after write reaches the node
+func chaosWrite[T any](inj *chaosInjector, method string, write func() (T, error)) (T, error) {
+	var zero T
+	corrupted, err := inj.inject(method)
+	if err != nil {
+		return zero, err
+	}
+	result, err := write()
+	if err != nil || !corrupted {
+		return result, err
+	}
+	return zero, fmt.Errorf("%s: chaos: response lost", method)
+}
+
+// removeRandom returns a copy of items without one of them
+func removeRandom[T any](inj *chaosInjector, items []T) []T {
+	if len(items) == 0 {
+		return items
+	}
+	idx := inj.intn(len(items))
+	result := make([]T, 0, len(items)-1)
+	result = append(result, items[:idx]...)
+	return append(result, items[idx+1:]...)
+}
+
+var _ IBchClient = (*chaosBchClient)(nil)
+
+type chaosBchClient struct {
+	cli IBchClient
+	inj *chaosInjector
+}
+
+func newChaosBchClient(cli IBchClient, inj *chaosInjector) IBchClient {
+	return &chaosBchClient{cli: cli, inj: inj}
+}
+
+func (c *chaosBchClient) GetBlockCount() (int64, error) {
+	return chaosRead(c.inj, "GetBlockCount", c.cli.GetBlockCount, func(h int64) int64 {
+		return h - 1 - int64(c.inj.intn(3)) // lagging node
+	})
+}
+
+func (c *chaosBchClient) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	return chaosRead(c.inj, "GetBlockHash", func() (*chainhash.Hash, error) {
+		return c.cli.GetBlockHash(height)
+	}, func(*chainhash.Hash) *chainhash.Hash {
+		return &chainhash.Hash{} // orphaned
+	})
+}
+
+func (c *chaosBchClient) GetBlock(height int64) (*btcjson.GetBlockVerboseTxResult, error) {
+	return chaosRead(c.inj, "GetBlock", func() (*btcjson.GetBlockVerboseTxResult, error) {
+		return c.cli.GetBlock(height)
+	}, func(block *btcjson.GetBlockVerboseTxResult) *btcjson.GetBlockVerboseTxResult {
+		corrupted := *block
+		corrupted.Tx = removeRandom(c.inj, block.Tx)
+		return &corrupted
+	})
+}
+
+func (c *chaosBchClient) GetCFilter(blockHash *chainhash.Hash) ([]byte, error) {
+	return chaosRead(c.inj, "GetCFilter", func() ([]byte, error) {
+		return c.cli.GetCFilter(blockHash)
+	}, func(filter []byte) []byte {
+		return filter[:len(filter)/2] // truncated
+	})
+}
+
+func (c *chaosBchClient) GetUTXOs(minVal, maxCount int64) ([]btcjson.ListUnspentResult, error) {
+	return chaosRead(c.inj, "GetUTXOs", func() ([]btcjson.ListUnspentResult, error) {
+		return c.cli.GetUTXOs(minVal, maxCount)
+	}, func(utxos []btcjson.ListUnspentResult) []btcjson.ListUnspentResult {
+		return removeRandom(c.inj, utxos)
+	})
+}
+
+func (c *chaosBchClient) GetAllUTXOs() ([]btcjson.ListUnspentResult, error) {
+	return chaosRead(c.inj, "GetAllUTXOs", c.cli.GetAllUTXOs, func(utxos []btcjson.ListUnspentResult) []btcjson.ListUnspentResult {
+		return removeRandom(c.inj, utxos)
+	})
+}
+
+func (c *chaosBchClient) GetTxConfirmations(txHashHex string) (int64, error) {
+	return chaosRead(c.inj, "GetTxConfirmations", func() (int64, error) {
+		return c.cli.GetTxConfirmations(txHashHex)
+	}, func(confirmations int64) int64 {
+		if confirmations <= 0 {
+			return confirmations
+		}
+		return int64(c.inj.intn(int(confirmations)))
+	})
+}
+
+func (c *chaosBchClient) GetTx(txHashHex string) (*btcjson.TxRawResult, error) {
+	return chaosRead(c.inj, "GetTx", func() (*btcjson.TxRawResult, error) {
+		return c.cli.GetTx(txHashHex)
+	}, func(tx *btcjson.TxRawResult) *btcjson.TxRawResult {
+		corrupted := *tx
+		corrupted.Confirmations = 0 // not mined yet
+		corrupted.BlockHash = ""
+		return &corrupted
+	})
+}
+
+func (c *chaosBchClient) GetTxOut(txHashHex string, vout uint32) (*btcjson.GetTxOutResult, error) {
+	return chaosRead(c.inj, "GetTxOut", func() (*btcjson.GetTxOutResult, error) {
+		return c.cli.GetTxOut(txHashHex, vout)
+	}, func(*btcjson.GetTxOutResult) *btcjson.GetTxOutResult {
+		return nil // spent
+	})
+}
+
+func (c *chaosBchClient) SendTx(tx *wire.MsgTx) (*chainhash.Hash, error) {
+	return chaosWrite(c.inj, "SendTx", func() (*chainhash.Hash, error) {
+		return c.cli.SendTx(tx)
+	})
+}
+
+var _ ISbchClient = (*chaosSbchClient)(nil)
+
+type chaosSbchClient struct {
+	cli ISbchClient
+	inj *chaosInjector
+}
+
+func newChaosSbchClient(cli ISbchClient, inj *chaosInjector) ISbchClient {
+	return &chaosSbchClient{cli: cli, inj: inj}
+}
+
+func (c *chaosSbchClient) getBlockNumber() (uint64, error) {
+	return chaosRead(c.inj, "getBlockNumber", c.cli.getBlockNumber, func(h uint64) uint64 {
+		lag := 1 + uint64(c.inj.intn(3))
+		if h < lag {
+			return 0
+		}
+		return h - lag
+	})
+}
+
+func (c *chaosSbchClient) getBlockTimeLatest() (uint64, error) {
+	return chaosRead(c.inj, "getBlockTimeLatest", c.cli.getBlockTimeLatest, func(ts uint64) uint64 {
+		lag := 1 + uint64(c.inj.intn(60))
+		if ts < lag {
+			return 0
+		}
+		return ts - lag
+	})
+}
+
+func (c *chaosSbchClient) getBalance() (*big.Int, error) {
+	return chaosRead(c.inj, "getBalance", c.cli.getBalance, func(*big.Int) *big.Int {
+		return big.NewInt(0)
+	})
+}
+
+func (c *chaosSbchClient) getTxTime(txHash gethcmn.Hash) (uint64, error) {
+	return chaosRead(c.inj, "getTxTime", func() (uint64, error) {
+		return c.cli.getTxTime(txHash)
+	}, func(uint64) uint64 {
+		return 0
+	})
+}
+
+func (c *chaosSbchClient) getTxReceipt(txHash gethcmn.Hash) (*types.Receipt, error) {
+	return chaosRead(c.inj, "getTxReceipt", func() (*types.Receipt, error) {
+		return c.cli.getTxReceipt(txHash)
+	}, func(receipt *types.Receipt) *types.Receipt {
+		corrupted := *receipt
+		corrupted.Logs = removeRandom(c.inj, receipt.Logs)
+		return &corrupted
+	})
+}
+
+func (c *chaosSbchClient) getHtlcLogs(fromBlock, toBlock uint64) ([]types.Log, error) {
+	return chaosRead(c.inj, "getHtlcLogs", func() ([]types.Log, error) {
+		return c.cli.getHtlcLogs(fromBlock, toBlock)
+	}, func(logs []types.Log) []types.Log {
+		return removeRandom(c.inj, logs)
+	})
+}
+
+func (c *chaosSbchClient) lockSbchToHtlc(userEvmAddr gethcmn.Address, hashLock gethcmn.Hash,
+	timeLock uint32, amt *big.Int) (*gethcmn.Hash, error) {
+	return chaosWrite(c.inj, "lockSbchToHtlc", func() (*gethcmn.Hash, error) {
+		return c.cli.lockSbchToHtlc(userEvmAddr, hashLock, timeLock, amt)
+	})
+}
+
+func (c *chaosSbchClient) unlockSbchFromHtlc(senderAddr gethcmn.Address, hashLock gethcmn.Hash,
+	secret gethcmn.Hash) (*gethcmn.Hash, error) {
+	return chaosWrite(c.inj, "unlockSbchFromHtlc", func() (*gethcmn.Hash, error) {
+		return c.cli.unlockSbchFromHtlc(senderAddr, hashLock, secret)
+	})
+}
+
+func (c *chaosSbchClient) refundSbchFromHtlc(senderAddr gethcmn.Address, hashLock gethcmn.Hash) (*gethcmn.Hash, error) {
+	return chaosWrite(c.inj, "refundSbchFromHtlc", func() (*gethcmn.Hash, error) {
+		return c.cli.refundSbchFromHtlc(senderAddr, hashLock)
+	})
+}
+
+func (c *chaosSbchClient) getSwapState(senderAddr gethcmn.Address, hashLock gethcmn.Hash) (uint8, error) {
+	return chaosRead(c.inj, "getSwapState", func() (uint8, error) {
+		return c.cli.getSwapState(senderAddr, hashLock)
+	}, func(uint8) uint8 {
+		return SwapInvalid // node has not seen the lock yet
+	})
+}
+
+func (c *chaosSbchClient) getSwapStates(keys []SwapStateKey) ([]uint8, error) {
+	return chaosRead(c.inj, "getSwapStates", func() ([]uint8, error) {
+		return c.cli.getSwapStates(keys)
+	}, func(states []uint8) []uint8 {
+		if len(states) == 0 {
+			return states
+		}
+		corrupted := append([]uint8(nil), states...)
+		corrupted[c.inj.intn(len(states))] = SwapInvalid
+		return corrupted
+	})
+}
+
+func (c *chaosSbchClient) getMarketMakerInfo(addr gethcmn.Address) (*htlcsbch.MarketMakerInfo, error) {
+	return chaosRead(c.inj, "getMarketMakerInfo", func() (*htlcsbch.MarketMakerInfo, error) {
+		return c.cli.getMarketMakerInfo(addr)
+	}, func(info *htlcsbch.MarketMakerInfo) *htlcsbch.MarketMakerInfo {
+		corrupted := *info
+		corrupted.RetiredAt = 1 // looks retired
+		return &corrupted
+	})
+}
+
+func (c *chaosSbchClient) getRawTx(txHash gethcmn.Hash) ([]byte, error) {
+	return chaosRead(c.inj, "getRawTx", func() ([]byte, error) {
+		return c.cli.getRawTx(txHash)
+	}, func(rawTx []byte) []byte {
+		return rawTx[:len(rawTx)/2] // truncated
+	})
+}
+
+func (c *chaosSbchClient) forHtlc(htlcAddr gethcmn.Address) ISbchClient {
+	return newChaosSbchClient(c.cli.forHtlc(htlcAddr), c.inj)
+}
+
+func (c *chaosSbchClient) lockTokenToHtlc(tokenAddr, userEvmAddr gethcmn.Address, hashLock gethcmn.Hash,
+	timeLock uint32, amt *big.Int) (*gethcmn.Hash, error) {
+	return chaosWrite(c.inj, "lockTokenToHtlc", func() (*gethcmn.Hash, error) {
+		return c.cli.lockTokenToHtlc(tokenAddr, userEvmAddr, hashLock, timeLock, amt)
+	})
+}
+
+func (c *chaosSbchClient) getTokenBalance(tokenAddr, owner gethcmn.Address) (*big.Int, error) {
+	return chaosRead(c.inj, "getTokenBalance", func() (*big.Int, error) {
+		return c.cli.getTokenBalance(tokenAddr, owner)
+	}, func(*big.Int) *big.Int {
+		return big.NewInt(0)
+	})
+}
+
+func (c *chaosSbchClient) getLatestRoundData(feedAddr gethcmn.Address) (*htlcsbch.RoundData, error) {
+	return chaosRead(c.inj, "getLatestRoundData", func() (*htlcsbch.RoundData, error) {
+		return c.cli.getLatestRoundData(feedAddr)
+	}, func(round *htlcsbch.RoundData) *htlcsbch.RoundData {
+		corrupted := *round
+		corrupted.UpdatedAt = big.NewInt(0) // stale
+		return &corrupted
+	})
+}
+
+func (c *chaosSbchClient) transferSbch(to gethcmn.Address, amt *big.Int) (*gethcmn.Hash, error) {
+	return chaosWrite(c.inj, "transferSbch", func() (*gethcmn.Hash, error) {
+		return c.cli.transferSbch(to, amt)
+	})
+}
+
+func (c *chaosSbchClient) sendData(to gethcmn.Address, data []byte) (*gethcmn.Hash, error) {
+	return chaosWrite(c.inj, "sendData", func() (*gethcmn.Hash, error) {
+		return c.cli.sendData(to, data)
+	})
+}
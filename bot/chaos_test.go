@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestCheckChaosConfig(t *testing.T) {
+	require.NoError(t, checkChaosConfig(nil, htlcbch.MainNet))
+	require.NoError(t, checkChaosConfig(&ChaosConfig{DropProb: 0.1}, htlcbch.TestNet3))
+	require.NoError(t, checkChaosConfig(&ChaosConfig{DelayProb: 1, MaxDelay: 100}, htlcbch.TestNet3))
+	require.ErrorContains(t, checkChaosConfig(&ChaosConfig{DropProb: 0.1}, htlcbch.MainNet),
+		"chaos is not allowed on mainnet")
+	require.ErrorContains(t, checkChaosConfig(&ChaosConfig{DropProb: 1.1}, htlcbch.TestNet3),
+		"invalid chaos probability: 1.1")
+	require.ErrorContains(t, checkChaosConfig(&ChaosConfig{CorruptProb: -0.1}, htlcbch.TestNet3),
+		"invalid chaos probability: -0.1")
+	require.ErrorContains(t, checkChaosConfig(&ChaosConfig{DelayProb: 0.5}, htlcbch.TestNet3),
+		"chaos delay probability is set without max delay")
+}
+
+func TestChaosBchClient_drop(t *testing.T) {
+	inj := newChaosInjector(&ChaosConfig{Seed: 1, DropProb: 1})
+	mockCli := newMockBchClient(100, 110)
+	cli := newChaosBchClient(mockCli, inj)
+
+	_, err := cli.GetBlockCount()
+	require.True(t, errors.Is(err, errChaosDropped))
+	require.ErrorContains(t, err, "GetBlockCount: chaos: RPC dropped")
+	_, err = cli.GetBlock(105)
+	require.True(t, errors.Is(err, errChaosDropped))
+	_, err = cli.SendTx(&wire.MsgTx{})
+	require.True(t, errors.Is(err, errChaosDropped))
+	require.Len(t, mockCli.sentTxs, 0)
+	require.Equal(t, ChaosStats{Dropped: 3}, inj.getStats())
+}
+
+func TestChaosBchClient_corrupt(t *testing.T) {
+	inj := newChaosInjector(&ChaosConfig{Seed: 1, CorruptProb: 1})
+	mockCli := newMockBchClient(100, 110)
+	mockCli.blocks[105] = &wire.MsgBlock{Transactions: []*wire.MsgTx{{Version: 1}, {Version: 2}}}
+	tx := &wire.MsgTx{TxOut: []*wire.TxOut{{Value: 1000}}}
+	mockCli.txs[tx.TxHash().String()] = tx
+	mockCli.confirmations[tx.TxHash().String()] = 5
+	cli := newChaosBchClient(mockCli, inj)
+
+	h, err := cli.GetBlockCount()
+	require.NoError(t, err)
+	require.Less(t, h, int64(110))
+	require.GreaterOrEqual(t, h, int64(107))
+
+	block, err := cli.GetBlock(105)
+	require.NoError(t, err)
+	require.Len(t, block.Tx, 1)
+	block, err = mockCli.GetBlock(105)
+	require.NoError(t, err)
+	require.Len(t, block.Tx, 2) // not changed
+
+	confirmations, err := cli.GetTxConfirmations(tx.TxHash().String())
+	require.NoError(t, err)
+	require.Less(t, confirmations, int64(5))
+
+	txOut, err := cli.GetTxOut(tx.TxHash().String(), 0)
+	require.NoError(t, err)
+	require.Nil(t, txOut)
+
+	_, err = cli.SendTx(tx)
+	require.ErrorContains(t, err, "SendTx: chaos: response lost")
+	require.Len(t, mockCli.sentTxs, 1) // sent anyway
+	require.Equal(t, ChaosStats{Corrupted: 5}, inj.getStats())
+}
+
+func TestChaosSbchClient(t *testing.T) {
+	inj := newChaosInjector(&ChaosConfig{Seed: 1, CorruptProb: 1})
+	mockCli := newMockSbchClient(100, 110, 12345)
+	cli := newChaosSbchClient(mockCli, inj)
+
+	h, err := cli.getBlockNumber()
+	require.NoError(t, err)
+	require.Less(t, h, uint64(110))
+
+	_, err = cli.transferSbch(gethAddr("user"), satsToWei(1000))
+	require.ErrorContains(t, err, "transferSbch: chaos: response lost")
+	require.Equal(t, satsToWei(1000), mockCli.sent[gethAddr("user")])
+
+	_, err = cli.forHtlc(gethAddr("htlc")).getBlockNumber()
+	require.NoError(t, err)
+	require.Equal(t, ChaosStats{Corrupted: 3}, inj.getStats())
+}
+
+func TestChaos_scanBchBlocks(t *testing.T) {
+	_botPkh := testBchPkh
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_timeLock := uint16(100)
+	_penaltyBPS := uint16(500)
+	_evmAddr := gethAddrBytes("evm")
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, _botPkh, _hashLock, _timeLock, _penaltyBPS)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 128)
+	_bchCli.blocks[126] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{
+						Value:    12345678,
+						PkScript: newP2SHPkScript(scriptHash),
+					},
+					{
+						PkScript: newHtlcDepositOpRet(_botPkh, _userPkh, _hashLock, _timeLock, _penaltyBPS, _evmAddr, 1e8),
+					},
+				},
+			},
+		},
+	}
+
+	inj := newChaosInjector(&ChaosConfig{Seed: 403, DropProb: 0.5})
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       newChaosBchClient(_bchCli, inj),
+		chaos:        inj,
+		errLogQueue:  newErrLogQueue(100),
+		bchPrivKey:   testBchPrivKey,
+		bchPkh:       _botPkh,
+		bchTimeLock:  _timeLock,
+		penaltyRatio: _penaltyBPS,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+	}
+	for i := 0; i < 100; i++ {
+		_bot.scanBchBlocks()
+		if h, _ := _db.getLastBchHeight(); h == 128 {
+			break
+		}
+	}
+	require.Greater(t, inj.getStats().Dropped, uint64(0))
+
+	newH, err := _db.getLastBchHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(128), newH)
+
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, uint64(126), records[0].BchLockHeight)
+}
@@ -18,10 +18,14 @@ import (
 
 type IBchClient interface {
 	GetBlockCount() (int64, error)
+	GetBlockHash(height int64) (*chainhash.Hash, error)
 	GetBlock(height int64) (*btcjson.GetBlockVerboseTxResult, error)
+	GetCFilter(blockHash *chainhash.Hash) ([]byte, error)
 	GetUTXOs(minVal, maxCount int64) ([]btcjson.ListUnspentResult, error)
 	GetAllUTXOs() ([]btcjson.ListUnspentResult, error)
 	GetTxConfirmations(txHashHex string) (int64, error)
+	GetTx(txHashHex string) (*btcjson.TxRawResult, error)
+	GetTxOut(txHashHex string, vout uint32) (*btcjson.GetTxOutResult, error)
 	SendTx(tx *wire.MsgTx) (*chainhash.Hash, error)
 }
 
@@ -57,6 +61,10 @@ func (c *BchClient) GetBlockCount() (int64, error) {
 	return c.client.GetBlockCount()
 }
 
+func (c *BchClient) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	return c.client.GetBlockHash(height)
+}
+
 func (c *BchClient) GetBlock(height int64) (*btcjson.GetBlockVerboseTxResult, error) {
 	blockHash, err := c.client.GetBlockHash(height)
 	if err != nil {
@@ -69,6 +77,15 @@ func (c *BchClient) GetBlock(height int64) (*btcjson.GetBlockVerboseTxResult, er
 	return block, err
 }
 
+// GetCFilter returns the basic compact filter of a block, serialized with N
+func (c *BchClient) GetCFilter(blockHash *chainhash.Hash) ([]byte, error) {
+	filter, err := c.client.GetCFilter(blockHash, wire.GCSFilterRegular)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Data, nil
+}
+
 func (c *BchClient) GetAllUTXOs() ([]btcjson.ListUnspentResult, error) {
 	minConf := 0
 	maxConf := 9999999
@@ -91,6 +108,15 @@ func (c *BchClient) GetUTXOs(minVal, maxCount int64) ([]btcjson.ListUnspentResul
 func findUTXOs(allUTXOs []btcjson.ListUnspentResult,
 	minVal, maxCount int64) ([]btcjson.ListUnspentResult, error) {
 
+	// sort by txid and vout first, so replicas select the same UTXOs
+	// whatever order their nodes list them in
+	slices.SortFunc(allUTXOs, func(a, b btcjson.ListUnspentResult) bool {
+		if a.TxID != b.TxID {
+			return a.TxID < b.TxID
+		}
+		return a.Vout < b.Vout
+	})
+
 	// try to find one
 	for _, unspent := range allUTXOs {
 		val := utxoAmtToSats(unspent.Amount)
@@ -100,7 +126,7 @@ func findUTXOs(allUTXOs []btcjson.ListUnspentResult,
 	}
 
 	// sort by value DESC
-	slices.SortFunc(allUTXOs, func(a, b btcjson.ListUnspentResult) bool {
+	slices.SortStableFunc(allUTXOs, func(a, b btcjson.ListUnspentResult) bool {
 		return a.Amount > b.Amount
 	})
 
@@ -137,6 +163,25 @@ func (c *BchClient) GetTxConfirmations(txHashHex string) (int64, error) {
 	return int64(tx.Confirmations), nil
 }
 
+func (c *BchClient) GetTx(txHashHex string) (*btcjson.TxRawResult, error) {
+	var txHash chainhash.Hash
+	err := chainhash.Decode(&txHash, txHashHex)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.GetRawTransactionVerbose(&txHash)
+}
+
+// returns nil if the output is spent (including by mempool txs) or does not exist
+func (c *BchClient) GetTxOut(txHashHex string, vout uint32) (*btcjson.GetTxOutResult, error) {
+	var txHash chainhash.Hash
+	err := chainhash.Decode(&txHash, txHashHex)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.GetTxOut(&txHash, vout, true)
+}
+
 func (c *BchClient) SendTx(tx *wire.MsgTx) (*chainhash.Hash, error) {
 	return c.client.SendRawTransaction(tx, false)
 }
@@ -1,12 +1,18 @@
 package bot
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 
 	gethcmn "github.com/ethereum/go-ethereum/common"
 	"github.com/gcash/bchd/btcjson"
 	"github.com/gcash/bchd/chaincfg/chainhash"
+	"github.com/gcash/bchd/txscript"
 	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil/gcs/builder"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
 )
 
 var _ IBchClient = (*MockBchClient)(nil)
@@ -16,6 +22,15 @@ type MockBchClient struct {
 	hTo           int64
 	blocks        map[int64]*wire.MsgBlock
 	confirmations map[string]int64
+	sentTxs       []*wire.MsgTx
+	utxos         []btcjson.ListUnspentResult
+	txs           map[string]*wire.MsgTx
+	spentOutputs  map[string]bool // txid:vout
+	sendErr       error           // returned by SendTx if set
+
+	cfilters bool // GetCFilter is supported
+
+	getBlockCalls int
 }
 
 func newMockBchClient(hFrom, hTo int64) *MockBchClient {
@@ -24,6 +39,8 @@ func newMockBchClient(hFrom, hTo int64) *MockBchClient {
 		hTo:           hTo,
 		blocks:        map[int64]*wire.MsgBlock{},
 		confirmations: map[string]int64{},
+		txs:           map[string]*wire.MsgTx{},
+		spentOutputs:  map[string]bool{},
 	}
 	for h := hFrom; h <= hTo; h++ {
 		cli.blocks[h] = &wire.MsgBlock{}
@@ -35,18 +52,75 @@ func (c *MockBchClient) GetBlockCount() (int64, error) {
 	return c.hTo, nil
 }
 
+func (c *MockBchClient) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	if height < c.hFrom || height > c.hTo {
+		return nil, fmt.Errorf("no block#%d", height)
+	}
+	hash := mockBlockHash(height, c.blocks[height])
+	return &hash, nil
+}
+
 func (c *MockBchClient) GetBlock(height int64) (*btcjson.GetBlockVerboseTxResult, error) {
 	if height < c.hFrom || height > c.hTo {
 		return nil, fmt.Errorf("no block#%d", height)
 	}
-	return msgBlockToVerbose(c.blocks[height]), nil
+	c.getBlockCalls++
+	block := msgBlockToVerbose(c.blocks[height])
+	block.Hash = mockBlockHash(height, c.blocks[height]).String()
+	return block, nil
 }
 
-func (*MockBchClient) GetAllUTXOs() ([]btcjson.ListUnspentResult, error) {
-	return nil, nil
+func (c *MockBchClient) GetCFilter(blockHash *chainhash.Hash) ([]byte, error) {
+	if !c.cfilters {
+		return nil, &btcjson.RPCError{Code: btcjson.ErrRPCNoCFIndex, Message: "The CF index must be enabled for this command"}
+	}
+	for h, block := range c.blocks {
+		if mockBlockHash(h, block) != *blockHash {
+			continue
+		}
+		// same as the basic filter of bchd
+		b := builder.WithKeyHash(blockHash)
+		for i, tx := range block.Transactions {
+			for _, txIn := range tx.TxIn {
+				if i > 0 {
+					var buf bytes.Buffer
+					_ = txIn.PreviousOutPoint.Serialize(&buf)
+					b.AddEntry(buf.Bytes())
+				}
+			}
+			for _, txOut := range tx.TxOut {
+				if len(txOut.PkScript) > 0 && txOut.PkScript[0] != txscript.OP_RETURN {
+					b.AddEntry(txOut.PkScript)
+				}
+			}
+		}
+		filter, err := b.Build()
+		if err != nil {
+			return nil, err
+		}
+		return filter.NBytes()
+	}
+	return nil, fmt.Errorf("block not found: %s", blockHash)
+}
+
+// headers of mock blocks are mostly empty, so the height and txs are hashed
+func mockBlockHash(height int64, block *wire.MsgBlock) chainhash.Hash {
+	data := binary.BigEndian.AppendUint64(nil, uint64(height))
+	for _, tx := range block.Transactions {
+		txHash := tx.TxHash()
+		data = append(data, txHash[:]...)
+	}
+	return chainhash.DoubleHashH(data)
+}
+
+func (c *MockBchClient) GetAllUTXOs() ([]btcjson.ListUnspentResult, error) {
+	return c.utxos, nil
 }
 
 func (c *MockBchClient) GetUTXOs(minVal, maxCount int64) ([]btcjson.ListUnspentResult, error) {
+	if c.utxos != nil {
+		return findUTXOs(c.utxos, minVal, maxCount)
+	}
 	return []btcjson.ListUnspentResult{{
 		TxID:   gethcmn.Hash{'f', 'a', 'k', 'e', 'u', 't', 'x', 'o'}.String(),
 		Vout:   0,
@@ -58,7 +132,32 @@ func (c *MockBchClient) GetTxConfirmations(txHashHex string) (int64, error) {
 	return c.confirmations[txHashHex], nil
 }
 
+func (c *MockBchClient) GetTx(txHashHex string) (*btcjson.TxRawResult, error) {
+	tx := c.txs[txHashHex]
+	if tx == nil {
+		return nil, fmt.Errorf("no tx: %s", txHashHex)
+	}
+	result := msgTxToVerbose(tx)
+	result.Confirmations = uint64(c.confirmations[txHashHex])
+	return &result, nil
+}
+
+func (c *MockBchClient) GetTxOut(txHashHex string, vout uint32) (*btcjson.GetTxOutResult, error) {
+	tx := c.txs[txHashHex]
+	if tx == nil || int(vout) >= len(tx.TxOut) || c.spentOutputs[fmt.Sprintf("%s:%d", txHashHex, vout)] {
+		return nil, nil
+	}
+	return &btcjson.GetTxOutResult{
+		Confirmations: c.confirmations[txHashHex],
+		Value:         float64(tx.TxOut[vout].Value) / 1e8,
+	}, nil
+}
+
 func (c *MockBchClient) SendTx(tx *wire.MsgTx) (*chainhash.Hash, error) {
+	if c.sendErr != nil {
+		return nil, c.sendErr
+	}
+	c.sentTxs = append(c.sentTxs, tx)
 	txHash := tx.TxHash()
 	return &txHash, nil
 }
@@ -71,6 +170,7 @@ func msgBlockToVerbose(block *wire.MsgBlock) *btcjson.GetBlockVerboseTxResult {
 func msgTxToVerbose(tx *wire.MsgTx) btcjson.TxRawResult {
 	return btcjson.TxRawResult{
 		Txid: tx.TxHash().String(),
+		Hex:  htlcbch.MsgTxToHex(tx),
 		Vin:  cast(tx.TxIn, txInToVin),
 		Vout: cast(tx.TxOut, txOutToVout),
 	}
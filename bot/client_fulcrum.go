@@ -0,0 +1,289 @@
+package bot
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gcash/bchd/btcjson"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	fulcrumProtocolVersion = "1.4"
+	fulcrumMaxLineSize     = 32 << 20
+)
+
+var errFulcrumDisconnected = errors.New("disconnected from Fulcrum")
+
+// IFulcrumClient watches BCH addresses by Electrum scripthash subscriptions
+type IFulcrumClient interface {
+	SubscribeScriptHash(scriptHash string) error
+	UnsubscribeScriptHash(scriptHash string) error
+	ChangedScriptHashes() []string
+	GetScriptHashHistory(scriptHash string) ([]FulcrumTxRef, error)
+	GetTx(txHash string) (*btcjson.TxRawResult, error)
+	Ping() error
+}
+
+type FulcrumTxRef struct {
+	TxHash string `json:"tx_hash"`
+	Height int64  `json:"height"` // 0 or -1 means in mempool
+}
+
+type fulcrumMsg struct {
+	Id     *uint64           `json:"id"`
+	Result json.RawMessage   `json:"result"`
+	Error  *fulcrumError     `json:"error"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type fulcrumError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// FulcrumClient talks Electrum protocol (newline delimited JSON-RPC) with a Fulcrum server.
+// It reconnects on demand, and subscriptions are restored after reconnecting.
+type FulcrumClient struct {
+	addr    string
+	useTLS  bool
+	timeout time.Duration
+
+	mu         sync.Mutex
+	conn       net.Conn
+	nextId     uint64
+	pending    map[uint64]chan *fulcrumMsg
+	subscribed map[string]bool // scripthash => true
+	changed    map[string]bool // scripthash => true, status changed since last ChangedScriptHashes()
+}
+
+func NewFulcrumClient(fulcrumUrl string, timeout time.Duration) (*FulcrumClient, error) {
+	u, err := url.Parse(fulcrumUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Fulcrum URL: %w", err)
+	}
+	if (u.Scheme != "tcp" && u.Scheme != "ssl") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Fulcrum URL, expected tcp://host:port or ssl://host:port: %s", fulcrumUrl)
+	}
+	return &FulcrumClient{
+		addr:       u.Host,
+		useTLS:     u.Scheme == "ssl",
+		timeout:    timeout,
+		pending:    map[uint64]chan *fulcrumMsg{},
+		subscribed: map[string]bool{},
+		changed:    map[string]bool{},
+	}, nil
+}
+
+// P2SHScriptHash returns Electrum scripthash of a P2SH address:
+// reversed sha256 of its locking script, in hex
+func P2SHScriptHash(scriptHash20 []byte) string {
+	pkScript := append(append([]byte{0xa9, 0x14}, scriptHash20...), 0x87)
+	h := sha256.Sum256(pkScript)
+	for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+		h[i], h[j] = h[j], h[i]
+	}
+	return toHex(h[:])
+}
+
+func (c *FulcrumClient) SubscribeScriptHash(scriptHash string) error {
+	// notifications may arrive right after the response,
+	// and it is kept to be retried after reconnecting if the call fails
+	c.mu.Lock()
+	c.subscribed[scriptHash] = true
+	c.mu.Unlock()
+
+	var status *string
+	if err := c.call("blockchain.scripthash.subscribe", []any{scriptHash}, &status); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if status != nil {
+		c.changed[scriptHash] = true // has history already
+	}
+	return nil
+}
+
+func (c *FulcrumClient) UnsubscribeScriptHash(scriptHash string) error {
+	c.mu.Lock()
+	delete(c.subscribed, scriptHash)
+	delete(c.changed, scriptHash)
+	c.mu.Unlock()
+
+	var ok bool
+	return c.call("blockchain.scripthash.unsubscribe", []any{scriptHash}, &ok)
+}
+
+func (c *FulcrumClient) ChangedScriptHashes() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scriptHashes := make([]string, 0, len(c.changed))
+	for scriptHash := range c.changed {
+		scriptHashes = append(scriptHashes, scriptHash)
+	}
+	c.changed = map[string]bool{}
+	return scriptHashes
+}
+
+func (c *FulcrumClient) GetScriptHashHistory(scriptHash string) (history []FulcrumTxRef, err error) {
+	err = c.call("blockchain.scripthash.get_history", []any{scriptHash}, &history)
+	return
+}
+
+func (c *FulcrumClient) GetTx(txHash string) (tx *btcjson.TxRawResult, err error) {
+	err = c.call("blockchain.transaction.get", []any{txHash, true}, &tx)
+	if err == nil && tx == nil {
+		err = fmt.Errorf("tx not found: %s", txHash)
+	}
+	return
+}
+
+func (c *FulcrumClient) Ping() error {
+	var result any
+	return c.call("server.ping", []any{}, &result)
+}
+
+func (c *FulcrumClient) call(method string, params []any, result any) error {
+	conn, isNew, err := c.getConn()
+	if err != nil {
+		return err
+	}
+	if isNew {
+		if err = c.onConnected(); err != nil {
+			c.closeConn(conn)
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	id := c.nextId
+	c.nextId++
+	respCh := make(chan *fulcrumMsg, 1)
+	c.pending[id] = respCh
+	c.mu.Unlock()
+
+	req, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	_ = conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err = conn.Write(append(req, '\n')); err != nil {
+		c.closeConn(conn)
+		return err
+	}
+
+	select {
+	case resp := <-respCh:
+		if resp == nil {
+			return errFulcrumDisconnected
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s failed: %s (%d)", method, resp.Error.Message, resp.Error.Code)
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-time.After(c.timeout):
+		c.closeConn(conn)
+		return fmt.Errorf("%s timeout", method)
+	}
+}
+
+func (c *FulcrumClient) getConn() (net.Conn, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return c.conn, false, nil
+	}
+
+	dialer := &net.Dialer{Timeout: c.timeout}
+	var conn net.Conn
+	var err error
+	if c.useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.addr, nil)
+	} else {
+		conn, err = dialer.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	log.Info("connected to Fulcrum: ", c.addr)
+	c.conn = conn
+	go c.readLoop(conn)
+	return conn, true, nil
+}
+
+// negotiate protocol version and restore subscriptions,
+// their status may have changed while disconnected
+func (c *FulcrumClient) onConnected() error {
+	var version []string
+	if err := c.call("server.version", []any{"asbot", fulcrumProtocolVersion}, &version); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	scriptHashes := make([]string, 0, len(c.subscribed))
+	for scriptHash := range c.subscribed {
+		scriptHashes = append(scriptHashes, scriptHash)
+	}
+	c.mu.Unlock()
+
+	for _, scriptHash := range scriptHashes {
+		if err := c.SubscribeScriptHash(scriptHash); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.changed[scriptHash] = true
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+func (c *FulcrumClient) readLoop(conn net.Conn) {
+	defer c.closeConn(conn)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), fulcrumMaxLineSize)
+	for scanner.Scan() {
+		var msg fulcrumMsg
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Warn("invalid msg from Fulcrum: ", err)
+			continue
+		}
+
+		c.mu.Lock()
+		if msg.Id != nil {
+			if respCh, ok := c.pending[*msg.Id]; ok {
+				respCh <- &msg
+				delete(c.pending, *msg.Id)
+			}
+		} else if msg.Method == "blockchain.scripthash.subscribe" && len(msg.Params) > 0 {
+			var scriptHash string
+			if json.Unmarshal(msg.Params[0], &scriptHash) == nil && c.subscribed[scriptHash] {
+				c.changed[scriptHash] = true
+			}
+		}
+		c.mu.Unlock()
+	}
+	log.Warn("disconnected from Fulcrum: ", scanner.Err())
+}
+
+// close the connection and fail all pending calls
+func (c *FulcrumClient) closeConn(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != conn {
+		return // closed already
+	}
+	_ = conn.Close()
+	c.conn = nil
+	for id, respCh := range c.pending {
+		respCh <- nil
+		delete(c.pending, id)
+	}
+}
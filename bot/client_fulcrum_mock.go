@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/wire"
+)
+
+var _ IFulcrumClient = (*MockFulcrumClient)(nil)
+
+type MockFulcrumClient struct {
+	subscribed map[string]bool
+	changed    []string
+	history    map[string][]FulcrumTxRef
+	txs        map[string]*wire.MsgTx
+}
+
+func newMockFulcrumClient() *MockFulcrumClient {
+	return &MockFulcrumClient{
+		subscribed: map[string]bool{},
+		history:    map[string][]FulcrumTxRef{},
+		txs:        map[string]*wire.MsgTx{},
+	}
+}
+
+// add tx to the history of the scripthash and notify the change
+func (c *MockFulcrumClient) addTx(scriptHash string, tx *wire.MsgTx) {
+	txHash := tx.TxHash().String()
+	c.txs[txHash] = tx
+	c.history[scriptHash] = append(c.history[scriptHash], FulcrumTxRef{TxHash: txHash})
+	if c.subscribed[scriptHash] {
+		c.changed = append(c.changed, scriptHash)
+	}
+}
+
+func (c *MockFulcrumClient) SubscribeScriptHash(scriptHash string) error {
+	c.subscribed[scriptHash] = true
+	if len(c.history[scriptHash]) > 0 {
+		c.changed = append(c.changed, scriptHash)
+	}
+	return nil
+}
+
+func (c *MockFulcrumClient) UnsubscribeScriptHash(scriptHash string) error {
+	delete(c.subscribed, scriptHash)
+	return nil
+}
+
+func (c *MockFulcrumClient) ChangedScriptHashes() []string {
+	changed := c.changed
+	c.changed = nil
+	return changed
+}
+
+func (c *MockFulcrumClient) GetScriptHashHistory(scriptHash string) ([]FulcrumTxRef, error) {
+	return c.history[scriptHash], nil
+}
+
+func (c *MockFulcrumClient) GetTx(txHash string) (*btcjson.TxRawResult, error) {
+	tx, ok := c.txs[txHash]
+	if !ok {
+		return nil, fmt.Errorf("tx not found: %s", txHash)
+	}
+	verbose := msgTxToVerbose(tx)
+	return &verbose, nil
+}
+
+func (c *MockFulcrumClient) Ping() error {
+	return nil
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFulcrumClient(t *testing.T) {
+	_, err := NewFulcrumClient("http://127.0.0.1:50001", time.Second)
+	require.ErrorContains(t, err, "expected tcp://host:port or ssl://host:port")
+
+	cli, err := NewFulcrumClient("ssl://127.0.0.1:50002", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:50002", cli.addr)
+	require.True(t, cli.useTLS)
+}
+
+func TestFulcrumClient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	// a fake Fulcrum server which notifies the status change right after subscription
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					var req struct {
+						Id     uint64 `json:"id"`
+						Method string `json:"method"`
+						Params []any  `json:"params"`
+					}
+					_ = json.Unmarshal(scanner.Bytes(), &req)
+					var result any
+					switch req.Method {
+					case "server.version":
+						result = []string{"Fulcrum 1.9.0", "1.4"}
+					case "blockchain.scripthash.subscribe":
+						result = nil
+					case "blockchain.scripthash.get_history":
+						result = []FulcrumTxRef{{TxHash: "aa", Height: 100}, {TxHash: "bb", Height: 0}}
+					case "blockchain.transaction.get":
+						result = map[string]any{"txid": req.Params[0], "hex": "00"}
+					case "server.ping":
+						result = nil
+					default:
+						_, _ = fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"unknown method"}}`+"\n", req.Id)
+						continue
+					}
+					resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.Id, "result": result})
+					_, _ = conn.Write(append(resp, '\n'))
+					if req.Method == "blockchain.scripthash.subscribe" {
+						_, _ = fmt.Fprintf(conn, `{"jsonrpc":"2.0","method":"blockchain.scripthash.subscribe","params":["%s","abcd"]}`+"\n", req.Params[0])
+					}
+				}
+			}()
+		}
+	}()
+
+	cli, err := NewFulcrumClient("tcp://"+ln.Addr().String(), 5*time.Second)
+	require.NoError(t, err)
+	require.NoError(t, cli.Ping())
+	require.NoError(t, cli.SubscribeScriptHash("sh1"))
+	require.Eventually(t, func() bool {
+		cli.mu.Lock()
+		defer cli.mu.Unlock()
+		return cli.changed["sh1"]
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"sh1"}, cli.ChangedScriptHashes())
+	require.Empty(t, cli.ChangedScriptHashes())
+
+	history, err := cli.GetScriptHashHistory("sh1")
+	require.NoError(t, err)
+	require.Equal(t, []FulcrumTxRef{{TxHash: "aa", Height: 100}, {TxHash: "bb", Height: 0}}, history)
+	tx, err := cli.GetTx("aa")
+	require.NoError(t, err)
+	require.Equal(t, "aa", tx.Txid)
+	err = cli.UnsubscribeScriptHash("sh1")
+	require.ErrorContains(t, err, "unknown method")
+
+	// subscriptions are restored after reconnecting
+	require.NoError(t, cli.SubscribeScriptHash("sh2"))
+	cli.closeConn(cli.conn)
+	require.NoError(t, cli.Ping())
+	require.Contains(t, cli.ChangedScriptHashes(), "sh2")
+}
@@ -3,6 +3,8 @@ package bot
 import (
 	"context"
 	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -27,37 +29,65 @@ const (
 	SwapLocked
 	SwapUnlocked
 	SwapRefunded
+
+	swapStateUnknown = 0xff // not queried
 )
 
+var errNoSbchPrivKey = errors.New("no sBCH private key (observer mode)")
+
+// view calls aggregated by one multicall, each getSwapState() costs about 10k gas
+const maxMulticallBatch = 100
+
+// SwapStateKey identifies a swap in an HTLC contract
+type SwapStateKey struct {
+	Sender   common.Address
+	HashLock common.Hash
+}
+
 var _ ISbchClient = (*SbchClient)(nil)
+var _ IEvmTxSender = (*SbchClient)(nil)
 
 type ISbchClient interface {
 	getBlockNumber() (uint64, error)
 	getBlockTimeLatest() (uint64, error)
+	getBalance() (*big.Int, error)
 	getTxTime(txHash common.Hash) (uint64, error)
+	getTxReceipt(txHash common.Hash) (*types.Receipt, error)
 	getHtlcLogs(fromBlock, toBlock uint64) ([]types.Log, error)
 	lockSbchToHtlc(userEvmAddr common.Address, hashLock common.Hash, timeLock uint32, amt *big.Int) (*common.Hash, error)
 	unlockSbchFromHtlc(senderAddr common.Address, hashLock common.Hash, secret common.Hash) (*common.Hash, error)
 	refundSbchFromHtlc(senderAddr common.Address, hashLock common.Hash) (*common.Hash, error)
 	getSwapState(senderAddr common.Address, hashLock common.Hash) (uint8, error)
+	getSwapStates(keys []SwapStateKey) ([]uint8, error)
 	getMarketMakerInfo(addr common.Address) (*htlcsbch.MarketMakerInfo, error)
+	getRawTx(txHash common.Hash) ([]byte, error)
+	forHtlc(htlcAddr common.Address) ISbchClient
+	lockTokenToHtlc(tokenAddr, userEvmAddr common.Address, hashLock common.Hash, timeLock uint32, amt *big.Int) (*common.Hash, error)
+	getTokenBalance(tokenAddr, owner common.Address) (*big.Int, error)
+	getLatestRoundData(feedAddr common.Address) (*htlcsbch.RoundData, error)
+	transferSbch(to common.Address, amt *big.Int) (*common.Hash, error)
+	sendData(to common.Address, data []byte) (*common.Hash, error)
 }
 
 type SbchClient struct {
-	client   *ethclient.Client
-	timeout  time.Duration
-	privKey  *ecdsa.PrivateKey
-	botAddr  common.Address
-	htlcAddr common.Address
-	chainId  *big.Int
-	gasPrice *big.Int
+	client         *ethclient.Client
+	timeout        time.Duration
+	privKey        *ecdsa.PrivateKey
+	botAddr        common.Address
+	htlcAddr       common.Address
+	tokenHtlcAddrs []common.Address // logs of these HTLCs are also watched
+	chain          *EvmChain
+	chainId        *big.Int // checked against RPC
+	nonceMgr       *NonceManager
 }
 
 func newSbchClient(
 	rawUrl string, timeout time.Duration,
 	privKey *ecdsa.PrivateKey,
 	htlcAddr common.Address,
-	gasPrice *big.Int,
+	tokenHtlcAddrs []common.Address,
+	chain *EvmChain,
+	db DB,
 ) (*SbchClient, error) {
 
 	client, err := ethclient.Dial(rawUrl)
@@ -65,15 +95,19 @@ func newSbchClient(
 		return nil, err
 	}
 
-	botAddr := crypto.PubkeyToAddress(privKey.PublicKey)
-	return &SbchClient{
-		client:   client,
-		timeout:  timeout,
-		privKey:  privKey,
-		botAddr:  botAddr,
-		htlcAddr: htlcAddr,
-		gasPrice: gasPrice,
-	}, nil
+	c := &SbchClient{
+		client:         client,
+		timeout:        timeout,
+		privKey:        privKey,
+		htlcAddr:       htlcAddr,
+		tokenHtlcAddrs: tokenHtlcAddrs,
+		chain:          chain,
+	}
+	if privKey != nil {
+		c.botAddr = crypto.PubkeyToAddress(privKey.PublicKey)
+		c.nonceMgr = newNonceManager(c, db, chain.Name, c.botAddr)
+	}
+	return c, nil
 }
 
 func (c *SbchClient) getBlockNumber() (uint64, error) {
@@ -92,6 +126,12 @@ func (c *SbchClient) getBlockTimeLatest() (uint64, error) {
 	return header.Time, nil
 }
 
+func (c *SbchClient) getBalance() (*big.Int, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
+	defer cancelFn()
+	return c.client.BalanceAt(ctx, c.botAddr, nil)
+}
+
 func (c *SbchClient) getTxTime(txHash common.Hash) (uint64, error) {
 	ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
 	defer cancelFn()
@@ -114,10 +154,70 @@ func (c *SbchClient) getHtlcLogs(fromBlock, toBlock uint64) ([]types.Log, error)
 	return c.client.FilterLogs(ctx, ethereum.FilterQuery{
 		FromBlock: big.NewInt(int64(fromBlock)),
 		ToBlock:   big.NewInt(int64(toBlock)),
-		Addresses: []common.Address{c.htlcAddr},
+		Addresses: append([]common.Address{c.htlcAddr}, c.tokenHtlcAddrs...),
 	})
 }
 
+// returns a client which talks to another HTLC contract, the nonce manager is shared
+func (c *SbchClient) forHtlc(htlcAddr common.Address) ISbchClient {
+	if htlcAddr == c.htlcAddr {
+		return c
+	}
+	c2 := *c
+	c2.htlcAddr = htlcAddr
+	return &c2
+}
+
+func (c *SbchClient) getTokenBalance(tokenAddr, owner common.Address) (*big.Int, error) {
+	callData, err := htlcsbch.PackBalanceOf(owner)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.callView(tokenAddr, callData)
+	if err != nil {
+		return nil, err
+	}
+	return htlcsbch.UnpackBalanceOf(result)
+}
+
+func (c *SbchClient) getTokenAllowance(tokenAddr, spender common.Address) (*big.Int, error) {
+	callData, err := htlcsbch.PackAllowance(c.botAddr, spender)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.callView(tokenAddr, callData)
+	if err != nil {
+		return nil, err
+	}
+	return htlcsbch.UnpackAllowance(result)
+}
+
+// query Chainlink-style price feed
+func (c *SbchClient) getLatestRoundData(feedAddr common.Address) (*htlcsbch.RoundData, error) {
+	callData, err := htlcsbch.PackLatestRoundData()
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.callView(feedAddr, callData)
+	if err != nil {
+		return nil, err
+	}
+	return htlcsbch.UnpackLatestRoundData(result)
+}
+
+func (c *SbchClient) callView(to common.Address, callData []byte) ([]byte, error) {
+	msg := ethereum.CallMsg{
+		From: c.botAddr,
+		To:   &to,
+		Gas:  500_000,
+		Data: callData,
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
+	defer cancelFn()
+	return c.client.CallContract(ctx, msg, nil)
+}
+
 func (c *SbchClient) getSwapState(senderAddr common.Address, hashLock common.Hash) (uint8, error) {
 	callData, err := htlcsbch.PackGetSwapState(senderAddr, hashLock)
 	if err != nil {
@@ -141,6 +241,61 @@ func (c *SbchClient) getSwapState(senderAddr common.Address, hashLock common.Has
 	return htlcsbch.UnpackGetSwapState(result)
 }
 
+// query states of many swaps, batched by the Multicall3 contract if it is configured
+func (c *SbchClient) getSwapStates(keys []SwapStateKey) ([]uint8, error) {
+	multicallAddr := c.chain.MulticallAddr
+	if multicallAddr == (common.Address{}) {
+		states := make([]uint8, len(keys))
+		for i, key := range keys {
+			state, err := c.getSwapState(key.Sender, key.HashLock)
+			if err != nil {
+				return nil, err
+			}
+			states[i] = state
+		}
+		return states, nil
+	}
+
+	states := make([]uint8, 0, len(keys))
+	for start := 0; start < len(keys); start += maxMulticallBatch {
+		end := start + maxMulticallBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+		calls := make([]htlcsbch.Call3, end-start)
+		for i, key := range keys[start:end] {
+			callData, err := htlcsbch.PackGetSwapState(key.Sender, key.HashLock)
+			if err != nil {
+				return nil, err
+			}
+			calls[i] = htlcsbch.Call3{Target: c.htlcAddr, CallData: callData}
+		}
+		callData, err := htlcsbch.PackAggregate3(calls)
+		if err != nil {
+			return nil, err
+		}
+		result, err := c.callView(multicallAddr, callData)
+		if err != nil {
+			return nil, err
+		}
+		results, err := htlcsbch.UnpackAggregate3(result)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) != len(calls) {
+			return nil, fmt.Errorf("multicall results mismatch: %d != %d", len(results), len(calls))
+		}
+		for _, r := range results {
+			state, err := htlcsbch.UnpackGetSwapState(r.ReturnData)
+			if err != nil {
+				return nil, err
+			}
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
 func (c *SbchClient) getMarketMakerInfo(addr common.Address) (*htlcsbch.MarketMakerInfo, error) {
 	callData, err := htlcsbch.PackGetMarketMaker(addr)
 	if err != nil {
@@ -186,6 +341,45 @@ func (c *SbchClient) lockSbchToHtlc(
 	return c.callHtlc(amt, data)
 }
 
+// call approve() if needed, then call lock() of token HTLC
+func (c *SbchClient) lockTokenToHtlc(
+	tokenAddr common.Address,
+	userEvmAddr common.Address,
+	hashLock common.Hash,
+	timeLock uint32,
+	amt *big.Int,
+) (*common.Hash, error) {
+	bchAddr := common.Address{}
+	log.Info("lock token to HTLC",
+		", token: ", tokenAddr.String(),
+		", userEvmAddr: ", userEvmAddr.String(),
+		", hashLock: ", hashLock.String(),
+		", timeLock: ", timeLock,
+		", amt :", amt.String())
+
+	allowance, err := c.getTokenAllowance(tokenAddr, c.htlcAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowance: %w", err)
+	}
+	if allowance.Cmp(amt) < 0 {
+		// approve once, so that the following locks need only one tx
+		maxAmt := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+		data, err := htlcsbch.PackApprove(c.htlcAddr, maxAmt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack calldata: %w", err)
+		}
+		if _, err = c.callContract(tokenAddr, big.NewInt(0), data); err != nil {
+			return nil, fmt.Errorf("failed to approve token: %w", err)
+		}
+	}
+
+	data, err := htlcsbch.PackLockToken(userEvmAddr, hashLock, timeLock, bchAddr, amt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack calldata: %w", err)
+	}
+	return c.callHtlc(big.NewInt(0), data)
+}
+
 // call unlock()
 func (c *SbchClient) unlockSbchFromHtlc(
 	senderAddr common.Address,
@@ -218,20 +412,34 @@ func (c *SbchClient) refundSbchFromHtlc(
 	return c.callHtlc(big.NewInt(0), data)
 }
 
+func (c *SbchClient) transferSbch(to common.Address, amt *big.Int) (*common.Hash, error) {
+	log.Info("transferSbch, to: ", to.String(), ", amt: ", amt.String())
+	return c.callContract(to, amt, nil)
+}
+
+// send a tx of 0 value which carries data
+func (c *SbchClient) sendData(to common.Address, data []byte) (*common.Hash, error) {
+	log.Info("sendData, to: ", to.String(), ", data: ", hex.EncodeToString(data))
+	return c.callContract(to, big.NewInt(0), data)
+}
+
 func (c *SbchClient) callHtlc(val *big.Int, data []byte) (*common.Hash, error) {
-	chainID, err := c.getChainId()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	return c.callContract(c.htlcAddr, val, data)
+}
+
+func (c *SbchClient) callContract(to common.Address, val *big.Int, data []byte) (*common.Hash, error) {
+	if c.privKey == nil {
+		return nil, errNoSbchPrivKey
 	}
 
-	nonce, err := c.getNonce(c.botAddr)
+	chainID, err := c.getChainId()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get nonce: %w", err)
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
 	}
 
 	gasLimit, err := c.estimateGas(ethereum.CallMsg{
 		From:  c.botAddr,
-		To:    &c.htlcAddr,
+		To:    &to,
 		Value: val,
 		Data:  data,
 	})
@@ -240,31 +448,26 @@ func (c *SbchClient) callHtlc(val *big.Int, data []byte) (*common.Hash, error) {
 	}
 
 	gasLimit = gasLimit * 120 / 100
-	signer := types.NewEIP155Signer(chainID)
-	tx, err := types.SignNewTx(c.privKey, signer, &types.LegacyTx{
-		Nonce:    nonce,
-		To:       &c.htlcAddr,
-		Value:    val,
-		Gas:      gasLimit,
-		GasPrice: c.gasPrice,
-		Data:     data,
+	signer := types.LatestSignerForChainID(chainID)
+	tx, err := c.nonceMgr.sendTx(func(nonce uint64) (*types.Transaction, error) {
+		txData, err := c.newTxData(nonce, to, val, gasLimit, data)
+		if err != nil {
+			return nil, err
+		}
+		return types.SignNewTx(c.privKey, signer, txData)
 	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to sign tx: %w", err)
-	}
-
-	err = c.sendTx(tx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send tx: %w", err)
 	}
 
 	txHash := tx.Hash()
-	log.Info("tx sent, hash: ", txHash.String())
+	log.Info("tx sent, hash: ", txHash.String(), ", nonce: ", tx.Nonce())
 
 	receipt, err := c.waitTxReceipt(txHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get receipt: %w", err)
 	}
+	c.nonceMgr.txMined(tx)
 	if receipt.Status != types.ReceiptStatusSuccessful {
 		return nil, fmt.Errorf("tx failed! tx hash: %s", txHash.String())
 	}
@@ -280,10 +483,14 @@ func (c *SbchClient) getChainId() (*big.Int, error) {
 	ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
 	defer cancelFn()
 	chainId, err := c.client.ChainID(ctx)
-	if err == nil {
-		c.chainId = chainId
+	if err != nil {
+		return nil, err
+	}
+	if c.chain != nil && c.chain.ChainId != nil && c.chain.ChainId.Cmp(chainId) != 0 {
+		return nil, fmt.Errorf("chain ID not match: %s != %s", chainId, c.chain.ChainId)
 	}
-	return chainId, err
+	c.chainId = chainId
+	return chainId, nil
 }
 
 func (c *SbchClient) getNonce(addr common.Address) (uint64, error) {
@@ -292,12 +499,46 @@ func (c *SbchClient) getNonce(addr common.Address) (uint64, error) {
 	return c.client.NonceAt(ctx, addr, nil)
 }
 
+func (c *SbchClient) getPendingNonce(addr common.Address) (uint64, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
+	defer cancelFn()
+	return c.client.PendingNonceAt(ctx, addr)
+}
+
+// send 0 sBCH to self, only used to fill nonce gap
+func (c *SbchClient) makeCancelTx(nonce uint64) (*types.Transaction, error) {
+	if c.privKey == nil {
+		return nil, errNoSbchPrivKey
+	}
+
+	chainID, err := c.getChainId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	txData, err := c.newTxData(nonce, c.botAddr, big.NewInt(0), 21000, nil)
+	if err != nil {
+		return nil, err
+	}
+	return types.SignNewTx(c.privKey, types.LatestSignerForChainID(chainID), txData)
+}
+
 func (c *SbchClient) estimateGas(msg ethereum.CallMsg) (uint64, error) {
 	ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
 	defer cancelFn()
 	return c.client.EstimateGas(ctx, msg)
 }
 
+func (c *SbchClient) getRawTx(txHash common.Hash) ([]byte, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
+	defer cancelFn()
+	tx, _, err := c.client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	return tx.MarshalBinary()
+}
+
 func (c *SbchClient) sendTx(tx *types.Transaction) error {
 	ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
 	defer cancelFn()
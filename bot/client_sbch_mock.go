@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/smartbch/atomic-swap-bot/htlcsbch"
@@ -17,6 +19,16 @@ type MockSbchClient struct {
 	hTo     uint64
 	logs    map[uint64][]types.Log
 	txTimes map[common.Hash]uint64
+	states  map[common.Hash]uint8                  // hashLock => swap state
+	balance *big.Int                               // nil means enough
+	tokens  map[common.Address]*big.Int            // token address => balance
+	locked  map[common.Hash]*big.Int               // hashLock => locked token amount
+	rounds  map[common.Address]*htlcsbch.RoundData // price feed address => latest round
+	sent    map[common.Address]*big.Int            // to address => transferred sBCH
+	rawTxs  map[common.Hash][]byte                 // tx hash => raw tx, faked if not set
+	dataTxs [][]byte                               // data of sent 0 value txs
+
+	swapStatesCalls int // batched state reads
 }
 
 func newMockSbchClient(hFrom, hTo, ts uint64) *MockSbchClient {
@@ -26,6 +38,12 @@ func newMockSbchClient(hFrom, hTo, ts uint64) *MockSbchClient {
 		hTo:     hTo,
 		logs:    map[uint64][]types.Log{},
 		txTimes: map[common.Hash]uint64{},
+		states:  map[common.Hash]uint8{},
+		tokens:  map[common.Address]*big.Int{},
+		locked:  map[common.Hash]*big.Int{},
+		rounds:  map[common.Address]*htlcsbch.RoundData{},
+		sent:    map[common.Address]*big.Int{},
+		rawTxs:  map[common.Hash][]byte{},
 	}
 	return cli
 }
@@ -38,10 +56,37 @@ func (c *MockSbchClient) getBlockTimeLatest() (uint64, error) {
 	return c.ts, nil
 }
 
+func (c *MockSbchClient) getBalance() (*big.Int, error) {
+	if c.balance == nil {
+		return satsToWei(21e14), nil
+	}
+	return c.balance, nil
+}
+
 func (c *MockSbchClient) getTxTime(txHash common.Hash) (uint64, error) {
 	return c.txTimes[txHash], nil
 }
 
+func (c *MockSbchClient) getTxReceipt(txHash common.Hash) (*types.Receipt, error) {
+	for _, logs := range c.logs {
+		for _, ethLog := range logs {
+			if ethLog.TxHash == txHash {
+				return &types.Receipt{TxHash: txHash, BlockHash: ethLog.BlockHash,
+					BlockNumber: new(big.Int).SetUint64(ethLog.BlockNumber)}, nil
+			}
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+func (c *MockSbchClient) getRawTx(txHash common.Hash) ([]byte, error) {
+	if rawTx, ok := c.rawTxs[txHash]; ok {
+		return rawTx, nil
+	}
+	// fake raw tx
+	return append([]byte{0xf8}, txHash[:]...), nil
+}
+
 func (c *MockSbchClient) getHtlcLogs(fromBlock, toBlock uint64) ([]types.Log, error) {
 	if fromBlock < c.hFrom || toBlock > c.hTo {
 		return nil, fmt.Errorf("invalid block range")
@@ -65,6 +110,19 @@ func (c *MockSbchClient) lockSbchToHtlc(
 	return &txHash, nil
 }
 
+func (c *MockSbchClient) lockTokenToHtlc(
+	tokenAddr common.Address,
+	userEvmAddr common.Address,
+	hashLock common.Hash,
+	timeLock uint32,
+	amt *big.Int,
+) (*common.Hash, error) {
+	log.Info("lockTokenToHtlc:", tokenAddr, userEvmAddr, hashLock, timeLock, amt)
+	c.locked[hashLock] = amt
+	txHash := common.BytesToHash(reverseBytes(hashLock[:]))
+	return &txHash, nil
+}
+
 func (c *MockSbchClient) unlockSbchFromHtlc(
 	senderAddr common.Address,
 	hashLock common.Hash,
@@ -85,9 +143,52 @@ func (c *MockSbchClient) refundSbchFromHtlc(
 }
 
 func (c *MockSbchClient) getSwapState(senderAddr common.Address, hashLock common.Hash) (uint8, error) {
-	panic("not implemented")
+	return c.states[hashLock], nil
+}
+
+func (c *MockSbchClient) getSwapStates(keys []SwapStateKey) ([]uint8, error) {
+	c.swapStatesCalls++
+	states := make([]uint8, len(keys))
+	for i, key := range keys {
+		states[i] = c.states[key.HashLock]
+	}
+	return states, nil
 }
 
 func (c *MockSbchClient) getMarketMakerInfo(addr common.Address) (*htlcsbch.MarketMakerInfo, error) {
 	panic("not implemented")
 }
+
+func (c *MockSbchClient) transferSbch(to common.Address, amt *big.Int) (*common.Hash, error) {
+	log.Info("transferSbch:", to, amt)
+	c.sent[to] = amt
+	if c.balance != nil {
+		c.balance = new(big.Int).Sub(c.balance, amt)
+	}
+	txHash := common.BytesToHash(to[:])
+	return &txHash, nil
+}
+
+func (c *MockSbchClient) sendData(to common.Address, data []byte) (*common.Hash, error) {
+	c.dataTxs = append(c.dataTxs, data)
+	txHash := crypto.Keccak256Hash(data)
+	return &txHash, nil
+}
+
+func (c *MockSbchClient) forHtlc(htlcAddr common.Address) ISbchClient {
+	return c
+}
+
+func (c *MockSbchClient) getTokenBalance(tokenAddr, owner common.Address) (*big.Int, error) {
+	if bal := c.tokens[tokenAddr]; bal != nil {
+		return bal, nil
+	}
+	return big.NewInt(0), nil
+}
+
+func (c *MockSbchClient) getLatestRoundData(feedAddr common.Address) (*htlcsbch.RoundData, error) {
+	if rd := c.rounds[feedAddr]; rd != nil {
+		return rd, nil
+	}
+	return nil, fmt.Errorf("no price feed: %s", feedAddr.String())
+}
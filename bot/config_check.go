@@ -0,0 +1,310 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+const configCheckTimeout = 10 * time.Second
+
+// ConfigProblem is found by CheckConfig, Field is the JSON key of the option
+type ConfigProblem struct {
+	Field   string
+	Problem string
+	Hint    string // how to fix it
+}
+
+func (p *ConfigProblem) String() string {
+	if p.Hint == "" {
+		return fmt.Sprintf("%s: %s", p.Field, p.Problem)
+	}
+	return fmt.Sprintf("%s: %s (%s)", p.Field, p.Problem, p.Hint)
+}
+
+type configChecker struct {
+	cfg      *Config
+	net      *htlcbch.ChainParams // nil if bch_net is invalid
+	problems []*ConfigProblem
+}
+
+func (c *configChecker) add(field, hint string, err error) {
+	if err != nil {
+		c.problems = append(c.problems, &ConfigProblem{Field: field, Problem: err.Error(), Hint: hint})
+	}
+}
+
+func (c *configChecker) addf(field, hint, format string, args ...any) {
+	c.add(field, hint, fmt.Errorf(format, args...))
+}
+
+// CheckConfig returns all problems of cfg instead of the first one.
+// Keys are only checked if given. RPC endpoints and contracts are checked if online is true.
+func CheckConfig(cfg *Config, online bool) []*ConfigProblem {
+	c := &configChecker{cfg: cfg}
+	c.checkBchNet()
+	c.checkKeys()
+	c.checkAddrs()
+	c.checkParams()
+	c.checkSubConfigs()
+	if online {
+		c.checkBchRpc()
+		c.checkSbchRpc()
+		c.checkEvmPeers()
+	}
+	return c.problems
+}
+
+func (c *configChecker) checkBchNet() {
+	net, err := getBchChainParams(c.cfg.BchNet, c.cfg.DebugMode)
+	c.add("bch_net", "use mainnet|testnet3|testnet4|chipnet|regtest", err)
+	c.net = net
+	_, err = htlcbch.ParseSigType(c.cfg.BchSigType)
+	c.add("bch_sig_type", "use ecdsa|schnorr", err)
+	_, err = htlcbch.ParseSigHashType(c.cfg.BchSigHashType)
+	c.add("bch_sighash_type", "use all|all+anyonecanpay", err)
+}
+
+func (c *configChecker) checkKeys() {
+	cfg := c.cfg
+	if cfg.ObserverMode {
+		return
+	}
+	if c.net != nil && (cfg.SlaveMode || cfg.BchPrivKeyWIF != "") {
+		_, _, _, _, err := loadBchKey(cfg.BchPrivKeyWIF, cfg.BchMasterAddr, c.net, cfg.SlaveMode)
+		if cfg.SlaveMode {
+			c.add("bch_master_addr", "P2PKH cash address of the master bot on "+c.net.Name, err)
+		} else {
+			c.add("bch_key", "WIF of a private key on "+c.net.Name, err)
+		}
+	}
+	if cfg.SbchPrivKeyHex != "" {
+		_, _, err := loadSbchKey(cfg.SbchPrivKeyHex, cfg.SbchMasterAddr, cfg.SlaveMode)
+		c.add("sbch_key", "32 bytes hex without 0x", err)
+	}
+	if cfg.SlaveMode && !gethcmn.IsHexAddress(cfg.SbchMasterAddr) {
+		c.addf("sbch_master_addr", "0x address of the master bot", "invalid address: %q", cfg.SbchMasterAddr)
+	}
+}
+
+func (c *configChecker) checkAddrs() {
+	cfg := c.cfg
+	if !gethcmn.IsHexAddress(cfg.SbchHtlcAddr) || cfg.getSbchHtlcAddr() == (gethcmn.Address{}) {
+		c.addf("sbch_htlc_addr", "0x address of the deployed HTLC contract", "invalid address: %q", cfg.SbchHtlcAddr)
+	}
+	if _, err := cfg.getEvmChain(); err != nil {
+		c.add("sbch_gas_strategy|sbch_multicall_addr", "use fixed|suggested|eip1559 and a 0x address", err)
+	}
+	if c.net == nil {
+		return
+	}
+	treasury, err := newTreasury(cfg, c.net)
+	c.add("bch_treasury_pubkeys", "m must be in [1, n], pubkeys are 33 bytes hex", err)
+	_, err = newColdWallets(cfg, c.net, treasury)
+	c.add("cold_bch_addr|cold_sbch_addr", "cold BCH address must be a P2PKH address on "+c.net.Name, err)
+	_, err = newFeeWallets(cfg, c.net)
+	c.add("fee_bch_addr|fee_sbch_addr", "fee BCH address must be a P2PKH address on "+c.net.Name, err)
+	_, err = newReferral(cfg, c.net)
+	c.add("referral_bch_addr|referral_bps", "a P2PKH address on "+c.net.Name+" with BPS in [1, 9999]", err)
+	_, err = newAffiliates(cfg.Affiliates, c.net)
+	c.add("affiliates", "BCH addresses must be P2PKH addresses on "+c.net.Name, err)
+	if cfg.BchXPub != "" {
+		_, err = newHdPkhs(cfg.BchXPub, cfg.BchXPubLookahead)
+		c.add("bch_xpub", "use an account level xpub", err)
+	}
+}
+
+func (c *configChecker) checkParams() {
+	cfg := c.cfg
+	if cfg.SbchGasStrategy == "" || cfg.SbchGasStrategy == GasStrategyFixed {
+		if cfg.SbchGasPrice <= 0 {
+			c.addf("sbch_gas_price", "1.05 on smartBCH", "must be positive: %v", cfg.SbchGasPrice)
+		}
+	}
+	if cfg.SbchMaxGasPrice < 0 || (cfg.SbchMaxGasPrice > 0 && cfg.SbchMaxGasPrice < cfg.SbchGasPrice) {
+		c.addf("sbch_max_gas_price", "0 means no cap", "less than sbch_gas_price: %v", cfg.SbchMaxGasPrice)
+	}
+	if cfg.BchConfirmations == 0 && !cfg.DebugMode {
+		c.addf("bch_confirmations", "deposits may be double spent, use 1 or more", "zero")
+	}
+	for i, rate := range []uint64{cfg.BchLockFeeRate, cfg.BchUnlockFeeRate, cfg.BchRefundFeeRate} {
+		if rate == 0 {
+			field := []string{"bch_lock_fee_rate", "bch_unlock_fee_rate", "bch_refund_fee_rate"}[i]
+			c.addf(field, "txs below 1 sat/byte are not relayed", "zero")
+		}
+	}
+	if cfg.DbQueryLimit <= 0 {
+		c.addf("db_query_limit", "100 by default", "must be positive: %d", cfg.DbQueryLimit)
+	}
+	if cfg.QuoteValidity == 0 {
+		c.addf("quote_validity", "600 by default", "zero")
+	}
+	if cfg.MaxRiskScore > 100 {
+		c.addf("max_risk_score", "in [1, 100], 0 means disabled", "scores never exceed 100: %d", cfg.MaxRiskScore)
+	}
+	if cfg.LeaderId != "" && cfg.LeaderTTL == 0 {
+		c.addf("leader_ttl", "30 by default", "zero")
+	}
+	if cfg.Bootstrap && !cfg.ObserverMode {
+		c.addf("bootstrap", "set observer too, bootstrap mode builds a swap index and never trades", "not in observer mode")
+	}
+	if cfg.Explorer && !cfg.ObserverMode {
+		c.addf("explorer", "set observer too, explorer mode serves a swap index and never trades", "not in observer mode")
+	}
+	if cfg.HeartbeatUrl != "" {
+		u, err := url.Parse(cfg.HeartbeatUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			c.addf("heartbeat_url", "ping URL of the monitor, e.g. https://hc-ping.com/<uuid>", "invalid URL")
+		}
+	}
+	if cfg.BlockCacheDir != "" && cfg.BlockCacheSize == 0 {
+		c.addf("block_cache_size", "100 by default", "zero")
+	}
+	if cfg.StuckTxBlocks > 0 {
+		c.add("bch_stuck_tx_strategy", "use alert|rebroadcast|cpfp", checkStuckTxStrategy(cfg.StuckTxStrategy))
+	}
+	if cfg.BchBatchReceipts {
+		c.add("bch_batch_max_inputs", "20 by default", checkBatchMaxInputs(cfg.BchBatchMaxInputs))
+	}
+	c.add("sbas_diagnostics", "use off|log|save", checkSbasDiagnostics(cfg.SbasDiagnostics))
+	c.add("negotiation", "penalty BPS in [0, 9999], min_expiration in [1, max_expiration], max_surcharge_bps below 10000",
+		checkNegotiationConfig(cfg.Negotiation))
+	c.add("chaos", "probabilities in [0, 1] with max_delay if delay_prob is set, not on mainnet",
+		checkChaosConfig(cfg.Chaos, c.net))
+	if (cfg.TlsCertFile == "") != (cfg.TlsKeyFile == "") {
+		c.addf("tls_cert_file|tls_key_file", "set both or none", "only one is set")
+	}
+}
+
+func (c *configChecker) checkSubConfigs() {
+	cfg := c.cfg
+	_, err := newTokens(cfg.Tokens)
+	c.add("tokens", "", err)
+	_, err = newAccessList(cfg.AccessListFile)
+	c.add("access_list_file", "", err)
+	_, err = newApiAuth(cfg.ApiKeys, cfg.JwtSecret)
+	c.add("api_keys", "", err)
+	_, err = newHttpPolicy(cfg)
+	c.add("cors_origins|trusted_proxies", "", err)
+	_, err = newWebhookDispatcher(DB{}, cfg.Webhooks, nil)
+	c.add("webhooks", "", err)
+	_, err = newAlertDispatcher(cfg.Alerts, "", nil)
+	c.add("alerts", "type twilio with account_sid, auth_token, from & to, or webhook with url", err)
+	_, err = newEmailNotifier(DB{}, cfg.Email, nil)
+	c.add("email", "smtp_addr as host:port, from as an email address, public_url as http(s) URL", err)
+	for i, peer := range cfg.EvmPeers {
+		field := fmt.Sprintf("evm_peers[%d]", i)
+		if peer.Name == "" || peer.RpcUrl == "" {
+			c.addf(field, "", "missing name or rpc_url")
+		}
+		if !gethcmn.IsHexAddress(peer.HtlcAddr) || !gethcmn.IsHexAddress(peer.HomeHtlcAddr) {
+			c.addf(field, "0x addresses of the deployed HTLC contracts", "invalid htlc_addr or home_htlc_addr")
+		}
+		c.add(field, "use fixed|suggested|eip1559", checkGasStrategy(peer.GasStrategy))
+	}
+}
+
+// the RPC must be reachable and on bch_net
+func (c *configChecker) checkBchRpc() {
+	if c.net == nil {
+		return
+	}
+	cli, err := NewBchClient(c.cfg.BchRpcUrl, nil)
+	if err != nil {
+		c.add("bch_rpc_url", "http(s)://user:pass@host:port", err)
+		return
+	}
+	info, err := cli.client.GetBlockChainInfo()
+	if err != nil {
+		c.add("bch_rpc_url", "is the node running and are the credentials right?",
+			fmt.Errorf("failed to connect: %w", err))
+		return
+	}
+	if !isBchChainOf(info.Chain, c.net) {
+		c.addf("bch_rpc_url", "set bch_net or use another node", "node is on %q, not %s", info.Chain, c.net.Name)
+	}
+}
+
+// chain names reported by getblockchaininfo: main, test, test4, chip, regtest
+func isBchChainOf(chain string, net *htlcbch.ChainParams) bool {
+	switch net {
+	case htlcbch.MainNet:
+		return chain == "main"
+	case htlcbch.RegTest:
+		return chain == "regtest"
+	}
+	return strings.HasPrefix(chain, "test") || strings.HasPrefix(chain, "chip")
+}
+
+// the RPC must be reachable, on the chain ID if set, and contracts must be deployed
+func (c *configChecker) checkSbchRpc() {
+	cfg := c.cfg
+	contracts := [][2]string{{"sbch_htlc_addr", cfg.SbchHtlcAddr}}
+	if cfg.SbchMulticallAddr != "" {
+		contracts = append(contracts, [2]string{"sbch_multicall_addr", cfg.SbchMulticallAddr})
+	}
+	for i, token := range cfg.Tokens {
+		contracts = append(contracts,
+			[2]string{fmt.Sprintf("tokens[%d].addr", i), token.Addr},
+			[2]string{fmt.Sprintf("tokens[%d].htlc_addr", i), token.HtlcAddr})
+	}
+	for i, peer := range cfg.EvmPeers {
+		contracts = append(contracts, [2]string{fmt.Sprintf("evm_peers[%d].home_htlc_addr", i), peer.HomeHtlcAddr})
+	}
+	c.checkEvmRpc("sbch_rpc_url", "sbch_chain_id", cfg.SbchRpcUrl, cfg.SbchChainId, contracts)
+}
+
+func (c *configChecker) checkEvmPeers() {
+	for i, peer := range c.cfg.EvmPeers {
+		if peer.RpcUrl == "" {
+			continue
+		}
+		field := fmt.Sprintf("evm_peers[%d]", i)
+		c.checkEvmRpc(field+".rpc_url", field+".chain_id", peer.RpcUrl, peer.ChainId,
+			[][2]string{{field + ".htlc_addr", peer.HtlcAddr}})
+	}
+}
+
+func (c *configChecker) checkEvmRpc(urlField, chainIdField, rpcUrl string, chainId uint64,
+	contracts [][2]string) { // field & address pairs
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), configCheckTimeout)
+	defer cancelFn()
+	client, err := ethclient.DialContext(ctx, rpcUrl)
+	if err != nil {
+		c.add(urlField, "http(s)://host:port or ws(s)://host:port", err)
+		return
+	}
+	defer client.Close()
+
+	rpcChainId, err := client.ChainID(ctx)
+	if err != nil {
+		c.add(urlField, "is the node running?", fmt.Errorf("failed to connect: %w", err))
+		return
+	}
+	if chainId > 0 && rpcChainId.Uint64() != chainId {
+		c.addf(chainIdField, "use another node or set 0 to accept any", "RPC reports chain ID %s, not %d",
+			rpcChainId, chainId)
+	}
+	for _, contract := range contracts {
+		field, addr := contract[0], contract[1]
+		if !gethcmn.IsHexAddress(addr) {
+			continue // reported by offline checks
+		}
+		code, err := client.CodeAt(ctx, gethcmn.HexToAddress(addr), nil)
+		if err != nil {
+			c.add(urlField, "", fmt.Errorf("failed to get code of %s: %w", addr, err))
+			return
+		}
+		if len(code) == 0 {
+			c.addf(field, "is it deployed on chain "+rpcChainId.String()+"?", "no contract at %s", addr)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gcash/bchutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func getProblemFields(problems []*ConfigProblem) []string {
+	fields := make([]string, len(problems))
+	for i, problem := range problems {
+		fields[i] = problem.Field
+	}
+	return fields
+}
+
+func TestCheckConfig_offline(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SbchHtlcAddr = gethAddr("htlc").String()
+	require.Len(t, CheckConfig(cfg, false), 0)
+
+	wif, err := bchutil.NewWIF(testBchPrivKey, htlcbch.TestNet3.Net, true)
+	require.NoError(t, err)
+	cfg.BchPrivKeyWIF = wif.String()
+	cfg.SbchPrivKeyHex = "not hex"
+	cfg.SbchGasPrice = 0
+	cfg.BchUnlockFeeRate = 0
+	cfg.DbQueryLimit = 0
+	cfg.TlsCertFile = "cert.pem"
+	cfg.ColdSbchAddr = "0x1234"
+	cfg.FeeSbchAddr = "0x1234"
+	cfg.ReferralBPS = 100
+	cfg.Tokens = []TokenConfig{{Symbol: "T"}}
+	problems := CheckConfig(cfg, false)
+	require.Equal(t, []string{
+		"bch_key",
+		"sbch_key",
+		"cold_bch_addr|cold_sbch_addr",
+		"fee_bch_addr|fee_sbch_addr",
+		"referral_bch_addr|referral_bps",
+		"sbch_gas_price",
+		"bch_unlock_fee_rate",
+		"db_query_limit",
+		"tls_cert_file|tls_key_file",
+		"tokens",
+	}, getProblemFields(problems))
+	require.Equal(t, "bch_key: WIF is not for mainnet (WIF of a private key on mainnet)", problems[0].String())
+
+	cfg = DefaultConfig()
+	cfg.BchNet = "foonet"
+	cfg.SlaveMode = true
+	require.Equal(t, []string{"bch_net", "sbch_master_addr", "sbch_htlc_addr"},
+		getProblemFields(CheckConfig(cfg, false)))
+
+	cfg = DefaultConfig()
+	cfg.SbchHtlcAddr = gethAddr("htlc").String()
+	cfg.BchBatchMaxInputs = 1
+	require.Len(t, CheckConfig(cfg, false), 0) // not used unless batch receipts are on
+	cfg.BchBatchReceipts = true
+	require.Equal(t, []string{"bch_batch_max_inputs"}, getProblemFields(CheckConfig(cfg, false)))
+}
+
+func TestCheckConfig_online(t *testing.T) {
+	htlcAddr := gethAddr("htlc")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []any           `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var result any
+		switch req.Method {
+		case "getblockchaininfo":
+			result = map[string]any{"chain": "test"}
+		case "eth_chainId":
+			result = "0x2710"
+		case "eth_getCode":
+			result = "0x"
+			if strings.EqualFold(req.Params[0].(string), htlcAddr.Hex()) {
+				result = "0x6080"
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.Id, "result": result})
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.BchNet = "testnet3"
+	cfg.BchRpcUrl = strings.Replace(server.URL, "http://", "http://user:pass@", 1)
+	cfg.SbchRpcUrl = server.URL
+	cfg.SbchHtlcAddr = htlcAddr.String()
+	cfg.SbchChainId = 10000
+	require.Len(t, CheckConfig(cfg, true), 0)
+
+	cfg.BchNet = "mainnet"
+	cfg.SbchChainId = 10001
+	cfg.SbchMulticallAddr = gethAddr("multicall").String()
+	problems := CheckConfig(cfg, true)
+	require.Equal(t, []string{"bch_rpc_url", "sbch_chain_id", "sbch_multicall_addr"}, getProblemFields(problems))
+	require.Equal(t, `node is on "test", not mainnet`, problems[0].Problem)
+
+	cfg.BchRpcUrl = "http://127.0.0.1:1"
+	cfg.SbchRpcUrl = "http://127.0.0.1:1"
+	require.Equal(t, []string{"bch_rpc_url", "sbch_rpc_url"}, getProblemFields(CheckConfig(cfg, true)))
+}
@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// String values of config file with this prefix are encrypted by EncryptConfigValue,
+// so that secrets (API keys, webhook secrets, RPC passwords, ...) can be kept in git.
+const (
+	EncryptedConfigPrefix = "enc:"
+	ConfigPassphraseEnv   = "ASBOT_CONFIG_PASSPHRASE"
+
+	configSaltLen = 16
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+)
+
+var ErrConfigPassphraseRequired = errors.New("config file has encrypted values, passphrase is required")
+
+// EncryptConfigValue returns "enc:" + base64(salt | nonce | AES-256-GCM ciphertext),
+// the key is derived from passphrase by scrypt
+func EncryptConfigValue(plaintext, passphrase string) (string, error) {
+	salt := make([]byte, configSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	aead, err := newConfigCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := append(salt, nonce...)
+	data = aead.Seal(data, nonce, []byte(plaintext), nil)
+	return EncryptedConfigPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func newConfigCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func decryptConfigValue(value, passphrase string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedConfigPrefix))
+	if err != nil || len(data) < configSaltLen {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	aead, err := newConfigCipher(passphrase, data[:configSaltLen])
+	if err != nil {
+		return "", err
+	}
+	data = data[configSaltLen:]
+	if len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt, wrong passphrase?")
+	}
+	return string(plaintext), nil
+}
+
+// decrypt encrypted string values in JSON, at any depth
+func decryptConfigNode(node any, path, passphrase string) (any, error) {
+	var err error
+	switch val := node.(type) {
+	case string:
+		if !strings.HasPrefix(val, EncryptedConfigPrefix) {
+			return val, nil
+		}
+		if passphrase == "" {
+			return nil, ErrConfigPassphraseRequired
+		}
+		if val, err = decryptConfigValue(val, passphrase); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return val, nil
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // report the same error every time
+		for _, k := range keys {
+			if val[k], err = decryptConfigNode(val[k], strings.TrimPrefix(path+"."+k, "."), passphrase); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, v := range val {
+			if val[i], err = decryptConfigNode(v, fmt.Sprintf("%s[%d]", path, i), passphrase); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return node, nil
+}
+
+// IsConfigFileEncrypted tells if the config file has encrypted values
+func IsConfigFileEncrypted(file string) (bool, error) {
+	doc, err := readConfigDoc(file)
+	if err != nil {
+		return false, err
+	}
+	_, err = decryptConfigNode(doc, "", "")
+	return errors.Is(err, ErrConfigPassphraseRequired), nil
+}
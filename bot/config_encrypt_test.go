@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptConfigValue(t *testing.T) {
+	encrypted, err := EncryptConfigValue("s3cret", "passphrase")
+	require.NoError(t, err)
+	require.Contains(t, encrypted, EncryptedConfigPrefix)
+
+	encrypted2, err := EncryptConfigValue("s3cret", "passphrase")
+	require.NoError(t, err)
+	require.NotEqual(t, encrypted, encrypted2) // salted
+
+	plaintext, err := decryptConfigValue(encrypted, "passphrase")
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", plaintext)
+
+	_, err = decryptConfigValue(encrypted, "wrong")
+	require.ErrorContains(t, err, "wrong passphrase")
+	_, err = decryptConfigValue("enc:1234", "passphrase")
+	require.ErrorContains(t, err, "malformed encrypted value")
+}
+
+func TestLoadConfigFile_encrypted(t *testing.T) {
+	adminToken, err := EncryptConfigValue("token", "passphrase")
+	require.NoError(t, err)
+	webhookSecret, err := EncryptConfigValue("whsec", "passphrase")
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(fmt.Sprintf(`{
+		"bch_net": "chipnet",
+		"admin_token": %q,
+		"sbch_chain_id": 9007199254740993,
+		"webhooks": [{"url": "https://example.com/hook", "secret": %q}]
+	}`, adminToken, webhookSecret)), 0600))
+
+	encrypted, err := IsConfigFileEncrypted(file)
+	require.NoError(t, err)
+	require.True(t, encrypted)
+
+	_, err = LoadConfigFile(file, "")
+	require.ErrorIs(t, err, ErrConfigPassphraseRequired)
+	_, err = LoadConfigFile(file, "wrong")
+	require.ErrorContains(t, err, "admin_token: failed to decrypt")
+
+	cfg, err := LoadConfigFile(file, "passphrase")
+	require.NoError(t, err)
+	require.Equal(t, "chipnet", cfg.BchNet)
+	require.Equal(t, "token", cfg.AdminToken)
+	require.Equal(t, uint64(9007199254740993), cfg.SbchChainId)
+	require.Equal(t, "whsec", cfg.Webhooks[0].Secret)
+	require.Equal(t, "passphrase", cfg.ConfigPassphrase)
+
+	require.NoError(t, os.WriteFile(file, []byte(`{"bch_net":"chipnet"}`), 0600))
+	encrypted, err = IsConfigFileEncrypted(file)
+	require.NoError(t, err)
+	require.False(t, encrypted)
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Config layers, later ones override earlier ones:
+//
+//	default config < base file < profile overlay < ASBOT_* env vars
+//
+// e.g. bot.json < bot.mainnet.json < ASBOT_BCH_RPC_URL. Objects are merged key by key,
+// arrays and other values are replaced.
+const ConfigEnvPrefix = "ASBOT_"
+
+// LoadLayeredConfig loads the base config file (may be empty), the overlay of profile
+// (may be empty) and env var overrides, see ConfigEnvPrefix
+func LoadLayeredConfig(file, profile, passphrase string) (*Config, error) {
+	doc := map[string]any{}
+	if file != "" {
+		base, err := readConfigDoc(file)
+		if err != nil {
+			return nil, err
+		}
+		mergeConfigDoc(doc, base)
+	}
+	if profile != "" {
+		if file == "" {
+			return nil, fmt.Errorf("config profile %s needs a base config file", profile)
+		}
+		overlay, err := readConfigDoc(GetProfileConfigFile(file, profile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config profile %s: %w", profile, err)
+		}
+		mergeConfigDoc(doc, overlay)
+	}
+	env, err := getConfigEnvDoc(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	mergeConfigDoc(doc, env)
+
+	cfg, err := newConfigFromDoc(doc, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ConfigProfile = profile
+	return cfg, nil
+}
+
+// GetProfileConfigFile returns the overlay file of profile, e.g. bot.json => bot.mainnet.json
+func GetProfileConfigFile(file, profile string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "." + profile + ext
+}
+
+func readConfigDoc(file string) (map[string]any, error) {
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]any{}
+	decoder := json.NewDecoder(bytes.NewReader(bz))
+	decoder.UseNumber() // keep large integers
+	if err = decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return doc, nil
+}
+
+func mergeConfigDoc(dst, src map[string]any) {
+	for k, v := range src {
+		srcObj, ok1 := v.(map[string]any)
+		dstObj, ok2 := dst[k].(map[string]any)
+		if ok1 && ok2 {
+			mergeConfigDoc(dstObj, srcObj)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+// ASBOT_ + upper case JSON key of each option, e.g. ASBOT_BCH_LOCK_FEE_RATE=5,
+// values of non-string options are JSON, e.g. ASBOT_CORS_ORIGINS='["*"]'
+func getConfigEnvDoc(lookupEnv func(string) (string, bool)) (map[string]any, error) {
+	doc := map[string]any{}
+	typ := reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		key := field.Tag.Get("json")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := ConfigEnvPrefix + strings.ToUpper(key)
+		val, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() == reflect.String {
+			doc[key] = val
+			continue
+		}
+		var v any
+		decoder := json.NewDecoder(strings.NewReader(val))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		doc[key] = v
+	}
+	return doc, nil
+}
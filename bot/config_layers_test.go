@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProfileConfigFile(t *testing.T) {
+	require.Equal(t, "conf/bot.mainnet.json", GetProfileConfigFile("conf/bot.json", "mainnet"))
+	require.Equal(t, "bot.dev", GetProfileConfigFile("bot", "dev"))
+}
+
+func TestLoadLayeredConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "bot.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{
+		"bch_net": "chipnet",
+		"bch_lock_fee_rate": 5,
+		"bch_rpc_url": "http://base:8332",
+		"cors_origins": ["https://a.com", "https://b.com"],
+		"tokens": [{"symbol": "T", "bch_price": 1, "token_price": 2}]
+	}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bot.mainnet.json"), []byte(`{
+		"bch_net": "mainnet",
+		"cors_origins": ["https://c.com"]
+	}`), 0600))
+
+	cfg, err := LoadLayeredConfig(file, "", "")
+	require.NoError(t, err)
+	require.Equal(t, "chipnet", cfg.BchNet)
+	require.Equal(t, []string{"https://a.com", "https://b.com"}, cfg.CorsOrigins)
+
+	cfg, err = LoadLayeredConfig(file, "mainnet", "")
+	require.NoError(t, err)
+	require.Equal(t, "mainnet", cfg.BchNet)
+	require.Equal(t, "mainnet", cfg.ConfigProfile)
+	require.Equal(t, uint64(5), cfg.BchLockFeeRate)
+	require.Equal(t, uint64(2), cfg.BchUnlockFeeRate) // default
+	require.Equal(t, []string{"https://c.com"}, cfg.CorsOrigins)
+	require.Len(t, cfg.Tokens, 1)
+
+	t.Setenv("ASBOT_BCH_RPC_URL", "http://env:8332")
+	t.Setenv("ASBOT_BCH_LOCK_FEE_RATE", "7")
+	t.Setenv("ASBOT_DEBUG", "true")
+	t.Setenv("ASBOT_TRUSTED_PROXIES", `["10.0.0.0/8"]`)
+	cfg, err = LoadLayeredConfig(file, "mainnet", "")
+	require.NoError(t, err)
+	require.Equal(t, "http://env:8332", cfg.BchRpcUrl)
+	require.Equal(t, uint64(7), cfg.BchLockFeeRate)
+	require.True(t, cfg.DebugMode)
+	require.Equal(t, []string{"10.0.0.0/8"}, cfg.TrustedProxies)
+	require.Equal(t, "mainnet", cfg.BchNet)
+
+	// env only
+	cfg, err = LoadLayeredConfig("", "", "")
+	require.NoError(t, err)
+	require.Equal(t, "http://env:8332", cfg.BchRpcUrl)
+	require.Equal(t, 100, cfg.DbQueryLimit)
+
+	t.Setenv("ASBOT_BCH_LOCK_FEE_RATE", "seven")
+	_, err = LoadLayeredConfig(file, "", "")
+	require.ErrorContains(t, err, "invalid ASBOT_BCH_LOCK_FEE_RATE")
+
+	_, err = LoadLayeredConfig(file, "testnet", "")
+	require.ErrorContains(t, err, "failed to load config profile testnet")
+	_, err = LoadLayeredConfig("", "testnet", "")
+	require.ErrorContains(t, err, "needs a base config file")
+}
+
+func TestMergeConfigDoc(t *testing.T) {
+	dst := map[string]any{"a": 1, "b": map[string]any{"x": 1, "y": 2}, "c": []any{1, 2}}
+	mergeConfigDoc(dst, map[string]any{"b": map[string]any{"y": 3}, "c": []any{3}, "d": "d"})
+	require.Equal(t, map[string]any{"a": 1, "b": map[string]any{"x": 1, "y": 3}, "c": []any{3}, "d": "d"}, dst)
+}
@@ -0,0 +1,273 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const configPollInterval = 5 * time.Second
+
+// WatchConfigFile reloads config when the file (or its profile overlay) is modified or SIGHUP is received
+func (bot *MarketMakerBot) WatchConfigFile(file string) {
+	bot.cfgFile = file
+	files := []string{file}
+	if bot.cfg.ConfigProfile != "" {
+		files = append(files, GetProfileConfigFile(file, bot.cfg.ConfigProfile))
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	lastModTime := getFilesModTime(files)
+	ticker := time.NewTicker(configPollInterval)
+	go func() {
+		for {
+			select {
+			case <-sigCh:
+				log.Info("SIGHUP received, reload config")
+			case <-ticker.C:
+				modTime := getFilesModTime(files)
+				if modTime.Equal(lastModTime) {
+					continue
+				}
+				lastModTime = modTime
+				log.Info("config file modified, reload config")
+			}
+			if err := bot.requestConfigReload(); err != nil {
+				bot.logError("failed to reload config: ", err)
+			}
+		}
+	}()
+}
+
+func getFileModTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// the latest one
+func getFilesModTime(files []string) time.Time {
+	var modTime time.Time
+	for _, file := range files {
+		if t := getFileModTime(file); t.After(modTime) {
+			modTime = t
+		}
+	}
+	return modTime
+}
+
+// load and check config file, the new config will be applied by the main loop
+func (bot *MarketMakerBot) requestConfigReload() error {
+	if bot.cfgFile == "" {
+		return fmt.Errorf("no config file")
+	}
+	newCfg, err := LoadLayeredConfig(bot.cfgFile, bot.cfg.ConfigProfile, bot.cfg.ConfigPassphrase)
+	if err != nil {
+		return err
+	}
+	if err = bot.checkNewConfig(newCfg); err != nil {
+		return err
+	}
+
+	// only keep the latest one
+	select {
+	case <-bot.reloadCh:
+	default:
+	}
+	bot.reloadCh <- newCfg
+	return nil
+}
+
+func (bot *MarketMakerBot) checkNewConfig(newCfg *Config) error {
+	// keys are never read from config file
+	newCfg.BchPrivKeyWIF = bot.cfg.BchPrivKeyWIF
+	newCfg.SbchPrivKeyHex = bot.cfg.SbchPrivKeyHex
+
+	if err := bot.cfg.checkImmutableFields(newCfg); err != nil {
+		return err
+	}
+	if newCfg.StuckTxBlocks > 0 {
+		if err := checkStuckTxStrategy(newCfg.StuckTxStrategy); err != nil {
+			return err
+		}
+	}
+	if newCfg.BchBatchReceipts {
+		if err := checkBatchMaxInputs(newCfg.BchBatchMaxInputs); err != nil {
+			return err
+		}
+	}
+	if err := checkGasStrategy(newCfg.SbchGasStrategy); err != nil {
+		return err
+	}
+	if err := checkSbasDiagnostics(newCfg.SbasDiagnostics); err != nil {
+		return err
+	}
+	if err := checkNegotiationConfig(newCfg.Negotiation); err != nil {
+		return err
+	}
+	if newCfg.DbQueryLimit <= 0 {
+		return fmt.Errorf("invalid db_query_limit: %d", newCfg.DbQueryLimit)
+	}
+	if err := checkTokenChanges(bot.cfg.Tokens, newCfg.Tokens); err != nil {
+		return err
+	}
+	if _, err := newTokens(newCfg.Tokens); err != nil {
+		return err
+	}
+	if _, err := newColdWallets(newCfg, bot.getBchNet(), bot.bchTreasury); err != nil {
+		return err
+	}
+	if _, err := newWebhookDispatcher(bot.db, newCfg.Webhooks, nil); err != nil {
+		return err
+	}
+	if _, err := newAffiliates(newCfg.Affiliates, bot.getBchNet()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// called by the main loop
+func (bot *MarketMakerBot) applyPendingConfig() {
+	select {
+	case newCfg := <-bot.reloadCh:
+		if err := bot.applyConfig(newCfg); err != nil {
+			bot.logError("failed to apply config: ", err)
+		}
+	default:
+	}
+}
+
+func (bot *MarketMakerBot) applyConfig(newCfg *Config) error {
+	oldCfg := bot.cfg
+
+	// recreate RPC clients if endpoints are changed
+	var bchCli IBchClient = bot.bchCli
+	if newCfg.BchRpcUrl != oldCfg.BchRpcUrl {
+		cli, err := NewBchClient(newCfg.BchRpcUrl, bot.bchAddr)
+		if err != nil {
+			return fmt.Errorf("failed to create BCH RPC client: %w", err)
+		}
+		bchCli = cli
+		if bot.chaos != nil {
+			bchCli = newChaosBchClient(cli, bot.chaos)
+		}
+	}
+	var sbchCli ISbchClient = bot.sbchCli
+	sbchCliRO := bot.sbchCliRO
+	if newCfg.SbchRpcUrl != oldCfg.SbchRpcUrl || newCfg.SbchGasPrice != oldCfg.SbchGasPrice ||
+		newCfg.SbchGasStrategy != oldCfg.SbchGasStrategy || newCfg.SbchMaxGasPrice != oldCfg.SbchMaxGasPrice ||
+		newCfg.SbchMulticallAddr != oldCfg.SbchMulticallAddr {
+		evmChain, err := newCfg.getEvmChain()
+		if err != nil {
+			return err
+		}
+		cli, err := newSbchClient(newCfg.SbchRpcUrl, 5*time.Second, bot.sbchPrivKey,
+			newCfg.getSbchHtlcAddr(), newCfg.getTokenHtlcAddrs(), evmChain, bot.db)
+		if err != nil {
+			return fmt.Errorf("failed to create sBCH RPC client: %w", err)
+		}
+		cliRO, err := newSbchClientRO(newCfg.SbchRpcUrl, 5*time.Second, bot.sbchAddr)
+		if err != nil {
+			return fmt.Errorf("failed to create sBCH RPC client (RO): %w", err)
+		}
+		sbchCli, sbchCliRO = cli, cliRO
+		if bot.chaos != nil {
+			sbchCli = newChaosSbchClient(cli, bot.chaos)
+		}
+	}
+	tokens, err := newTokens(newCfg.Tokens)
+	if err != nil {
+		return fmt.Errorf("failed to load tokens: %w", err)
+	}
+	if newCfg.AccessListFile != oldCfg.AccessListFile {
+		accessList, err := newAccessList(newCfg.AccessListFile)
+		if err != nil {
+			return fmt.Errorf("failed to load access list: %w", err)
+		}
+		bot.accessList = accessList
+	}
+	coldWallets, err := newColdWallets(newCfg, bot.getBchNet(), bot.bchTreasury)
+	if err != nil {
+		return fmt.Errorf("failed to load cold wallets: %w", err)
+	}
+	feeWallets, err := newFeeWallets(newCfg, bot.getBchNet())
+	if err != nil {
+		return fmt.Errorf("failed to load fee wallets: %w", err)
+	}
+	affiliates, err := newAffiliates(newCfg.Affiliates, bot.getBchNet())
+	if err != nil {
+		return fmt.Errorf("failed to load affiliates: %w", err)
+	}
+	if bot.httpPolicy != nil {
+		if err = bot.httpPolicy.set(newCfg); err != nil {
+			return fmt.Errorf("failed to load HTTP policy: %w", err)
+		}
+	}
+	if bot.apiAuth != nil {
+		if err = bot.apiAuth.set(newCfg.ApiKeys, newCfg.JwtSecret); err != nil {
+			return fmt.Errorf("failed to load API keys: %w", err)
+		}
+	}
+	if bot.webhooks != nil {
+		if err = bot.webhooks.set(newCfg.Webhooks); err != nil {
+			return fmt.Errorf("failed to load webhooks: %w", err)
+		}
+	}
+
+	bot.bchCli = bchCli
+	bot.sbchCli = sbchCli
+	for _, peer := range bot.evmPeers {
+		// share the nonce manager of the new client
+		peer.homeCli = sbchCli.forHtlc(peer.HomeHtlcAddr)
+	}
+	bot.sbchCliRO = sbchCliRO
+	bot.bchLockMinerFeeRate = newCfg.BchLockFeeRate
+	bot.bchUnlockMinerFeeRate = newCfg.BchUnlockFeeRate
+	bot.bchRefundMinerFeeRate = newCfg.BchRefundFeeRate
+	bot.bchDepositFloor = newCfg.BchDepositFloor
+	bot.strictDepositOutputs = newCfg.StrictDepositOutputs
+	bot.sbasDiagnostics = newCfg.SbasDiagnostics
+	bot.negotiation = newCfg.Negotiation
+	bot.bchConfirmations = newCfg.BchConfirmations
+	bot.sbchConfirmations = newCfg.SbchConfirmations
+	bot.sbchLockConfs = newCfg.SbchLockConfs
+	bot.sbchUnlockConfs = newCfg.SbchUnlockConfs
+	bot.dbQueryLimit = newCfg.DbQueryLimit
+	bot.lazyMaster = newCfg.DebugMode && newCfg.LazyMaster
+	bot.quoteValidity = newCfg.QuoteValidity
+	bot.quoteCommitment = newCfg.QuoteCommitment
+	bot.idempotencyGuard = newCfg.IdempotencyGuard
+	bot.stuckTxBlocks = newCfg.StuckTxBlocks
+	bot.stuckTxStrategy = newCfg.StuckTxStrategy
+	bot.batchReceipts = newCfg.BchBatchReceipts
+	bot.batchMaxInputs = newCfg.BchBatchMaxInputs
+	bot.reconcileInterval = newCfg.ReconcileInterval
+	bot.retentionDays = newCfg.RetentionDays
+	bot.refundAlertBlocks = newCfg.RefundAlertBlocks
+	bot.coldWallets = coldWallets
+	bot.feeWallets = feeWallets
+	bot.affiliates = affiliates
+	bot.affiliatePayoutInterval = newCfg.AffiliatePayoutInterval
+	bot.gaugeInterval = newCfg.GaugeInterval
+	bot.sbchGasPrice = newCfg.getSbchGasPrice().Uint64()
+	bot.profitabilityGate = newCfg.ProfitabilityGate
+	bot.partialFill = newCfg.PartialFill
+	bot.antiFeeSniping = newCfg.AntiFeeSniping
+	bot.reserveInventory = newCfg.ReserveInventory
+	bot.maxRiskScore = newCfg.MaxRiskScore
+	bot.maxCounterpartyExposure = newCfg.MaxCounterpartyExposure
+	bot.swapWorkers = newSwapWorkers(int(newCfg.SwapWorkers))
+	bot.tokens = tokens
+	bot.lastPricesUpdatedAt = 0 // refresh oracle prices of new tokens
+	bot.cfg = newCfg
+	log.Info("config reloaded: ", toJSON(newCfg))
+	return nil
+}
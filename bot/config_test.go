@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"bch_net":"chipnet","bch_lock_fee_rate":5}`), 0600))
+	cfg, err := LoadConfigFile(file, "")
+	require.NoError(t, err)
+	require.Equal(t, "chipnet", cfg.BchNet)
+	require.Equal(t, uint64(5), cfg.BchLockFeeRate)
+	require.Equal(t, uint64(2), cfg.BchUnlockFeeRate)
+	require.Equal(t, 100, cfg.DbQueryLimit)
+
+	require.NoError(t, os.WriteFile(file, []byte(`{"bch_net":`), 0600))
+	_, err = LoadConfigFile(file, "")
+	require.ErrorContains(t, err, "failed to parse config file")
+}
+
+func TestCheckImmutableFields(t *testing.T) {
+	cfg := DefaultConfig()
+	newCfg := DefaultConfig()
+	newCfg.BchLockFeeRate = 10
+	newCfg.BchRpcUrl = "http://127.0.0.1:8332"
+	require.NoError(t, cfg.checkImmutableFields(newCfg))
+
+	newCfg.BchNet = "chipnet"
+	require.ErrorContains(t, cfg.checkImmutableFields(newCfg), "bch_net can not be changed")
+
+	newCfg = DefaultConfig()
+	newCfg.SbchHtlcAddr = "0x1234"
+	require.ErrorContains(t, cfg.checkImmutableFields(newCfg), "sbch_htlc_addr can not be changed")
+}
+
+func TestConfigReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	cfg := DefaultConfig()
+	cfg.BchPrivKeyWIF = "wif"
+	_bot := &MarketMakerBot{
+		cfg:                   cfg,
+		cfgFile:               file,
+		reloadCh:              make(chan *Config, 1),
+		errLogQueue:           newErrLogQueue(100),
+		bchLockMinerFeeRate:   cfg.BchLockFeeRate,
+		bchUnlockMinerFeeRate: cfg.BchUnlockFeeRate,
+		bchRefundMinerFeeRate: cfg.BchRefundFeeRate,
+	}
+
+	// immutable field changed
+	require.NoError(t, os.WriteFile(file, []byte(`{"bch_net":"chipnet"}`), 0600))
+	require.ErrorContains(t, _bot.requestConfigReload(), "bch_net can not be changed")
+	require.NoError(t, os.WriteFile(file, []byte(`{"bch_stuck_tx_blocks":3,"bch_stuck_tx_strategy":"xx"}`), 0600))
+	require.Error(t, _bot.requestConfigReload())
+
+	// mutable fields changed
+	require.NoError(t, os.WriteFile(file, []byte(`{"bch_lock_fee_rate":5,"bch_confirmations":3,"profitability_gate":true}`), 0600))
+	require.NoError(t, _bot.requestConfigReload())
+	require.Equal(t, uint64(2), _bot.bchLockMinerFeeRate)
+	_bot.applyPendingConfig()
+	require.Equal(t, uint64(5), _bot.bchLockMinerFeeRate)
+	require.Equal(t, uint64(2), _bot.bchUnlockMinerFeeRate)
+	require.Equal(t, uint8(3), _bot.bchConfirmations)
+	require.True(t, _bot.profitabilityGate)
+	require.Equal(t, "wif", _bot.cfg.BchPrivKeyWIF)
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+const (
+	CovenantUnconfirmed = "unconfirmed"
+	CovenantUnspent     = "unspent"
+	CovenantSpent       = "spent" // unlocked or refunded, maybe by a mempool tx
+)
+
+// CovenantInfo describes the covenant of a BCH deposit and its UTXO, for support and debugging
+type CovenantInfo struct {
+	DepositTx     string                `json:"deposit_tx"`
+	Value         uint64                `json:"value"`           // in sats
+	SenderEvmAddr string                `json:"sender_evm_addr"` // hex
+	ExpectedPrice uint64                `json:"expected_price"`  // 8 decimals
+	Covenant      *htlcbch.CovenantDump `json:"covenant"`
+	Status        string                `json:"status"`        // see CovenantXxx
+	Confirmations int64                 `json:"confirmations"` // of the deposit tx
+	RefundableIn  int64                 `json:"refundable_in"` // blocks (or seconds if time based) until the sender can refund, 0 means now
+}
+
+// reconstruct the covenant of a deposit tx from its OP_RETURN and query the status of its UTXO
+func InspectCovenant(bchCli IBchClient, params *htlcbch.ChainParams, txHash string) (*CovenantInfo, error) {
+	tx, err := bchCli.GetTx(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deposit tx: %w", err)
+	}
+	deposit := params.GetHtlcLockInfoWithFilter(*tx, &htlcbch.DepositFilter{AnyOutputOrder: true})
+	if deposit == nil {
+		return nil, fmt.Errorf("not a valid deposit tx: %s", txHash)
+	}
+	c, err := params.NewCovenantOfDeposit(deposit)
+	if err != nil {
+		return nil, err
+	}
+	dump, err := c.Dump()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CovenantInfo{
+		DepositTx:     deposit.TxHash,
+		Value:         deposit.Value,
+		SenderEvmAddr: toHex(deposit.SenderEvmAddr),
+		ExpectedPrice: deposit.ExpectedPrice,
+		Covenant:      dump,
+		Confirmations: int64(tx.Confirmations),
+	}
+	txOut, err := bchCli.GetTxOut(txHash, deposit.Vout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get UTXO: %w", err)
+	}
+	switch {
+	case txOut == nil:
+		info.Status = CovenantSpent
+	case deposit.ExpirationType == htlcbch.ExpirationTypeTime:
+		info.Status = CovenantUnspent
+		if info.Confirmations == 0 {
+			info.Status = CovenantUnconfirmed
+		}
+		info.RefundableIn = deposit.RefundableIn(tx.Confirmations, tx.Blocktime, time.Now().Unix())
+	case info.Confirmations == 0:
+		info.Status = CovenantUnconfirmed
+		info.RefundableIn = int64(deposit.Expiration)
+	default:
+		info.Status = CovenantUnspent
+		if n := int64(deposit.Expiration) - info.Confirmations; n > 0 {
+			info.RefundableIn = n
+		}
+	}
+	return info, nil
+}
+
+// return the covenant of a BCH deposit and the status of its UTXO
+func (bot *MarketMakerBot) handleCovenant(w http.ResponseWriter, r *http.Request) {
+	txHash := r.URL.Query().Get("tx_hash")
+	if txHash == "" {
+		NewErrResp("missing tx_hash").WriteTo(w)
+		return
+	}
+	info, err := InspectCovenant(bot.bchCli, bot.getBchNet(), txHash)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(info).WriteTo(w)
+}
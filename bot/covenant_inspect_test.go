@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectCovenant(t *testing.T) {
+	senderPkh := gethAddrBytes("user")
+	hashLock := gethHash32Bytes("hashlock")
+	depositTx := wire.NewMsgTx(2)
+	depositTx.AddTxOut(wire.NewTxOut(100000, getHtlcP2shPkScript(senderPkh, testBchPkh, hashLock, 36, 500)))
+	depositTx.AddTxOut(wire.NewTxOut(0, newHtlcDepositOpRet(testBchPkh, senderPkh, hashLock, 36, 500,
+		gethAddrBytes("evm"), 1e8)))
+	txHash := depositTx.TxHash().String()
+
+	bchCli := newMockBchClient(122, 129)
+	bchCli.txs[txHash] = depositTx
+	_bot := &MarketMakerBot{bchCli: bchCli}
+
+	getInfo := func(txHash string) (info *CovenantInfo, errMsg string) {
+		w := httptest.NewRecorder()
+		_bot.handleCovenant(w, httptest.NewRequest("GET", "/covenant?tx_hash="+txHash, nil))
+		var resp struct {
+			Error  string        `json:"error"`
+			Result *CovenantInfo `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Result, resp.Error
+	}
+
+	_, errMsg := getInfo(gethHash32("none").String())
+	require.Contains(t, errMsg, "failed to get deposit tx")
+
+	info, errMsg := getInfo(txHash)
+	require.Empty(t, errMsg)
+	require.Equal(t, txHash, info.DepositTx)
+	require.Equal(t, uint64(100000), info.Value)
+	require.Equal(t, toHex(gethAddrBytes("evm")), info.SenderEvmAddr)
+	require.Equal(t, uint64(1e8), info.ExpectedPrice)
+	require.Equal(t, toHex(senderPkh), info.Covenant.Args.SenderPkh)
+	require.Equal(t, toHex(testBchPkh), info.Covenant.Args.RecipientPkh)
+	require.Equal(t, uint16(36), info.Covenant.Args.Expiration)
+	require.Contains(t, info.Covenant.Disassembly, "OP_CHECKSEQUENCEVERIFY")
+	require.Regexp(t, "^bitcoincash:p", info.Covenant.P2SHAddress)
+	require.Equal(t, CovenantUnconfirmed, info.Status)
+	require.Equal(t, int64(36), info.RefundableIn)
+
+	bchCli.confirmations[txHash] = 10
+	info, _ = getInfo(txHash)
+	require.Equal(t, CovenantUnspent, info.Status)
+	require.Equal(t, int64(10), info.Confirmations)
+	require.Equal(t, int64(26), info.RefundableIn)
+
+	bchCli.confirmations[txHash] = 40
+	info, _ = getInfo(txHash)
+	require.Equal(t, CovenantUnspent, info.Status)
+	require.Equal(t, int64(0), info.RefundableIn)
+
+	bchCli.spentOutputs[txHash+":0"] = true
+	info, _ = getInfo(txHash)
+	require.Equal(t, CovenantSpent, info.Status)
+
+	// not a deposit
+	otherTx := wire.NewMsgTx(2)
+	otherTx.AddTxOut(wire.NewTxOut(100000, newP2SHPkScript(testBchPkh)))
+	bchCli.txs[otherTx.TxHash().String()] = otherTx
+	_, errMsg = getInfo(otherTx.TxHash().String())
+	require.Contains(t, errMsg, "not a valid deposit tx")
+}
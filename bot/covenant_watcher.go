@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+const fulcrumPingInterval = 60 // 1m
+
+// watch covenants of in-flight sbch2bch swaps (BCH locked by bot) by Fulcrum scripthash subscriptions,
+// so that secrets revealed by users are found as soon as the unlock txs are seen,
+// without parsing every BCH block
+func (bot *MarketMakerBot) watchBchCovenants() {
+	if bot.fulcrumCli == nil {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastFulcrumPingAt >= fulcrumPingInterval {
+		bot.lastFulcrumPingAt = now
+		if err := bot.fulcrumCli.Ping(); err != nil {
+			bot.logError("Fulcrum error, failed to ping: ", err)
+		}
+	}
+
+	log.Info("watch BCH covenants ...")
+	records, err := bot.db.getSbch2BchRecordsByStatus(Sbch2BchStatusBchLocked, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get SBCH2BCH records: ", err)
+		return
+	}
+
+	inFlight := make(map[string]*Sbch2BchRecord, len(records))
+	for _, record := range records {
+		scriptHash := P2SHScriptHash(gethcmn.FromHex(record.HtlcScriptHash))
+		inFlight[scriptHash] = record
+		if bot.watchedCovenants[scriptHash] {
+			continue
+		}
+		if err = bot.fulcrumCli.SubscribeScriptHash(scriptHash); err != nil {
+			bot.logError("Fulcrum error, failed to subscribe scripthash: ", err)
+			return
+		}
+		bot.watchedCovenants[scriptHash] = true
+	}
+	for scriptHash := range bot.watchedCovenants {
+		if inFlight[scriptHash] == nil {
+			delete(bot.watchedCovenants, scriptHash)
+			if err = bot.fulcrumCli.UnsubscribeScriptHash(scriptHash); err != nil {
+				log.Warn("Fulcrum error, failed to unsubscribe scripthash: ", err)
+			}
+		}
+	}
+
+	for _, scriptHash := range bot.fulcrumCli.ChangedScriptHashes() {
+		if record := inFlight[scriptHash]; record != nil {
+			bot.checkBchCovenantSpent(scriptHash, record)
+		}
+	}
+}
+
+// find the unlock tx of the covenant in its history
+func (bot *MarketMakerBot) checkBchCovenantSpent(scriptHash string, record *Sbch2BchRecord) {
+	history, err := bot.fulcrumCli.GetScriptHashHistory(scriptHash)
+	if err != nil {
+		bot.logError("Fulcrum error, failed to get scripthash history: ", err)
+		return
+	}
+	for _, txRef := range history {
+		if txRef.TxHash == record.BchLockTxHash {
+			continue
+		}
+		tx, err := bot.fulcrumCli.GetTx(txRef.TxHash)
+		if err != nil {
+			bot.logError("Fulcrum error, failed to get tx: ", err)
+			return
+		}
+		receipt := htlcbch.GetHtlcUnlockInfo(*tx)
+		if receipt == nil || receipt.PrevTxHash != record.BchLockTxHash {
+			continue // refunded by bot or not related
+		}
+		log.Info("HTLC receipt:", toJSON(receipt))
+		h := uint64(0) // in mempool
+		if txRef.Height > 0 {
+			h = uint64(txRef.Height)
+		}
+		if bot.publishEvent(EventBchReceipt, receipt.TxHash, h, receipt) {
+			bot.handleEvents()
+		}
+		return
+	}
+}
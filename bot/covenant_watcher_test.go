@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestWatchBchCovenants(t *testing.T) {
+	_secret := gethHash32Bytes("secret")
+	_hashLock := gethcmn.FromHex(secretToHashLock(_secret))
+	_timeLock := uint16(888)
+	_userBchPkh := gethAddrBytes("ubch")
+	_bchLockTxHash := bchHash32("bchlocktx")
+
+	c, err := htlcbch.NewMainnetCovenant(testBchPkh, _userBchPkh, _hashLock, _timeLock, 0)
+	require.NoError(t, err)
+	_scriptHash, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	_sigScript, err := c.BuildUnlockSigScript(_secret)
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    uint64(time.Now().Unix()),
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlocktx")),
+		Value:           12345678,
+		SbchPrice:       1e8,
+		SbchSenderAddr:  gethAddr("uevm").String(),
+		BchRecipientPkh: toHex(_userBchPkh),
+		HashLock:        toHex(_hashLock),
+		TimeLock:        uint32(_timeLock),
+		HtlcScriptHash:  toHex(_scriptHash),
+		BchLockTxHash:   _bchLockTxHash.String(),
+		Status:          Sbch2BchStatusBchLocked,
+	}))
+
+	_fulcrumCli := newMockFulcrumClient()
+	_bot := &MarketMakerBot{
+		db:               _db,
+		dbQueryLimit:     100,
+		fulcrumCli:       _fulcrumCli,
+		watchedCovenants: map[string]bool{},
+		errLogQueue:      newErrLogQueue(100),
+	}
+
+	_covenantScriptHash := P2SHScriptHash(_scriptHash)
+	_bot.watchBchCovenants()
+	require.True(t, _fulcrumCli.subscribed[_covenantScriptHash])
+
+	// the lock tx itself and unrelated txs are ignored
+	_fulcrumCli.addTx(_covenantScriptHash, &wire.MsgTx{
+		TxIn: []*wire.TxIn{{SignatureScript: _sigScript}},
+	})
+	_bot.watchBchCovenants()
+	records, err := _db.getSbch2BchRecordsByStatus(Sbch2BchStatusBchLocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	// unlocked by user
+	_unlockTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: wire.OutPoint{Hash: _bchLockTxHash},
+			SignatureScript:  _sigScript,
+		}},
+	}
+	_fulcrumCli.addTx(_covenantScriptHash, _unlockTx)
+	_bot.watchBchCovenants()
+	records, err = _db.getSbch2BchRecordsByStatus(Sbch2BchStatusSecretRevealed, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, toHex(_secret), records[0].Secret)
+	require.Equal(t, _unlockTx.TxHash().String(), records[0].BchUnlockTxHash)
+
+	// not in-flight any more
+	_bot.watchBchCovenants()
+	require.Empty(t, _fulcrumCli.subscribed)
+	require.Empty(t, _bot.watchedCovenants)
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	_ "embed"
+	"net/http"
+	"time"
+)
+
+// a single page without build steps, it reads the public API and the admin API with the
+// token entered by operator (kept in the local storage of browser)
+//
+//go:embed dashboard.html
+var dashboardHtml []byte
+
+const dashboardErrLogs = 20
+
+// DashboardStatus is what the dashboard polls besides the swap table
+type DashboardStatus struct {
+	Paused         bool     `json:"paused"` // new swaps are not taken, in-flight ones are still unlocked or refunded
+	Frozen         bool     `json:"frozen"` // nothing is signed or broadcast, see isFrozen()
+	LastBchHeight  uint64   `json:"last_bch_height"`
+	LastSbchHeight uint64   `json:"last_sbch_height"`
+	BchScanLag     int64    `json:"bch_scan_lag"`  // in seconds, -1 means not caught up yet
+	SbchScanLag    int64    `json:"sbch_scan_lag"` // in seconds, -1 means not caught up yet
+	Inventory      *Info    `json:"inventory"`
+	RecentErrors   []ErrLog `json:"recent_errors"` // latest first, not removed from the queue of /logs
+}
+
+// new BCH and sBCH deposits, EVM swaps and quotes are not handled while paused
+func (bot *MarketMakerBot) isPaused() bool {
+	return bot.paused.Load()
+}
+
+func (bot *MarketMakerBot) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(dashboardHtml)
+}
+
+func (bot *MarketMakerBot) handleAdminStatus(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleReader, false) {
+		return
+	}
+	heights, err := bot.db.getLastHeights()
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	info, err := bot.getBotInfo()
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	now := time.Now().Unix()
+	status := &DashboardStatus{
+		Paused:         bot.isPaused(),
+		Frozen:         bot.isFrozen(),
+		LastBchHeight:  heights.LastBchHeight,
+		LastSbchHeight: heights.LastSbchHeight,
+		BchScanLag:     getScanLag(bot.bchScannedAt, now),
+		SbchScanLag:    getScanLag(bot.sbchScannedAt, now),
+		Inventory:      info,
+		RecentErrors:   bot.errLogQueue.peekErrLogs(dashboardErrLogs),
+	}
+	NewOkResp(status).WriteTo(w)
+}
+
+func getScanLag(scannedAt, now int64) int64 {
+	if scannedAt == 0 {
+		return -1
+	}
+	return now - scannedAt
+}
+
+func (bot *MarketMakerBot) handlePause(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleOperator, false) {
+		return
+	}
+	if !bot.paused.Swap(true) {
+		bot.logWarnf("paused by admin API, new swaps are not taken")
+	}
+	NewOkResp("paused").WriteTo(w)
+}
+
+func (bot *MarketMakerBot) handleResume(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleOperator, false) {
+		return
+	}
+	if bot.paused.Swap(false) {
+		bot.logWarnf("resumed by admin API")
+	}
+	NewOkResp("resumed").WriteTo(w)
+}
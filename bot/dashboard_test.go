@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseResume(t *testing.T) {
+	_bot := &MarketMakerBot{
+		adminToken:  "secret",
+		errLogQueue: newErrLogQueue(100),
+	}
+
+	call := func(handler http.HandlerFunc, token string) (result, errMsg string) {
+		req := httptest.NewRequest("POST", "/admin/x", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		var resp struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+			Result  string `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Result, resp.Error
+	}
+
+	_, errMsg := call(_bot.handlePause, "wrong")
+	require.Contains(t, errMsg, "unauthorized")
+	require.False(t, _bot.isPaused())
+
+	result, errMsg := call(_bot.handlePause, "secret")
+	require.Empty(t, errMsg)
+	require.Equal(t, "paused", result)
+	require.True(t, _bot.isPaused())
+	_, err := _bot.makeQuote(&QuoteReq{})
+	require.ErrorContains(t, err, "bot is paused")
+
+	// paused again, logged once
+	call(_bot.handlePause, "secret")
+	logs := _bot.errLogQueue.peekErrLogs(10)
+	require.Len(t, logs, 1)
+	require.Contains(t, logs[0].Msg, "paused by admin API")
+
+	result, _ = call(_bot.handleResume, "secret")
+	require.Equal(t, "resumed", result)
+	require.False(t, _bot.isPaused())
+	logs = _bot.errLogQueue.peekErrLogs(10)
+	require.Len(t, logs, 2)
+	require.Equal(t, "resumed by admin API", logs[0].Msg) // latest first
+	require.Len(t, _bot.errLogQueue.peekErrLogs(1), 1)
+	require.Len(t, _bot.errLogQueue.removeErrLogs(10), 2) // not removed by peek
+}
+
+func TestDashboardPage(t *testing.T) {
+	_bot := &MarketMakerBot{}
+	w := httptest.NewRecorder()
+	_bot.handleDashboard(w, httptest.NewRequest("GET", "/dashboard", nil))
+	require.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Contains(t, w.Body.String(), "/admin/status")
+	require.Contains(t, w.Body.String(), "/admin/pause")
+}
+
+func TestGetScanLag(t *testing.T) {
+	require.Equal(t, int64(-1), getScanLag(0, 1000))
+	require.Equal(t, int64(30), getScanLag(970, 1000))
+}
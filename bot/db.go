@@ -1,15 +1,19 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"strings"
+	"time"
 )
 
 type (
 	Sbch2BchStatus int
 	Bch2SbchStatus int
+	EvmSwapStatus  int
 )
 
 const (
@@ -20,6 +24,10 @@ const (
 	Bch2SbchStatusSbchRefunded
 	Bch2SbchStatusTooLateToLockSbch
 	Bch2SbchStatusPriceChanged
+	Bch2SbchStatusUnprofitable
+	Bch2SbchStatusRemainderRefunded // partially filled, BCH unlocked and the unfilled part is paid back
+	Bch2SbchStatusRejected          // vetoed by swap hooks before locking sBCH
+	Bch2SbchStatusCancelled         // cancelled by user before locking sBCH
 )
 
 const (
@@ -30,6 +38,19 @@ const (
 	Sbch2BchStatusBchRefunded
 	Sbch2BchStatusTooLateToLockBch
 	Sbch2BchStatusPriceChanged
+	Sbch2BchStatusUnprofitable
+	Sbch2BchStatusRejected  // vetoed by swap hooks before locking BCH
+	Sbch2BchStatusCancelled // cancelled by user before locking BCH
+)
+
+const (
+	EvmSwapStatusNew EvmSwapStatus = iota
+	EvmSwapStatusBotLocked
+	EvmSwapStatusSecretRevealed
+	EvmSwapStatusBotUnlocked
+	EvmSwapStatusBotRefunded
+	EvmSwapStatusTooLateToLock
+	EvmSwapStatusRejected // invalid, or not enough inventory
 )
 
 type LastHeights struct {
@@ -38,16 +59,336 @@ type LastHeights struct {
 	LastSbchHeight uint64
 }
 
+type Quote struct {
+	gorm.Model
+	HashLock   string `gorm:"unique"`   // hex
+	Direction  string `gorm:"not null"` // bch2sbch|sbch2bch
+	Value      uint64 `gorm:"not null"` // in sats
+	Price      uint64 `gorm:"not null"` // 8 decimals
+	ValidUntil int64  `gorm:"not null"` // unix timestamp
+	Token      string ``                // SEP20 token symbol, empty means sBCH
+	PaymentUri string ``                // bch2sbch only
+	QuoteHash  string ``                // hex, the signed hash, see getQuoteHash()
+	CommitTx   string `gorm:"index"`    // hex, sBCH tx committing QuoteHash on chain, empty means not committed
+	Negotiated bool   ``                // terms are agreed by negotiation, see NegotiationConfig
+	PenaltyBPS uint16 ``                // negotiated only
+	TimeLock   uint32 ``                // negotiated only, in BCH blocks (bch2sbch) or sBCH seconds (sbch2bch)
+}
+
+type SwapTx struct {
+	gorm.Model
+	HashLock string `gorm:"index"`                        // hex
+	Leg      string `gorm:"not null;uniqueIndex:idx_leg"` // see SwapLegXxx
+	TxHash   string `gorm:"not null;uniqueIndex:idx_leg"` // hex
+	RawTx    string `gorm:"not null"`                     // hex
+}
+
+type PendingBchTx struct {
+	gorm.Model
+	HashLock   string `gorm:"index"`
+	Leg        string `gorm:"not null"` // see SwapLegXxx
+	TxHash     string `gorm:"unique"`   // hex
+	RawTx      string `gorm:"not null"` // hex
+	SentHeight int64  `gorm:"not null"` // BCH height when tx is sent or bumped
+	Bumps      uint8  `gorm:"not null"`
+	Confirmed  bool   `gorm:"index"`
+	CpfpRawTx  string // hex, the last CPFP tx, chained off the previous one on each bump
+	CpfpSize   int64  // total size of CPFP txs sent
+	CpfpFee    int64  // total miner fee paid by CPFP txs sent
+}
+
+type PendingSbchTx struct {
+	gorm.Model
+	Nonce  uint64 `gorm:"unique"`
+	TxHash string `gorm:"not null"` // hex
+	RawTx  string `gorm:"not null"` // hex
+}
+
+// pending txs of bot on peer EVM chains, see PendingSbchTx
+type PendingEvmTx struct {
+	gorm.Model
+	Chain  string `gorm:"not null;uniqueIndex:idx_chain_nonce"` // name of the peer chain
+	Nonce  uint64 `gorm:"not null;uniqueIndex:idx_chain_nonce"`
+	TxHash string `gorm:"not null"` // hex
+	RawTx  string `gorm:"not null"` // hex
+}
+
+type SwapCost struct {
+	gorm.Model
+	HashLock      string `gorm:"unique"`   // hex
+	Direction     string `gorm:"not null"` // bch2sbch|sbch2bch
+	ServiceFee    uint64 `gorm:"not null"` // in sats
+	EstimatedCost uint64 `gorm:"not null"` // in sats
+	BchMinerFee   uint64 `gorm:"not null"` // in sats, paid by bot
+	SbchGasFee    uint64 `gorm:"not null"` // in sats, paid by bot (estimated)
+}
+
+// service fee earned by a completed swap, swept to the fee wallet of its asset
+type FeeRevenue struct {
+	gorm.Model
+	HashLock string `gorm:"unique"`   // hex
+	Asset    string `gorm:"not null"` // bch|sbch, see FeeAssetXxx
+	Amount   uint64 `gorm:"not null"` // in sats
+	SweepTx  string `gorm:"index"`    // hex, empty means not swept yet
+}
+
+type AffiliateAccrual struct {
+	gorm.Model
+	HashLock string `gorm:"unique"`   // hex
+	Code     string `gorm:"index"`    // see AffiliateConfig
+	Asset    string `gorm:"not null"` // bch|sbch, see FeeAssetXxx
+	Amount   uint64 `gorm:"not null"` // in sats
+	PayoutTx string `gorm:"index"`    // hex, empty means not paid yet
+}
+
+// ChainJob is a broadcast of a swap leg, claimed by one worker at a time,
+// see MarketMakerBot.claimChainJob()
+type ChainJob struct {
+	gorm.Model
+	IdemKey      string `gorm:"unique"`   // idempotency key, <leg>:<hashLock>
+	HashLock     string `gorm:"index"`    // hex
+	Leg          string `gorm:"not null"` // see SwapLegXxx
+	Owner        string // instance ID of the worker which claimed it
+	ClaimedUntil int64  `gorm:"index"` // unix seconds, other workers may take over after it
+	Attempts     uint32 `gorm:"not null"`
+	TxHash       string // hex, set before (BCH) or after (sBCH) the tx is broadcast
+	RawTx        string // hex, BCH only
+	LastError    string
+	Done         bool `gorm:"index"`
+}
+
+type LeaderLease struct {
+	gorm.Model
+	Name      string `gorm:"unique"`
+	Holder    string `gorm:"not null"` // instance ID of current leader
+	Epoch     uint64 `gorm:"not null"` // fencing token, increased on every takeover
+	RenewedAt int64  `gorm:"not null"` // unix timestamp
+}
+
+type SwapEvent struct {
+	gorm.Model
+	Kind    string `gorm:"not null;uniqueIndex:idx_event"` // see EventXxx
+	Key     string `gorm:"not null;uniqueIndex:idx_event"` // tx hash, or tx hash:log index
+	Height  uint64 `gorm:"not null"`                       // BCH or sBCH height
+	Payload string `gorm:"not null"`                       // JSON
+	Handled bool   `gorm:"index"`
+}
+
+// TreasuryProposal is a spend of the multisig treasury collecting signatures of co-signers
+type TreasuryProposal struct {
+	gorm.Model
+	ToAddr     string `gorm:"not null"`
+	Value      int64  `gorm:"not null"` // in sats, miner fee deducted
+	UnsignedTx string `gorm:"not null"` // hex
+	InAmounts  string `gorm:"not null"` // JSON, values of inputs in tx order
+	Sigs       string `gorm:"not null"` // JSON, hex pubkey => hex signatures of all inputs
+	Status     string `gorm:"index"`    // see TreasuryProposalXxx
+	TxHash     string ``                // set when the signed tx is broadcast
+}
+
+// SwapStatusChange is saved together with the swap record whose status is changed,
+// and soft deleted when it is fanned out to webhook deliveries
+type SwapStatusChange struct {
+	gorm.Model
+	Direction string `gorm:"not null"` // bch2sbch|sbch2bch
+	HashLock  string `gorm:"not null"` //
+	Status    string `gorm:"not null"` // the new status
+	Payload   string `gorm:"not null"` // JSON, WebhookEvent without id & time
+}
+
+type WebhookDelivery struct {
+	gorm.Model
+	EventId       uint   `gorm:"not null"` // ID of the SwapStatusChange
+	Url           string `gorm:"not null"` //
+	Payload       string `gorm:"not null"` // JSON, WebhookEvent
+	Attempts      uint32 `gorm:"not null"` // failed attempts
+	NextAttemptAt int64  `gorm:"index"`    // unix seconds
+	Status        string `gorm:"index"`    // see WebhookDeliveryXxx, delivered ones are deleted
+	LastError     string ``                //
+}
+
+// EmailSubscription is an email registered with a quote, see EmailNotifier
+type EmailSubscription struct {
+	gorm.Model
+	HashLock     string `gorm:"unique"`   // hex
+	Direction    string `gorm:"not null"` // bch2sbch|sbch2bch
+	Email        string `gorm:"not null"` //
+	Token        string `gorm:"unique"`   // hex, unsubscribe token
+	Unsubscribed bool   ``                //
+}
+
+type EmailDelivery struct {
+	gorm.Model
+	EventId       uint   `gorm:"not null"` // ID of the SwapStatusChange
+	Kind          string `gorm:"not null"` // see EmailKindXxx
+	Email         string `gorm:"not null"` //
+	Token         string `gorm:"index"`    // unsubscribe token of the subscription
+	Payload       string `gorm:"not null"` // JSON, WebhookEvent
+	Attempts      uint32 `gorm:"not null"` // failed attempts
+	NextAttemptAt int64  `gorm:"index"`    // unix seconds
+	Status        string `gorm:"index"`    // see WebhookDeliveryXxx, delivered ones are deleted
+	LastError     string ``                //
+}
+
+// SwapTimer is a deadline of an in-flight swap, armed when the swap enters a status, see TimerXxx
+type SwapTimer struct {
+	gorm.Model
+	HashLock string `gorm:"not null;uniqueIndex:idx_timer"` // hex
+	Kind     string `gorm:"not null;uniqueIndex:idx_timer"` // see TimerXxx
+	FireAt   int64  `gorm:"index"`                          // unix seconds
+	Fired    bool   `gorm:"index"`                          // the action is taken or not needed anymore
+}
+
+// InventoryReservation holds the amount which will be locked by bot for a quoted or
+// detected swap, so that concurrent swaps can not take more than the free inventory
+type InventoryReservation struct {
+	gorm.Model
+	HashLock  string `gorm:"uniqueIndex"`    // hex
+	Asset     string `gorm:"not null;index"` // see AssetXxx
+	Amount    uint64 `gorm:"not null"`       // in sats
+	ExpiresAt int64  `gorm:"index"`          // unix seconds
+}
+
+// SwapMilestone is the time a swap reached a status, kept after the swap is archived
+type SwapMilestone struct {
+	gorm.Model
+	Direction string `gorm:"not null;uniqueIndex:idx_milestone"` // bch2sbch|sbch2bch
+	HashLock  string `gorm:"not null;uniqueIndex:idx_milestone"` // hex
+	Status    string `gorm:"not null;uniqueIndex:idx_milestone"` // the status reached
+	At        int64  `gorm:"index"`                              // unix seconds
+}
+
+// SuspectBchTx looks like an HTLC tx but is not a valid one, see htlcbch.SuspectTx
+type SuspectBchTx struct {
+	gorm.Model
+	TxHash string `gorm:"unique"`   // hex
+	Height uint64 `gorm:"not null"` // BCH height
+	Reason string `gorm:"not null"` //
+	RawTx  string `gorm:"not null"` // hex
+}
+
+// CounterpartyRisk counts outcomes of the swaps of a counterparty, see CounterpartyRisk.Score()
+type CounterpartyRisk struct {
+	gorm.Model
+	Counterparty string `gorm:"unique"`   // bch:<pkh> or evm:<addr>, hex
+	Completed    uint32 `gorm:"not null"` // secret revealed by user
+	Refunded     uint32 `gorm:"not null"` // bot locked but user never claimed, so bot refunded
+	Cancelled    uint32 `gorm:"not null"` // cancelled by user before bot locked
+}
+
+// FreezeEvent is a freeze or thaw of the kill switch, the latest one is in effect, see isFrozen()
+type FreezeEvent struct {
+	gorm.Model
+	Frozen bool   `gorm:"not null"` // false means thawed
+	Reason string `gorm:"not null"` //
+	Source string `gorm:"not null"` // api|cli
+}
+
+// SbasBchTx carries the SBAS protocol ID, valid or not, see htlcbch.SbasTx
+type SbasBchTx struct {
+	gorm.Model
+	TxHash  string `gorm:"unique"`   // hex
+	Height  uint64 `gorm:"not null"` // BCH height
+	Vout    uint32 `gorm:"not null"` // index of the SBAS OP_RETURN output
+	Payload string `gorm:"not null"` // hex pushes after the protocol ID, separated by spaces
+	Valid   bool   `gorm:"index"`    // is a valid deposit
+	Reason  string `gorm:"not null"` // why it is not a valid deposit
+	RawTx   string `gorm:"not null"` // hex
+}
+
+// IndexedBchHtlc is a BCH covenant locked by anyone, indexed in bootstrap mode, see swap_index.go
+type IndexedBchHtlc struct {
+	gorm.Model
+	LockTxHash    string `gorm:"unique"`   // hex
+	LockVout      uint32 `gorm:"not null"` // index of the covenant output
+	LockHeight    uint64 `gorm:"index"`    // BCH height
+	HashLock      string `gorm:"index"`    // hex
+	SenderPkh     string `gorm:"index"`    // hex
+	RecipientPkh  string `gorm:"index"`    // hex
+	SenderEvmAddr string `gorm:"index"`    // hex
+	ScriptHash    string `gorm:"not null"` // hex
+	Value         uint64 `gorm:"not null"` // in sats
+	Expiration    uint32 `gorm:"not null"` // got from OP_RETURN, see htlcbch.ExpirationType
+	PenaltyBPS    uint16 `gorm:"not null"` //
+	ExpectedPrice uint64 `gorm:"not null"` // 8 decimals
+	Template      string ``                // version of the covenant template, empty means v1
+	UnlockTxHash  string `gorm:"index"`    // hex, set when unlocked by the recipient
+	UnlockHeight  uint64 ``                // set when unlocked or refunded
+	Secret        string ``                // hex, set when unlocked by the recipient
+	RefundTxHash  string `gorm:"index"`    // hex, set when refunded to the sender
+}
+
+// IndexedSbchHtlc is an HTLC locked by anyone on smartBCH, indexed in bootstrap mode, see swap_index.go
+type IndexedSbchHtlc struct {
+	gorm.Model
+	HtlcAddr        string `gorm:"not null"` // hex, HTLC contract which emitted the lock event
+	LockTxHash      string `gorm:"unique"`   // hex
+	LockHeight      uint64 `gorm:"index"`    // sBCH height
+	HashLock        string `gorm:"index"`    // hex
+	LockerAddr      string `gorm:"index"`    // hex
+	UnlockerAddr    string `gorm:"index"`    // hex
+	BchRecipientPkh string `gorm:"index"`    // hex
+	Value           string `gorm:"not null"` // decimal, in wei or token units
+	UnlockTime      uint64 `gorm:"not null"` // unix timestamp
+	CreatedTime     uint64 `gorm:"not null"` // unix timestamp
+	PenaltyBPS      uint16 `gorm:"not null"` //
+	ExpectedPrice   string `gorm:"not null"` // decimal, 8 decimals
+	UnlockTxHash    string `gorm:"index"`    // hex, set when unlocked by the unlocker
+	UnlockHeight    uint64 ``                // set when unlocked or refunded
+	Secret          string ``                // hex, set when unlocked by the unlocker
+	RefundTxHash    string `gorm:"index"`    // hex, set when refunded to the locker
+}
+
+// scan checkpoints of a peer EVM chain
+type EvmPeerHeights struct {
+	gorm.Model
+	Peer           string `gorm:"unique"`
+	LastHomeHeight uint64 // of the dedicated HTLC contract on smartBCH
+	LastPeerHeight uint64 // of the HTLC contract on the peer chain
+}
+
+// EvmSwapRecord is a swap between smartBCH and a peer EVM chain. User locks on the
+// source chain, bot locks on the target chain with half of the time lock.
+type EvmSwapRecord struct {
+	gorm.Model
+	Peer             string        `gorm:"not null;index"` // name of the peer chain
+	Direction        string        `gorm:"not null"`       // home2peer|peer2home
+	HashLock         string        `gorm:"unique"`         // got from event
+	UserLockTxHash   string        `gorm:"unique"`         // got from event, on the source chain
+	UserLockTime     uint64        `gorm:"not null"`       // got from event
+	UserAddr         string        `gorm:"not null"`       // got from event, locker on the source chain
+	RecipientAddr    string        `gorm:"not null"`       // got from event, user's address on the target chain
+	Value            uint64        `gorm:"not null"`       // got from event, 8 decimals
+	Price            uint64        `gorm:"not null"`       // bot's price, 8 decimals
+	TimeLock         uint32        `gorm:"not null"`       // got from event, in seconds
+	BotLockTxHash    string        ``                      // set when status changed to EvmSwapStatusBotLocked
+	BotLockTime      uint64        ``                      // set when status changed to EvmSwapStatusBotLocked
+	UserUnlockTxHash string        ``                      // set when status changed to EvmSwapStatusSecretRevealed
+	Secret           string        ``                      // set when status changed to EvmSwapStatusSecretRevealed
+	BotUnlockTxHash  string        ``                      // set when status changed to EvmSwapStatusBotUnlocked
+	BotRefundTxHash  string        ``                      // set when status changed to EvmSwapStatusBotRefunded
+	Status           EvmSwapStatus `gorm:"not null;index"` //
+}
+
+type HdReceivePkh struct {
+	gorm.Model
+	Idx      uint32 `gorm:"unique"` // derivation index
+	Pkh      string `gorm:"unique"` // hash160 of xpub/0/idx
+	HashLock string ``              // set when this PKH is used by a BCH2SBCH swap
+}
+
 type Bch2SbchRecord struct {
 	gorm.Model
 	BchLockHeight    uint64         `gorm:"not null"` // got from tx
 	BchLockTxHash    string         `gorm:"unique"`   // got from tx
+	BchLockVout      uint32         ``                // got from tx, index of the deposit output
 	Value            uint64         `gorm:"not null"` // got from tx, in Sats
 	BchPrice         uint64         `gorm:"not null"` // got from tx, 8 decimals
 	RecipientPkh     string         `gorm:"not null"` // got from retData
 	SenderPkh        string         `gorm:"not null"` // got from retData
 	HashLock         string         `gorm:"unique"`   // got from retData, in Blocks
-	TimeLock         uint32         `gorm:"not null"` // got from retData
+	TimeLock         uint32         `gorm:"not null"` // got from retData, or the terms of bot if BchExpiresAt is set
+	BchExpiresAt     uint32         ``                // got from retData, unix timestamp of absolute expiration, 0 means relative
 	PenaltyBPS       uint16         `gorm:"not null"` // got from retData
 	SenderEvmAddr    string         `gorm:"not null"` // got from retData
 	HtlcScriptHash   string         `gorm:"not null"` // calculated
@@ -57,6 +398,14 @@ type Bch2SbchRecord struct {
 	Secret           string         ``                // set when status changed to Bch2SbchStatusSecretRevealed
 	BchUnlockTxHash  string         ``                // set when status changed to Bch2SbchStatusBchUnlocked
 	SbchRefundTxHash string         ``                // set when status changed to Bch2SbchStatusSbchRefunded
+	FilledValue      uint64         ``                // set when sBCH is locked for part of Value, in Sats, 0 means fully filled
+	RemainderTxHash  string         ``                // set when status changed to Bch2SbchStatusRemainderRefunded
+	Token            string         ``                // got from quote, SEP20 token symbol, empty means sBCH
+	Memo             string         ``                // got from retData, optional user reference ID
+	Affiliate        string         ``                // code of the affiliate tagged by memo, empty means none
+	ReferralPkh      string         ``                // got from retData, optional, paid by the BCH unlock tx
+	ReferralBPS      uint16         ``                // got from retData, 0 means no referral
+	CovenantHash     string         ``                // set by bot, hash of the covenant template matched by deposit, empty means v1
 	Status           Bch2SbchStatus `gorm:"not null"` //
 }
 
@@ -64,7 +413,7 @@ type Sbch2BchRecord struct {
 	gorm.Model
 	SbchLockTime     uint64         `gorm:"not null"` // got from event
 	SbchLockTxHash   string         `gorm:"unique"`   // got from event
-	Value            uint64         `gorm:"not null"` // got from txValue, in Sats
+	Value            uint64         `gorm:"not null"` // got from txValue, in Sats (tokens are scaled to 8 decimals)
 	SbchPrice        uint64         `gorm:"not null"` // got from event, 8 decimals
 	SbchSenderAddr   string         `gorm:"not null"` // got from event
 	BchRecipientPkh  string         `gorm:"not null"` // got from event
@@ -77,9 +426,23 @@ type Sbch2BchRecord struct {
 	Secret           string         ``                // set when status changed to Sbch2BchStatusSecretRevealed
 	SbchUnlockTxHash string         ``                // set when status changed to Sbch2BchStatusSbchUnlocked
 	BchRefundTxHash  string         ``                // set when status changed to Sbch2BchStatusBchRefunded
+	Token            string         ``                // got from HTLC address, SEP20 token symbol, empty means sBCH
+	Memo             string         ``                // got from calldata of lock tx, optional user reference ID
+	Affiliate        string         ``                // code of the affiliate tagged by memo, empty means none
+	ReferralPkh      string         ``                // set by bot, optional, paid by the BCH unlock tx
+	ReferralBPS      uint16         ``                // set by bot, 0 means no referral
+	CovenantHash     string         ``                // set by bot, hash of the current covenant template, empty means v1
 	Status           Sbch2BchStatus `gorm:"not null"` //
 }
 
+// finished swaps moved out of the hot tables by retention policy
+type ArchivedBch2SbchRecord struct {
+	Bch2SbchRecord
+}
+type ArchivedSbch2BchRecord struct {
+	Sbch2BchRecord
+}
+
 func (record *Bch2SbchRecord) UpdateStatusToSbchLocked(sbchLockTxHash string, sbchLockTxTime uint64) *Bch2SbchRecord {
 	record.Status = Bch2SbchStatusSbchLocked
 	record.SbchLockTxHash = sbchLockTxHash
@@ -102,6 +465,23 @@ func (record *Bch2SbchRecord) UpdateStatusToSbchRefunded(sbchRefundTxHash string
 	record.SbchRefundTxHash = sbchRefundTxHash
 	return record
 }
+func (record *Bch2SbchRecord) UpdateStatusToRemainderRefunded(remainderTxHash string) *Bch2SbchRecord {
+	record.Status = Bch2SbchStatusRemainderRefunded
+	record.RemainderTxHash = remainderTxHash
+	return record
+}
+
+func (record *Bch2SbchRecord) IsPartiallyFilled() bool {
+	return record.FilledValue > 0 && record.FilledValue < record.Value
+}
+
+// the part of Value which is swapped, in Sats
+func (record *Bch2SbchRecord) GetFilledValue() uint64 {
+	if record.IsPartiallyFilled() {
+		return record.FilledValue
+	}
+	return record.Value
+}
 
 func (record *Sbch2BchRecord) UpdateStatusToBchLocked(bchLockTxHash string) *Sbch2BchRecord {
 	record.Status = Sbch2BchStatusBchLocked
@@ -125,6 +505,29 @@ func (record *Sbch2BchRecord) UpdateStatusToBchRefunded(bchRefundTxHash string)
 	return record
 }
 
+func (record *EvmSwapRecord) UpdateStatusToBotLocked(botLockTxHash string, botLockTime uint64) *EvmSwapRecord {
+	record.Status = EvmSwapStatusBotLocked
+	record.BotLockTxHash = botLockTxHash
+	record.BotLockTime = botLockTime
+	return record
+}
+func (record *EvmSwapRecord) UpdateStatusToSecretRevealed(secret, userUnlockTxHash string) *EvmSwapRecord {
+	record.Status = EvmSwapStatusSecretRevealed
+	record.Secret = secret
+	record.UserUnlockTxHash = userUnlockTxHash
+	return record
+}
+func (record *EvmSwapRecord) UpdateStatusToBotUnlocked(botUnlockTxHash string) *EvmSwapRecord {
+	record.Status = EvmSwapStatusBotUnlocked
+	record.BotUnlockTxHash = botUnlockTxHash
+	return record
+}
+func (record *EvmSwapRecord) UpdateStatusToBotRefunded(botRefundTxHash string) *EvmSwapRecord {
+	record.Status = EvmSwapStatusBotRefunded
+	record.BotRefundTxHash = botRefundTxHash
+	return record
+}
+
 // ========== DB ==========
 
 type DB struct {
@@ -132,6 +535,10 @@ type DB struct {
 }
 
 func OpenDB(dbFile string) (DB, error) {
+	// concurrent writers (API, swap workers) wait for each other instead of failing
+	if !strings.Contains(dbFile, "?") {
+		dbFile += "?_busy_timeout=5000"
+	}
 	db, err := gorm.Open(sqlite.Open(dbFile), &gorm.Config{})
 	if err != nil {
 		return DB{}, err
@@ -139,8 +546,18 @@ func OpenDB(dbFile string) (DB, error) {
 	return DB{db}, nil
 }
 
+// all tables, new models must be added here
+var dbModels = []any{&Bch2SbchRecord{}, &Sbch2BchRecord{}, &LastHeights{},
+	&HdReceivePkh{}, &Quote{}, &SwapTx{}, &PendingBchTx{}, &PendingSbchTx{},
+	&SwapCost{}, &LeaderLease{}, &ArchivedBch2SbchRecord{}, &ArchivedSbch2BchRecord{},
+	&SwapEvent{}, &TreasuryProposal{}, &SwapStatusChange{}, &WebhookDelivery{},
+	&SwapTimer{}, &PendingEvmTx{}, &EvmPeerHeights{}, &EvmSwapRecord{}, &InventoryReservation{},
+	&SwapMilestone{}, &SuspectBchTx{}, &FeeRevenue{}, &AffiliateAccrual{}, &ChainJob{},
+	&SbasBchTx{}, &CounterpartyRisk{}, &FreezeEvent{}, &IndexedBchHtlc{}, &IndexedSbchHtlc{},
+	&EmailSubscription{}, &EmailDelivery{}}
+
 func (db DB) syncSchemas() error {
-	return db.db.AutoMigrate(&Bch2SbchRecord{}, &Sbch2BchRecord{}, &LastHeights{})
+	return db.db.AutoMigrate(dbModels...)
 }
 
 func (db DB) initLastHeights(lastBchHeight, lastSbchHeight uint64) error {
@@ -204,8 +621,18 @@ func (db DB) addBch2SbchRecord(record *Bch2SbchRecord) error {
 		return fmt.Errorf("missing required fields")
 	}
 
-	result := db.db.Create(record)
-	return result.Error
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(newBch2SbchStatusChange(record)).Error; err != nil {
+			return err
+		}
+		if err := addSwapMilestone(tx, DirectionBch2Sbch, record.HashLock, record.Status.String()); err != nil {
+			return err
+		}
+		return armSwapTimer(tx, newBch2SbchTimer(record, time.Now().Unix()))
+	})
 }
 
 func (db DB) addSbch2BchRecord(record *Sbch2BchRecord) error {
@@ -221,8 +648,18 @@ func (db DB) addSbch2BchRecord(record *Sbch2BchRecord) error {
 		return fmt.Errorf("missing required fields")
 	}
 
-	result := db.db.Create(record)
-	return result.Error
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(newSbch2BchStatusChange(record)).Error; err != nil {
+			return err
+		}
+		if err := addSwapMilestone(tx, DirectionSbch2Bch, record.HashLock, record.Status.String()); err != nil {
+			return err
+		}
+		return armSwapTimer(tx, newSbch2BchTimer(record, time.Now().Unix()))
+	})
 }
 
 func (db DB) getBch2SbchRecordsByStatus(status Bch2SbchStatus, limit int) (records []*Bch2SbchRecord, err error) {
@@ -234,6 +671,15 @@ func (db DB) getBch2SbchRecordsByStatus(status Bch2SbchStatus, limit int) (recor
 	return
 }
 
+func (db DB) getPartiallyFilledBch2SbchRecordsByStatus(status Bch2SbchStatus, limit int) (records []*Bch2SbchRecord, err error) {
+	result := db.db.Where("status = ? AND filled_value > 0 AND filled_value < value", status).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "updated_at"}, Desc: false}).
+		Limit(limit).
+		Find(&records)
+	err = result.Error
+	return
+}
+
 func (db DB) getSbch2BchRecordsByStatus(status Sbch2BchStatus, limit int) (records []*Sbch2BchRecord, err error) {
 	result := db.db.Where("status = ?", status).
 		Order(clause.OrderByColumn{Column: clause.Column{Name: "updated_at"}, Desc: false}).
@@ -277,9 +723,40 @@ func (db DB) updateBch2SbchRecord(record *Bch2SbchRecord) error {
 		if record.BchUnlockTxHash == "" {
 			return fmt.Errorf("BchUnlockTxHash is empty")
 		}
+	} else if record.Status == Bch2SbchStatusRemainderRefunded {
+		if record.RemainderTxHash == "" {
+			return fmt.Errorf("RemainderTxHash is empty")
+		}
 	} //else if record.Status == Bch2SbchStatusTooLateToLockSbch {}
-	result := db.db.Save(record)
-	return result.Error
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		var old Bch2SbchRecord
+		if err := tx.Select("status").Where("id = ?", record.ID).Limit(1).Find(&old).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(record).Error; err != nil {
+			return err
+		}
+		if old.Status == record.Status {
+			return nil
+		}
+		if err := tx.Create(newBch2SbchStatusChange(record)).Error; err != nil {
+			return err
+		}
+		if err := addSwapMilestone(tx, DirectionBch2Sbch, record.HashLock, record.Status.String()); err != nil {
+			return err
+		}
+		if err := countRiskOutcome(tx, getBch2SbchRiskOutcome(record.Status),
+			record.SenderPkh, record.SenderEvmAddr); err != nil {
+			return err
+		}
+		if old.Status == Bch2SbchStatusNew {
+			// locked by bot or given up
+			if err := releaseReservation(tx, record.HashLock); err != nil {
+				return err
+			}
+		}
+		return armSwapTimer(tx, newBch2SbchTimer(record, time.Now().Unix()))
+	})
 }
 
 func (db DB) updateSbch2BchRecord(record *Sbch2BchRecord) error {
@@ -299,8 +776,35 @@ func (db DB) updateSbch2BchRecord(record *Sbch2BchRecord) error {
 			return fmt.Errorf("BchUnlockTxHash is empty")
 		}
 	} //else if record.Status == Sbch2BchStatusTooLateToLockBch {}
-	result := db.db.Save(record)
-	return result.Error
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		var old Sbch2BchRecord
+		if err := tx.Select("status").Where("id = ?", record.ID).Limit(1).Find(&old).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(record).Error; err != nil {
+			return err
+		}
+		if old.Status == record.Status {
+			return nil
+		}
+		if err := tx.Create(newSbch2BchStatusChange(record)).Error; err != nil {
+			return err
+		}
+		if err := addSwapMilestone(tx, DirectionSbch2Bch, record.HashLock, record.Status.String()); err != nil {
+			return err
+		}
+		if err := countRiskOutcome(tx, getSbch2BchRiskOutcome(record.Status),
+			record.BchRecipientPkh, record.SbchSenderAddr); err != nil {
+			return err
+		}
+		if old.Status == Sbch2BchStatusNew {
+			// locked by bot or given up
+			if err := releaseReservation(tx, record.HashLock); err != nil {
+				return err
+			}
+		}
+		return armSwapTimer(tx, newSbch2BchTimer(record, time.Now().Unix()))
+	})
 }
 
 func (db DB) GetAllBch2SbchRecords() (records []*Bch2SbchRecord, err error) {
@@ -313,3 +817,1059 @@ func (db DB) GetAllSbch2BchRecords() (records []*Sbch2BchRecord, err error) {
 	err = result.Error
 	return
 }
+
+// archived records are included
+func (db DB) getBch2SbchRecordsCreatedSince(t time.Time) (records []*Bch2SbchRecord, err error) {
+	result := db.db.Where("created_at >= ?", t).Find(&records)
+	if err = result.Error; err != nil {
+		return
+	}
+	var archived []*ArchivedBch2SbchRecord
+	result = db.db.Where("created_at >= ?", t).Find(&archived)
+	for _, record := range archived {
+		records = append(records, &record.Bch2SbchRecord)
+	}
+	err = result.Error
+	return
+}
+
+// archived records are included
+func (db DB) getSbch2BchRecordsCreatedSince(t time.Time) (records []*Sbch2BchRecord, err error) {
+	result := db.db.Where("created_at >= ?", t).Find(&records)
+	if err = result.Error; err != nil {
+		return
+	}
+	var archived []*ArchivedSbch2BchRecord
+	result = db.db.Where("created_at >= ?", t).Find(&archived)
+	for _, record := range archived {
+		records = append(records, &record.Sbch2BchRecord)
+	}
+	err = result.Error
+	return
+}
+
+func (db DB) addHdReceivePkh(pkh *HdReceivePkh) error {
+	if pkh.Pkh == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	result := db.db.Create(pkh)
+	return result.Error
+}
+
+func (db DB) getAllHdReceivePkhs() (pkhs []*HdReceivePkh, err error) {
+	result := db.db.Order("idx").Find(&pkhs)
+	err = result.Error
+	return
+}
+
+func (db DB) setHdReceivePkhHashLock(idx uint32, hashLock string) error {
+	result := db.db.Model(&HdReceivePkh{}).
+		Where("idx = ?", idx).
+		Update("hash_lock", hashLock)
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected != 1 {
+		return fmt.Errorf("HD PKH#%d not found", idx)
+	}
+	return nil
+}
+
+// POST /quote is public, so an unexpired quote can not be replaced by anyone who learns its hash lock
+func (db DB) addQuote(quote *Quote, now int64) error {
+	if quote.HashLock == "" ||
+		quote.Direction == "" ||
+		quote.Value == 0 ||
+		quote.Price == 0 ||
+		quote.ValidUntil == 0 {
+
+		return fmt.Errorf("missing required fields")
+	}
+
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		result := tx.Model(&Quote{}).
+			Where("hash_lock = ? AND valid_until >= ?", quote.HashLock, now).
+			Count(&n)
+		if result.Error != nil {
+			return result.Error
+		}
+		if n > 0 {
+			return ErrQuoteExists
+		}
+
+		// a newer quote replaces the expired one of the same hash lock
+		result = tx.Unscoped().Where("hash_lock = ?", quote.HashLock).Delete(&Quote{})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Create(quote).Error
+	})
+}
+
+// delete the quote and the reservation of its hash lock
+func (db DB) deleteQuote(quote *Quote) error {
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(quote).Error; err != nil {
+			return err
+		}
+		return releaseReservation(tx, quote.HashLock)
+	})
+}
+
+func (db DB) getQuoteByHashLock(hashLock string) (quote *Quote, err error) {
+	quote = &Quote{}
+	result := db.db.Where("hash_lock = ?", hashLock).First(quote)
+	return quote, result.Error
+}
+
+// unexpired quotes whose hashes are not committed on chain yet
+func (db DB) getUncommittedQuotes(now int64, limit int) (quotes []*Quote, err error) {
+	result := db.db.Where("quote_hash != '' AND commit_tx = '' AND valid_until >= ?", now).
+		Order("id").Limit(limit).Find(&quotes)
+	err = result.Error
+	return
+}
+
+// quotes of direction valid since validSince, deposits made for them may still be mined
+func (db DB) getValidQuotes(direction string, validSince int64) (quotes []*Quote, err error) {
+	result := db.db.Where("direction = ? AND valid_until >= ?", direction, validSince).Find(&quotes)
+	err = result.Error
+	return
+}
+
+func (db DB) setQuotesCommitted(ids []uint, commitTx string) error {
+	result := db.db.Model(&Quote{}).Where("id IN ?", ids).Update("commit_tx", commitTx)
+	return result.Error
+}
+
+func (db DB) addSwapTx(tx *SwapTx) error {
+	if tx.HashLock == "" ||
+		tx.Leg == "" ||
+		tx.TxHash == "" ||
+		tx.RawTx == "" {
+
+		return fmt.Errorf("missing required fields")
+	}
+
+	// the same tx may be observed more than once
+	result := db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(tx)
+	return result.Error
+}
+
+func (db DB) getSwapTxsByHashLock(hashLock string) (txs []*SwapTx, err error) {
+	result := db.db.Where("hash_lock = ?", hashLock).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+		Find(&txs)
+	err = result.Error
+	return
+}
+
+// the same event may be published again if blocks are scanned again,
+// returns false if it is published already
+func (db DB) addSwapEvent(event *SwapEvent) (bool, error) {
+	if event.Kind == "" || event.Key == "" || event.Payload == "" {
+		return false, fmt.Errorf("missing required fields")
+	}
+	result := db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (db DB) getUnhandledSwapEvents(limit int) (events []*SwapEvent, err error) {
+	result := db.db.Where("handled = ?", false).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+		Limit(limit).
+		Find(&events)
+	err = result.Error
+	return
+}
+
+func (db DB) getSwapEventsAfter(id uint, limit int) (events []*SwapEvent, err error) {
+	result := db.db.Where("id > ?", id).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+		Limit(limit).
+		Find(&events)
+	err = result.Error
+	return
+}
+
+func (db DB) markSwapEventHandled(id uint) error {
+	result := db.db.Model(&SwapEvent{}).Where("id = ?", id).Update("handled", true)
+	return result.Error
+}
+
+// events rolled back by reorgs are deleted, so that they can be published again
+func (db DB) deleteSwapEvent(id uint) error {
+	return db.db.Unscoped().Delete(&SwapEvent{}, id).Error
+}
+
+func (db DB) addTreasuryProposal(proposal *TreasuryProposal) error {
+	if proposal.ToAddr == "" || proposal.UnsignedTx == "" || proposal.Status == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	result := db.db.Create(proposal)
+	return result.Error
+}
+
+func (db DB) getTreasuryProposal(id uint) (*TreasuryProposal, error) {
+	var proposal TreasuryProposal
+	result := db.db.First(&proposal, id)
+	return &proposal, result.Error
+}
+
+// latest first
+func (db DB) getTreasuryProposals(limit int) (proposals []*TreasuryProposal, err error) {
+	result := db.db.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: true}).
+		Limit(limit).
+		Find(&proposals)
+	err = result.Error
+	return
+}
+
+func (db DB) updateTreasuryProposal(proposal *TreasuryProposal) error {
+	result := db.db.Save(proposal)
+	return result.Error
+}
+
+// timers are armed once, nil timer is ignored
+// reserve amount of the asset for the swap, or update its reservation, it fails with
+// ErrNotEnoughInventory if other active reservations plus amount exceed free inventory
+func (db DB) reserveInventory(res *InventoryReservation, free uint64, now int64) error {
+	reservationMu.Lock()
+	defer reservationMu.Unlock()
+
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("expires_at <= ?", now).Delete(&InventoryReservation{}).Error; err != nil {
+			return err
+		}
+		var reserved uint64
+		err := tx.Model(&InventoryReservation{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("asset = ? AND hash_lock <> ?", res.Asset, res.HashLock).
+			Scan(&reserved).Error
+		if err != nil {
+			return err
+		}
+		if reserved+res.Amount > free {
+			return fmt.Errorf("%w: %s, free: %d, reserved: %d, amount: %d",
+				ErrNotEnoughInventory, res.Asset, free, reserved, res.Amount)
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hash_lock"}},
+			DoUpdates: clause.AssignmentColumns([]string{"updated_at", "asset", "amount", "expires_at"}),
+		}).Create(res).Error
+	})
+}
+
+func releaseReservation(tx *gorm.DB, hashLock string) error {
+	return tx.Unscoped().Where("hash_lock = ?", hashLock).Delete(&InventoryReservation{}).Error
+}
+
+// total amount of active reservations of the asset
+func (db DB) getReservedInventory(asset string, now int64) (reserved uint64, err error) {
+	err = db.db.Model(&InventoryReservation{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("asset = ? AND expires_at > ?", asset, now).
+		Scan(&reserved).Error
+	return
+}
+
+// total amount of active reservations of the asset made for other swaps than hashLock
+func (db DB) getReservedInventoryOfOthers(asset, hashLock string, now int64) (reserved uint64, err error) {
+	err = db.db.Model(&InventoryReservation{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("asset = ? AND hash_lock <> ? AND expires_at > ?", asset, hashLock, now).
+		Scan(&reserved).Error
+	return
+}
+
+func armSwapTimer(tx *gorm.DB, timer *SwapTimer) error {
+	if timer == nil {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(timer).Error
+}
+
+func (db DB) armSwapTimer(timer *SwapTimer) error {
+	return armSwapTimer(db.db, timer)
+}
+
+// return nil if there is no unfired timer
+func (db DB) getNextSwapTimer() (*SwapTimer, error) {
+	var timers []*SwapTimer
+	result := db.db.Where("fired = ?", false).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "fire_at"}, Desc: false}).
+		Limit(1).
+		Find(&timers)
+	if result.Error != nil || len(timers) == 0 {
+		return nil, result.Error
+	}
+	return timers[0], nil
+}
+
+func (db DB) getDueSwapTimers(now int64, limit int) (timers []*SwapTimer, err error) {
+	result := db.db.Where("fired = ? AND fire_at <= ?", false, now).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "fire_at"}, Desc: false}).
+		Limit(limit).
+		Find(&timers)
+	err = result.Error
+	return
+}
+
+func (db DB) updateSwapTimer(timer *SwapTimer) error {
+	result := db.db.Save(timer)
+	return result.Error
+}
+
+func addSwapMilestone(tx *gorm.DB, direction, hashLock, status string) error {
+	milestone := &SwapMilestone{Direction: direction, HashLock: hashLock, Status: status, At: time.Now().Unix()}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(milestone).Error
+}
+
+// latencies of swaps delivered at or after since, from the milestone after afterId
+func (db DB) getSwapLatencies(afterId uint, since int64, limit int) (latencies []*SwapLatency, err error) {
+	err = db.db.Table("swap_milestones AS d").
+		Select("d.id AS id, d.direction AS direction, d.at AS delivered_at, d.at - n.at AS latency").
+		Joins("JOIN swap_milestones AS n ON n.direction = d.direction AND n.hash_lock = d.hash_lock AND n.status = ?",
+			MilestoneDepositConfirmed).
+		Where("d.status = ? AND d.id > ? AND d.at >= ?", MilestoneDelivered, afterId, since).
+		Order("d.id").
+		Limit(limit).
+		Scan(&latencies).Error
+	return
+}
+
+// in the order statuses were reached
+func (db DB) getSwapMilestones(direction, hashLock string) (milestones []*SwapMilestone, err error) {
+	result := db.db.Where("direction = ? AND hash_lock = ?", direction, hashLock).
+		Order("at").Order("id").
+		Find(&milestones)
+	err = result.Error
+	return
+}
+
+func (db DB) getSwapStatusChanges(limit int) (changes []*SwapStatusChange, err error) {
+	result := db.db.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+		Limit(limit).
+		Find(&changes)
+	err = result.Error
+	return
+}
+
+// replace status changes with their deliveries, changes are soft deleted so that their IDs are not reused
+func (db DB) fanOutSwapStatusChanges(changes []*SwapStatusChange, deliveries []*WebhookDelivery,
+	emails []*EmailDelivery) error {
+
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		if len(deliveries) > 0 {
+			if err := tx.Create(deliveries).Error; err != nil {
+				return err
+			}
+		}
+		if len(emails) > 0 {
+			if err := tx.Create(emails).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(changes).Error
+	})
+}
+
+func (db DB) getDueWebhookDeliveries(now int64, limit int) (deliveries []*WebhookDelivery, err error) {
+	result := db.db.Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryPending, now).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+		Limit(limit).
+		Find(&deliveries)
+	err = result.Error
+	return
+}
+
+func (db DB) getWebhookDeliveriesByStatus(status string, limit int) (deliveries []*WebhookDelivery, err error) {
+	result := db.db.Where("status = ?", status).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+		Limit(limit).
+		Find(&deliveries)
+	err = result.Error
+	return
+}
+
+func (db DB) updateWebhookDelivery(delivery *WebhookDelivery) error {
+	result := db.db.Save(delivery)
+	return result.Error
+}
+
+func (db DB) deleteWebhookDelivery(delivery *WebhookDelivery) error {
+	result := db.db.Unscoped().Delete(delivery)
+	return result.Error
+}
+
+func (db DB) addEmailSubscription(sub *EmailSubscription) error {
+	if sub.HashLock == "" ||
+		sub.Direction == "" ||
+		sub.Email == "" ||
+		sub.Token == "" {
+
+		return fmt.Errorf("missing required fields")
+	}
+
+	// a newer quote replaces the subscription of the old one
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("hash_lock = ?", sub.HashLock).Delete(&EmailSubscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Create(sub).Error
+	})
+}
+
+func (db DB) getEmailSubscriptions(hashLocks []string) (subs []*EmailSubscription, err error) {
+	result := db.db.Where("hash_lock IN ? AND unsubscribed = ?", hashLocks, false).Find(&subs)
+	err = result.Error
+	return
+}
+
+// returns false if the token is unknown, pending deliveries of the subscription are dropped
+func (db DB) unsubscribeEmail(token string) (ok bool, err error) {
+	err = db.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&EmailSubscription{}).
+			Where("token = ?", token).
+			Update("unsubscribed", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		ok = result.RowsAffected > 0
+		return tx.Unscoped().
+			Where("token = ? AND status = ?", token, WebhookDeliveryPending).
+			Delete(&EmailDelivery{}).Error
+	})
+	return
+}
+
+func (db DB) getDueEmailDeliveries(now int64, limit int) (deliveries []*EmailDelivery, err error) {
+	result := db.db.Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryPending, now).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+		Limit(limit).
+		Find(&deliveries)
+	err = result.Error
+	return
+}
+
+func (db DB) updateEmailDelivery(delivery *EmailDelivery) error {
+	result := db.db.Save(delivery)
+	return result.Error
+}
+
+func (db DB) deleteEmailDelivery(delivery *EmailDelivery) error {
+	result := db.db.Unscoped().Delete(delivery)
+	return result.Error
+}
+
+func (db DB) addPendingBchTx(tx *PendingBchTx) error {
+	if tx.HashLock == "" ||
+		tx.Leg == "" ||
+		tx.TxHash == "" ||
+		tx.RawTx == "" {
+
+		return fmt.Errorf("missing required fields")
+	}
+	result := db.db.Create(tx)
+	return result.Error
+}
+
+func (db DB) getUnconfirmedBchTxs(limit int) (txs []*PendingBchTx, err error) {
+	result := db.db.Where("confirmed = ?", false).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+		Limit(limit).
+		Find(&txs)
+	err = result.Error
+	return
+}
+
+func (db DB) updatePendingBchTx(tx *PendingBchTx) error {
+	result := db.db.Save(tx)
+	return result.Error
+}
+
+// a replacement tx overwrites the old one of the same nonce
+func (db DB) savePendingSbchTx(tx *PendingSbchTx) error {
+	if tx.TxHash == "" || tx.RawTx == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	result := db.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "nonce"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tx_hash", "raw_tx", "updated_at"}),
+	}).Create(tx)
+	return result.Error
+}
+
+func (db DB) getPendingSbchTxs() (txs []*PendingSbchTx, err error) {
+	result := db.db.Order(clause.OrderByColumn{Column: clause.Column{Name: "nonce"}, Desc: false}).
+		Find(&txs)
+	err = result.Error
+	return
+}
+
+func (db DB) deletePendingSbchTx(nonce uint64) error {
+	result := db.db.Unscoped().Where("nonce = ?", nonce).Delete(&PendingSbchTx{})
+	return result.Error
+}
+
+func (db DB) deletePendingSbchTxsBelow(nonce uint64) error {
+	result := db.db.Unscoped().Where("nonce < ?", nonce).Delete(&PendingSbchTx{})
+	return result.Error
+}
+
+func (db DB) savePendingEvmTx(tx *PendingEvmTx) error {
+	if tx.Chain == "" || tx.TxHash == "" || tx.RawTx == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	result := db.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain"}, {Name: "nonce"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tx_hash", "raw_tx", "updated_at"}),
+	}).Create(tx)
+	return result.Error
+}
+
+func (db DB) getPendingEvmTxs(chain string) (txs []*PendingEvmTx, err error) {
+	result := db.db.Where("chain = ?", chain).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "nonce"}, Desc: false}).
+		Find(&txs)
+	err = result.Error
+	return
+}
+
+func (db DB) deletePendingEvmTx(chain string, nonce uint64) error {
+	result := db.db.Unscoped().Where("chain = ? AND nonce = ?", chain, nonce).Delete(&PendingEvmTx{})
+	return result.Error
+}
+
+func (db DB) deletePendingEvmTxsBelow(chain string, nonce uint64) error {
+	result := db.db.Unscoped().Where("chain = ? AND nonce < ?", chain, nonce).Delete(&PendingEvmTx{})
+	return result.Error
+}
+
+// zero heights if the peer is never scanned
+func (db DB) getEvmPeerHeights(peer string) (*EvmPeerHeights, error) {
+	heights := &EvmPeerHeights{}
+	result := db.db.Where(EvmPeerHeights{Peer: peer}).FirstOrInit(heights)
+	return heights, result.Error
+}
+
+func (db DB) setEvmPeerHeights(heights *EvmPeerHeights) error {
+	if heights.Peer == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	return db.db.Save(heights).Error
+}
+
+func (db DB) addEvmSwapRecord(record *EvmSwapRecord) error {
+	if record.Peer == "" ||
+		record.Direction == "" ||
+		record.HashLock == "" ||
+		record.UserLockTxHash == "" ||
+		record.UserAddr == "" ||
+		record.RecipientAddr == "" ||
+		record.Value == 0 ||
+		record.TimeLock == 0 {
+
+		return fmt.Errorf("missing required fields")
+	}
+	return db.db.Create(record).Error
+}
+
+func (db DB) getEvmSwapRecordByHashLock(hashLock string) (record *EvmSwapRecord, err error) {
+	record = &EvmSwapRecord{}
+	result := db.db.Where("hash_lock = ?", hashLock).First(record)
+	return record, result.Error
+}
+
+func (db DB) getEvmSwapRecordsByStatus(peer string, status EvmSwapStatus, limit int) (records []*EvmSwapRecord, err error) {
+	result := db.db.Where("peer = ? AND status = ?", peer, status).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "updated_at"}, Desc: false}).
+		Limit(limit).
+		Find(&records)
+	err = result.Error
+	return
+}
+
+func (db DB) updateEvmSwapRecord(record *EvmSwapRecord) error {
+	switch record.Status {
+	case EvmSwapStatusBotLocked:
+		if record.BotLockTxHash == "" {
+			return fmt.Errorf("BotLockTxHash is empty")
+		}
+	case EvmSwapStatusSecretRevealed:
+		if record.Secret == "" {
+			return fmt.Errorf("secret is empty")
+		}
+	case EvmSwapStatusBotUnlocked:
+		if record.BotUnlockTxHash == "" {
+			return fmt.Errorf("BotUnlockTxHash is empty")
+		}
+	case EvmSwapStatusBotRefunded:
+		if record.BotRefundTxHash == "" {
+			return fmt.Errorf("BotRefundTxHash is empty")
+		}
+	}
+	return db.db.Save(record).Error
+}
+
+func (db DB) addSwapCost(cost *SwapCost) error {
+	if cost.HashLock == "" || cost.Direction == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	result := db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(cost)
+	return result.Error
+}
+
+func (db DB) getSwapCost(hashLock string) (cost *SwapCost, err error) {
+	cost = &SwapCost{}
+	result := db.db.Where("hash_lock = ?", hashLock).First(cost)
+	return cost, result.Error
+}
+
+func (db DB) addSwapBchMinerFee(hashLock string, fee uint64) error {
+	result := db.db.Model(&SwapCost{}).Where("hash_lock = ?", hashLock).
+		Update("bch_miner_fee", gorm.Expr("bch_miner_fee + ?", fee))
+	return result.Error
+}
+
+func (db DB) addSwapSbchGasFee(hashLock string, fee uint64) error {
+	result := db.db.Model(&SwapCost{}).Where("hash_lock = ?", hashLock).
+		Update("sbch_gas_fee", gorm.Expr("sbch_gas_fee + ?", fee))
+	return result.Error
+}
+
+type SwapCostTotals struct {
+	Swaps         int64
+	ServiceFee    uint64
+	EstimatedCost uint64
+	BchMinerFee   uint64
+	SbchGasFee    uint64
+}
+
+func (db DB) getSwapCostTotals() (totals SwapCostTotals, err error) {
+	result := db.db.Model(&SwapCost{}).Select(
+		"count(*) as swaps, " +
+			"coalesce(sum(service_fee), 0) as service_fee, " +
+			"coalesce(sum(estimated_cost), 0) as estimated_cost, " +
+			"coalesce(sum(bch_miner_fee), 0) as bch_miner_fee, " +
+			"coalesce(sum(sbch_gas_fee), 0) as sbch_gas_fee").
+		Scan(&totals)
+	err = result.Error
+	return
+}
+
+func (db DB) addFeeRevenue(revenue *FeeRevenue) error {
+	if revenue.HashLock == "" || revenue.Asset == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	result := db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(revenue)
+	return result.Error
+}
+
+func (db DB) getUnsweptFeeRevenues(asset string, limit int) (revenues []*FeeRevenue, err error) {
+	result := db.db.Where("asset = ? AND sweep_tx = ''", asset).Order("id").Limit(limit).Find(&revenues)
+	err = result.Error
+	return
+}
+
+func (db DB) setFeeRevenuesSwept(ids []uint, sweepTx string) error {
+	result := db.db.Model(&FeeRevenue{}).Where("id IN ?", ids).Update("sweep_tx", sweepTx)
+	return result.Error
+}
+
+func (db DB) addAffiliateAccrual(accrual *AffiliateAccrual) error {
+	if accrual.HashLock == "" || accrual.Code == "" || accrual.Asset == "" {
+		return fmt.Errorf("missing required fields")
+	}
+	result := db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(accrual)
+	return result.Error
+}
+
+func (db DB) getUnpaidAffiliateAccruals(code, asset string, limit int) (accruals []*AffiliateAccrual, err error) {
+	result := db.db.Where("code = ? AND asset = ? AND payout_tx = ''", code, asset).
+		Order("id").Limit(limit).Find(&accruals)
+	err = result.Error
+	return
+}
+
+func (db DB) setAffiliateAccrualsPaid(ids []uint, payoutTx string) error {
+	result := db.db.Model(&AffiliateAccrual{}).Where("id IN ?", ids).Update("payout_tx", payoutTx)
+	return result.Error
+}
+
+// accruals of an affiliate created in [from, to)
+func (db DB) getAffiliateAccruals(code string, from, to time.Time) (accruals []*AffiliateAccrual, err error) {
+	result := db.db.Where("code = ? AND created_at >= ? AND created_at < ?", code, from, to).
+		Order("id").Find(&accruals)
+	err = result.Error
+	return
+}
+
+func (db DB) getLeaderLease(name string) (lease *LeaderLease, err error) {
+	lease = &LeaderLease{}
+	result := db.db.Where("name = ?", name).First(lease)
+	return lease, result.Error
+}
+
+func (db DB) addLeaderLease(lease *LeaderLease) error {
+	result := db.db.Create(lease)
+	return result.Error
+}
+
+// renew the lease if it is still held by holder with the same epoch
+func (db DB) renewLeaderLease(name, holder string, epoch uint64, now int64) (bool, error) {
+	result := db.db.Model(&LeaderLease{}).
+		Where("name = ? AND holder = ? AND epoch = ?", name, holder, epoch).
+		Update("renewed_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// take over the lease if nobody else has taken it over since epoch
+func (db DB) takeOverLeaderLease(name, holder string, epoch uint64, now int64) (bool, error) {
+	result := db.db.Model(&LeaderLease{}).
+		Where("name = ? AND epoch = ?", name, epoch).
+		Updates(map[string]any{"holder": holder, "epoch": epoch + 1, "renewed_at": now})
+	return result.RowsAffected == 1, result.Error
+}
+
+// claim the job of idemKey for owner until claimedUntil, the job is created if it does not exist,
+// returns nil if it is done, or claimed by another owner and the claim has not expired
+func (db DB) claimChainJob(idemKey, hashLock, leg, owner string, now, claimedUntil int64) (job *ChainJob, err error) {
+	err = db.db.Transaction(func(tx *gorm.DB) error {
+		job = &ChainJob{}
+		result := tx.Where("idem_key = ?", idemKey).First(job)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			job = &ChainJob{
+				IdemKey:      idemKey,
+				HashLock:     hashLock,
+				Leg:          leg,
+				Owner:        owner,
+				ClaimedUntil: claimedUntil,
+				Attempts:     1,
+			}
+			return tx.Create(job).Error
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if job.Done || job.Owner != owner && job.ClaimedUntil >= now {
+			job = nil
+			return nil
+		}
+		// the claim is checked again in case it is taken over meanwhile
+		result = tx.Model(&ChainJob{}).
+			Where("id = ? AND owner = ? AND claimed_until = ?", job.ID, job.Owner, job.ClaimedUntil).
+			Updates(map[string]any{"owner": owner, "claimed_until": claimedUntil, "attempts": job.Attempts + 1})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			job = nil
+			return nil
+		}
+		job.Owner = owner
+		job.ClaimedUntil = claimedUntil
+		job.Attempts++
+		return nil
+	})
+	return
+}
+
+func (db DB) setChainJobTx(id uint, txHash, rawTx string) error {
+	result := db.db.Model(&ChainJob{}).Where("id = ?", id).
+		Updates(map[string]any{"tx_hash": txHash, "raw_tx": rawTx})
+	return result.Error
+}
+
+func (db DB) finishChainJob(id uint) error {
+	result := db.db.Model(&ChainJob{}).Where("id = ?", id).
+		Updates(map[string]any{"done": true, "claimed_until": 0})
+	return result.Error
+}
+
+// give up the claim, so that the job can be retried by any worker at once
+func (db DB) releaseChainJob(id uint, lastError string) error {
+	result := db.db.Model(&ChainJob{}).Where("id = ?", id).
+		Updates(map[string]any{"claimed_until": 0, "last_error": lastError})
+	return result.Error
+}
+
+func (db DB) getChainJob(idemKey string) (job *ChainJob, err error) {
+	job = &ChainJob{}
+	result := db.db.Where("idem_key = ?", idemKey).First(job)
+	return job, result.Error
+}
+
+// swaps in these states never change again
+var (
+	finishedBch2SbchStatuses = []Bch2SbchStatus{
+		Bch2SbchStatusBchUnlocked, // partially filled ones are not finished until remainder is refunded
+		Bch2SbchStatusSbchRefunded,
+		Bch2SbchStatusTooLateToLockSbch,
+		Bch2SbchStatusPriceChanged,
+		Bch2SbchStatusUnprofitable,
+		Bch2SbchStatusRemainderRefunded,
+		Bch2SbchStatusRejected,
+		Bch2SbchStatusCancelled,
+	}
+	finishedSbch2BchStatuses = []Sbch2BchStatus{
+		Sbch2BchStatusSbchUnlocked,
+		Sbch2BchStatusBchRefunded,
+		Sbch2BchStatusTooLateToLockBch,
+		Sbch2BchStatusPriceChanged,
+		Sbch2BchStatusUnprofitable,
+		Sbch2BchStatusRejected,
+		Sbch2BchStatusCancelled,
+	}
+)
+
+// move finished BCH2SBCH records not updated since t to archive table
+func (db DB) archiveBch2SbchRecords(t time.Time, limit int) (n int, err error) {
+	err = db.db.Transaction(func(tx *gorm.DB) error {
+		var records []*Bch2SbchRecord
+		result := tx.Where("status IN ? AND updated_at < ?", finishedBch2SbchStatuses, t).
+			Where("NOT (status = ? AND filled_value > 0 AND filled_value < value)", Bch2SbchStatusBchUnlocked).
+			Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+			Limit(limit).
+			Find(&records)
+		if result.Error != nil || len(records) == 0 {
+			return result.Error
+		}
+
+		archived := make([]*ArchivedBch2SbchRecord, len(records))
+		for i, record := range records {
+			archived[i] = &ArchivedBch2SbchRecord{Bch2SbchRecord: *record}
+		}
+		if err := tx.Create(archived).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(records).Error; err != nil {
+			return err
+		}
+		n = len(records)
+		return nil
+	})
+	return
+}
+
+// move finished SBCH2BCH records not updated since t to archive table
+func (db DB) archiveSbch2BchRecords(t time.Time, limit int) (n int, err error) {
+	err = db.db.Transaction(func(tx *gorm.DB) error {
+		var records []*Sbch2BchRecord
+		result := tx.Where("status IN ? AND updated_at < ?", finishedSbch2BchStatuses, t).
+			Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: false}).
+			Limit(limit).
+			Find(&records)
+		if result.Error != nil || len(records) == 0 {
+			return result.Error
+		}
+
+		archived := make([]*ArchivedSbch2BchRecord, len(records))
+		for i, record := range records {
+			archived[i] = &ArchivedSbch2BchRecord{Sbch2BchRecord: *record}
+		}
+		if err := tx.Create(archived).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(records).Error; err != nil {
+			return err
+		}
+		n = len(records)
+		return nil
+	})
+	return
+}
+
+func (db DB) isBch2SbchRecordArchived(hashLock string) bool {
+	var n int64
+	db.db.Model(&ArchivedBch2SbchRecord{}).Where("hash_lock = ?", hashLock).Count(&n)
+	return n > 0
+}
+
+func (db DB) isSbch2BchRecordArchived(hashLock string) bool {
+	var n int64
+	db.db.Model(&ArchivedSbch2BchRecord{}).Where("hash_lock = ?", hashLock).Count(&n)
+	return n > 0
+}
+
+// delete quotes expired before t
+func (db DB) pruneQuotes(t time.Time) (int64, error) {
+	result := db.db.Unscoped().Where("valid_until < ?", t.Unix()).Delete(&Quote{})
+	return result.RowsAffected, result.Error
+}
+
+// delete handled events not updated since t
+func (db DB) pruneSwapEvents(t time.Time) (int64, error) {
+	result := db.db.Unscoped().Where("handled = ? AND updated_at < ?", true, t).Delete(&SwapEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// a suspect found again by a later scan is ignored
+func (db DB) addSuspectBchTx(suspect *SuspectBchTx) error {
+	return db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(suspect).Error
+}
+
+// latest suspects first
+func (db DB) getSuspectBchTxs(limit int) (suspects []*SuspectBchTx, err error) {
+	result := db.db.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: true}).
+		Limit(limit).
+		Find(&suspects)
+	err = result.Error
+	return
+}
+
+// delete suspects found before t
+func (db DB) pruneSuspectBchTxs(t time.Time) (int64, error) {
+	result := db.db.Unscoped().Where("created_at < ?", t).Delete(&SuspectBchTx{})
+	return result.RowsAffected, result.Error
+}
+
+// increase the outcome column (completed|refunded|cancelled) of the counterparties of a swap,
+// outcome may be empty
+func countRiskOutcome(tx *gorm.DB, outcome, userBchPkh, userEvmAddr string) error {
+	if outcome == "" {
+		return nil
+	}
+	for _, counterparty := range getCounterparties(userBchPkh, userEvmAddr) {
+		result := tx.Model(&CounterpartyRisk{}).Where("counterparty = ?", counterparty).
+			Update(outcome, gorm.Expr(outcome+" + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			continue
+		}
+		risk := &CounterpartyRisk{Counterparty: counterparty}
+		risk.addOutcome(outcome)
+		if err := tx.Create(risk).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db DB) getCounterpartyRisks(counterparties []string) (risks []*CounterpartyRisk, err error) {
+	result := db.db.Where("counterparty IN ?", counterparties).Find(&risks)
+	err = result.Error
+	return
+}
+
+// swaps of a counterparty which bot has locked for and user has not claimed yet
+func (db DB) getInFlightRecordsOf(userBchPkh, userEvmAddr string,
+) (b2sRecords []*Bch2SbchRecord, s2bRecords []*Sbch2BchRecord, err error) {
+	err = db.db.Where("status = ? AND (sender_pkh = ? OR sender_evm_addr = ?)",
+		Bch2SbchStatusSbchLocked, userBchPkh, userEvmAddr).Find(&b2sRecords).Error
+	if err != nil {
+		return
+	}
+	err = db.db.Where("status = ? AND (bch_recipient_pkh = ? OR sbch_sender_addr = ?)",
+		Sbch2BchStatusBchLocked, userBchPkh, userEvmAddr).Find(&s2bRecords).Error
+	return
+}
+
+// swaps which still need the covenant templates they were taken with, but none of covenantHashes:
+// the BCH deposit is to be unlocked (bch2sbch) or the BCH lock may be refunded (sbch2bch)
+func (db DB) countInFlightSwapsOfOtherCovenants(covenantHashes []string) (n int64, err error) {
+	var n2 int64
+	err = db.db.Model(&Bch2SbchRecord{}).
+		Where("status IN ? AND covenant_hash != '' AND covenant_hash NOT IN ?", []Bch2SbchStatus{
+			Bch2SbchStatusNew, Bch2SbchStatusSbchLocked, Bch2SbchStatusSecretRevealed,
+		}, covenantHashes).Count(&n).Error
+	if err != nil {
+		return
+	}
+	err = db.db.Model(&Sbch2BchRecord{}).
+		Where("status = ? AND covenant_hash != '' AND covenant_hash NOT IN ?",
+			Sbch2BchStatusBchLocked, covenantHashes).Count(&n2).Error
+	return n + n2, err
+}
+
+func (db DB) addFreezeEvent(event *FreezeEvent) error {
+	return db.db.Create(event).Error
+}
+
+// return nil if bot has never been frozen
+func (db DB) getLastFreezeEvent() (*FreezeEvent, error) {
+	var events []*FreezeEvent
+	result := db.db.Order("id DESC").Limit(1).Find(&events)
+	if result.Error != nil || len(events) == 0 {
+		return nil, result.Error
+	}
+	return events[0], nil
+}
+
+// a tx found again by a later scan is ignored
+func (db DB) addSbasBchTx(sbasTx *SbasBchTx) error {
+	return db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(sbasTx).Error
+}
+
+// latest txs first
+func (db DB) getSbasBchTxs(invalidOnly bool, limit int) (sbasTxs []*SbasBchTx, err error) {
+	q := db.db.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: true})
+	if invalidOnly {
+		q = q.Where("valid = ?", false)
+	}
+	result := q.Limit(limit).Find(&sbasTxs)
+	err = result.Error
+	return
+}
+
+// a covenant found again by a later scan is ignored
+func (db DB) addIndexedBchHtlc(htlc *IndexedBchHtlc) error {
+	return db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(htlc).Error
+}
+
+// returns the unspent covenants locked by lockTxHashes
+func (db DB) getUnspentIndexedBchHtlcs(lockTxHashes []string) (htlcs []*IndexedBchHtlc, err error) {
+	result := db.db.Where("lock_tx_hash IN ? AND unlock_tx_hash = ? AND refund_tx_hash = ?",
+		lockTxHashes, "", "").Find(&htlcs)
+	err = result.Error
+	return
+}
+
+func (db DB) setIndexedBchHtlcUnlocked(lockTxHash, unlockTxHash, secret string, h uint64) error {
+	return db.db.Model(&IndexedBchHtlc{}).
+		Where("lock_tx_hash = ? AND unlock_tx_hash = ?", lockTxHash, "").
+		Updates(map[string]any{"unlock_tx_hash": unlockTxHash, "secret": secret, "unlock_height": h}).Error
+}
+
+func (db DB) setIndexedBchHtlcRefunded(lockTxHash, refundTxHash string, h uint64) error {
+	return db.db.Model(&IndexedBchHtlc{}).
+		Where("lock_tx_hash = ? AND refund_tx_hash = ?", lockTxHash, "").
+		Updates(map[string]any{"refund_tx_hash": refundTxHash, "unlock_height": h}).Error
+}
+
+// an HTLC found again by a later scan is ignored
+func (db DB) addIndexedSbchHtlc(htlc *IndexedSbchHtlc) error {
+	return db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(htlc).Error
+}
+
+// hash locks are only unique per HTLC contract, so the open one is updated
+func (db DB) setIndexedSbchHtlcUnlocked(htlcAddr, hashLock, unlockTxHash, secret string, h uint64) error {
+	return db.db.Model(&IndexedSbchHtlc{}).
+		Where("htlc_addr = ? AND hash_lock = ? AND unlock_tx_hash = ? AND refund_tx_hash = ?",
+			htlcAddr, hashLock, "", "").
+		Updates(map[string]any{"unlock_tx_hash": unlockTxHash, "secret": secret, "unlock_height": h}).Error
+}
+
+func (db DB) setIndexedSbchHtlcRefunded(htlcAddr, hashLock, refundTxHash string, h uint64) error {
+	return db.db.Model(&IndexedSbchHtlc{}).
+		Where("htlc_addr = ? AND hash_lock = ? AND unlock_tx_hash = ? AND refund_tx_hash = ?",
+			htlcAddr, hashLock, "", "").
+		Updates(map[string]any{"refund_tx_hash": refundTxHash, "unlock_height": h}).Error
+}
+
+// delete SBAS txs found before t
+func (db DB) pruneSbasBchTxs(t time.Time) (int64, error) {
+	result := db.db.Unscoped().Where("created_at < ?", t).Delete(&SbasBchTx{})
+	return result.RowsAffected, result.Error
+}
+
+// delete confirmed pending BCH txs not updated since t
+func (db DB) prunePendingBchTxs(t time.Time) (int64, error) {
+	result := db.db.Unscoped().Where("confirmed = ? AND updated_at < ?", true, t).Delete(&PendingBchTx{})
+	return result.RowsAffected, result.Error
+}
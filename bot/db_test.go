@@ -246,6 +246,25 @@ func TestGetSbch2BchRecordsByStatus_orderBy(t *testing.T) {
 	require.Equal(t, []uint64{555, 777, 888}, getSbch2BchRecordValues(records))
 }
 
+func TestAddSwapTx(t *testing.T) {
+	db := initDB(t, 123, 456)
+
+	require.ErrorContains(t, db.addSwapTx(&SwapTx{HashLock: "h1", Leg: SwapLegBchLock, TxHash: "tx1"}),
+		"missing required fields")
+	require.NoError(t, db.addSwapTx(&SwapTx{HashLock: "h1", Leg: SwapLegBchLock, TxHash: "tx1", RawTx: "raw1"}))
+	require.NoError(t, db.addSwapTx(&SwapTx{HashLock: "h1", Leg: SwapLegBchLock, TxHash: "tx1", RawTx: "raw1"})) // dup
+	require.NoError(t, db.addSwapTx(&SwapTx{HashLock: "h1", Leg: SwapLegSbchLock, TxHash: "tx2", RawTx: "raw2"}))
+	require.NoError(t, db.addSwapTx(&SwapTx{HashLock: "h2", Leg: SwapLegBchLock, TxHash: "tx3", RawTx: "raw3"}))
+
+	txs, err := db.getSwapTxsByHashLock("h1")
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	require.Equal(t, SwapLegBchLock, txs[0].Leg)
+	require.Equal(t, "raw1", txs[0].RawTx)
+	require.Equal(t, SwapLegSbchLock, txs[1].Leg)
+	require.Equal(t, "raw2", txs[1].RawTx)
+}
+
 func initDB(t *testing.T, lastBchHeight, lastSbchHeight uint64) DB {
 	_ = os.Remove(testDbFile)
 	db, err := OpenDB(testDbFile)
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	TimerRefundSbch = "refund_sbch" // bch2sbch SbchLocked     : bot's sBCH becomes refundable
+	TimerUnlockBch  = "unlock_bch"  // bch2sbch SecretRevealed : bot must unlock BCH before user's BCH becomes refundable
+	TimerRefundBch  = "refund_bch"  // sbch2bch BchLocked      : bot's BCH becomes refundable (estimated)
+	TimerUnlockSbch = "unlock_sbch" // sbch2bch SecretRevealed : bot must unlock sBCH before user's sBCH becomes refundable
+
+	deadlinePollInterval = 5 * time.Second // new timers are noticed within it
+	deadlineRetrySeconds = 60              // fired timers are re-armed if the action can not be taken yet
+)
+
+// DeadlineWatchdog watches the persistent timers of in-flight swaps. Timers are armed in DB
+// together with the status changes which start them, so they survive restarts. The watchdog
+// goroutine sleeps until the next timer is due and notifies the main loop, which handles
+// fired timers before and between scan batches, so redeems and refunds are not delayed by
+// a scan backlog. Swaps are still checked by the regular steps of the loop as before.
+type DeadlineWatchdog struct {
+	db      DB
+	firedCh chan struct{}
+}
+
+func newDeadlineWatchdog(db DB) *DeadlineWatchdog {
+	return &DeadlineWatchdog{
+		db:      db,
+		firedCh: make(chan struct{}, 1),
+	}
+}
+
+func (wd *DeadlineWatchdog) run() {
+	for {
+		wait := deadlinePollInterval
+		timer, err := wd.db.getNextSwapTimer()
+		if err != nil {
+			log.Error("DB error, failed to get next swap timer: ", err)
+		} else if timer != nil {
+			if d := time.Until(time.Unix(timer.FireAt, 0)); d <= 0 {
+				wd.notify()
+			} else if d < wait {
+				wait = d
+			}
+		}
+		time.Sleep(wait)
+	}
+}
+
+func (wd *DeadlineWatchdog) notify() {
+	select {
+	case wd.firedCh <- struct{}{}:
+	default:
+	}
+}
+
+func (wd *DeadlineWatchdog) takeFired() bool {
+	select {
+	case <-wd.firedCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// arm the timer of the status the swap enters, statusTime is when it enters the status
+func newBch2SbchTimer(record *Bch2SbchRecord, statusTime int64) *SwapTimer {
+	switch record.Status {
+	case Bch2SbchStatusSbchLocked:
+		refundableTime := record.SbchLockTxTime + uint64(bchTimeLockToSeconds(record.TimeLock)/2)
+		return &SwapTimer{HashLock: record.HashLock, Kind: TimerRefundSbch, FireAt: int64(refundableTime) + 1}
+	case Bch2SbchStatusSecretRevealed:
+		return &SwapTimer{HashLock: record.HashLock, Kind: TimerUnlockBch, FireAt: statusTime}
+	}
+	return nil
+}
+
+func newSbch2BchTimer(record *Sbch2BchRecord, statusTime int64) *SwapTimer {
+	switch record.Status {
+	case Sbch2BchStatusBchLocked:
+		// BCH lock tx is not confirmed yet when the status is changed
+		bchTimeLock := sbchTimeLockToBlocks(record.TimeLock) / 2
+		fireAt := statusTime + int64(bchTimeLockToSeconds(uint32(bchTimeLock)+1))
+		return &SwapTimer{HashLock: record.HashLock, Kind: TimerRefundBch, FireAt: fireAt}
+	case Sbch2BchStatusSecretRevealed:
+		return &SwapTimer{HashLock: record.HashLock, Kind: TimerUnlockSbch, FireAt: statusTime}
+	}
+	return nil
+}
+
+// arm timers of in-flight swaps saved before timers were introduced
+func (bot *MarketMakerBot) armInFlightSwapTimers() error {
+	for _, status := range []Bch2SbchStatus{Bch2SbchStatusSbchLocked, Bch2SbchStatusSecretRevealed} {
+		records, err := bot.db.getBch2SbchRecordsByStatus(status, -1)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err = bot.db.armSwapTimer(newBch2SbchTimer(record, record.UpdatedAt.Unix())); err != nil {
+				return err
+			}
+		}
+	}
+	for _, status := range []Sbch2BchStatus{Sbch2BchStatusBchLocked, Sbch2BchStatusSecretRevealed} {
+		records, err := bot.db.getSbch2BchRecordsByStatus(status, -1)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err = bot.db.armSwapTimer(newSbch2BchTimer(record, record.UpdatedAt.Unix())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// called by the main loop before and between scan batches
+func (bot *MarketMakerBot) handleDeadlines() {
+	if bot.deadlines == nil || !bot.deadlines.takeFired() || !bot.canSign() {
+		return
+	}
+
+	now := time.Now().Unix()
+	timers, err := bot.db.getDueSwapTimers(now, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get due swap timers: ", err)
+		return
+	}
+	log.Info("fired swap timers: ", len(timers))
+
+	for _, timer := range timers {
+		log.Info("swap timer: ", toJSON(timer))
+		if bot.fireSwapTimer(timer) {
+			timer.Fired = true
+		} else {
+			timer.FireAt = now + deadlineRetrySeconds
+		}
+		if err = bot.db.updateSwapTimer(timer); err != nil {
+			bot.logError("DB error, failed to update swap timer: ", err)
+		}
+	}
+}
+
+// return true if the timer is done: the action is taken, or not needed anymore
+func (bot *MarketMakerBot) fireSwapTimer(timer *SwapTimer) bool {
+	switch timer.Kind {
+	case TimerRefundSbch, TimerUnlockBch:
+		record, err := bot.db.getBch2SbchRecordByHashLock(timer.HashLock)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true
+		}
+		if err != nil {
+			bot.logError("DB error, failed to get BCH2SBCH record: ", err)
+			return false
+		}
+		if timer.Kind == TimerUnlockBch {
+			if record.Status != Bch2SbchStatusSecretRevealed {
+				return true
+			}
+			return bot.unlockBchUserDeposit(record, time.Now())
+		}
+		if record.Status != Bch2SbchStatusSbchLocked {
+			return true
+		}
+		sbchNow, err := bot.sbchCli.getBlockTimeLatest()
+		if err != nil {
+			bot.logError("RPC error, failed to get sBCH time: ", err)
+			return false
+		}
+		return bot.refundLockedSbchRecord(record, sbchNow)
+
+	case TimerRefundBch, TimerUnlockSbch:
+		record, err := bot.db.getSbch2BchRecordByHashLock(timer.HashLock)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true
+		}
+		if err != nil {
+			bot.logError("DB error, failed to get SBCH2BCH record: ", err)
+			return false
+		}
+		if timer.Kind == TimerUnlockSbch {
+			if record.Status != Sbch2BchStatusSecretRevealed {
+				return true
+			}
+			return bot.unlockSbchUserDeposit(record, time.Now())
+		}
+		if record.Status != Sbch2BchStatusBchLocked {
+			return true
+		}
+		return bot.refundLockedBchRecord(record)
+	}
+
+	log.Info("unknown swap timer: ", timer.Kind)
+	return true
+}
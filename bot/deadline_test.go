@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapTimers(t *testing.T) {
+	db := initDB(t, 123, 456)
+	record := &Bch2SbchRecord{
+		BchLockHeight:  11,
+		BchLockTxHash:  "22",
+		Value:          44,
+		RecipientPkh:   "55",
+		SenderPkh:      "66",
+		HashLock:       "77",
+		TimeLock:       72,
+		HtlcScriptHash: "99",
+		SenderEvmAddr:  "aa",
+	}
+	require.NoError(t, db.addBch2SbchRecord(record))
+	timer, err := db.getNextSwapTimer()
+	require.NoError(t, err)
+	require.Nil(t, timer)
+
+	// SbchLocked => refund_sbch
+	require.NoError(t, db.updateBch2SbchRecord(record.UpdateStatusToSbchLocked("bb", 1000000)))
+	timer, err = db.getNextSwapTimer()
+	require.NoError(t, err)
+	require.Equal(t, TimerRefundSbch, timer.Kind)
+	require.Equal(t, "77", timer.HashLock)
+	require.Equal(t, int64(1000000+72*600/2+1), timer.FireAt)
+
+	// SecretRevealed => unlock_bch
+	now := time.Now().Unix()
+	require.NoError(t, db.updateBch2SbchRecord(record.UpdateStatusToSecretRevealed("cc", "dd")))
+	timers, err := db.getDueSwapTimers(now+5, 100)
+	require.NoError(t, err)
+	require.Len(t, timers, 2)
+	require.Equal(t, TimerRefundSbch, timers[0].Kind)
+	require.Equal(t, TimerUnlockBch, timers[1].Kind)
+	require.InDelta(t, now, timers[1].FireAt, 5)
+
+	// BchLocked => refund_bch, estimated by BCH block time
+	record2 := &Sbch2BchRecord{
+		SbchLockTime:    11,
+		SbchLockTxHash:  "cc",
+		Value:           33,
+		SbchSenderAddr:  "44",
+		BchRecipientPkh: "55",
+		HashLock:        "66",
+		TimeLock:        72000,
+		HtlcScriptHash:  "88",
+	}
+	require.NoError(t, db.addSbch2BchRecord(record2))
+	require.NoError(t, db.updateSbch2BchRecord(record2.UpdateStatusToBchLocked("dd")))
+	timers, err = db.getDueSwapTimers(now+1e6, 100)
+	require.NoError(t, err)
+	require.Len(t, timers, 3)
+	require.Equal(t, TimerRefundBch, timers[2].Kind)
+	require.InDelta(t, now+61*600, timers[2].FireAt, 5)
+
+	// timers are armed once
+	timers[0].Fired = true
+	require.NoError(t, db.updateSwapTimer(timers[0]))
+	_bot := &MarketMakerBot{db: db}
+	require.NoError(t, _bot.armInFlightSwapTimers())
+	timers, err = db.getDueSwapTimers(now+1e6, 100)
+	require.NoError(t, err)
+	require.Len(t, timers, 2)
+}
+
+func TestHandleDeadlines(t *testing.T) {
+	_secret := gethHash32("secret")
+	_hashLock := sha256.Sum256(_secret[:])
+	_sbchLockTxHash := gethHash32Bytes("sbchlock")
+	_sbchNow := uint64(time.Now().Unix())
+	_sbchLockTxTime := _sbchNow - 22000
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock")),
+		Value:          12345678,
+		BchPrice:       1e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(_hashLock[:]),
+		TimeLock:       72,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		SbchLockTxTime: _sbchLockTxTime,
+		SbchLockTxHash: toHex(_sbchLockTxHash),
+		Status:         Bch2SbchStatusSbchLocked,
+	}))
+	// not refundable yet
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  123,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock2")),
+		Value:          12345678,
+		BchPrice:       1e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(gethHash32Bytes("hashlock2")),
+		TimeLock:       72,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		SbchLockTxTime: _sbchNow,
+		SbchLockTxHash: toHex(gethHash32Bytes("sbchlock2")),
+		Status:         Bch2SbchStatusSbchLocked,
+	}))
+
+	_sbchCli := newMockSbchClient(457, 999, _sbchNow)
+	_sbchCli.txTimes[gethcmn.BytesToHash(_sbchLockTxHash)] = _sbchLockTxTime
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		sbchCli:      _sbchCli,
+		bchPkh:       testBchPkh,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+		deadlines:    newDeadlineWatchdog(_db),
+	}
+
+	// nothing happens until the watchdog notifies
+	_bot.handleDeadlines()
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusSbchRefunded, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 0)
+
+	_bot.deadlines.notify()
+	_bot.handleDeadlines()
+	records, err = _db.getBch2SbchRecordsByStatus(Bch2SbchStatusSbchRefunded, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, toHex(_hashLock[:]), records[0].HashLock)
+
+	timer, err := _db.getNextSwapTimer()
+	require.NoError(t, err)
+	require.Equal(t, toHex(gethHash32Bytes("hashlock2")), timer.HashLock)
+	timers, err := _db.getDueSwapTimers(time.Now().Unix(), 100)
+	require.NoError(t, err)
+	require.Len(t, timers, 0)
+}
@@ -0,0 +1,354 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Users may register an email with their quotes, they are then emailed when their deposits are
+// detected, when their swaps are completed and when their deposits can be refunded. Emails are
+// generated from the same swap status changes as webhooks and sent by a goroutine with the
+// backoff of webhooks. Each email has an unsubscribe link, see /unsubscribe.
+
+const (
+	EmailKindDeposit  = "deposit"  // the deposit of the user is detected
+	EmailKindComplete = "complete" // the user has received the counter value
+	EmailKindRefund   = "refund"   // the swap will not be completed, the deposit can be refunded after its time lock
+
+	emailTokenLen = 16
+)
+
+// EmailConfig is the SMTP server sending notifications to users
+type EmailConfig struct {
+	SmtpAddr    string `json:"smtp_addr"`    // host:port
+	Username    string `json:"username"`     // PLAIN auth, empty means no auth
+	Password    string `json:"password"`     //
+	From        string `json:"from"`         // sender address
+	PublicUrl   string `json:"public_url"`   // base URL of the public API, used by unsubscribe links
+	TemplateDir string `json:"template_dir"` // optional, <kind>.tmpl files replace the built-in templates
+}
+
+// EmailData is passed to email templates, the first line of a rendered template is the subject
+type EmailData struct {
+	*WebhookEvent
+	Kind           string // see EmailKindXxx
+	Amount         string // value with 8 decimals
+	Unit           string // BCH or token symbol
+	UnsubscribeUrl string
+}
+
+var defaultEmailTemplates = map[string]string{
+	EmailKindDeposit: `Your deposit of {{.Amount}} {{.Unit}} is detected
+Hello,
+
+Your deposit of {{.Amount}} {{.Unit}} ({{.Direction}}) is detected, hash lock: {{.HashLock}}.
+You will be notified when the swap is completed.
+
+Unsubscribe: {{.UnsubscribeUrl}}
+`,
+	EmailKindComplete: `Your swap of {{.Amount}} {{.Unit}} is completed
+Hello,
+
+Your swap of {{.Amount}} {{.Unit}} ({{.Direction}}) is completed, hash lock: {{.HashLock}}.
+{{range $leg, $tx := .Txs}}
+{{$leg}}: {{$tx}}{{end}}
+
+Unsubscribe: {{.UnsubscribeUrl}}
+`,
+	EmailKindRefund: `Your deposit of {{.Amount}} {{.Unit}} can be refunded
+Hello,
+
+Your swap of {{.Amount}} {{.Unit}} ({{.Direction}}) will not be completed ({{.Status}}), hash lock: {{.HashLock}}.
+Your wallet can refund the deposit once its time lock expires.
+
+Unsubscribe: {{.UnsubscribeUrl}}
+`,
+}
+
+// swap status => email kind, statuses of both directions
+var emailKindsByStatus = map[string]string{
+	"New":               EmailKindDeposit,
+	"SecretRevealed":    EmailKindComplete,
+	"SbchRefunded":      EmailKindRefund,
+	"BchRefunded":       EmailKindRefund,
+	"TooLateToLockSbch": EmailKindRefund,
+	"TooLateToLockBch":  EmailKindRefund,
+	"PriceChanged":      EmailKindRefund,
+	"Unprofitable":      EmailKindRefund,
+	"Rejected":          EmailKindRefund,
+	"Cancelled":         EmailKindRefund,
+}
+
+type EmailNotifier struct {
+	db        DB
+	cfg       *EmailConfig
+	templates map[string]*template.Template
+	send      func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	wakeCh    chan struct{}
+	errLog    *ErrLogQueue
+}
+
+// returns nil if cfg is nil
+func newEmailNotifier(db DB, cfg *EmailConfig, errLog *ErrLogQueue) (*EmailNotifier, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if _, _, err := net.SplitHostPort(cfg.SmtpAddr); err != nil {
+		return nil, fmt.Errorf("invalid smtp_addr: %s", cfg.SmtpAddr)
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("invalid from: %s", cfg.From)
+	}
+	u, err := url.Parse(cfg.PublicUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid public_url: %s", cfg.PublicUrl)
+	}
+
+	templates := map[string]*template.Template{}
+	for kind, text := range defaultEmailTemplates {
+		if cfg.TemplateDir != "" {
+			bz, err := os.ReadFile(filepath.Join(cfg.TemplateDir, kind+".tmpl"))
+			if err == nil {
+				text = string(bz)
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		templates[kind], err = template.New(kind).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of %s: %w", kind, err)
+		}
+	}
+
+	return &EmailNotifier{
+		db:        db,
+		cfg:       cfg,
+		templates: templates,
+		send:      smtp.SendMail,
+		wakeCh:    make(chan struct{}, 1),
+		errLog:    errLog,
+	}, nil
+}
+
+// an email address without display name, so that it can be put into headers as is
+func checkEmailAddr(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("invalid email: %s", email)
+	}
+	return nil
+}
+
+func newEmailToken() (string, error) {
+	token := make([]byte, emailTokenLen)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// register the email of a quote, called by makeQuote
+func (bot *MarketMakerBot) subscribeEmail(quote *QuoteInfo, email string) error {
+	token, err := newEmailToken()
+	if err != nil {
+		return err
+	}
+	return bot.db.addEmailSubscription(&EmailSubscription{
+		HashLock:  quote.HashLock,
+		Direction: quote.Direction,
+		Email:     email,
+		Token:     token,
+	})
+}
+
+// one delivery per subscribed user of each notified event, nil notifier creates none
+func (n *EmailNotifier) newDeliveries(events []*WebhookEvent) ([]*EmailDelivery, error) {
+	if n == nil {
+		return nil, nil
+	}
+	var hashLocks []string
+	for _, event := range events {
+		if emailKindsByStatus[event.Status] != "" {
+			hashLocks = append(hashLocks, event.HashLock)
+		}
+	}
+	if len(hashLocks) == 0 {
+		return nil, nil
+	}
+	subs, err := n.db.getEmailSubscriptions(hashLocks)
+	if err != nil {
+		return nil, err
+	}
+	subsByHashLock := map[string]*EmailSubscription{}
+	for _, sub := range subs {
+		subsByHashLock[sub.HashLock] = sub
+	}
+
+	var deliveries []*EmailDelivery
+	for _, event := range events {
+		sub := subsByHashLock[event.HashLock]
+		kind := emailKindsByStatus[event.Status]
+		if sub == nil || kind == "" || sub.Direction != event.Direction {
+			continue
+		}
+		deliveries = append(deliveries, &EmailDelivery{
+			EventId:       event.Id,
+			Kind:          kind,
+			Email:         sub.Email,
+			Token:         sub.Token,
+			Payload:       toJSON(event),
+			NextAttemptAt: event.Time,
+			Status:        WebhookDeliveryPending,
+		})
+	}
+	return deliveries, nil
+}
+
+// send due emails when woken up by the main loop
+func (n *EmailNotifier) run() {
+	for range n.wakeCh {
+		n.deliverDue(time.Now().Unix())
+	}
+}
+
+func (n *EmailNotifier) wake() {
+	select {
+	case n.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+func (n *EmailNotifier) deliverDue(now int64) {
+	deliveries, err := n.db.getDueEmailDeliveries(now, webhookBatchSize)
+	if err != nil {
+		n.logError("DB error, failed to get email deliveries", err)
+		return
+	}
+	for _, delivery := range deliveries {
+		n.deliver(delivery, now)
+	}
+}
+
+func (n *EmailNotifier) deliver(delivery *EmailDelivery, now int64) {
+	msg, err := n.render(delivery)
+	if err == nil {
+		err = n.send(n.cfg.SmtpAddr, n.getAuth(), n.cfg.From, []string{delivery.Email}, msg)
+	}
+	if err == nil {
+		log.Infof("email sent, kind: %s, event: %d", delivery.Kind, delivery.EventId)
+		if err = n.db.deleteEmailDelivery(delivery); err != nil {
+			n.logError("DB error, failed to delete email delivery", err)
+		}
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if len(delivery.LastError) > webhookMaxErrLength {
+		delivery.LastError = delivery.LastError[:webhookMaxErrLength]
+	}
+	if delivery.Attempts >= webhookMaxAttempts {
+		delivery.Status = WebhookDeliveryFailed
+		n.logError(fmt.Sprintf("email delivery failed, kind: %s, event: %d, attempts: %d",
+			delivery.Kind, delivery.EventId, delivery.Attempts), err)
+	} else {
+		delivery.NextAttemptAt = now + getWebhookBackoff(delivery.Attempts)
+		log.Infof("email delivery failed, kind: %s, event: %d, attempts: %d, error: %s",
+			delivery.Kind, delivery.EventId, delivery.Attempts, err.Error())
+	}
+	if err = n.db.updateEmailDelivery(delivery); err != nil {
+		n.logError("DB error, failed to update email delivery", err)
+	}
+}
+
+func (n *EmailNotifier) getAuth() smtp.Auth {
+	if n.cfg.Username == "" {
+		return nil
+	}
+	host, _, _ := net.SplitHostPort(n.cfg.SmtpAddr)
+	return smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, host)
+}
+
+func (n *EmailNotifier) logError(msg string, err error) {
+	log.Error(msg, ": ", err)
+	n.errLog.recordErrLog("error", fmt.Sprintf("%s: %s", msg, err))
+}
+
+func (n *EmailNotifier) getUnsubscribeUrl(token string) string {
+	return strings.TrimSuffix(n.cfg.PublicUrl, "/") + "/unsubscribe?token=" + token
+}
+
+// the RFC 5322 message of a delivery
+func (n *EmailNotifier) render(delivery *EmailDelivery) ([]byte, error) {
+	event := &WebhookEvent{}
+	if err := json.Unmarshal([]byte(delivery.Payload), event); err != nil {
+		return nil, err
+	}
+	tmpl := n.templates[delivery.Kind]
+	if tmpl == nil {
+		return nil, fmt.Errorf("unknown email kind: %s", delivery.Kind)
+	}
+	data := &EmailData{
+		WebhookEvent:   event,
+		Kind:           delivery.Kind,
+		Amount:         fmt.Sprintf("%d.%08d", event.Value/1e8, event.Value%1e8),
+		Unit:           "BCH",
+		UnsubscribeUrl: n.getUnsubscribeUrl(delivery.Token),
+	}
+	if event.Token != "" {
+		data.Unit = event.Token
+	}
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	subject, body, _ := strings.Cut(text.String(), "\n")
+
+	var msg bytes.Buffer
+	header := func(k, v string) { msg.WriteString(k + ": " + v + "\r\n") }
+	header("From", n.cfg.From)
+	header("To", delivery.Email)
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject)))
+	header("Date", time.Unix(event.Time, 0).UTC().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("List-Unsubscribe", "<"+data.UnsubscribeUrl+">")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
+
+// public, linked by every email
+func (bot *MarketMakerBot) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		NewErrResp("missing token").WriteTo(w)
+		return
+	}
+	ok, err := bot.db.unsubscribeEmail(token)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	if !ok {
+		NewErrResp("subscription not found").WriteTo(w)
+		return
+	}
+	NewOkResp("unsubscribed").WriteTo(w)
+}
@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEmailNotifier(t *testing.T) {
+	n, err := newEmailNotifier(DB{}, nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, n)
+
+	cfg := &EmailConfig{SmtpAddr: "smtp.a.io", From: "bot@a.io", PublicUrl: "https://a.io"}
+	_, err = newEmailNotifier(DB{}, cfg, nil)
+	require.ErrorContains(t, err, "invalid smtp_addr")
+	cfg.SmtpAddr = "smtp.a.io:587"
+	cfg.From = "bot"
+	_, err = newEmailNotifier(DB{}, cfg, nil)
+	require.ErrorContains(t, err, "invalid from")
+	cfg.From = "Swap Bot <bot@a.io>"
+	cfg.PublicUrl = "a.io"
+	_, err = newEmailNotifier(DB{}, cfg, nil)
+	require.ErrorContains(t, err, "invalid public_url")
+
+	cfg.PublicUrl = "https://a.io"
+	cfg.TemplateDir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.TemplateDir, "refund.tmpl"), []byte("{{.Foo"), 0600))
+	_, err = newEmailNotifier(DB{}, cfg, nil)
+	require.ErrorContains(t, err, "invalid template of refund")
+
+	require.NoError(t, checkEmailAddr("u@a.io"))
+	require.Error(t, checkEmailAddr("U <u@a.io>"))
+	require.Error(t, checkEmailAddr("u@a.io\r\nBcc: x@b.io"))
+}
+
+func TestEmailNotifications(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	errLogQueue := newErrLogQueue(100)
+	webhooks, err := newWebhookDispatcher(_db, nil, errLogQueue)
+	require.NoError(t, err)
+	cfg := &EmailConfig{SmtpAddr: "smtp.a.io:587", From: "bot@a.io", PublicUrl: "https://a.io/"}
+	cfg.TemplateDir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.TemplateDir, "complete.tmpl"),
+		[]byte("Done {{.Amount}} {{.Unit}}\nbye {{.UnsubscribeUrl}}\n"), 0600))
+	emails, err := newEmailNotifier(_db, cfg, errLogQueue)
+	require.NoError(t, err)
+	var sent []string
+	failing := true
+	emails.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		if failing {
+			return fmt.Errorf("421 try again")
+		}
+		require.Equal(t, "smtp.a.io:587", addr)
+		require.Equal(t, "bot@a.io", from)
+		sent = append(sent, to[0]+"|"+string(msg))
+		return nil
+	}
+	_bot := &MarketMakerBot{
+		db:           _db,
+		webhooks:     webhooks,
+		emails:       emails,
+		dbQueryLimit: 100,
+		errLogQueue:  errLogQueue,
+	}
+
+	quote := &QuoteInfo{Direction: DirectionSbch2Bch, HashLock: "66"}
+	require.NoError(t, _bot.subscribeEmail(quote, "u@a.io"))
+	subs, err := _db.getEmailSubscriptions([]string{"66"})
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	token := subs[0].Token
+	require.Len(t, token, 32)
+
+	record := &Sbch2BchRecord{
+		SbchLockTime:    11,
+		SbchLockTxHash:  "cc",
+		Value:           123456789,
+		Token:           "USDT",
+		SbchSenderAddr:  "44",
+		BchRecipientPkh: "55",
+		HashLock:        "66",
+		TimeLock:        77,
+		HtlcScriptHash:  "88",
+	}
+	require.NoError(t, _db.addSbch2BchRecord(record))
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{ // not subscribed
+		SbchLockTime:    12,
+		SbchLockTxHash:  "cd",
+		Value:           33,
+		SbchSenderAddr:  "44",
+		BchRecipientPkh: "55",
+		HashLock:        "67",
+		TimeLock:        77,
+		HtlcScriptHash:  "88",
+	}))
+	require.NoError(t, _db.updateSbch2BchRecord(record.UpdateStatusToBchLocked("dd"))) // not notified
+	_bot.dispatchWebhooks()
+
+	// deposit detected, retried with backoff
+	deliveries, err := _db.getDueEmailDeliveries(1<<40, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, EmailKindDeposit, deliveries[0].Kind)
+	now := deliveries[0].NextAttemptAt
+	emails.deliverDue(now)
+	deliveries, err = _db.getDueEmailDeliveries(now+10, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, "421 try again", deliveries[0].LastError)
+	failing = false
+	emails.deliverDue(now + 10)
+	require.Len(t, sent, 1)
+	require.True(t, strings.HasPrefix(sent[0], "u@a.io|From: bot@a.io\r\nTo: u@a.io\r\n"+
+		"Subject: Your deposit of 1.23456789 USDT is detected\r\n"), sent[0])
+	require.Contains(t, sent[0], "List-Unsubscribe: <https://a.io/unsubscribe?token="+token+">\r\n")
+	require.Contains(t, sent[0], "\r\n\r\nHello,\r\n\r\nYour deposit of 1.23456789 USDT (sbch2bch) is detected, hash lock: 66.\r\n")
+
+	// completed, by the template of template_dir
+	require.NoError(t, _db.updateSbch2BchRecord(record.UpdateStatusToSecretRevealed("ee", "ff")))
+	_bot.dispatchWebhooks()
+	emails.deliverDue(1 << 40)
+	require.Len(t, sent, 2)
+	require.Contains(t, sent[1], "Subject: Done 1.23456789 USDT\r\n")
+	require.True(t, strings.HasSuffix(sent[1], "\r\n\r\nbye https://a.io/unsubscribe?token="+token+"\r\n"))
+
+	// unsubscribed
+	handler := _bot.createHttpHandlers()
+	unsubscribe := func(token string) (resp Resp) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsubscribe?token="+token, nil))
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return
+	}
+	require.Equal(t, "subscription not found", unsubscribe("1234").Error)
+	require.True(t, unsubscribe(token).Success)
+	record.Status = Sbch2BchStatusBchRefunded
+	require.NoError(t, _db.updateSbch2BchRecord(record))
+	_bot.dispatchWebhooks()
+	deliveries, err = _db.getDueEmailDeliveries(1<<40, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 0)
+
+}
+
+func TestMakeQuote_email(t *testing.T) {
+	_sbchKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:            _db,
+		bchPkh:        testBchPkh,
+		sbchPrivKey:   _sbchKey,
+		sbchAddr:      gethcrypto.PubkeyToAddress(_sbchKey.PublicKey),
+		bchTimeLock:   100,
+		sbchTimeLock:  36000,
+		penaltyRatio:  500,
+		bchPrice:      0.99e8,
+		sbchPrice:     0.98e8,
+		minSwapVal:    1000,
+		quoteValidity: 600,
+	}
+	req := &QuoteReq{
+		Direction:     DirectionBch2Sbch,
+		Value:         1e8,
+		HashLock:      toHex(gethHash32Bytes("hash")),
+		SenderPkh:     toHex(gethAddrBytes("user")),
+		SenderEvmAddr: toHex(gethAddrBytes("evm")),
+		Email:         "u@a.io",
+	}
+	_, err = _bot.makeQuote(req)
+	require.EqualError(t, err, "email notifications are not enabled")
+
+	_bot.emails, err = newEmailNotifier(_db, &EmailConfig{
+		SmtpAddr: "smtp.a.io:587", From: "bot@a.io", PublicUrl: "https://a.io"}, nil)
+	require.NoError(t, err)
+	req.Email = "U <u@a.io>"
+	_, err = _bot.makeQuote(req)
+	require.EqualError(t, err, "invalid email: U <u@a.io>")
+
+	req.Email = "u@a.io"
+	_, err = _bot.makeQuote(req)
+	require.NoError(t, err)
+	_, err = _bot.makeQuote(req) // not requoted before expiry
+	require.ErrorIs(t, err, ErrQuoteExists)
+	subs, err := _db.getEmailSubscriptions([]string{req.HashLock})
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	require.Equal(t, DirectionBch2Sbch, subs[0].Direction)
+	require.Equal(t, "u@a.io", subs[0].Email)
+}
@@ -44,6 +44,19 @@ func (q *ErrLogQueue) recordErrLog(level, errMsg string) {
 	})
 }
 
+// return the latest n logs without removing them, latest first
+func (q *ErrLogQueue) peekErrLogs(n int) []ErrLog {
+	q.errLogMutex.Lock()
+	defer q.errLogMutex.Unlock()
+
+	all := q.errLogQueue.PeekAll()
+	logs := make([]ErrLog, 0, n)
+	for i := len(all) - 1; i >= 0 && len(logs) < n; i-- {
+		logs = append(logs, all[i])
+	}
+	return logs
+}
+
 func (q *ErrLogQueue) removeErrLogs(n int) []ErrLog {
 	q.errLogMutex.Lock()
 	defer q.errLogMutex.Unlock()
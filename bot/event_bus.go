@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+const (
+	EventBchDeposit = "bch_deposit" // payload: htlcbch.HtlcLockInfo
+	EventBchReceipt = "bch_receipt" // payload: htlcbch.HtlcUnlockInfo
+	EventSbchLock   = "sbch_lock"   // payload: gethtypes.Log
+	EventSbchUnlock = "sbch_unlock" // payload: gethtypes.Log
+	EventEvmLock    = "evm_lock"    // payload: EvmLogEvent
+	EventEvmUnlock  = "evm_unlock"  // payload: EvmLogEvent
+
+	eventQueueSize = 1024
+)
+
+// EventBus decouples chain watchers from the swap state machine.
+// Watchers publish typed events, which are saved to DB before being queued,
+// and the bot loop is the single consumer. Events not handled before the bot
+// stops are replayed on next startup.
+type EventBus struct {
+	db         DB
+	queue      chan *SwapEvent
+	overflowed bool                // some unhandled events are only in DB
+	deferred   map[uint]*SwapEvent // not final yet, retried on next drain
+}
+
+func newEventBus(db DB) *EventBus {
+	return &EventBus{
+		db:         db,
+		queue:      make(chan *SwapEvent, eventQueueSize),
+		overflowed: true, // load events left by last run
+		deferred:   map[uint]*SwapEvent{},
+	}
+}
+
+func (bus *EventBus) publish(kind, key string, height uint64, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	event := &SwapEvent{Kind: kind, Key: key, Height: height, Payload: string(data)}
+	added, err := bus.db.addSwapEvent(event)
+	if err != nil {
+		return err
+	}
+	if !added {
+		log.Infof("event published already, kind: %s, key: %s", kind, key)
+		return nil
+	}
+	bus.enqueue(event)
+	return nil
+}
+
+func (bus *EventBus) enqueue(event *SwapEvent) {
+	select {
+	case bus.queue <- event:
+	default:
+		bus.overflowed = true
+	}
+}
+
+// queue unhandled events in DB, must be called when the queue is empty
+func (bus *EventBus) load() error {
+	events, err := bus.db.getUnhandledSwapEvents(eventQueueSize)
+	if err != nil {
+		return err
+	}
+	bus.overflowed = len(events) == eventQueueSize
+	for _, event := range events {
+		if bus.deferred[event.ID] == nil {
+			bus.queue <- event
+		}
+	}
+	return nil
+}
+
+// keep an event unhandled until next drain
+func (bus *EventBus) deferEvent(event *SwapEvent) {
+	bus.deferred[event.ID] = event
+}
+
+// queue deferred events again, must be called when the queue is empty
+func (bus *EventBus) requeueDeferred() {
+	for id, event := range bus.deferred {
+		delete(bus.deferred, id)
+		bus.enqueue(event)
+	}
+}
+
+func (bot *MarketMakerBot) getEventBus() *EventBus {
+	if bot.eventBus == nil {
+		bot.eventBus = newEventBus(bot.db)
+	}
+	return bot.eventBus
+}
+
+func (bot *MarketMakerBot) publishEvent(kind, key string, height uint64, payload any) bool {
+	if err := bot.getEventBus().publish(kind, key, height, payload); err != nil {
+		bot.logError(fmt.Sprintf("DB error, failed to publish %s event: ", kind), err)
+		return false
+	}
+	return true
+}
+
+// drain the event bus, this is the only place where chain events change swap records
+func (bot *MarketMakerBot) handleEvents() {
+	bus := bot.getEventBus()
+	bus.requeueDeferred()
+	for {
+		select {
+		case event := <-bus.queue:
+			bot.handleEvent(event)
+		default:
+			if !bus.overflowed {
+				return
+			}
+			if err := bus.load(); err != nil {
+				bot.logError("DB error, failed to load events: ", err)
+				return
+			}
+			if len(bus.queue) == 0 {
+				return
+			}
+		}
+	}
+}
+
+func (bot *MarketMakerBot) handleEvent(event *SwapEvent) {
+	log.Infof("handle event#%d, kind: %s, key: %s", event.ID, event.Kind, event.Key)
+	if event.Kind == EventSbchLock || event.Kind == EventSbchUnlock {
+		switch bot.checkSbchEventFinality(event) {
+		case sbchEventPending:
+			bot.getEventBus().deferEvent(event)
+			return
+		case sbchEventRolledBack:
+			return
+		}
+	}
+
+	var err error
+	switch event.Kind {
+	case EventBchDeposit:
+		deposit := &htlcbch.HtlcLockInfo{}
+		if err = json.Unmarshal([]byte(event.Payload), deposit); err == nil {
+			bot.handleBchDepositTxB2S(event.Height, deposit)
+			bot.handleBchDepositTxS2B(event.Height, deposit)
+		}
+	case EventBchReceipt:
+		receipt := &htlcbch.HtlcUnlockInfo{}
+		if err = json.Unmarshal([]byte(event.Payload), receipt); err == nil {
+			bot.handleBchReceiptTx(receipt)
+		}
+	case EventSbchLock:
+		ethLog := gethtypes.Log{}
+		if err = json.Unmarshal([]byte(event.Payload), &ethLog); err == nil {
+			bot.handleSbchLockEventS2B(ethLog)
+			bot.handleSbchLockEventB2S(ethLog)
+		}
+	case EventSbchUnlock:
+		ethLog := gethtypes.Log{}
+		if err = json.Unmarshal([]byte(event.Payload), &ethLog); err == nil {
+			bot.handleSbchUnlockEvent(ethLog)
+		}
+	case EventEvmLock, EventEvmUnlock:
+		evmLog := &EvmLogEvent{}
+		if err = json.Unmarshal([]byte(event.Payload), evmLog); err == nil {
+			bot.handleEvmLogEvent(event.Kind, evmLog)
+		}
+	default:
+		err = fmt.Errorf("unknown kind: %s", event.Kind)
+	}
+	if err != nil {
+		bot.logError(fmt.Sprintf("invalid event#%d: ", event.ID), err)
+	}
+
+	// invalid events are not retried
+	if err = bot.db.markSwapEventHandled(event.ID); err != nil {
+		bot.logError("DB error, failed to mark event as handled: ", err)
+	}
+}
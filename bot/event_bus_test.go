@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestEventBus_SyntheticEvents(t *testing.T) {
+	_secret := gethHash32Bytes("secret")
+	_hashLock := secretToHashLock(_secret)
+	_bchLockTxHash := bchHash32("bchlocktx").String()
+	_bchUnlockTxHash := bchHash32("bchunlocktx").String()
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    uint64(time.Now().Unix()),
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlocktx")),
+		Value:           12345678,
+		SbchPrice:       1e8,
+		SbchSenderAddr:  gethAddr("uevm").String(),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        _hashLock,
+		TimeLock:        888,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		BchLockTxHash:   _bchLockTxHash,
+		Status:          Sbch2BchStatusBchLocked,
+	}))
+
+	_bot := &MarketMakerBot{
+		db:          _db,
+		errLogQueue: newErrLogQueue(100),
+	}
+	receipt := &htlcbch.HtlcUnlockInfo{
+		PrevTxHash: _bchLockTxHash,
+		TxHash:     _bchUnlockTxHash,
+		Secret:     toHex(_secret),
+		RawTx:      "1234",
+	}
+	require.True(t, _bot.publishEvent(EventBchReceipt, receipt.TxHash, 124, receipt))
+	require.True(t, _bot.publishEvent(EventBchReceipt, receipt.TxHash, 124, receipt)) // duplicated
+	require.True(t, _bot.publishEvent("unknown", "key", 124, receipt))
+	require.Len(t, _bot.getEventBus().queue, 2)
+
+	_bot.handleEvents()
+	require.Len(t, _bot.getEventBus().queue, 0)
+	record, err := _db.getSbch2BchRecordByHashLock(_hashLock)
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusSecretRevealed, record.Status)
+	require.Equal(t, toHex(_secret), record.Secret)
+	require.Equal(t, _bchUnlockTxHash, record.BchUnlockTxHash)
+
+	events, err := _db.getUnhandledSwapEvents(100)
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+	require.Len(t, _bot.errLogQueue.removeErrLogs(100), 1) // unknown kind
+}
+
+func TestEventBus_Replay(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	bus := newEventBus(_db)
+	bus.overflowed = false
+	for i := 0; i < eventQueueSize+10; i++ {
+		require.NoError(t, bus.publish(EventSbchUnlock, fmt.Sprint(i), 0, i))
+	}
+	require.True(t, bus.overflowed)
+	require.Len(t, bus.queue, eventQueueSize)
+
+	// events left by last run are loaded by a new bus
+	_bot := &MarketMakerBot{
+		db:          _db,
+		errLogQueue: newErrLogQueue(100),
+	}
+	events, err := _db.getUnhandledSwapEvents(eventQueueSize * 2)
+	require.NoError(t, err)
+	require.Len(t, events, eventQueueSize+10)
+	_bot.handleEvents()
+	events, err = _db.getUnhandledSwapEvents(eventQueueSize * 2)
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+	require.False(t, _bot.getEventBus().overflowed)
+
+	// handled events are pruned
+	n, err := _db.pruneSwapEvents(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(eventQueueSize+10), n)
+}
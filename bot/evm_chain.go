@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	GasStrategyFixed     = "fixed"     // legacy txs at the configured gas price, smartBCH
+	GasStrategySuggested = "suggested" // legacy txs at eth_gasPrice, capped
+	GasStrategyEip1559   = "eip1559"   // dynamic fee txs, fee cap is twice the base fee plus the tip, capped
+)
+
+// EvmChain describes the EVM chain which HTLC contracts are deployed on,
+// so the same engine can serve smartBCH and other EVM chains.
+type EvmChain struct {
+	Name        string   // name of peer chain, empty means smartBCH
+	ChainId     *big.Int // nil means queried from RPC, otherwise RPC must report it
+	GasStrategy string
+	GasPrice    *big.Int // in wei, used by the fixed strategy
+	MaxGasPrice *big.Int // in wei, cap of the other strategies, nil means no cap
+
+	MulticallAddr common.Address // Multicall3 contract to batch view calls, zero means not batched
+}
+
+func checkGasStrategy(strategy string) error {
+	switch strategy {
+	case "", GasStrategyFixed, GasStrategySuggested, GasStrategyEip1559: // empty means fixed
+		return nil
+	}
+	return fmt.Errorf("invalid gas strategy: %s", strategy)
+}
+
+func capGasPrice(price, maxPrice *big.Int) *big.Int {
+	if maxPrice != nil && price.Cmp(maxPrice) > 0 {
+		return maxPrice
+	}
+	return price
+}
+
+// build the unsigned tx according to the gas strategy of the chain
+func (c *SbchClient) newTxData(nonce uint64, to common.Address, val *big.Int, gas uint64, data []byte) (types.TxData, error) {
+	switch c.chain.GasStrategy {
+	case GasStrategySuggested:
+		ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
+		defer cancelFn()
+		gasPrice, err := c.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gas price: %w", err)
+		}
+		return &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &to,
+			Value:    val,
+			Gas:      gas,
+			GasPrice: capGasPrice(gasPrice, c.chain.MaxGasPrice),
+			Data:     data,
+		}, nil
+
+	case GasStrategyEip1559:
+		chainId, err := c.getChainId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chain ID: %w", err)
+		}
+		ctx, cancelFn := context.WithTimeout(context.Background(), c.timeout)
+		defer cancelFn()
+		header, err := c.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest header: %w", err)
+		}
+		if header.BaseFee == nil {
+			return nil, fmt.Errorf("EIP-1559 is not activated")
+		}
+		tip, err := c.client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gas tip: %w", err)
+		}
+		feeCap := new(big.Int).Add(new(big.Int).Mul(header.BaseFee, big.NewInt(2)), tip)
+		feeCap = capGasPrice(feeCap, c.chain.MaxGasPrice)
+		return &types.DynamicFeeTx{
+			ChainID:   chainId,
+			Nonce:     nonce,
+			To:        &to,
+			Value:     val,
+			Gas:       gas,
+			GasTipCap: capGasPrice(tip, feeCap),
+			GasFeeCap: feeCap,
+			Data:      data,
+		}, nil
+	}
+
+	return &types.LegacyTx{
+		Nonce:    nonce,
+		To:       &to,
+		Value:    val,
+		Gas:      gas,
+		GasPrice: c.chain.GasPrice,
+		Data:     data,
+	}, nil
+}
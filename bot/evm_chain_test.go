@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEvmChain(t *testing.T) {
+	cfg := DefaultConfig()
+	chain, err := cfg.getEvmChain()
+	require.NoError(t, err)
+	require.Equal(t, GasStrategyFixed, chain.GasStrategy)
+	require.Nil(t, chain.ChainId)
+	require.Nil(t, chain.MaxGasPrice)
+	require.Equal(t, big.NewInt(1.05e9), chain.GasPrice)
+
+	cfg.SbchChainId = 10000
+	cfg.SbchGasStrategy = GasStrategyEip1559
+	cfg.SbchMaxGasPrice = 30
+	chain, err = cfg.getEvmChain()
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(10000), chain.ChainId)
+	require.Equal(t, big.NewInt(30e9), chain.MaxGasPrice)
+
+	cfg.SbchGasStrategy = "fast"
+	_, err = cfg.getEvmChain()
+	require.ErrorContains(t, err, "invalid gas strategy: fast")
+}
+
+func TestCapGasPrice(t *testing.T) {
+	require.Equal(t, big.NewInt(5), capGasPrice(big.NewInt(5), nil))
+	require.Equal(t, big.NewInt(5), capGasPrice(big.NewInt(5), big.NewInt(6)))
+	require.Equal(t, big.NewInt(4), capGasPrice(big.NewInt(5), big.NewInt(4)))
+}
+
+func TestNewTxData_fixed(t *testing.T) {
+	c := &SbchClient{chain: &EvmChain{GasPrice: big.NewInt(1.05e9)}}
+	txData, err := c.newTxData(7, gethAddr("to"), big.NewInt(1), 21000, nil)
+	require.NoError(t, err)
+	tx := types.NewTx(txData)
+	require.Equal(t, uint8(types.LegacyTxType), tx.Type())
+	require.Equal(t, uint64(7), tx.Nonce())
+	require.Equal(t, big.NewInt(1.05e9), tx.GasPrice())
+}
@@ -0,0 +1,436 @@
+package bot
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+const (
+	DirectionHome2Peer = "home2peer" // user locks sBCH, bot locks the peer coin
+	DirectionPeer2Home = "peer2home" // user locks the peer coin, bot locks sBCH
+
+	evmScanBatch = uint64(200)
+)
+
+// EvmPeer is another EVM chain swapped with smartBCH. Both legs are HTLC contracts with
+// the sBCH HTLC ABI: a dedicated one on smartBCH (home) and one on the peer chain. The
+// BCH recipient field of Lock events carries the user's address on the other chain.
+// User locks on the source chain, bot locks on the target chain with half of the time
+// lock, and unlocks the source leg with the secret revealed by the user on the target.
+type EvmPeer struct {
+	Name          string
+	HomeHtlcAddr  gethcmn.Address
+	HtlcAddr      gethcmn.Address
+	HomePrice     uint64 // BCH price in peer coin, 8 decimals, used by home2peer
+	PeerPrice     uint64 // peer coin price in BCH, 8 decimals, used by peer2home
+	confirmations uint8
+
+	homeCli ISbchClient // talks to HomeHtlcAddr, the nonce manager is shared with sbchCli
+	peerCli ISbchClient
+}
+
+// EvmLogEvent is the payload of EventEvmLock and EventEvmUnlock
+type EvmLogEvent struct {
+	Peer string        `json:"peer"`
+	Home bool          `json:"home"` // logged on smartBCH
+	Log  gethtypes.Log `json:"log"`
+}
+
+func newEvmPeers(cfgs []EvmPeerConfig, sbchCli ISbchClient, sbchHtlcAddr gethcmn.Address,
+	privKey *ecdsa.PrivateKey, db DB) ([]*EvmPeer, error) {
+
+	var peers []*EvmPeer
+	names := map[string]bool{}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("missing EVM peer name")
+		}
+		if names[cfg.Name] {
+			return nil, fmt.Errorf("duplicated EVM peer: %s", cfg.Name)
+		}
+		names[cfg.Name] = true
+		if !gethcmn.IsHexAddress(cfg.HtlcAddr) || !gethcmn.IsHexAddress(cfg.HomeHtlcAddr) {
+			return nil, fmt.Errorf("invalid htlc_addr or home_htlc_addr of EVM peer: %s", cfg.Name)
+		}
+		if gethcmn.HexToAddress(cfg.HomeHtlcAddr) == sbchHtlcAddr {
+			return nil, fmt.Errorf("home_htlc_addr of EVM peer %s is the sBCH HTLC", cfg.Name)
+		}
+		if cfg.HomePrice == 0 || cfg.PeerPrice == 0 {
+			return nil, fmt.Errorf("missing prices of EVM peer: %s", cfg.Name)
+		}
+		if err := checkGasStrategy(cfg.GasStrategy); err != nil {
+			return nil, fmt.Errorf("invalid EVM peer %s: %w", cfg.Name, err)
+		}
+
+		chain := &EvmChain{
+			Name:        cfg.Name,
+			GasStrategy: cfg.GasStrategy,
+			GasPrice:    big.NewInt(int64(cfg.GasPrice * 1e9)),
+		}
+		if cfg.ChainId > 0 {
+			chain.ChainId = new(big.Int).SetUint64(cfg.ChainId)
+		}
+		if cfg.MaxGasPrice > 0 {
+			chain.MaxGasPrice = big.NewInt(int64(cfg.MaxGasPrice * 1e9))
+		}
+		peerCli, err := newSbchClient(cfg.RpcUrl, 5*time.Second, privKey,
+			gethcmn.HexToAddress(cfg.HtlcAddr), nil, chain, db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create RPC client of EVM peer %s: %w", cfg.Name, err)
+		}
+		if chain.ChainId != nil {
+			if _, err = peerCli.getChainId(); err != nil {
+				return nil, fmt.Errorf("failed to check chain ID of EVM peer %s: %w", cfg.Name, err)
+			}
+		}
+
+		peers = append(peers, &EvmPeer{
+			Name:          cfg.Name,
+			HomeHtlcAddr:  gethcmn.HexToAddress(cfg.HomeHtlcAddr),
+			HtlcAddr:      gethcmn.HexToAddress(cfg.HtlcAddr),
+			HomePrice:     cfg.HomePrice,
+			PeerPrice:     cfg.PeerPrice,
+			confirmations: cfg.Confirmations,
+			homeCli:       sbchCli.forHtlc(gethcmn.HexToAddress(cfg.HomeHtlcAddr)),
+			peerCli:       peerCli,
+		})
+	}
+	return peers, nil
+}
+
+func (bot *MarketMakerBot) getEvmPeer(name string) *EvmPeer {
+	for _, peer := range bot.evmPeers {
+		if peer.Name == name {
+			return peer
+		}
+	}
+	return nil
+}
+
+// the clients of the chain user locks on and the chain bot locks on
+func (peer *EvmPeer) getClients(direction string) (source, target ISbchClient) {
+	if direction == DirectionHome2Peer {
+		return peer.homeCli, peer.peerCli
+	}
+	return peer.peerCli, peer.homeCli
+}
+
+// ========== watchers ==========
+
+// scan HTLC logs on both sides of all EVM peers and publish them to event bus
+func (bot *MarketMakerBot) scanEvmPeers() {
+	for _, peer := range bot.evmPeers {
+		heights, err := bot.db.getEvmPeerHeights(peer.Name)
+		if err != nil {
+			bot.logError("DB error, failed to get EVM peer heights: ", err)
+			continue
+		}
+		if bot.scanEvmPeerLogs(peer, heights, true) {
+			bot.scanEvmPeerLogs(peer, heights, false)
+		}
+	}
+	bot.handleEvents()
+}
+
+func (bot *MarketMakerBot) scanEvmPeerLogs(peer *EvmPeer, heights *EvmPeerHeights, home bool) bool {
+	cli, htlcAddr, lastH, confirmations := peer.peerCli, peer.HtlcAddr, &heights.LastPeerHeight, peer.confirmations
+	if home {
+		cli, htlcAddr, lastH, confirmations = peer.homeCli, peer.HomeHtlcAddr, &heights.LastHomeHeight, bot.sbchConfirmations
+	}
+
+	latest, err := cli.getBlockNumber()
+	if err != nil {
+		bot.logError(fmt.Sprintf("failed to get height of EVM peer %s (home: %t): ", peer.Name, home), err)
+		return false
+	}
+	if latest <= uint64(confirmations) {
+		return true
+	}
+	latest -= uint64(confirmations)
+	if *lastH == 0 {
+		*lastH = latest - 1
+		log.Infof("init last height of EVM peer %s (home: %t): %d", peer.Name, home, *lastH)
+	}
+
+	for fromH := *lastH + 1; fromH <= latest; fromH += evmScanBatch {
+		toH := fromH + evmScanBatch - 1
+		if toH > latest {
+			toH = latest
+		}
+		logs, err := cli.getHtlcLogs(fromH, toH)
+		if err != nil {
+			bot.logError(fmt.Sprintf("failed to get logs of EVM peer %s (home: %t): ", peer.Name, home), err)
+			return false
+		}
+		for _, ethLog := range logs {
+			if ethLog.Address != htlcAddr || len(ethLog.Topics) == 0 {
+				continue
+			}
+			kind := ""
+			switch ethLog.Topics[0] {
+			case htlcsbch.LockEventId:
+				kind = EventEvmLock
+			case htlcsbch.UnlockEventId:
+				kind = EventEvmUnlock
+			default:
+				continue
+			}
+			key := fmt.Sprintf("%s:%s:%d", peer.Name, ethLog.TxHash.Hex(), ethLog.Index)
+			event := &EvmLogEvent{Peer: peer.Name, Home: home, Log: ethLog}
+			if !bot.publishEvent(kind, key, ethLog.BlockNumber, event) {
+				return false
+			}
+		}
+
+		*lastH = toH
+		if err = bot.db.setEvmPeerHeights(heights); err != nil {
+			log.Fatal("DB error, failed to update EVM peer heights: ", err)
+		}
+	}
+	return true
+}
+
+// ========== events ==========
+
+func (bot *MarketMakerBot) handleEvmLogEvent(kind string, event *EvmLogEvent) {
+	peer := bot.getEvmPeer(event.Peer)
+	if peer == nil {
+		log.Info("unknown EVM peer: ", event.Peer)
+		return
+	}
+	if kind == EventEvmLock {
+		bot.handleEvmLockLog(peer, event.Home, event.Log)
+	} else {
+		bot.handleEvmUnlockLog(peer, event.Home, event.Log)
+	}
+}
+
+// create EVM swap records (status=New) for user's locks
+func (bot *MarketMakerBot) handleEvmLockLog(peer *EvmPeer, home bool, ethLog gethtypes.Log) {
+	lockLog := htlcsbch.ParseHtlcLockLog(ethLog)
+	if lockLog == nil {
+		return
+	}
+	if lockLog.UnlockerAddr != bot.sbchAddr {
+		log.Info("not locked to me, unlockerAddr: ", lockLog.UnlockerAddr.String())
+		return
+	}
+	if lockLog.BchRecipientPkh == (gethcmn.Address{}) {
+		log.Info("recipient is zero, skip")
+		return
+	}
+	if !bot.isSenderAllowed(nil, lockLog.LockerAddr[:]) {
+		log.Info("sender not allowed, lockerAddr: ", lockLog.LockerAddr.String())
+		return
+	}
+	if lockLog.PenaltyBPS != bot.penaltyRatio {
+		log.Infof("invalid penaltyRatio: %d != %d", lockLog.PenaltyBPS, bot.penaltyRatio)
+		return
+	}
+	timeLock := uint32(lockLog.UnlockTime - lockLog.CreatedTime)
+	if timeLock != bot.sbchTimeLock {
+		log.Infof("invalid TimeLock: %d != %d", timeLock, bot.sbchTimeLock)
+		return
+	}
+
+	direction, price := DirectionPeer2Home, peer.PeerPrice
+	if home {
+		direction, price = DirectionHome2Peer, peer.HomePrice
+	}
+	value := weiToSats(lockLog.Value)
+	swapVal := value // always checked in sats
+	if !home {
+		swapVal = mulByPrice(value, peer.PeerPrice)
+	}
+	if swapVal < bot.minSwapVal ||
+		(bot.maxSwapVal > 0 && swapVal > bot.maxSwapVal) {
+
+		log.Infof("value out of range: %d ∉ [%d, %d]",
+			swapVal, bot.minSwapVal, bot.maxSwapVal)
+		return
+	}
+	if expectedPrice := weiToSats(lockLog.ExpectedPrice); expectedPrice > price {
+		log.Infof("expected price is too high: %d > %d", expectedPrice, price)
+		return
+	}
+
+	log.Info("got an EVM Lock log: ", toJSON(lockLog))
+	err := bot.db.addEvmSwapRecord(&EvmSwapRecord{
+		Peer:           peer.Name,
+		Direction:      direction,
+		HashLock:       toHex(lockLog.HashLock[:]),
+		UserLockTxHash: toHex(ethLog.TxHash[:]),
+		UserLockTime:   lockLog.CreatedTime,
+		UserAddr:       toHex(lockLog.LockerAddr[:]),
+		RecipientAddr:  toHex(lockLog.BchRecipientPkh[:]),
+		Value:          value,
+		Price:          price,
+		TimeLock:       timeLock,
+	})
+	if err != nil {
+		bot.logError("DB error, failed to save EVM swap record: ", err)
+	}
+}
+
+// EVM swap record: BotLocked => SecretRevealed, if user unlocks on the target chain
+func (bot *MarketMakerBot) handleEvmUnlockLog(peer *EvmPeer, home bool, ethLog gethtypes.Log) {
+	unlockLog := htlcsbch.ParseHtlcUnlockLog(ethLog)
+	if unlockLog == nil {
+		return
+	}
+
+	hashLock := toHex(unlockLog.HashLock[:])
+	record, err := bot.db.getEvmSwapRecordByHashLock(hashLock)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			bot.logError("DB error, failed to get EVM swap record: ", err)
+		}
+		return
+	}
+	if record.Peer != peer.Name || (record.Direction == DirectionPeer2Home) != home {
+		// bot's own unlock on the source chain
+		return
+	}
+	if record.Status != EvmSwapStatusBotLocked {
+		log.Info("wrong status: ", record.Status)
+		return
+	}
+	if sha256.Sum256(unlockLog.Secret[:]) != unlockLog.HashLock {
+		log.Info("secret not match, hashLock: ", hashLock)
+		return
+	}
+
+	record.UpdateStatusToSecretRevealed(toHex(unlockLog.Secret[:]), toHex(unlockLog.TxHash[:]))
+	if err = bot.db.updateEvmSwapRecord(record); err != nil {
+		bot.logError("DB error, failed to update status of EVM swap record: ", err)
+	}
+}
+
+// ========== actions ==========
+
+func (bot *MarketMakerBot) handleEvmSwaps() {
+	if len(bot.evmPeers) == 0 || bot.isSlaveMode || !bot.canSign() {
+		return
+	}
+	for _, peer := range bot.evmPeers {
+		bot.lockEvmSwaps(peer)
+		bot.unlockEvmSwaps(peer)
+		bot.refundEvmSwaps(peer)
+	}
+}
+
+// EVM swap record: New => BotLocked
+func (bot *MarketMakerBot) lockEvmSwaps(peer *EvmPeer) {
+	if bot.isPaused() {
+		return
+	}
+	records, err := bot.db.getEvmSwapRecordsByStatus(peer.Name, EvmSwapStatusNew, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get EVM swap records: ", err)
+		return
+	}
+
+	for _, record := range records {
+		log.Info("lock EVM swap: ", record.HashLock, ", direction: ", record.Direction)
+		now := time.Now().Unix()
+
+		// leave a quarter of the time lock to unlock the source leg after the secret is revealed
+		if uint64(now) >= record.UserLockTime+uint64(record.TimeLock/4) {
+			log.Info("too late to lock, userLockTime: ", record.UserLockTime)
+			record.Status = EvmSwapStatusTooLateToLock
+			if err = bot.db.updateEvmSwapRecord(record); err != nil {
+				bot.logError("DB error, failed to update status of EVM swap record: ", err)
+			}
+			continue
+		}
+
+		_, target := peer.getClients(record.Direction)
+		outVal := mulByPrice(record.Value, record.Price)
+		balance, err := target.getBalance()
+		if err != nil {
+			bot.logError("RPC error, failed to get balance: ", err)
+			continue
+		}
+		if weiToSats(balance) < outVal {
+			log.Infof("not enough inventory: %d < %d", weiToSats(balance), outVal)
+			continue
+		}
+
+		txHash, err := target.lockSbchToHtlc(gethcmn.HexToAddress(record.RecipientAddr),
+			gethcmn.HexToHash(record.HashLock), record.TimeLock/2, satsToWei(outVal))
+		if err != nil {
+			bot.logError("failed to lock EVM swap: ", err)
+			continue
+		}
+
+		record.UpdateStatusToBotLocked(toHex(txHash[:]), uint64(now))
+		if err = bot.db.updateEvmSwapRecord(record); err != nil {
+			bot.logError("DB error, failed to update status of EVM swap record: ", err)
+		}
+	}
+}
+
+// EVM swap record: SecretRevealed => BotUnlocked
+func (bot *MarketMakerBot) unlockEvmSwaps(peer *EvmPeer) {
+	records, err := bot.db.getEvmSwapRecordsByStatus(peer.Name, EvmSwapStatusSecretRevealed, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get EVM swap records: ", err)
+		return
+	}
+
+	for _, record := range records {
+		log.Info("unlock EVM swap: ", record.HashLock, ", direction: ", record.Direction)
+		source, _ := peer.getClients(record.Direction)
+		txHash, err := source.unlockSbchFromHtlc(gethcmn.HexToAddress(record.UserAddr),
+			gethcmn.HexToHash(record.HashLock), gethcmn.HexToHash(record.Secret))
+		if err != nil {
+			bot.logError("failed to unlock EVM swap: ", err)
+			continue
+		}
+
+		record.UpdateStatusToBotUnlocked(toHex(txHash[:]))
+		if err = bot.db.updateEvmSwapRecord(record); err != nil {
+			bot.logError("DB error, failed to update status of EVM swap record: ", err)
+		}
+	}
+}
+
+// EVM swap record: BotLocked => BotRefunded, if user does not unlock in time
+func (bot *MarketMakerBot) refundEvmSwaps(peer *EvmPeer) {
+	records, err := bot.db.getEvmSwapRecordsByStatus(peer.Name, EvmSwapStatusBotLocked, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get EVM swap records: ", err)
+		return
+	}
+
+	now := uint64(time.Now().Unix())
+	for _, record := range records {
+		if now <= record.BotLockTime+uint64(record.TimeLock/2) {
+			continue
+		}
+
+		log.Info("refund EVM swap: ", record.HashLock, ", direction: ", record.Direction)
+		_, target := peer.getClients(record.Direction)
+		txHash, err := target.refundSbchFromHtlc(bot.sbchAddr, gethcmn.HexToHash(record.HashLock))
+		if err != nil {
+			bot.logError("failed to refund EVM swap: ", err)
+			continue
+		}
+
+		record.UpdateStatusToBotRefunded(toHex(txHash[:]))
+		if err = bot.db.updateEvmSwapRecord(record); err != nil {
+			bot.logError("DB error, failed to update status of EVM swap record: ", err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+func newTestEvmLockLog(htlcAddr gethcmn.Address, h uint64, txHash gethcmn.Hash, locker, recipient gethcmn.Address,
+	hashLock gethcmn.Hash, createdAt, timeLock, val uint64) gethtypes.Log {
+
+	return gethtypes.Log{
+		Address:     htlcAddr,
+		BlockNumber: h,
+		TxHash:      txHash,
+		Topics: []gethcmn.Hash{
+			htlcsbch.LockEventId,
+			gethAddrToHash32(locker),
+			gethAddrToHash32(testEvmAddr),
+		},
+		Data: joinBytes(
+			hashLock[:],
+			int64ToBytes32(int64(createdAt+timeLock)),
+			satsToWeiBytes32(val),
+			rightPad0(recipient[:], 12),
+			int64ToBytes32(int64(createdAt)),
+			int64ToBytes32(500),
+			int64ToBytes32(0), // expected price
+		),
+	}
+}
+
+func newTestEvmUnlockLog(htlcAddr gethcmn.Address, h uint64, txHash, hashLock, secret gethcmn.Hash) gethtypes.Log {
+	return gethtypes.Log{
+		Address:     htlcAddr,
+		BlockNumber: h,
+		TxHash:      txHash,
+		Topics:      []gethcmn.Hash{htlcsbch.UnlockEventId, hashLock, secret},
+	}
+}
+
+func TestEvmSwap_home2peer(t *testing.T) {
+	_secret := gethHash32("secret")
+	_hashLock := gethcmn.Hash(sha256.Sum256(_secret[:]))
+	_userAddr := gethAddr("user")
+	_userPeerAddr := gethAddr("upeer")
+	_homeHtlc := gethAddr("homehtlc")
+	_peerHtlc := gethAddr("peerhtlc")
+	_now := uint64(time.Now().Unix())
+
+	_db := initDB(t, 123, 456)
+	_homeCli := newMockSbchClient(457, 459, 0)
+	_homeCli.logs[459] = []gethtypes.Log{
+		// another contract
+		newTestEvmLockLog(gethAddr("htlc"), 459, gethHash32("locktx0"), _userAddr, _userPeerAddr,
+			gethHash32("hashlock0"), _now, 12*3600, 1e8),
+		newTestEvmLockLog(_homeHtlc, 459, gethHash32("locktx"), _userAddr, _userPeerAddr,
+			_hashLock, _now-100, 12*3600, 1e8),
+	}
+	_peerCli := newMockSbchClient(100, 200, 0)
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		sbchAddr:     testEvmAddr,
+		sbchTimeLock: 12 * 3600,
+		penaltyRatio: 500,
+		evmPeers: []*EvmPeer{{
+			Name:         "eth",
+			HomeHtlcAddr: _homeHtlc,
+			HtlcAddr:     _peerHtlc,
+			HomePrice:    5e6,
+			PeerPrice:    2e9,
+			homeCli:      _homeCli,
+			peerCli:      _peerCli,
+		}},
+	}
+
+	_bot.scanEvmPeers()
+	records, err := _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, DirectionHome2Peer, records[0].Direction)
+	require.Equal(t, toHex(_hashLock[:]), records[0].HashLock)
+	require.Equal(t, toHex(_userAddr[:]), records[0].UserAddr)
+	require.Equal(t, toHex(_userPeerAddr[:]), records[0].RecipientAddr)
+	require.Equal(t, uint64(1e8), records[0].Value)
+	require.Equal(t, uint64(5e6), records[0].Price)
+	require.Equal(t, uint32(12*3600), records[0].TimeLock)
+
+	heights, err := _db.getEvmPeerHeights("eth")
+	require.NoError(t, err)
+	require.Equal(t, uint64(459), heights.LastHomeHeight)
+	require.Equal(t, uint64(200), heights.LastPeerHeight)
+
+	// bot locks on the peer chain
+	_bot.handleEvmSwaps()
+	records, err = _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusBotLocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.InDelta(t, _now, records[0].BotLockTime, 5)
+
+	// user unlocks on the peer chain
+	_peerCli.hTo = 201
+	_peerCli.logs[201] = []gethtypes.Log{
+		newTestEvmUnlockLog(_peerHtlc, 201, gethHash32("unlocktx"), _hashLock, _secret),
+	}
+	_bot.scanEvmPeers()
+	records, err = _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusSecretRevealed, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, toHex(_secret[:]), records[0].Secret)
+
+	// bot unlocks on smartBCH
+	_bot.handleEvmSwaps()
+	records, err = _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusBotUnlocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	// bot's own unlock is ignored
+	_homeCli.hTo = 460
+	_homeCli.logs[460] = []gethtypes.Log{
+		newTestEvmUnlockLog(_homeHtlc, 460, gethHash32("unlocktx2"), _hashLock, _secret),
+	}
+	_bot.scanEvmPeers()
+	records, err = _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusBotUnlocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
+
+func TestEvmSwap_peer2home(t *testing.T) {
+	_userAddr := gethAddr("user")
+	_userHomeAddr := gethAddr("uhome")
+	_peerHtlc := gethAddr("peerhtlc")
+	_now := uint64(time.Now().Unix())
+
+	_db := initDB(t, 123, 456)
+	_homeCli := newMockSbchClient(457, 459, 0)
+	_peerCli := newMockSbchClient(100, 200, 0)
+	_peerCli.logs[200] = []gethtypes.Log{
+		newTestEvmLockLog(_peerHtlc, 200, gethHash32("locktx1"), _userAddr, _userHomeAddr,
+			gethHash32("hashlock1"), _now-100, 12*3600, 1e8),
+		newTestEvmLockLog(_peerHtlc, 200, gethHash32("locktx2"), _userAddr, _userHomeAddr,
+			gethHash32("hashlock2"), _now-4*3600, 12*3600, 1e8),
+		// invalid time lock
+		newTestEvmLockLog(_peerHtlc, 200, gethHash32("locktx3"), _userAddr, _userHomeAddr,
+			gethHash32("hashlock3"), _now-100, 6*3600, 1e8),
+	}
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		sbchAddr:     testEvmAddr,
+		sbchTimeLock: 12 * 3600,
+		penaltyRatio: 500,
+		evmPeers: []*EvmPeer{{
+			Name:         "eth",
+			HomeHtlcAddr: gethAddr("homehtlc"),
+			HtlcAddr:     _peerHtlc,
+			HomePrice:    5e6,
+			PeerPrice:    2e9,
+			homeCli:      _homeCli,
+			peerCli:      _peerCli,
+		}},
+	}
+	heights := &EvmPeerHeights{Peer: "eth", LastHomeHeight: 458, LastPeerHeight: 199}
+	require.NoError(t, _db.setEvmPeerHeights(heights))
+
+	_bot.scanEvmPeers()
+	records, err := _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, DirectionPeer2Home, records[0].Direction)
+	require.Equal(t, uint64(2e9), records[0].Price)
+
+	// the second one is too late to lock
+	_bot.handleEvmSwaps()
+	records, err = _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusBotLocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, toHex(gethHash32Bytes("hashlock1")), records[0].HashLock)
+	records, err = _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusTooLateToLock, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	// user does not unlock in time
+	record, err := _db.getEvmSwapRecordByHashLock(toHex(gethHash32Bytes("hashlock1")))
+	require.NoError(t, err)
+	record.BotLockTime = _now - 6*3600 - 1
+	require.NoError(t, _db.updateEvmSwapRecord(record))
+	_bot.handleEvmSwaps()
+	records, err = _db.getEvmSwapRecordsByStatus("eth", EvmSwapStatusBotRefunded, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
+
+func TestNewEvmPeers(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	cfg := EvmPeerConfig{
+		Name:         "eth",
+		RpcUrl:       "http://127.0.0.1:8545",
+		HtlcAddr:     "0x0000000000000000000000000000000000000001",
+		HomeHtlcAddr: "0x0000000000000000000000000000000000000002",
+		GasStrategy:  GasStrategyEip1559,
+		HomePrice:    5e6,
+		PeerPrice:    2e9,
+	}
+	peers, err := newEvmPeers([]EvmPeerConfig{cfg}, newMockSbchClient(1, 2, 0), gethcmn.Address{}, nil, _db)
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	require.Equal(t, gethcmn.HexToAddress(cfg.HomeHtlcAddr), peers[0].HomeHtlcAddr)
+
+	_, err = newEvmPeers([]EvmPeerConfig{cfg, cfg}, newMockSbchClient(1, 2, 0), gethcmn.Address{}, nil, _db)
+	require.ErrorContains(t, err, "duplicated EVM peer: eth")
+
+	_, err = newEvmPeers([]EvmPeerConfig{cfg}, newMockSbchClient(1, 2, 0),
+		gethcmn.HexToAddress(cfg.HomeHtlcAddr), nil, _db)
+	require.ErrorContains(t, err, "is the sBCH HTLC")
+
+	cfg.PeerPrice = 0
+	_, err = newEvmPeers([]EvmPeerConfig{cfg}, newMockSbchClient(1, 2, 0), gethcmn.Address{}, nil, _db)
+	require.ErrorContains(t, err, "missing prices of EVM peer: eth")
+}
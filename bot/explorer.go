@@ -0,0 +1,298 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchutil"
+	"gorm.io/gorm/clause"
+)
+
+// The explorer API serves the swap index (see swap_index.go) to the public: every SBAS swap
+// observed on both chains, whichever bot took part in it, grouped by hash lock.
+
+const (
+	defaultExplorerLimit = 20
+	maxExplorerLimit     = 100
+)
+
+var errSwapIndexDisabled = errors.New("swap index is not enabled, start the bot with --explorer or --bootstrap")
+
+type ExplorerBchHtlc struct {
+	LockTxHash    string `json:"lock_tx_hash"`
+	LockVout      uint32 `json:"lock_vout"`
+	LockHeight    uint64 `json:"lock_height"`
+	SenderPkh     string `json:"sender_pkh"`
+	RecipientPkh  string `json:"recipient_pkh"`
+	SenderEvmAddr string `json:"sender_evm_addr"`
+	ScriptHash    string `json:"script_hash"`
+	Value         uint64 `json:"value"`          // in sats
+	Expiration    uint32 `json:"expiration"`     // in blocks
+	PenaltyBPS    uint16 `json:"penalty_bps"`    //
+	ExpectedPrice uint64 `json:"expected_price"` // 8 decimals
+	Status        string `json:"status"`         // locked|unlocked|refunded
+	UnlockTxHash  string `json:"unlock_tx_hash,omitempty"`
+	RefundTxHash  string `json:"refund_tx_hash,omitempty"`
+	UnlockHeight  uint64 `json:"unlock_height,omitempty"` // of the unlock or refund tx
+	Secret        string `json:"secret,omitempty"`
+}
+
+type ExplorerSbchHtlc struct {
+	HtlcAddr        string `json:"htlc_addr"`
+	LockTxHash      string `json:"lock_tx_hash"`
+	LockHeight      uint64 `json:"lock_height"`
+	LockerAddr      string `json:"locker_addr"`
+	UnlockerAddr    string `json:"unlocker_addr"`
+	BchRecipientPkh string `json:"bch_recipient_pkh"`
+	Value           string `json:"value"`          // decimal, in wei or token units
+	UnlockTime      uint64 `json:"unlock_time"`    // unix timestamp
+	CreatedTime     uint64 `json:"created_time"`   // unix timestamp
+	PenaltyBPS      uint16 `json:"penalty_bps"`    //
+	ExpectedPrice   string `json:"expected_price"` // decimal, 8 decimals
+	Status          string `json:"status"`         // locked|unlocked|refunded
+	UnlockTxHash    string `json:"unlock_tx_hash,omitempty"`
+	RefundTxHash    string `json:"refund_tx_hash,omitempty"`
+	UnlockHeight    uint64 `json:"unlock_height,omitempty"` // of the unlock or refund tx
+	Secret          string `json:"secret,omitempty"`
+}
+
+// ExplorerSwap is made of the HTLCs sharing a hash lock, usually one on each chain
+type ExplorerSwap struct {
+	HashLock  string             `json:"hash_lock"`
+	BchHtlcs  []ExplorerBchHtlc  `json:"bch_htlcs"`
+	SbchHtlcs []ExplorerSbchHtlc `json:"sbch_htlcs"`
+	UpdatedAt int64              `json:"updated_at"` // unix timestamp, when the latest HTLC was indexed or updated
+}
+
+func getIndexedHtlcStatus(unlockTxHash, refundTxHash string) string {
+	switch {
+	case unlockTxHash != "":
+		return "unlocked"
+	case refundTxHash != "":
+		return "refunded"
+	default:
+		return "locked"
+	}
+}
+
+func newExplorerBchHtlc(htlc *IndexedBchHtlc) ExplorerBchHtlc {
+	return ExplorerBchHtlc{
+		LockTxHash:    htlc.LockTxHash,
+		LockVout:      htlc.LockVout,
+		LockHeight:    htlc.LockHeight,
+		SenderPkh:     htlc.SenderPkh,
+		RecipientPkh:  htlc.RecipientPkh,
+		SenderEvmAddr: htlc.SenderEvmAddr,
+		ScriptHash:    htlc.ScriptHash,
+		Value:         htlc.Value,
+		Expiration:    htlc.Expiration,
+		PenaltyBPS:    htlc.PenaltyBPS,
+		ExpectedPrice: htlc.ExpectedPrice,
+		Status:        getIndexedHtlcStatus(htlc.UnlockTxHash, htlc.RefundTxHash),
+		UnlockTxHash:  htlc.UnlockTxHash,
+		RefundTxHash:  htlc.RefundTxHash,
+		UnlockHeight:  htlc.UnlockHeight,
+		Secret:        htlc.Secret,
+	}
+}
+
+func newExplorerSbchHtlc(htlc *IndexedSbchHtlc) ExplorerSbchHtlc {
+	return ExplorerSbchHtlc{
+		HtlcAddr:        htlc.HtlcAddr,
+		LockTxHash:      htlc.LockTxHash,
+		LockHeight:      htlc.LockHeight,
+		LockerAddr:      htlc.LockerAddr,
+		UnlockerAddr:    htlc.UnlockerAddr,
+		BchRecipientPkh: htlc.BchRecipientPkh,
+		Value:           htlc.Value,
+		UnlockTime:      htlc.UnlockTime,
+		CreatedTime:     htlc.CreatedTime,
+		PenaltyBPS:      htlc.PenaltyBPS,
+		ExpectedPrice:   htlc.ExpectedPrice,
+		Status:          getIndexedHtlcStatus(htlc.UnlockTxHash, htlc.RefundTxHash),
+		UnlockTxHash:    htlc.UnlockTxHash,
+		RefundTxHash:    htlc.RefundTxHash,
+		UnlockHeight:    htlc.UnlockHeight,
+		Secret:          htlc.Secret,
+	}
+}
+
+// newest first, key is matched against all addresses, hash locks and tx hashes
+func (db DB) searchIndexedBchHtlcs(key string, limit int) (htlcs []*IndexedBchHtlc, err error) {
+	result := db.db.Where("lock_tx_hash = ? OR unlock_tx_hash = ? OR refund_tx_hash = ? OR hash_lock = ? OR "+
+		"sender_pkh = ? OR recipient_pkh = ? OR sender_evm_addr = ? OR script_hash = ?",
+		key, key, key, key, key, key, key, key).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: true}).
+		Limit(limit).Find(&htlcs)
+	err = result.Error
+	return
+}
+
+// newest first, key is matched against all addresses, hash locks and tx hashes
+func (db DB) searchIndexedSbchHtlcs(key string, limit int) (htlcs []*IndexedSbchHtlc, err error) {
+	result := db.db.Where("lock_tx_hash = ? OR unlock_tx_hash = ? OR refund_tx_hash = ? OR hash_lock = ? OR "+
+		"locker_addr = ? OR unlocker_addr = ? OR bch_recipient_pkh = ?",
+		key, key, key, key, key, key, key).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: true}).
+		Limit(limit).Find(&htlcs)
+	err = result.Error
+	return
+}
+
+func (db DB) getIndexedBchHtlcsByHashLocks(hashLocks []string) (htlcs []*IndexedBchHtlc, err error) {
+	result := db.db.Where("hash_lock IN ?", hashLocks).Order("id").Find(&htlcs)
+	err = result.Error
+	return
+}
+
+func (db DB) getIndexedSbchHtlcsByHashLocks(hashLocks []string) (htlcs []*IndexedSbchHtlc, err error) {
+	result := db.db.Where("hash_lock IN ?", hashLocks).Order("id").Find(&htlcs)
+	err = result.Error
+	return
+}
+
+func (bot *MarketMakerBot) isSwapIndexEnabled() bool {
+	return bot.bootstrap || bot.explorer
+}
+
+// normalizes a search query to the hex stored in the index: BCH cash addresses are
+// converted to their PKH or script hash, tx hashes and hash locks are lower cased
+func (bot *MarketMakerBot) parseExplorerQuery(q string) (string, error) {
+	q = strings.TrimSpace(q)
+	if addr, err := bchutil.DecodeAddress(q, bot.getBchNet().Net); err == nil {
+		switch addr.(type) {
+		case *bchutil.AddressPubKeyHash, *bchutil.AddressScriptHash:
+			return toHex(addr.ScriptAddress()), nil
+		}
+	}
+	bz := gethcmn.FromHex(q)
+	if len(bz) != 20 && len(bz) != 32 || toHex(bz) != strings.TrimPrefix(strings.ToLower(q), "0x") {
+		return "", fmt.Errorf("invalid query, use a BCH address, 20-byte hex address or 32-byte hex hash: %q", q)
+	}
+	return toHex(bz), nil
+}
+
+// returns up to limit swaps with an HTLC matching q, the most recently updated first
+func (bot *MarketMakerBot) searchSwaps(q string, limit int) ([]*ExplorerSwap, error) {
+	if !bot.isSwapIndexEnabled() {
+		return nil, errSwapIndexDisabled
+	}
+	if limit <= 0 || limit > maxExplorerLimit {
+		return nil, fmt.Errorf("limit must be in [1, %d]", maxExplorerLimit)
+	}
+	key, err := bot.parseExplorerQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	bchHtlcs, err := bot.db.searchIndexedBchHtlcs(key, limit)
+	if err != nil {
+		return nil, err
+	}
+	sbchHtlcs, err := bot.db.searchIndexedSbchHtlcs(key, limit)
+	if err != nil {
+		return nil, err
+	}
+	var hashLocks []string
+	seen := map[string]bool{}
+	addHashLock := func(hashLock string) {
+		if !seen[hashLock] {
+			seen[hashLock] = true
+			hashLocks = append(hashLocks, hashLock)
+		}
+	}
+	for _, htlc := range bchHtlcs {
+		addHashLock(htlc.HashLock)
+	}
+	for _, htlc := range sbchHtlcs {
+		addHashLock(htlc.HashLock)
+	}
+
+	swaps, err := bot.getExplorerSwaps(hashLocks)
+	if err != nil {
+		return nil, err
+	}
+	if len(swaps) > limit {
+		swaps = swaps[:limit]
+	}
+	return swaps, nil
+}
+
+// returns the swaps of hashLocks which are indexed, the most recently updated first
+func (bot *MarketMakerBot) getExplorerSwaps(hashLocks []string) ([]*ExplorerSwap, error) {
+	swaps := []*ExplorerSwap{}
+	if len(hashLocks) == 0 {
+		return swaps, nil
+	}
+	bchHtlcs, err := bot.db.getIndexedBchHtlcsByHashLocks(hashLocks)
+	if err != nil {
+		return nil, err
+	}
+	sbchHtlcs, err := bot.db.getIndexedSbchHtlcsByHashLocks(hashLocks)
+	if err != nil {
+		return nil, err
+	}
+
+	swapsByHashLock := map[string]*ExplorerSwap{}
+	getSwap := func(hashLock string, updatedAt int64) *ExplorerSwap {
+		swap := swapsByHashLock[hashLock]
+		if swap == nil {
+			swap = &ExplorerSwap{
+				HashLock:  hashLock,
+				BchHtlcs:  []ExplorerBchHtlc{},
+				SbchHtlcs: []ExplorerSbchHtlc{},
+			}
+			swapsByHashLock[hashLock] = swap
+			swaps = append(swaps, swap)
+		}
+		if updatedAt > swap.UpdatedAt {
+			swap.UpdatedAt = updatedAt
+		}
+		return swap
+	}
+	for _, htlc := range bchHtlcs {
+		swap := getSwap(htlc.HashLock, htlc.UpdatedAt.Unix())
+		swap.BchHtlcs = append(swap.BchHtlcs, newExplorerBchHtlc(htlc))
+	}
+	for _, htlc := range sbchHtlcs {
+		swap := getSwap(htlc.HashLock, htlc.UpdatedAt.Unix())
+		swap.SbchHtlcs = append(swap.SbchHtlcs, newExplorerSbchHtlc(htlc))
+	}
+	sort.SliceStable(swaps, func(i, j int) bool {
+		return swaps[i].UpdatedAt > swaps[j].UpdatedAt
+	})
+	return swaps, nil
+}
+
+// return swaps with an HTLC matching an address, hash lock or tx hash
+func (bot *MarketMakerBot) handleExplorerSearch(w http.ResponseWriter, r *http.Request) {
+	swaps, err := bot.searchSwaps(r.URL.Query().Get("q"), getIntQueryParam(r, "limit", defaultExplorerLimit))
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(swaps).WriteTo(w)
+}
+
+// return the swap of a hash lock
+func (bot *MarketMakerBot) handleExplorerSwap(w http.ResponseWriter, r *http.Request) {
+	if !bot.isSwapIndexEnabled() {
+		NewErrResp(errSwapIndexDisabled.Error()).WriteTo(w)
+		return
+	}
+	hashLock := r.URL.Query().Get("hash_lock")
+	swaps, err := bot.getExplorerSwaps([]string{toHex(gethcmn.FromHex(hashLock))})
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	if len(swaps) == 0 {
+		NewErrResp("swap not found").WriteTo(w)
+		return
+	}
+	NewOkResp(swaps[0]).WriteTo(w)
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestExplorer(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i := 0; i < 3; i++ {
+		sender := "user"
+		if i == 2 {
+			sender = "user2"
+		}
+		require.NoError(t, _db.addIndexedBchHtlc(&IndexedBchHtlc{
+			LockTxHash:    toHex(gethHash32Bytes("bchlock" + string(rune('0'+i)))),
+			LockHeight:    100 + uint64(i),
+			HashLock:      toHex(gethHash32Bytes("hash" + string(rune('0'+i)))),
+			SenderPkh:     toHex(gethAddrBytes(sender)),
+			RecipientPkh:  toHex(gethAddrBytes("bot")),
+			SenderEvmAddr: toHex(gethAddrBytes("evm")),
+			ScriptHash:    toHex(gethAddrBytes("htlc" + string(rune('0'+i)))),
+			Value:         12345678,
+		}))
+	}
+	require.NoError(t, _db.setIndexedBchHtlcUnlocked(toHex(gethHash32Bytes("bchlock0")),
+		toHex(gethHash32Bytes("bchunlock0")), toHex(gethHash32Bytes("secret0")), 110))
+	require.NoError(t, _db.addIndexedSbchHtlc(&IndexedSbchHtlc{
+		HtlcAddr:     toHex(gethAddrBytes("sbchhtlc")),
+		LockTxHash:   toHex(gethHash32Bytes("sbchlock0")),
+		HashLock:     toHex(gethHash32Bytes("hash0")),
+		LockerAddr:   toHex(gethAddrBytes("botevm")),
+		UnlockerAddr: toHex(gethAddrBytes("evm")),
+		Value:        "123456780000000000",
+	}))
+
+	_bot := &MarketMakerBot{db: _db}
+	_, err := _bot.searchSwaps(toHex(gethAddrBytes("user")), 10)
+	require.ErrorIs(t, err, errSwapIndexDisabled)
+
+	_bot.explorer = true
+	swaps, err := _bot.searchSwaps(toHex(gethAddrBytes("user")), 10)
+	require.NoError(t, err)
+	require.Len(t, swaps, 2)
+	require.Equal(t, toHex(gethHash32Bytes("hash0")), swaps[0].HashLock) // updated last
+	require.Len(t, swaps[0].BchHtlcs, 1)
+	require.Equal(t, "unlocked", swaps[0].BchHtlcs[0].Status)
+	require.Equal(t, toHex(gethHash32Bytes("secret0")), swaps[0].BchHtlcs[0].Secret)
+	require.Len(t, swaps[0].SbchHtlcs, 1)
+	require.Equal(t, "locked", swaps[0].SbchHtlcs[0].Status)
+	require.Equal(t, "locked", swaps[1].BchHtlcs[0].Status)
+	require.Len(t, swaps[1].SbchHtlcs, 0)
+
+	// limit
+	swaps, err = _bot.searchSwaps(toHex(gethAddrBytes("user")), 1)
+	require.NoError(t, err)
+	require.Len(t, swaps, 1)
+	_, err = _bot.searchSwaps(toHex(gethAddrBytes("user")), 1000)
+	require.ErrorContains(t, err, "limit must be in")
+
+	// EVM address of sBCH HTLCs, 0x prefix and upper case
+	swaps, err = _bot.searchSwaps("0x"+toHex(gethAddrBytes("botevm")), 10)
+	require.NoError(t, err)
+	require.Len(t, swaps, 1)
+	require.Len(t, swaps[0].BchHtlcs, 1)
+	swaps, err = _bot.searchSwaps("0X"+strings.ToUpper(toHex(gethHash32Bytes("sbchlock0"))), 10)
+	require.NoError(t, err)
+	require.Len(t, swaps, 1)
+
+	// tx hashes
+	swaps, err = _bot.searchSwaps(toHex(gethHash32Bytes("bchunlock0")), 10)
+	require.NoError(t, err)
+	require.Len(t, swaps, 1)
+	swaps, err = _bot.searchSwaps(toHex(gethHash32Bytes("sbchlock0")), 10)
+	require.NoError(t, err)
+	require.Len(t, swaps, 1)
+
+	// cash addresses
+	addr, err := htlcbch.MainNet.NewP2PKHAddress(gethAddrBytes("user2"))
+	require.NoError(t, err)
+	swaps, err = _bot.searchSwaps(addr.String(), 10)
+	require.NoError(t, err)
+	require.Len(t, swaps, 1)
+	require.Equal(t, toHex(gethHash32Bytes("hash2")), swaps[0].HashLock)
+	_, err = _bot.searchSwaps("bitcoincash:qq", 10)
+	require.ErrorContains(t, err, "invalid query")
+	_, err = _bot.searchSwaps("1234", 10)
+	require.ErrorContains(t, err, "invalid query")
+
+	// HTTP
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/v1/explorer/swap?hash_lock=0x"+toHex(gethHash32Bytes("hash1")), nil)
+	_bot.handleExplorerSwap(w, r)
+	var resp struct {
+		Success bool
+		Error   string
+		Result  ExplorerSwap
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(t, resp.Success)
+	require.Len(t, resp.Result.BchHtlcs, 1)
+	require.Equal(t, uint64(101), resp.Result.BchHtlcs[0].LockHeight)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/api/v1/explorer/swap?hash_lock="+toHex(gethHash32Bytes("hash9")), nil)
+	_bot.handleExplorerSwap(w, r)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.False(t, resp.Success)
+	require.Equal(t, "swap not found", resp.Error)
+}
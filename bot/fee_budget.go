@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"math/big"
+
+	"github.com/gcash/bchd/wire"
+	log "github.com/sirupsen/logrus"
+)
+
+// rough tx sizes and gas usages, used to estimate the cost of a swap
+const (
+	bchLockTxSize   = 350 // bytes
+	bchUnlockTxSize = 330 // bytes
+	bchRefundTxSize = 330 // bytes
+	sbchLockGas     = 120000
+	sbchUnlockGas   = 60000
+	sbchRefundGas   = 60000
+)
+
+// the fee earned by bot: value - value * price / 1e8
+func getServiceFee(value, price uint64) uint64 {
+	counterValue := mulByPrice(value, price)
+	if counterValue >= value {
+		return 0
+	}
+	return value - counterValue
+}
+
+func (bot *MarketMakerBot) getSbchGasFee(gas uint64) uint64 {
+	return weiToSats(big.NewInt(0).Mul(big.NewInt(int64(gas)), big.NewInt(int64(bot.sbchGasPrice))))
+}
+
+// bch2sbch: bot locks sBCH and unlocks BCH
+func (bot *MarketMakerBot) estimateBch2SbchCost() uint64 {
+	return bot.getSbchGasFee(sbchLockGas) + bchUnlockTxSize*bot.bchUnlockMinerFeeRate
+}
+
+// sbch2bch: bot locks BCH and unlocks sBCH
+func (bot *MarketMakerBot) estimateSbch2BchCost() uint64 {
+	return bchLockTxSize*bot.bchLockMinerFeeRate + bot.getSbchGasFee(sbchUnlockGas)
+}
+
+// save the fee budget of a swap, return false if the swap is not profitable and should be skipped
+func (bot *MarketMakerBot) checkProfitability(hashLock, direction string, serviceFee, estimatedCost uint64) bool {
+	err := bot.db.addSwapCost(&SwapCost{
+		HashLock:      hashLock,
+		Direction:     direction,
+		ServiceFee:    serviceFee,
+		EstimatedCost: estimatedCost,
+	})
+	if err != nil {
+		bot.logError("DB error, failed to save swap cost: ", err)
+	}
+
+	if bot.profitabilityGate && estimatedCost > serviceFee {
+		log.Infof("unprofitable swap, hashLock: %s, service fee: %d, estimated cost: %d",
+			hashLock, serviceFee, estimatedCost)
+		return false
+	}
+	return true
+}
+
+func (bot *MarketMakerBot) recordBchMinerFee(hashLock string, inAmt int64, tx *wire.MsgTx) {
+	fee := inAmt
+	for _, txOut := range tx.TxOut {
+		fee -= txOut.Value
+	}
+	if fee <= 0 {
+		return
+	}
+	if err := bot.db.addSwapBchMinerFee(hashLock, uint64(fee)); err != nil {
+		bot.logError("DB error, failed to update swap cost: ", err)
+	}
+}
+
+func (bot *MarketMakerBot) recordSbchGasFee(hashLock string, gas uint64) {
+	if err := bot.db.addSwapSbchGasFee(hashLock, bot.getSbchGasFee(gas)); err != nil {
+		bot.logError("DB error, failed to update swap cost: ", err)
+	}
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetServiceFee(t *testing.T) {
+	require.Equal(t, uint64(0), getServiceFee(1e8, 1e8))
+	require.Equal(t, uint64(0), getServiceFee(1e8, 1.1e8))
+	require.Equal(t, uint64(0.02e8), getServiceFee(1e8, 0.98e8))
+}
+
+func TestSwapCostTotals(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:           _db,
+		sbchGasPrice: 1.05e10,
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	totals, err := _db.getSwapCostTotals()
+	require.NoError(t, err)
+	require.Equal(t, SwapCostTotals{}, totals)
+
+	require.True(t, _bot.checkProfitability("h1", DirectionBch2Sbch, 10000, 2000))
+	require.True(t, _bot.checkProfitability("h2", DirectionSbch2Bch, 20000, 3000))
+	require.True(t, _bot.checkProfitability("h2", DirectionSbch2Bch, 99999, 3000)) // ignored
+	_bot.profitabilityGate = true
+	require.False(t, _bot.checkProfitability("h3", DirectionSbch2Bch, 100, 3000))
+
+	_bot.recordSbchGasFee("h1", 100000)
+	require.NoError(t, _db.addSwapBchMinerFee("h2", 700))
+	require.NoError(t, _db.addSwapBchMinerFee("h2", 300))
+
+	totals, err = _db.getSwapCostTotals()
+	require.NoError(t, err)
+	require.Equal(t, SwapCostTotals{
+		Swaps:         3,
+		ServiceFee:    30100,
+		EstimatedCost: 8000,
+		BchMinerFee:   1000,
+		SbchGasFee:    105000,
+	}, totals)
+}
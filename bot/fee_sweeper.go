@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+const (
+	FeeAssetBch  = "bch"  // earned by bch2sbch swaps
+	FeeAssetSbch = "sbch" // earned by sbch2bch swaps
+)
+
+// FeeWallets are accounting addresses the service fees of completed swaps are swept to,
+// so that revenue is kept apart from working inventory
+type FeeWallets struct {
+	bchPkh   []byte          // P2PKH, nil means BCH fees are not swept
+	sbchAddr gethcmn.Address // EOA or contract, zero means sBCH fees are not swept
+}
+
+func newFeeWallets(cfg *Config, bchNet *htlcbch.ChainParams) (*FeeWallets, error) {
+	wallets := &FeeWallets{}
+	if cfg.FeeBchAddr != "" {
+		addr, err := bchNet.DecodeP2PKHAddress(cfg.FeeBchAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee BCH address: %w", err)
+		}
+		wallets.bchPkh = addr.ScriptAddress()
+	}
+	if cfg.FeeSbchAddr != "" {
+		if !gethcmn.IsHexAddress(cfg.FeeSbchAddr) {
+			return nil, fmt.Errorf("invalid fee sBCH address: %s", cfg.FeeSbchAddr)
+		}
+		wallets.sbchAddr = gethcmn.HexToAddress(cfg.FeeSbchAddr)
+	}
+	return wallets, nil
+}
+
+// bch2sbch: the fee is earned in BCH when bot unlocks the BCH of user, minus the referral fee
+func (bot *MarketMakerBot) recordBch2SbchFee(record *Bch2SbchRecord) {
+	filledVal := record.GetFilledValue()
+	fee := bot.getSwapServiceFee(record.Token, DirectionBch2Sbch, filledVal, record.BchPrice)
+	if referralFee := getReferralFee(record.Value, record.ReferralBPS); referralFee < fee {
+		fee -= referralFee
+	} else {
+		fee = 0
+	}
+	bot.recordFeeRevenue(record.HashLock, record.Affiliate, FeeAssetBch, fee)
+}
+
+// sbch2bch: the fee is earned in sBCH when bot unlocks the sBCH of user,
+// fees of token swaps are earned in tokens and not recorded
+func (bot *MarketMakerBot) recordSbch2BchFee(record *Sbch2BchRecord) {
+	if record.Token != "" {
+		return
+	}
+	bot.recordFeeRevenue(record.HashLock, record.Affiliate, FeeAssetSbch,
+		bot.getSwapServiceFee(record.Token, DirectionSbch2Bch, record.Value, record.SbchPrice))
+}
+
+// periodically sweep unswept fee revenue to fee wallets
+func (bot *MarketMakerBot) sweepFees() {
+	if bot.feeWallets == nil || bot.isSlaveMode || !bot.canSign() {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastFeeSweptAt < sweepInterval {
+		return
+	}
+	bot.lastFeeSweptAt = now
+
+	if bot.feeWallets.bchPkh != nil {
+		bot.sweepFeesOf(FeeAssetBch, func(amount uint64) (string, error) {
+			return bot.sendBchTo(bot.feeWallets.bchPkh, amount)
+		})
+	}
+	if bot.feeWallets.sbchAddr != (gethcmn.Address{}) {
+		bot.sweepFeesOf(FeeAssetSbch, func(amount uint64) (string, error) {
+			return bot.sendSbchTo(bot.feeWallets.sbchAddr, amount)
+		})
+	}
+}
+
+func (bot *MarketMakerBot) sweepFeesOf(asset string, sweep func(amount uint64) (string, error)) {
+	revenues, err := bot.db.getUnsweptFeeRevenues(asset, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get fee revenues: ", err)
+		return
+	}
+	var amount uint64
+	ids := make([]uint, len(revenues))
+	for i, revenue := range revenues {
+		amount += revenue.Amount
+		ids[i] = revenue.ID
+	}
+	if amount < minSweepVal {
+		return
+	}
+
+	txHash, err := sweep(amount)
+	if err != nil {
+		bot.logError("failed to sweep fees: ", err)
+		return
+	}
+	if err = bot.db.setFeeRevenuesSwept(ids, txHash); err != nil {
+		bot.logError("DB error, failed to mark fee revenues as swept: ", err)
+	}
+	bot.logWarnf("swept %d sats %s fees of %d swaps to fee wallet, tx hash: %s",
+		amount, asset, len(ids), txHash)
+}
+
+// pay amount sats from BCH inventory to a P2PKH, the miner fee is deducted from it
+func (bot *MarketMakerBot) sendBchTo(pkh []byte, amount uint64) (string, error) {
+	utxos, err := bot.bchCli.GetUTXOs(int64(amount), 10)
+	if err != nil {
+		return "", fmt.Errorf("failed to get UTXOs: %w", err)
+	}
+	inputs := make([]htlcbch.InputInfo, len(utxos))
+	for i, utxo := range utxos {
+		inputs[i] = htlcbch.InputInfo{
+			TxID:   gethcmn.FromHex(utxo.TxID),
+			Vout:   utxo.Vout,
+			Amount: utxoAmtToSats(utxo.Amount),
+		}
+	}
+	toAddr, err := bot.getBchNet().NewP2PKHAddress(pkh)
+	if err != nil {
+		return "", err
+	}
+	tx, err := htlcbch.MakePayToAddrTx(bot.bchPrivKey, inputs,
+		toAddr, int64(amount), bot.bchLockMinerFeeRate, bot.bchSigType, bot.getBchTxLockTime(), bot.getBchNet().Net)
+	if err != nil {
+		return "", fmt.Errorf("failed to create BCH tx: %w", err)
+	}
+	log.Info("BCH tx hex: ", htlcbch.MsgTxToHex(tx))
+	txHash, err := bot.bchCli.SendTx(tx)
+	if err != nil {
+		return "", fmt.Errorf("failed to send BCH tx: %w", err)
+	}
+	return txHash.String(), nil
+}
+
+func (bot *MarketMakerBot) sendSbchTo(addr gethcmn.Address, amount uint64) (string, error) {
+	txHash, err := bot.sbchCli.transferSbch(addr, satsToWei(amount))
+	if err != nil {
+		return "", fmt.Errorf("failed to send sBCH tx: %w", err)
+	}
+	return toHex(txHash[:]), nil
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestNewFeeWallets(t *testing.T) {
+	feeBchAddr, err := htlcbch.MainNet.NewP2PKHAddress(gethAddrBytes("fee"))
+	require.NoError(t, err)
+
+	_, err = newFeeWallets(&Config{FeeSbchAddr: "0x1234"}, htlcbch.MainNet)
+	require.ErrorContains(t, err, "invalid fee sBCH address")
+	_, err = newFeeWallets(&Config{FeeBchAddr: feeBchAddr.String()}, htlcbch.TestNet3)
+	require.ErrorContains(t, err, "invalid fee BCH address")
+
+	wallets, err := newFeeWallets(&Config{FeeBchAddr: feeBchAddr.String()}, htlcbch.MainNet)
+	require.NoError(t, err)
+	require.Equal(t, gethAddrBytes("fee"), wallets.bchPkh)
+	require.Equal(t, gethcmn.Address{}, wallets.sbchAddr)
+}
+
+func TestSweepFees(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 125)
+	_bchCli.utxos = []btcjson.ListUnspentResult{
+		{TxID: gethcmn.Hash{'u', '1'}.String(), Vout: 0, Amount: 1.5},
+	}
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	feeSbchAddr := gethAddr("feesbch")
+	_bot := &MarketMakerBot{
+		db:                  _db,
+		bchCli:              _bchCli,
+		sbchCli:             _sbchCli,
+		bchPrivKey:          testBchPrivKey,
+		bchPkh:              testBchPkh,
+		bchLockMinerFeeRate: 2,
+		dbQueryLimit:        100,
+		feeWallets: &FeeWallets{
+			bchPkh:   gethAddrBytes("feebch"),
+			sbchAddr: feeSbchAddr,
+		},
+		errLogQueue: newErrLogQueue(100),
+	}
+
+	// price 0.999: the fee is 0.1% of the value
+	_bot.recordBch2SbchFee(&Bch2SbchRecord{HashLock: "h1", Value: 1e8, BchPrice: 0.999e8})
+	_bot.recordBch2SbchFee(&Bch2SbchRecord{HashLock: "h2", Value: 1e7, BchPrice: 0.999e8})
+	_bot.recordBch2SbchFee(&Bch2SbchRecord{HashLock: "h2", Value: 1e7, BchPrice: 0.999e8}) // duplicated
+	_bot.recordSbch2BchFee(&Sbch2BchRecord{HashLock: "h3", Value: 1e8, SbchPrice: 0.999e8})
+	_bot.recordSbch2BchFee(&Sbch2BchRecord{HashLock: "h4", Value: 1e8, SbchPrice: 0.999e8, Token: "T"})
+
+	// 110000 BCH sats and 100000 sBCH sats
+	_bot.sweepFees()
+	require.Len(t, _bchCli.sentTxs, 1)
+	feeOut := findTxOut(_bchCli.sentTxs[0], gethAddrBytes("feebch"))
+	require.NotNil(t, feeOut)
+	require.Less(t, feeOut.Value, int64(110000))
+	require.Greater(t, feeOut.Value, int64(110000-1000))
+	require.Equal(t, satsToWei(100000), _sbchCli.sent[feeSbchAddr])
+	require.Len(t, _bot.errLogQueue.removeErrLogs(10), 2) // alerts
+
+	revenues, err := _db.getUnsweptFeeRevenues(FeeAssetBch, 100)
+	require.NoError(t, err)
+	require.Empty(t, revenues)
+	revenues, err = _db.getUnsweptFeeRevenues(FeeAssetSbch, 100)
+	require.NoError(t, err)
+	require.Empty(t, revenues)
+
+	// not swept within the interval, nor if the fees are too small
+	_bot.recordBch2SbchFee(&Bch2SbchRecord{HashLock: "h5", Value: 1e7, BchPrice: 0.999e8})
+	_bot.sweepFees()
+	require.Len(t, _bchCli.sentTxs, 1)
+	_bot.lastFeeSweptAt = 0
+	_bot.sweepFees()
+	require.Len(t, _bchCli.sentTxs, 1)
+	revenues, err = _db.getUnsweptFeeRevenues(FeeAssetBch, 100)
+	require.NoError(t, err)
+	require.Len(t, revenues, 1)
+	require.Equal(t, uint64(10000), revenues[0].Amount)
+}
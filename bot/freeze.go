@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	FreezeSourceApi = "api"
+	FreezeSourceCli = "cli"
+)
+
+// The kill switch is for suspected key compromise or protocol bugs. While frozen, bot signs and
+// broadcasts nothing (no locks, unlocks, refunds, sweeps or EVM sends), but keeps scanning both
+// chains and updating records, so that swap timers of future refunds are still armed and fire
+// once it is thawed. Unlike pause, freeze is saved in DB and survives restarts.
+
+// tell if the kill switch is on, see canSign()
+func (bot *MarketMakerBot) isFrozen() bool {
+	return bot.frozen.Load()
+}
+
+// load the kill switch from DB, which may be thrown by the CLI (or by another instance),
+// called on start and once per round
+func (bot *MarketMakerBot) syncFreeze() error {
+	event, err := bot.db.getLastFreezeEvent()
+	if err != nil {
+		return err
+	}
+	frozen := event != nil && event.Frozen
+	if bot.frozen.Swap(frozen) != frozen {
+		if frozen {
+			bot.logCriticalf("frozen by %s, nothing is signed or broadcast, reason: %s", event.Source, event.Reason)
+		} else {
+			bot.logWarnf("thawed by %s, reason: %s", event.Source, event.Reason)
+		}
+	}
+	return nil
+}
+
+// save the kill switch to DB, and apply it at once
+func (bot *MarketMakerBot) setFrozen(frozen bool, reason, source string) error {
+	err := bot.db.addFreezeEvent(&FreezeEvent{Frozen: frozen, Reason: reason, Source: source})
+	if err != nil {
+		return err
+	}
+	return bot.syncFreeze()
+}
+
+// SetFrozen throws the kill switch in the DB file of a bot, which may be running
+func SetFrozen(dbFile string, frozen bool, reason string) error {
+	db, err := OpenDB(dbFile)
+	if err != nil {
+		return fmt.Errorf("failed to open DB file: %w", err)
+	}
+	if err = db.db.AutoMigrate(&FreezeEvent{}); err != nil {
+		return err
+	}
+	return db.addFreezeEvent(&FreezeEvent{Frozen: frozen, Reason: reason, Source: FreezeSourceCli})
+}
+
+func (bot *MarketMakerBot) handleFreeze(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleOperator, false) {
+		return
+	}
+	if err := bot.setFrozen(true, r.URL.Query().Get("reason"), FreezeSourceApi); err != nil {
+		bot.logError("DB error, failed to freeze: ", err)
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp("frozen").WriteTo(w)
+}
+
+func (bot *MarketMakerBot) handleThaw(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleAdmin, true) {
+		return
+	}
+	if err := bot.setFrozen(false, r.URL.Query().Get("reason"), FreezeSourceApi); err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp("thawed").WriteTo(w)
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreezeThaw(t *testing.T) {
+	_bot := &MarketMakerBot{
+		db:          initDB(t, 123, 456),
+		adminToken:  "secret",
+		errLogQueue: newErrLogQueue(100),
+	}
+
+	call := func(handler http.HandlerFunc, token, reason string) (result, errMsg string) {
+		req := httptest.NewRequest("POST", "/admin/x?reason="+reason, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		var resp struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+			Result  string `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Result, resp.Error
+	}
+
+	require.NoError(t, _bot.syncFreeze())
+	require.False(t, _bot.isFrozen())
+	require.True(t, _bot.canSign())
+
+	_, errMsg := call(_bot.handleFreeze, "wrong", "")
+	require.Contains(t, errMsg, "unauthorized")
+	require.False(t, _bot.isFrozen())
+
+	result, errMsg := call(_bot.handleFreeze, "secret", "leaked")
+	require.Empty(t, errMsg)
+	require.Equal(t, "frozen", result)
+	require.True(t, _bot.isFrozen())
+	require.False(t, _bot.canSign())
+	require.Nil(t, _bot.claimChainJob(toHex(gethHash32Bytes("hashlock")), SwapLegBchLock))
+	logs := _bot.errLogQueue.peekErrLogs(10)
+	require.Len(t, logs, 1)
+	require.Equal(t, "frozen by api, nothing is signed or broadcast, reason: leaked", logs[0].Msg)
+
+	// survives restarts
+	_bot2 := &MarketMakerBot{db: _bot.db, errLogQueue: newErrLogQueue(100)}
+	require.NoError(t, _bot2.syncFreeze())
+	require.True(t, _bot2.isFrozen())
+
+	result, errMsg = call(_bot.handleThaw, "secret", "rotated")
+	require.Empty(t, errMsg)
+	require.Equal(t, "thawed", result)
+	require.False(t, _bot.isFrozen())
+	require.True(t, _bot.canSign())
+
+	// thrown by CLI, picked up by the running bot
+	require.NoError(t, SetFrozen(testDbFile, true, "cli"))
+	require.False(t, _bot.isFrozen())
+	require.NoError(t, _bot.syncFreeze())
+	require.True(t, _bot.isFrozen())
+	require.NoError(t, SetFrozen(testDbFile, false, ""))
+	require.NoError(t, _bot.syncFreeze())
+	require.False(t, _bot.isFrozen())
+}
+
+func TestFrozenBotScansButSendsNothing(t *testing.T) {
+	_hashLock := gethHash32Bytes("hashlock")
+	_lockTime := uint64(time.Now().Unix() - 60)
+	_timeLock := uint32(36000)
+
+	_bot := &MarketMakerBot{
+		db:           initDB(t, 123, 456),
+		dbQueryLimit: 100,
+		bchCli:       newMockBchClient(100, 101),
+		bchPrivKey:   testBchPrivKey,
+		bchPkh:       testBchPkh,
+		sbchCli:      newMockSbchClient(457, 500, _lockTime+60),
+		sbchAddr:     testEvmAddr,
+		sbchTimeLock: _timeLock,
+		bchPrice:     1e8,
+		sbchPrice:    8e7,
+		errLogQueue:  newErrLogQueue(100),
+	}
+	record := &Sbch2BchRecord{
+		SbchLockTime:    _lockTime,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlocktx")),
+		Value:           12345678,
+		SbchPrice:       8e7 - 1,
+		SbchSenderAddr:  gethAddr("uevm").String(),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        toHex(_hashLock),
+		TimeLock:        _timeLock,
+		Status:          Sbch2BchStatusNew,
+	}
+	scriptHash, err := _bot.getChainAdapter().ScriptHash(_bot.newSbch2BchHtlcSpec(record))
+	require.NoError(t, err)
+	record.HtlcScriptHash = toHex(scriptHash)
+	require.NoError(t, _bot.db.addSbch2BchRecord(record))
+
+	require.NoError(t, _bot.setFrozen(true, "test", FreezeSourceApi))
+	_bot.handleSbchUserDeposits()
+	_bot.refundLockedBCH(true)
+	require.Len(t, _bot.bchCli.(*MockBchClient).sentTxs, 0)
+	record, err = _bot.db.getSbch2BchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusNew, record.Status)
+
+	// the deposit is locked once thawed
+	require.NoError(t, _bot.setFrozen(false, "test", FreezeSourceApi))
+	_bot.handleSbchUserDeposits()
+	require.Len(t, _bot.bchCli.(*MockBchClient).sentTxs, 1)
+}
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// gauges sampled on a schedule, exported in Prometheus text format
+const (
+	GaugeBchHotBalance  = "asbot_bch_hot_balance"                        // in BCH
+	GaugeSbchBalance    = "asbot_sbch_balance"                           // in sBCH
+	GaugeLockedBch2Sbch = `asbot_locked_in_flight{direction="bch2sbch"}` // sBCH locked by bot
+	GaugeLockedSbch2Bch = `asbot_locked_in_flight{direction="sbch2bch"}` // BCH locked by bot
+	GaugeBchScanLag     = `asbot_scan_lag_seconds{chain="bch"}`
+	GaugeSbchScanLag    = `asbot_scan_lag_seconds{chain="sbch"}`
+	GaugeSampledAt      = "asbot_gauges_sampled_at" // unix timestamp
+
+	// formatted with token symbol
+	GaugeTokenBalance = "asbot_token_balance{token=%q}"
+	GaugeTokenLocked  = "asbot_token_locked_in_flight{token=%q}" // tokens locked by bot
+)
+
+type Gauges struct {
+	mu     sync.RWMutex
+	values map[string]float64
+}
+
+func newGauges() *Gauges {
+	return &Gauges{values: map[string]float64{}}
+}
+
+func (g *Gauges) set(name string, val float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[name] = val
+}
+
+func (g *Gauges) get(name string) (float64, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	val, ok := g.values[name]
+	return val, ok
+}
+
+// write all gauges in Prometheus text format
+func (g *Gauges) writeTo(w io.Writer) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	names := make([]string, 0, len(g.values))
+	for name := range g.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lastMetric := ""
+	for _, name := range names {
+		metric, _, _ := strings.Cut(name, "{")
+		if metric != lastMetric {
+			_, _ = fmt.Fprintf(w, "# TYPE %s gauge\n", metric)
+			lastMetric = metric
+		}
+		_, _ = fmt.Fprintf(w, "%s %v\n", name, g.values[name])
+	}
+}
+
+// sample inventory and scan lag gauges periodically
+func (bot *MarketMakerBot) sampleGauges() {
+	if bot.gaugeInterval == 0 || bot.gauges == nil {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastGaugesSampledAt < int64(bot.gaugeInterval) {
+		return
+	}
+	bot.lastGaugesSampledAt = now
+	log.Info("sample gauges ...")
+
+	if freeBch, err := bot.getFreeBch(); err == nil {
+		bot.gauges.set(GaugeBchHotBalance, freeBch)
+	} else {
+		bot.logError("RPC error, failed to query UTXOs: ", err)
+	}
+	if bot.sbchCliRO != nil {
+		if freeSbch, err := bot.getFreeSbch(); err == nil {
+			bot.gauges.set(GaugeSbchBalance, freeSbch)
+		} else {
+			bot.logError("RPC error, failed to query sBCH balance: ", err)
+		}
+	}
+	if _, lockedBch, _, err := bot.getSbch2BchInfo(); err == nil {
+		bot.gauges.set(GaugeLockedSbch2Bch, lockedBch)
+	} else {
+		bot.logError("DB error, failed to get SBCH2BCH records: ", err)
+	}
+	if _, lockedSbch, _, err := bot.getBch2SbchInfo(); err == nil {
+		bot.gauges.set(GaugeLockedBch2Sbch, lockedSbch)
+	} else {
+		bot.logError("DB error, failed to get BCH2SBCH records: ", err)
+	}
+	if len(bot.tokens) > 0 {
+		if infos, err := bot.getTokenInfos(); err == nil {
+			for _, info := range infos {
+				bot.gauges.set(fmt.Sprintf(GaugeTokenBalance, info.Symbol), info.Free)
+				bot.gauges.set(fmt.Sprintf(GaugeTokenLocked, info.Symbol), info.Locked)
+			}
+		} else {
+			bot.logError("failed to query token inventory: ", err)
+		}
+	}
+	if bot.bchScannedAt > 0 {
+		bot.gauges.set(GaugeBchScanLag, float64(now-bot.bchScannedAt))
+	}
+	if bot.sbchScannedAt > 0 {
+		bot.gauges.set(GaugeSbchScanLag, float64(now-bot.sbchScannedAt))
+	}
+	bot.sampleSwapLatencies()
+	bot.gauges.set(GaugeSampledAt, float64(now))
+}
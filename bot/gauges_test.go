@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGauges_writeTo(t *testing.T) {
+	g := newGauges()
+	g.set(GaugeLockedSbch2Bch, 1.5)
+	g.set(GaugeLockedBch2Sbch, 2)
+	g.set(GaugeBchHotBalance, 0.25)
+
+	sb := &strings.Builder{}
+	g.writeTo(sb)
+	require.Equal(t, `# TYPE asbot_bch_hot_balance gauge
+asbot_bch_hot_balance 0.25
+# TYPE asbot_locked_in_flight gauge
+asbot_locked_in_flight{direction="bch2sbch"} 2
+asbot_locked_in_flight{direction="sbch2bch"} 1.5
+`, sb.String())
+}
+
+func TestSampleGauges(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    1234567890,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock")),
+		Value:           12345678,
+		SbchPrice:       1e8,
+		SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        toHex(gethHash32Bytes("s2b")),
+		TimeLock:        72000,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		Status:          Sbch2BchStatusBchLocked,
+	}))
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		bchCli:       newMockBchClient(122, 129),
+		gauges:       newGauges(),
+		bchScannedAt: time.Now().Unix() - 30,
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	// disabled
+	_bot.sampleGauges()
+	_, ok := _bot.gauges.get(GaugeSampledAt)
+	require.False(t, ok)
+
+	_bot.gaugeInterval = 60
+	_bot.sampleGauges()
+	val, ok := _bot.gauges.get(GaugeLockedSbch2Bch)
+	require.True(t, ok)
+	require.Equal(t, 0.12345678, val)
+	val, _ = _bot.gauges.get(GaugeLockedBch2Sbch)
+	require.Equal(t, float64(0), val)
+	val, _ = _bot.gauges.get(GaugeBchScanLag)
+	require.InDelta(t, 30, val, 2)
+	_, ok = _bot.gauges.get(GaugeSbchScanLag)
+	require.False(t, ok)
+
+	w := httptest.NewRecorder()
+	_bot.handleMetrics(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Contains(t, w.Body.String(), `asbot_locked_in_flight{direction="sbch2bch"} 0.12345678`)
+}
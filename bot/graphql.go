@@ -0,0 +1,281 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// The GraphQL endpoint serves the same swap records as /api/v1/swaps, but a frontend can join the
+// raw txs of both chains and the status milestones of each swap in one round-trip. Joins are only
+// queried if their fields are requested. 64-bit numbers (values, prices, timestamps) are Float,
+// since Int of GraphQL is 32-bit.
+
+const graphqlSchemaDoc = `
+schema {
+	query: Query
+}
+
+type Query {
+	# the swap of a hash lock, in either direction
+	swap(hashLock: String!, archived: Boolean): Swap
+	# a page of swaps, same as /api/v1/swaps
+	swaps(direction: String!, status: String, sender: String, from: Float, to: Float,
+		cursor: Int, order: String, limit: Int, archived: Boolean): SwapPage!
+}
+
+type SwapPage {
+	swaps: [Swap!]!
+	# absent on the last page
+	nextCursor: Int
+}
+
+type Swap {
+	id: Int!
+	direction: String!
+	hashLock: String!
+	value: Float!
+	price: Float!
+	status: String!
+	statusMsg: String!
+	token: String
+	memo: String
+	sender: String!
+	recipient: String!
+	createdAt: Float!
+	updatedAt: Float!
+	# raw txs of all legs on both chains
+	txs: [SwapTx!]!
+	# when the swap reached each status
+	milestones: [SwapMilestone!]!
+}
+
+type SwapTx {
+	leg: String!
+	chain: String!
+	txHash: String!
+	rawTx: String!
+	createdAt: Float!
+}
+
+type SwapMilestone {
+	status: String!
+	at: Float!
+}
+`
+
+// deep enough for swaps { swaps { txs { txHash } } }
+const maxGraphqlDepth = 5
+
+var (
+	graphqlSchema     *graphql.Schema
+	graphqlSchemaOnce sync.Once
+)
+
+// GraphqlReq is the body of POST /graphql
+type GraphqlReq struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type graphqlCtxKey struct{}
+
+// bot and request state passed to resolvers
+type graphqlCtx struct {
+	bot  *MarketMakerBot
+	lang string
+}
+
+func getGraphqlSchema() *graphql.Schema {
+	graphqlSchemaOnce.Do(func() {
+		graphqlSchema = graphql.MustParseSchema(graphqlSchemaDoc, &graphqlResolver{},
+			graphql.MaxDepth(maxGraphqlDepth))
+	})
+	return graphqlSchema
+}
+
+func (bot *MarketMakerBot) execGraphql(ctx context.Context, req *GraphqlReq, lang string) *graphql.Response {
+	ctx = context.WithValue(ctx, graphqlCtxKey{}, &graphqlCtx{bot: bot, lang: lang})
+	return getGraphqlSchema().Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
+
+// run a GraphQL query, the response is a standard GraphQL response instead of Resp
+func (bot *MarketMakerBot) handleGraphql(w http.ResponseWriter, r *http.Request) {
+	var req GraphqlReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		NewErrResp("invalid request: " + err.Error()).WriteTo(w)
+		return
+	}
+	resp := bot.execGraphql(r.Context(), &req, getRequestLang(r))
+	bz, err := json.Marshal(resp)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(bz)
+}
+
+type graphqlResolver struct{}
+
+func getGraphqlCtx(ctx context.Context) *graphqlCtx {
+	return ctx.Value(graphqlCtxKey{}).(*graphqlCtx)
+}
+
+func (*graphqlResolver) Swap(ctx context.Context, args struct {
+	HashLock string
+	Archived *bool
+}) (*swapResolver, error) {
+
+	gctx := getGraphqlCtx(ctx)
+	for _, direction := range []string{DirectionBch2Sbch, DirectionSbch2Bch} {
+		f := &SwapFilter{
+			HashLock: toHex(gethcmn.FromHex(args.HashLock)),
+			Limit:    1,
+			Archived: args.Archived != nil && *args.Archived,
+		}
+		list, err := gctx.bot.listSwaps(direction, "", gctx.lang, f)
+		if err != nil {
+			return nil, err
+		}
+		if len(list.Swaps) > 0 {
+			return &swapResolver{bot: gctx.bot, item: list.Swaps[0]}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (*graphqlResolver) Swaps(ctx context.Context, args struct {
+	Direction string
+	Status    *string
+	Sender    *string
+	From      *float64
+	To        *float64
+	Cursor    *int32
+	Order     *string
+	Limit     *int32
+	Archived  *bool
+}) (*swapPageResolver, error) {
+
+	gctx := getGraphqlCtx(ctx)
+	f := &SwapFilter{
+		Desc:     args.Order == nil || *args.Order != "asc",
+		Limit:    defaultSwapListLimit,
+		Archived: args.Archived != nil && *args.Archived,
+	}
+	if args.Sender != nil {
+		f.Sender = toHex(gethcmn.FromHex(*args.Sender))
+	}
+	if args.From != nil {
+		f.From = time.Unix(int64(*args.From), 0)
+	}
+	if args.To != nil {
+		f.To = time.Unix(int64(*args.To), 0)
+	}
+	if args.Cursor != nil {
+		f.Cursor = uint(*args.Cursor)
+	}
+	if args.Limit != nil {
+		f.Limit = int(*args.Limit)
+	}
+	status := ""
+	if args.Status != nil {
+		status = *args.Status
+	}
+
+	list, err := gctx.bot.listSwaps(args.Direction, status, gctx.lang, f)
+	if err != nil {
+		return nil, err
+	}
+	page := &swapPageResolver{}
+	for _, item := range list.Swaps {
+		page.swaps = append(page.swaps, &swapResolver{bot: gctx.bot, item: item})
+	}
+	if list.NextCursor > 0 {
+		cursor := int32(list.NextCursor)
+		page.nextCursor = &cursor
+	}
+	return page, nil
+}
+
+type swapPageResolver struct {
+	swaps      []*swapResolver
+	nextCursor *int32
+}
+
+func (r *swapPageResolver) Swaps() []*swapResolver { return r.swaps }
+func (r *swapPageResolver) NextCursor() *int32     { return r.nextCursor }
+
+type swapResolver struct {
+	bot  *MarketMakerBot
+	item SwapListItem
+}
+
+func (r *swapResolver) Id() int32          { return int32(r.item.Id) }
+func (r *swapResolver) Direction() string  { return r.item.Direction }
+func (r *swapResolver) HashLock() string   { return r.item.HashLock }
+func (r *swapResolver) Value() float64     { return r.item.Value }
+func (r *swapResolver) Price() float64     { return float64(r.item.Price) }
+func (r *swapResolver) Status() string     { return r.item.Status }
+func (r *swapResolver) StatusMsg() string  { return r.item.StatusMsg }
+func (r *swapResolver) Token() *string     { return optionalString(r.item.Token) }
+func (r *swapResolver) Memo() *string      { return optionalString(r.item.Memo) }
+func (r *swapResolver) Sender() string     { return r.item.Sender }
+func (r *swapResolver) Recipient() string  { return r.item.Recipient }
+func (r *swapResolver) CreatedAt() float64 { return float64(r.item.CreatedAt) }
+func (r *swapResolver) UpdatedAt() float64 { return float64(r.item.UpdatedAt) }
+
+func (r *swapResolver) Txs() ([]*swapTxResolver, error) {
+	txs, err := r.bot.db.getSwapTxsByHashLock(r.item.HashLock)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*swapTxResolver, len(txs))
+	for i, tx := range txs {
+		resolvers[i] = &swapTxResolver{tx: tx}
+	}
+	return resolvers, nil
+}
+
+func (r *swapResolver) Milestones() ([]*swapMilestoneResolver, error) {
+	milestones, err := r.bot.db.getSwapMilestones(r.item.Direction, r.item.HashLock)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*swapMilestoneResolver, len(milestones))
+	for i, milestone := range milestones {
+		resolvers[i] = &swapMilestoneResolver{milestone: milestone}
+	}
+	return resolvers, nil
+}
+
+type swapTxResolver struct {
+	tx *SwapTx
+}
+
+func (r *swapTxResolver) Leg() string        { return r.tx.Leg }
+func (r *swapTxResolver) Chain() string      { return strings.SplitN(r.tx.Leg, "_", 2)[0] } // bch|sbch
+func (r *swapTxResolver) TxHash() string     { return r.tx.TxHash }
+func (r *swapTxResolver) RawTx() string      { return r.tx.RawTx }
+func (r *swapTxResolver) CreatedAt() float64 { return float64(r.tx.CreatedAt.Unix()) }
+
+type swapMilestoneResolver struct {
+	milestone *SwapMilestone
+}
+
+func (r *swapMilestoneResolver) Status() string { return r.milestone.Status }
+func (r *swapMilestoneResolver) At() float64    { return float64(r.milestone.At) }
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
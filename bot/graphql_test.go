@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphql(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockHeight:  122,
+			BchLockTxHash:  toHex(gethHash32Bytes("bchlock" + string(rune('0'+i)))),
+			Value:          uint64(i+1) * 1e8,
+			BchPrice:       0.99e8,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(gethAddrBytes("user")),
+			HashLock:       toHex(gethHash32Bytes("b2s" + string(rune('0'+i)))),
+			TimeLock:       100,
+			SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+			Status:         Bch2SbchStatusNew,
+		}))
+	}
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    1234567890,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock")),
+		Value:           2e8,
+		SbchPrice:       0.98e8,
+		SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        toHex(gethHash32Bytes("s2b")),
+		TimeLock:        72000,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		Status:          Sbch2BchStatusNew,
+	}))
+	for _, leg := range []string{SwapLegBchLock, SwapLegSbchLock} {
+		require.NoError(t, _db.addSwapTx(&SwapTx{
+			HashLock: toHex(gethHash32Bytes("b2s1")),
+			Leg:      leg,
+			TxHash:   toHex(gethHash32Bytes(leg)),
+			RawTx:    "1234",
+		}))
+	}
+
+	_bot := &MarketMakerBot{db: _db, dbQueryLimit: 100}
+	mux := _bot.createHttpHandlers()
+	query := func(q string, vars map[string]any) (data map[string]any, errs []any) {
+		bz, _ := json.Marshal(GraphqlReq{Query: q, Variables: vars})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(string(bz))))
+		var resp struct {
+			Data   map[string]any
+			Errors []any
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+		return resp.Data, resp.Errors
+	}
+
+	// a page with joins
+	data, errs := query(`{ swaps(direction: "bch2sbch", limit: 2) {
+		swaps { id hashLock value txs { leg chain txHash } milestones { status } }
+		nextCursor } }`, nil)
+	require.Empty(t, errs)
+	require.Equal(t, map[string]any{
+		"swaps": []any{
+			map[string]any{
+				"id": 3.0, "hashLock": toHex(gethHash32Bytes("b2s2")), "value": 3.0,
+				"txs":        []any{},
+				"milestones": []any{map[string]any{"status": "New"}},
+			},
+			map[string]any{
+				"id": 2.0, "hashLock": toHex(gethHash32Bytes("b2s1")), "value": 2.0,
+				"txs": []any{
+					map[string]any{"leg": "bch_lock", "chain": "bch", "txHash": toHex(gethHash32Bytes(SwapLegBchLock))},
+					map[string]any{"leg": "sbch_lock", "chain": "sbch", "txHash": toHex(gethHash32Bytes(SwapLegSbchLock))},
+				},
+				"milestones": []any{map[string]any{"status": "New"}},
+			},
+		},
+		"nextCursor": 2.0,
+	}, data["swaps"])
+
+	// a swap by hash lock, in either direction
+	data, errs = query(`query($h: String!) { swap(hashLock: $h) { direction sender recipient token } }`,
+		map[string]any{"h": "0x" + toHex(gethHash32Bytes("s2b"))})
+	require.Empty(t, errs)
+	require.Equal(t, map[string]any{
+		"direction": DirectionSbch2Bch,
+		"sender":    toHex(gethAddrBytes("uevm")),
+		"recipient": toHex(gethAddrBytes("ubch")),
+		"token":     nil,
+	}, data["swap"])
+	data, errs = query(`{ swap(hashLock: "1234") { id } }`, nil)
+	require.Empty(t, errs)
+	require.Nil(t, data["swap"])
+
+	// errors
+	_, errs = query(`{ swaps(direction: "b2s") { nextCursor } }`, nil)
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].(map[string]any)["message"], "invalid direction")
+	_, errs = query(`{ swaps(direction: "bch2sbch") { swaps { secret } } }`, nil)
+	require.Len(t, errs, 1)
+
+	// body is validated
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"variables":{}}`)))
+	require.Equal(t, 400, w.Code)
+}
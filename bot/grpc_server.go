@@ -0,0 +1,400 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/smartbch/atomic-swap-bot/grpcapi"
+)
+
+const (
+	grpcEventBatch        = 100
+	grpcEventPollInterval = time.Second
+)
+
+// roles required by admin methods, other methods are public
+var grpcAdminRoles = map[string]string{
+	grpcapi.AtomicSwapBot_GetAccessList_FullMethodName:    RoleReader,
+	grpcapi.AtomicSwapBot_ReloadAccessList_FullMethodName: RoleOperator,
+	grpcapi.AtomicSwapBot_ReloadConfig_FullMethodName:     RoleOperator,
+}
+
+// GrpcServer serves the gRPC API, it mirrors the HTTP API
+// except sensitive admin requests, which must be signed over HTTP
+type GrpcServer struct {
+	grpcapi.UnimplementedAtomicSwapBotServer
+	bot *MarketMakerBot
+}
+
+func (bot *MarketMakerBot) StartGrpcServer(listenAddr string) {
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(bot.grpcAuthInterceptor)}
+	if bot.cfg.TlsCertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(bot.cfg.TlsCertFile, bot.cfg.TlsKeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	server := grpc.NewServer(opts...)
+	grpcapi.RegisterAtomicSwapBotServer(server, &GrpcServer{bot: bot})
+
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Info("gRPC server listening at:", listenAddr, "...")
+	if err = server.Serve(ln); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// admin methods require "authorization: Bearer <admin token|API key|JWT>" metadata
+func (bot *MarketMakerBot) grpcAuthInterceptor(ctx context.Context, req any,
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+	role, ok := grpcAdminRoles[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		token = strings.TrimPrefix(md.Get("authorization")[0], "Bearer ")
+	}
+	caller, httpStatus, err := bot.authorizeApiCaller(token, role, time.Now().Unix())
+	if err != nil {
+		return nil, status.Error(httpStatusToGrpcCode(httpStatus), err.Error())
+	}
+	callerName := "admin token"
+	if caller != nil {
+		callerName = caller.name
+	}
+	log.Infof("admin API %s called by %s", info.FullMethod, callerName)
+	return handler(ctx, req)
+}
+
+func httpStatusToGrpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.FailedPrecondition
+	}
+}
+
+func (s *GrpcServer) Ping(context.Context, *grpcapi.PingReq) (*grpcapi.PingResp, error) {
+	return &grpcapi.PingResp{Msg: "pong"}, nil
+}
+
+func (s *GrpcServer) GetInfo(context.Context, *grpcapi.GetInfoReq) (*grpcapi.Info, error) {
+	info, err := s.bot.getBotInfo()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &grpcapi.Info{
+		FreeBch:          info.FreeBch,
+		FreeSbch:         info.FreeSbch,
+		LockedBch:        info.LockedBch,
+		LockedSbch:       info.LockedSbch,
+		ToBeUnlockedBch:  info.ToBeUnlockedBch,
+		ToBeUnlockedSbch: info.ToBeUnlockedSbch,
+		S2BSwaps:         toGrpcSwapInfos(info.S2BSwaps),
+		B2SSwaps:         toGrpcSwapInfos(info.B2SSwaps),
+	}, nil
+}
+
+func toGrpcSwapInfos(swaps []SwapInfo) []*grpcapi.SwapInfo {
+	infos := make([]*grpcapi.SwapInfo, len(swaps))
+	for i, swap := range swaps {
+		infos[i] = &grpcapi.SwapInfo{
+			HashLock: swap.HashLock,
+			Value:    swap.Value,
+			Status:   swap.Status,
+			Token:    swap.Token,
+		}
+	}
+	return infos
+}
+
+func (s *GrpcServer) GetReceivePkh(context.Context, *grpcapi.GetReceivePkhReq) (*grpcapi.ReceivePkh, error) {
+	if s.bot.hdPkhs == nil {
+		return &grpcapi.ReceivePkh{Pkh: toHex(s.bot.bchPkh), Idx: -1}, nil
+	}
+	idx, pkh, err := s.bot.nextHdReceivePkh()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &grpcapi.ReceivePkh{Pkh: pkh, Idx: int64(idx)}, nil
+}
+
+func (s *GrpcServer) GetTokens(context.Context, *grpcapi.GetTokensReq) (*grpcapi.TokenList, error) {
+	tokens, err := s.bot.getTokenInfos()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	list := &grpcapi.TokenList{Tokens: make([]*grpcapi.Token, len(tokens))}
+	for i, token := range tokens {
+		list.Tokens[i] = &grpcapi.Token{
+			Symbol:       token.Symbol,
+			Addr:         token.Addr,
+			HtlcAddr:     token.HtlcAddr,
+			Decimals:     uint32(token.Decimals),
+			BchPrice:     token.BchPrice,
+			TokenPrice:   token.TokenPrice,
+			PriceStale:   token.PriceStale,
+			Free:         token.Free,
+			Locked:       token.Locked,
+			ToBeUnlocked: token.ToBeUnlocked,
+		}
+	}
+	return list, nil
+}
+
+func (s *GrpcServer) GetQuote(_ context.Context, req *grpcapi.QuoteReq) (*grpcapi.Quote, error) {
+	if req.Expiration > 0xFFFF {
+		return nil, status.Error(codes.InvalidArgument, "invalid expiration")
+	}
+	quote, err := s.bot.makeQuote(&QuoteReq{
+		Direction:     req.Direction,
+		Value:         req.Value,
+		HashLock:      req.HashLock,
+		Expiration:    uint16(req.Expiration),
+		SenderPkh:     req.SenderPkh,
+		SenderEvmAddr: req.SenderEvmAddr,
+		RecipientPkh:  req.RecipientPkh,
+		Token:         req.Token,
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &grpcapi.Quote{
+		Direction:    quote.Direction,
+		Token:        quote.Token,
+		Value:        quote.Value,
+		CounterValue: quote.CounterValue,
+		Fee:          quote.Fee,
+		Price:        quote.Price,
+		HashLock:     quote.HashLock,
+		CovenantAddr: quote.CovenantAddr,
+		OpReturn:     quote.OpRetPayload,
+		BchTimeLock:  uint32(quote.BchTimeLock),
+		SbchTimeLock: quote.SbchTimeLock,
+		PenaltyBps:   uint32(quote.PenaltyBPS),
+		ValidUntil:   quote.ValidUntil,
+		Signer:       quote.Signer,
+		Signature:    quote.Signature,
+		PaymentUri:   quote.PaymentUri,
+	}, nil
+}
+
+func (s *GrpcServer) ListSwaps(_ context.Context, req *grpcapi.ListSwapsReq) (*grpcapi.SwapList, error) {
+	f := &SwapFilter{
+		Cursor:   uint(req.Cursor),
+		Desc:     !req.Asc,
+		Limit:    int(req.Limit),
+		Archived: req.Archived,
+	}
+	if f.Limit == 0 {
+		f.Limit = defaultSwapListLimit
+	}
+	if req.Sender != "" {
+		f.Sender = toHex(gethcmn.FromHex(req.Sender))
+	}
+	if req.From > 0 {
+		f.From = time.Unix(req.From, 0)
+	}
+	if req.To > 0 {
+		f.To = time.Unix(req.To, 0)
+	}
+
+	list, err := s.bot.listSwaps(req.Direction, req.Status, "en", f)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	result := &grpcapi.SwapList{
+		Swaps:      make([]*grpcapi.SwapListItem, len(list.Swaps)),
+		NextCursor: uint64(list.NextCursor),
+	}
+	for i, swap := range list.Swaps {
+		result.Swaps[i] = &grpcapi.SwapListItem{
+			Id:        uint64(swap.Id),
+			Direction: swap.Direction,
+			HashLock:  swap.HashLock,
+			Value:     swap.Value,
+			Price:     swap.Price,
+			Status:    swap.Status,
+			Token:     swap.Token,
+			Sender:    swap.Sender,
+			Recipient: swap.Recipient,
+			CreatedAt: swap.CreatedAt,
+			UpdatedAt: swap.UpdatedAt,
+		}
+	}
+	return result, nil
+}
+
+func (s *GrpcServer) GetSwapTxs(_ context.Context, req *grpcapi.GetSwapTxsReq) (*grpcapi.SwapTxList, error) {
+	if req.HashLock == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing hash_lock")
+	}
+	txs, err := s.bot.db.getSwapTxsByHashLock(toHex(gethcmn.FromHex(req.HashLock)))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	list := &grpcapi.SwapTxList{Txs: make([]*grpcapi.SwapTx, len(txs))}
+	for i, tx := range txs {
+		list.Txs[i] = &grpcapi.SwapTx{
+			Leg:    tx.Leg,
+			TxHash: tx.TxHash,
+			RawTx:  tx.RawTx,
+		}
+	}
+	return list, nil
+}
+
+func (s *GrpcServer) GetStats(context.Context, *grpcapi.GetStatsReq) (*grpcapi.Stats, error) {
+	stats, err := s.bot.getStatsInfo()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &grpcapi.Stats{
+		Swaps:         stats.Swaps,
+		ServiceFee:    stats.ServiceFee,
+		EstimatedCost: stats.EstimatedCost,
+		BchMinerFee:   stats.BchMinerFee,
+		SbchGasFee:    stats.SbchGasFee,
+		Profit:        stats.Profit,
+	}, nil
+}
+
+func (s *GrpcServer) GetSwapStats(_ context.Context, req *grpcapi.GetSwapStatsReq) (*grpcapi.SwapStats, error) {
+	days := int(req.Days)
+	if days == 0 {
+		days = defaultStatsDays
+	}
+	if days > maxStatsDays {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("days must be in [1, %d]", maxStatsDays))
+	}
+	stats, err := s.bot.getSwapStats(days)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result := &grpcapi.SwapStats{
+		GeneratedAt: stats.GeneratedAt,
+		Days:        uint32(stats.Days),
+		Daily:       make([]*grpcapi.DailySwapStats, len(stats.Daily)),
+		Bch2Sbch:    toGrpcDirectionStats(stats.Bch2Sbch),
+		Sbch2Bch:    toGrpcDirectionStats(stats.Sbch2Bch),
+		FeesEarned:  stats.FeesEarned,
+		LargestSwap: stats.LargestSwap,
+	}
+	for i, daily := range stats.Daily {
+		result.Daily[i] = &grpcapi.DailySwapStats{
+			Date:     daily.Date,
+			Bch2Sbch: daily.Bch2Sbch,
+			Sbch2Bch: daily.Sbch2Bch,
+		}
+	}
+	return result, nil
+}
+
+func toGrpcDirectionStats(stats *DirectionStats) *grpcapi.DirectionStats {
+	if stats == nil {
+		return nil
+	}
+	return &grpcapi.DirectionStats{
+		Swaps:             stats.Swaps,
+		Completed:         stats.Completed,
+		Refunded:          stats.Refunded,
+		Volume:            stats.Volume,
+		LargestSwap:       stats.LargestSwap,
+		AvgCompletionTime: stats.AvgCompletionTime,
+		RefundRate:        stats.RefundRate,
+		FeesEarned:        stats.FeesEarned,
+	}
+}
+
+// send saved events in ID order, then poll for new ones until the client goes away
+func (s *GrpcServer) StreamSwapEvents(req *grpcapi.StreamSwapEventsReq,
+	stream grpcapi.AtomicSwapBot_StreamSwapEventsServer) error {
+
+	afterId := uint(req.AfterId)
+	for {
+		events, err := s.bot.db.getSwapEventsAfter(afterId, grpcEventBatch)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		for _, event := range events {
+			err = stream.Send(&grpcapi.SwapEvent{
+				Id:        uint64(event.ID),
+				Kind:      event.Kind,
+				Key:       event.Key,
+				Height:    event.Height,
+				Payload:   event.Payload,
+				CreatedAt: event.CreatedAt.Unix(),
+			})
+			if err != nil {
+				return err
+			}
+			afterId = event.ID
+		}
+		if len(events) == grpcEventBatch {
+			continue
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-time.After(grpcEventPollInterval):
+		}
+	}
+}
+
+func (s *GrpcServer) GetAccessList(context.Context, *grpcapi.GetAccessListReq) (*grpcapi.AccessList, error) {
+	if s.bot.accessList == nil {
+		return nil, status.Error(codes.FailedPrecondition, "access list is not enabled")
+	}
+	return toGrpcAccessList(s.bot.accessList.get()), nil
+}
+
+func (s *GrpcServer) ReloadAccessList(context.Context, *grpcapi.ReloadAccessListReq) (*grpcapi.AccessList, error) {
+	if s.bot.accessList == nil {
+		return nil, status.Error(codes.FailedPrecondition, "access list is not enabled")
+	}
+	if err := s.bot.accessList.reload(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return toGrpcAccessList(s.bot.accessList.get()), nil
+}
+
+func toGrpcAccessList(cfg AccessListConfig) *grpcapi.AccessList {
+	return &grpcapi.AccessList{
+		WhitelistPkhs:     cfg.WhitelistPkhs,
+		WhitelistEvmAddrs: cfg.WhitelistEvmAddrs,
+		BlacklistPkhs:     cfg.BlacklistPkhs,
+		BlacklistEvmAddrs: cfg.BlacklistEvmAddrs,
+	}
+}
+
+func (s *GrpcServer) ReloadConfig(context.Context, *grpcapi.ReloadConfigReq) (*grpcapi.ReloadConfigResp, error) {
+	if err := s.bot.requestConfigReload(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &grpcapi.ReloadConfigResp{Msg: "scheduled"}, nil
+}
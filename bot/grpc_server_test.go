@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/smartbch/atomic-swap-bot/grpcapi"
+)
+
+func startTestGrpcServer(t *testing.T, _bot *MarketMakerBot) grpcapi.AtomicSwapBotClient {
+	ln := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(_bot.grpcAuthInterceptor))
+	grpcapi.RegisterAtomicSwapBotServer(server, &GrpcServer{bot: _bot})
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return ln.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpcapi.NewAtomicSwapBotClient(conn)
+}
+
+func TestGrpcServer(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock")),
+		Value:          1e8,
+		BchPrice:       0.99e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(gethHash32Bytes("b2s")),
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		Status:         Bch2SbchStatusSbchLocked,
+	}))
+	require.NoError(t, _db.addSwapTx(&SwapTx{
+		HashLock: toHex(gethHash32Bytes("b2s")),
+		Leg:      SwapLegBchLock,
+		TxHash:   toHex(gethHash32Bytes("bchlock")),
+		RawTx:    "1234",
+	}))
+
+	_bot := &MarketMakerBot{
+		db:          _db,
+		bchPkh:      testBchPkh,
+		adminToken:  "secret",
+		errLogQueue: newErrLogQueue(100),
+	}
+	client := startTestGrpcServer(t, _bot)
+	ctx := context.Background()
+
+	pong, err := client.Ping(ctx, &grpcapi.PingReq{})
+	require.NoError(t, err)
+	require.Equal(t, "pong", pong.Msg)
+
+	pkh, err := client.GetReceivePkh(ctx, &grpcapi.GetReceivePkhReq{})
+	require.NoError(t, err)
+	require.Equal(t, toHex(testBchPkh), pkh.Pkh)
+	require.Equal(t, int64(-1), pkh.Idx)
+
+	swaps, err := client.ListSwaps(ctx, &grpcapi.ListSwapsReq{Direction: DirectionBch2Sbch, Status: "SbchLocked"})
+	require.NoError(t, err)
+	require.Len(t, swaps.Swaps, 1)
+	require.Equal(t, toHex(gethHash32Bytes("b2s")), swaps.Swaps[0].HashLock)
+	require.Equal(t, "SbchLocked", swaps.Swaps[0].Status)
+	require.Equal(t, uint64(0), swaps.NextCursor)
+
+	_, err = client.ListSwaps(ctx, &grpcapi.ListSwapsReq{Direction: "b2s"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	txs, err := client.GetSwapTxs(ctx, &grpcapi.GetSwapTxsReq{HashLock: toHex(gethHash32Bytes("b2s"))})
+	require.NoError(t, err)
+	require.Len(t, txs.Txs, 1)
+	require.Equal(t, SwapLegBchLock, txs.Txs[0].Leg)
+
+	// admin
+	_, err = client.ReloadConfig(ctx, &grpcapi.ReloadConfigReq{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	_, err = client.GetAccessList(adminCtx, &grpcapi.GetAccessListReq{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.ErrorContains(t, err, "access list is not enabled")
+}
+
+func TestGrpcServer_streamSwapEvents(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	bus := newEventBus(_db)
+	require.NoError(t, bus.publish(EventSbchLock, "0x01:0", 457, map[string]int{"a": 1}))
+	require.NoError(t, bus.publish(EventSbchUnlock, "0x02:0", 458, map[string]int{"b": 2}))
+
+	client := startTestGrpcServer(t, &MarketMakerBot{db: _db})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamSwapEvents(ctx, &grpcapi.StreamSwapEventsReq{AfterId: 1})
+	require.NoError(t, err)
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), event.Id)
+	require.Equal(t, EventSbchUnlock, event.Kind)
+	require.Equal(t, `{"b":2}`, event.Payload)
+
+	// new events are pushed
+	require.NoError(t, bus.publish(EventBchReceipt, "0x03", 124, map[string]int{"c": 3}))
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), event.Id)
+	require.Equal(t, uint64(124), event.Height)
+}
@@ -0,0 +1,219 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gcash/bchutil"
+	"github.com/gcash/bchutil/hdkeychain"
+	log "github.com/sirupsen/logrus"
+)
+
+// HdPkhs derives the bot's BCH receive PKHs from an xpub (external chain, xpub/0/i),
+// so that each BCH2SBCH swap can use a fresh PKH instead of the static one.
+type HdPkhs struct {
+	mutex     sync.Mutex
+	extKey    *hdkeychain.ExtendedKey // xpub/0
+	lookahead uint32
+	pkhToIdx  map[string]uint32
+	idxToPkh  map[uint32]string
+	usedIdx   map[uint32]bool
+	leasedIdx map[uint32]int64 // handed out but not used yet, index => unix time the lease ends
+}
+
+func newHdPkhs(xpub string, lookahead uint32) (*HdPkhs, error) {
+	key, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode xpub: %w", err)
+	}
+	if key.IsPrivate() {
+		return nil, fmt.Errorf("xprv is not allowed, please use xpub")
+	}
+	extKey, err := key.Child(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive external chain: %w", err)
+	}
+	if lookahead == 0 {
+		lookahead = 1
+	}
+	return &HdPkhs{
+		extKey:    extKey,
+		lookahead: lookahead,
+		pkhToIdx:  map[string]uint32{},
+		idxToPkh:  map[uint32]string{},
+		usedIdx:   map[uint32]bool{},
+		leasedIdx: map[uint32]int64{},
+	}, nil
+}
+
+func (h *HdPkhs) derivePkh(idx uint32) (string, error) {
+	child, err := h.extKey.Child(idx)
+	if err != nil {
+		return "", err
+	}
+	pbk, err := child.ECPubKey()
+	if err != nil {
+		return "", err
+	}
+	return toHex(bchutil.Hash160(pbk.SerializeCompressed())), nil
+}
+
+// derive PKHs up to (the last used or leased index + lookahead), returns newly derived ones
+func (h *HdPkhs) extend() (newPkhs []*HdReceivePkh, err error) {
+	var end uint32
+	for idx := range h.usedIdx {
+		if idx+1 > end {
+			end = idx + 1
+		}
+	}
+	for idx := range h.leasedIdx {
+		if idx+1 > end {
+			end = idx + 1
+		}
+	}
+	end += h.lookahead
+
+	for idx := uint32(0); idx < end; idx++ {
+		if _, ok := h.idxToPkh[idx]; ok {
+			continue
+		}
+		pkh, err := h.derivePkh(idx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive PKH#%d: %w", idx, err)
+		}
+		h.pkhToIdx[pkh] = idx
+		h.idxToPkh[idx] = pkh
+		newPkhs = append(newPkhs, &HdReceivePkh{Idx: idx, Pkh: pkh})
+	}
+	return
+}
+
+func (h *HdPkhs) getIdx(pkh string) (uint32, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	idx, ok := h.pkhToIdx[pkh]
+	return idx, ok
+}
+
+// return the first index which has not been used by any swap, nor handed out to others
+// until now, and lease it until leaseEnd. Expired leases are handed out again, so that
+// the gap of unused PKHs is bounded by the requests made within a lease.
+// Caller should hold the lock, and derive the PKH by extend().
+func (h *HdPkhs) leaseNextUnused(now, leaseEnd int64) uint32 {
+	for idx := uint32(0); ; idx++ {
+		if h.usedIdx[idx] {
+			continue
+		}
+		if end, ok := h.leasedIdx[idx]; ok && end > now {
+			continue
+		}
+		h.leasedIdx[idx] = leaseEnd
+		return idx
+	}
+}
+
+// return a PKH which is not used by any swap and is handed out to one requester at a time,
+// it is leased for the validity of a quote, see HdPkhs.leaseNextUnused()
+func (bot *MarketMakerBot) nextHdReceivePkh() (uint32, string, error) {
+	h := bot.hdPkhs
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := time.Now().Unix()
+	idx := h.leaseNextUnused(now, now+int64(bot.quoteValidity))
+	if err := bot.saveNewHdReceivePkhs(); err != nil {
+		delete(h.leasedIdx, idx)
+		return 0, "", fmt.Errorf("failed to derive more HD PKHs: %w", err)
+	}
+	return idx, h.idxToPkh[idx], nil
+}
+
+// load PKHs from DB, and derive & save more PKHs if needed
+func (bot *MarketMakerBot) loadHdReceivePkhs() error {
+	if bot.hdPkhs == nil {
+		return nil
+	}
+
+	savedPkhs, err := bot.db.getAllHdReceivePkhs()
+	if err != nil {
+		return fmt.Errorf("failed to load HD PKHs: %w", err)
+	}
+
+	h := bot.hdPkhs
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, savedPkh := range savedPkhs {
+		pkh, err := h.derivePkh(savedPkh.Idx)
+		if err != nil {
+			return fmt.Errorf("failed to derive PKH#%d: %w", savedPkh.Idx, err)
+		}
+		if pkh != savedPkh.Pkh {
+			return fmt.Errorf("PKH#%d mismatch: %s != %s, wrong xpub?",
+				savedPkh.Idx, pkh, savedPkh.Pkh)
+		}
+		h.pkhToIdx[pkh] = savedPkh.Idx
+		h.idxToPkh[savedPkh.Idx] = pkh
+		if savedPkh.HashLock != "" {
+			h.usedIdx[savedPkh.Idx] = true
+		}
+	}
+	return bot.saveNewHdReceivePkhs()
+}
+
+// caller should hold the lock
+func (bot *MarketMakerBot) saveNewHdReceivePkhs() error {
+	newPkhs, err := bot.hdPkhs.extend()
+	if err != nil {
+		return err
+	}
+	for _, newPkh := range newPkhs {
+		if err = bot.db.addHdReceivePkh(newPkh); err != nil {
+			return fmt.Errorf("failed to save HD PKH: %w", err)
+		}
+	}
+	if len(newPkhs) > 0 {
+		log.Info("HD receive PKHs: ", len(bot.hdPkhs.idxToPkh))
+	}
+	return nil
+}
+
+// the static PKH or one of the derived PKHs
+func (bot *MarketMakerBot) isMyReceivePkh(pkh []byte) bool {
+	if bytes.Equal(pkh, bot.bchPkh) {
+		return true
+	}
+	if bot.hdPkhs == nil {
+		return false
+	}
+	_, ok := bot.hdPkhs.getIdx(toHex(pkh))
+	return ok
+}
+
+// record which derivation index the swap used, and move the lookahead window forward
+func (bot *MarketMakerBot) markHdReceivePkhUsed(pkh []byte, hashLock string) {
+	if bot.hdPkhs == nil {
+		return
+	}
+	idx, ok := bot.hdPkhs.getIdx(toHex(pkh))
+	if !ok {
+		return
+	}
+
+	log.Infof("HD PKH#%d is used by swap, hashLock: %s", idx, hashLock)
+	err := bot.db.setHdReceivePkhHashLock(idx, hashLock)
+	if err != nil {
+		bot.logError("DB error, failed to update HD PKH: ", err)
+		return
+	}
+
+	bot.hdPkhs.mutex.Lock()
+	defer bot.hdPkhs.mutex.Unlock()
+	bot.hdPkhs.usedIdx[idx] = true
+	delete(bot.hdPkhs.leasedIdx, idx)
+	if err = bot.saveNewHdReceivePkhs(); err != nil {
+		bot.logError("failed to derive more HD PKHs: ", err)
+	}
+}
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil/hdkeychain"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestHdReceivePkhs(t *testing.T) {
+	master, err := hdkeychain.NewMaster(gethcmn.FromHex("0x000102030405060708090a0b0c0d0e0f"), &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	xpub, err := master.Neuter()
+	require.NoError(t, err)
+
+	hdPkhs, err := newHdPkhs(xpub.String(), 3)
+	require.NoError(t, err)
+	_, err = newHdPkhs(master.String(), 3)
+	require.ErrorContains(t, err, "xprv is not allowed")
+
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:            _db,
+		dbQueryLimit:  100,
+		bchPkh:        testBchPkh,
+		hdPkhs:        hdPkhs,
+		bchTimeLock:   100,
+		penaltyRatio:  500,
+		bchPrice:      1e8,
+		sbchPrice:     1e8,
+		quoteValidity: 600,
+	}
+	require.NoError(t, _bot.loadHdReceivePkhs())
+
+	pkhs, err := _db.getAllHdReceivePkhs()
+	require.NoError(t, err)
+	require.Len(t, pkhs, 3)
+
+	idx, pkh, err := _bot.nextHdReceivePkh()
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), idx)
+	require.Equal(t, pkhs[0].Pkh, pkh)
+	require.True(t, _bot.isMyReceivePkh(testBchPkh))
+	require.True(t, _bot.isMyReceivePkh(gethcmn.FromHex(pkhs[2].Pkh)))
+	require.False(t, _bot.isMyReceivePkh(gethAddrBytes("user")))
+
+	// user locks BCH to PKH#0
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_hdPkh := gethcmn.FromHex(pkh)
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, _hdPkh, _hashLock, 100, 500)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+
+	_bchCli := newMockBchClient(124, 124)
+	_bchCli.blocks[124] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+					{PkScript: newHtlcDepositOpRet(_hdPkh, _userPkh, _hashLock, 100, 500, gethAddrBytes("evm"), 1e8)},
+				},
+			},
+		},
+	}
+	_bot.bchCli = _bchCli
+	_bot.scanBchBlocks()
+
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, pkh, records[0].RecipientPkh)
+
+	// PKH#0 is used, lookahead window moved forward
+	pkhs, err = _db.getAllHdReceivePkhs()
+	require.NoError(t, err)
+	require.Len(t, pkhs, 4)
+	require.Equal(t, toHex(_hashLock), pkhs[0].HashLock)
+	idx, pkh, err = _bot.nextHdReceivePkh()
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), idx)
+	require.Equal(t, pkhs[1].Pkh, pkh)
+
+	// reload from DB
+	hdPkhs2, err := newHdPkhs(xpub.String(), 3)
+	require.NoError(t, err)
+	_bot.hdPkhs = hdPkhs2
+	require.NoError(t, _bot.loadHdReceivePkhs())
+	idx, _, err = _bot.nextHdReceivePkh()
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), idx)
+}
+
+func TestHdReceivePkhs_leased(t *testing.T) {
+	master, err := hdkeychain.NewMaster(gethcmn.FromHex("0x000102030405060708090a0b0c0d0e0f"), &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	xpub, err := master.Neuter()
+	require.NoError(t, err)
+	hdPkhs, err := newHdPkhs(xpub.String(), 2)
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:            _db,
+		hdPkhs:        hdPkhs,
+		quoteValidity: 600,
+	}
+	require.NoError(t, _bot.loadHdReceivePkhs())
+
+	// back-to-back requests get different PKHs, beyond the lookahead window
+	seen := map[string]bool{}
+	for i := uint32(0); i < 4; i++ {
+		idx, pkh, err := _bot.nextHdReceivePkh()
+		require.NoError(t, err)
+		require.Equal(t, i, idx)
+		require.False(t, seen[pkh])
+		seen[pkh] = true
+		require.True(t, _bot.isMyReceivePkh(gethcmn.FromHex(pkh)))
+	}
+	pkhs, err := _db.getAllHdReceivePkhs()
+	require.NoError(t, err)
+	require.Len(t, pkhs, 6)
+
+	// expired leases are handed out again
+	hdPkhs.mutex.Lock()
+	hdPkhs.leasedIdx[1] = time.Now().Unix()
+	hdPkhs.mutex.Unlock()
+	idx, _, err := _bot.nextHdReceivePkh()
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), idx)
+}
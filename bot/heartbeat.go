@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The leader pings an external monitor (healthchecks.io, Uptime Kuma push monitors, ...) from the
+// main loop, which alerts the operator when pings stop coming: the host is down, the bot crashed,
+// or the main loop is stuck. Pings carry a HeartbeatInfo as body, and go to <url>/fail instead if
+// a scanner lags behind the chain tip for longer than max lag, which the monitor alerts at once.
+
+const (
+	defaultHeartbeatInterval = 60
+	heartbeatTimeout         = 10 * time.Second
+)
+
+// HeartbeatInfo is the JSON body of heartbeat pings
+type HeartbeatInfo struct {
+	Source     string   `json:"source"`           // sBCH address of the bot
+	Time       int64    `json:"time"`             // unix seconds
+	Status     string   `json:"status"`           // ok|lagging|paused|frozen
+	BchHeight  uint64   `json:"bch_height"`       // last scanned
+	SbchHeight uint64   `json:"sbch_height"`      // last scanned
+	BchLag     int64    `json:"bch_lag"`          // seconds since the scanner caught up with the chain tip, -1 means not yet
+	SbchLag    int64    `json:"sbch_lag"`         // same as bch_lag
+	FreeBch    float64  `json:"free_bch"`         // hot wallet
+	FreeSbch   float64  `json:"free_sbch"`        //
+	LockedBch  float64  `json:"locked_bch"`       // locked by bot for in-flight swaps
+	LockedSbch float64  `json:"locked_sbch"`      //
+	Errors     []string `json:"errors,omitempty"` // failed queries, pinged anyway
+}
+
+// called by the main loop, only when this instance is leading
+func (bot *MarketMakerBot) sendHeartbeat() {
+	if bot.heartbeatUrl == "" {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastHeartbeatAt < int64(bot.heartbeatInterval) {
+		return
+	}
+	bot.lastHeartbeatAt = now
+
+	info := bot.getHeartbeatInfo(now)
+	pingUrl := bot.heartbeatUrl
+	if info.Status == "lagging" {
+		pingUrl = strings.TrimSuffix(pingUrl, "/") + "/fail"
+	}
+	log.Info("send heartbeat, status: ", info.Status)
+	if err := postHeartbeat(pingUrl, info); err != nil {
+		bot.logError("failed to send heartbeat: ", err)
+	}
+}
+
+func (bot *MarketMakerBot) getHeartbeatInfo(now int64) *HeartbeatInfo {
+	info := &HeartbeatInfo{
+		Source:  bot.sbchAddr.String(),
+		Time:    now,
+		Status:  "ok",
+		BchLag:  -1,
+		SbchLag: -1,
+	}
+	addErr := func(msg string, err error) {
+		info.Errors = append(info.Errors, fmt.Sprintf("%s: %s", msg, err))
+	}
+
+	if heights, err := bot.db.getLastHeights(); err == nil {
+		info.BchHeight, info.SbchHeight = heights.LastBchHeight, heights.LastSbchHeight
+	} else {
+		addErr("failed to get last heights", err)
+	}
+	if bot.bchScannedAt > 0 {
+		info.BchLag = now - bot.bchScannedAt
+	}
+	if bot.sbchScannedAt > 0 {
+		info.SbchLag = now - bot.sbchScannedAt
+	}
+
+	var err error
+	if info.FreeBch, err = bot.getFreeBch(); err != nil {
+		addErr("failed to query UTXOs", err)
+	}
+	if bot.sbchCliRO != nil {
+		if info.FreeSbch, err = bot.getFreeSbch(); err != nil {
+			addErr("failed to query sBCH balance", err)
+		}
+	}
+	if _, info.LockedBch, _, err = bot.getSbch2BchInfo(); err != nil {
+		addErr("failed to get SBCH2BCH records", err)
+	}
+	if _, info.LockedSbch, _, err = bot.getBch2SbchInfo(); err != nil {
+		addErr("failed to get BCH2SBCH records", err)
+	}
+
+	maxLag := int64(bot.heartbeatMaxLag)
+	switch {
+	case maxLag > 0 && (info.BchLag > maxLag || info.SbchLag > maxLag):
+		info.Status = "lagging"
+	case bot.isFrozen():
+		info.Status = "frozen"
+	case bot.isPaused():
+		info.Status = "paused"
+	}
+	return info
+}
+
+func postHeartbeat(pingUrl string, info *HeartbeatInfo) error {
+	body, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: heartbeatTimeout}
+	resp, err := client.Post(pingUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
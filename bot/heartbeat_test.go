@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gcash/bchd/btcjson"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendHeartbeat(t *testing.T) {
+	var paths []string
+	var infos []*HeartbeatInfo
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		info := &HeartbeatInfo{}
+		require.NoError(t, json.Unmarshal(body, info))
+		paths = append(paths, r.URL.Path)
+		infos = append(infos, info)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(123, 130)
+	_bchCli.utxos = []btcjson.ListUnspentResult{{Amount: 1.5}, {Amount: 0.25}}
+	_bot := &MarketMakerBot{
+		db:                _db,
+		bchCli:            _bchCli,
+		sbchAddr:          gethAddr("bot"),
+		dbQueryLimit:      100,
+		errLogQueue:       newErrLogQueue(100),
+		heartbeatUrl:      server.URL + "/ping/uuid/",
+		heartbeatInterval: 60,
+		heartbeatMaxLag:   600,
+	}
+
+	_bot.sendHeartbeat()
+	require.Equal(t, []string{"/ping/uuid/"}, paths)
+	require.Equal(t, gethAddr("bot").String(), infos[0].Source)
+	require.Equal(t, "ok", infos[0].Status)
+	require.Equal(t, uint64(123), infos[0].BchHeight)
+	require.Equal(t, uint64(456), infos[0].SbchHeight)
+	require.Equal(t, int64(-1), infos[0].BchLag)
+	require.Equal(t, 1.75, infos[0].FreeBch)
+	require.Empty(t, infos[0].Errors)
+
+	// not pinged again within the interval
+	_bot.sendHeartbeat()
+	require.Len(t, paths, 1)
+
+	// lagging scanners are pinged as failure
+	now := time.Now().Unix()
+	_bot.lastHeartbeatAt = 0
+	_bot.bchScannedAt = now - 10
+	_bot.sbchScannedAt = now - 1000
+	_bot.sendHeartbeat()
+	require.Equal(t, "/ping/uuid/fail", paths[1])
+	require.Equal(t, "lagging", infos[1].Status)
+	require.GreaterOrEqual(t, infos[1].SbchLag, int64(1000))
+
+	_bot.lastHeartbeatAt = 0
+	_bot.sbchScannedAt = now
+	_bot.paused.Store(true)
+	_bot.sendHeartbeat()
+	require.Equal(t, "/ping/uuid/", paths[2])
+	require.Equal(t, "paused", infos[2].Status)
+
+	// failed pings are logged
+	status = http.StatusNotFound
+	_bot.lastHeartbeatAt = 0
+	_bot.sendHeartbeat()
+	logs := _bot.errLogQueue.peekErrLogs(10)
+	require.Len(t, logs, 1)
+	require.Contains(t, logs[0].Msg, "failed to send heartbeat: ")
+	require.Contains(t, logs[0].Msg, "unexpected status: 404 Not Found")
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxRateWindows = 10000
+
+// HttpPolicy makes the API usable by browser frontends and behind reverse proxies:
+// it answers CORS requests from allowed origins, and limits request rate per client IP,
+// which is taken from X-Forwarded-For if the request comes from a trusted proxy.
+type HttpPolicy struct {
+	mu             sync.Mutex
+	corsOrigins    map[string]bool // "*" means any origin
+	trustedProxies []*net.IPNet
+	rateLimit      uint32                 // requests per minute per client IP, 0 means unlimited
+	windows        map[string]*rateWindow // client IP => current window
+}
+
+func newHttpPolicy(cfg *Config) (*HttpPolicy, error) {
+	policy := &HttpPolicy{windows: map[string]*rateWindow{}}
+	return policy, policy.set(cfg)
+}
+
+func (policy *HttpPolicy) set(cfg *Config) error {
+	origins := map[string]bool{}
+	for _, origin := range cfg.CorsOrigins {
+		origins[strings.TrimSuffix(strings.TrimSpace(origin), "/")] = true
+	}
+	var proxies []*net.IPNet
+	for _, s := range cfg.TrustedProxies {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			if strings.Contains(s, ":") {
+				s += "/128"
+			} else {
+				s += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy: %s", s)
+		}
+		proxies = append(proxies, ipNet)
+	}
+
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+	policy.corsOrigins = origins
+	policy.trustedProxies = proxies
+	policy.rateLimit = cfg.PublicRateLimit
+	return nil
+}
+
+func (policy *HttpPolicy) isTrustedProxy(ip net.IP) bool {
+	for _, ipNet := range policy.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// the IP of the client, X-Forwarded-For is only believed if the peer is a trusted proxy
+func (policy *HttpPolicy) getClientIP(r *http.Request) string {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !policy.isTrustedProxy(ip) {
+		return host
+	}
+
+	// the rightmost untrusted address is the client
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !policy.isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// count a request of the client, return false if it exceeds rate limit
+func (policy *HttpPolicy) allow(clientIP string, now int64) bool {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+	if policy.rateLimit == 0 {
+		return true
+	}
+
+	if _, ok := policy.windows[clientIP]; !ok && len(policy.windows) >= maxRateWindows {
+		policy.evictRateWindows(now)
+	}
+	w := policy.windows[clientIP]
+	if w == nil || now-w.start >= rateLimitWindow {
+		w = &rateWindow{start: now}
+		policy.windows[clientIP] = w
+	}
+	w.count++
+	return w.count <= policy.rateLimit
+}
+
+// make room for a new client IP, evict expired windows, or the oldest one if none is expired,
+// so that the map never grows past maxRateWindows
+func (policy *HttpPolicy) evictRateWindows(now int64) {
+	oldestIP := ""
+	for ip, w := range policy.windows {
+		if now-w.start >= rateLimitWindow {
+			delete(policy.windows, ip)
+		} else if oldestIP == "" || w.start < policy.windows[oldestIP].start {
+			oldestIP = ip
+		}
+	}
+	if len(policy.windows) >= maxRateWindows {
+		delete(policy.windows, oldestIP)
+	}
+}
+
+func (policy *HttpPolicy) isAllowedOrigin(origin string) bool {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+	return policy.corsOrigins["*"] || policy.corsOrigins[origin]
+}
+
+// wrap API handlers with CORS and rate limit
+func (policy *HttpPolicy) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && policy.isAllowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers",
+					"Authorization, Content-Type, X-Api-Timestamp, X-Api-Signature")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if !policy.allow(policy.getClientIP(r), time.Now().Unix()) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			NewErrResp("rate limit exceeded").WriteTo(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
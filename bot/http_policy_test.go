@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpPolicy_clientIP(t *testing.T) {
+	_, err := newHttpPolicy(&Config{TrustedProxies: []string{"10.0.0.0/33"}})
+	require.ErrorContains(t, err, "invalid trusted proxy")
+
+	policy, err := newHttpPolicy(&Config{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "::1"}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/info", nil)
+	req.RemoteAddr = "1.2.3.4:5678"
+	req.Header.Set("X-Forwarded-For", "6.6.6.6")
+	require.Equal(t, "1.2.3.4", policy.getClientIP(req)) // untrusted peer
+
+	req.RemoteAddr = "10.1.2.3:5678"
+	require.Equal(t, "6.6.6.6", policy.getClientIP(req))
+	req.Header.Set("X-Forwarded-For", "6.6.6.6, 7.7.7.7, 192.168.1.1")
+	require.Equal(t, "7.7.7.7", policy.getClientIP(req)) // spoofed 6.6.6.6 is ignored
+	req.Header.Set("X-Forwarded-For", "")
+	require.Equal(t, "10.1.2.3", policy.getClientIP(req))
+
+	req.RemoteAddr = "[::1]:5678"
+	req.Header.Set("X-Forwarded-For", "2001:db8::1")
+	require.Equal(t, "2001:db8::1", policy.getClientIP(req))
+}
+
+func TestHttpPolicy_wrap(t *testing.T) {
+	policy, err := newHttpPolicy(&Config{
+		CorsOrigins:     []string{"https://swap.example.com/"},
+		TrustedProxies:  []string{"10.0.0.1"},
+		PublicRateLimit: 2,
+	})
+	require.NoError(t, err)
+	_bot := &MarketMakerBot{}
+	handler := policy.wrap(_bot.createHttpHandlers())
+
+	call := func(method, origin, xff string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/ping", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("Origin", origin)
+		req.Header.Set("X-Forwarded-For", xff)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// preflight is not rate limited
+	w := call(http.MethodOptions, "https://swap.example.com", "1.1.1.1")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "https://swap.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+
+	w = call(http.MethodGet, "https://evil.example.com", "1.1.1.1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	w = call(http.MethodGet, "https://swap.example.com", "1.1.1.1")
+	require.Contains(t, w.Body.String(), "pong")
+	require.Equal(t, "https://swap.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, http.StatusTooManyRequests, call(http.MethodGet, "", "1.1.1.1").Code)
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "", "2.2.2.2").Code)
+
+	// hot reload
+	require.NoError(t, policy.set(&Config{CorsOrigins: []string{"*"}}))
+	w = call(http.MethodGet, "https://any.example.com", "1.1.1.1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "https://any.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestHttpPolicy_rateWindow(t *testing.T) {
+	policy, err := newHttpPolicy(&Config{PublicRateLimit: 1})
+	require.NoError(t, err)
+	now := time.Now().Unix()
+	require.True(t, policy.allow("1.1.1.1", now))
+	require.False(t, policy.allow("1.1.1.1", now+1))
+	require.True(t, policy.allow("1.1.1.1", now+rateLimitWindow))
+
+	// the map is capped, the oldest window is evicted if none is expired
+	for i := 1; i < maxRateWindows; i++ {
+		require.True(t, policy.allow(fmt.Sprintf("2.2.%d.%d", i/256, i%256), now+rateLimitWindow+1))
+	}
+	require.Len(t, policy.windows, maxRateWindows)
+	require.True(t, policy.allow("3.3.3.3", now+rateLimitWindow+2))
+	require.Len(t, policy.windows, maxRateWindows)
+	require.Nil(t, policy.windows["1.1.1.1"])
+	require.False(t, policy.allow("3.3.3.3", now+rateLimitWindow+3))
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"bytes"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// without Fulcrum, BCH locks of bot are searched in at most this many recent blocks
+const maxGuardScanBlocks = 36
+
+// With the idempotency guard enabled, bot looks up the chains before building a lock or claim tx
+// which is not tracked by a ChainJob, and does not act again if it has been done.
+// This protects against duplicates after the DB is restored from an older backup.
+
+// find the BCH lock tx of bot for the sBCH deposit on chain, empty if not found
+func (bot *MarketMakerBot) findBchLockOnChain(record *Sbch2BchRecord) (string, error) {
+	// the lock of bot can be in any output order, and is never below the floor
+	filter := &htlcbch.DepositFilter{AnyOutputOrder: true}
+	findLock := func(block *btcjson.GetBlockVerboseTxResult) string {
+		for _, deposit := range bot.getChainAdapter().ScanBlock(block, filter).Deposits {
+			if bytes.Equal(deposit.SenderPkh, bot.bchPkh) &&
+				toHex(deposit.HashLock) == record.HashLock &&
+				toHex(deposit.ScriptHash) == record.HtlcScriptHash {
+				return deposit.TxHash
+			}
+		}
+		return ""
+	}
+
+	if bot.fulcrumCli != nil {
+		// mempool txs are included
+		history, err := bot.fulcrumCli.GetScriptHashHistory(P2SHScriptHash(gethcmn.FromHex(record.HtlcScriptHash)))
+		if err != nil {
+			return "", err
+		}
+		for _, txRef := range history {
+			tx, err := bot.fulcrumCli.GetTx(txRef.TxHash)
+			if err != nil {
+				return "", err
+			}
+			if txHash := findLock(&btcjson.GetBlockVerboseTxResult{Tx: []btcjson.TxRawResult{*tx}}); txHash != "" {
+				return txHash, nil
+			}
+		}
+		return "", nil
+	}
+
+	// the lock is made after the sBCH deposit, which is about n blocks ago
+	n := (time.Now().Unix()-int64(record.SbchLockTime))/600 + 2
+	if n > maxGuardScanBlocks {
+		n = maxGuardScanBlocks
+	}
+	tip, err := bot.bchCli.GetBlockCount()
+	if err != nil {
+		return "", err
+	}
+	for h := tip; h > tip-n && h > 0; h-- {
+		block, err := bot.bchCli.GetBlock(h)
+		if err != nil {
+			return "", err
+		}
+		if txHash := findLock(block); txHash != "" {
+			return txHash, nil
+		}
+	}
+	return "", nil
+}
+
+// the BCH deposit has been unlocked by bot (or refunded by the user), in blocks or in mempool
+func (bot *MarketMakerBot) isBchDepositSpent(record *Bch2SbchRecord) (bool, error) {
+	txOut, err := bot.bchCli.GetTxOut(record.BchLockTxHash, record.BchLockVout)
+	if err != nil {
+		return false, err
+	}
+	return txOut == nil, nil
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSbch2Bch_botLockBch_guarded(t *testing.T) {
+	_hashLock := gethHash32Bytes("hashlock")
+	_lockTime := uint64(time.Now().Unix() - 60)
+	_timeLock := uint32(36000)
+
+	newBot := func() *MarketMakerBot {
+		return &MarketMakerBot{
+			db:               initDB(t, 123, 456),
+			dbQueryLimit:     100,
+			bchCli:           newMockBchClient(100, 101),
+			bchPrivKey:       testBchPrivKey,
+			bchPkh:           testBchPkh,
+			sbchCli:          newMockSbchClient(457, 500, _lockTime+60),
+			sbchAddr:         testEvmAddr,
+			sbchTimeLock:     _timeLock,
+			bchPrice:         1e8,
+			sbchPrice:        8e7,
+			idempotencyGuard: true,
+			errLogQueue:      newErrLogQueue(100),
+		}
+	}
+	addRecord := func(_bot *MarketMakerBot) {
+		record := &Sbch2BchRecord{
+			SbchLockTime:    _lockTime,
+			SbchLockTxHash:  toHex(gethHash32Bytes("sbchlocktx")),
+			Value:           12345678,
+			SbchPrice:       8e7 - 1,
+			SbchSenderAddr:  gethAddr("uevm").String(),
+			BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+			HashLock:        toHex(_hashLock),
+			TimeLock:        _timeLock,
+			Status:          Sbch2BchStatusNew,
+		}
+		scriptHash, err := _bot.getChainAdapter().ScriptHash(_bot.newSbch2BchHtlcSpec(record))
+		require.NoError(t, err)
+		record.HtlcScriptHash = toHex(scriptHash)
+		require.NoError(t, _bot.db.addSbch2BchRecord(record))
+	}
+	getRecord := func(_bot *MarketMakerBot) *Sbch2BchRecord {
+		record, err := _bot.db.getSbch2BchRecordByHashLock(toHex(_hashLock))
+		require.NoError(t, err)
+		return record
+	}
+
+	// nothing found on chain, BCH is locked
+	bot1 := newBot()
+	addRecord(bot1)
+	bot1.handleSbchUserDeposits()
+	sentTxs := bot1.bchCli.(*MockBchClient).sentTxs
+	require.Len(t, sentTxs, 1)
+	lockTx := sentTxs[0]
+	require.Equal(t, lockTx.TxHash().String(), getRecord(bot1).BchLockTxHash)
+
+	// DB is restored from a backup made before the lock, which is found in blocks
+	bot2 := newBot()
+	addRecord(bot2)
+	bchCli2 := bot2.bchCli.(*MockBchClient)
+	bchCli2.blocks[100].Transactions = []*wire.MsgTx{lockTx}
+	bot2.handleSbchUserDeposits()
+	require.Len(t, bchCli2.sentTxs, 0)
+	require.Equal(t, Sbch2BchStatusBchLocked, getRecord(bot2).Status)
+	require.Equal(t, lockTx.TxHash().String(), getRecord(bot2).BchLockTxHash)
+	require.Len(t, bot2.errLogQueue.removeErrLogs(10), 1)
+
+	// or found in mempool by Fulcrum
+	bot3 := newBot()
+	addRecord(bot3)
+	fulcrumCli := newMockFulcrumClient()
+	fulcrumCli.addTx(P2SHScriptHash(gethcmn.FromHex(getRecord(bot3).HtlcScriptHash)), lockTx)
+	bot3.fulcrumCli = fulcrumCli
+	bot3.handleSbchUserDeposits()
+	require.Len(t, bot3.bchCli.(*MockBchClient).sentTxs, 0)
+	require.Equal(t, lockTx.TxHash().String(), getRecord(bot3).BchLockTxHash)
+}
+
+func TestBch2Sbch_botUnlockBch_guarded(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(100, 101)
+	_bot := &MarketMakerBot{
+		db:               _db,
+		bchCli:           _bchCli,
+		bchPrivKey:       testBchPrivKey,
+		bchPkh:           testBchPkh,
+		idempotencyGuard: true,
+		errLogQueue:      newErrLogQueue(100),
+	}
+	addRecord := func(name string) *Bch2SbchRecord {
+		secret := gethHash32Bytes(name)
+		hashLock := sha256.Sum256(secret)
+		lockTx := wire.NewMsgTx(2)
+		lockTx.AddTxOut(wire.NewTxOut(12345678, nil))
+		lockTx.LockTime = uint32(len(name))
+		_bchCli.txs[lockTx.TxHash().String()] = lockTx
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockHeight:  100,
+			BchLockTxHash:  lockTx.TxHash().String(),
+			Value:          12345678,
+			BchPrice:       1e8,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(gethAddrBytes("user")),
+			HashLock:       toHex(hashLock[:]),
+			TimeLock:       100,
+			SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+			SbchLockTxHash: toHex(gethHash32Bytes("sbchlock")),
+			Secret:         toHex(secret),
+			Status:         Bch2SbchStatusSecretRevealed,
+		}))
+		record, err := _db.getBch2SbchRecordByHashLock(toHex(hashLock[:]))
+		require.NoError(t, err)
+		return record
+	}
+
+	// the deposit is unspent, it is unlocked
+	record := addRecord("s1")
+	require.True(t, _bot.unlockBchUserDeposit(record, time.Now()))
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Equal(t, _bchCli.sentTxs[0].TxHash().String(), record.BchUnlockTxHash)
+
+	// the deposit has been unlocked before the DB backup was restored
+	record = addRecord("s22")
+	_bchCli.spentOutputs[record.BchLockTxHash+":0"] = true
+	require.True(t, _bot.unlockBchUserDeposit(record, time.Now()))
+	require.Len(t, _bchCli.sentTxs, 1)
+	record, err := _db.getBch2SbchRecordByHashLock(record.HashLock)
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusBchUnlocked, record.Status)
+	require.Equal(t, "?", record.BchUnlockTxHash)
+}
+
+func TestBch2Sbch_botLockSbch_guarded(t *testing.T) {
+	_hashLock := gethHash32Bytes("hashlock")
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock")),
+		Value:          12345678,
+		BchPrice:       1e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(_hashLock),
+		TimeLock:       72,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+	}))
+
+	_sbchCli := newMockSbchClient(457, 500, 0)
+	_sbchCli.states[gethcmn.BytesToHash(_hashLock)] = SwapLocked
+	_bot := &MarketMakerBot{
+		db:               _db,
+		dbQueryLimit:     100,
+		bchCli:           newMockBchClient(100, 101),
+		sbchCli:          _sbchCli,
+		sbchAddr:         testEvmAddr,
+		bchTimeLock:      72,
+		idempotencyGuard: true,
+	}
+
+	// locked before the DB backup was restored, even if the price has changed since then
+	_bot.handleBchUserDeposits()
+	record, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusSbchLocked, record.Status)
+	require.Equal(t, "?", record.SbchLockTxHash)
+	txs, err := _db.getSwapTxsByHashLock(record.HashLock)
+	require.NoError(t, err)
+	require.Len(t, txs, 0)
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// end-to-end latency of a swap: from the user's deposit being confirmed (record created)
+// to the counter-asset being delivered (the user unlocks bot's lock with the secret)
+const (
+	MilestoneDepositConfirmed = "New"
+	MilestoneDelivered        = "SecretRevealed"
+
+	MetricSwapLatency    = "asbot_swap_latency_seconds"
+	swapLatencyBatchSize = 1000
+)
+
+// upper bounds of histogram buckets, in seconds
+var swapLatencyBuckets = []float64{60, 300, 600, 1200, 1800, 3600, 7200, 14400, 43200, 86400}
+
+type SwapLatency struct {
+	Id          uint   // ID of the delivered milestone
+	Direction   string //
+	DeliveredAt int64  // unix seconds
+	Latency     int64  // in seconds
+}
+
+type latencyHistogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// SwapLatencyHistograms are built from the milestones saved in DB, so they survive restarts
+type SwapLatencyHistograms struct {
+	mu     sync.RWMutex
+	lastId uint                         // ID of the last observed milestone
+	hists  map[string]*latencyHistogram // direction => histogram
+}
+
+func newSwapLatencyHistograms() *SwapLatencyHistograms {
+	return &SwapLatencyHistograms{hists: map[string]*latencyHistogram{
+		DirectionBch2Sbch: newLatencyHistogram(),
+		DirectionSbch2Bch: newLatencyHistogram(),
+	}}
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(swapLatencyBuckets))}
+}
+
+func (h *SwapLatencyHistograms) observe(latency *SwapLatency) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if latency.Id > h.lastId {
+		h.lastId = latency.Id
+	}
+	hist := h.hists[latency.Direction]
+	if hist == nil {
+		return
+	}
+	val := float64(latency.Latency)
+	if i := sort.SearchFloat64s(swapLatencyBuckets, val); i < len(hist.counts) {
+		hist.counts[i]++
+	}
+	hist.count++
+	hist.sum += val
+}
+
+func (h *SwapLatencyHistograms) getLastId() uint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastId
+}
+
+// write histograms in Prometheus text format
+func (h *SwapLatencyHistograms) writeTo(w io.Writer) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	_, _ = fmt.Fprintf(w, "# TYPE %s histogram\n", MetricSwapLatency)
+	for _, direction := range []string{DirectionBch2Sbch, DirectionSbch2Bch} {
+		hist := h.hists[direction]
+		cumulative := uint64(0)
+		for i, le := range swapLatencyBuckets {
+			cumulative += hist.counts[i]
+			_, _ = fmt.Fprintf(w, "%s_bucket{direction=%q,le=\"%v\"} %d\n",
+				MetricSwapLatency, direction, le, cumulative)
+		}
+		_, _ = fmt.Fprintf(w, "%s_bucket{direction=%q,le=\"+Inf\"} %d\n", MetricSwapLatency, direction, hist.count)
+		_, _ = fmt.Fprintf(w, "%s_sum{direction=%q} %v\n", MetricSwapLatency, direction, hist.sum)
+		_, _ = fmt.Fprintf(w, "%s_count{direction=%q} %d\n", MetricSwapLatency, direction, hist.count)
+	}
+}
+
+// observe swaps delivered since last sampling, all of them after restart
+func (bot *MarketMakerBot) sampleSwapLatencies() {
+	if bot.latencies == nil {
+		return
+	}
+	for {
+		latencies, err := bot.db.getSwapLatencies(bot.latencies.getLastId(), 0, swapLatencyBatchSize)
+		if err != nil {
+			bot.logError("DB error, failed to get swap latencies: ", err)
+			return
+		}
+		for _, latency := range latencies {
+			bot.latencies.observe(latency)
+		}
+		if len(latencies) < swapLatencyBatchSize {
+			return
+		}
+		log.Info("observed swap latencies: ", len(latencies))
+	}
+}
+
+// nearest-rank percentile of sorted values, p in (0, 1]
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapLatencies(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i, latency := range []int64{100, 200, 300, 400} {
+		record := &Bch2SbchRecord{
+			BchLockHeight:  122,
+			BchLockTxHash:  toHex(gethHash32Bytes("bchlock" + string(rune('0'+i)))),
+			Value:          1e8,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(gethAddrBytes("user")),
+			HashLock:       toHex(gethHash32Bytes("b2s" + string(rune('0'+i)))),
+			TimeLock:       100,
+			SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		}
+		require.NoError(t, _db.addBch2SbchRecord(record))
+		require.NoError(t, _db.updateBch2SbchRecord(record.UpdateStatusToSbchLocked("sbchlock", 123)))
+		require.NoError(t, _db.updateBch2SbchRecord(record.UpdateStatusToSecretRevealed("secret", "sbchunlock")))
+		require.NoError(t, _db.db.Model(&SwapMilestone{}).
+			Where("hash_lock = ? AND status = ?", record.HashLock, MilestoneDepositConfirmed).
+			UpdateColumn("at", time.Now().Unix()-latency).Error)
+	}
+	record := &Sbch2BchRecord{
+		SbchLockTime:    1234567890,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock")),
+		Value:           2e8,
+		SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        toHex(gethHash32Bytes("s2b")),
+		TimeLock:        72000,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+	}
+	require.NoError(t, _db.addSbch2BchRecord(record))
+	require.NoError(t, _db.updateSbch2BchRecord(record.UpdateStatusToBchLocked("bchlock")))
+	require.NoError(t, _db.db.Model(&SwapMilestone{}).
+		Where("hash_lock = ? AND status = ?", record.HashLock, MilestoneDepositConfirmed).
+		UpdateColumn("at", time.Now().Unix()-5000).Error)
+
+	// not delivered yet
+	latencies, err := _db.getSwapLatencies(0, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, latencies, 4)
+	require.NoError(t, _db.updateSbch2BchRecord(record.UpdateStatusToSecretRevealed("secret", "bchunlock")))
+	latencies, err = _db.getSwapLatencies(latencies[3].Id, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, latencies, 1)
+	require.Equal(t, DirectionSbch2Bch, latencies[0].Direction)
+	require.InDelta(t, 5000, latencies[0].Latency, 2)
+
+	// stats API
+	_bot := &MarketMakerBot{db: _db, latencies: newSwapLatencyHistograms()}
+	stats, err := _bot.computeSwapStats(30, time.Now())
+	require.NoError(t, err)
+	require.InDelta(t, 200, stats.Bch2Sbch.LatencyP50, 2)
+	require.InDelta(t, 400, stats.Bch2Sbch.LatencyP95, 2)
+	require.InDelta(t, 5000, stats.Sbch2Bch.LatencyP50, 2)
+	require.InDelta(t, 5000, stats.Sbch2Bch.LatencyP95, 2)
+
+	// histograms
+	_bot.sampleSwapLatencies()
+	sb := &strings.Builder{}
+	_bot.latencies.writeTo(sb)
+	out := sb.String()
+	require.True(t, strings.HasPrefix(out, "# TYPE asbot_swap_latency_seconds histogram\n"))
+	require.Contains(t, out, `asbot_swap_latency_seconds_bucket{direction="bch2sbch",le="60"} 0`)
+	require.Contains(t, out, `asbot_swap_latency_seconds_bucket{direction="bch2sbch",le="300"} 3`)
+	require.Contains(t, out, `asbot_swap_latency_seconds_bucket{direction="bch2sbch",le="600"} 4`)
+	require.Contains(t, out, `asbot_swap_latency_seconds_bucket{direction="bch2sbch",le="+Inf"} 4`)
+	require.Contains(t, out, `asbot_swap_latency_seconds_count{direction="bch2sbch"} 4`)
+	require.Contains(t, out, `asbot_swap_latency_seconds_bucket{direction="sbch2bch",le="3600"} 0`)
+	require.Contains(t, out, `asbot_swap_latency_seconds_bucket{direction="sbch2bch",le="7200"} 1`)
+
+	// observed only once
+	_bot.sampleSwapLatencies()
+	sb.Reset()
+	_bot.latencies.writeTo(sb)
+	require.Equal(t, out, sb.String())
+}
+
+func TestPercentile(t *testing.T) {
+	require.Equal(t, int64(0), percentile(nil, 0.5))
+	require.Equal(t, int64(7), percentile([]int64{7}, 0.95))
+	sorted := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	require.Equal(t, int64(5), percentile(sorted, 0.5))
+	require.Equal(t, int64(10), percentile(sorted, 0.95))
+}
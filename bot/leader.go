@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const leaderLeaseName = "signer"
+
+// LeaderElector elects one signing instance among bots sharing the same DB.
+// A standby takes over the lease when the leader misses heartbeats for TTL seconds,
+// and the takeover increases epoch so that the old leader is fenced off.
+type LeaderElector struct {
+	db      DB
+	holder  string // instance ID
+	ttl     int64  // in seconds
+	epoch   uint64 // fencing token held by this instance
+	leading bool
+}
+
+func newLeaderElector(db DB, holder string, ttl uint32) *LeaderElector {
+	return &LeaderElector{
+		db:     db,
+		holder: holder,
+		ttl:    int64(ttl),
+	}
+}
+
+// acquire or renew the lease, return true if this instance is the leader
+func (le *LeaderElector) heartbeat(now int64) (bool, error) {
+	leading, err := le.tryAcquire(now)
+	if err != nil {
+		leading = false
+	}
+	if leading != le.leading {
+		if leading {
+			log.Info("became leader, epoch: ", le.epoch)
+		} else {
+			log.Info("lost leadership, epoch: ", le.epoch)
+		}
+	}
+	le.leading = leading
+	return leading, err
+}
+
+func (le *LeaderElector) tryAcquire(now int64) (bool, error) {
+	lease, err := le.db.getLeaderLease(leaderLeaseName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = le.db.addLeaderLease(&LeaderLease{
+			Name:      leaderLeaseName,
+			Holder:    le.holder,
+			Epoch:     1,
+			RenewedAt: now,
+		})
+		if err != nil {
+			return false, err // maybe created by others
+		}
+		le.epoch = 1
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if lease.Holder == le.holder && lease.Epoch == le.epoch {
+		return le.db.renewLeaderLease(leaderLeaseName, le.holder, le.epoch, now)
+	}
+	if lease.Holder != le.holder && now-lease.RenewedAt <= le.ttl {
+		return false, nil
+	}
+
+	// lease expired, or held by a previous run of this instance
+	ok, err := le.db.takeOverLeaderLease(leaderLeaseName, le.holder, lease.Epoch, now)
+	if ok {
+		le.epoch = lease.Epoch + 1
+	}
+	return ok, err
+}
+
+// check that the lease is still held by this instance with the same epoch,
+// must be called before signing or broadcasting anything
+func (le *LeaderElector) checkFence(now int64) bool {
+	if !le.leading {
+		return false
+	}
+	lease, err := le.db.getLeaderLease(leaderLeaseName)
+	if err != nil {
+		log.Info("DB error, failed to get leader lease: ", err)
+		return false
+	}
+	return lease.Holder == le.holder &&
+		lease.Epoch == le.epoch &&
+		now-lease.RenewedAt <= le.ttl
+}
+
+// return false if this instance is standby
+func (bot *MarketMakerBot) keepLeadership() bool {
+	if bot.leader == nil {
+		return true
+	}
+	leading, err := bot.leader.heartbeat(time.Now().Unix())
+	if err != nil {
+		bot.logError("DB error, failed to renew leader lease: ", err)
+	}
+	if !leading {
+		log.Info("standby, leader lease is held by others")
+	}
+	return leading
+}
+
+// return true if this instance is allowed to sign and broadcast txs
+func (bot *MarketMakerBot) canSign() bool {
+	if bot.isObserverMode {
+		return false
+	}
+	if bot.isFrozen() {
+		log.Info("frozen, nothing is signed or broadcast")
+		return false
+	}
+	if bot.leader == nil {
+		return true
+	}
+	if !bot.leader.checkFence(time.Now().Unix()) {
+		log.Info("fenced off, not leader anymore")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderElector(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	a := newLeaderElector(_db, "a", 30)
+	b := newLeaderElector(_db, "b", 30)
+	now := int64(1000)
+
+	ok, err := a.heartbeat(now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(1), a.epoch)
+	require.True(t, a.checkFence(now))
+
+	ok, err = b.heartbeat(now + 1)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.False(t, b.checkFence(now+1))
+
+	// renew
+	ok, err = a.heartbeat(now + 20)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = b.heartbeat(now + 40)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a misses heartbeats
+	require.False(t, a.checkFence(now+51))
+	ok, err = b.heartbeat(now + 51)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), b.epoch)
+	require.True(t, b.checkFence(now+51))
+
+	// a is fenced off
+	require.False(t, a.checkFence(now+52))
+	ok, err = a.heartbeat(now + 52)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	lease, err := _db.getLeaderLease(leaderLeaseName)
+	require.NoError(t, err)
+	require.Equal(t, "b", lease.Holder)
+	require.Equal(t, uint64(2), lease.Epoch)
+	require.Equal(t, now+51, lease.RenewedAt)
+}
+
+func TestCanSign(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{db: _db, errLogQueue: newErrLogQueue(100)}
+	require.True(t, _bot.keepLeadership())
+	require.True(t, _bot.canSign())
+
+	_bot.isObserverMode = true
+	require.False(t, _bot.canSign())
+	_bot.isObserverMode = false
+
+	// standby
+	require.NoError(t, _db.addLeaderLease(&LeaderLease{
+		Name:      leaderLeaseName,
+		Holder:    "other",
+		Epoch:     5,
+		RenewedAt: time.Now().Unix(),
+	}))
+	_bot.leader = newLeaderElector(_db, "me", 30)
+	require.False(t, _bot.keepLeadership())
+	require.False(t, _bot.canSign())
+}
@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// NegotiationConfig bounds non-default swap terms which users may propose at /negotiate.
+// The risk of accepted terms is charged as a surcharge on the quoted price:
+//   - a penalty below the default makes refunding cheaper for the user, the shortfall is charged 1:1
+//   - an expiration beyond the default keeps bot's inventory locked longer, ExpirationSurchargeBPS
+//     is charged per extra default expiration
+//
+// Expirations are in BCH blocks (bch2sbch) or sBCH seconds/600 (sbch2bch), as QuoteReq.Expiration.
+type NegotiationConfig struct {
+	MinPenaltyBPS          uint16 `json:"min_penalty_bps"`
+	MaxPenaltyBPS          uint16 `json:"max_penalty_bps"`
+	MinExpiration          uint16 `json:"min_expiration"`
+	MaxExpiration          uint16 `json:"max_expiration"`
+	ExpirationSurchargeBPS uint16 `json:"expiration_surcharge_bps"`
+	MaxSurchargeBPS        uint16 `json:"max_surcharge_bps"` // proposals costing more are denied
+}
+
+func checkNegotiationConfig(c *NegotiationConfig) error {
+	if c == nil {
+		return nil
+	}
+	if c.MinPenaltyBPS > c.MaxPenaltyBPS || c.MaxPenaltyBPS >= 10000 {
+		return fmt.Errorf("invalid negotiable penalty: [%d, %d]", c.MinPenaltyBPS, c.MaxPenaltyBPS)
+	}
+	if c.MinExpiration == 0 || c.MinExpiration > c.MaxExpiration {
+		return fmt.Errorf("invalid negotiable expiration: [%d, %d]", c.MinExpiration, c.MaxExpiration)
+	}
+	if c.MaxSurchargeBPS >= 10000 {
+		return fmt.Errorf("invalid max surcharge: %d", c.MaxSurchargeBPS)
+	}
+	return nil
+}
+
+// return the surcharge of proposed terms in BPS, or why they are denied
+func (c *NegotiationConfig) evaluate(expiration, penaltyBPS, defaultExpiration, defaultPenaltyBPS uint16,
+) (surchargeBPS uint16, reason string) {
+
+	if penaltyBPS < c.MinPenaltyBPS || penaltyBPS > c.MaxPenaltyBPS {
+		return 0, fmt.Sprintf("penalty_bps out of range: %d ∉ [%d, %d]",
+			penaltyBPS, c.MinPenaltyBPS, c.MaxPenaltyBPS)
+	}
+	if expiration < c.MinExpiration || expiration > c.MaxExpiration {
+		return 0, fmt.Sprintf("expiration out of range: %d ∉ [%d, %d]",
+			expiration, c.MinExpiration, c.MaxExpiration)
+	}
+
+	surcharge := uint64(0)
+	if penaltyBPS < defaultPenaltyBPS {
+		surcharge += uint64(defaultPenaltyBPS - penaltyBPS)
+	}
+	if expiration > defaultExpiration && defaultExpiration > 0 {
+		surcharge += uint64(c.ExpirationSurchargeBPS) * uint64(expiration-defaultExpiration) / uint64(defaultExpiration)
+	}
+	if surcharge > uint64(c.MaxSurchargeBPS) {
+		return 0, fmt.Sprintf("surcharge is too high: %d > %d", surcharge, c.MaxSurchargeBPS)
+	}
+	return uint16(surcharge), ""
+}
+
+// swap terms agreed by negotiation, they replace the defaults of bot in the quote
+type swapTerms struct {
+	expiration   uint16 // as QuoteReq.Expiration
+	penaltyBPS   uint16
+	surchargeBPS uint16
+}
+
+func (t *swapTerms) adjustPrice(price uint64) uint64 {
+	if t == nil {
+		return price
+	}
+	return price * uint64(10000-t.surchargeBPS) / 10000
+}
+
+type NegotiationReq struct {
+	QuoteReq
+	PenaltyBPS uint16 `json:"penalty_bps"` // proposed penalty, expiration is proposed by QuoteReq.Expiration
+}
+
+type NegotiationResult struct {
+	Accepted     bool       `json:"accepted"`
+	Reason       string     `json:"reason,omitempty"` // why the proposal is denied
+	SurchargeBPS uint16     `json:"surcharge_bps"`    // charged on the price for the risk of the terms
+	Quote        *QuoteInfo `json:"quote,omitempty"`  // adjusted quote with the agreed terms, only if accepted
+}
+
+// evaluate non-default terms proposed by user, and return an adjusted quote if they are accepted
+func (bot *MarketMakerBot) negotiate(req *NegotiationReq) (*NegotiationResult, error) {
+	cfg := bot.negotiation
+	if cfg == nil {
+		return nil, fmt.Errorf("negotiation is disabled")
+	}
+	var defaultExpiration uint16
+	switch req.Direction {
+	case DirectionBch2Sbch:
+		defaultExpiration = bot.bchTimeLock
+	case DirectionSbch2Bch:
+		defaultExpiration = uint16(bot.sbchTimeLock / 600)
+	default:
+		return nil, fmt.Errorf("invalid direction: %s", req.Direction)
+	}
+	expiration := req.Expiration
+	if expiration == 0 {
+		expiration = defaultExpiration
+	}
+
+	surchargeBPS, reason := cfg.evaluate(expiration, req.PenaltyBPS, defaultExpiration, bot.penaltyRatio)
+	if reason != "" {
+		log.Info("negotiation denied: ", reason, ", hashLock: ", req.HashLock)
+		return &NegotiationResult{Reason: reason}, nil
+	}
+	quote, err := bot.makeQuoteWithTerms(&req.QuoteReq, &swapTerms{
+		expiration:   expiration,
+		penaltyBPS:   req.PenaltyBPS,
+		surchargeBPS: surchargeBPS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &NegotiationResult{
+		Accepted:     true,
+		SurchargeBPS: surchargeBPS,
+		Quote:        quote,
+	}, nil
+}
+
+// tell if the time lock and penalty of a detected swap are the defaults of bot, or the terms
+// agreed by a valid negotiated quote, timeLock is in BCH blocks (bch2sbch) or sBCH seconds (sbch2bch)
+func (bot *MarketMakerBot) areTermsAccepted(direction, hashLock string, value uint64,
+	timeLock uint32, penaltyBPS uint16, detectedAt time.Time) bool {
+
+	defaultTimeLock := uint32(bot.bchTimeLock)
+	if direction == DirectionSbch2Bch {
+		defaultTimeLock = bot.sbchTimeLock
+	}
+	if timeLock == defaultTimeLock && penaltyBPS == bot.penaltyRatio {
+		return true
+	}
+
+	quote, err := bot.db.getQuoteByHashLock(hashLock)
+	if err != nil || !quote.Negotiated ||
+		quote.Direction != direction ||
+		quote.Value != value ||
+		quote.ValidUntil < detectedAt.Unix() {
+
+		log.Infof("invalid terms: timeLock %d != %d or penaltyRatio %d != %d",
+			timeLock, defaultTimeLock, penaltyBPS, bot.penaltyRatio)
+		return false
+	}
+	if quote.TimeLock != timeLock || quote.PenaltyBPS != penaltyBPS {
+		log.Infof("terms not agreed: timeLock %d != %d or penaltyRatio %d != %d",
+			timeLock, quote.TimeLock, penaltyBPS, quote.PenaltyBPS)
+		return false
+	}
+	log.Info("use negotiated terms, hashLock: ", hashLock)
+	return true
+}
+
+// tell if the terms of a detected bch2sbch swap with absolute (CLTV) expiration are accepted, the
+// sBCH time lock is still half of the default or negotiated BCH time lock, so the expiry must not be
+// earlier than twice of the sBCH time lock (in seconds) after detectedAt.
+// Return the BCH time lock in blocks which the sBCH time lock is derived from
+func (bot *MarketMakerBot) areExpiresAtTermsAccepted(hashLock string, value uint64,
+	expiresAt uint32, penaltyBPS uint16, detectedAt time.Time) (uint32, bool) {
+
+	timeLock := uint32(bot.bchTimeLock)
+	quote, err := bot.db.getQuoteByHashLock(hashLock)
+	if err == nil && quote.Negotiated && quote.Direction == DirectionBch2Sbch {
+		timeLock = quote.TimeLock
+	}
+	if !bot.areTermsAccepted(DirectionBch2Sbch, hashLock, value, timeLock, penaltyBPS, detectedAt) {
+		return 0, false
+	}
+	sbchTimeLock := bchTimeLockToSeconds(timeLock) / 2
+	if int64(expiresAt) < detectedAt.Unix()+2*int64(sbchTimeLock) {
+		log.Infof("BCH expiry too early: %d < %d + 2 * %d",
+			expiresAt, detectedAt.Unix(), sbchTimeLock)
+		return 0, false
+	}
+	return timeLock, true
+}
+
+func (bot *MarketMakerBot) handleNegotiate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		NewErrResp("POST only").WriteTo(w)
+		return
+	}
+	var req NegotiationReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		NewErrResp("invalid request: " + err.Error()).WriteTo(w)
+		return
+	}
+	result, err := bot.negotiate(&req)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(result).WriteTo(w)
+}
@@ -0,0 +1,226 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestNegotiationConfig_evaluate(t *testing.T) {
+	cfg := &NegotiationConfig{
+		MinPenaltyBPS:          100,
+		MaxPenaltyBPS:          1000,
+		MinExpiration:          72,
+		MaxExpiration:          288,
+		ExpirationSurchargeBPS: 50,
+		MaxSurchargeBPS:        300,
+	}
+	require.NoError(t, checkNegotiationConfig(cfg))
+	require.NoError(t, checkNegotiationConfig(nil))
+	require.Error(t, checkNegotiationConfig(&NegotiationConfig{MaxPenaltyBPS: 10000, MinExpiration: 1, MaxExpiration: 1}))
+	require.Error(t, checkNegotiationConfig(&NegotiationConfig{MinExpiration: 2, MaxExpiration: 1}))
+
+	for _, tc := range []struct {
+		expiration, penaltyBPS uint16
+		surchargeBPS           uint16
+		reason                 string
+	}{
+		{144, 500, 0, ""},   // defaults
+		{72, 1000, 0, ""},   // in favor of bot
+		{144, 300, 200, ""}, // penalty shortfall is charged 1:1
+		{288, 500, 50, ""},  // one extra default expiration
+		{288, 300, 250, ""},
+		{288, 100, 0, "surcharge is too high: 450 > 300"},
+		{144, 50, 0, "penalty_bps out of range: 50 ∉ [100, 1000]"},
+		{300, 500, 0, "expiration out of range: 300 ∉ [72, 288]"},
+	} {
+		surchargeBPS, reason := cfg.evaluate(tc.expiration, tc.penaltyBPS, 144, 500)
+		require.Equal(t, tc.surchargeBPS, surchargeBPS, tc)
+		require.Equal(t, tc.reason, reason, tc)
+	}
+}
+
+func TestNegotiate_bch2sbch(t *testing.T) {
+	_sbchKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	_userPkh := gethAddrBytes("user")
+	_userEvmAddr := gethAddrBytes("evm")
+	_hashLock := gethHash32Bytes("hash")
+
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:            _db,
+		bchPkh:        testBchPkh,
+		sbchPrivKey:   _sbchKey,
+		sbchAddr:      gethcrypto.PubkeyToAddress(_sbchKey.PublicKey),
+		bchTimeLock:   144,
+		sbchTimeLock:  36000,
+		penaltyRatio:  500,
+		bchPrice:      1e8,
+		sbchPrice:     0.98e8,
+		minSwapVal:    1000,
+		quoteValidity: 600,
+	}
+	req := &NegotiationReq{
+		QuoteReq: QuoteReq{
+			Direction:     DirectionBch2Sbch,
+			Value:         1e8,
+			HashLock:      toHex(_hashLock),
+			Expiration:    288,
+			SenderPkh:     toHex(_userPkh),
+			SenderEvmAddr: toHex(_userEvmAddr),
+		},
+		PenaltyBPS: 300,
+	}
+
+	_, err = _bot.negotiate(req)
+	require.ErrorContains(t, err, "negotiation is disabled")
+
+	_bot.negotiation = &NegotiationConfig{
+		MinPenaltyBPS:          100,
+		MaxPenaltyBPS:          1000,
+		MinExpiration:          72,
+		MaxExpiration:          288,
+		ExpirationSurchargeBPS: 50,
+		MaxSurchargeBPS:        300,
+	}
+	req.PenaltyBPS = 50
+	result, err := _bot.negotiate(req)
+	require.NoError(t, err)
+	require.False(t, result.Accepted)
+	require.Contains(t, result.Reason, "penalty_bps out of range")
+	require.Nil(t, result.Quote)
+
+	req.PenaltyBPS = 300
+	result, err = _bot.negotiate(req)
+	require.NoError(t, err)
+	require.True(t, result.Accepted)
+	require.Equal(t, uint16(250), result.SurchargeBPS)
+	quote := result.Quote
+	require.NoError(t, VerifyQuote(quote))
+	require.Equal(t, uint64(0.975e8), quote.Price)
+	require.Equal(t, uint16(288), quote.BchTimeLock)
+	require.Equal(t, bchTimeLockToSeconds(288)/2, quote.SbchTimeLock)
+	require.Equal(t, uint16(300), quote.PenaltyBPS)
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, testBchPkh, _hashLock, 288, 300)
+	require.NoError(t, err)
+	addr, err := covenant.GetP2SHAddress()
+	require.NoError(t, err)
+	require.Equal(t, addr, quote.CovenantAddr)
+	opRet, err := covenant.BuildOpRetPkScript(_userEvmAddr, 0.975e8)
+	require.NoError(t, err)
+	require.Equal(t, toHex(opRet), quote.OpRetPayload)
+
+	makeDeposit := func(hashLock []byte, expiration, penaltyBPS uint16, expectedPrice uint64) *htlcbch.HtlcLockInfo {
+		return &htlcbch.HtlcLockInfo{
+			TxHash:        toHex(hashLock),
+			RecipientPkh:  testBchPkh,
+			SenderPkh:     _userPkh,
+			HashLock:      hashLock,
+			Expiration:    expiration,
+			PenaltyBPS:    penaltyBPS,
+			Value:         1e8,
+			SenderEvmAddr: _userEvmAddr,
+			ExpectedPrice: expectedPrice,
+			ScriptHash:    gethAddrBytes("htlc"),
+		}
+	}
+	getRecord := func(hashLock []byte) *Bch2SbchRecord {
+		record, err := _db.getBch2SbchRecordByHashLock(toHex(hashLock))
+		if err != nil {
+			return nil
+		}
+		return record
+	}
+
+	// the terms are not negotiated for other hash locks
+	_hashLock2 := gethHash32Bytes("hash2")
+	_bot.handleBchDepositTxB2S(124, makeDeposit(_hashLock2, 288, 300, 0.975e8))
+	require.Nil(t, getRecord(_hashLock2))
+	// the terms do not match the agreed ones
+	_bot.handleBchDepositTxB2S(124, makeDeposit(_hashLock, 288, 200, 0.975e8))
+	require.Nil(t, getRecord(_hashLock))
+	// the surcharge can not be dodged
+	_bot.handleBchDepositTxB2S(124, makeDeposit(_hashLock, 288, 300, 1e8))
+	require.Nil(t, getRecord(_hashLock))
+
+	_bot.handleBchDepositTxB2S(124, makeDeposit(_hashLock, 288, 300, 0.975e8))
+	record := getRecord(_hashLock)
+	require.NotNil(t, record)
+	require.Equal(t, uint32(288), record.TimeLock)
+	require.Equal(t, uint16(300), record.PenaltyBPS)
+	require.Equal(t, uint64(0.975e8), record.BchPrice)
+
+	// default terms are still accepted without negotiation
+	_hashLock3 := gethHash32Bytes("hash3")
+	_bot.handleBchDepositTxB2S(124, makeDeposit(_hashLock3, 144, 500, 1e8))
+	require.NotNil(t, getRecord(_hashLock3))
+}
+
+func TestHandleBchDepositTxB2S_expiresAt(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:           _db,
+		bchPkh:       testBchPkh,
+		bchTimeLock:  144,
+		sbchTimeLock: 36000,
+		penaltyRatio: 500,
+		bchPrice:     1e8,
+		sbchPrice:    0.98e8,
+		minSwapVal:   1000,
+		errLogQueue:  newErrLogQueue(100),
+	}
+	now := time.Now().Unix()
+	makeDeposit := func(hashLock []byte, expirationType htlcbch.ExpirationType, expiresAt int64) *htlcbch.HtlcLockInfo {
+		return &htlcbch.HtlcLockInfo{
+			TxHash:         toHex(hashLock),
+			RecipientPkh:   testBchPkh,
+			SenderPkh:      _userPkh,
+			HashLock:       hashLock,
+			ExpirationType: expirationType,
+			ExpiresAt:      uint32(expiresAt),
+			PenaltyBPS:     500,
+			Value:          1e8,
+			SenderEvmAddr:  gethAddrBytes("evm"),
+			ExpectedPrice:  1e8,
+			ScriptHash:     gethAddrBytes("htlc"),
+		}
+	}
+	getRecord := func(hashLock []byte) *Bch2SbchRecord {
+		record, err := _db.getBch2SbchRecordByHashLock(toHex(hashLock))
+		if err != nil {
+			return nil
+		}
+		return record
+	}
+
+	// relative expirations in seconds are not supported
+	_hashLock := gethHash32Bytes("hash")
+	_bot.handleBchDepositTxB2S(124, makeDeposit(_hashLock, htlcbch.ExpirationTypeTime, 0))
+	require.Nil(t, getRecord(_hashLock))
+
+	// the expiry must leave twice of the sBCH time lock
+	sbchTimeLock := int64(bchTimeLockToSeconds(144) / 2)
+	_bot.handleBchDepositTxB2S(124, makeDeposit(_hashLock, htlcbch.ExpirationTypeTimestamp, now+2*sbchTimeLock-60))
+	require.Nil(t, getRecord(_hashLock))
+
+	_bot.handleBchDepositTxB2S(124, makeDeposit(_hashLock, htlcbch.ExpirationTypeTimestamp, now+2*sbchTimeLock+60))
+	record := getRecord(_hashLock)
+	require.NotNil(t, record)
+	require.Equal(t, uint32(144), record.TimeLock)
+	require.Equal(t, uint32(now+2*sbchTimeLock+60), record.BchExpiresAt)
+	require.Equal(t, record.BchExpiresAt, newBch2SbchHtlcSpec(record).ExpiresAt)
+
+	tooLate, err := _bot.isTooLateToLockSbch(record)
+	require.NoError(t, err)
+	require.False(t, tooLate)
+	record.BchExpiresAt = uint32(now + 2*sbchTimeLock*2/3 - 60)
+	tooLate, err = _bot.isTooLateToLockSbch(record)
+	require.NoError(t, err)
+	require.True(t, tooLate)
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+)
+
+type IEvmTxSender interface {
+	getNonce(addr common.Address) (uint64, error)        // latest
+	getPendingNonce(addr common.Address) (uint64, error) // pending
+	sendTx(tx *types.Transaction) error
+	makeCancelTx(nonce uint64) (*types.Transaction, error)
+}
+
+// NonceManager serializes sBCH tx sending of bot account, and
+// persists pending txs so that nonce gaps can be filled after restart.
+type NonceManager struct {
+	mu     sync.Mutex
+	sender IEvmTxSender
+	db     DB
+	chain  string // name of peer EVM chain, empty means smartBCH
+	addr   common.Address
+	next   uint64
+	synced bool
+}
+
+func newNonceManager(sender IEvmTxSender, db DB, chain string, addr common.Address) *NonceManager {
+	return &NonceManager{
+		sender: sender,
+		db:     db,
+		chain:  chain,
+		addr:   addr,
+	}
+}
+
+// sign tx with the next nonce and send it
+func (nm *NonceManager) sendTx(signFn func(nonce uint64) (*types.Transaction, error)) (*types.Transaction, error) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+
+	if !nm.synced {
+		if err := nm.sync(); err != nil {
+			return nil, fmt.Errorf("failed to sync nonce: %w", err)
+		}
+	}
+
+	tx, err := signFn(nm.next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign tx: %w", err)
+	}
+	if err = nm.savePendingTx(tx); err != nil {
+		return nil, fmt.Errorf("failed to save pending tx: %w", err)
+	}
+
+	err = nm.sender.sendTx(tx)
+	if err != nil {
+		_ = nm.deletePendingTx(tx.Nonce())
+		if isNonceErr(err) {
+			log.Info("nonce error, resync later: ", err)
+			nm.synced = false
+		}
+		return nil, err
+	}
+
+	nm.next++
+	return tx, nil
+}
+
+// tx is mined (successful or failed), forget it
+func (nm *NonceManager) txMined(tx *types.Transaction) {
+	if err := nm.deletePendingTx(tx.Nonce()); err != nil {
+		log.Error("DB error, failed to delete pending sBCH tx: ", err)
+	}
+}
+
+// query latest and pending nonce, rebroadcast dropped txs and fill gaps
+func (nm *NonceManager) sync() error {
+	latest, err := nm.sender.getNonce(nm.addr)
+	if err != nil {
+		return fmt.Errorf("failed to get latest nonce: %w", err)
+	}
+	pending, err := nm.sender.getPendingNonce(nm.addr)
+	if err != nil {
+		return fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+	log.Info("sBCH nonce, latest: ", latest, ", pending: ", pending)
+
+	if err = nm.deletePendingTxsBelow(latest); err != nil {
+		return fmt.Errorf("failed to delete mined txs: %w", err)
+	}
+	txs, err := nm.getPendingTxs()
+	if err != nil {
+		return fmt.Errorf("failed to get pending txs: %w", err)
+	}
+
+	next := pending
+	if n := len(txs); n > 0 && txs[n-1].Nonce+1 > next {
+		next = txs[n-1].Nonce + 1
+	}
+
+	// txs with nonce in [latest, pending) are still in mempool,
+	// txs with nonce in [pending, next) are dropped or queued behind a gap
+	txByNonce := make(map[uint64]*PendingSbchTx, len(txs))
+	for _, tx := range txs {
+		txByNonce[tx.Nonce] = tx
+	}
+	for nonce := pending; nonce < next; nonce++ {
+		if pendingTx, ok := txByNonce[nonce]; ok {
+			log.Info("rebroadcast sBCH tx, nonce: ", nonce, ", hash: ", pendingTx.TxHash)
+			tx := &types.Transaction{}
+			if err = tx.UnmarshalBinary(common.FromHex(pendingTx.RawTx)); err != nil {
+				return fmt.Errorf("failed to decode tx: %w", err)
+			}
+			if err = nm.sender.sendTx(tx); err != nil && !isKnownTxErr(err) {
+				return fmt.Errorf("failed to rebroadcast tx: %w", err)
+			}
+			continue
+		}
+
+		log.Info("fill nonce gap with cancel tx, nonce: ", nonce)
+		tx, err := nm.sender.makeCancelTx(nonce)
+		if err != nil {
+			return fmt.Errorf("failed to make cancel tx: %w", err)
+		}
+		if err = nm.savePendingTx(tx); err != nil {
+			return fmt.Errorf("failed to save cancel tx: %w", err)
+		}
+		if err = nm.sender.sendTx(tx); err != nil {
+			return fmt.Errorf("failed to send cancel tx: %w", err)
+		}
+	}
+
+	nm.next = next
+	nm.synced = true
+	return nil
+}
+
+func (nm *NonceManager) savePendingTx(tx *types.Transaction) error {
+	rawTx, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if nm.chain != "" {
+		return nm.db.savePendingEvmTx(&PendingEvmTx{
+			Chain:  nm.chain,
+			Nonce:  tx.Nonce(),
+			TxHash: toHex(tx.Hash().Bytes()),
+			RawTx:  toHex(rawTx),
+		})
+	}
+	return nm.db.savePendingSbchTx(&PendingSbchTx{
+		Nonce:  tx.Nonce(),
+		TxHash: toHex(tx.Hash().Bytes()),
+		RawTx:  toHex(rawTx),
+	})
+}
+
+func (nm *NonceManager) getPendingTxs() ([]*PendingSbchTx, error) {
+	if nm.chain == "" {
+		return nm.db.getPendingSbchTxs()
+	}
+	evmTxs, err := nm.db.getPendingEvmTxs(nm.chain)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*PendingSbchTx, len(evmTxs))
+	for i, tx := range evmTxs {
+		txs[i] = &PendingSbchTx{Nonce: tx.Nonce, TxHash: tx.TxHash, RawTx: tx.RawTx}
+	}
+	return txs, nil
+}
+
+func (nm *NonceManager) deletePendingTx(nonce uint64) error {
+	if nm.chain == "" {
+		return nm.db.deletePendingSbchTx(nonce)
+	}
+	return nm.db.deletePendingEvmTx(nm.chain, nonce)
+}
+
+func (nm *NonceManager) deletePendingTxsBelow(nonce uint64) error {
+	if nm.chain == "" {
+		return nm.db.deletePendingSbchTxsBelow(nonce)
+	}
+	return nm.db.deletePendingEvmTxsBelow(nm.chain, nonce)
+}
+
+// other errors mentioning nonce (e.g. "invalid nonce" of a malformed tx) must not resync the nonce
+func isNonceErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") ||
+		strings.Contains(msg, "nonce too high") ||
+		strings.Contains(msg, "replacement transaction underpriced")
+}
+
+func isKnownTxErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "known transaction") ||
+		strings.Contains(msg, "already known")
+}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type mockEvmTxSender struct {
+	latest  uint64
+	pending uint64
+	sent    []*types.Transaction
+	sendErr error
+}
+
+func (s *mockEvmTxSender) getNonce(addr common.Address) (uint64, error) {
+	return s.latest, nil
+}
+func (s *mockEvmTxSender) getPendingNonce(addr common.Address) (uint64, error) {
+	return s.pending, nil
+}
+func (s *mockEvmTxSender) sendTx(tx *types.Transaction) error {
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	s.sent = append(s.sent, tx)
+	return nil
+}
+func (s *mockEvmTxSender) makeCancelTx(nonce uint64) (*types.Transaction, error) {
+	return newTestEvmTx(nonce, 0), nil
+}
+
+func newTestEvmTx(nonce uint64, val int64) *types.Transaction {
+	return types.NewTx(&types.LegacyTx{
+		Nonce: nonce,
+		Value: big.NewInt(val),
+		Gas:   21000,
+	})
+}
+
+func TestNonceManager_sendTx(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	sender := &mockEvmTxSender{latest: 5, pending: 7}
+	nm := newNonceManager(sender, _db, "", testEvmAddr)
+
+	tx, err := nm.sendTx(func(nonce uint64) (*types.Transaction, error) {
+		return newTestEvmTx(nonce, 1), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), tx.Nonce())
+
+	tx, err = nm.sendTx(func(nonce uint64) (*types.Transaction, error) {
+		return newTestEvmTx(nonce, 2), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), tx.Nonce())
+
+	txs, err := _db.getPendingSbchTxs()
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+
+	nm.txMined(tx)
+	txs, err = _db.getPendingSbchTxs()
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.Equal(t, uint64(7), txs[0].Nonce)
+
+	// nonce error, resync on next send
+	sender.sendErr = fmt.Errorf("nonce too low")
+	_, err = nm.sendTx(func(nonce uint64) (*types.Transaction, error) {
+		return newTestEvmTx(nonce, 3), nil
+	})
+	require.Error(t, err)
+	require.False(t, nm.synced)
+	txs, err = _db.getPendingSbchTxs()
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+}
+
+func TestNonceManager_fillGaps(t *testing.T) {
+	_db := initDB(t, 123, 456)
+
+	// txs sent before restart: nonce 3 is mined, 5 and 7 are dropped, 6 is lost
+	for _, nonce := range []uint64{3, 4, 5, 7} {
+		tx := newTestEvmTx(nonce, 1)
+		rawTx, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, _db.savePendingSbchTx(&PendingSbchTx{
+			Nonce:  nonce,
+			TxHash: toHex(tx.Hash().Bytes()),
+			RawTx:  toHex(rawTx),
+		}))
+	}
+
+	sender := &mockEvmTxSender{latest: 4, pending: 5}
+	nm := newNonceManager(sender, _db, "", testEvmAddr)
+	tx, err := nm.sendTx(func(nonce uint64) (*types.Transaction, error) {
+		return newTestEvmTx(nonce, 1), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), tx.Nonce())
+
+	require.Len(t, sender.sent, 4)
+	require.Equal(t, uint64(5), sender.sent[0].Nonce()) // rebroadcast
+	require.Equal(t, int64(1), sender.sent[0].Value().Int64())
+	require.Equal(t, uint64(6), sender.sent[1].Nonce()) // cancel
+	require.Equal(t, int64(0), sender.sent[1].Value().Int64())
+	require.Equal(t, uint64(7), sender.sent[2].Nonce()) // rebroadcast
+	require.Equal(t, uint64(8), sender.sent[3].Nonce()) // new
+
+	txs, err := _db.getPendingSbchTxs()
+	require.NoError(t, err)
+	var nonces []uint64
+	for _, tx := range txs {
+		nonces = append(nonces, tx.Nonce)
+	}
+	require.Equal(t, []uint64{4, 5, 6, 7, 8}, nonces)
+}
+
+func TestNonceManager_peerChain(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.savePendingSbchTx(&PendingSbchTx{Nonce: 7, TxHash: "aa", RawTx: "bb"}))
+
+	// same nonce on another chain
+	sender := &mockEvmTxSender{latest: 5, pending: 7}
+	nm := newNonceManager(sender, _db, "eth", testEvmAddr)
+	tx, err := nm.sendTx(func(nonce uint64) (*types.Transaction, error) {
+		return newTestEvmTx(nonce, 1), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), tx.Nonce())
+
+	evmTxs, err := _db.getPendingEvmTxs("eth")
+	require.NoError(t, err)
+	require.Len(t, evmTxs, 1)
+	require.Equal(t, uint64(7), evmTxs[0].Nonce)
+	txs, err := _db.getPendingSbchTxs()
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.Equal(t, "aa", txs[0].TxHash)
+
+	nm.txMined(tx)
+	evmTxs, err = _db.getPendingEvmTxs("eth")
+	require.NoError(t, err)
+	require.Len(t, evmTxs, 0)
+}
+
+func TestIsNonceErr(t *testing.T) {
+	require.True(t, isNonceErr(fmt.Errorf("nonce too low")))
+	require.True(t, isNonceErr(fmt.Errorf("Nonce too high: address 0x12, tx: 9 state: 7")))
+	require.True(t, isNonceErr(fmt.Errorf("replacement transaction underpriced")))
+	require.False(t, isNonceErr(fmt.Errorf("invalid nonce")))
+	require.False(t, isNonceErr(fmt.Errorf("nonce has max value")))
+}
@@ -0,0 +1,427 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const maxApiBodySize = 1 << 20
+
+var (
+	openApiDoc     *OpenApiDoc
+	openApiDocOnce sync.Once
+)
+
+// ApiRoute defines an HTTP endpoint, it is used to register the handler,
+// to generate the OpenAPI document, and to validate requests before they reach the handler.
+type ApiRoute struct {
+	Path        string
+	Methods     []string // empty means GET
+	Summary     string
+	Params      []ApiParam // query params
+	Body        any        // zero value of the JSON request body type, nil means no body
+	Required    []string   // required fields of the body
+	Result      any        // zero value of the result type of Resp, nil means any
+	ContentType string     // of non-Resp responses, such as image/png
+	Role        string     // admin endpoints only
+	Signed      bool       // POST must be signed by API key
+	handler     func(bot *MarketMakerBot, w http.ResponseWriter, r *http.Request)
+}
+
+type ApiParam struct {
+	Name        string
+	Type        string // integer|string|boolean
+	Required    bool
+	Min, Max    int64 // integer only, ignored if both are 0
+	Enum        []string
+	Description string
+}
+
+type OpenApiSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Minimum              *int64                    `json:"minimum,omitempty"`
+	Maximum              *int64                    `json:"maximum,omitempty"`
+	Items                *OpenApiSchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenApiSchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenApiSchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+// OpenAPI 3 document of the HTTP API, generated from apiRoutes
+type OpenApiDoc struct {
+	OpenApi    string                                `json:"openapi"`
+	Info       map[string]string                     `json:"info"`
+	Paths      map[string]map[string]*OpenApiOp      `json:"paths"`
+	Components map[string]map[string]json.RawMessage `json:"components"`
+	schemas    map[string]*OpenApiSchema
+}
+
+type OpenApiOp struct {
+	OperationId string                    `json:"operationId"`
+	Summary     string                    `json:"summary,omitempty"`
+	Parameters  []map[string]any          `json:"parameters,omitempty"`
+	RequestBody map[string]any            `json:"requestBody,omitempty"`
+	Responses   map[string]map[string]any `json:"responses"`
+	Security    []map[string][]string     `json:"security,omitempty"`
+}
+
+func getOpenApiDoc() *OpenApiDoc {
+	openApiDocOnce.Do(func() { openApiDoc = newOpenApiDoc(getApiRoutes()) })
+	return openApiDoc
+}
+
+func newOpenApiDoc(routes []ApiRoute) *OpenApiDoc {
+	doc := &OpenApiDoc{
+		OpenApi: "3.0.3",
+		Info:    map[string]string{"title": "Atomic Swap Bot API", "version": "1"},
+		Paths:   map[string]map[string]*OpenApiOp{},
+		schemas: map[string]*OpenApiSchema{},
+	}
+	for _, route := range routes {
+		ops := map[string]*OpenApiOp{}
+		for _, method := range route.getMethods() {
+			ops[strings.ToLower(method)] = doc.newOp(&route, method)
+		}
+		doc.Paths[route.Path] = ops
+	}
+
+	schemas := map[string]json.RawMessage{}
+	for name, schema := range doc.schemas {
+		schemas[name], _ = json.Marshal(schema)
+	}
+	doc.Components = map[string]map[string]json.RawMessage{
+		"schemas": schemas,
+		"securitySchemes": {
+			"bearerAuth": json.RawMessage(`{"type":"http","scheme":"bearer","description":"admin token, API key or JWT"}`),
+		},
+	}
+	return doc
+}
+
+func (doc *OpenApiDoc) newOp(route *ApiRoute, method string) *OpenApiOp {
+	op := &OpenApiOp{
+		OperationId: strings.ToLower(method) + operationName(route.Path),
+		Summary:     route.Summary,
+	}
+	for _, param := range route.Params {
+		op.Parameters = append(op.Parameters, map[string]any{
+			"name":        param.Name,
+			"in":          "query",
+			"required":    param.Required,
+			"description": param.Description,
+			"schema":      param.schema(),
+		})
+	}
+	if route.Body != nil && method == http.MethodPost {
+		schema := doc.schemaOf(reflect.TypeOf(route.Body))
+		op.RequestBody = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": schema}},
+		}
+	}
+	if route.Signed && method == http.MethodPost {
+		for _, name := range []string{"X-Api-Timestamp", "X-Api-Signature"} {
+			op.Parameters = append(op.Parameters, map[string]any{
+				"name":     name,
+				"in":       "header",
+				"required": true,
+				"schema":   &OpenApiSchema{Type: "string"},
+			})
+		}
+	}
+	if route.Role != "" {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	resp := &OpenApiSchema{
+		Type: "object",
+		Properties: map[string]*OpenApiSchema{
+			"success": {Type: "boolean"},
+			"error":   {Type: "string"},
+			"result":  {},
+		},
+		Required: []string{"success"},
+	}
+	if route.Result != nil {
+		resp.Properties["result"] = doc.schemaOf(reflect.TypeOf(route.Result))
+	}
+	content := map[string]any{"application/json": map[string]any{"schema": resp}}
+	if route.ContentType != "" {
+		content[route.ContentType] = map[string]any{"schema": &OpenApiSchema{Type: "string", Format: "binary"}}
+	}
+	op.Responses = map[string]map[string]any{
+		"200": {"description": "OK, errors are replied with success=false", "content": content},
+	}
+	for _, code := range route.errorCodes() {
+		op.Responses[strconv.Itoa(code)] = map[string]any{
+			"description": http.StatusText(code),
+			"content":     map[string]any{"application/json": map[string]any{"schema": resp}},
+		}
+	}
+	return op
+}
+
+// /api/v1/swaps => ApiV1Swaps
+func operationName(path string) string {
+	var sb strings.Builder
+	for _, part := range strings.FieldsFunc(path, func(c rune) bool { return c == '/' || c == '-' || c == '.' }) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+func (route *ApiRoute) getMethods() []string {
+	if len(route.Methods) == 0 {
+		return []string{http.MethodGet}
+	}
+	return route.Methods
+}
+
+func (route *ApiRoute) errorCodes() []int {
+	codes := []int{http.StatusBadRequest, http.StatusMethodNotAllowed}
+	if route.Role != "" {
+		codes = append(codes, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests)
+	}
+	return codes
+}
+
+func (param *ApiParam) schema() *OpenApiSchema {
+	schema := &OpenApiSchema{Type: param.Type, Enum: param.Enum}
+	if param.Min != 0 || param.Max != 0 {
+		min, max := param.Min, param.Max
+		schema.Minimum, schema.Maximum = &min, &max
+	}
+	return schema
+}
+
+// JSON schema of a Go type, structs are put into components and referenced
+func (doc *OpenApiDoc) schemaOf(t reflect.Type) *OpenApiSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenApiSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intSchema(-1<<(t.Bits()-1), 1<<(t.Bits()-1)-1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t.Bits() == 64 {
+			return intSchema(0, math.MaxInt64) // JSON clients can not do better
+		}
+		return intSchema(0, 1<<t.Bits()-1)
+	case reflect.Float32, reflect.Float64:
+		return &OpenApiSchema{Type: "number"}
+	case reflect.String:
+		return &OpenApiSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &OpenApiSchema{Type: "array", Items: doc.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &OpenApiSchema{Type: "object", AdditionalProperties: doc.schemaOf(t.Elem())}
+	case reflect.Struct:
+		ref := &OpenApiSchema{Ref: "#/components/schemas/" + t.Name()}
+		if _, ok := doc.schemas[t.Name()]; ok {
+			return ref
+		}
+		schema := &OpenApiSchema{Type: "object", Properties: map[string]*OpenApiSchema{}}
+		doc.schemas[t.Name()] = schema // placeholder for recursive types
+		doc.addProperties(schema, t)
+		return ref
+	default:
+		return &OpenApiSchema{}
+	}
+}
+
+// fields of embedded structs are inlined, like encoding/json does
+func (doc *OpenApiDoc) addProperties(schema *OpenApiSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			doc.addProperties(schema, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = doc.schemaOf(field.Type)
+	}
+}
+
+func intSchema(min, max int64) *OpenApiSchema {
+	return &OpenApiSchema{Type: "integer", Format: "int64", Minimum: &min, Maximum: &max}
+}
+
+// check query params and JSON body of the request against the route definition
+func (doc *OpenApiDoc) validateRequest(route *ApiRoute, r *http.Request) (int, error) {
+	allowed := false
+	for _, method := range route.getMethods() {
+		allowed = allowed || r.Method == method || (r.Method == http.MethodHead && method == http.MethodGet)
+	}
+	if !allowed {
+		return http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method)
+	}
+
+	query := r.URL.Query()
+	for _, param := range route.Params {
+		if err := param.validate(query[param.Name]); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	if route.Body == nil || r.Method != http.MethodPost {
+		return http.StatusOK, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxApiBodySize+1))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if len(body) > maxApiBodySize {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("request body is too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var val any
+	if err = dec.Decode(&val); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid request: %w", err)
+	}
+	bodySchema := doc.schemaOf(reflect.TypeOf(route.Body))
+	if err = doc.validateJSON(val, bodySchema, "body"); err != nil {
+		return http.StatusBadRequest, err
+	}
+	obj, _ := val.(map[string]any)
+	for _, name := range route.Required {
+		if _, ok := obj[name]; !ok {
+			return http.StatusBadRequest, fmt.Errorf("missing %s", name)
+		}
+	}
+	return http.StatusOK, nil
+}
+
+func (param *ApiParam) validate(vals []string) error {
+	if len(vals) == 0 || vals[0] == "" {
+		if param.Required {
+			return fmt.Errorf("missing %s", param.Name)
+		}
+		return nil
+	}
+	val := vals[0]
+	switch param.Type {
+	case "integer":
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer", param.Name)
+		}
+		if (param.Min != 0 || param.Max != 0) && (n < param.Min || n > param.Max) {
+			return fmt.Errorf("%s must be in [%d, %d]", param.Name, param.Min, param.Max)
+		}
+	case "boolean":
+		if val != "true" && val != "false" {
+			return fmt.Errorf("%s must be true or false", param.Name)
+		}
+	}
+	if len(param.Enum) > 0 && !containsString(param.Enum, val) {
+		return fmt.Errorf("%s must be one of %s", param.Name, strings.Join(param.Enum, "|"))
+	}
+	return nil
+}
+
+func (doc *OpenApiDoc) validateJSON(val any, schema *OpenApiSchema, path string) error {
+	if schema.Ref != "" {
+		schema = doc.schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	if val == nil || schema.Type == "" {
+		return nil // null is accepted as zero value, like encoding/json does
+	}
+	switch schema.Type {
+	case "boolean":
+		if _, ok := val.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	case "string":
+		if _, ok := val.(string); !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+	case "number":
+		if _, ok := val.(json.Number); !ok {
+			return fmt.Errorf("%s must be a number", path)
+		}
+	case "integer":
+		num, ok := val.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+		n, err := num.Int64()
+		if err != nil {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+		if n < *schema.Minimum || n > *schema.Maximum {
+			return fmt.Errorf("%s must be in [%d, %d]", path, *schema.Minimum, *schema.Maximum)
+		}
+	case "array":
+		arr, ok := val.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		for i, item := range arr {
+			if err := doc.validateJSON(item, schema.Items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := val.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for name, item := range obj {
+			propSchema := schema.Properties[name]
+			if propSchema == nil {
+				propSchema = schema.AdditionalProperties
+			}
+			if propSchema == nil {
+				continue // unknown fields are ignored, like encoding/json does
+			}
+			if err := doc.validateJSON(item, propSchema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if len(schema.Enum) > 0 {
+		if s, ok := val.(string); ok && !containsString(schema.Enum, s) {
+			return fmt.Errorf("%s must be one of %s", path, strings.Join(schema.Enum, "|"))
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// return the OpenAPI 3 document of the HTTP API
+func (bot *MarketMakerBot) handleOpenApi(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	bytes, _ := json.Marshal(getOpenApiDoc())
+	_, _ = w.Write(bytes)
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenApiDoc(t *testing.T) {
+	doc := getOpenApiDoc()
+	for _, route := range getApiRoutes() {
+		require.Contains(t, doc.Paths, route.Path)
+	}
+	require.Contains(t, doc.Paths["/quote"], "post")
+	require.NotContains(t, doc.Paths["/quote"], "get")
+	require.Equal(t, "postQuote", doc.Paths["/quote"]["post"].OperationId)
+	require.Equal(t, "getApiV1Swaps", doc.Paths["/api/v1/swaps"]["get"].OperationId)
+	require.NotEmpty(t, doc.Paths["/admin/reload-config"]["get"].Security)
+	require.Empty(t, doc.Paths["/info"]["get"].Security)
+
+	quoteReq := doc.schemas["QuoteReq"]
+	require.Equal(t, "string", quoteReq.Properties["direction"].Type)
+	require.Equal(t, int64(65535), *quoteReq.Properties["expiration"].Maximum)
+	require.Equal(t, "#/components/schemas/SwapInfo", doc.schemas["Info"].Properties["s2b_swaps"].Items.Ref)
+
+	w := httptest.NewRecorder()
+	(&MarketMakerBot{}).createHttpHandlers().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &m))
+	require.Equal(t, "3.0.3", m["openapi"])
+	require.Contains(t, m["components"].(map[string]any)["schemas"], "QuoteInfo")
+}
+
+func TestOpenApiValidation(t *testing.T) {
+	mux := (&MarketMakerBot{}).createHttpHandlers()
+	call := func(method, uri, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, uri, strings.NewReader(body)))
+		return w
+	}
+
+	w := call(http.MethodPost, "/ping", "")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Contains(t, w.Body.String(), "POST is not allowed")
+	require.Equal(t, http.StatusOK, call(http.MethodHead, "/ping", "").Code)
+
+	w = call(http.MethodGet, "/api/v1/swaps?direction=b2s", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "direction must be one of bch2sbch|sbch2bch")
+	w = call(http.MethodGet, "/api/v1/swaps?direction=bch2sbch&limit=x", "")
+	require.Contains(t, w.Body.String(), "limit must be an integer")
+	w = call(http.MethodGet, "/api/v1/swaps?direction=bch2sbch&limit=501", "")
+	require.Contains(t, w.Body.String(), "limit must be in [1, 500]")
+	w = call(http.MethodGet, "/api/v1/swaps?direction=bch2sbch&archived=1", "")
+	require.Contains(t, w.Body.String(), "archived must be true or false")
+	w = call(http.MethodGet, "/swap-txs", "")
+	require.Contains(t, w.Body.String(), "missing hash_lock")
+
+	w = call(http.MethodPost, "/quote", `{"direction":"bch2sbch","value":"100"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "body.value must be an integer")
+	w = call(http.MethodPost, "/quote", `{"direction":"bch2sbch","value":100,"expiration":70000}`)
+	require.Contains(t, w.Body.String(), "body.expiration must be in [0, 65535]")
+	w = call(http.MethodPost, "/quote", `{"direction":"bch2sbch","value":100}`)
+	require.Contains(t, w.Body.String(), "missing hash_lock")
+	w = call(http.MethodPost, "/quote", `[1]`)
+	require.Contains(t, w.Body.String(), "body must be an object")
+
+	// the body is passed on to the handler
+	w = call(http.MethodPost, "/admin/access-list", `{"blacklist_pkhs":[1]}`)
+	require.Contains(t, w.Body.String(), "body.blacklist_pkhs[0] must be a string")
+	w = call(http.MethodPost, "/admin/access-list", `{"blacklist_pkhs":["0x1234"],"foo":1}`)
+	require.Contains(t, w.Body.String(), "admin API is disabled")
+}
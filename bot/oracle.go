@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PriceSourceChainlink = "chainlink"
+	PriceSourceHttp      = "http"
+
+	defaultOracleMaxAge = 600 // 10m
+	priceSourceTimeout  = 5 * time.Second
+)
+
+func checkOracleConfig(cfg *OracleConfig) error {
+	if len(cfg.Sources) == 0 {
+		return fmt.Errorf("no price sources")
+	}
+	if cfg.MinSources > len(cfg.Sources) {
+		return fmt.Errorf("min_sources > sources: %d > %d", cfg.MinSources, len(cfg.Sources))
+	}
+	if cfg.SpreadBPS >= 10000 {
+		return fmt.Errorf("invalid spread_bps: %d", cfg.SpreadBPS)
+	}
+	for _, src := range cfg.Sources {
+		switch src.Type {
+		case PriceSourceChainlink:
+			if !gethcmn.IsHexAddress(src.Addr) {
+				return fmt.Errorf("invalid price feed address: %s", src.Addr)
+			}
+		case PriceSourceHttp:
+			if src.Url == "" || src.Path == "" {
+				return fmt.Errorf("missing url or path of price source")
+			}
+		default:
+			return fmt.Errorf("invalid price source type: %s", src.Type)
+		}
+	}
+	return nil
+}
+
+func getOracleMaxAge(cfg *OracleConfig) int64 {
+	if cfg.MaxAge == 0 {
+		return defaultOracleMaxAge
+	}
+	return int64(cfg.MaxAge)
+}
+
+// quoting is paused if oracle price is not updated in time
+func (t *Token) isPriceStale(now int64) bool {
+	if t == nil || t.oracle == nil {
+		return false
+	}
+	return now-t.priceUpdatedAt > getOracleMaxAge(t.oracle)
+}
+
+// update prices of tokens which have oracles
+func (bot *MarketMakerBot) updateTokenPrices() {
+	now := time.Now().Unix()
+	for _, token := range bot.tokens {
+		if token.oracle == nil {
+			continue
+		}
+		midPrice, err := bot.queryOraclePrice(token.oracle, now)
+		if err != nil {
+			bot.logWarnf("failed to update %s price: %s", token.Symbol, err.Error())
+			continue
+		}
+		token.BchPrice, token.TokenPrice = getSpreadPrices(midPrice, token.oracle.SpreadBPS)
+		token.priceUpdatedAt = now
+		log.Infof("new %s prices, mid: %f, BCH price: %d, token price: %d",
+			token.Symbol, midPrice, token.BchPrice, token.TokenPrice)
+	}
+}
+
+// median of fresh prices, in token per BCH
+func (bot *MarketMakerBot) queryOraclePrice(cfg *OracleConfig, now int64) (float64, error) {
+	maxAge := getOracleMaxAge(cfg)
+	var prices []float64
+	for _, src := range cfg.Sources {
+		price, updatedAt, err := bot.queryPriceSource(src)
+		if err != nil {
+			log.Warnf("failed to query price source %s: %s", src.Type, err.Error())
+			continue
+		}
+		if now-updatedAt > maxAge {
+			log.Infof("stale price from %s, updated at: %d", src.Type, updatedAt)
+			continue
+		}
+		if price <= 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+			log.Infof("invalid price from %s: %f", src.Type, price)
+			continue
+		}
+		if src.Invert {
+			price = 1 / price
+		}
+		prices = append(prices, price)
+	}
+
+	minSources := cfg.MinSources
+	if minSources == 0 {
+		minSources = 1
+	}
+	if len(prices) < minSources {
+		return 0, fmt.Errorf("not enough fresh prices: %d < %d", len(prices), minSources)
+	}
+	return median(prices), nil
+}
+
+func (bot *MarketMakerBot) queryPriceSource(src PriceSourceConfig) (price float64, updatedAt int64, err error) {
+	switch src.Type {
+	case PriceSourceChainlink:
+		rd, err := bot.sbchCli.getLatestRoundData(gethcmn.HexToAddress(src.Addr))
+		if err != nil {
+			return 0, 0, err
+		}
+		price, _ = new(big.Float).Quo(new(big.Float).SetInt(rd.Answer), new(big.Float).SetInt(pow10(src.Decimals))).Float64()
+		return price, rd.UpdatedAt.Int64(), nil
+	case PriceSourceHttp:
+		price, err = queryHttpPrice(src.Url, src.Path)
+		return price, time.Now().Unix(), err
+	default:
+		return 0, 0, fmt.Errorf("invalid price source type: %s", src.Type)
+	}
+}
+
+func queryHttpPrice(url, path string) (float64, error) {
+	client := &http.Client{Timeout: priceSourceTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var v any
+	if err = json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return getJSONNumber(v, path)
+}
+
+// get number (or numeric string) at dot separated path, array elements are selected by index
+func getJSONNumber(v any, path string) (float64, error) {
+	for _, key := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]any:
+			v = x[key]
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(x) {
+				return 0, fmt.Errorf("invalid index: %s", key)
+			}
+			v = x[idx]
+		default:
+			return 0, fmt.Errorf("path not found: %s", path)
+		}
+	}
+
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case string:
+		return strconv.ParseFloat(x, 64)
+	default:
+		return 0, fmt.Errorf("not a number at path: %s", path)
+	}
+}
+
+func median(prices []float64) float64 {
+	sorted := append([]float64(nil), prices...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// midPrice is token per BCH, both returned prices have 8 decimals and include spread
+func getSpreadPrices(midPrice float64, spreadBPS uint16) (bchPrice, tokenPrice uint64) {
+	ratio := float64(10000-spreadBPS) / 10000
+	bchPrice = uint64(midPrice * ratio * 1e8)
+	tokenPrice = uint64(ratio / midPrice * 1e8)
+	return
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+func TestMedian(t *testing.T) {
+	require.Equal(t, 2.0, median([]float64{3, 1, 2}))
+	require.Equal(t, 2.5, median([]float64{4, 1, 3, 2}))
+	require.Equal(t, 7.0, median([]float64{7}))
+}
+
+func TestGetSpreadPrices(t *testing.T) {
+	bchPrice, tokenPrice := getSpreadPrices(250, 100)
+	require.Equal(t, uint64(247.5e8), bchPrice)
+	require.Equal(t, uint64(0.99/250*1e8), tokenPrice)
+
+	bchPrice, tokenPrice = getSpreadPrices(250, 0)
+	require.Equal(t, uint64(250e8), bchPrice)
+	require.Equal(t, uint64(0.004e8), tokenPrice)
+}
+
+func TestGetJSONNumber(t *testing.T) {
+	v := map[string]any{
+		"data": map[string]any{
+			"price":  250.5,
+			"prices": []any{"249.5", 251.0},
+		},
+	}
+	n, err := getJSONNumber(v, "data.price")
+	require.NoError(t, err)
+	require.Equal(t, 250.5, n)
+	n, err = getJSONNumber(v, "data.prices.0")
+	require.NoError(t, err)
+	require.Equal(t, 249.5, n)
+	n, err = getJSONNumber(v, "data.prices.1")
+	require.NoError(t, err)
+	require.Equal(t, 251.0, n)
+
+	_, err = getJSONNumber(v, "data.prices.2")
+	require.ErrorContains(t, err, "invalid index")
+	_, err = getJSONNumber(v, "data.price.x")
+	require.ErrorContains(t, err, "path not found")
+	_, err = getJSONNumber(v, "data.volume")
+	require.ErrorContains(t, err, "not a number")
+}
+
+func TestCheckOracleConfig(t *testing.T) {
+	require.ErrorContains(t, checkOracleConfig(&OracleConfig{}), "no price sources")
+	require.ErrorContains(t, checkOracleConfig(&OracleConfig{
+		Sources:    []PriceSourceConfig{{Type: PriceSourceHttp, Url: "http://x", Path: "p"}},
+		MinSources: 2,
+	}), "min_sources > sources")
+	require.ErrorContains(t, checkOracleConfig(&OracleConfig{
+		Sources: []PriceSourceConfig{{Type: PriceSourceChainlink, Addr: "0x"}},
+	}), "invalid price feed address")
+	require.ErrorContains(t, checkOracleConfig(&OracleConfig{
+		Sources: []PriceSourceConfig{{Type: "ws"}},
+	}), "invalid price source type")
+	require.NoError(t, checkOracleConfig(&OracleConfig{
+		Sources:   []PriceSourceConfig{{Type: PriceSourceHttp, Url: "http://x", Path: "p"}},
+		SpreadBPS: 100,
+	}))
+}
+
+func TestQueryOraclePrice(t *testing.T) {
+	now := time.Now().Unix()
+	feed1 := gethAddr("feed1")
+	feed2 := gethAddr("feed2")
+	_sbchCli := newMockSbchClient(1, 2, 0)
+	_sbchCli.rounds[feed1] = &htlcsbch.RoundData{
+		Answer:    big.NewInt(252e8),
+		UpdatedAt: big.NewInt(now - 10),
+	}
+	_sbchCli.rounds[feed2] = &htlcsbch.RoundData{
+		Answer:    big.NewInt(0.004e18), // BCH per token
+		UpdatedAt: big.NewInt(now - 1000),
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"price":"248"}}`))
+	}))
+	defer srv.Close()
+
+	_bot := &MarketMakerBot{sbchCli: _sbchCli}
+	cfg := &OracleConfig{
+		Sources: []PriceSourceConfig{
+			{Type: PriceSourceChainlink, Addr: feed1.String(), Decimals: 8},
+			{Type: PriceSourceChainlink, Addr: feed2.String(), Decimals: 18, Invert: true},
+			{Type: PriceSourceHttp, Url: srv.URL, Path: "data.price"},
+			{Type: PriceSourceChainlink, Addr: gethAddr("nofeed").String()},
+		},
+		MinSources: 2,
+	}
+
+	// feed2 is stale
+	price, err := _bot.queryOraclePrice(cfg, now)
+	require.NoError(t, err)
+	require.Equal(t, 250.0, price)
+
+	cfg.MinSources = 3
+	_, err = _bot.queryOraclePrice(cfg, now)
+	require.ErrorContains(t, err, "not enough fresh prices: 2 < 3")
+
+	cfg.MaxAge = 2000
+	price, err = _bot.queryOraclePrice(cfg, now)
+	require.NoError(t, err)
+	require.Equal(t, 250.0, price)
+}
+
+func TestUpdateTokenPrices(t *testing.T) {
+	feed := gethAddr("feed")
+	_sbchCli := newMockSbchClient(1, 2, 0)
+	_sbchKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+
+	cfg := testTokenCfg
+	cfg.BchPrice = 0
+	cfg.TokenPrice = 0
+	cfg.Oracle = &OracleConfig{
+		Sources:   []PriceSourceConfig{{Type: PriceSourceChainlink, Addr: feed.String(), Decimals: 8}},
+		MaxAge:    60,
+		SpreadBPS: 100,
+	}
+	tokens, err := newTokens([]TokenConfig{cfg})
+	require.NoError(t, err)
+	usdt := tokens["USDT"]
+
+	_bot := &MarketMakerBot{
+		db:            initDB(t, 123, 456),
+		sbchCli:       _sbchCli,
+		bchPkh:        testBchPkh,
+		sbchPrivKey:   _sbchKey,
+		bchTimeLock:   72,
+		sbchTimeLock:  36000,
+		bchPrice:      1e8,
+		sbchPrice:     1e8,
+		minSwapVal:    1e6,
+		quoteValidity: 600,
+		tokens:        tokens,
+		cfg:           &Config{Tokens: []TokenConfig{cfg}},
+		errLogQueue:   newErrLogQueue(100),
+	}
+	req := &QuoteReq{
+		Direction:     DirectionBch2Sbch,
+		Value:         1e8,
+		HashLock:      toHex(gethHash32Bytes("hash")),
+		SenderPkh:     toHex(gethAddrBytes("user")),
+		SenderEvmAddr: toHex(gethAddrBytes("evm")),
+		Token:         "USDT",
+	}
+
+	// no price yet
+	_bot.updateTokenPrices()
+	require.True(t, usdt.isPriceStale(time.Now().Unix()))
+	_, err = _bot.makeQuote(req)
+	require.ErrorContains(t, err, "price of USDT is stale, quoting is paused")
+
+	_sbchCli.rounds[feed] = &htlcsbch.RoundData{
+		Answer:    big.NewInt(250e8),
+		UpdatedAt: big.NewInt(time.Now().Unix()),
+	}
+	_bot.updateTokenPrices()
+	require.False(t, usdt.isPriceStale(time.Now().Unix()))
+	require.True(t, usdt.isPriceStale(time.Now().Unix()+61))
+	require.Equal(t, uint64(247.5e8), usdt.BchPrice)
+	require.Equal(t, uint64(0.396e6), usdt.TokenPrice)
+
+	quote, err := _bot.makeQuote(req)
+	require.NoError(t, err)
+	require.Equal(t, uint64(247.5e8), quote.Price)
+
+	// tokens without oracle are never stale
+	require.False(t, (&Token{}).isPriceStale(time.Now().Unix()))
+	require.False(t, (*Token)(nil).isPriceStale(time.Now().Unix()))
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/wire"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+const (
+	SwapLegBchRemainder = "bch_remainder"
+
+	// do not fill partially if the remainder is too small to be paid back
+	minRemainderVal = 5000 // in sats
+)
+
+// return the part of BCH deposit (in sats) which can be filled by free sBCH,
+// 0 means the deposit can not be filled for now.
+// sBCH reserved for other swaps is not free, see reserveForSwap().
+func (bot *MarketMakerBot) getFillableValue(record *Bch2SbchRecord) (uint64, error) {
+	balance, err := bot.sbchCli.getBalance()
+	if err != nil {
+		return 0, err
+	}
+	reserved, err := bot.db.getReservedInventoryOfOthers(AssetSbch, record.HashLock, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	// keep some sBCH to pay gas fee
+	freeSbch := weiToSats(balance)
+	gasFee := bot.getSbchGasFee(sbchLockGas)
+	if freeSbch <= gasFee+reserved {
+		return 0, nil
+	}
+	freeSbch -= gasFee + reserved
+
+	if mulByPrice(record.Value, record.BchPrice) <= freeSbch {
+		return record.Value, nil
+	}
+	filledVal := divByPrice(freeSbch, record.BchPrice)
+	if filledVal < bot.minSwapVal || record.Value-filledVal < minRemainderVal {
+		return 0, nil
+	}
+	return filledVal, nil
+}
+
+// bch2sbch: BchUnlocked => RemainderRefunded, for partially filled records
+func (bot *MarketMakerBot) refundRemainders() {
+	if bot.isSlaveMode || !bot.canSign() {
+		return
+	}
+
+	records, err := bot.db.getPartiallyFilledBch2SbchRecordsByStatus(Bch2SbchStatusBchUnlocked, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get BCH2SBCH records: ", err)
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+	log.Info("partially filled BCH user deposits to pay back: ", len(records))
+
+	for _, record := range records {
+		remainder := int64(record.Value - record.FilledValue)
+		log.Infof("pay back remainder, hashLock: %s, remainder: %d, senderPkh: %s",
+			record.HashLock, remainder, record.SenderPkh)
+
+		job := bot.claimChainJob(record.HashLock, SwapLegBchRemainder)
+		if job == nil {
+			continue
+		}
+		tx, built, err := bot.sendBchJobTx(job, func() (*wire.MsgTx, error) {
+			utxos, err := bot.bchCli.GetUTXOs(remainder+5000, 10)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get UTXOs: %w", err)
+			}
+			inputs := make([]htlcbch.InputInfo, len(utxos))
+			for i, utxo := range utxos {
+				inputs[i] = htlcbch.InputInfo{
+					TxID:   gethcmn.FromHex(utxo.TxID),
+					Vout:   utxo.Vout,
+					Amount: utxoAmtToSats(utxo.Amount),
+				}
+			}
+
+			// miner fee is paid by user
+			tx, err := bot.getBchNet().MakePayTxWithSigType(bot.bchPrivKey, inputs,
+				gethcmn.FromHex(record.SenderPkh), remainder, bot.bchRefundMinerFeeRate, bot.bchSigType,
+				bot.getBchTxLockTime())
+			if err != nil {
+				return nil, fmt.Errorf("failed to create BCH tx: %w", err)
+			}
+			log.Info("BCH tx hex: ", htlcbch.MsgTxToHex(tx))
+			return tx, nil
+		})
+		if err != nil {
+			bot.logError("failed to send BCH tx: ", err)
+			bot.releaseChainJob(job, err)
+			continue
+		}
+		txHash := tx.TxHash()
+		log.Info("remainder paid back, tx hash: ", txHash.String())
+		if built {
+			bot.saveBchSwapMsgTx(record.HashLock, SwapLegBchRemainder, tx)
+		}
+
+		record.UpdateStatusToRemainderRefunded(txHash.String())
+		err = bot.db.updateBch2SbchRecord(record)
+		if err != nil {
+			bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
+			continue
+		}
+		bot.finishChainJob(job)
+	}
+}
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestPartialFill(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_hashLock1 := gethHash32Bytes("hash1")
+	_hashLock2 := gethHash32Bytes("hash2")
+
+	_db := initDB(t, 123, 456)
+	for i, hashLock := range [][]byte{_hashLock1, _hashLock2} {
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockHeight:  123,
+			BchLockTxHash:  toHex(gethHash32Bytes("bchlock" + string(rune('0'+i)))),
+			Value:          1e8 * uint64(i+1),
+			BchPrice:       1e8,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(_userPkh),
+			HashLock:       toHex(hashLock),
+			TimeLock:       100,
+			SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+			Status:         Bch2SbchStatusNew,
+		}))
+	}
+
+	_bchCli := newMockBchClient(124, 125)
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_sbchCli.balance = satsToWei(16e7)
+	_bot := &MarketMakerBot{
+		db:                    _db,
+		dbQueryLimit:          100,
+		bchCli:                _bchCli,
+		sbchCli:               _sbchCli,
+		bchPrivKey:            testBchPrivKey,
+		bchPkh:                testBchPkh,
+		bchTimeLock:           72,
+		bchPrice:              1e8,
+		sbchPrice:             1e8,
+		minSwapVal:            1e6,
+		partialFill:           true,
+		bchRefundMinerFeeRate: 2,
+		errLogQueue:           newErrLogQueue(100),
+	}
+
+	// 1 BCH is fully filled, 2 BCH is partially filled (mock balance is not reduced by lock)
+	_bot.handleBchUserDeposits()
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusSbchLocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, uint64(0), records[0].FilledValue)
+	require.Equal(t, uint64(1e8), records[0].GetFilledValue())
+	records, err = _db.getPartiallyFilledBch2SbchRecordsByStatus(Bch2SbchStatusSbchLocked, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, toHex(_hashLock2), records[0].HashLock)
+	require.Equal(t, uint64(16e7), records[0].FilledValue)
+	require.True(t, records[0].IsPartiallyFilled())
+
+	// remainder is paid back after BCH is unlocked
+	_bot.refundRemainders()
+	require.Len(t, _bchCli.sentTxs, 0)
+	for _, hashLock := range [][]byte{_hashLock1, _hashLock2} {
+		record, err := _db.getBch2SbchRecordByHashLock(toHex(hashLock))
+		require.NoError(t, err)
+		record.UpdateStatusToBchUnlocked("bchunlock")
+		require.NoError(t, _db.updateBch2SbchRecord(record))
+	}
+	_bot.refundRemainders()
+	require.Len(t, _bchCli.sentTxs, 1)
+	payTx := _bchCli.sentTxs[0]
+	toPkScript, err := htlcbch.MainNet.NewP2PKHAddress(_userPkh)
+	require.NoError(t, err)
+	toOut := findTxOut(payTx, toPkScript.ScriptAddress())
+	require.NotNil(t, toOut)
+	require.Less(t, toOut.Value, int64(4e7))
+	require.Greater(t, toOut.Value, int64(4e7-1000))
+
+	record, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock2))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusRemainderRefunded, record.Status)
+	require.Equal(t, payTx.TxHash().String(), record.RemainderTxHash)
+	record, err = _db.getBch2SbchRecordByHashLock(toHex(_hashLock1))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusBchUnlocked, record.Status)
+
+	// paid back only once
+	_bot.refundRemainders()
+	require.Len(t, _bchCli.sentTxs, 1)
+}
+
+func TestGetFillableValue(t *testing.T) {
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_bot := &MarketMakerBot{
+		db:         initDB(t, 123, 456),
+		sbchCli:    _sbchCli,
+		minSwapVal: 1e6,
+	}
+	record := &Bch2SbchRecord{Value: 1e8, BchPrice: 8e7, HashLock: toHex(gethHash32Bytes("hash"))}
+
+	_sbchCli.balance = satsToWei(8e7)
+	val, err := _bot.getFillableValue(record)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1e8), val)
+
+	_sbchCli.balance = satsToWei(4e7)
+	val, err = _bot.getFillableValue(record)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5e7), val)
+
+	// filled value is too small
+	_sbchCli.balance = satsToWei(7e5)
+	val, err = _bot.getFillableValue(record)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), val)
+
+	// remainder is too small
+	_sbchCli.balance = satsToWei(8e7 - 1000)
+	val, err = _bot.getFillableValue(record)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), val)
+
+	// sBCH reserved for other swaps is not free, the swap's own reservation is
+	now := time.Now().Unix()
+	require.NoError(t, _bot.db.reserveInventory(&InventoryReservation{
+		HashLock: toHex(gethHash32Bytes("other")), Asset: AssetSbch, Amount: 4e7, ExpiresAt: now + 600,
+	}, 1e9, now))
+	require.NoError(t, _bot.db.reserveInventory(&InventoryReservation{
+		HashLock: record.HashLock, Asset: AssetSbch, Amount: 8e7, ExpiresAt: now + 600,
+	}, 1e9, now))
+	_sbchCli.balance = satsToWei(8e7)
+	val, err = _bot.getFillableValue(record)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5e7), val)
+
+	_sbchCli.balance = satsToWei(4e7)
+	val, err = _bot.getFillableValue(record)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), val)
+}
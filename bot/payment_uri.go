@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gcash/bchd/txscript"
+
+	"github.com/smartbch/atomic-swap-bot/qrcode"
+)
+
+const (
+	defaultQrScale = 8
+	maxQrScale     = 20
+)
+
+// BIP-21 style URI to pay the covenant from mobile wallets.
+// The deposit needs an OP_RETURN output, it is carried by op_return_raw
+// (an Electron Cash extension, script data after OP_RETURN opcode).
+func makePaymentUri(covenantAddr string, value uint64, hashLock string, opRet []byte) string {
+	amount := strings.TrimRight(fmt.Sprintf("%d.%08d", value/1e8, value%1e8), "0")
+	amount = strings.TrimSuffix(amount, ".")
+	uri := fmt.Sprintf("%s?amount=%s&label=sbas-%s", covenantAddr, amount, hashLock[:16])
+	if len(opRet) > 1 {
+		uri += "&op_return_raw=" + toHex(opRet[1:])
+	}
+	return uri
+}
+
+// the covenant address and the OP_RETURN script of a URI made by makePaymentUri
+func parsePaymentUri(uri string) (covenantAddr string, opRet []byte, err error) {
+	covenantAddr, query, _ := strings.Cut(uri, "?")
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", nil, err
+	}
+	if raw := values.Get("op_return_raw"); raw != "" {
+		data, err := hex.DecodeString(raw)
+		if err != nil {
+			return "", nil, err
+		}
+		opRet = append([]byte{txscript.OP_RETURN}, data...)
+	}
+	return covenantAddr, opRet, nil
+}
+
+// return QR code (PNG) of the payment URI of a bch2sbch quote
+func (bot *MarketMakerBot) handleQuoteQr(w http.ResponseWriter, r *http.Request) {
+	hashLock := strings.TrimPrefix(r.URL.Query().Get("hash_lock"), "0x")
+	scale := getIntQueryParam(r, "scale", defaultQrScale)
+	if scale <= 0 || scale > maxQrScale {
+		NewErrResp(fmt.Sprintf("scale must be in [1, %d]", maxQrScale)).WriteTo(w)
+		return
+	}
+	quote, err := bot.db.getQuoteByHashLock(hashLock)
+	if err != nil {
+		NewErrResp("quote not found").WriteTo(w)
+		return
+	}
+	if quote.PaymentUri == "" {
+		NewErrResp("no payment URI, bch2sbch only").WriteTo(w)
+		return
+	}
+	qr, err := qrcode.Encode([]byte(quote.PaymentUri))
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	bz, err := qr.PNG(scale)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(bz)
+}
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakePaymentUri(t *testing.T) {
+	hashLock := toHex(gethHash32Bytes("hash"))
+	require.Equal(t, "bitcoincash:pabc?amount=1.2345&label=sbas-"+hashLock[:16]+"&op_return_raw=0401020304",
+		makePaymentUri("bitcoincash:pabc", 123450000, hashLock, []byte{0x6a, 0x04, 1, 2, 3, 4}))
+	require.Equal(t, "bitcoincash:pabc?amount=0.00001&label=sbas-"+hashLock[:16],
+		makePaymentUri("bitcoincash:pabc", 1000, hashLock, nil))
+}
+
+func TestParsePaymentUri(t *testing.T) {
+	hashLock := toHex(gethHash32Bytes("hash"))
+	addr, opRet, err := parsePaymentUri(makePaymentUri("bitcoincash:pabc", 123450000, hashLock, []byte{0x6a, 0x04, 1, 2, 3, 4}))
+	require.NoError(t, err)
+	require.Equal(t, "bitcoincash:pabc", addr)
+	require.Equal(t, []byte{0x6a, 0x04, 1, 2, 3, 4}, opRet)
+
+	addr, opRet, err = parsePaymentUri(makePaymentUri("bitcoincash:pabc", 1000, hashLock, nil))
+	require.NoError(t, err)
+	require.Equal(t, "bitcoincash:pabc", addr)
+	require.Nil(t, opRet)
+
+	_, _, err = parsePaymentUri("bitcoincash:pabc?op_return_raw=xyz")
+	require.Error(t, err)
+}
+
+func TestHandleQuoteQr(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	hashLock := toHex(gethHash32Bytes("hash"))
+	require.NoError(t, _db.addQuote(&Quote{
+		HashLock:   hashLock,
+		Direction:  DirectionBch2Sbch,
+		Value:      1e8,
+		Price:      1e8,
+		ValidUntil: 1,
+		PaymentUri: makePaymentUri("bitcoincash:pabc", 1e8, hashLock, nil),
+	}, time.Now().Unix()))
+	_bot := &MarketMakerBot{db: _db}
+	handler := _bot.createHttpHandlers()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quote/qr?hash_lock=0x"+hashLock+"&scale=2", nil))
+	require.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, (33+8)*2, img.Bounds().Dx()) // version 4
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quote/qr?hash_lock=1234", nil))
+	require.Contains(t, w.Body.String(), "quote not found")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quote/qr?hash_lock="+hashLock+"&scale=100", nil))
+	require.Contains(t, w.Body.String(), "scale must be in [1, 20]")
+}
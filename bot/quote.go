@@ -0,0 +1,337 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DirectionBch2Sbch = "bch2sbch"
+	DirectionSbch2Bch = "sbch2bch"
+)
+
+var ErrQuoteExists = errors.New("an unexpired quote exists for this hash lock")
+
+type QuoteReq struct {
+	Direction     string `json:"direction"`       // bch2sbch|sbch2bch
+	Value         uint64 `json:"value"`           // in sats
+	HashLock      string `json:"hash_lock"`       // hex
+	Expiration    uint16 `json:"expiration"`      // optional, BCH blocks (bch2sbch) or sBCH seconds/600 (sbch2bch)
+	SenderPkh     string `json:"sender_pkh"`      // bch2sbch only, user's BCH PKH
+	SenderEvmAddr string `json:"sender_evm_addr"` // bch2sbch only, user's sBCH address
+	RecipientPkh  string `json:"recipient_pkh"`   // sbch2bch only, user's BCH PKH
+	Token         string `json:"token"`           // optional, SEP20 token symbol, empty means sBCH
+	Email         string `json:"email"`           // optional, notified of the swap progress, see EmailConfig
+}
+
+type QuoteInfo struct {
+	Direction    string `json:"direction"`
+	Token        string `json:"token,omitempty"`
+	Value        uint64 `json:"value"`         // in sats, or token amount with 8 decimals (sbch2bch)
+	CounterValue uint64 `json:"counter_value"` // in sats, value * price / 1e8
+	Fee          uint64 `json:"fee"`           // in sats, value - counterValue (tokens are valued at bot's prices)
+	Price        uint64 `json:"price"`         // 8 decimals
+	HashLock     string `json:"hash_lock"`
+	CovenantAddr string `json:"covenant_addr"`       // BCH HTLC P2SH address
+	OpRetPayload string `json:"op_return,omitempty"` // hex, bch2sbch only
+	BchTimeLock  uint16 `json:"bch_time_lock"`       // in blocks
+	SbchTimeLock uint32 `json:"sbch_time_lock"`      // in seconds
+	PenaltyBPS   uint16 `json:"penalty_bps"`
+	ReferralPkh  string `json:"referral_pkh,omitempty"` // hex, the covenant is split, see Referral
+	ReferralBPS  uint16 `json:"referral_bps,omitempty"` // share of the BCH value paid to referral on unlock
+	ValidUntil   int64  `json:"valid_until"`            // unix timestamp
+	Signer       string `json:"signer"`                 // bot's sBCH address
+	Signature    string `json:"signature,omitempty"`
+	PaymentUri   string `json:"payment_uri,omitempty"` // bch2sbch only, BIP-21 style URI to pay the covenant
+	Batchable    bool   `json:"batchable,omitempty"`   // bch2sbch only, the covenant is the batchable variant
+}
+
+func (bot *MarketMakerBot) makeQuote(req *QuoteReq) (*QuoteInfo, error) {
+	return bot.makeQuoteWithTerms(req, nil)
+}
+
+// terms are agreed by negotiation, nil means the defaults of bot
+func (bot *MarketMakerBot) makeQuoteWithTerms(req *QuoteReq, terms *swapTerms) (*QuoteInfo, error) {
+	if bot.isPaused() {
+		return nil, fmt.Errorf("bot is paused")
+	}
+	hashLock := gethcmn.FromHex(req.HashLock)
+	if len(hashLock) != 32 {
+		return nil, fmt.Errorf("hash_lock is not 32 bytes")
+	}
+	token, err := bot.getToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if token.isPriceStale(time.Now().Unix()) {
+		return nil, fmt.Errorf("price of %s is stale, quoting is paused", token.Symbol)
+	}
+	// checked again when saved, before touching reservations here
+	if old, err := bot.db.getQuoteByHashLock(toHex(hashLock)); err == nil && old.ValidUntil >= time.Now().Unix() {
+		return nil, ErrQuoteExists
+	}
+	// swap value is always checked in sats
+	swapVal := req.Value
+	if token != nil && req.Direction == DirectionSbch2Bch {
+		swapVal = mulByPrice(req.Value, token.TokenPrice)
+	}
+	if swapVal < bot.minSwapVal ||
+		(bot.maxSwapVal > 0 && swapVal > bot.maxSwapVal) {
+		return nil, fmt.Errorf("value out of range: %d ∉ [%d, %d]",
+			swapVal, bot.minSwapVal, bot.maxSwapVal)
+	}
+
+	quote := &QuoteInfo{
+		Direction:    req.Direction,
+		Token:        token.getSymbol(),
+		Value:        req.Value,
+		HashLock:     toHex(hashLock),
+		SbchTimeLock: bot.sbchTimeLock,
+		ValidUntil:   time.Now().Unix() + int64(bot.quoteValidity),
+		Signer:       bot.sbchAddr.String(),
+	}
+
+	switch req.Direction {
+	case DirectionBch2Sbch:
+		senderPkh := gethcmn.FromHex(req.SenderPkh)
+		senderEvmAddr := gethcmn.FromHex(req.SenderEvmAddr)
+		if len(senderPkh) != 20 {
+			return nil, fmt.Errorf("sender_pkh is not 20 bytes")
+		}
+		if len(senderEvmAddr) != 20 {
+			return nil, fmt.Errorf("sender_evm_addr is not 20 bytes")
+		}
+		bchTimeLock, penaltyBPS := bot.bchTimeLock, bot.penaltyRatio
+		if terms != nil {
+			bchTimeLock, penaltyBPS = terms.expiration, terms.penaltyBPS
+			quote.SbchTimeLock = bchTimeLockToSeconds(uint32(bchTimeLock)) / 2
+		} else if req.Expiration != 0 && req.Expiration != bot.bchTimeLock {
+			return nil, fmt.Errorf("invalid expiration: %d != %d", req.Expiration, bot.bchTimeLock)
+		}
+
+		recipientPkh := bot.bchPkh
+		if bot.hdPkhs != nil {
+			_, pkh, err := bot.nextHdReceivePkh()
+			if err != nil {
+				return nil, err
+			}
+			recipientPkh = gethcmn.FromHex(pkh)
+		}
+
+		covenant, err := bot.getBchNet().NewCovenant(senderPkh, recipientPkh, hashLock,
+			bchTimeLock, penaltyBPS)
+		if err == nil {
+			covenant, err = bot.referral.apply(covenant)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTLC covenant: %w", err)
+		}
+		if bot.batchReceipts {
+			// only bot claims deposits of quotes, so only it needs to know the variant
+			covenant = covenant.WithBatchable()
+			quote.Batchable = true
+		}
+		bchPrice := bot.bchPrice
+		if token != nil {
+			bchPrice = token.BchPrice
+		}
+		bchPrice = terms.adjustPrice(bchPrice)
+		opRet, err := covenant.BuildOpRetPkScript(senderEvmAddr, bchPrice)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build OP_RETURN: %w", err)
+		}
+		quote.CovenantAddr, err = covenant.GetP2SHAddress()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get P2SH address: %w", err)
+		}
+		quote.OpRetPayload = toHex(opRet)
+		quote.PaymentUri = makePaymentUri(quote.CovenantAddr, quote.Value, quote.HashLock, opRet)
+		quote.Price = bchPrice
+		quote.BchTimeLock = bchTimeLock
+		quote.PenaltyBPS = penaltyBPS
+
+	case DirectionSbch2Bch:
+		recipientPkh := gethcmn.FromHex(req.RecipientPkh)
+		if len(recipientPkh) != 20 {
+			return nil, fmt.Errorf("recipient_pkh is not 20 bytes")
+		}
+		penaltyBPS := bot.penaltyRatio
+		if terms != nil {
+			quote.SbchTimeLock, penaltyBPS = uint32(terms.expiration)*600, terms.penaltyBPS
+		} else if req.Expiration != 0 && uint32(req.Expiration)*600 != bot.sbchTimeLock {
+			return nil, fmt.Errorf("invalid expiration: %d != %d", uint32(req.Expiration)*600, bot.sbchTimeLock)
+		}
+
+		// the covenant which will be created by bot
+		bchTimeLock := sbchTimeLockToBlocks(quote.SbchTimeLock) / 2
+		covenant, err := bot.getBchNet().NewCovenant(bot.bchPkh, recipientPkh, hashLock,
+			bchTimeLock, 0)
+		if err == nil {
+			covenant, err = bot.referral.apply(covenant)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTLC covenant: %w", err)
+		}
+		quote.CovenantAddr, err = covenant.GetP2SHAddress()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get P2SH address: %w", err)
+		}
+		quote.Price = bot.sbchPrice
+		if token != nil {
+			quote.Price = token.TokenPrice
+		}
+		quote.Price = terms.adjustPrice(quote.Price)
+		quote.BchTimeLock = bchTimeLock
+		quote.PenaltyBPS = penaltyBPS
+
+	default:
+		return nil, fmt.Errorf("invalid direction: %s", req.Direction)
+	}
+
+	quote.ReferralPkh = bot.referral.getPkh()
+	quote.ReferralBPS = bot.referral.getBPS()
+	quote.CounterValue = mulByPrice(quote.Value, quote.Price)
+	quote.Fee = bot.getSwapServiceFee(quote.Token, quote.Direction, quote.Value, quote.Price)
+
+	if req.Email != "" {
+		if bot.emails == nil {
+			return nil, fmt.Errorf("email notifications are not enabled")
+		}
+		if err = checkEmailAddr(req.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := bot.signQuote(quote); err != nil {
+		return nil, fmt.Errorf("failed to sign quote: %w", err)
+	}
+
+	record := &Quote{
+		HashLock:   quote.HashLock,
+		Direction:  quote.Direction,
+		Value:      quote.Value,
+		Price:      quote.Price,
+		ValidUntil: quote.ValidUntil,
+		Token:      quote.Token,
+		PaymentUri: quote.PaymentUri,
+		QuoteHash:  toHex(getQuoteHash(quote)),
+	}
+	if terms != nil {
+		record.Negotiated = true
+		record.PenaltyBPS = quote.PenaltyBPS
+		record.TimeLock = uint32(quote.BchTimeLock) // the time lock of the user's deposit
+		if quote.Direction == DirectionSbch2Bch {
+			record.TimeLock = quote.SbchTimeLock
+		}
+	}
+	err = bot.db.addQuote(record, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save quote: %w", err)
+	}
+
+	// reserved after the quote is saved, so that the reservation of an unexpired quote
+	// of the same hash lock is not overwritten, the new quote is dropped if it fails
+	err = bot.reserveForSwap(quote.HashLock, quote.Direction, quote.Token, quote.CounterValue, quote.ValidUntil)
+	if err != nil {
+		bot.dropQuote(record)
+		return nil, fmt.Errorf("failed to reserve inventory: %w", err)
+	}
+	if req.Email != "" {
+		if err = bot.subscribeEmail(quote, req.Email); err != nil {
+			bot.dropQuote(record)
+			return nil, fmt.Errorf("failed to save email: %w", err)
+		}
+	}
+
+	log.Info("new quote: ", toJSON(quote))
+	return quote, nil
+}
+
+// delete the quote which is not returned to user, and release its reservation
+func (bot *MarketMakerBot) dropQuote(quote *Quote) {
+	if err := bot.db.deleteQuote(quote); err != nil {
+		bot.logError("DB error, failed to delete quote: ", err)
+	}
+}
+
+// personal_sign(keccak256(json(quote without signature)))
+func (bot *MarketMakerBot) signQuote(quote *QuoteInfo) error {
+	if bot.sbchPrivKey == nil {
+		return fmt.Errorf("no sBCH key")
+	}
+	quote.Signature = ""
+	sig, err := gethcrypto.Sign(getQuoteHash(quote), bot.sbchPrivKey)
+	if err != nil {
+		return err
+	}
+	quote.Signature = toHex(sig)
+	return nil
+}
+
+// VerifyQuote checks the signature of quote against its signer, so users can prove
+// what the bot promised
+func VerifyQuote(quote *QuoteInfo) error {
+	sig := gethcmn.FromHex(quote.Signature)
+	if len(sig) != 65 {
+		return fmt.Errorf("invalid signature length: %d", len(sig))
+	}
+	pubKey, err := gethcrypto.SigToPub(getQuoteHash(quote), sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if gethcrypto.PubkeyToAddress(*pubKey) != gethcmn.HexToAddress(quote.Signer) {
+		return fmt.Errorf("quote is not signed by %s", quote.Signer)
+	}
+	return nil
+}
+
+func getQuoteHash(quote *QuoteInfo) []byte {
+	quote2 := *quote
+	quote2.Signature = ""
+	bz, _ := json.Marshal(quote2)
+	return accounts.TextHash(bz)
+}
+
+// the bot (or token) price, or the quoted price if the deposit is covered by a valid quote
+func (bot *MarketMakerBot) getBchPriceFor(token *Token, hashLock string, value uint64, detectedAt time.Time) uint64 {
+	currPrice := bot.bchPrice
+	if token != nil {
+		currPrice = token.BchPrice
+	}
+	return bot.getQuotedPrice(DirectionBch2Sbch, token.getSymbol(), hashLock, value, detectedAt, currPrice)
+}
+func (bot *MarketMakerBot) getSbchPriceFor(token *Token, hashLock string, value uint64, detectedAt time.Time) uint64 {
+	currPrice := bot.sbchPrice
+	if token != nil {
+		currPrice = token.TokenPrice
+	}
+	return bot.getQuotedPrice(DirectionSbch2Bch, token.getSymbol(), hashLock, value, detectedAt, currPrice)
+}
+
+func (bot *MarketMakerBot) getQuotedPrice(direction, symbol, hashLock string, value uint64,
+	detectedAt time.Time, currPrice uint64) uint64 {
+
+	quote, err := bot.db.getQuoteByHashLock(hashLock)
+	if err != nil {
+		return currPrice
+	}
+	if quote.Direction != direction ||
+		quote.Token != symbol ||
+		quote.Value != value ||
+		quote.ValidUntil < detectedAt.Unix() {
+		return currPrice
+	}
+	// the adjusted price of a negotiated quote is binding, otherwise the surcharge could be dodged
+	if quote.Price > currPrice || quote.Negotiated {
+		log.Info("use quoted price: ", quote.Price, ", hashLock: ", hashLock)
+		return quote.Price
+	}
+	return currPrice
+}
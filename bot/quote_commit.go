@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"net/http"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	quoteCommitInterval = 60  // in seconds, quotes made meanwhile are committed by one tx
+	maxQuoteCommitBatch = 500 // 16000 bytes of calldata
+)
+
+type QuoteCommitmentInfo struct {
+	HashLock  string `json:"hash_lock"`
+	QuoteHash string `json:"quote_hash"`          // hex, personal_sign hash of the quote, see VerifyQuote()
+	CommitTx  string `json:"commit_tx,omitempty"` // hex, sBCH tx whose calldata contains quote_hash, empty means not committed yet
+}
+
+// periodically commit hashes of new quotes on chain, by a 0 value sBCH tx from bot to itself
+// whose calldata is the concatenated hashes, so bot can not deny having signed them
+func (bot *MarketMakerBot) commitQuotes() {
+	if !bot.quoteCommitment || bot.isSlaveMode || !bot.canSign() {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastQuotesCommittedAt < quoteCommitInterval {
+		return
+	}
+	bot.lastQuotesCommittedAt = now
+
+	limit := bot.dbQueryLimit
+	if limit <= 0 || limit > maxQuoteCommitBatch {
+		limit = maxQuoteCommitBatch
+	}
+	quotes, err := bot.db.getUncommittedQuotes(now, limit)
+	if err != nil {
+		bot.logError("DB error, failed to get uncommitted quotes: ", err)
+		return
+	}
+	if len(quotes) == 0 {
+		return
+	}
+
+	data := make([]byte, 0, len(quotes)*32)
+	ids := make([]uint, len(quotes))
+	for i, quote := range quotes {
+		data = append(data, gethcmn.FromHex(quote.QuoteHash)...)
+		ids[i] = quote.ID
+	}
+	txHash, err := bot.sbchCli.sendData(bot.sbchAddr, data)
+	if err != nil {
+		bot.logError("failed to commit quotes: ", err)
+		return
+	}
+	if err = bot.db.setQuotesCommitted(ids, toHex(txHash[:])); err != nil {
+		bot.logError("DB error, failed to mark quotes as committed: ", err)
+	}
+}
+
+// return the hash of a quote and the sBCH tx committing it
+func (bot *MarketMakerBot) handleQuoteCommitment(w http.ResponseWriter, r *http.Request) {
+	hashLock := r.URL.Query().Get("hash_lock")
+	if hashLock == "" {
+		NewErrResp("missing hash_lock").WriteTo(w)
+		return
+	}
+	quote, err := bot.db.getQuoteByHashLock(toHex(gethcmn.FromHex(hashLock)))
+	if err != nil || quote.QuoteHash == "" {
+		NewErrResp("quote not found").WriteTo(w)
+		return
+	}
+	NewOkResp(QuoteCommitmentInfo{
+		HashLock:  quote.HashLock,
+		QuoteHash: quote.QuoteHash,
+		CommitTx:  quote.CommitTx,
+	}).WriteTo(w)
+}
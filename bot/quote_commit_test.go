@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitQuotes(t *testing.T) {
+	_sbchKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	_sbchCli := newMockSbchClient(457, 459, 0)
+	_bot := &MarketMakerBot{
+		db:              initDB(t, 123, 456),
+		sbchCli:         _sbchCli,
+		bchPkh:          testBchPkh,
+		sbchPrivKey:     _sbchKey,
+		sbchAddr:        gethcrypto.PubkeyToAddress(_sbchKey.PublicKey),
+		bchTimeLock:     100,
+		sbchTimeLock:    36000,
+		penaltyRatio:    500,
+		bchPrice:        0.99e8,
+		sbchPrice:       0.98e8,
+		minSwapVal:      1000,
+		quoteValidity:   600,
+		dbQueryLimit:    100,
+		quoteCommitment: true,
+	}
+	newQuote := func(hashLock string) *QuoteInfo {
+		quote, err := _bot.makeQuote(&QuoteReq{
+			Direction:     DirectionBch2Sbch,
+			Value:         1e8,
+			HashLock:      toHex(gethHash32Bytes(hashLock)),
+			SenderPkh:     toHex(gethAddrBytes("user")),
+			SenderEvmAddr: toHex(gethAddrBytes("evm")),
+		})
+		require.NoError(t, err)
+		return quote
+	}
+	getCommitment := func(hashLock string) (resp struct {
+		Success bool                `json:"success"`
+		Error   string              `json:"error"`
+		Result  QuoteCommitmentInfo `json:"result"`
+	}) {
+		w := httptest.NewRecorder()
+		_bot.handleQuoteCommitment(w, httptest.NewRequest("GET", "/quote/commitment?hash_lock="+hashLock, nil))
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return
+	}
+
+	q1 := newQuote("hash1")
+	q2 := newQuote("hash2")
+	require.Equal(t, "", getCommitment(q1.HashLock).Result.CommitTx)
+
+	// one tx commits both quote hashes
+	_bot.commitQuotes()
+	require.Len(t, _sbchCli.dataTxs, 1)
+	require.Equal(t, joinBytes(getQuoteHash(q1), getQuoteHash(q2)), _sbchCli.dataTxs[0])
+	txHash := gethcrypto.Keccak256Hash(_sbchCli.dataTxs[0])
+	resp := getCommitment("0x" + q2.HashLock)
+	require.True(t, resp.Success)
+	require.Equal(t, QuoteCommitmentInfo{
+		HashLock:  q2.HashLock,
+		QuoteHash: toHex(getQuoteHash(q2)),
+		CommitTx:  toHex(txHash[:]),
+	}, resp.Result)
+
+	// not committed again within the interval, new quotes are committed later
+	q3 := newQuote("hash3")
+	_bot.commitQuotes()
+	require.Len(t, _sbchCli.dataTxs, 1)
+	_bot.lastQuotesCommittedAt = 0
+	_bot.commitQuotes()
+	require.Len(t, _sbchCli.dataTxs, 2)
+	require.Equal(t, getQuoteHash(q3), _sbchCli.dataTxs[1])
+	_bot.lastQuotesCommittedAt = 0
+	_bot.commitQuotes()
+	require.Len(t, _sbchCli.dataTxs, 2)
+
+	require.Equal(t, "quote not found", getCommitment(toHex(gethHash32Bytes("hash4"))).Error)
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestMakeQuote_bch2sbch(t *testing.T) {
+	_sbchKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	_userPkh := gethAddrBytes("user")
+	_userEvmAddr := gethAddrBytes("evm")
+	_hashLock := gethHash32Bytes("hash")
+
+	_bot := &MarketMakerBot{
+		db:            initDB(t, 123, 456),
+		bchPkh:        testBchPkh,
+		sbchPrivKey:   _sbchKey,
+		sbchAddr:      gethcrypto.PubkeyToAddress(_sbchKey.PublicKey),
+		bchTimeLock:   100,
+		sbchTimeLock:  36000,
+		penaltyRatio:  500,
+		bchPrice:      0.99e8,
+		sbchPrice:     0.98e8,
+		minSwapVal:    1000,
+		quoteValidity: 600,
+	}
+
+	_, err = _bot.makeQuote(&QuoteReq{
+		Direction:     DirectionBch2Sbch,
+		Value:         100,
+		HashLock:      toHex(_hashLock),
+		SenderPkh:     toHex(_userPkh),
+		SenderEvmAddr: toHex(_userEvmAddr),
+	})
+	require.ErrorContains(t, err, "value out of range")
+
+	_, err = _bot.makeQuote(&QuoteReq{
+		Direction:     DirectionBch2Sbch,
+		Value:         1e8,
+		HashLock:      toHex(_hashLock),
+		Expiration:    99,
+		SenderPkh:     toHex(_userPkh),
+		SenderEvmAddr: toHex(_userEvmAddr),
+	})
+	require.ErrorContains(t, err, "invalid expiration")
+
+	quote, err := _bot.makeQuote(&QuoteReq{
+		Direction:     DirectionBch2Sbch,
+		Value:         1e8,
+		HashLock:      toHex(_hashLock),
+		SenderPkh:     toHex(_userPkh),
+		SenderEvmAddr: toHex(_userEvmAddr),
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0.99e8), quote.Price)
+	require.Equal(t, uint64(0.99e8), quote.CounterValue)
+	require.Equal(t, uint64(0.01e8), quote.Fee)
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, testBchPkh, _hashLock, 100, 500)
+	require.NoError(t, err)
+	addr, err := covenant.GetP2SHAddress()
+	require.NoError(t, err)
+	require.Equal(t, addr, quote.CovenantAddr)
+	opRet, err := covenant.BuildOpRetPkScript(_userEvmAddr, 0.99e8)
+	require.NoError(t, err)
+	require.Equal(t, toHex(opRet), quote.OpRetPayload)
+	require.Equal(t, addr+"?amount=1&label=sbas-"+toHex(_hashLock)[:16]+"&op_return_raw="+toHex(opRet[1:]),
+		quote.PaymentUri)
+
+	// check signature
+	sig := gethcmn.FromHex(quote.Signature)
+	pubKey, err := gethcrypto.SigToPub(getQuoteHash(quote), sig)
+	require.NoError(t, err)
+	require.Equal(t, _bot.sbchAddr, gethcrypto.PubkeyToAddress(*pubKey))
+	require.NoError(t, VerifyQuote(quote))
+	tampered := *quote
+	tampered.Price++
+	require.ErrorContains(t, VerifyQuote(&tampered), "quote is not signed by "+quote.Signer)
+	tampered.Signature = "0x1234"
+	require.ErrorContains(t, VerifyQuote(&tampered), "invalid signature length: 2")
+
+	// price dropped, quote is honored within validity window
+	_bot.bchPrice = 0.95e8
+	now := time.Now()
+	require.Equal(t, uint64(0.99e8), _bot.getBchPriceFor(nil, quote.HashLock, 1e8, now))
+	require.Equal(t, uint64(0.95e8), _bot.getBchPriceFor(nil, quote.HashLock, 2e8, now))
+	require.Equal(t, uint64(0.98e8), _bot.getSbchPriceFor(nil, quote.HashLock, 1e8, now))
+	require.Equal(t, uint64(0.95e8), _bot.getBchPriceFor(nil, quote.HashLock, 1e8, now.Add(time.Hour)))
+	require.Equal(t, uint64(0.95e8), _bot.getBchPriceFor(nil, toHex(gethHash32Bytes("hash2")), 1e8, now))
+	require.False(t, quote.Batchable)
+
+	// batched receipts are on, quote the batchable covenant
+	_bot.batchReceipts = true
+	_hashLock3 := gethHash32Bytes("hash3")
+	quote, err = _bot.makeQuote(&QuoteReq{
+		Direction:     DirectionBch2Sbch,
+		Value:         1e8,
+		HashLock:      toHex(_hashLock3),
+		SenderPkh:     toHex(_userPkh),
+		SenderEvmAddr: toHex(_userEvmAddr),
+	})
+	require.NoError(t, err)
+	require.True(t, quote.Batchable)
+	covenant, err = htlcbch.NewMainnetCovenant(_userPkh, testBchPkh, _hashLock3, 100, 500)
+	require.NoError(t, err)
+	addr, err = covenant.WithBatchable().GetP2SHAddress()
+	require.NoError(t, err)
+	require.Equal(t, addr, quote.CovenantAddr)
+}
+
+func TestAddQuote_unexpired(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	quote := &Quote{HashLock: "1234", Direction: DirectionBch2Sbch, Value: 1e8, Price: 0.99e8, ValidUntil: 1000}
+	require.NoError(t, _db.addQuote(quote, 900))
+
+	// replaced only after expiry
+	attack := &Quote{HashLock: "1234", Direction: DirectionBch2Sbch, Value: 1e8, Price: 0.5e8, ValidUntil: 1600}
+	require.ErrorIs(t, _db.addQuote(attack, 1000), ErrQuoteExists)
+	saved, err := _db.getQuoteByHashLock("1234")
+	require.NoError(t, err)
+	require.Equal(t, uint64(0.99e8), saved.Price)
+
+	require.NoError(t, _db.addQuote(attack, 1001))
+	saved, err = _db.getQuoteByHashLock("1234")
+	require.NoError(t, err)
+	require.Equal(t, uint64(0.5e8), saved.Price)
+}
@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// periodically re-query both chains for in-flight swaps,
+// correct DB drift caused by missed events or manual intervention,
+// and alert on drifts which can not be corrected automatically
+func (bot *MarketMakerBot) reconcile() {
+	if bot.reconcileInterval == 0 {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastReconciledAt < int64(bot.reconcileInterval) {
+		return
+	}
+	bot.lastReconciledAt = now
+
+	log.Info("reconcile in-flight swaps ...")
+	bot.reconcileBch2SbchRecords()
+	bot.reconcileSbch2BchRecords()
+}
+
+func (bot *MarketMakerBot) reconcileBch2SbchRecords() {
+	// BCH lock tx must exist
+	for _, status := range []Bch2SbchStatus{Bch2SbchStatusNew, Bch2SbchStatusSbchLocked, Bch2SbchStatusSecretRevealed} {
+		records, err := bot.db.getBch2SbchRecordsByStatus(status, bot.dbQueryLimit)
+		if err != nil {
+			bot.logError("DB error, failed to get BCH2SBCH records: ", err)
+			return
+		}
+		for _, record := range records {
+			if _, err := bot.bchCli.GetTxConfirmations(record.BchLockTxHash); err != nil {
+				bot.logWarnf("BCH lock tx not found! hashLock: %s, BCH lock tx: %s, status: %d, err: %s",
+					record.HashLock, record.BchLockTxHash, record.Status, err.Error())
+			}
+		}
+	}
+
+	// sBCH lock made by bot must be in Locked state
+	records, err := bot.db.getBch2SbchRecordsByStatus(Bch2SbchStatusSbchLocked, bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get BCH2SBCH records: ", err)
+		return
+	}
+	tokens := make([]string, len(records))
+	keys := make([]SwapStateKey, len(records))
+	for i, record := range records {
+		tokens[i] = record.Token
+		keys[i] = SwapStateKey{Sender: bot.sbchAddr, HashLock: gethcmn.HexToHash(record.HashLock)}
+	}
+	states, err := bot.getSbchSwapStates(tokens, keys)
+	if err != nil {
+		bot.logError("RPC error, failed to get sBCH swap states: ", err)
+		return
+	}
+	for i, record := range records {
+		switch states[i] {
+		case swapStateUnknown:
+			// token is not configured any more
+		case SwapLocked:
+			// ok
+		case SwapRefunded:
+			log.Info("sBCH is refunded, hashLock: ", record.HashLock)
+			record.UpdateStatusToSbchRefunded("?")
+			if err = bot.db.updateBch2SbchRecord(record); err != nil {
+				bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
+			}
+		case SwapUnlocked:
+			bot.logWarnf("sBCH is unlocked but secret is not recorded, rescan sBCH blocks to recover it! hashLock: %s",
+				record.HashLock)
+		default:
+			bot.logWarnf("sBCH lock not found! hashLock: %s, sBCH lock tx: %s",
+				record.HashLock, record.SbchLockTxHash)
+		}
+	}
+}
+
+func (bot *MarketMakerBot) reconcileSbch2BchRecords() {
+	for _, status := range []Sbch2BchStatus{Sbch2BchStatusNew, Sbch2BchStatusBchLocked, Sbch2BchStatusSecretRevealed} {
+		records, err := bot.db.getSbch2BchRecordsByStatus(status, bot.dbQueryLimit)
+		if err != nil {
+			bot.logError("DB error, failed to get SBCH2BCH records: ", err)
+			return
+		}
+		if !bot.reconcileSbch2BchStatus(records) {
+			return
+		}
+	}
+}
+
+// return false if RPC error occurs
+func (bot *MarketMakerBot) reconcileSbch2BchStatus(records []*Sbch2BchRecord) bool {
+	// BCH lock tx made by bot must exist
+	for _, record := range records {
+		if record.Status != Sbch2BchStatusNew {
+			if _, err := bot.bchCli.GetTxConfirmations(record.BchLockTxHash); err != nil {
+				bot.logWarnf("BCH lock tx not found! hashLock: %s, BCH lock tx: %s, status: %d, err: %s",
+					record.HashLock, record.BchLockTxHash, record.Status, err.Error())
+			}
+		}
+	}
+
+	// sBCH lock made by user must be in Locked state
+	tokens := make([]string, len(records))
+	keys := make([]SwapStateKey, len(records))
+	for i, record := range records {
+		tokens[i] = record.Token
+		keys[i] = SwapStateKey{
+			Sender:   gethcmn.HexToAddress(record.SbchSenderAddr),
+			HashLock: gethcmn.HexToHash(record.HashLock),
+		}
+	}
+	states, err := bot.getSbchSwapStates(tokens, keys)
+	if err != nil {
+		bot.logError("RPC error, failed to get sBCH swap states: ", err)
+		return false
+	}
+	for i, record := range records {
+		if states[i] != swapStateUnknown {
+			bot.reconcileSbch2BchRecord(record, states[i])
+		}
+	}
+	return true
+}
+
+func (bot *MarketMakerBot) reconcileSbch2BchRecord(record *Sbch2BchRecord, state uint8) {
+	switch {
+	case state == SwapLocked:
+		// ok
+	case state == SwapUnlocked && record.Status == Sbch2BchStatusSecretRevealed:
+		log.Info("sBCH is unlocked, hashLock: ", record.HashLock)
+		record.UpdateStatusToSbchUnlocked("?")
+		if err := bot.db.updateSbch2BchRecord(record); err != nil {
+			bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		}
+	case state == SwapRefunded && record.Status == Sbch2BchStatusNew:
+		// user has refunded sBCH, so BCH must not be locked
+		log.Info("sBCH is refunded by user, hashLock: ", record.HashLock)
+		record.Status = Sbch2BchStatusTooLateToLockBch
+		if err := bot.db.updateSbch2BchRecord(record); err != nil {
+			bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+		}
+	default:
+		bot.logWarnf("unexpected sBCH swap state! hashLock: %s, state: %d, status: %d",
+			record.HashLock, state, record.Status)
+	}
+}
+
+// query sBCH swap states in batches, swaps of the same token are in the same HTLC contract,
+// states of swaps whose token is not configured are swapStateUnknown
+func (bot *MarketMakerBot) getSbchSwapStates(tokens []string, keys []SwapStateKey) ([]uint8, error) {
+	states := make([]uint8, len(keys))
+	var groupTokens []string
+	groups := map[string][]int{} // token => indexes of keys
+	for i, token := range tokens {
+		if _, ok := groups[token]; !ok {
+			groupTokens = append(groupTokens, token)
+		}
+		groups[token] = append(groups[token], i)
+	}
+
+	for _, token := range groupTokens {
+		idxs := groups[token]
+		sbchCli, err := bot.sbchCliFor(token)
+		if err != nil {
+			bot.logError("failed to get sBCH swap state: ", err)
+			for _, idx := range idxs {
+				states[idx] = swapStateUnknown
+			}
+			continue
+		}
+		groupKeys := make([]SwapStateKey, len(idxs))
+		for i, idx := range idxs {
+			groupKeys[i] = keys[idx]
+		}
+		groupStates, err := sbchCli.getSwapStates(groupKeys)
+		if err != nil {
+			return nil, err
+		}
+		for i, idx := range idxs {
+			states[idx] = groupStates[i]
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	_hashLock1 := gethHash32("hash1")
+	_hashLock2 := gethHash32("hash2")
+	_hashLock3 := gethHash32("hash3")
+	_hashLock4 := gethHash32("hash4")
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock1")),
+		Value:          12345678,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(_hashLock1[:]),
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		Status:         Bch2SbchStatusSbchLocked,
+	}))
+	for i, x := range []struct {
+		hashLock []byte
+		status   Sbch2BchStatus
+	}{
+		{_hashLock2[:], Sbch2BchStatusSecretRevealed},
+		{_hashLock3[:], Sbch2BchStatusNew},
+		{_hashLock4[:], Sbch2BchStatusNew},
+	} {
+		require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+			SbchLockTime:    1234567890,
+			SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock" + string(rune('0'+i)))),
+			Value:           12345678,
+			SbchPrice:       1e8,
+			SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+			BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+			HashLock:        toHex(x.hashLock),
+			TimeLock:        72000,
+			HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+			Status:          x.status,
+		}))
+	}
+
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_sbchCli.states[_hashLock1] = SwapRefunded
+	_sbchCli.states[_hashLock2] = SwapUnlocked
+	_sbchCli.states[_hashLock3] = SwapRefunded
+	_sbchCli.states[_hashLock4] = SwapLocked
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       newMockBchClient(122, 129),
+		sbchCli:      _sbchCli,
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	// disabled
+	_bot.reconcile()
+	record1, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock1[:]))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusSbchLocked, record1.Status)
+
+	_bot.reconcileInterval = 600
+	_bot.reconcile()
+	require.NotZero(t, _bot.lastReconciledAt)
+
+	record1, err = _db.getBch2SbchRecordByHashLock(toHex(_hashLock1[:]))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusSbchRefunded, record1.Status)
+
+	record2, err := _db.getSbch2BchRecordByHashLock(toHex(_hashLock2[:]))
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusSbchUnlocked, record2.Status)
+
+	record3, err := _db.getSbch2BchRecordByHashLock(toHex(_hashLock3[:]))
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusTooLateToLockBch, record3.Status)
+
+	record4, err := _db.getSbch2BchRecordByHashLock(toHex(_hashLock4[:]))
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusNew, record4.Status)
+	require.Equal(t, 3, _sbchCli.swapStatesCalls) // batched by status
+
+	// not due yet
+	_sbchCli.states[_hashLock4] = SwapRefunded
+	_bot.reconcile()
+	record4, err = _db.getSbch2BchRecordByHashLock(toHex(_hashLock4[:]))
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusNew, record4.Status)
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gcash/bchd/btcjson"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// BlockRecorder archives scanned blocks into a dir which can be fed to Replay,
+// or inspected after an incident, see RecordedBotInfoFile for its layout
+type BlockRecorder struct {
+	dir    string
+	allTxs bool // record all txs of BCH blocks, not only HTLC related ones
+}
+
+func newBlockRecorder(dir string, allTxs bool) (*BlockRecorder, error) {
+	for _, subDir := range []string{RecordedBchDir, RecordedSbchDir} {
+		if err := os.MkdirAll(filepath.Join(dir, subDir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create record dir: %w", err)
+		}
+	}
+	return &BlockRecorder{dir: dir, allTxs: allTxs}, nil
+}
+
+func (r *BlockRecorder) recordBotInfo(info *RecordedBotInfo) error {
+	return r.writeFile(RecordedBotInfoFile, info)
+}
+
+// blocks without HTLC txs are skipped unless all txs are recorded
+func (r *BlockRecorder) recordBchBlock(h int64, block *btcjson.GetBlockVerboseTxResult,
+	scan *htlcbch.BlockScan) error {
+
+	htlcTxs := map[string]bool{}
+	for _, deposit := range scan.Deposits {
+		htlcTxs[deposit.TxHash] = true
+	}
+	for _, receipt := range scan.Receipts {
+		htlcTxs[receipt.TxHash] = true
+	}
+	for _, suspect := range scan.Suspects {
+		htlcTxs[suspect.TxHash] = true
+	}
+
+	recorded := &RecordedBchBlock{Height: h, Hash: block.Hash, Time: block.Time}
+	for _, tx := range block.Tx {
+		if r.allTxs || htlcTxs[tx.Txid] {
+			recorded.Txs = append(recorded.Txs, tx.Hex)
+		}
+	}
+	if len(recorded.Txs) == 0 {
+		return nil
+	}
+	return r.writeFile(filepath.Join(RecordedBchDir, fmt.Sprintf("%d.json", h)), recorded)
+}
+
+// logs are grouped by block, getTxTime gives the block time of a log
+func (r *BlockRecorder) recordSbchLogs(logs []gethtypes.Log,
+	getTxTime func(log gethtypes.Log) (uint64, error)) error {
+
+	var blocks []*RecordedSbchBlock
+	for _, ethLog := range logs {
+		if len(blocks) == 0 || blocks[len(blocks)-1].Height != ethLog.BlockNumber {
+			ts, err := getTxTime(ethLog)
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, &RecordedSbchBlock{Height: ethLog.BlockNumber, Time: ts})
+		}
+		blocks[len(blocks)-1].Logs = append(blocks[len(blocks)-1].Logs, ethLog)
+	}
+	for _, block := range blocks {
+		file := filepath.Join(RecordedSbchDir, fmt.Sprintf("%d.json", block.Height))
+		if err := r.writeFile(file, block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write to a temp file then rename it, so that a crash never leaves a partial file
+func (r *BlockRecorder) writeFile(name string, v any) error {
+	bz, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(r.dir, name)
+	if err = os.WriteFile(file+".tmp", bz, 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestBlockRecorder(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_sbchLockTxHash := gethHash32("sbchlocktx")
+	_timeLock := uint16(100)
+
+	_bchCli := newMockBchClient(124, 126)
+	_bchCli.blocks[125] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{TxIn: []*wire.TxIn{}, TxOut: []*wire.TxOut{{Value: 1000, PkScript: newP2SHPkScript(gethAddrBytes("p2sh"))}}},
+			{
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{
+						Value:    12345678,
+						PkScript: getHtlcP2shPkScript(_userPkh, testBchPkh, _hashLock, _timeLock, 500),
+					},
+					{
+						PkScript: newHtlcDepositOpRet(testBchPkh, _userPkh, _hashLock, _timeLock, 500,
+							gethAddrBytes("evm"), 1e8),
+					},
+				},
+			},
+		},
+	}
+	_sbchCli := newMockSbchClient(457, 460, 0)
+	_sbchCli.logs[459] = []gethtypes.Log{
+		newTestEvmLockLog(gethAddr("htlc"), 459, _sbchLockTxHash, gethAddr("uevm"), gethAddr("ubch"),
+			gethHash32("hashlock"), 987600000, 12*3600, 12345678),
+	}
+	_sbchCli.txTimes[_sbchLockTxHash] = 987600000
+
+	dir := t.TempDir()
+	recorder, err := newBlockRecorder(dir, false)
+	require.NoError(t, err)
+	_bot := &MarketMakerBot{
+		db:           initDB(t, 123, 456),
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		sbchCli:      _sbchCli,
+		bchPkh:       testBchPkh,
+		sbchAddr:     testEvmAddr,
+		bchTimeLock:  _timeLock,
+		sbchTimeLock: 12 * 3600,
+		penaltyRatio: 500,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+		recorder:     recorder,
+	}
+	_bot.scanBchBlocks()
+	_bot.scanSbchEvents()
+
+	var bchBlocks []*RecordedBchBlock
+	require.NoError(t, readRecordedBlocks(filepath.Join(dir, RecordedBchDir), &bchBlocks))
+	require.Len(t, bchBlocks, 1)
+	require.Equal(t, int64(125), bchBlocks[0].Height)
+	require.Len(t, bchBlocks[0].Txs, 1) // the deposit tx only
+	require.Equal(t, htlcbch.MsgTxToHex(_bchCli.blocks[125].Transactions[1]), bchBlocks[0].Txs[0])
+
+	var sbchBlocks []*RecordedSbchBlock
+	require.NoError(t, readRecordedBlocks(filepath.Join(dir, RecordedSbchDir), &sbchBlocks))
+	require.Len(t, sbchBlocks, 1)
+	require.Equal(t, uint64(459), sbchBlocks[0].Height)
+	require.Equal(t, uint64(987600000), sbchBlocks[0].Time)
+	require.Equal(t, _sbchCli.logs[459], sbchBlocks[0].Logs)
+
+	// replay what is recorded
+	require.NoError(t, recorder.recordBotInfo(&RecordedBotInfo{
+		BchNet:       "mainnet",
+		BchPkh:       toHex(testBchPkh),
+		SbchAddr:     testEvmAddr.Hex(),
+		BchTimeLock:  _timeLock,
+		SbchTimeLock: 12 * 3600,
+		PenaltyBPS:   500,
+		BchPrice:     1e8,
+		SbchPrice:    1e8,
+		MaxSwapVal:   1e9,
+	}))
+	result, err := Replay(DefaultConfig(), dir)
+	require.NoError(t, err)
+	require.Len(t, result.States[DirectionBch2Sbch], 1)
+	require.Len(t, result.States[DirectionSbch2Bch], 1)
+	require.Contains(t, result.States[DirectionSbch2Bch], toHex(gethHash32Bytes("hashlock")))
+
+	// all txs
+	recorder.allTxs = true
+	block, _ := _bchCli.GetBlock(125)
+	require.NoError(t, recorder.recordBchBlock(125, block, _bot.getChainAdapter().ScanBlock(block, nil)))
+	bchBlocks = nil
+	require.NoError(t, readRecordedBlocks(filepath.Join(dir, RecordedBchDir), &bchBlocks))
+	require.Len(t, bchBlocks[0].Txs, 2)
+
+	entries, err := os.ReadDir(filepath.Join(dir, RecordedBchDir))
+	require.NoError(t, err)
+	require.Len(t, entries, 1) // no temp files left
+}
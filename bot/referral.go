@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcmath"
+)
+
+// Referral is an integrator who is paid a fixed share of swap values. BCH covenants quoted or
+// locked by bot are split covenants which pay the share to it on unlock, see
+// htlcbch.HtlcCovenant.WithReferral. So the user pays it on sbch2bch swaps, and bot pays it on
+// bch2sbch swaps, whose deposits may only carry this referral.
+type Referral struct {
+	pkh []byte // P2PKH
+	bps uint16
+}
+
+// nil if referral_bch_addr is not set
+func newReferral(cfg *Config, bchNet *htlcbch.ChainParams) (*Referral, error) {
+	if cfg.ReferralBchAddr == "" {
+		if cfg.ReferralBPS != 0 {
+			return nil, fmt.Errorf("referral BPS is set without referral BCH address")
+		}
+		return nil, nil
+	}
+	addr, err := bchNet.DecodeP2PKHAddress(cfg.ReferralBchAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid referral BCH address: %w", err)
+	}
+	if cfg.ReferralBPS == 0 || cfg.ReferralBPS >= htlcmath.MaxBPS {
+		return nil, fmt.Errorf("referral BPS not in (0, %d): %d", htlcmath.MaxBPS, cfg.ReferralBPS)
+	}
+	return &Referral{pkh: addr.ScriptAddress(), bps: cfg.ReferralBPS}, nil
+}
+
+func (r *Referral) getPkh() string {
+	if r == nil {
+		return ""
+	}
+	return toHex(r.pkh)
+}
+
+func (r *Referral) getBPS() uint16 {
+	if r == nil {
+		return 0
+	}
+	return r.bps
+}
+
+// applies the referral to covenants made for quotes
+func (r *Referral) apply(c *htlcbch.HtlcCovenant) (*htlcbch.HtlcCovenant, error) {
+	if r == nil {
+		return c, nil
+	}
+	return c.WithReferral(r.pkh, r.bps)
+}
+
+// a deposit must carry no referral, or exactly this one
+func (r *Referral) matchesDeposit(deposit *htlcbch.HtlcLockInfo) bool {
+	if deposit.ReferralBPS == 0 {
+		return true
+	}
+	return r != nil && deposit.ReferralBPS == r.bps && bytes.Equal(deposit.ReferralPkh, r.pkh)
+}
+
+// paid by the unlock tx of a split covenant of value sats, 0 if there is no referral
+func getReferralFee(value uint64, referralBPS uint16) uint64 {
+	fee, err := htlcmath.Penalty(value, referralBPS)
+	if err != nil {
+		return 0
+	}
+	return fee
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// RefundAgent runs on the user side. It follows BCH deposits of the user and broadcasts
+// their refund txs as soon as they expire, unless they are unlocked by the bot before that.
+// Refund txs need no signature, so the agent needs only the deposit tx hashes.
+type RefundAgent struct {
+	bchCli   IBchClient
+	params   *htlcbch.ChainParams
+	feeRate  uint64
+	deposits map[string]string // deposit tx hash => refund tx hash (empty if not sent)
+}
+
+func NewRefundAgent(bchCli IBchClient, params *htlcbch.ChainParams, depositTxs []string, feeRate uint64) *RefundAgent {
+	deposits := make(map[string]string, len(depositTxs))
+	for _, txHash := range depositTxs {
+		deposits[txHash] = ""
+	}
+	return &RefundAgent{
+		bchCli:   bchCli,
+		params:   params,
+		feeRate:  feeRate,
+		deposits: deposits,
+	}
+}
+
+// Run checks the deposits every interval, returns when all of them are spent
+func (agent *RefundAgent) Run(interval time.Duration) {
+	for {
+		if agent.Check() {
+			return
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Check refunds expired deposits, and stops following spent ones,
+// returns true if there is nothing left to follow
+func (agent *RefundAgent) Check() bool {
+	for txHash := range agent.deposits {
+		spent, err := agent.checkDeposit(txHash)
+		if err != nil {
+			log.Errorf("deposit %s: %s", txHash, err.Error())
+			continue
+		}
+		if spent {
+			delete(agent.deposits, txHash)
+		}
+	}
+	return len(agent.deposits) == 0
+}
+
+func (agent *RefundAgent) checkDeposit(txHash string) (spent bool, err error) {
+	tx, err := agent.bchCli.GetTx(txHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to get deposit tx: %w", err)
+	}
+	deposit := agent.params.GetHtlcLockInfo(*tx)
+	if deposit == nil {
+		return false, fmt.Errorf("not a valid deposit tx")
+	}
+	txOut, err := agent.bchCli.GetTxOut(txHash, 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to get UTXO: %w", err)
+	}
+	if txOut == nil {
+		if refundTxHash := agent.deposits[txHash]; refundTxHash != "" {
+			log.Infof("deposit %s: refunded by tx %s", txHash, refundTxHash)
+		} else {
+			log.Infof("deposit %s: spent, unlocked by the bot or refunded by someone else", txHash)
+		}
+		return true, nil
+	}
+	// same as the bot, see refundLockedBCH()
+	if n := deposit.RefundableIn(tx.Confirmations, tx.Blocktime, time.Now().Unix()); n > 0 {
+		log.Infof("deposit %s: refundable after %d more %s", txHash, n, deposit.ExpirationType.Unit())
+		return false, nil
+	}
+
+	// expired and still unspent, (re)send the refund tx
+	c, err := agent.params.NewCovenantOfDeposit(deposit)
+	if err != nil {
+		return false, err
+	}
+	refundTx, err := c.MakeRefundTx(gethcmn.FromHex(txHash), deposit.Vout, int64(deposit.Value), agent.feeRate)
+	if err != nil {
+		return false, fmt.Errorf("failed to make refund tx: %w", err)
+	}
+	refundTxHash, err := agent.bchCli.SendTx(refundTx)
+	if err != nil {
+		return false, fmt.Errorf("failed to send refund tx: %w", err)
+	}
+	agent.deposits[txHash] = refundTxHash.String()
+	log.Infof("deposit %s: refund tx %s sent", txHash, refundTxHash.String())
+	return false, nil
+}
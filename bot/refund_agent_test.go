@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestRefundAgent(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_expiration := uint16(12)
+	_penaltyBPS := uint16(500)
+
+	newDepositTx := func(hashLock []byte) *wire.MsgTx {
+		return &wire.MsgTx{
+			TxIn: []*wire.TxIn{},
+			TxOut: []*wire.TxOut{
+				{
+					Value:    100000,
+					PkScript: getHtlcP2shPkScript(_userPkh, testBchPkh, hashLock, _expiration, _penaltyBPS),
+				},
+				{
+					PkScript: newHtlcDepositOpRet(testBchPkh, _userPkh, hashLock, _expiration, _penaltyBPS, testEvmAddr[:], 1e8),
+				},
+			},
+		}
+	}
+	_depositTx := newDepositTx(gethHash32Bytes("hash"))
+	_depositTx2 := newDepositTx(gethHash32Bytes("hash2"))
+	_txHash := _depositTx.TxHash().String()
+	_txHash2 := _depositTx2.TxHash().String()
+
+	_bchCli := newMockBchClient(100, 120)
+	_bchCli.txs[_txHash] = _depositTx
+	_bchCli.txs[_txHash2] = _depositTx2
+	_bchCli.confirmations[_txHash] = 12
+	_bchCli.confirmations[_txHash2] = 12
+
+	agent := NewRefundAgent(_bchCli, htlcbch.MainNet, []string{_txHash, _txHash2, "unknown"}, 2)
+	require.False(t, agent.Check())
+	require.Len(t, _bchCli.sentTxs, 0)
+
+	// the second deposit is unlocked by bot
+	_bchCli.spentOutputs[_txHash2+":0"] = true
+	_bchCli.confirmations[_txHash] = 13
+	require.False(t, agent.Check())
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Equal(t, _depositTx.TxHash(), _bchCli.sentTxs[0].TxIn[0].PreviousOutPoint.Hash)
+	require.Equal(t, _bchCli.sentTxs[0].TxHash().String(), agent.deposits[_txHash])
+	require.Len(t, agent.deposits, 2)
+
+	// refund tx dropped, sent again
+	require.False(t, agent.Check())
+	require.Len(t, _bchCli.sentTxs, 2)
+
+	// refunded, only the unknown deposit is left
+	_bchCli.spentOutputs[_txHash+":0"] = true
+	require.False(t, agent.Check())
+	require.Len(t, agent.deposits, 1)
+
+	delete(agent.deposits, "unknown")
+	require.True(t, agent.Check())
+}
@@ -0,0 +1,410 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/chaincfg/chainhash"
+	"github.com/gcash/bchd/wire"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+// Layout of a recorded blocks dir:
+//
+//	bot.json            RecordedBotInfo
+//	bch/<height>.json   RecordedBchBlock, blocks without HTLC txs may be missing
+//	sbch/<height>.json  RecordedSbchBlock, blocks without HTLC logs may be missing
+//	expected.json       ReplayStates, checked by Replay
+const (
+	RecordedBotInfoFile = "bot.json"
+	RecordedBchDir      = "bch"
+	RecordedSbchDir     = "sbch"
+	ReplayExpectedFile  = "expected.json"
+)
+
+// RecordedBotInfo is what the bot got from its MarketMaker contract when blocks were recorded
+type RecordedBotInfo struct {
+	BchNet       string `json:"bch_net"`
+	BchPkh       string `json:"bch_pkh"`   // hex
+	SbchAddr     string `json:"sbch_addr"` // hex
+	BchTimeLock  uint16 `json:"bch_time_lock"`
+	SbchTimeLock uint32 `json:"sbch_time_lock"`
+	PenaltyBPS   uint16 `json:"penalty_bps"`
+	BchPrice     uint64 `json:"bch_price"`
+	SbchPrice    uint64 `json:"sbch_price"`
+	MinSwapVal   uint64 `json:"min_swap_val"` // in sats
+	MaxSwapVal   uint64 `json:"max_swap_val"` // in sats
+}
+
+type RecordedBchBlock struct {
+	Height int64    `json:"height"`
+	Hash   string   `json:"hash"`
+	Time   int64    `json:"time"`
+	Txs    []string `json:"txs"` // raw txs in hex, all of the block or only HTLC related ones
+}
+
+type RecordedSbchBlock struct {
+	Height uint64          `json:"height"`
+	Time   uint64          `json:"time"`
+	Logs   []gethtypes.Log `json:"logs"` // HTLC logs
+}
+
+// ReplayStates is direction => hash lock => status
+type ReplayStates map[string]map[string]string
+
+type ReplayResult struct {
+	BchBlocks  int          `json:"bch_blocks"`
+	SbchBlocks int          `json:"sbch_blocks"`
+	States     ReplayStates `json:"states"`
+	Mismatches []string     `json:"mismatches"` // against expected.json, if any
+}
+
+// the BCH chain seen by the bot during replay
+type replayBchClient struct {
+	*MockBchClient
+	txHeights map[string]int64
+	recorded  []*wire.MsgTx
+}
+
+// Txs sent by the bot are replaced by the recorded ones they correspond to,
+// so that later spends of them in recorded blocks are recognized.
+// A recorded tx corresponds to a sent one if it spends the same covenant UTXO,
+// or pays to the same covenant by its first output.
+func (c *replayBchClient) SendTx(tx *wire.MsgTx) (*chainhash.Hash, error) {
+	c.sentTxs = append(c.sentTxs, tx)
+	for _, recorded := range c.recorded {
+		if len(recorded.TxIn) > 0 && len(tx.TxIn) > 0 &&
+			recorded.TxIn[0].PreviousOutPoint == tx.TxIn[0].PreviousOutPoint {
+			txHash := recorded.TxHash()
+			return &txHash, nil
+		}
+	}
+	for _, recorded := range c.recorded {
+		if len(recorded.TxOut) > 0 && len(tx.TxOut) > 0 &&
+			bytes.Equal(recorded.TxOut[0].PkScript, tx.TxOut[0].PkScript) {
+			txHash := recorded.TxHash()
+			return &txHash, nil
+		}
+	}
+	txHash := tx.TxHash()
+	return &txHash, nil
+}
+
+func (c *replayBchClient) addBlock(block *RecordedBchBlock) error {
+	msgBlock := &wire.MsgBlock{}
+	for _, rawTx := range block.Txs {
+		tx, err := htlcbch.MsgTxFromBytes(gethcmn.FromHex(rawTx))
+		if err != nil {
+			return fmt.Errorf("invalid tx in BCH block#%d: %w", block.Height, err)
+		}
+		msgBlock.Transactions = append(msgBlock.Transactions, tx)
+		c.txHeights[tx.TxHash().String()] = block.Height
+	}
+	for h := c.hTo + 1; h < block.Height; h++ {
+		c.blocks[h] = &wire.MsgBlock{}
+	}
+	c.blocks[block.Height] = msgBlock
+	c.hTo = block.Height
+	for txHash, h := range c.txHeights {
+		c.confirmations[txHash] = c.hTo - h + 1
+	}
+	return nil
+}
+
+func (c *replayBchClient) addFutureTxs(blocks []*RecordedBchBlock) {
+	for _, block := range blocks {
+		for _, rawTx := range block.Txs {
+			if tx, err := htlcbch.MsgTxFromBytes(gethcmn.FromHex(rawTx)); err == nil {
+				c.recorded = append(c.recorded, tx)
+			}
+		}
+	}
+}
+
+func addReplaySbchBlock(c *MockSbchClient, block *RecordedSbchBlock) {
+	c.logs[block.Height] = block.Logs
+	c.hTo = block.Height
+	c.ts = block.Time
+	for _, ethLog := range block.Logs {
+		c.txTimes[ethLog.TxHash] = block.Time
+		if len(ethLog.Topics) == 0 {
+			continue
+		}
+		switch ethLog.Topics[0] {
+		case htlcsbch.LockEventId:
+			if lockLog := htlcsbch.ParseHtlcLockLog(ethLog); lockLog != nil {
+				c.states[lockLog.HashLock] = SwapLocked
+			}
+		case htlcsbch.UnlockEventId:
+			if unlockLog := htlcsbch.ParseHtlcUnlockLog(ethLog); unlockLog != nil {
+				c.states[unlockLog.HashLock] = SwapUnlocked
+			}
+		case htlcsbch.RefundEventId:
+			if refundLog := htlcsbch.ParseHtlcRefundLog(ethLog); refundLog != nil {
+				c.states[refundLog.HashLock] = SwapRefunded
+			}
+		}
+	}
+}
+
+// Replay feeds recorded blocks of both chains, in the order of their timestamps, through
+// the scanners and the swap engine of a bot with a temporary DB, and checks resulting swap
+// states against expected.json (if any). Keys are random, txs sent by the bot are not broadcast.
+// Recorded blocks are treated as final, cfg only gives engine options (fee rates, ...).
+func Replay(cfg *Config, dir string) (*ReplayResult, error) {
+	info := &RecordedBotInfo{}
+	if err := readJsonFile(filepath.Join(dir, RecordedBotInfoFile), info); err != nil {
+		return nil, err
+	}
+	var bchBlocks []*RecordedBchBlock
+	if err := readRecordedBlocks(filepath.Join(dir, RecordedBchDir), &bchBlocks); err != nil {
+		return nil, err
+	}
+	var sbchBlocks []*RecordedSbchBlock
+	if err := readRecordedBlocks(filepath.Join(dir, RecordedSbchDir), &sbchBlocks); err != nil {
+		return nil, err
+	}
+	if len(bchBlocks) == 0 && len(sbchBlocks) == 0 {
+		return nil, fmt.Errorf("no recorded blocks")
+	}
+	sort.Slice(bchBlocks, func(i, j int) bool { return bchBlocks[i].Height < bchBlocks[j].Height })
+	sort.Slice(sbchBlocks, func(i, j int) bool { return sbchBlocks[i].Height < sbchBlocks[j].Height })
+
+	tmpDir, err := os.MkdirTemp("", "asbot-replay")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	bot, err := newReplayBot(cfg, info, filepath.Join(tmpDir, "replay.db"))
+	if err != nil {
+		return nil, err
+	}
+	// a chain without recorded blocks stays at height 1
+	firstBchH, firstSbchH, firstSbchTime := int64(2), uint64(2), uint64(0)
+	if len(bchBlocks) > 0 {
+		firstBchH = bchBlocks[0].Height
+	}
+	if len(sbchBlocks) > 0 {
+		firstSbchH, firstSbchTime = sbchBlocks[0].Height, sbchBlocks[0].Time
+	}
+	if err = bot.db.initLastHeights(uint64(firstBchH-1), firstSbchH-1); err != nil {
+		return nil, err
+	}
+	bchCli := &replayBchClient{MockBchClient: newMockBchClient(firstBchH, firstBchH-1), txHeights: map[string]int64{}}
+	bchCli.addFutureTxs(bchBlocks)
+	sbchCli := newMockSbchClient(firstSbchH, firstSbchH-1, firstSbchTime)
+	bot.bchCli, bot.sbchCli = bchCli, sbchCli
+
+	// interleave blocks of both chains by time
+	for i, j := 0, 0; i < len(bchBlocks) || j < len(sbchBlocks); {
+		if j == len(sbchBlocks) || (i < len(bchBlocks) && bchBlocks[i].Time <= int64(sbchBlocks[j].Time)) {
+			log.Infof("replay BCH block#%d", bchBlocks[i].Height)
+			if err = bchCli.addBlock(bchBlocks[i]); err != nil {
+				return nil, err
+			}
+			i++
+		} else {
+			log.Infof("replay sBCH block#%d", sbchBlocks[j].Height)
+			addReplaySbchBlock(sbchCli, sbchBlocks[j])
+			j++
+		}
+		bot.replayRound()
+	}
+
+	result := &ReplayResult{BchBlocks: len(bchBlocks), SbchBlocks: len(sbchBlocks)}
+	if result.States, err = bot.getReplayStates(); err != nil {
+		return nil, err
+	}
+	expected := ReplayStates{}
+	err = readJsonFile(filepath.Join(dir, ReplayExpectedFile), &expected)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		result.Mismatches = compareReplayStates(expected, result.States)
+	}
+	return result, nil
+}
+
+func newReplayBot(cfg *Config, info *RecordedBotInfo, dbFile string) (*MarketMakerBot, error) {
+	bchNet, err := getBchChainParams(info.BchNet, false)
+	if err != nil {
+		return nil, err
+	}
+	bchSigType, err := getBchSigType(cfg.BchSigType, cfg.BchSigHashType)
+	if err != nil {
+		return nil, err
+	}
+	bchPkh := gethcmn.FromHex(info.BchPkh)
+	bchAddr, err := bchNet.NewP2PKHAddress(bchPkh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BCH PKH: %w", err)
+	}
+	bchPrivKey, err := bchec.NewPrivateKey(bchec.S256())
+	if err != nil {
+		return nil, err
+	}
+	sbchPrivKey, err := gethcrypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	referral, err := newReferral(cfg, bchNet)
+	if err != nil {
+		return nil, err
+	}
+	affiliates, err := newAffiliates(cfg.Affiliates, bchNet)
+	if err != nil {
+		return nil, err
+	}
+	db, err := OpenDB(dbFile)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.syncSchemas(); err != nil {
+		return nil, err
+	}
+	return &MarketMakerBot{
+		db:                    db,
+		bchPrivKey:            bchPrivKey,
+		bchPkh:                bchPkh,
+		bchAddr:               bchAddr,
+		bchNet:                bchNet,
+		sbchPrivKey:           sbchPrivKey,
+		sbchAddr:              gethcmn.HexToAddress(info.SbchAddr),
+		bchTimeLock:           info.BchTimeLock,
+		sbchTimeLock:          info.SbchTimeLock,
+		penaltyRatio:          info.PenaltyBPS,
+		bchPrice:              info.BchPrice,
+		sbchPrice:             info.SbchPrice,
+		minSwapVal:            info.MinSwapVal,
+		maxSwapVal:            info.MaxSwapVal,
+		bchLockMinerFeeRate:   cfg.BchLockFeeRate,
+		bchUnlockMinerFeeRate: cfg.BchUnlockFeeRate,
+		bchRefundMinerFeeRate: cfg.BchRefundFeeRate,
+		bchDepositFloor:       cfg.BchDepositFloor,
+		strictDepositOutputs:  cfg.StrictDepositOutputs,
+		referral:              referral,
+		affiliates:            affiliates,
+		bchSigType:            bchSigType,
+		dbQueryLimit:          cfg.DbQueryLimit,
+		profitabilityGate:     cfg.ProfitabilityGate,
+		partialFill:           cfg.PartialFill,
+		sbchGasPrice:          cfg.getSbchGasPrice().Uint64(),
+		swapWorkers:           newSwapWorkers(1),
+		watchedCovenants:      map[string]bool{},
+		statsCache:            newStatsCache(),
+		errLogQueue:           newErrLogQueue(5000),
+		cfg:                   cfg,
+		reloadCh:              make(chan *Config, 1),
+	}, nil
+}
+
+// steps of Loop() which scan chains and drive swaps
+func (bot *MarketMakerBot) replayRound() {
+	bot.handleEvents()
+	bot.refundLockedSbch()
+	gotNewBlocks := bot.scanBchBlocks()
+	bot.refundLockedBCH(gotNewBlocks)
+	bot.handleBchUserDeposits()
+	bot.unlockBchUserDeposits()
+	bot.refundRemainders()
+	bot.scanSbchEvents()
+	bot.handleSbchUserDeposits()
+	bot.unlockSbchUserDeposits()
+}
+
+func (bot *MarketMakerBot) getReplayStates() (ReplayStates, error) {
+	states := ReplayStates{DirectionBch2Sbch: {}, DirectionSbch2Bch: {}}
+	bch2SbchRecords, err := bot.db.getBch2SbchRecordsCreatedSince(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range bch2SbchRecords {
+		states[DirectionBch2Sbch][record.HashLock] = record.Status.String()
+	}
+	sbch2BchRecords, err := bot.db.getSbch2BchRecordsCreatedSince(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range sbch2BchRecords {
+		states[DirectionSbch2Bch][record.HashLock] = record.Status.String()
+	}
+	return states, nil
+}
+
+func compareReplayStates(expected, actual ReplayStates) (mismatches []string) {
+	for _, direction := range []string{DirectionBch2Sbch, DirectionSbch2Bch} {
+		var hashLocks []string
+		for hashLock := range expected[direction] {
+			hashLocks = append(hashLocks, hashLock)
+		}
+		for hashLock := range actual[direction] {
+			if _, ok := expected[direction][hashLock]; !ok {
+				hashLocks = append(hashLocks, hashLock)
+			}
+		}
+		sort.Strings(hashLocks)
+		for _, hashLock := range hashLocks {
+			want, ok1 := expected[direction][hashLock]
+			got, ok2 := actual[direction][hashLock]
+			switch {
+			case !ok2:
+				mismatches = append(mismatches, fmt.Sprintf("%s %s: missing, expected %s", direction, hashLock, want))
+			case !ok1:
+				mismatches = append(mismatches, fmt.Sprintf("%s %s: unexpected, got %s", direction, hashLock, got))
+			case want != got:
+				mismatches = append(mismatches, fmt.Sprintf("%s %s: expected %s, got %s", direction, hashLock, want, got))
+			}
+		}
+	}
+	return
+}
+
+func readJsonFile(file string, v any) error {
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(bz, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return nil
+}
+
+// read <height>.json files in dir into blocks, which is a pointer to a slice of block pointers
+func readRecordedBlocks[T any](dir string, blocks *[]*T) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, err = strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64); err != nil ||
+			!strings.HasSuffix(name, ".json") {
+			continue
+		}
+		block := new(T)
+		if err = readJsonFile(filepath.Join(dir, name), block); err != nil {
+			return err
+		}
+		*blocks = append(*blocks, block)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func writeJsonFile(t *testing.T, file string, v any) {
+	bz, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0700))
+	require.NoError(t, os.WriteFile(file, bz, 0600))
+}
+
+func newReplayTestDir(t *testing.T) string {
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_timeLock := uint16(100)
+	_penaltyBPS := uint16(500)
+
+	dir := t.TempDir()
+	writeJsonFile(t, filepath.Join(dir, RecordedBotInfoFile), &RecordedBotInfo{
+		BchNet:       "mainnet",
+		BchPkh:       toHex(testBchPkh),
+		SbchAddr:     gethAddr("bot").Hex(),
+		BchTimeLock:  _timeLock,
+		SbchTimeLock: 3600,
+		PenaltyBPS:   _penaltyBPS,
+		BchPrice:     1e8,
+		SbchPrice:    1e8,
+		MinSwapVal:   1e5,
+		MaxSwapVal:   1e9,
+	})
+
+	depositTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{},
+		TxOut: []*wire.TxOut{
+			{
+				Value:    12345678,
+				PkScript: getHtlcP2shPkScript(_userPkh, testBchPkh, _hashLock, _timeLock, _penaltyBPS),
+			},
+			{
+				PkScript: newHtlcDepositOpRet(testBchPkh, _userPkh, _hashLock, _timeLock, _penaltyBPS,
+					gethAddrBytes("evm"), 1e8),
+			},
+		},
+	}
+	writeJsonFile(t, filepath.Join(dir, RecordedBchDir, "101.json"), &RecordedBchBlock{
+		Height: 101,
+		Time:   1000,
+		Txs:    []string{toHex(htlcbch.MsgTxToBytes(depositTx))},
+	})
+	writeJsonFile(t, filepath.Join(dir, RecordedBchDir, "103.json"), &RecordedBchBlock{
+		Height: 103,
+		Time:   2200,
+	})
+	writeJsonFile(t, filepath.Join(dir, RecordedSbchDir, "501.json"), &RecordedSbchBlock{
+		Height: 501,
+		Time:   1100,
+	})
+	return dir
+}
+
+func TestReplay(t *testing.T) {
+	dir := newReplayTestDir(t)
+	cfg := DefaultConfig()
+
+	result, err := Replay(cfg, dir)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.BchBlocks)
+	require.Equal(t, 1, result.SbchBlocks)
+	require.Equal(t, ReplayStates{
+		DirectionBch2Sbch: {toHex(gethHash32Bytes("hash")): Bch2SbchStatusSbchLocked.String()},
+		DirectionSbch2Bch: {},
+	}, result.States)
+	require.Nil(t, result.Mismatches)
+
+	writeJsonFile(t, filepath.Join(dir, ReplayExpectedFile), result.States)
+	result, err = Replay(cfg, dir)
+	require.NoError(t, err)
+	require.Len(t, result.Mismatches, 0)
+
+	writeJsonFile(t, filepath.Join(dir, ReplayExpectedFile), ReplayStates{
+		DirectionSbch2Bch: {"1234": Sbch2BchStatusNew.String()},
+	})
+	result, err = Replay(cfg, dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"bch2sbch " + toHex(gethHash32Bytes("hash")) + ": unexpected, got " + Bch2SbchStatusSbchLocked.String(),
+		"sbch2bch 1234: missing, expected " + Sbch2BchStatusNew.String(),
+	}, result.Mismatches)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, RecordedSbchDir)))
+	result, err = Replay(cfg, dir)
+	require.NoError(t, err)
+	require.Equal(t, 0, result.SbchBlocks)
+	require.Len(t, result.States[DirectionBch2Sbch], 1)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, RecordedBchDir)))
+	_, err = Replay(cfg, dir)
+	require.ErrorContains(t, err, "no recorded blocks")
+}
+
+func TestCompareReplayStates(t *testing.T) {
+	require.Equal(t, []string{"bch2sbch a: expected new, got refunded"}, compareReplayStates(
+		ReplayStates{DirectionBch2Sbch: {"a": "new"}},
+		ReplayStates{DirectionBch2Sbch: {"a": "refunded"}, DirectionSbch2Bch: {}}))
+}
@@ -0,0 +1,329 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+const (
+	RescanChainBch  = "bch"
+	RescanChainSbch = "sbch"
+)
+
+// RescanIssue is a discrepancy between chain data and DB found by rescan
+type RescanIssue struct {
+	Chain    string `json:"chain"`
+	Height   uint64 `json:"height"`
+	TxHash   string `json:"tx_hash"`
+	HashLock string `json:"hash_lock"`
+	Issue    string `json:"issue"`
+	Repaired bool   `json:"repaired"`
+}
+
+// Rescan re-parses historical blocks in [fromH, toH] and reconciles found
+// deposits/receipts/refunds against DB. Discrepancies are repaired if repair is true.
+// Last heights in DB are not changed.
+func (bot *MarketMakerBot) Rescan(chain string, fromH, toH uint64, repair bool) ([]*RescanIssue, error) {
+	if fromH > toH {
+		return nil, fmt.Errorf("invalid block range: %d > %d", fromH, toH)
+	}
+	switch chain {
+	case RescanChainBch:
+		return bot.rescanBchBlocks(fromH, toH, repair)
+	case RescanChainSbch:
+		return bot.rescanSbchBlocks(fromH, toH, repair)
+	default:
+		return nil, fmt.Errorf("invalid chain: %s", chain)
+	}
+}
+
+func (bot *MarketMakerBot) rescanBchBlocks(fromH, toH uint64, repair bool) (issues []*RescanIssue, err error) {
+	// -1 means no limit
+	bchLocked, err := bot.db.getSbch2BchRecordsByStatus(Sbch2BchStatusBchLocked, -1)
+	if err != nil {
+		return nil, fmt.Errorf("DB error, failed to get SBCH2BCH records: %w", err)
+	}
+	bchLockedByTxHash := map[string]*Sbch2BchRecord{}
+	for _, record := range bchLocked {
+		bchLockedByTxHash[record.BchLockTxHash] = record
+	}
+
+	for h := fromH; h <= toH; h++ {
+		log.Info("rescan BCH block#", h)
+		scan, err := bot.getBchBlockScan(int64(h))
+		if err != nil {
+			return issues, fmt.Errorf("RPC error, failed to get BCH block#%d: %w", h, err)
+		}
+
+		for _, deposit := range scan.Deposits {
+			if issue := bot.rescanBchDeposit(h, deposit, repair); issue != nil {
+				issues = append(issues, issue)
+			}
+		}
+
+		for _, receipt := range scan.Receipts {
+			if issue := bot.rescanBchReceipt(h, receipt, bchLockedByTxHash, repair); issue != nil {
+				issues = append(issues, issue)
+			}
+		}
+
+		for _, spend := range scan.Spends {
+			if issue := bot.rescanBchRefund(h, spend, bchLockedByTxHash, repair); issue != nil {
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return issues, nil
+}
+
+func (bot *MarketMakerBot) rescanBchDeposit(h uint64, deposit *htlcbch.HtlcLockInfo, repair bool) *RescanIssue {
+	hashLock := toHex(deposit.HashLock)
+	issue := &RescanIssue{Chain: RescanChainBch, Height: h, TxHash: deposit.TxHash, HashLock: hashLock}
+
+	if bot.isMyReceivePkh(deposit.RecipientPkh) {
+		if _, err := bot.db.getBch2SbchRecordByHashLock(hashLock); err == nil || bot.db.isBch2SbchRecordArchived(hashLock) {
+			return nil
+		}
+		issue.Issue = "BCH2SBCH record not found"
+		if repair {
+			bot.handleBchDepositTxB2S(h, deposit)
+			_, err := bot.db.getBch2SbchRecordByHashLock(hashLock)
+			issue.Repaired = err == nil
+		}
+		return issue
+	}
+
+	if bot.isSlaveMode && bytes.Equal(deposit.SenderPkh, bot.bchPkh) {
+		record, err := bot.db.getSbch2BchRecordByHashLock(hashLock)
+		if err != nil || record.Status != Sbch2BchStatusNew {
+			return nil
+		}
+		issue.Issue = "SBCH2BCH record not marked as BchLocked"
+		if repair {
+			bot.handleBchDepositTxS2B(h, deposit)
+			record, err = bot.db.getSbch2BchRecordByHashLock(hashLock)
+			issue.Repaired = err == nil && record.Status == Sbch2BchStatusBchLocked
+		}
+		return issue
+	}
+	return nil
+}
+
+func (bot *MarketMakerBot) rescanBchReceipt(h uint64, receipt *htlcbch.HtlcUnlockInfo,
+	bchLockedByTxHash map[string]*Sbch2BchRecord, repair bool) *RescanIssue {
+
+	record := bchLockedByTxHash[receipt.PrevTxHash]
+	if record == nil {
+		return nil
+	}
+	issue := &RescanIssue{
+		Chain:    RescanChainBch,
+		Height:   h,
+		TxHash:   receipt.TxHash,
+		HashLock: record.HashLock,
+		Issue:    "secret revealed but not recorded",
+	}
+	if repair {
+		bot.handleBchReceiptTx(receipt)
+		record, err := bot.db.getSbch2BchRecordByHashLock(record.HashLock)
+		issue.Repaired = err == nil && record.Status == Sbch2BchStatusSecretRevealed
+	}
+	return issue
+}
+
+func (bot *MarketMakerBot) rescanBchRefund(h uint64, spend *CachedBchSpend,
+	bchLockedByTxHash map[string]*Sbch2BchRecord, repair bool) *RescanIssue {
+
+	for _, prevTxHash := range spend.PrevTxHashes {
+		record := bchLockedByTxHash[prevTxHash]
+		if record == nil {
+			continue
+		}
+		issue := &RescanIssue{
+			Chain:    RescanChainBch,
+			Height:   h,
+			TxHash:   spend.TxHash,
+			HashLock: record.HashLock,
+			Issue:    "BCH refunded but not recorded",
+		}
+		if repair {
+			record.UpdateStatusToBchRefunded(spend.TxHash)
+			err := bot.db.updateSbch2BchRecord(record)
+			if err != nil {
+				bot.logError("DB error, failed to update status of SBCH2BCH record: ", err)
+			}
+			issue.Repaired = err == nil
+		}
+		return issue
+	}
+	return nil
+}
+
+func (bot *MarketMakerBot) rescanSbchBlocks(fromH, toH uint64, repair bool) (issues []*RescanIssue, err error) {
+	blockBatch := uint64(200)
+	for batchFromH := fromH; batchFromH <= toH; batchFromH += blockBatch {
+		batchToH := batchFromH + blockBatch - 1
+		if batchToH > toH {
+			batchToH = toH
+		}
+
+		log.Infof("rescan sBCH block#%d ~ block#%d", batchFromH, batchToH)
+		logs, err := bot.sbchCli.getHtlcLogs(batchFromH, batchToH)
+		if err != nil {
+			return issues, fmt.Errorf("RPC error, failed to get sBCH logs: %w", err)
+		}
+
+		for _, ethLog := range logs {
+			var issue *RescanIssue
+			switch ethLog.Topics[0] {
+			case htlcsbch.LockEventId:
+				issue = bot.rescanSbchLockEvent(ethLog, repair)
+			case htlcsbch.UnlockEventId:
+				issue = bot.rescanSbchUnlockEvent(ethLog, repair)
+			case htlcsbch.RefundEventId:
+				issue = bot.rescanSbchRefundEvent(ethLog, repair)
+			}
+			if issue != nil {
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return issues, nil
+}
+
+func newSbchRescanIssue(ethLog gethtypes.Log, hashLock gethcmn.Hash, issue string) *RescanIssue {
+	return &RescanIssue{
+		Chain:    RescanChainSbch,
+		Height:   ethLog.BlockNumber,
+		TxHash:   toHex(ethLog.TxHash[:]),
+		HashLock: toHex(hashLock[:]),
+		Issue:    issue,
+	}
+}
+
+func (bot *MarketMakerBot) rescanSbchLockEvent(ethLog gethtypes.Log, repair bool) *RescanIssue {
+	lockLog := htlcsbch.ParseHtlcLockLog(ethLog)
+	if lockLog == nil {
+		return nil
+	}
+	hashLock := toHex(lockLog.HashLock[:])
+
+	if lockLog.UnlockerAddr == bot.sbchAddr {
+		if _, err := bot.db.getSbch2BchRecordByHashLock(hashLock); err == nil || bot.db.isSbch2BchRecordArchived(hashLock) {
+			return nil
+		}
+		issue := newSbchRescanIssue(ethLog, lockLog.HashLock, "SBCH2BCH record not found")
+		if repair {
+			bot.handleSbchLockEventS2B(ethLog)
+			_, err := bot.db.getSbch2BchRecordByHashLock(hashLock)
+			issue.Repaired = err == nil
+		}
+		return issue
+	}
+
+	if bot.isSlaveMode && lockLog.LockerAddr == bot.sbchAddr {
+		record, err := bot.db.getBch2SbchRecordByHashLock(hashLock)
+		if err != nil || record.Status != Bch2SbchStatusNew {
+			return nil
+		}
+		issue := newSbchRescanIssue(ethLog, lockLog.HashLock, "BCH2SBCH record not marked as SbchLocked")
+		if repair {
+			bot.handleSbchLockEventB2S(ethLog)
+			record, err = bot.db.getBch2SbchRecordByHashLock(hashLock)
+			issue.Repaired = err == nil && record.Status == Bch2SbchStatusSbchLocked
+		}
+		return issue
+	}
+	return nil
+}
+
+func (bot *MarketMakerBot) rescanSbchUnlockEvent(ethLog gethtypes.Log, repair bool) *RescanIssue {
+	unlockLog := htlcsbch.ParseHtlcUnlockLog(ethLog)
+	if unlockLog == nil {
+		return nil
+	}
+	hashLock := toHex(unlockLog.HashLock[:])
+	record, err := bot.db.getBch2SbchRecordByHashLock(hashLock)
+	if err != nil || record.Status != Bch2SbchStatusSbchLocked {
+		return nil
+	}
+
+	issue := newSbchRescanIssue(ethLog, unlockLog.HashLock, "secret revealed but not recorded")
+	if repair {
+		bot.handleSbchUnlockEvent(ethLog)
+		record, err = bot.db.getBch2SbchRecordByHashLock(hashLock)
+		issue.Repaired = err == nil && record.Status == Bch2SbchStatusSecretRevealed
+	}
+	return issue
+}
+
+func (bot *MarketMakerBot) rescanSbchRefundEvent(ethLog gethtypes.Log, repair bool) *RescanIssue {
+	refundLog := htlcsbch.ParseHtlcRefundLog(ethLog)
+	if refundLog == nil {
+		return nil
+	}
+	hashLock := toHex(refundLog.HashLock[:])
+	record, err := bot.db.getBch2SbchRecordByHashLock(hashLock)
+	if err != nil || record.Status != Bch2SbchStatusSbchLocked {
+		return nil
+	}
+
+	issue := newSbchRescanIssue(ethLog, refundLog.HashLock, "sBCH refunded but not recorded")
+	if repair {
+		record.UpdateStatusToSbchRefunded(toHex(refundLog.TxHash[:]))
+		err = bot.db.updateBch2SbchRecord(record)
+		if err != nil {
+			bot.logError("DB error, failed to update status of BCH2SBCH record: ", err)
+		}
+		issue.Repaired = err == nil
+	}
+	return issue
+}
+
+// rescan the last blocks before the checkpoints once on startup, so events around
+// a short downtime or a reorg are never lost; events already recorded are skipped,
+// lock tx hashes and hash locks of swaps are both unique in DB so nothing is counted twice
+func (bot *MarketMakerBot) rescanOnStartup() {
+	if bot.startupRescanned {
+		return
+	}
+	bot.startupRescanned = true
+
+	if bot.startupRescanBch > 0 {
+		lastH, err := bot.db.getLastBchHeight()
+		if err != nil {
+			log.Fatal("DB error, failed to get last BCH height: ", err)
+		}
+		bot.rescanWindowOnStartup(RescanChainBch, lastH, uint64(bot.startupRescanBch))
+	}
+	if bot.startupRescanSbch > 0 {
+		lastH, err := bot.db.getLastSbchHeight()
+		if err != nil {
+			log.Fatal("DB error, failed to get last sBCH height: ", err)
+		}
+		bot.rescanWindowOnStartup(RescanChainSbch, lastH, uint64(bot.startupRescanSbch))
+	}
+}
+
+func (bot *MarketMakerBot) rescanWindowOnStartup(chain string, lastH, n uint64) {
+	fromH := uint64(1)
+	if lastH > n {
+		fromH = lastH - n + 1
+	}
+	log.Infof("startup rescan %s block#%d ~ block#%d", chain, fromH, lastH)
+	issues, err := bot.Rescan(chain, fromH, lastH, true)
+	for _, issue := range issues {
+		bot.logWarnf("startup rescan found discrepancy, chain: %s, height: %d, tx: %s, hashLock: %s, issue: %s, repaired: %v",
+			issue.Chain, issue.Height, issue.TxHash, issue.HashLock, issue.Issue, issue.Repaired)
+	}
+	if err != nil {
+		bot.logError("failed to rescan on startup: ", err)
+	}
+}
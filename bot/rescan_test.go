@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+func TestRescan_bch(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_hashLock2 := gethHash32Bytes("hash2")
+	_timeLock := uint16(100)
+	_penaltyBPS := uint16(500)
+	_evmAddr := gethAddrBytes("evm")
+	_bchLockTxHash := bchHash32("bchlocktx")
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, testBchPkh, _hashLock, _timeLock, _penaltyBPS)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+
+	_db := initDB(t, 200, 456)
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    1234567890,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlocktx")),
+		Value:           12345678,
+		SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+		TimeLock:        72000,
+		SbchPrice:       1e8,
+		BchRecipientPkh: toHex(_userPkh),
+		HashLock:        toHex(_hashLock2),
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		BchLockTxHash:   _bchLockTxHash.String(),
+		Status:          Sbch2BchStatusBchLocked,
+	}))
+
+	_bchCli := newMockBchClient(124, 128)
+	// missed deposit
+	_bchCli.blocks[125] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+					{PkScript: newHtlcDepositOpRet(testBchPkh, _userPkh, _hashLock, _timeLock, _penaltyBPS, _evmAddr, 1e8)},
+				},
+			},
+		},
+	}
+	// missed refund
+	_bchCli.blocks[127] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{
+				TxIn:  []*wire.TxIn{{PreviousOutPoint: wire.OutPoint{Hash: _bchLockTxHash}}},
+				TxOut: []*wire.TxOut{{Value: 12345000, PkScript: []byte{0x76}}},
+			},
+		},
+	}
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		bchPkh:       testBchPkh,
+		bchTimeLock:  _timeLock,
+		penaltyRatio: _penaltyBPS,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	_, err = _bot.Rescan(RescanChainBch, 124, 129, false)
+	require.ErrorContains(t, err, "failed to get BCH block#129")
+	_, err = _bot.Rescan("eth", 124, 128, false)
+	require.ErrorContains(t, err, "invalid chain")
+
+	// report only
+	issues, err := _bot.Rescan(RescanChainBch, 124, 128, false)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.Equal(t, uint64(125), issues[0].Height)
+	require.Equal(t, toHex(_hashLock), issues[0].HashLock)
+	require.Equal(t, "BCH2SBCH record not found", issues[0].Issue)
+	require.False(t, issues[0].Repaired)
+	require.Equal(t, uint64(127), issues[1].Height)
+	require.Equal(t, toHex(_hashLock2), issues[1].HashLock)
+	require.Equal(t, "BCH refunded but not recorded", issues[1].Issue)
+	require.False(t, issues[1].Repaired)
+	_, err = _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.Error(t, err)
+
+	// repair
+	issues, err = _bot.Rescan(RescanChainBch, 124, 128, true)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.True(t, issues[0].Repaired)
+	require.True(t, issues[1].Repaired)
+
+	record, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, uint64(125), record.BchLockHeight)
+	require.Equal(t, Bch2SbchStatusNew, record.Status)
+	record2, err := _db.getSbch2BchRecordByHashLock(toHex(_hashLock2))
+	require.NoError(t, err)
+	require.Equal(t, Sbch2BchStatusBchRefunded, record2.Status)
+	require.Equal(t, _bchCli.blocks[127].Transactions[0].TxHash().String(), record2.BchRefundTxHash)
+
+	// nothing to repair
+	issues, err = _bot.Rescan(RescanChainBch, 124, 128, true)
+	require.NoError(t, err)
+	require.Len(t, issues, 0)
+
+	// last heights are not changed
+	lastH, err := _db.getLastBchHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(200), lastH)
+}
+
+func TestRescan_sbch(t *testing.T) {
+	_secret := gethHash32("secret")
+	_hashLock := gethcmn.Hash(sha256.Sum256(_secret[:]))
+	_hashLock2 := gethHash32("hash2")
+
+	_db := initDB(t, 123, 999)
+	for i, hashLock := range []gethcmn.Hash{_hashLock, _hashLock2} {
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockHeight:  122,
+			BchLockTxHash:  toHex(gethHash32Bytes("bchlock" + string(rune('0'+i)))),
+			TimeLock:       100,
+			Value:          12345678,
+			BchPrice:       1e8,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(gethAddrBytes("user")),
+			HashLock:       toHex(hashLock[:]),
+			SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+			SbchLockTxHash: toHex(gethHash32Bytes("sbchlock" + string(rune('0'+i)))),
+			Status:         Bch2SbchStatusSbchLocked,
+		}))
+	}
+
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_sbchCli.logs[458] = []gethtypes.Log{
+		{
+			BlockNumber: 458,
+			Topics:      []gethcmn.Hash{htlcsbch.UnlockEventId, _hashLock, _secret},
+		},
+	}
+	_sbchCli.logs[600] = []gethtypes.Log{
+		{
+			BlockNumber: 600,
+			TxHash:      gethHash32("refundtx"),
+			Topics:      []gethcmn.Hash{htlcsbch.RefundEventId, _hashLock2},
+		},
+	}
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		sbchCli:      _sbchCli,
+		bchPkh:       testBchPkh,
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	issues, err := _bot.Rescan(RescanChainSbch, 457, 999, false)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.Equal(t, "secret revealed but not recorded", issues[0].Issue)
+	require.Equal(t, uint64(458), issues[0].Height)
+	require.Equal(t, "sBCH refunded but not recorded", issues[1].Issue)
+	require.Equal(t, uint64(600), issues[1].Height)
+
+	issues, err = _bot.Rescan(RescanChainSbch, 457, 999, true)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.True(t, issues[0].Repaired)
+	require.True(t, issues[1].Repaired)
+
+	record, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock[:]))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusSecretRevealed, record.Status)
+	require.Equal(t, toHex(_secret[:]), record.Secret)
+	record2, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock2[:]))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusSbchRefunded, record2.Status)
+	require.Equal(t, toHex(gethHash32Bytes("refundtx")), record2.SbchRefundTxHash)
+}
+
+func TestRescanOnStartup(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_timeLock := uint16(100)
+	_penaltyBPS := uint16(500)
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, testBchPkh, _hashLock, _timeLock, _penaltyBPS)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+
+	// the deposit in block#126 was missed, and the scanner checkpoint is block#128
+	_db := initDB(t, 128, 999)
+	_bchCli := newMockBchClient(120, 130)
+	_bchCli.blocks[126] = &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			{
+				TxIn: []*wire.TxIn{},
+				TxOut: []*wire.TxOut{
+					{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+					{PkScript: newHtlcDepositOpRet(testBchPkh, _userPkh, _hashLock, _timeLock, _penaltyBPS, gethAddrBytes("evm"), 1e8)},
+				},
+			},
+		},
+	}
+
+	_bot := &MarketMakerBot{
+		db:               _db,
+		dbQueryLimit:     100,
+		bchCli:           _bchCli,
+		bchPkh:           testBchPkh,
+		bchTimeLock:      _timeLock,
+		penaltyRatio:     _penaltyBPS,
+		bchPrice:         1e8,
+		sbchPrice:        1e8,
+		startupRescanBch: 2,
+		errLogQueue:      newErrLogQueue(100),
+	}
+
+	// block#126 is out of the window
+	_bot.rescanOnStartup()
+	_, err = _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.Error(t, err)
+
+	// only rescan once
+	_bot.startupRescanBch = 3
+	_bot.rescanOnStartup()
+	_, err = _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.Error(t, err)
+
+	_bot.startupRescanned = false
+	_bot.rescanOnStartup()
+	record, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, uint64(126), record.BchLockHeight)
+	require.Len(t, _bot.errLogQueue.removeErrLogs(10), 1)
+
+	// the deposit is not recorded twice
+	_bot.startupRescanned = false
+	_bot.rescanOnStartup()
+	require.Len(t, _bot.errLogQueue.removeErrLogs(10), 0)
+	records, err := _db.GetAllBch2SbchRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	// last heights are not changed
+	lastH, err := _db.getLastBchHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(128), lastH)
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// assets of inventory reservations
+const (
+	AssetBch  = "bch"  // locked by bot for sbch2bch swaps
+	AssetSbch = "sbch" // locked by bot for bch2sbch swaps
+)
+
+var ErrNotEnoughInventory = errors.New("not enough inventory")
+
+// reservations are made by both the API (quotes) and the main loop (deposits)
+var reservationMu sync.Mutex
+
+// reserve the counter-asset (in sats) which bot will lock for the swap until expiresAt,
+// the reservation is released once the swap leaves New status, see DB.updateBch2SbchRecord().
+// SEP20 tokens locked for bch2sbch swaps are not reserved.
+func (bot *MarketMakerBot) reserveForSwap(hashLock, direction, token string, counterVal uint64, expiresAt int64) error {
+	if !bot.reserveInventory || bot.isSlaveMode {
+		return nil
+	}
+	asset := AssetBch
+	if direction == DirectionBch2Sbch {
+		if token != "" {
+			return nil
+		}
+		asset = AssetSbch
+	}
+
+	free, err := bot.getFreeInventory(asset)
+	if err != nil {
+		return fmt.Errorf("failed to get free inventory: %w", err)
+	}
+	return bot.db.reserveInventory(&InventoryReservation{
+		HashLock:  hashLock,
+		Asset:     asset,
+		Amount:    counterVal,
+		ExpiresAt: expiresAt,
+	}, free, time.Now().Unix())
+}
+
+// in sats
+func (bot *MarketMakerBot) getFreeInventory(asset string) (uint64, error) {
+	if asset == AssetSbch {
+		balance, err := bot.sbchCli.getBalance()
+		if err != nil {
+			return 0, err
+		}
+		return weiToSats(balance), nil
+	}
+	freeBch, err := bot.getFreeBch()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(utxoAmtToSats(freeBch)), nil
+}
+
+// deposits are reserved when bot locks for them, under beginLocking(), until it is too late to lock
+func (bot *MarketMakerBot) reserveForBch2SbchRecord(record *Bch2SbchRecord, sbchVal uint64) bool {
+	expiresAt := record.CreatedAt.Unix() + int64(bchTimeLockToSeconds(uint32(bot.bchTimeLock)/3))
+	err := bot.reserveForSwap(record.HashLock, DirectionBch2Sbch, record.Token, sbchVal, expiresAt)
+	if err != nil {
+		log.Infof("failed to reserve sBCH, hashLock: %s, err: %s", record.HashLock, err.Error())
+		return false
+	}
+	return true
+}
+
+func (bot *MarketMakerBot) reserveForSbch2BchRecord(record *Sbch2BchRecord) bool {
+	expiresAt := int64(record.SbchLockTime) + int64(bot.sbchTimeLock/3)
+	bchVal := mulByPrice(record.Value, record.SbchPrice)
+	err := bot.reserveForSwap(record.HashLock, DirectionSbch2Bch, record.Token, bchVal, expiresAt)
+	if err != nil {
+		log.Infof("failed to reserve BCH, hashLock: %s, err: %s", record.HashLock, err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_reserveInventory(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	now := time.Now().Unix()
+
+	res := func(hashLock string, amt uint64, expiresAt int64) *InventoryReservation {
+		return &InventoryReservation{HashLock: hashLock, Asset: AssetSbch, Amount: amt, ExpiresAt: expiresAt}
+	}
+	require.NoError(t, _db.reserveInventory(res("h1", 6e8, now+100), 10e8, now))
+	require.NoError(t, _db.reserveInventory(res("h2", 4e8, now+100), 10e8, now))
+	require.ErrorIs(t, _db.reserveInventory(res("h3", 1, now+100), 10e8, now), ErrNotEnoughInventory)
+
+	// BCH is reserved separately
+	require.NoError(t, _db.reserveInventory(&InventoryReservation{
+		HashLock: "h3", Asset: AssetBch, Amount: 1e8, ExpiresAt: now + 100}, 1e8, now))
+
+	// update own reservation
+	require.NoError(t, _db.reserveInventory(res("h1", 5e8, now+200), 10e8, now))
+	reserved, err := _db.getReservedInventory(AssetSbch, now)
+	require.NoError(t, err)
+	require.Equal(t, uint64(9e8), reserved)
+
+	// expired ones are released
+	reserved, err = _db.getReservedInventory(AssetSbch, now+150)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5e8), reserved)
+	require.NoError(t, _db.reserveInventory(res("h4", 5e8, now+300), 10e8, now+150))
+	reserved, err = _db.getReservedInventory(AssetSbch, now+150)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10e8), reserved)
+}
+
+func TestReservation_releasedOnStatusChange(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:               _db,
+		sbchCli:          newMockSbchClient(457, 459, 0),
+		reserveInventory: true,
+		bchTimeLock:      72,
+	}
+	record := &Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock")),
+		Value:          1e8,
+		BchPrice:       0.99e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(gethHash32Bytes("b2s")),
+		TimeLock:       72,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+	}
+	require.NoError(t, _db.addBch2SbchRecord(record))
+	require.True(t, _bot.reserveForBch2SbchRecord(record, 0.99e8))
+
+	now := time.Now().Unix()
+	reserved, err := _db.getReservedInventory(AssetSbch, now)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0.99e8), reserved)
+
+	record.UpdateStatusToSbchLocked(toHex(gethHash32Bytes("sbchlock")), uint64(now))
+	require.NoError(t, _db.updateBch2SbchRecord(record))
+	reserved, err = _db.getReservedInventory(AssetSbch, now)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), reserved)
+}
+
+func TestMakeQuote_reserveInventory(t *testing.T) {
+	_sbchKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	_sbchCli := newMockSbchClient(457, 459, 0)
+	_sbchCli.balance = satsToWei(1.5e8)
+
+	_bot := &MarketMakerBot{
+		db:               initDB(t, 123, 456),
+		sbchCli:          _sbchCli,
+		bchPkh:           testBchPkh,
+		sbchPrivKey:      _sbchKey,
+		sbchAddr:         gethcrypto.PubkeyToAddress(_sbchKey.PublicKey),
+		bchTimeLock:      100,
+		sbchTimeLock:     36000,
+		penaltyRatio:     500,
+		bchPrice:         0.99e8,
+		sbchPrice:        0.98e8,
+		minSwapVal:       1000,
+		quoteValidity:    600,
+		reserveInventory: true,
+	}
+	newReq := func(hashLock string) *QuoteReq {
+		return &QuoteReq{
+			Direction:     DirectionBch2Sbch,
+			Value:         1e8,
+			HashLock:      toHex(gethHash32Bytes(hashLock)),
+			SenderPkh:     toHex(gethAddrBytes("user")),
+			SenderEvmAddr: toHex(gethAddrBytes("evm")),
+		}
+	}
+
+	quote1, err := _bot.makeQuote(newReq("hash1"))
+	require.NoError(t, err)
+	_, err = _bot.makeQuote(newReq("hash2"))
+	require.ErrorIs(t, err, ErrNotEnoughInventory)
+	_, err = _bot.db.getQuoteByHashLock(newReq("hash2").HashLock)
+	require.Error(t, err) // dropped
+
+	// unexpired quotes are not replaced, nor their reservations
+	_bot.quoteValidity = 1200
+	_, err = _bot.makeQuote(newReq("hash1"))
+	require.ErrorIs(t, err, ErrQuoteExists)
+	reserved, err := _bot.db.getReservedInventory(AssetSbch, quote1.ValidUntil-1)
+	require.NoError(t, err)
+	require.Equal(t, quote1.CounterValue, reserved)
+	reserved, err = _bot.db.getReservedInventory(AssetSbch, quote1.ValidUntil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), reserved)
+
+	_sbchCli.balance = new(big.Int).Mul(satsToWei(1.5e8), big.NewInt(2))
+	_, err = _bot.makeQuote(newReq("hash2"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,215 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/chaincfg/chainhash"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// ReservesReport is a solvency attestation of bot: its reserves on both chains and what it owes
+// to users of in-flight swaps. It is signed by both keys of bot, so anyone can check it is made by
+// the holder of the published addresses, and check the UTXOs and the balance on chain.
+type ReservesReport struct {
+	CreatedAt  int64  `json:"created_at"` // unix seconds
+	BchNet     string `json:"bch_net"`
+	BchAddr    string `json:"bch_addr"`
+	SbchAddr   string `json:"sbch_addr"`
+	BchHeight  int64  `json:"bch_height"`
+	SbchHeight uint64 `json:"sbch_height"`
+
+	// reserves, in sats
+	BchUtxos    []ReservesUtxo `json:"bch_utxos"`
+	BchBalance  uint64         `json:"bch_balance"`
+	SbchBalance uint64         `json:"sbch_balance"`
+
+	Liabilities ReservesLiabilities `json:"liabilities"`
+
+	BchSignature  string `json:"bch_signature,omitempty"`  // base64, Bitcoin signed message of the unsigned report
+	SbchSignature string `json:"sbch_signature,omitempty"` // hex, personal_sign of the unsigned report
+}
+
+type ReservesUtxo struct {
+	TxID  string `json:"txid"`
+	Vout  uint32 `json:"vout"`
+	Value uint64 `json:"value"` // in sats
+}
+
+// in-flight swaps (token swaps are not included), in sats
+type ReservesLiabilities struct {
+	Swaps       int    `json:"swaps"`
+	PendingBch  uint64 `json:"pending_bch"`  // to be locked by bot for accepted sbch2bch deposits, covered by BCH reserves
+	PendingSbch uint64 `json:"pending_sbch"` // to be locked by bot for accepted bch2sbch deposits, covered by sBCH reserves
+	LockedBch   uint64 `json:"locked_bch"`   // locked by bot in covenants, claimable by users
+	LockedSbch  uint64 `json:"locked_sbch"`  // locked by bot in the HTLC contract, claimable by users
+}
+
+// IsSolvent tells if reserves cover the pending liabilities of both assets
+func (report *ReservesReport) IsSolvent() bool {
+	return report.BchBalance >= report.Liabilities.PendingBch &&
+		report.SbchBalance >= report.Liabilities.PendingSbch
+}
+
+// MakeReservesReport queries reserves from both chains and liabilities from DB, and signs the report
+func (bot *MarketMakerBot) MakeReservesReport() (*ReservesReport, error) {
+	if bot.bchPrivKey == nil || bot.sbchPrivKey == nil || bot.isSlaveMode {
+		return nil, fmt.Errorf("the report must be signed by the keys of master bot")
+	}
+	bchAddr, err := bot.getBchNet().NewP2PKHAddress(bot.bchPkh)
+	if err != nil {
+		return nil, err
+	}
+	report := &ReservesReport{
+		CreatedAt: time.Now().Unix(),
+		BchNet:    bot.getBchNet().Name,
+		BchAddr:   bchAddr.String(),
+		SbchAddr:  bot.sbchAddr.Hex(),
+	}
+
+	if report.BchHeight, err = bot.bchCli.GetBlockCount(); err != nil {
+		return nil, fmt.Errorf("failed to get BCH height: %w", err)
+	}
+	utxos, err := bot.bchCli.GetAllUTXOs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get UTXOs: %w", err)
+	}
+	report.BchUtxos = make([]ReservesUtxo, len(utxos))
+	for i, utxo := range utxos {
+		report.BchUtxos[i] = ReservesUtxo{TxID: utxo.TxID, Vout: utxo.Vout, Value: uint64(utxoAmtToSats(utxo.Amount))}
+		report.BchBalance += report.BchUtxos[i].Value
+	}
+	if report.SbchHeight, err = bot.sbchCli.getBlockNumber(); err != nil {
+		return nil, fmt.Errorf("failed to get sBCH height: %w", err)
+	}
+	balance, err := bot.sbchCli.getBalance()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sBCH balance: %w", err)
+	}
+	report.SbchBalance = weiToSats(balance)
+
+	if report.Liabilities, err = bot.getReservesLiabilities(); err != nil {
+		return nil, fmt.Errorf("DB error, failed to get in-flight swaps: %w", err)
+	}
+	if err = bot.signReservesReport(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (bot *MarketMakerBot) getReservesLiabilities() (liabilities ReservesLiabilities, err error) {
+	for _, status := range []Bch2SbchStatus{Bch2SbchStatusNew, Bch2SbchStatusSbchLocked, Bch2SbchStatusSecretRevealed} {
+		records, err := bot.db.getBch2SbchRecordsByStatus(status, -1) // all
+		if err != nil {
+			return liabilities, err
+		}
+		for _, record := range records {
+			if record.Token != "" {
+				continue
+			}
+			liabilities.Swaps++
+			sbchVal := mulByPrice(record.GetFilledValue(), record.BchPrice)
+			if status == Bch2SbchStatusNew {
+				liabilities.PendingSbch += sbchVal
+			} else {
+				liabilities.LockedSbch += sbchVal
+			}
+		}
+	}
+	for _, status := range []Sbch2BchStatus{Sbch2BchStatusNew, Sbch2BchStatusBchLocked, Sbch2BchStatusSecretRevealed} {
+		records, err := bot.db.getSbch2BchRecordsByStatus(status, -1) // all
+		if err != nil {
+			return liabilities, err
+		}
+		for _, record := range records {
+			if record.Token != "" {
+				continue
+			}
+			liabilities.Swaps++
+			bchVal := mulByPrice(record.Value, record.SbchPrice)
+			if status == Sbch2BchStatusNew {
+				liabilities.PendingBch += bchVal
+			} else {
+				liabilities.LockedBch += bchVal
+			}
+		}
+	}
+	return liabilities, nil
+}
+
+func (bot *MarketMakerBot) signReservesReport(report *ReservesReport) error {
+	msg := getReservesReportMsg(report)
+	bchSig, err := bchec.SignCompact(bchec.S256(), bot.bchPrivKey, getBchSignedMsgHash(msg), true)
+	if err != nil {
+		return err
+	}
+	sbchSig, err := gethcrypto.Sign(accounts.TextHash(msg), bot.sbchPrivKey)
+	if err != nil {
+		return err
+	}
+	report.BchSignature = base64.StdEncoding.EncodeToString(bchSig)
+	report.SbchSignature = toHex(sbchSig)
+	return nil
+}
+
+// VerifyReservesReport checks the signatures of report against its BCH and sBCH addresses
+func VerifyReservesReport(report *ReservesReport) error {
+	msg := getReservesReportMsg(report)
+
+	net, err := htlcbch.GetChainParams(report.BchNet)
+	if err != nil {
+		return err
+	}
+	bchSig, err := base64.StdEncoding.DecodeString(report.BchSignature)
+	if err != nil {
+		return fmt.Errorf("invalid BCH signature: %w", err)
+	}
+	pubKey, _, err := bchec.RecoverCompact(bchec.S256(), bchSig, getBchSignedMsgHash(msg))
+	if err != nil {
+		return fmt.Errorf("invalid BCH signature: %w", err)
+	}
+	bchAddr, err := net.NewP2PKHAddress(bchutil.Hash160(pubKey.SerializeCompressed()))
+	if err != nil {
+		return err
+	}
+	if bchAddr.String() != report.BchAddr {
+		return fmt.Errorf("BCH signature is not signed by %s", report.BchAddr)
+	}
+
+	sbchSig := gethcmn.FromHex(report.SbchSignature)
+	sbchPubKey, err := gethcrypto.SigToPub(accounts.TextHash(msg), sbchSig)
+	if err != nil {
+		return fmt.Errorf("invalid sBCH signature: %w", err)
+	}
+	if gethcrypto.PubkeyToAddress(*sbchPubKey) != gethcmn.HexToAddress(report.SbchAddr) {
+		return fmt.Errorf("sBCH signature is not signed by %s", report.SbchAddr)
+	}
+	return nil
+}
+
+// JSON of the report without signatures
+func getReservesReportMsg(report *ReservesReport) []byte {
+	report2 := *report
+	report2.BchSignature = ""
+	report2.SbchSignature = ""
+	bz, _ := json.Marshal(report2)
+	return bz
+}
+
+// the hash signed by "signmessage" of BCH wallets
+func getBchSignedMsgHash(msg []byte) []byte {
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, "Bitcoin Signed Message:\n")
+	_ = wire.WriteVarBytes(&buf, 0, msg)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
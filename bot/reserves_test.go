@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"encoding/json"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestMakeReservesReport(t *testing.T) {
+	_sbchKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	newRecord := newSnapshotTestRecord("hashnew", 1e8)
+	newRecord.Status = Bch2SbchStatusNew
+	newRecord.BchPrice = 0.9e8
+	lockedRecord := newSnapshotTestRecord("hashlocked", 2e8)
+	lockedRecord.BchPrice = 1e8
+	tokenRecord := newSnapshotTestRecord("hashtoken", 3e8)
+	tokenRecord.Token = "T"
+	for _, record := range []*Bch2SbchRecord{newRecord, lockedRecord, tokenRecord} {
+		require.NoError(t, _db.addBch2SbchRecord(record))
+	}
+	require.NoError(t, _db.db.Create(&Sbch2BchRecord{SbchLockTxHash: "s1", HashLock: "hashs2b",
+		Value: 5e7, SbchPrice: 1e8, Status: Sbch2BchStatusBchLocked}).Error)
+	require.NoError(t, _db.db.Create(&Sbch2BchRecord{SbchLockTxHash: "s2", HashLock: "hashs2b2",
+		Value: 4e7, SbchPrice: 0.5e8, Status: Sbch2BchStatusSbchUnlocked}).Error) // finished
+
+	_bchCli := newMockBchClient(124, 125)
+	_bchCli.utxos = []btcjson.ListUnspentResult{
+		{TxID: gethcmn.Hash{'u', '1'}.String(), Vout: 0, Amount: 1.5},
+		{TxID: gethcmn.Hash{'u', '2'}.String(), Vout: 1, Amount: 0.7},
+	}
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_sbchCli.balance = satsToWei(0.8e8)
+	_bot := &MarketMakerBot{
+		db:          _db,
+		bchCli:      _bchCli,
+		sbchCli:     _sbchCli,
+		bchNet:      htlcbch.TestNet3,
+		bchPrivKey:  testBchPrivKey,
+		bchPkh:      testBchPkh,
+		sbchPrivKey: _sbchKey,
+		sbchAddr:    gethcrypto.PubkeyToAddress(_sbchKey.PublicKey),
+	}
+
+	report, err := _bot.MakeReservesReport()
+	require.NoError(t, err)
+	require.Equal(t, "testnet3", report.BchNet)
+	require.Equal(t, int64(125), report.BchHeight)
+	require.Equal(t, uint64(999), report.SbchHeight)
+	require.Len(t, report.BchUtxos, 2)
+	require.Equal(t, uint64(2.2e8), report.BchBalance)
+	require.Equal(t, uint64(0.8e8), report.SbchBalance)
+	require.Equal(t, ReservesLiabilities{
+		Swaps:       3,
+		PendingSbch: 0.9e8,
+		LockedSbch:  2e8,
+		LockedBch:   5e7,
+	}, report.Liabilities)
+	require.False(t, report.IsSolvent()) // 0.8 sBCH < 0.9 sBCH
+	require.NoError(t, VerifyReservesReport(report))
+
+	// round trip through JSON
+	bz, err := json.Marshal(report)
+	require.NoError(t, err)
+	report2 := &ReservesReport{}
+	require.NoError(t, json.Unmarshal(bz, report2))
+	require.NoError(t, VerifyReservesReport(report2))
+
+	// tampered
+	report2.SbchBalance++
+	require.ErrorContains(t, VerifyReservesReport(report2), "BCH signature is not signed by")
+	report2.SbchBalance--
+	sbchSig := gethcmn.FromHex(report2.SbchSignature)
+	sbchSig[64] ^= 1 // recovers another pubkey
+	report2.SbchSignature = toHex(sbchSig)
+	require.ErrorContains(t, VerifyReservesReport(report2), "sBCH signature")
+
+	// slave can not sign
+	_bot.isSlaveMode = true
+	_, err = _bot.MakeReservesReport()
+	require.ErrorContains(t, err, "must be signed by the keys of master bot")
+}
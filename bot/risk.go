@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// outcomes of swaps counted in CounterpartyRisk, also the column names
+const (
+	RiskOutcomeCompleted = "completed"
+	RiskOutcomeRefunded  = "refunded"
+	RiskOutcomeCancelled = "cancelled"
+)
+
+// weights of bad outcomes in risk scores: a refund after bot has locked ties up its inventory
+// for the whole time lock like a chargeback, a cancel before bot locks costs little
+const (
+	riskWeightRefunded  = 3
+	riskWeightCancelled = 1
+)
+
+// Swaps are taken by the engine only if the risk score of each counterparty (the user BCH PKH
+// and EVM address) is not above --max-risk-score, and the BCH value bot has locked for the swaps
+// of them which are not claimed yet, plus the new one, is not above --max-counterparty-exposure.
+// Swaps of risky counterparties are rejected, swaps over the exposure cap are retried next round.
+
+// the outcome of a bch2sbch swap reaching status, empty if it does not tell anything about the user
+func getBch2SbchRiskOutcome(status Bch2SbchStatus) string {
+	switch status {
+	case Bch2SbchStatusSecretRevealed:
+		return RiskOutcomeCompleted
+	case Bch2SbchStatusSbchRefunded:
+		return RiskOutcomeRefunded
+	case Bch2SbchStatusCancelled:
+		return RiskOutcomeCancelled
+	default:
+		return ""
+	}
+}
+
+func getSbch2BchRiskOutcome(status Sbch2BchStatus) string {
+	switch status {
+	case Sbch2BchStatusSecretRevealed:
+		return RiskOutcomeCompleted
+	case Sbch2BchStatusBchRefunded:
+		return RiskOutcomeRefunded
+	case Sbch2BchStatusCancelled:
+		return RiskOutcomeCancelled
+	default:
+		return ""
+	}
+}
+
+// as PKHs and addresses are saved in records: lowercase hex without 0x
+func normalizeRiskHex(s string) string {
+	return strings.ToLower(strings.TrimPrefix(s, "0x"))
+}
+
+// counterparties of a swap, as keys of CounterpartyRisk
+func getCounterparties(userBchPkh, userEvmAddr string) []string {
+	var counterparties []string
+	if pkh := normalizeRiskHex(userBchPkh); pkh != "" {
+		counterparties = append(counterparties, "bch:"+pkh)
+	}
+	if addr := normalizeRiskHex(userEvmAddr); addr != "" {
+		counterparties = append(counterparties, "evm:"+addr)
+	}
+	return counterparties
+}
+
+func (r *CounterpartyRisk) addOutcome(outcome string) {
+	switch outcome {
+	case RiskOutcomeCompleted:
+		r.Completed++
+	case RiskOutcomeRefunded:
+		r.Refunded++
+	case RiskOutcomeCancelled:
+		r.Cancelled++
+	}
+}
+
+// Score is in [0, 100], the weighted share of bad outcomes, 0 if there is no history
+func (r *CounterpartyRisk) Score() uint32 {
+	total := r.Completed + r.Refunded + r.Cancelled
+	if total == 0 {
+		return 0
+	}
+	bad := riskWeightRefunded*r.Refunded + riskWeightCancelled*r.Cancelled
+	return 100 * bad / (riskWeightRefunded * total)
+}
+
+// BCH value (in sats) bot has locked for the swaps of a counterparty which are not claimed yet
+func (bot *MarketMakerBot) getCounterpartyExposure(userBchPkh, userEvmAddr string) (uint64, error) {
+	b2sRecords, s2bRecords, err := bot.db.getInFlightRecordsOf(
+		normalizeRiskHex(userBchPkh), normalizeRiskHex(userEvmAddr))
+	if err != nil {
+		return 0, err
+	}
+	exposure := uint64(0)
+	for _, record := range b2sRecords {
+		exposure += record.GetFilledValue() // sBCH|tokens of the same value
+	}
+	for _, record := range s2bRecords {
+		exposure += mulByPrice(record.Value, record.SbchPrice)
+	}
+	return exposure, nil
+}
+
+// called before bot locks for a swap, like swap hooks
+func (bot *MarketMakerBot) checkCounterpartyRisk(action *SwapAction) error {
+	if bot.maxRiskScore > 0 {
+		risks, err := bot.db.getCounterpartyRisks(getCounterparties(action.UserBchPkh, action.UserEvmAddr))
+		if err != nil {
+			return fmt.Errorf("%w, failed to get risk scores: %s", ErrSwapHookRetry, err.Error())
+		}
+		for _, risk := range risks {
+			if score := risk.Score(); score > bot.maxRiskScore {
+				err = fmt.Errorf("risk score of %s is too high: %d > %d", risk.Counterparty, score, bot.maxRiskScore)
+				bot.logWarnf("swap rejected, hashLock: %s, reason: %s", action.HashLock, err)
+				return err
+			}
+		}
+	}
+
+	if bot.maxCounterpartyExposure > 0 {
+		exposure, err := bot.getCounterpartyExposure(action.UserBchPkh, action.UserEvmAddr)
+		if err != nil {
+			return fmt.Errorf("%w, failed to get exposure: %s", ErrSwapHookRetry, err.Error())
+		}
+		swapExposure := action.Value
+		if action.Direction == DirectionSbch2Bch {
+			swapExposure = mulByPrice(action.Value, action.Price)
+		}
+		if exposure+swapExposure > bot.maxCounterpartyExposure {
+			log.Infof("exposure to counterparty is capped, hashLock: %s, exposure: %d + %d > %d",
+				action.HashLock, exposure, swapExposure, bot.maxCounterpartyExposure)
+			return fmt.Errorf("%w, exposure to counterparty is capped", ErrSwapHookRetry)
+		}
+	}
+	return nil
+}
+
+type CounterpartyRiskInfo struct {
+	Counterparty string `json:"counterparty"`
+	Completed    uint32 `json:"completed"`
+	Refunded     uint32 `json:"refunded"`
+	Cancelled    uint32 `json:"cancelled"`
+	Score        uint32 `json:"score"`
+}
+
+type CounterpartyRisksInfo struct {
+	Risks    []*CounterpartyRiskInfo `json:"risks"`
+	Exposure uint64                  `json:"exposure"` // in sats, locked by bot for unclaimed swaps
+}
+
+// return risk scores and exposure of a counterparty given by its BCH PKH and/or EVM address
+func (bot *MarketMakerBot) handleCounterpartyRisk(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleReader, false) {
+		return
+	}
+	bchPkh := r.URL.Query().Get("bch_pkh")
+	evmAddr := r.URL.Query().Get("evm_addr")
+	counterparties := getCounterparties(bchPkh, evmAddr)
+	if len(counterparties) == 0 {
+		NewErrResp("missing bch_pkh or evm_addr").WriteTo(w)
+		return
+	}
+	risks, err := bot.db.getCounterpartyRisks(counterparties)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	exposure, err := bot.getCounterpartyExposure(bchPkh, evmAddr)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	info := &CounterpartyRisksInfo{Exposure: exposure}
+	for _, risk := range risks {
+		info.Risks = append(info.Risks, &CounterpartyRiskInfo{
+			Counterparty: risk.Counterparty,
+			Completed:    risk.Completed,
+			Refunded:     risk.Refunded,
+			Cancelled:    risk.Cancelled,
+			Score:        risk.Score(),
+		})
+	}
+	NewOkResp(info).WriteTo(w)
+}
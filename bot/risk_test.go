@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterpartyRiskScore(t *testing.T) {
+	require.Equal(t, uint32(0), (&CounterpartyRisk{}).Score())
+	require.Equal(t, uint32(0), (&CounterpartyRisk{Completed: 5}).Score())
+	require.Equal(t, uint32(100), (&CounterpartyRisk{Refunded: 2}).Score())
+	require.Equal(t, uint32(33), (&CounterpartyRisk{Cancelled: 2}).Score())
+	require.Equal(t, uint32(50), (&CounterpartyRisk{Completed: 1, Refunded: 1}).Score())
+	require.Equal(t, uint32(37), (&CounterpartyRisk{Completed: 3, Refunded: 2, Cancelled: 3}).Score())
+}
+
+func TestGetCounterparties(t *testing.T) {
+	require.Len(t, getCounterparties("", ""), 0)
+	require.Equal(t, []string{"bch:abcd", "evm:ef01"}, getCounterparties("0xABCD", "ef01"))
+	require.Equal(t, []string{"evm:ef01"}, getCounterparties("", "0xEF01"))
+}
+
+func TestCountRiskOutcome(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	userPkh := toHex(gethAddrBytes("ubch"))
+	userAddr := toHex(gethAddrBytes("uevm"))
+
+	addB2S := func(name string) *Bch2SbchRecord {
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockTxHash:  toHex(gethHash32Bytes(name)),
+			BchLockHeight:  100,
+			RecipientPkh:   toHex(testBchPkh),
+			TimeLock:       72,
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+			Value:          12345678,
+			SenderPkh:      userPkh,
+			SenderEvmAddr:  userAddr,
+			HashLock:       toHex(gethHash32Bytes(name)),
+		}))
+		record, err := _db.getBch2SbchRecordByHashLock(toHex(gethHash32Bytes(name)))
+		require.NoError(t, err)
+		return record
+	}
+
+	record := addB2S("s1")
+	record.Status = Bch2SbchStatusSbchLocked
+	record.SbchLockTxHash = "lock1"
+	require.NoError(t, _db.updateBch2SbchRecord(record))
+	record.Status = Bch2SbchStatusSecretRevealed
+	record.Secret = "secret1"
+	record.SbchUnlockTxHash = "unlock1"
+	require.NoError(t, _db.updateBch2SbchRecord(record))
+	require.NoError(t, _db.updateBch2SbchRecord(record)) // status unchanged, not counted again
+
+	record = addB2S("s2")
+	record.Status = Bch2SbchStatusSbchLocked
+	record.SbchLockTxHash = "lock2"
+	require.NoError(t, _db.updateBch2SbchRecord(record))
+	record.Status = Bch2SbchStatusSbchRefunded
+	record.SbchRefundTxHash = "refund2"
+	require.NoError(t, _db.updateBch2SbchRecord(record))
+
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTxHash:  "s3",
+		SbchLockTime:    1,
+		TimeLock:        36000,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		Value:           12345678,
+		SbchSenderAddr:  userAddr,
+		BchRecipientPkh: toHex(gethAddrBytes("other")),
+		HashLock:        toHex(gethHash32Bytes("s3")),
+	}))
+	s2bRecord, err := _db.getSbch2BchRecordByHashLock(toHex(gethHash32Bytes("s3")))
+	require.NoError(t, err)
+	s2bRecord.Status = Sbch2BchStatusCancelled
+	require.NoError(t, _db.updateSbch2BchRecord(s2bRecord))
+
+	risks, err := _db.getCounterpartyRisks(getCounterparties(userPkh, userAddr))
+	require.NoError(t, err)
+	require.Len(t, risks, 2)
+	for _, risk := range risks {
+		if risk.Counterparty == "bch:"+userPkh {
+			require.Equal(t, [3]uint32{1, 1, 0}, [3]uint32{risk.Completed, risk.Refunded, risk.Cancelled})
+		} else {
+			require.Equal(t, "evm:"+userAddr, risk.Counterparty)
+			require.Equal(t, [3]uint32{1, 1, 1}, [3]uint32{risk.Completed, risk.Refunded, risk.Cancelled})
+		}
+	}
+}
+
+func TestCheckCounterpartyRisk(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	userPkh := toHex(gethAddrBytes("ubch"))
+	userAddr := toHex(gethAddrBytes("uevm"))
+	_bot := &MarketMakerBot{
+		db:          _db,
+		errLogQueue: newErrLogQueue(100),
+	}
+	action := &SwapAction{
+		Direction:   DirectionBch2Sbch,
+		HashLock:    toHex(gethHash32Bytes("new")),
+		Value:       3000,
+		Price:       1e8,
+		UserBchPkh:  userPkh,
+		UserEvmAddr: userAddr,
+	}
+
+	// disabled
+	require.NoError(t, _bot.checkCounterpartyRisk(action))
+
+	// risk score
+	_bot.maxRiskScore = 40
+	require.NoError(t, _db.db.Create(&CounterpartyRisk{Counterparty: "evm:" + userAddr, Completed: 2, Refunded: 1}).Error)
+	require.NoError(t, _bot.checkCounterpartyRisk(action))
+	require.NoError(t, _db.db.Model(&CounterpartyRisk{}).Where("counterparty = ?", "evm:"+userAddr).
+		Update("refunded", 3).Error)
+	err := _bot.checkCounterpartyRisk(action)
+	require.ErrorContains(t, err, "risk score of evm:"+userAddr+" is too high: 60 > 40")
+	require.False(t, errors.Is(err, ErrSwapHookRetry))
+	require.Len(t, _bot.errLogQueue.removeErrLogs(10), 1)
+	_bot.maxRiskScore = 0
+
+	// exposure
+	_bot.maxCounterpartyExposure = 10000
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockTxHash:  "b2s",
+		BchLockHeight:  100,
+		RecipientPkh:   toHex(testBchPkh),
+		TimeLock:       72,
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		Value:          5000,
+		SenderPkh:      userPkh,
+		SenderEvmAddr:  toHex(gethAddrBytes("other")),
+		HashLock:       toHex(gethHash32Bytes("b2s")),
+		Status:         Bch2SbchStatusSbchLocked,
+	}))
+	require.NoError(t, _bot.checkCounterpartyRisk(action))
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTxHash:  "s2b",
+		SbchLockTime:    1,
+		TimeLock:        36000,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		Value:           4000,
+		SbchPrice:       5e7,
+		SbchSenderAddr:  userAddr,
+		BchRecipientPkh: toHex(gethAddrBytes("other")),
+		HashLock:        toHex(gethHash32Bytes("s2b")),
+		Status:          Sbch2BchStatusBchLocked,
+	}))
+	require.NoError(t, _bot.checkCounterpartyRisk(action)) // 5000 + 2000 + 3000
+	action.Value = 3001
+	err = _bot.checkCounterpartyRisk(action)
+	require.ErrorContains(t, err, "exposure to counterparty is capped")
+	require.True(t, errors.Is(err, ErrSwapHookRetry))
+
+	// API
+	_bot.adminToken = "secret"
+	req := httptest.NewRequest("GET", "/admin/counterparty-risk?evm_addr=0x"+userAddr, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	_bot.handleCounterpartyRisk(w, req)
+	var resp struct {
+		Success bool                  `json:"success"`
+		Result  CounterpartyRisksInfo `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(t, resp.Success)
+	require.Equal(t, uint64(2000), resp.Result.Exposure)
+	require.Len(t, resp.Result.Risks, 1)
+	require.Equal(t, uint32(60), resp.Result.Risks[0].Score)
+}
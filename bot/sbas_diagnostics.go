@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// what to do with BCH txs carrying the SBAS protocol ID, see htlcbch.SbasTx
+const (
+	SbasDiagnosticsOff  = "off"
+	SbasDiagnosticsLog  = "log"  // log each tx with its payload, and the reason if it is not a valid deposit
+	SbasDiagnosticsSave = "save" // also save them in DB
+)
+
+func checkSbasDiagnostics(mode string) error {
+	switch mode {
+	case "", SbasDiagnosticsOff, SbasDiagnosticsLog, SbasDiagnosticsSave:
+		return nil
+	default:
+		return fmt.Errorf("invalid SBAS diagnostics mode: %s", mode)
+	}
+}
+
+type SbasTxInfo struct {
+	TxHash  string   `json:"tx_hash"`
+	Height  uint64   `json:"height"`
+	Vout    uint32   `json:"vout"`
+	Payload []string `json:"payload"` // hex pushes after the protocol ID
+	Valid   bool     `json:"valid"`
+	Reason  string   `json:"reason,omitempty"`
+	RawTx   string   `json:"raw_tx"`
+	FoundAt int64    `json:"found_at"` // unix timestamp
+}
+
+func (bot *MarketMakerBot) sbasDiagnosticsEnabled() bool {
+	return bot.sbasDiagnostics == SbasDiagnosticsLog || bot.sbasDiagnostics == SbasDiagnosticsSave
+}
+
+func formatSbasPayload(payload []hexutil.Bytes) string {
+	pushes := make([]string, len(payload))
+	for i, data := range payload {
+		pushes[i] = toHex(data)
+	}
+	return strings.Join(pushes, " ")
+}
+
+// empty pushes (e.g. the memo followed by referral args) are kept
+func parseSbasPayload(payload string) []string {
+	if payload == "" {
+		return nil
+	}
+	return strings.Split(payload, " ")
+}
+
+// log txs carrying the SBAS protocol ID, and save them with --sbas-diagnostics=save, so that malformed
+// deposits made by third-party tooling are visible with the exact reason rather than silently dropped
+func (bot *MarketMakerBot) logSbasTxs(h uint64, sbasTxs []*htlcbch.SbasTx) bool {
+	for _, sbasTx := range sbasTxs {
+		payload := formatSbasPayload(sbasTx.Payload)
+		if sbasTx.Deposit != nil {
+			log.Infof("SBAS tx: %s, output#%d, valid deposit, payload: %s", sbasTx.TxHash, sbasTx.Vout, payload)
+		} else {
+			log.Warnf("SBAS tx: %s, output#%d, invalid: %s, payload: %s", sbasTx.TxHash, sbasTx.Vout, sbasTx.Reason, payload)
+		}
+		if bot.sbasDiagnostics != SbasDiagnosticsSave {
+			continue
+		}
+		err := bot.db.addSbasBchTx(&SbasBchTx{
+			TxHash:  sbasTx.TxHash,
+			Height:  h,
+			Vout:    sbasTx.Vout,
+			Payload: payload,
+			Valid:   sbasTx.Deposit != nil,
+			Reason:  sbasTx.Reason,
+			RawTx:   sbasTx.RawTx,
+		})
+		if err != nil {
+			bot.logError("DB error, failed to save SBAS tx: ", err)
+			return false
+		}
+	}
+	return true
+}
+
+// return the latest saved SBAS txs
+func (bot *MarketMakerBot) handleSbasTxs(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleReader, false) {
+		return
+	}
+	n := getIntQueryParam(r, "n", 100)
+	if n <= 0 || n > bot.dbQueryLimit {
+		n = bot.dbQueryLimit
+	}
+	sbasTxs, err := bot.db.getSbasBchTxs(getIntQueryParam(r, "invalid", 0) == 1, n)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	infos := make([]*SbasTxInfo, len(sbasTxs))
+	for i, sbasTx := range sbasTxs {
+		infos[i] = &SbasTxInfo{
+			TxHash:  sbasTx.TxHash,
+			Height:  sbasTx.Height,
+			Vout:    sbasTx.Vout,
+			Payload: parseSbasPayload(sbasTx.Payload),
+			Valid:   sbasTx.Valid,
+			Reason:  sbasTx.Reason,
+			RawTx:   sbasTx.RawTx,
+			FoundAt: sbasTx.CreatedAt.Unix(),
+		}
+	}
+	NewOkResp(infos).WriteTo(w)
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestSbasBchTxs(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:              _db,
+		adminToken:      "secret",
+		dbQueryLimit:    100,
+		sbasDiagnostics: SbasDiagnosticsLog,
+		errLogQueue:     newErrLogQueue(100),
+	}
+	require.True(t, _bot.getDepositFilter().Diagnose)
+
+	sbasTxs := []*htlcbch.SbasTx{
+		{TxHash: "tx1", Vout: 1, Payload: []hexutil.Bytes{{0x12, 0x34}, {}, {0x56}},
+			Deposit: &htlcbch.HtlcLockInfo{}, RawTx: "raw1"},
+		{TxHash: "tx2", Vout: 2, Payload: []hexutil.Bytes{{0xab}},
+			Reason: "malformed SBAS OP_RETURN: invalid number of pushes: 2", RawTx: "raw2"},
+	}
+	// only logged
+	require.True(t, _bot.logSbasTxs(123, sbasTxs))
+	saved, err := _db.getSbasBchTxs(false, 100)
+	require.NoError(t, err)
+	require.Len(t, saved, 0)
+
+	_bot.sbasDiagnostics = SbasDiagnosticsSave
+	require.True(t, _bot.logSbasTxs(123, sbasTxs))
+	// found again
+	require.True(t, _bot.logSbasTxs(124, sbasTxs[:1]))
+
+	getSbasTxs := func(query string) []*SbasTxInfo {
+		req := httptest.NewRequest("GET", "/admin/sbas-txs"+query, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		_bot.handleSbasTxs(w, req)
+		var resp struct {
+			Success bool          `json:"success"`
+			Result  []*SbasTxInfo `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.True(t, resp.Success)
+		return resp.Result
+	}
+
+	infos := getSbasTxs("")
+	require.Len(t, infos, 2)
+	require.Equal(t, "tx2", infos[0].TxHash)
+	require.False(t, infos[0].Valid)
+	require.Equal(t, "malformed SBAS OP_RETURN: invalid number of pushes: 2", infos[0].Reason)
+	require.Equal(t, uint32(2), infos[0].Vout)
+	require.True(t, infos[1].Valid)
+	require.Equal(t, uint64(123), infos[1].Height)
+	require.Equal(t, []string{"1234", "", "56"}, infos[1].Payload)
+	infos = getSbasTxs("?invalid=1")
+	require.Len(t, infos, 1)
+	require.Equal(t, "tx2", infos[0].TxHash)
+
+	n, err := _db.pruneSbasBchTxs(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	require.NoError(t, checkSbasDiagnostics(SbasDiagnosticsSave))
+	require.Error(t, checkSbasDiagnostics("all"))
+	_bot.sbasDiagnostics = SbasDiagnosticsOff
+	require.False(t, _bot.getDepositFilter().Diagnose)
+}
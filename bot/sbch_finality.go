@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+type sbchEventFinality int
+
+const (
+	sbchEventFinal      sbchEventFinality = iota
+	sbchEventPending                      // not enough confirmations yet
+	sbchEventRolledBack                   // the log was removed by a reorg
+)
+
+// confirmations required before the given kind of EVM event is treated as final
+func (bot *MarketMakerBot) getSbchEventConfirmations(kind string) uint64 {
+	confs := bot.sbchConfirmations
+	if kind == EventSbchLock && bot.sbchLockConfs > 0 {
+		confs = bot.sbchLockConfs
+	}
+	if kind == EventSbchUnlock && bot.sbchUnlockConfs > 0 {
+		confs = bot.sbchUnlockConfs
+	}
+	return uint64(confs)
+}
+
+// EVM logs are scanned with the least confirmations of all actions,
+// events which need more confirmations are deferred by the event bus
+func (bot *MarketMakerBot) getSbchScanConfirmations() uint64 {
+	lockConfs := bot.getSbchEventConfirmations(EventSbchLock)
+	unlockConfs := bot.getSbchEventConfirmations(EventSbchUnlock)
+	if lockConfs < unlockConfs {
+		return lockConfs
+	}
+	return unlockConfs
+}
+
+func (bot *MarketMakerBot) getSbchLatestHeight() (uint64, error) {
+	if bot.sbchLatestHeight > 0 {
+		return bot.sbchLatestHeight, nil
+	}
+	h, err := bot.sbchCli.getBlockNumber()
+	if err == nil {
+		bot.sbchLatestHeight = h
+	}
+	return h, err
+}
+
+func (bot *MarketMakerBot) checkSbchEventFinality(event *SwapEvent) sbchEventFinality {
+	confs := bot.getSbchEventConfirmations(event.Kind)
+	if confs == 0 {
+		return sbchEventFinal
+	}
+	latestH, err := bot.getSbchLatestHeight()
+	if err != nil {
+		bot.logError("failed to get height of smartBCH: ", err)
+		return sbchEventPending
+	}
+	if event.Height+confs > latestH {
+		log.Infof("event#%d needs %d confirmations, height: %d, latest: %d",
+			event.ID, confs, event.Height, latestH)
+		return sbchEventPending
+	}
+	if event.Kind == EventSbchUnlock && bot.hasDeferredSbchLock(event) {
+		// the lock must be handled first
+		return sbchEventPending
+	}
+	if confs <= bot.getSbchScanConfirmations() {
+		// the log was already this deep when scanned
+		return sbchEventFinal
+	}
+
+	// the log was scanned with fewer confirmations, make sure it is still there
+	var ethLog gethtypes.Log
+	if err = json.Unmarshal([]byte(event.Payload), &ethLog); err != nil {
+		return sbchEventFinal // let handleEvent report it
+	}
+	receipt, err := bot.sbchCli.getTxReceipt(ethLog.TxHash)
+	if errors.Is(err, ethereum.NotFound) {
+		bot.rollbackSbchEvent(event)
+		return sbchEventRolledBack
+	}
+	if err != nil {
+		bot.logError("failed to get smartBCH tx receipt: ", err)
+		return sbchEventPending
+	}
+	if receipt.BlockHash == ethLog.BlockHash {
+		return sbchEventFinal
+	}
+
+	// the tx was packed into another block
+	log.Warnf("event#%d moved from block#%d to block#%d", event.ID, event.Height, receipt.BlockNumber.Uint64())
+	ethLog.BlockNumber = receipt.BlockNumber.Uint64()
+	ethLog.BlockHash = receipt.BlockHash
+	event.Height = ethLog.BlockNumber
+	event.Payload = toJSON(ethLog)
+	return bot.checkSbchEventFinality(event)
+}
+
+func (bot *MarketMakerBot) hasDeferredSbchLock(unlockEvent *SwapEvent) bool {
+	var unlockLog gethtypes.Log
+	if err := json.Unmarshal([]byte(unlockEvent.Payload), &unlockLog); err != nil {
+		return false
+	}
+	parsedUnlock := htlcsbch.ParseHtlcUnlockLog(unlockLog)
+	if parsedUnlock == nil {
+		return false
+	}
+	for _, event := range bot.getEventBus().deferred {
+		if event.Kind != EventSbchLock {
+			continue
+		}
+		var lockLog gethtypes.Log
+		if err := json.Unmarshal([]byte(event.Payload), &lockLog); err != nil {
+			continue
+		}
+		if parsedLock := htlcsbch.ParseHtlcLockLog(lockLog); parsedLock != nil &&
+			parsedLock.HashLock == parsedUnlock.HashLock {
+			return true
+		}
+	}
+	return false
+}
+
+// forget the event and rescan from its height, like BCH reorgs,
+// so that it is published again if the tx is packed into another block
+func (bot *MarketMakerBot) rollbackSbchEvent(event *SwapEvent) {
+	log.Warnf("event#%d (block#%d) is rolled back, kind: %s, key: %s",
+		event.ID, event.Height, event.Kind, event.Key)
+	if err := bot.db.deleteSwapEvent(event.ID); err != nil {
+		bot.logError("DB error, failed to delete event: ", err)
+		return
+	}
+	lastH, err := bot.db.getLastSbchHeight()
+	if err != nil {
+		bot.logError("DB error, failed to get last sBCH height: ", err)
+		return
+	}
+	if event.Height > 0 && event.Height-1 < lastH {
+		if err = bot.db.setLastSbchHeight(event.Height - 1); err != nil {
+			bot.logError("DB error, failed to update last sBCH height: ", err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+func TestSbchEventConfirmations(t *testing.T) {
+	_bot := &MarketMakerBot{sbchConfirmations: 2}
+	require.Equal(t, uint64(2), _bot.getSbchEventConfirmations(EventSbchLock))
+	require.Equal(t, uint64(2), _bot.getSbchEventConfirmations(EventSbchUnlock))
+	require.Equal(t, uint64(2), _bot.getSbchScanConfirmations())
+
+	_bot.sbchLockConfs = 10
+	require.Equal(t, uint64(10), _bot.getSbchEventConfirmations(EventSbchLock))
+	require.Equal(t, uint64(2), _bot.getSbchEventConfirmations(EventSbchUnlock))
+	require.Equal(t, uint64(2), _bot.getSbchScanConfirmations())
+
+	_bot.sbchUnlockConfs = 1
+	require.Equal(t, uint64(1), _bot.getSbchEventConfirmations(EventSbchUnlock))
+	require.Equal(t, uint64(1), _bot.getSbchScanConfirmations())
+}
+
+func newSbchFinalityTestBot(t *testing.T) (*MarketMakerBot, *MockSbchClient, gethtypes.Log) {
+	_secret := gethHash32("secret")
+	_hashLock := sha256.Sum256(_secret[:])
+
+	_db := initDB(t, 123, 456)
+	require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+		BchLockHeight:  122,
+		BchLockTxHash:  toHex(gethHash32Bytes("bchlock")),
+		Value:          12345678,
+		BchPrice:       1e8,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       toHex(_hashLock[:]),
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		SbchLockTxHash: toHex(gethHash32Bytes("sbchlock")),
+		Status:         Bch2SbchStatusSbchLocked,
+	}))
+
+	unlockLog := gethtypes.Log{
+		Topics:      []gethcmn.Hash{htlcsbch.UnlockEventId, _hashLock, _secret},
+		BlockNumber: 458,
+		BlockHash:   gethHash32("block458"),
+		TxHash:      gethHash32("sbchunlock"),
+	}
+	_sbchCli := newMockSbchClient(457, 460, 0)
+	_sbchCli.logs[458] = []gethtypes.Log{unlockLog}
+
+	_bot := &MarketMakerBot{
+		db:              _db,
+		dbQueryLimit:    100,
+		sbchCli:         _sbchCli,
+		bchPkh:          testBchPkh,
+		bchPrice:        1e8,
+		sbchPrice:       1e8,
+		sbchUnlockConfs: 5,
+		errLogQueue:     newErrLogQueue(100),
+	}
+	return _bot, _sbchCli, unlockLog
+}
+
+func requireBch2SbchStatus(t *testing.T, _db DB, status Bch2SbchStatus) {
+	records, err := _db.getBch2SbchRecordsByStatus(status, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
+
+func TestSbchEventFinality_deferred(t *testing.T) {
+	_bot, _sbchCli, _ := newSbchFinalityTestBot(t)
+
+	// scanned without confirmations, but handled after 5
+	_bot.scanSbchEvents()
+	requireBch2SbchStatus(t, _bot.db, Bch2SbchStatusSbchLocked)
+	events, err := _bot.db.getUnhandledSwapEvents(100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Len(t, _bot.getEventBus().deferred, 1)
+
+	_sbchCli.hTo = 462
+	_bot.scanSbchEvents()
+	requireBch2SbchStatus(t, _bot.db, Bch2SbchStatusSbchLocked)
+
+	_sbchCli.hTo = 463
+	_bot.scanSbchEvents()
+	requireBch2SbchStatus(t, _bot.db, Bch2SbchStatusSecretRevealed)
+	events, err = _bot.db.getUnhandledSwapEvents(100)
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+	require.Len(t, _bot.getEventBus().deferred, 0)
+}
+
+func TestSbchEventFinality_rolledBack(t *testing.T) {
+	_bot, _sbchCli, unlockLog := newSbchFinalityTestBot(t)
+	_bot.scanSbchEvents()
+	require.Len(t, _bot.getEventBus().deferred, 1)
+
+	// the tx is dropped by a reorg
+	delete(_sbchCli.logs, 458)
+	_sbchCli.hTo = 463
+	_bot.scanSbchEvents()
+	requireBch2SbchStatus(t, _bot.db, Bch2SbchStatusSbchLocked)
+	events, err := _bot.db.getUnhandledSwapEvents(100)
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+	lastH, err := _bot.db.getLastSbchHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(457), lastH)
+
+	// and packed again into another block
+	unlockLog.BlockNumber = 461
+	unlockLog.BlockHash = gethHash32("block461")
+	_sbchCli.logs[461] = []gethtypes.Log{unlockLog}
+	_bot.scanSbchEvents()
+	requireBch2SbchStatus(t, _bot.db, Bch2SbchStatusSbchLocked)
+
+	_sbchCli.hTo = 466
+	_bot.scanSbchEvents()
+	requireBch2SbchStatus(t, _bot.db, Bch2SbchStatusSecretRevealed)
+}
+
+func TestSbchEventFinality_moved(t *testing.T) {
+	_bot, _sbchCli, unlockLog := newSbchFinalityTestBot(t)
+	_bot.scanSbchEvents()
+	require.Len(t, _bot.getEventBus().deferred, 1)
+
+	// the tx is packed into block#460 after a reorg, the log is not scanned again
+	unlockLog.BlockNumber = 460
+	unlockLog.BlockHash = gethHash32("block460")
+	_sbchCli.logs[458] = nil
+	_sbchCli.logs[460] = []gethtypes.Log{unlockLog}
+	_sbchCli.hTo = 463
+	_bot.scanSbchEvents()
+	requireBch2SbchStatus(t, _bot.db, Bch2SbchStatusSbchLocked)
+	require.Len(t, _bot.getEventBus().deferred, 1)
+
+	_sbchCli.hTo = 465
+	_bot.scanSbchEvents()
+	requireBch2SbchStatus(t, _bot.db, Bch2SbchStatusSecretRevealed)
+}
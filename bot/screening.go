@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const screeningTimeout = 10 * time.Second
+
+type ScreeningReq struct {
+	Direction string `json:"direction"` // bch2sbch|sbch2bch
+	HashLock  string `json:"hash_lock"`
+	EvmAddr   string `json:"evm_addr"`
+	BchPkh    string `json:"bch_pkh"`
+}
+
+type ScreeningResp struct {
+	Flagged bool   `json:"flagged"`
+	Reason  string `json:"reason"`
+}
+
+// ScreeningHook asks a screening service (a compliance API, or a local allow/deny list service)
+// about the counterparty before the bot locks anything for it, flagged counterparties are rejected.
+// The service is POSTed a ScreeningReq and replies a ScreeningResp. If it can not be reached,
+// the swap is retried next round, until it is too late to lock.
+type ScreeningHook struct {
+	NopSwapHook
+	url    string
+	client *http.Client
+}
+
+func newScreeningHook(screeningUrl string) (*ScreeningHook, error) {
+	u, err := url.Parse(screeningUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid screening URL: %s", screeningUrl)
+	}
+	return &ScreeningHook{
+		url:    screeningUrl,
+		client: &http.Client{Timeout: screeningTimeout},
+	}, nil
+}
+
+func (h *ScreeningHook) BeforeLock(action *SwapAction) (string, error) {
+	resp, err := h.screen(&ScreeningReq{
+		Direction: action.Direction,
+		HashLock:  action.HashLock,
+		EvmAddr:   action.UserEvmAddr,
+		BchPkh:    action.UserBchPkh,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w, screening failed: %s", ErrSwapHookRetry, err.Error())
+	}
+	if resp.Flagged {
+		return "", fmt.Errorf("counterparty flagged by screening, evmAddr: %s, bchPkh: %s, reason: %s",
+			action.UserEvmAddr, action.UserBchPkh, resp.Reason)
+	}
+	log.Infof("counterparty passed screening, hashLock: %s, evmAddr: %s, bchPkh: %s",
+		action.HashLock, action.UserEvmAddr, action.UserBchPkh)
+	return "screened", nil
+}
+
+func (h *ScreeningHook) screen(req *ScreeningReq) (*ScreeningResp, error) {
+	body, _ := json.Marshal(req)
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	result := &ScreeningResp{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result, nil
+}
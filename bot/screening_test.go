@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScreeningHook(t *testing.T) {
+	_, err := newScreeningHook("tcp://127.0.0.1:8080")
+	require.ErrorContains(t, err, "invalid screening URL")
+	_, err = newScreeningHook("https://screening.example.com/v1/check")
+	require.NoError(t, err)
+}
+
+func TestScreeningHook(t *testing.T) {
+	var reqs []ScreeningReq
+	down := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req ScreeningReq
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		reqs = append(reqs, req)
+		_ = json.NewEncoder(w).Encode(ScreeningResp{
+			Flagged: req.EvmAddr == "0xbad",
+			Reason:  "sanctioned",
+		})
+	}))
+	defer server.Close()
+
+	hook, err := newScreeningHook(server.URL)
+	require.NoError(t, err)
+
+	action := &SwapAction{Direction: DirectionBch2Sbch, HashLock: "0x1234", UserEvmAddr: "0xgood", UserBchPkh: "0x5678"}
+	note, err := hook.BeforeLock(action)
+	require.NoError(t, err)
+	require.Equal(t, "screened", note)
+	require.Equal(t, []ScreeningReq{{DirectionBch2Sbch, "0x1234", "0xgood", "0x5678"}}, reqs)
+
+	action.UserEvmAddr = "0xbad"
+	_, err = hook.BeforeLock(action)
+	require.ErrorContains(t, err, "reason: sanctioned")
+	require.False(t, errors.Is(err, ErrSwapHookRetry))
+
+	down = true
+	_, err = hook.BeforeLock(action)
+	require.ErrorContains(t, err, "503")
+	require.True(t, errors.Is(err, ErrSwapHookRetry))
+}
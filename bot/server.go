@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	gethcmn "github.com/ethereum/go-ethereum/common"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -25,6 +26,27 @@ type SwapInfo struct {
 	HashLock string  `json:"hash_lock"`
 	Value    float64 `json:"value"`
 	Status   string  `json:"status"`
+	Token    string  `json:"token,omitempty"` // SEP20 token symbol
+}
+
+type StatsInfo struct {
+	Swaps         int64 `json:"swaps"`
+	ServiceFee    int64 `json:"service_fee"`    // in sats
+	EstimatedCost int64 `json:"estimated_cost"` // in sats
+	BchMinerFee   int64 `json:"bch_miner_fee"`  // in sats
+	SbchGasFee    int64 `json:"sbch_gas_fee"`   // in sats
+	Profit        int64 `json:"profit"`         // in sats, serviceFee - bchMinerFee - sbchGasFee
+}
+
+type SwapTxInfo struct {
+	Leg    string `json:"leg"`
+	TxHash string `json:"tx_hash"`
+	RawTx  string `json:"raw_tx"`
+}
+
+type ReceivePkhInfo struct {
+	Pkh string `json:"pkh"`
+	Idx int64  `json:"idx"` // -1 means the static PKH
 }
 
 type Resp struct {
@@ -48,34 +70,222 @@ func NewOkResp(result any) Resp {
 
 func (resp Resp) WriteTo(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
-	//w.Header().Set("Access-Control-Allow-Origin", "*")
-	//w.Header().Set("Access-Control-Allow-Methods", "*")
-	//w.Header().Set("Access-Control-Allow-Headers", "origin, content-type, accept")
 
 	bytes, _ := json.Marshal(resp)
 	_, _ = w.Write(bytes)
 }
 
 func (bot *MarketMakerBot) StartHttpServer(listenAddr string) {
-	mux := bot.createHttpHandlers()
+	var handler http.Handler = bot.createHttpHandlers()
+	if bot.httpPolicy != nil {
+		handler = bot.httpPolicy.wrap(handler)
+	}
 	server := http.Server{
 		Addr:         listenAddr,
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  3 * time.Second,
 		WriteTimeout: 5 * time.Second,
 	}
-	log.Info("server listening at:", listenAddr, "...")
-	err := server.ListenAndServe()
+	var err error
+	if bot.cfg.TlsCertFile != "" {
+		log.Info("server listening at:", listenAddr, " (TLS) ...")
+		err = server.ListenAndServeTLS(bot.cfg.TlsCertFile, bot.cfg.TlsKeyFile)
+	} else {
+		log.Info("server listening at:", listenAddr, "...")
+		err = server.ListenAndServe()
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// HTTP endpoints, the OpenAPI document (/openapi.json) is generated from them
+func getApiRoutes() []ApiRoute {
+	hashLockParam := ApiParam{Name: "hash_lock", Type: "string", Required: true, Description: "hex"}
+	return []ApiRoute{
+		{Path: "/ping", Summary: "return pong", Result: "",
+			handler: (*MarketMakerBot).handlePing},
+		{Path: "/logs", Summary: "remove and return a number of error logs",
+			Params:  []ApiParam{{Name: "n", Type: "integer", Description: "default 100"}},
+			Result:  []ErrLog{},
+			handler: (*MarketMakerBot).handleLogs},
+		{Path: "/info", Summary: "return bot balance info", Result: Info{},
+			handler: (*MarketMakerBot).handleInfo},
+		{Path: "/receive-pkh", Summary: "return the BCH PKH to receive the next bch2sbch swap", Result: ReceivePkhInfo{},
+			handler: (*MarketMakerBot).handleReceivePkh},
+		{Path: "/stats", Summary: "return fee and cost totals of all swaps", Result: StatsInfo{},
+			handler: (*MarketMakerBot).handleStats},
+		{Path: "/api/v1/bot/info", Summary: "return version, git commit and covenant template hash of the bot",
+			Result: BotInfoV1{}, handler: (*MarketMakerBot).handleBotInfoV1},
+		{Path: "/api/v1/stats", Summary: "return swap statistics of the last N days",
+			Params:  []ApiParam{{Name: "days", Type: "integer", Min: 1, Max: maxStatsDays}},
+			Result:  SwapStats{},
+			handler: (*MarketMakerBot).handleSwapStats},
+		{Path: "/api/v1/swaps", Summary: "return a page of swaps",
+			Params: []ApiParam{
+				{Name: "direction", Type: "string", Required: true, Enum: []string{DirectionBch2Sbch, DirectionSbch2Bch}},
+				{Name: "status", Type: "string", Description: "comma separated status names"},
+				{Name: "sender", Type: "string", Description: "BCH PKH or EVM address of user"},
+				{Name: "from", Type: "integer", Description: "unix timestamp, inclusive"},
+				{Name: "to", Type: "integer", Description: "unix timestamp, exclusive"},
+				{Name: "cursor", Type: "integer", Description: "next_cursor of previous page"},
+				{Name: "order", Type: "string", Enum: []string{"asc", "desc"}},
+				{Name: "limit", Type: "integer", Min: 1, Max: maxSwapListLimit},
+				{Name: "archived", Type: "boolean"},
+			},
+			Result:  SwapList{},
+			handler: (*MarketMakerBot).handleListSwaps},
+		{Path: "/graphql", Methods: []string{http.MethodPost},
+			Summary: "run a GraphQL query over swaps, their txs on both chains and status milestones, see graphql.go for the schema",
+			Body:    GraphqlReq{}, Required: []string{"query"}, ContentType: "application/json",
+			handler: (*MarketMakerBot).handleGraphql},
+		{Path: "/api/v1/explorer/search", Summary: "return indexed swaps with an HTLC matching an address, hash lock or tx hash",
+			Params: []ApiParam{
+				{Name: "q", Type: "string", Required: true, Description: "BCH address, hex PKH, EVM address, hash lock or tx hash"},
+				{Name: "limit", Type: "integer", Min: 1, Max: maxExplorerLimit},
+			},
+			Result:  []ExplorerSwap{},
+			handler: (*MarketMakerBot).handleExplorerSearch},
+		{Path: "/api/v1/explorer/swap", Summary: "return the indexed HTLCs of a hash lock on both chains",
+			Params:  []ApiParam{hashLockParam},
+			Result:  ExplorerSwap{},
+			handler: (*MarketMakerBot).handleExplorerSwap},
+		{Path: "/metrics", Summary: "return sampled gauges in Prometheus text format", ContentType: "text/plain",
+			handler: (*MarketMakerBot).handleMetrics},
+		{Path: "/swap-txs", Summary: "return raw txs of all legs of a swap",
+			Params:  []ApiParam{hashLockParam},
+			Result:  []SwapTxInfo{},
+			handler: (*MarketMakerBot).handleSwapTxs},
+		{Path: "/covenant", Summary: "return the covenant of a BCH deposit (redeem script disassembly, address) and its UTXO status",
+			Params:  []ApiParam{{Name: "tx_hash", Type: "string", Required: true, Description: "hash of the deposit tx"}},
+			Result:  CovenantInfo{},
+			handler: (*MarketMakerBot).handleCovenant},
+		{Path: "/capacity", Summary: "return max swap values the bot can fill now in each direction", Result: CapacityInfo{},
+			handler: (*MarketMakerBot).handleCapacity},
+		{Path: "/tokens", Summary: "return supported SEP20 tokens", Result: []TokenInfo{},
+			handler: (*MarketMakerBot).handleTokens},
+		{Path: "/quote", Methods: []string{http.MethodPost}, Summary: "return a signed quote",
+			Body: QuoteReq{}, Required: []string{"direction", "value", "hash_lock"}, Result: QuoteInfo{},
+			handler: (*MarketMakerBot).handleQuote},
+		{Path: "/unsubscribe", Summary: "stop emails of a swap, linked by every email",
+			Params:  []ApiParam{{Name: "token", Type: "string", Required: true, Description: "unsubscribe token"}},
+			Result:  "",
+			handler: (*MarketMakerBot).handleUnsubscribe},
+		{Path: "/negotiate", Methods: []string{http.MethodPost},
+			Summary: "propose non-default penalty and expiration, return accept|deny and a signed adjusted quote if accepted",
+			Body:    NegotiationReq{}, Required: []string{"direction", "value", "hash_lock", "penalty_bps"},
+			Result:  NegotiationResult{},
+			handler: (*MarketMakerBot).handleNegotiate},
+		{Path: "/cancel", Methods: []string{http.MethodPost},
+			Summary: "cancel a detected swap the bot has not locked for, signed by the sender of the deposit",
+			Body:    CancelReq{}, Required: []string{"direction", "hash_lock", "signature"}, Result: "",
+			handler: (*MarketMakerBot).handleCancelSwap},
+		{Path: "/quote/commitment", Summary: "return the hash of a quote and the sBCH tx committing it on chain",
+			Params:  []ApiParam{hashLockParam},
+			Result:  QuoteCommitmentInfo{},
+			handler: (*MarketMakerBot).handleQuoteCommitment},
+		{Path: "/quote/qr", Summary: "return QR code of the payment URI of a bch2sbch quote",
+			Params:      []ApiParam{hashLockParam, {Name: "scale", Type: "integer", Min: 1, Max: maxQrScale}},
+			ContentType: "image/png",
+			handler:     (*MarketMakerBot).handleQuoteQr},
+		{Path: "/openapi.json", Summary: "return this document", ContentType: "application/json",
+			handler: (*MarketMakerBot).handleOpenApi},
+		{Path: "/admin/access-list", Methods: []string{http.MethodGet, http.MethodPost},
+			Summary: "GET: return access list (reader), POST: replace access list (admin, signed)",
+			Body:    AccessListConfig{}, Result: AccessListConfig{}, Role: RoleReader, Signed: true,
+			handler: (*MarketMakerBot).handleAccessList},
+		{Path: "/admin/access-list/reload", Summary: "reload access list from file",
+			Result: AccessListConfig{}, Role: RoleOperator,
+			handler: (*MarketMakerBot).handleReloadAccessList},
+		{Path: "/admin/reload-config", Summary: "reload config file",
+			Result: "", Role: RoleOperator,
+			handler: (*MarketMakerBot).handleReloadConfig},
+		{Path: "/admin/treasury", Summary: "return multisig treasury and its latest spend proposals",
+			Result: TreasuryInfo{}, Role: RoleReader,
+			handler: (*MarketMakerBot).handleTreasury},
+		{Path: "/admin/treasury/propose", Methods: []string{http.MethodPost},
+			Summary: "propose a spend of the treasury, the bot signs it if it is a co-signer",
+			Body:    TreasuryProposeReq{}, Required: []string{"to_addr", "value", "inputs"},
+			Result: TreasuryProposalInfo{}, Role: RoleAdmin, Signed: true,
+			handler: (*MarketMakerBot).handleTreasuryPropose},
+		{Path: "/admin/treasury/sign", Methods: []string{http.MethodPost},
+			Summary: "add signatures of a co-signer, the tx is broadcast once m co-signers have signed",
+			Body:    TreasurySignReq{}, Required: []string{"id", "pubkey", "sigs"},
+			Result: TreasuryProposalInfo{}, Role: RoleOperator,
+			handler: (*MarketMakerBot).handleTreasurySign},
+		{Path: "/admin/treasury/cancel", Methods: []string{http.MethodPost},
+			Summary: "cancel a pending spend proposal",
+			Body:    TreasuryCancelReq{}, Required: []string{"id"},
+			Result: TreasuryProposalInfo{}, Role: RoleAdmin, Signed: true,
+			handler: (*MarketMakerBot).handleTreasuryCancel},
+		{Path: "/admin/suspects", Summary: "return the latest txs which look like HTLC txs but are not valid ones",
+			Params: []ApiParam{{Name: "n", Type: "integer", Description: "default 100"}},
+			Result: []SuspectTxInfo{}, Role: RoleReader,
+			handler: (*MarketMakerBot).handleSuspects},
+		{Path: "/admin/sbas-txs", Summary: "return the latest txs carrying the SBAS protocol ID, saved with --sbas-diagnostics=save",
+			Params: []ApiParam{
+				{Name: "n", Type: "integer", Description: "default 100"},
+				{Name: "invalid", Type: "integer", Description: "1 means only txs which are not valid deposits"},
+			},
+			Result: []SbasTxInfo{}, Role: RoleReader,
+			handler: (*MarketMakerBot).handleSbasTxs},
+		{Path: "/admin/counterparty-risk", Summary: "return risk scores and in-flight exposure of a counterparty",
+			Params: []ApiParam{
+				{Name: "bch_pkh", Type: "string", Description: "hex"},
+				{Name: "evm_addr", Type: "string", Description: "hex"},
+			},
+			Result: CounterpartyRisksInfo{}, Role: RoleReader,
+			handler: (*MarketMakerBot).handleCounterpartyRisk},
+		{Path: "/admin/webhooks", Summary: "return webhook URLs and deliveries which are given up",
+			Result: WebhooksInfo{}, Role: RoleReader,
+			handler: (*MarketMakerBot).handleWebhooks},
+		{Path: "/admin/affiliate-statement", Summary: "return accruals and payouts of an affiliate in a period",
+			Params: []ApiParam{
+				{Name: "code", Type: "string", Required: true},
+				{Name: "from", Type: "integer", Description: "unix timestamp, inclusive, default 30 days before to"},
+				{Name: "to", Type: "integer", Description: "unix timestamp, exclusive, default now"},
+			},
+			Result: AffiliateStatement{}, Role: RoleReader,
+			handler: (*MarketMakerBot).handleAffiliateStatement},
+		{Path: "/admin/status", Summary: "return pause and freeze state, scan progress, inventory and recent errors",
+			Result: DashboardStatus{}, Role: RoleReader,
+			handler: (*MarketMakerBot).handleAdminStatus},
+		{Path: "/admin/pause", Methods: []string{http.MethodPost},
+			Summary: "stop taking new swaps (deposits, EVM swaps and quotes), in-flight swaps are still unlocked or refunded",
+			Result:  "", Role: RoleOperator,
+			handler: (*MarketMakerBot).handlePause},
+		{Path: "/admin/resume", Methods: []string{http.MethodPost}, Summary: "take new swaps again",
+			Result: "", Role: RoleOperator,
+			handler: (*MarketMakerBot).handleResume},
+		{Path: "/admin/freeze", Methods: []string{http.MethodPost},
+			Summary: "kill switch: stop signing and broadcasting anything, scanning goes on, saved in DB",
+			Params:  []ApiParam{{Name: "reason", Type: "string"}},
+			Result:  "", Role: RoleOperator,
+			handler: (*MarketMakerBot).handleFreeze},
+		{Path: "/admin/thaw", Methods: []string{http.MethodPost},
+			Summary: "turn off the kill switch, signing and broadcasting resume",
+			Params:  []ApiParam{{Name: "reason", Type: "string"}},
+			Result:  "", Role: RoleAdmin, Signed: true,
+			handler: (*MarketMakerBot).handleThaw},
+		{Path: "/dashboard", Summary: "return the operator dashboard", ContentType: "text/html",
+			handler: (*MarketMakerBot).handleDashboard},
+	}
+}
+
 func (bot *MarketMakerBot) createHttpHandlers() *http.ServeMux {
+	doc := getOpenApiDoc()
 	mux := http.NewServeMux()
-	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) { bot.handlePing(w, r) })
-	mux.HandleFunc("/logs", func(w http.ResponseWriter, r *http.Request) { bot.handleLogs(w, r) })
-	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) { bot.handleInfo(w, r) })
+	for _, route := range getApiRoutes() {
+		route := route
+		mux.HandleFunc(route.Path, func(w http.ResponseWriter, r *http.Request) {
+			if httpStatus, err := doc.validateRequest(&route, r); err != nil {
+				w.WriteHeader(httpStatus)
+				NewErrResp(err.Error()).WriteTo(w)
+				return
+			}
+			route.handler(bot, w, r)
+		})
+	}
 	return mux
 }
 
@@ -101,6 +311,172 @@ func (bot *MarketMakerBot) handleInfo(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// return the BCH PKH which should be used as recipient of the next BCH2SBCH swap,
+// each request gets its own PKH, see nextHdReceivePkh()
+func (bot *MarketMakerBot) handleReceivePkh(w http.ResponseWriter, r *http.Request) {
+	if bot.hdPkhs == nil {
+		NewOkResp(ReceivePkhInfo{Pkh: toHex(bot.bchPkh), Idx: -1}).WriteTo(w)
+		return
+	}
+	idx, pkh, err := bot.nextHdReceivePkh()
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(ReceivePkhInfo{Pkh: pkh, Idx: int64(idx)}).WriteTo(w)
+}
+
+// return fee and cost totals of all swaps
+func (bot *MarketMakerBot) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := bot.getStatsInfo()
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(stats).WriteTo(w)
+}
+
+// return rolling swap statistics of the last N days (cached for a while)
+func (bot *MarketMakerBot) handleSwapStats(w http.ResponseWriter, r *http.Request) {
+	days := getIntQueryParam(r, "days", defaultStatsDays)
+	if days <= 0 || days > maxStatsDays {
+		NewErrResp(fmt.Sprintf("days must be in [1, %d]", maxStatsDays)).WriteTo(w)
+		return
+	}
+	stats, err := bot.getSwapStats(days)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(stats).WriteTo(w)
+}
+
+// return sampled gauges in Prometheus text format
+func (bot *MarketMakerBot) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if bot.gauges != nil {
+		bot.gauges.writeTo(w)
+	}
+	if bot.latencies != nil {
+		bot.latencies.writeTo(w)
+	}
+}
+
+// return raw txs of all legs of a swap
+func (bot *MarketMakerBot) handleSwapTxs(w http.ResponseWriter, r *http.Request) {
+	hashLock := r.URL.Query().Get("hash_lock")
+	if hashLock == "" {
+		NewErrResp("missing hash_lock").WriteTo(w)
+		return
+	}
+	txs, err := bot.db.getSwapTxsByHashLock(toHex(gethcmn.FromHex(hashLock)))
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	infos := make([]SwapTxInfo, len(txs))
+	for i, tx := range txs {
+		infos[i] = SwapTxInfo{
+			Leg:    tx.Leg,
+			TxHash: tx.TxHash,
+			RawTx:  tx.RawTx,
+		}
+	}
+	NewOkResp(infos).WriteTo(w)
+}
+
+// GET: return access list, POST: replace access list
+func (bot *MarketMakerBot) handleAccessList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		if !bot.checkApiAuth(w, r, RoleReader, false) {
+			return
+		}
+	} else if !bot.checkApiAuth(w, r, RoleAdmin, true) {
+		return
+	}
+	if bot.accessList == nil {
+		NewErrResp("access list is not enabled").WriteTo(w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		NewOkResp(bot.accessList.get()).WriteTo(w)
+		return
+	}
+
+	var cfg AccessListConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		NewErrResp("invalid request: " + err.Error()).WriteTo(w)
+		return
+	}
+	if err := bot.accessList.set(cfg, true); err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(bot.accessList.get()).WriteTo(w)
+}
+
+// reload access list from file
+func (bot *MarketMakerBot) handleReloadAccessList(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleOperator, false) {
+		return
+	}
+	if bot.accessList == nil {
+		NewErrResp("access list is not enabled").WriteTo(w)
+		return
+	}
+	if err := bot.accessList.reload(); err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(bot.accessList.get()).WriteTo(w)
+}
+
+// reload config file, the new config is applied by the main loop
+func (bot *MarketMakerBot) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleOperator, false) {
+		return
+	}
+	if err := bot.requestConfigReload(); err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp("scheduled").WriteTo(w)
+}
+
+// return a signed quote for the swap params posted by user
+func (bot *MarketMakerBot) handleQuote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		NewErrResp("POST only").WriteTo(w)
+		return
+	}
+	var req QuoteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		NewErrResp("invalid request: " + err.Error()).WriteTo(w)
+		return
+	}
+	quote, err := bot.makeQuote(&req)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(quote).WriteTo(w)
+}
+
+func (bot *MarketMakerBot) getStatsInfo() (*StatsInfo, error) {
+	totals, err := bot.db.getSwapCostTotals()
+	if err != nil {
+		return nil, err
+	}
+	return &StatsInfo{
+		Swaps:         totals.Swaps,
+		ServiceFee:    int64(totals.ServiceFee),
+		EstimatedCost: int64(totals.EstimatedCost),
+		BchMinerFee:   int64(totals.BchMinerFee),
+		SbchGasFee:    int64(totals.SbchGasFee),
+		Profit:        int64(totals.ServiceFee) - int64(totals.BchMinerFee) - int64(totals.SbchGasFee),
+	}, nil
+}
+
 func (bot *MarketMakerBot) getBotInfo() (*Info, error) {
 	freeBch, err := bot.getFreeBch()
 	if err != nil {
@@ -175,30 +551,44 @@ func (bot *MarketMakerBot) getSbch2BchInfo() (
 		return 0, 0, nil, err
 	}
 
+	// tokens locked by users are tracked by getTokenInfos()
 	for _, record := range newRecords {
-		toBeUnlockedSbch += satsToUtxoAmt(record.Value)
+		if record.Token == "" {
+			toBeUnlockedSbch += satsToUtxoAmt(record.Value)
+		}
 		swapInfos = append(swapInfos, SwapInfo{
 			HashLock: record.HashLock,
 			Value:    satsToUtxoAmt(record.Value),
 			Status:   "New",
+			Token:    record.Token,
 		})
 	}
 	for _, record := range bchLockedRecords {
-		toBeUnlockedSbch += satsToUtxoAmt(record.Value)
-		lockedBch += satsToUtxoAmt(record.Value)
+		if record.Token == "" {
+			toBeUnlockedSbch += satsToUtxoAmt(record.Value)
+			lockedBch += satsToUtxoAmt(record.Value)
+		} else {
+			lockedBch += satsToUtxoAmt(mulByPrice(record.Value, record.SbchPrice))
+		}
 		swapInfos = append(swapInfos, SwapInfo{
 			HashLock: record.HashLock,
 			Value:    satsToUtxoAmt(record.Value),
 			Status:   "BchLocked",
+			Token:    record.Token,
 		})
 	}
 	for _, record := range secretRevealedRecords {
-		toBeUnlockedSbch += satsToUtxoAmt(record.Value)
-		lockedBch += satsToUtxoAmt(record.Value)
+		if record.Token == "" {
+			toBeUnlockedSbch += satsToUtxoAmt(record.Value)
+			lockedBch += satsToUtxoAmt(record.Value)
+		} else {
+			lockedBch += satsToUtxoAmt(mulByPrice(record.Value, record.SbchPrice))
+		}
 		swapInfos = append(swapInfos, SwapInfo{
 			HashLock: record.HashLock,
 			Value:    satsToUtxoAmt(record.Value),
 			Status:   "SecretRevealed",
+			Token:    record.Token,
 		})
 	}
 
@@ -223,30 +613,38 @@ func (bot *MarketMakerBot) getBch2SbchInfo() (
 		return 0, 0, nil, err
 	}
 
+	// tokens locked by bot are tracked by getTokenInfos()
 	for _, record := range newRecords {
 		toBeUnlockedBch += satsToUtxoAmt(record.Value)
 		swapInfos = append(swapInfos, SwapInfo{
 			HashLock: record.HashLock,
 			Value:    satsToUtxoAmt(record.Value),
 			Status:   "New",
+			Token:    record.Token,
 		})
 	}
 	for _, record := range sbchLockedRecords {
 		toBeUnlockedBch += satsToUtxoAmt(record.Value)
-		lockedSbch += satsToUtxoAmt(record.Value)
+		if record.Token == "" {
+			lockedSbch += satsToUtxoAmt(record.GetFilledValue())
+		}
 		swapInfos = append(swapInfos, SwapInfo{
 			HashLock: record.HashLock,
 			Value:    satsToUtxoAmt(record.Value),
 			Status:   "SbchLocked",
+			Token:    record.Token,
 		})
 	}
 	for _, record := range secretRevealedRecords {
 		toBeUnlockedBch += satsToUtxoAmt(record.Value)
-		lockedSbch += satsToUtxoAmt(record.Value)
+		if record.Token == "" {
+			lockedSbch += satsToUtxoAmt(record.GetFilledValue())
+		}
 		swapInfos = append(swapInfos, SwapInfo{
 			HashLock: record.HashLock,
 			Value:    satsToUtxoAmt(record.Value),
 			Status:   "SecretRevealed",
+			Token:    record.Token,
 		})
 	}
 
@@ -0,0 +1,187 @@
+package bot
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// A snapshot is a gzipped gob stream:
+//
+//	SnapshotHeader
+//	for each table: table name, then batches of rows ([]Model), ended by an empty batch
+//
+// Soft deleted rows and IDs are kept, leader leases are not (they belong to running instances).
+// It does not depend on the DB backend, so it can also be used to migrate between backends.
+const (
+	SnapshotMagic   = "asbot-snapshot"
+	SnapshotVersion = 1
+
+	snapshotBatchSize = 1000
+)
+
+type SnapshotHeader struct {
+	Magic          string
+	Version        int
+	CreatedAt      int64    // unix seconds
+	LastBchHeight  uint64   // scanner checkpoints, also in LastHeights
+	LastSbchHeight uint64   //
+	Tables         []string // in the order of the stream
+}
+
+// SnapshotStats is the number of rows of each table
+type SnapshotStats struct {
+	Header *SnapshotHeader
+	Rows   map[string]int
+}
+
+var snapshotModels = getSnapshotModels()
+
+func getSnapshotModels() map[string]reflect.Type {
+	models := map[string]reflect.Type{}
+	for _, model := range dbModels {
+		typ := reflect.TypeOf(model).Elem()
+		if typ != reflect.TypeOf(LeaderLease{}) {
+			models[typ.Name()] = typ
+		}
+	}
+	return models
+}
+
+func getSnapshotTables() (tables []string) {
+	for _, model := range dbModels {
+		name := reflect.TypeOf(model).Elem().Name()
+		if _, ok := snapshotModels[name]; ok {
+			tables = append(tables, name)
+		}
+	}
+	return
+}
+
+// BackupDB writes a snapshot of the DB, consistent even if the bot is running
+func BackupDB(dbFile string, w io.Writer) (*SnapshotStats, error) {
+	db, err := OpenDB(dbFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DB file: %w", err)
+	}
+	heights, err := db.getLastHeights()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last heights: %w", err)
+	}
+
+	stats := &SnapshotStats{
+		Header: &SnapshotHeader{
+			Magic:          SnapshotMagic,
+			Version:        SnapshotVersion,
+			CreatedAt:      time.Now().Unix(),
+			LastBchHeight:  heights.LastBchHeight,
+			LastSbchHeight: heights.LastSbchHeight,
+			Tables:         getSnapshotTables(),
+		},
+		Rows: map[string]int{},
+	}
+	zw := gzip.NewWriter(w)
+	enc := gob.NewEncoder(zw)
+	if err = enc.Encode(stats.Header); err != nil {
+		return nil, err
+	}
+	err = db.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range stats.Header.Tables {
+			if err := enc.Encode(table); err != nil {
+				return err
+			}
+			batch := reflect.New(reflect.SliceOf(snapshotModels[table]))
+			result := tx.Unscoped().Order("id").FindInBatches(batch.Interface(), snapshotBatchSize,
+				func(_ *gorm.DB, _ int) error {
+					stats.Rows[table] += batch.Elem().Len()
+					return enc.Encode(batch.Interface())
+				})
+			if result.Error != nil {
+				return fmt.Errorf("failed to back up %s: %w", table, result.Error)
+			}
+			if err := enc.Encode(reflect.MakeSlice(reflect.SliceOf(snapshotModels[table]), 0, 0).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, zw.Close()
+}
+
+// RestoreDB creates the DB from a snapshot, the DB must be new or empty
+func RestoreDB(r io.Reader, dbFile string) (*SnapshotStats, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot: %w", err)
+	}
+	dec := gob.NewDecoder(zr)
+	header := &SnapshotHeader{}
+	if err = dec.Decode(header); err != nil || header.Magic != SnapshotMagic {
+		return nil, fmt.Errorf("not a snapshot")
+	}
+	if header.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version: %d", header.Version)
+	}
+
+	db, err := OpenDB(dbFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DB file: %w", err)
+	}
+	if err = db.syncSchemas(); err != nil {
+		return nil, err
+	}
+	for _, model := range dbModels {
+		var n int64
+		if err = db.db.Unscoped().Model(model).Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, fmt.Errorf("DB is not empty: %s has %d rows", reflect.TypeOf(model).Elem().Name(), n)
+		}
+	}
+
+	stats := &SnapshotStats{Header: header, Rows: map[string]int{}}
+	err = db.db.Transaction(func(tx *gorm.DB) error {
+		for range header.Tables {
+			var table string
+			if err := dec.Decode(&table); err != nil {
+				return fmt.Errorf("corrupted snapshot: %w", err)
+			}
+			typ, ok := snapshotModels[table]
+			if !ok {
+				return fmt.Errorf("unknown table in snapshot: %s", table)
+			}
+			for {
+				batch := reflect.New(reflect.SliceOf(typ))
+				if err := dec.Decode(batch.Interface()); err != nil {
+					return fmt.Errorf("corrupted snapshot: %w", err)
+				}
+				if batch.Elem().Len() == 0 {
+					break
+				}
+				if err := tx.CreateInBatches(batch.Interface(), 100).Error; err != nil {
+					return fmt.Errorf("failed to restore %s: %w", table, err)
+				}
+				stats.Rows[table] += batch.Elem().Len()
+			}
+		}
+		var extra string
+		if err := dec.Decode(&extra); !errors.Is(err, io.EOF) {
+			return fmt.Errorf("corrupted snapshot: unexpected data after tables")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
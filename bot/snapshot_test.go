@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newSnapshotTestRecord(hashLock string, value uint64) *Bch2SbchRecord {
+	return &Bch2SbchRecord{
+		BchLockHeight:  100,
+		BchLockTxHash:  "lock" + hashLock,
+		Value:          value,
+		RecipientPkh:   toHex(testBchPkh),
+		SenderPkh:      toHex(gethAddrBytes("user")),
+		HashLock:       hashLock,
+		TimeLock:       100,
+		SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+		HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+		Status:         Bch2SbchStatusSbchLocked,
+	}
+}
+
+func TestBackupRestoreDB(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, _db.addBch2SbchRecord(newSnapshotTestRecord("hash"+string(rune('a'+i)), uint64(10000*(i+1)))))
+	}
+	require.NoError(t, _db.db.Create(&Sbch2BchRecord{SbchLockTxHash: "sbchlock", HashLock: "hashs2b"}).Error)
+	_, err := _db.addSwapEvent(&SwapEvent{Kind: EventBchDeposit, Key: "tx1", Height: 100, Payload: "{}"})
+	require.NoError(t, err)
+	require.NoError(t, _db.db.Delete(&Bch2SbchRecord{}, 2).Error) // soft deleted
+	require.NoError(t, _db.db.Create(&LeaderLease{Name: "leader", Holder: "a"}).Error)
+
+	var buf bytes.Buffer
+	stats, err := BackupDB(testDbFile, &buf)
+	require.NoError(t, err)
+	require.Equal(t, uint64(123), stats.Header.LastBchHeight)
+	require.Equal(t, uint64(456), stats.Header.LastSbchHeight)
+	require.Equal(t, 3, stats.Rows["Bch2SbchRecord"])
+	require.Equal(t, 1, stats.Rows["Sbch2BchRecord"])
+	require.Equal(t, 1, stats.Rows["SwapEvent"])
+	require.NotContains(t, stats.Header.Tables, "LeaderLease")
+	snapshot := buf.Bytes()
+
+	dbFile := filepath.Join(t.TempDir(), "restored.db")
+	stats2, err := RestoreDB(bytes.NewReader(snapshot), dbFile)
+	require.NoError(t, err)
+	require.Equal(t, stats.Rows, stats2.Rows)
+
+	db2, err := OpenDB(dbFile)
+	require.NoError(t, err)
+	heights, err := db2.getLastHeights()
+	require.NoError(t, err)
+	require.Equal(t, uint64(123), heights.LastBchHeight)
+	require.Equal(t, uint64(456), heights.LastSbchHeight)
+
+	var records, records2 []Bch2SbchRecord
+	require.NoError(t, _db.db.Unscoped().Order("id").Find(&records).Error)
+	require.NoError(t, db2.db.Unscoped().Order("id").Find(&records2).Error)
+	require.Len(t, records2, 3)
+	for i := range records {
+		require.Equal(t, toJSON(records[i]), toJSON(records2[i]))
+	}
+	require.True(t, records2[1].DeletedAt.Valid)
+	record, err := db2.getBch2SbchRecordByHashLock("hashc")
+	require.NoError(t, err)
+	require.Equal(t, uint(3), record.ID)
+
+	// new rows continue IDs
+	require.NoError(t, db2.addBch2SbchRecord(newSnapshotTestRecord("new", 1)))
+	record, err = db2.getBch2SbchRecordByHashLock("new")
+	require.NoError(t, err)
+	require.Equal(t, uint(4), record.ID)
+
+	// not empty
+	_, err = RestoreDB(bytes.NewReader(snapshot), dbFile)
+	require.ErrorContains(t, err, "DB is not empty")
+
+	// corrupted
+	_, err = RestoreDB(bytes.NewReader([]byte("hello")), filepath.Join(t.TempDir(), "x.db"))
+	require.ErrorContains(t, err, "not a snapshot")
+	_, err = RestoreDB(bytes.NewReader(snapshot[:len(snapshot)/2]), filepath.Join(t.TempDir(), "y.db"))
+	require.ErrorContains(t, err, "corrupted snapshot")
+}
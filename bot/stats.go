@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	statsCacheTTL     = time.Minute
+	defaultStatsDays  = 30
+	maxStatsDays      = 365
+	statsDateTemplate = "2006-01-02"
+)
+
+type SwapStats struct {
+	GeneratedAt int64             `json:"generated_at"` // unix timestamp
+	Days        int               `json:"days"`
+	Daily       []*DailySwapStats `json:"daily"`
+	Bch2Sbch    *DirectionStats   `json:"bch2sbch"`
+	Sbch2Bch    *DirectionStats   `json:"sbch2bch"`
+	FeesEarned  uint64            `json:"fees_earned"`  // in sats
+	LargestSwap uint64            `json:"largest_swap"` // in sats
+}
+
+type DailySwapStats struct {
+	Date     string `json:"date"` // UTC
+	Bch2Sbch int64  `json:"bch2sbch"`
+	Sbch2Bch int64  `json:"sbch2bch"`
+}
+
+type DirectionStats struct {
+	Swaps             int64   `json:"swaps"`
+	Completed         int64   `json:"completed"`
+	Refunded          int64   `json:"refunded"`
+	Volume            uint64  `json:"volume"`              // in sats, of completed swaps
+	LargestSwap       uint64  `json:"largest_swap"`        // in sats
+	AvgCompletionTime int64   `json:"avg_completion_time"` // in seconds
+	RefundRate        float64 `json:"refund_rate"`         // refunded / (completed + refunded)
+	FeesEarned        uint64  `json:"fees_earned"`         // in sats, of completed swaps
+	LatencyP50        int64   `json:"latency_p50"`         // in seconds, deposit confirmed => counter-asset delivered
+	LatencyP95        int64   `json:"latency_p95"`         // in seconds, of swaps delivered in the period
+}
+
+// cache computed stats for a while, stats are expensive to compute
+type StatsCache struct {
+	mu      sync.Mutex
+	entries map[int]*SwapStats // days => stats
+}
+
+func newStatsCache() *StatsCache {
+	return &StatsCache{entries: map[int]*SwapStats{}}
+}
+
+func (c *StatsCache) get(days int, now time.Time) *SwapStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.entries[days]
+	if stats == nil || now.Sub(time.Unix(stats.GeneratedAt, 0)) > statsCacheTTL {
+		return nil
+	}
+	return stats
+}
+
+func (c *StatsCache) set(days int, stats *SwapStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[days] = stats
+}
+
+func (bot *MarketMakerBot) getSwapStats(days int) (*SwapStats, error) {
+	now := time.Now()
+	if bot.statsCache != nil {
+		if stats := bot.statsCache.get(days, now); stats != nil {
+			return stats, nil
+		}
+	}
+
+	stats, err := bot.computeSwapStats(days, now)
+	if err != nil {
+		return nil, err
+	}
+	if bot.statsCache != nil {
+		bot.statsCache.set(days, stats)
+	}
+	return stats, nil
+}
+
+func (bot *MarketMakerBot) computeSwapStats(days int, now time.Time) (*SwapStats, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, 1-days)
+
+	b2sRecords, err := bot.db.getBch2SbchRecordsCreatedSince(since)
+	if err != nil {
+		return nil, err
+	}
+	s2bRecords, err := bot.db.getSbch2BchRecordsCreatedSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SwapStats{
+		GeneratedAt: now.Unix(),
+		Days:        days,
+		Bch2Sbch:    &DirectionStats{},
+		Sbch2Bch:    &DirectionStats{},
+	}
+	daily := map[string]*DailySwapStats{}
+	for i := 0; i < days; i++ {
+		date := since.AddDate(0, 0, i).Format(statsDateTemplate)
+		dailyStats := &DailySwapStats{Date: date}
+		daily[date] = dailyStats
+		stats.Daily = append(stats.Daily, dailyStats)
+	}
+
+	var b2sCompletionTime, s2bCompletionTime int64
+	for _, record := range b2sRecords {
+		if dailyStats := daily[record.CreatedAt.UTC().Format(statsDateTemplate)]; dailyStats != nil {
+			dailyStats.Bch2Sbch++
+		}
+		switch record.Status {
+		case Bch2SbchStatusBchUnlocked, Bch2SbchStatusRemainderRefunded:
+			b2sCompletionTime += int64(record.UpdatedAt.Sub(record.CreatedAt).Seconds())
+			filledVal := record.GetFilledValue()
+			stats.Bch2Sbch.addCompleted(filledVal,
+				bot.getSwapServiceFee(record.Token, DirectionBch2Sbch, filledVal, record.BchPrice))
+		case Bch2SbchStatusSbchRefunded:
+			stats.Bch2Sbch.Refunded++
+		}
+		stats.Bch2Sbch.addSwap(record.Value)
+	}
+	for _, record := range s2bRecords {
+		value := record.Value
+		if record.Token != "" {
+			// volume is always in sats
+			value = mulByPrice(value, record.SbchPrice)
+		}
+		if dailyStats := daily[record.CreatedAt.UTC().Format(statsDateTemplate)]; dailyStats != nil {
+			dailyStats.Sbch2Bch++
+		}
+		switch record.Status {
+		case Sbch2BchStatusSbchUnlocked:
+			s2bCompletionTime += int64(record.UpdatedAt.Sub(record.CreatedAt).Seconds())
+			stats.Sbch2Bch.addCompleted(value,
+				bot.getSwapServiceFee(record.Token, DirectionSbch2Bch, record.Value, record.SbchPrice))
+		case Sbch2BchStatusBchRefunded:
+			stats.Sbch2Bch.Refunded++
+		}
+		stats.Sbch2Bch.addSwap(value)
+	}
+
+	latencies, err := bot.db.getSwapLatencies(0, since.Unix(), -1)
+	if err != nil {
+		return nil, err
+	}
+	b2sLatencies := make([]int64, 0, len(latencies))
+	s2bLatencies := make([]int64, 0, len(latencies))
+	for _, latency := range latencies {
+		if latency.Direction == DirectionBch2Sbch {
+			b2sLatencies = append(b2sLatencies, latency.Latency)
+		} else if latency.Direction == DirectionSbch2Bch {
+			s2bLatencies = append(s2bLatencies, latency.Latency)
+		}
+	}
+
+	stats.Bch2Sbch.finish(b2sCompletionTime, b2sLatencies)
+	stats.Sbch2Bch.finish(s2bCompletionTime, s2bLatencies)
+	stats.FeesEarned = stats.Bch2Sbch.FeesEarned + stats.Sbch2Bch.FeesEarned
+	stats.LargestSwap = stats.Bch2Sbch.LargestSwap
+	if stats.Sbch2Bch.LargestSwap > stats.LargestSwap {
+		stats.LargestSwap = stats.Sbch2Bch.LargestSwap
+	}
+	return stats, nil
+}
+
+func (s *DirectionStats) addSwap(value uint64) {
+	s.Swaps++
+	if value > s.LargestSwap {
+		s.LargestSwap = value
+	}
+}
+
+func (s *DirectionStats) addCompleted(value, fee uint64) {
+	s.Completed++
+	s.Volume += value
+	s.FeesEarned += fee
+}
+
+func (s *DirectionStats) finish(totalCompletionTime int64, latencies []int64) {
+	if s.Completed > 0 {
+		s.AvgCompletionTime = totalCompletionTime / s.Completed
+	}
+	if n := s.Completed + s.Refunded; n > 0 {
+		s.RefundRate = float64(s.Refunded) / float64(n)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	s.LatencyP50 = percentile(latencies, 0.5)
+	s.LatencyP95 = percentile(latencies, 0.95)
+}
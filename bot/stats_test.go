@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapStats(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i, x := range []struct {
+		value  uint64
+		status Bch2SbchStatus
+	}{
+		{1e8, Bch2SbchStatusBchUnlocked},
+		{3e8, Bch2SbchStatusBchUnlocked},
+		{5e8, Bch2SbchStatusSbchRefunded},
+		{7e8, Bch2SbchStatusNew},
+	} {
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockHeight:  122,
+			BchLockTxHash:  toHex(gethHash32Bytes("bchlock" + string(rune('0'+i)))),
+			Value:          x.value,
+			BchPrice:       0.99e8,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(gethAddrBytes("user")),
+			HashLock:       toHex(gethHash32Bytes("b2s" + string(rune('0'+i)))),
+			TimeLock:       100,
+			SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+			Status:         x.status,
+		}))
+	}
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    1234567890,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock")),
+		Value:           2e8,
+		SbchPrice:       0.98e8,
+		SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        toHex(gethHash32Bytes("s2b")),
+		TimeLock:        72000,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		Status:          Sbch2BchStatusSbchUnlocked,
+	}))
+	// created 10 minutes before completed
+	require.NoError(t, _db.db.Model(&Bch2SbchRecord{}).Where("status = ?", Bch2SbchStatusBchUnlocked).
+		UpdateColumn("created_at", time.Now().Add(-10*time.Minute)).Error)
+	// too old
+	require.NoError(t, _db.db.Model(&Bch2SbchRecord{}).Where("status = ?", Bch2SbchStatusNew).
+		UpdateColumn("created_at", time.Now().AddDate(0, 0, -40)).Error)
+
+	_bot := &MarketMakerBot{db: _db, statsCache: newStatsCache()}
+	stats, err := _bot.getSwapStats(30)
+	require.NoError(t, err)
+	require.Equal(t, 30, stats.Days)
+	require.Len(t, stats.Daily, 30)
+	require.Equal(t, time.Now().UTC().Format(statsDateTemplate), stats.Daily[29].Date)
+	require.Equal(t, int64(3), stats.Daily[29].Bch2Sbch+stats.Daily[28].Bch2Sbch)
+	require.Equal(t, int64(1), stats.Daily[29].Sbch2Bch+stats.Daily[28].Sbch2Bch)
+
+	require.Equal(t, int64(3), stats.Bch2Sbch.Swaps)
+	require.Equal(t, int64(2), stats.Bch2Sbch.Completed)
+	require.Equal(t, int64(1), stats.Bch2Sbch.Refunded)
+	require.Equal(t, uint64(4e8), stats.Bch2Sbch.Volume)
+	require.Equal(t, uint64(5e8), stats.Bch2Sbch.LargestSwap)
+	require.InDelta(t, 600, stats.Bch2Sbch.AvgCompletionTime, 5)
+	require.InDelta(t, 1.0/3, stats.Bch2Sbch.RefundRate, 1e-9)
+	require.Equal(t, uint64(4e6), stats.Bch2Sbch.FeesEarned)
+
+	require.Equal(t, int64(1), stats.Sbch2Bch.Swaps)
+	require.Equal(t, uint64(2e8), stats.Sbch2Bch.Volume)
+	require.Equal(t, uint64(4e6), stats.Sbch2Bch.FeesEarned)
+	require.Equal(t, float64(0), stats.Sbch2Bch.RefundRate)
+
+	require.Equal(t, uint64(8e6), stats.FeesEarned)
+	require.Equal(t, uint64(5e8), stats.LargestSwap)
+
+	// cached
+	require.NoError(t, _db.db.Model(&Sbch2BchRecord{}).Where("status = ?", Sbch2BchStatusSbchUnlocked).
+		UpdateColumn("status", Sbch2BchStatusBchRefunded).Error)
+	stats2, err := _bot.getSwapStats(30)
+	require.NoError(t, err)
+	require.Same(t, stats, stats2)
+	stats3, err := _bot.getSwapStats(1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats3.Sbch2Bch.Refunded)
+
+	w := httptest.NewRecorder()
+	_bot.handleSwapStats(w, httptest.NewRequest("GET", "/api/v1/stats?days=400", nil))
+	require.True(t, strings.Contains(w.Body.String(), "days must be in [1, 365]"))
+}
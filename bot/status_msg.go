@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// Message catalog of the user-facing status messages returned by the public API. The language
+// is selected by the Accept-Language header, English is used if no language matches or a key is
+// not translated. Translations are added as a new catalog and a new tag in statusMsgLangs.
+
+const (
+	msgRefundInBlocks  = "refund_in_blocks"
+	msgRefundInMinutes = "refund_in_minutes"
+	msgRefundNow       = "refund_now"
+	msgSeparator       = "separator"
+)
+
+var (
+	statusMsgLangs    = []language.Tag{language.English, language.Chinese} // the first one is the default
+	statusMsgMatcher  = language.NewMatcher(statusMsgLangs)
+	statusMsgCatalogs = map[string]map[string]string{
+		"en": {
+			"bch2sbch.New":               "waiting for BCH confirmation, then the bot will lock sBCH",
+			"bch2sbch.SbchLocked":        "sBCH is locked by the bot, unlock it with your secret",
+			"bch2sbch.SecretRevealed":    "secret revealed, the bot is unlocking your BCH",
+			"bch2sbch.BchUnlocked":       "swap completed",
+			"bch2sbch.SbchRefunded":      "secret not revealed in time, sBCH is refunded to the bot",
+			"bch2sbch.TooLateToLockSbch": "BCH confirmed too late, the bot will not lock sBCH",
+			"bch2sbch.PriceChanged":      "price changed, the bot will not lock sBCH",
+			"bch2sbch.Unprofitable":      "value too small to cover the fees, the bot will not lock sBCH",
+			"bch2sbch.RemainderRefunded": "partially filled, the remainder is refunded",
+			"bch2sbch.Rejected":          "swap rejected by the bot",
+			"bch2sbch.Cancelled":         "swap cancelled by you, the bot will not lock sBCH",
+			"sbch2bch.New":               "waiting for the bot to lock BCH",
+			"sbch2bch.BchLocked":         "BCH is locked by the bot, unlock it with your secret",
+			"sbch2bch.SecretRevealed":    "secret revealed, the bot is unlocking your sBCH",
+			"sbch2bch.SbchUnlocked":      "swap completed",
+			"sbch2bch.BchRefunded":       "secret not revealed in time, BCH is refunded to the bot",
+			"sbch2bch.TooLateToLockBch":  "sBCH locked too long ago, the bot will not lock BCH",
+			"sbch2bch.PriceChanged":      "price changed, the bot will not lock BCH",
+			"sbch2bch.Unprofitable":      "value too small to cover the fees, the bot will not lock BCH",
+			"sbch2bch.Rejected":          "swap rejected by the bot",
+			"sbch2bch.Cancelled":         "swap cancelled by you, the bot will not lock BCH",
+			msgRefundInBlocks:            "refund available in %d blocks",
+			msgRefundInMinutes:           "refund available in %d minutes",
+			msgRefundNow:                 "refund available now",
+			msgSeparator:                 "; ",
+		},
+		"zh": {
+			"bch2sbch.New":               "等待BCH确认，之后机器人将锁定sBCH",
+			"bch2sbch.SbchLocked":        "机器人已锁定sBCH，请用您的密钥解锁",
+			"bch2sbch.SecretRevealed":    "密钥已公开，机器人正在解锁您的BCH",
+			"bch2sbch.BchUnlocked":       "兑换完成",
+			"bch2sbch.SbchRefunded":      "未按时公开密钥，sBCH已退还给机器人",
+			"bch2sbch.TooLateToLockSbch": "BCH确认过晚，机器人不会锁定sBCH",
+			"bch2sbch.PriceChanged":      "价格已变化，机器人不会锁定sBCH",
+			"bch2sbch.Unprofitable":      "金额过小不足以支付手续费，机器人不会锁定sBCH",
+			"bch2sbch.RemainderRefunded": "部分成交，剩余部分已退还",
+			"bch2sbch.Rejected":          "兑换被机器人拒绝",
+			"bch2sbch.Cancelled":         "您已取消兑换，机器人不会锁定sBCH",
+			"sbch2bch.New":               "等待机器人锁定BCH",
+			"sbch2bch.BchLocked":         "机器人已锁定BCH，请用您的密钥解锁",
+			"sbch2bch.SecretRevealed":    "密钥已公开，机器人正在解锁您的sBCH",
+			"sbch2bch.SbchUnlocked":      "兑换完成",
+			"sbch2bch.BchRefunded":       "未按时公开密钥，BCH已退还给机器人",
+			"sbch2bch.TooLateToLockBch":  "sBCH锁定过久，机器人不会锁定BCH",
+			"sbch2bch.PriceChanged":      "价格已变化，机器人不会锁定BCH",
+			"sbch2bch.Unprofitable":      "金额过小不足以支付手续费，机器人不会锁定BCH",
+			"sbch2bch.Rejected":          "兑换被机器人拒绝",
+			"sbch2bch.Cancelled":         "您已取消兑换，机器人不会锁定BCH",
+			msgRefundInBlocks:            "%d个区块后可退款",
+			msgRefundInMinutes:           "%d分钟后可退款",
+			msgRefundNow:                 "现在可退款",
+			msgSeparator:                 "；",
+		},
+	}
+)
+
+// return the catalog language which best matches the Accept-Language header of request
+func getRequestLang(r *http.Request) string {
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil {
+		return statusMsgLangs[0].String()
+	}
+	_, idx, confidence := statusMsgMatcher.Match(tags...)
+	if confidence == language.No {
+		idx = 0
+	}
+	return statusMsgLangs[idx].String()
+}
+
+func getStatusMsg(lang, key string, args ...any) string {
+	format, ok := statusMsgCatalogs[lang][key]
+	if !ok {
+		if format, ok = statusMsgCatalogs["en"][key]; !ok {
+			return key
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// users can refund their own deposits of the swaps which the bot does not fill
+func isBch2SbchRefundable(status Bch2SbchStatus) bool {
+	switch status {
+	case Bch2SbchStatusSbchRefunded, Bch2SbchStatusTooLateToLockSbch, Bch2SbchStatusPriceChanged,
+		Bch2SbchStatusUnprofitable, Bch2SbchStatusRejected, Bch2SbchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func isSbch2BchRefundable(status Sbch2BchStatus) bool {
+	switch status {
+	case Sbch2BchStatusBchRefunded, Sbch2BchStatusTooLateToLockBch, Sbch2BchStatusPriceChanged,
+		Sbch2BchStatusUnprofitable, Sbch2BchStatusRejected, Sbch2BchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// the user's BCH becomes refundable at BchLockHeight + TimeLock, or at BchExpiresAt if it is set
+func getBch2SbchStatusMsg(lang string, record *Bch2SbchRecord, bchHeight uint64, now int64) string {
+	msg := getStatusMsg(lang, DirectionBch2Sbch+"."+record.Status.String())
+	if !isBch2SbchRefundable(record.Status) {
+		return msg
+	}
+	if record.BchExpiresAt != 0 {
+		refundTime := int64(record.BchExpiresAt)
+		if now >= refundTime {
+			return msg + getStatusMsg(lang, msgSeparator) + getStatusMsg(lang, msgRefundNow)
+		}
+		return msg + getStatusMsg(lang, msgSeparator) +
+			getStatusMsg(lang, msgRefundInMinutes, (refundTime-now+59)/60)
+	}
+	refundHeight := record.BchLockHeight + uint64(record.TimeLock)
+	if bchHeight >= refundHeight {
+		return msg + getStatusMsg(lang, msgSeparator) + getStatusMsg(lang, msgRefundNow)
+	}
+	return msg + getStatusMsg(lang, msgSeparator) +
+		getStatusMsg(lang, msgRefundInBlocks, refundHeight-bchHeight)
+}
+
+// the user's sBCH becomes refundable at SbchLockTime + TimeLock
+func getSbch2BchStatusMsg(lang string, record *Sbch2BchRecord, now int64) string {
+	msg := getStatusMsg(lang, DirectionSbch2Bch+"."+record.Status.String())
+	if !isSbch2BchRefundable(record.Status) {
+		return msg
+	}
+	refundTime := int64(record.SbchLockTime) + int64(record.TimeLock)
+	if now >= refundTime {
+		return msg + getStatusMsg(lang, msgSeparator) + getStatusMsg(lang, msgRefundNow)
+	}
+	return msg + getStatusMsg(lang, msgSeparator) +
+		getStatusMsg(lang, msgRefundInMinutes, (refundTime-now+59)/60)
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRequestLang(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		lang   string
+	}{
+		{"", "en"},
+		{"en-US,en;q=0.9", "en"},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh"},
+		{"fr-FR,zh;q=0.5", "zh"},
+		{"fr-FR", "en"},
+		{"!!", "en"},
+	} {
+		r := httptest.NewRequest("GET", "/api/v1/swaps", nil)
+		r.Header.Set("Accept-Language", tc.header)
+		require.Equal(t, tc.lang, getRequestLang(r), tc.header)
+	}
+}
+
+func TestGetStatusMsg(t *testing.T) {
+	b2s := &Bch2SbchRecord{BchLockHeight: 100, TimeLock: 72, Status: Bch2SbchStatusNew}
+	require.Equal(t, "waiting for BCH confirmation, then the bot will lock sBCH",
+		getBch2SbchStatusMsg("en", b2s, 150, 0))
+	b2s.Status = Bch2SbchStatusPriceChanged
+	require.Equal(t, "price changed, the bot will not lock sBCH; refund available in 22 blocks",
+		getBch2SbchStatusMsg("en", b2s, 150, 0))
+	require.Equal(t, "价格已变化，机器人不会锁定sBCH；22个区块后可退款",
+		getBch2SbchStatusMsg("zh", b2s, 150, 0))
+	require.Equal(t, "price changed, the bot will not lock sBCH; refund available now",
+		getBch2SbchStatusMsg("en", b2s, 172, 0))
+	b2s.BchExpiresAt = 1700003600
+	require.Equal(t, "price changed, the bot will not lock sBCH; refund available in 30 minutes",
+		getBch2SbchStatusMsg("en", b2s, 172, 1700001801))
+	require.Equal(t, "price changed, the bot will not lock sBCH; refund available now",
+		getBch2SbchStatusMsg("en", b2s, 100, 1700003600))
+
+	s2b := &Sbch2BchRecord{SbchLockTime: 10000, TimeLock: 3600, Status: Sbch2BchStatusUnprofitable}
+	require.Equal(t, "value too small to cover the fees, the bot will not lock BCH; refund available in 30 minutes",
+		getSbch2BchStatusMsg("en", s2b, 11801))
+	require.Equal(t, "value too small to cover the fees, the bot will not lock BCH; refund available now",
+		getSbch2BchStatusMsg("en", s2b, 13600))
+	s2b.Status = Sbch2BchStatusSbchUnlocked
+	require.Equal(t, "兑换完成", getSbch2BchStatusMsg("zh", s2b, 0))
+
+	// fall back to English, then to the key
+	require.Equal(t, "swap completed", getStatusMsg("de", "bch2sbch.BchUnlocked"))
+	require.Equal(t, "bch2sbch.Unknown(99)", getStatusMsg("en", "bch2sbch.Unknown(99)"))
+}
@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"fmt"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/wire"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// how to handle BCH unlock|refund txs which are not confirmed in time
+const (
+	StuckTxStrategyAlert       = "alert"
+	StuckTxStrategyRebroadcast = "rebroadcast"
+	StuckTxStrategyCpfp        = "cpfp"
+
+	SwapLegBchCpfp = "bch_cpfp"
+
+	maxStuckTxBumps = 3
+)
+
+func checkStuckTxStrategy(strategy string) error {
+	switch strategy {
+	case StuckTxStrategyAlert, StuckTxStrategyRebroadcast, StuckTxStrategyCpfp:
+		return nil
+	default:
+		return fmt.Errorf("invalid stuck tx strategy: %s", strategy)
+	}
+}
+
+// remember the BCH tx sent by bot, so that we can detect if it is stuck,
+// fwdTx is the tx forwarding its output to the treasury, if any
+func (bot *MarketMakerBot) watchBchTx(hashLock, leg string, tx, fwdTx *wire.MsgTx) {
+	if bot.stuckTxBlocks == 0 {
+		return
+	}
+
+	h, err := bot.bchCli.GetBlockCount()
+	if err != nil {
+		bot.logError("RPC error, failed to get BCH height: ", err)
+		return
+	}
+
+	pendingTx := &PendingBchTx{
+		HashLock:   hashLock,
+		Leg:        leg,
+		TxHash:     tx.TxHash().String(),
+		RawTx:      htlcbch.MsgTxToHex(tx),
+		SentHeight: h,
+	}
+	if fwdTx != nil {
+		// rebroadcast with tx, it is a CPFP tx too
+		pendingTx.CpfpRawTx = htlcbch.MsgTxToHex(fwdTx)
+		pendingTx.CpfpSize = int64(len(htlcbch.MsgTxToBytes(fwdTx)))
+		pendingTx.CpfpFee = -fwdTx.TxOut[0].Value
+		for _, txIn := range fwdTx.TxIn { // all outputs of a batch claim tx paid to bot
+			pendingTx.CpfpFee += tx.TxOut[txIn.PreviousOutPoint.Index].Value
+		}
+	}
+	err = bot.db.addPendingBchTx(pendingTx)
+	if err != nil {
+		bot.logError("DB error, failed to save pending BCH tx: ", err)
+	}
+}
+
+// find BCH unlock|refund txs which are not confirmed within N blocks,
+// and rebroadcast|bump|alert them according to the configured strategy
+func (bot *MarketMakerBot) checkStuckBchTxs() {
+	if bot.stuckTxBlocks == 0 || !bot.canSign() {
+		return
+	}
+	log.Info("check stuck BCH txs ...")
+
+	txs, err := bot.db.getUnconfirmedBchTxs(bot.dbQueryLimit)
+	if err != nil {
+		bot.logError("DB error, failed to get pending BCH txs: ", err)
+		return
+	}
+	log.Info("pending BCH txs: ", len(txs))
+	if len(txs) == 0 {
+		return
+	}
+
+	h, err := bot.bchCli.GetBlockCount()
+	if err != nil {
+		bot.logError("RPC error, failed to get BCH height: ", err)
+		return
+	}
+
+	for _, tx := range txs {
+		// tx may be dropped from mempool, treat it as unconfirmed
+		confirmations, _ := bot.bchCli.GetTxConfirmations(tx.TxHash)
+		if confirmations > 0 {
+			tx.Confirmed = true
+			if err = bot.db.updatePendingBchTx(tx); err != nil {
+				bot.logError("DB error, failed to update pending BCH tx: ", err)
+			}
+			continue
+		}
+		if h-tx.SentHeight < int64(bot.stuckTxBlocks) {
+			continue
+		}
+
+		bot.handleStuckBchTx(tx)
+		tx.SentHeight = h
+		tx.Bumps++
+		if err = bot.db.updatePendingBchTx(tx); err != nil {
+			bot.logError("DB error, failed to update pending BCH tx: ", err)
+		}
+	}
+}
+
+func (bot *MarketMakerBot) handleStuckBchTx(tx *PendingBchTx) {
+	bot.logWarnf("BCH tx not confirmed in %d blocks, hashLock: %s, leg: %s, txHash: %s, bumps: %d",
+		bot.stuckTxBlocks, tx.HashLock, tx.Leg, tx.TxHash, tx.Bumps)
+	if bot.stuckTxStrategy == StuckTxStrategyAlert || bot.stuckTxStrategy == "" {
+		return
+	}
+
+	parentTx, err := htlcbch.MsgTxFromBytes(gethcmn.FromHex(tx.RawTx))
+	if err != nil {
+		bot.logError("failed to decode raw BCH tx: ", err)
+		return
+	}
+
+	// parent tx may be evicted from mempool, always rebroadcast it
+	bot.rebroadcastBchTx(parentTx)
+
+	// the last CPFP tx is rebroadcast, and the next one is chained off it,
+	// since BCH nodes reject a new child spending the same parent output (no RBF)
+	var lastCpfpTx *wire.MsgTx
+	if tx.CpfpRawTx != "" {
+		lastCpfpTx, err = htlcbch.MsgTxFromBytes(gethcmn.FromHex(tx.CpfpRawTx))
+		if err != nil {
+			bot.logError("failed to decode raw CPFP tx: ", err)
+			return
+		}
+		bot.rebroadcastBchTx(lastCpfpTx)
+	}
+
+	if bot.stuckTxStrategy != StuckTxStrategyCpfp {
+		return
+	}
+	if bot.bchPrivKey == nil {
+		log.Info("no BCH private key, can not create CPFP tx")
+		return
+	}
+	if lastCpfpTx != nil && bot.bchTreasury != nil {
+		log.Info("output is forwarded to the treasury, can not create CPFP tx")
+		return
+	}
+	if tx.Bumps >= maxStuckTxBumps {
+		bot.logWarnf("too many CPFP bumps, hashLock: %s, txHash: %s", tx.HashLock, tx.TxHash)
+		return
+	}
+
+	// double the fee rate on each bump
+	feeRate := bot.bchUnlockMinerFeeRate
+	if bot.bchRefundMinerFeeRate > feeRate {
+		feeRate = bot.bchRefundMinerFeeRate
+	}
+	feeRate <<= tx.Bumps + 1
+
+	// the child pays for the whole package at the new fee rate
+	cpfpParent := parentTx
+	ancestorSize := int64(0)
+	if lastCpfpTx != nil {
+		cpfpParent = lastCpfpTx
+		// parent tx and all CPFP txs but the last one
+		ancestorSize = int64(len(htlcbch.MsgTxToBytes(parentTx))) +
+			tx.CpfpSize - int64(len(htlcbch.MsgTxToBytes(lastCpfpTx)))
+	}
+	childTx, err := htlcbch.MakePackageCpfpTx(cpfpParent, ancestorSize, tx.CpfpFee, bot.bchPrivKey, feeRate,
+		bot.bchSigType, bot.getBchTxLockTime())
+	if err != nil {
+		bot.logError("failed to create CPFP tx: ", err)
+		return
+	}
+	log.Info("CPFP tx: ", htlcbch.MsgTxToHex(childTx))
+
+	txHash, err := bot.bchCli.SendTx(childTx)
+	if err != nil {
+		bot.logError("failed to send CPFP tx: ", err)
+		return
+	}
+	log.Info("CPFP tx sent, hash: ", txHash.String())
+	bot.saveBchSwapMsgTx(tx.HashLock, SwapLegBchCpfp, childTx)
+
+	tx.CpfpRawTx = htlcbch.MsgTxToHex(childTx)
+	tx.CpfpSize += int64(len(htlcbch.MsgTxToBytes(childTx)))
+	tx.CpfpFee += cpfpParent.TxOut[childTx.TxIn[0].PreviousOutPoint.Index].Value - childTx.TxOut[0].Value
+}
+
+func (bot *MarketMakerBot) rebroadcastBchTx(tx *wire.MsgTx) {
+	if txHash, err := bot.bchCli.SendTx(tx); err == nil {
+		log.Info("BCH tx rebroadcasted, hash: ", txHash.String())
+	} else {
+		log.Info("failed to rebroadcast BCH tx: ", err)
+	}
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestCheckStuckBchTxs_cpfp(t *testing.T) {
+	_hashLock := gethHash32Bytes("hash")
+	covenant, err := htlcbch.NewMainnetCovenant(testBchPkh, gethAddrBytes("user"), _hashLock, 100, 0)
+	require.NoError(t, err)
+	parentTx, err := covenant.MakeRefundTx(gethHash32Bytes("utxo"), 0, 100000, 1)
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 128)
+	_bot := &MarketMakerBot{
+		db:                    _db,
+		bchCli:                _bchCli,
+		bchPrivKey:            testBchPrivKey,
+		bchPkh:                testBchPkh,
+		bchRefundMinerFeeRate: 2,
+		dbQueryLimit:          100,
+		stuckTxBlocks:         3,
+		stuckTxStrategy:       StuckTxStrategyCpfp,
+		errLogQueue:           newErrLogQueue(100),
+	}
+
+	_bot.watchBchTx(toHex(_hashLock), SwapLegBchRefund, parentTx, nil)
+	txs, err := _db.getUnconfirmedBchTxs(100)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.Equal(t, int64(128), txs[0].SentHeight)
+
+	// not stuck yet
+	_bchCli.hTo = 130
+	_bot.checkStuckBchTxs()
+	require.Len(t, _bchCli.sentTxs, 0)
+
+	// stuck, rebroadcast parent and send child
+	_bchCli.hTo = 131
+	_bot.checkStuckBchTxs()
+	require.Len(t, _bchCli.sentTxs, 2)
+	require.Equal(t, parentTx.TxHash(), _bchCli.sentTxs[0].TxHash())
+	require.Equal(t, parentTx.TxHash(), _bchCli.sentTxs[1].TxIn[0].PreviousOutPoint.Hash)
+	txs, err = _db.getUnconfirmedBchTxs(100)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.Equal(t, int64(131), txs[0].SentHeight)
+	require.Equal(t, uint8(1), txs[0].Bumps)
+
+	child1 := _bchCli.sentTxs[1]
+	require.Equal(t, htlcbch.MsgTxToHex(child1), txs[0].CpfpRawTx)
+	require.Equal(t, int64(len(htlcbch.MsgTxToBytes(child1))), txs[0].CpfpSize)
+	require.Equal(t, parentTx.TxOut[0].Value-child1.TxOut[0].Value, txs[0].CpfpFee)
+
+	swapTxs, err := _db.getSwapTxsByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Len(t, swapTxs, 1)
+	require.Equal(t, SwapLegBchCpfp, swapTxs[0].Leg)
+
+	// still stuck, rebroadcast parent and child, and chain a new child off the last one,
+	// which pays for the package at the doubled fee rate
+	_bchCli.hTo = 134
+	_bot.checkStuckBchTxs()
+	require.Len(t, _bchCli.sentTxs, 5)
+	require.Equal(t, parentTx.TxHash(), _bchCli.sentTxs[2].TxHash())
+	require.Equal(t, child1.TxHash(), _bchCli.sentTxs[3].TxHash())
+	child2 := _bchCli.sentTxs[4]
+	require.Equal(t, child1.TxHash(), child2.TxIn[0].PreviousOutPoint.Hash)
+	packageSize := len(htlcbch.MsgTxToBytes(parentTx)) + len(htlcbch.MsgTxToBytes(child1)) +
+		len(htlcbch.MsgTxToBytes(child2))
+	require.InDelta(t, packageSize*8, parentTx.TxOut[0].Value-child2.TxOut[0].Value, 16)
+	txs, err = _db.getUnconfirmedBchTxs(100)
+	require.NoError(t, err)
+	require.Equal(t, uint8(2), txs[0].Bumps)
+	require.Equal(t, htlcbch.MsgTxToHex(child2), txs[0].CpfpRawTx)
+	require.Equal(t, parentTx.TxOut[0].Value-child2.TxOut[0].Value, txs[0].CpfpFee)
+
+	// confirmed
+	_bchCli.hTo = 140
+	_bchCli.confirmations[parentTx.TxHash().String()] = 1
+	_bot.checkStuckBchTxs()
+	require.Len(t, _bchCli.sentTxs, 5)
+	txs, err = _db.getUnconfirmedBchTxs(100)
+	require.NoError(t, err)
+	require.Len(t, txs, 0)
+}
+
+func TestCheckStuckBchTxs_alert(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 128)
+	_bot := &MarketMakerBot{
+		db:              _db,
+		bchCli:          _bchCli,
+		dbQueryLimit:    100,
+		stuckTxBlocks:   3,
+		stuckTxStrategy: StuckTxStrategyAlert,
+		errLogQueue:     newErrLogQueue(100),
+	}
+
+	covenant, err := htlcbch.NewMainnetCovenant(gethAddrBytes("user"), testBchPkh, gethHash32Bytes("hash"), 100, 0)
+	require.NoError(t, err)
+	tx, err := covenant.MakeUnlockTx(gethHash32Bytes("utxo"), 0, 100000, 1, gethHash32Bytes("secret"))
+	require.NoError(t, err)
+
+	_bot.watchBchTx("hash", SwapLegBchUnlock, tx, nil)
+	_bchCli.hTo = 131
+	_bot.checkStuckBchTxs()
+	require.Len(t, _bchCli.sentTxs, 0)
+	logs := _bot.errLogQueue.removeErrLogs(10)
+	require.Len(t, logs, 1)
+	require.Contains(t, logs[0].Msg, "BCH tx not confirmed in 3 blocks")
+}
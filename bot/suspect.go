@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+type SuspectTxInfo struct {
+	TxHash  string `json:"tx_hash"`
+	Height  uint64 `json:"height"`
+	Reason  string `json:"reason"`
+	RawTx   string `json:"raw_tx"`
+	FoundAt int64  `json:"found_at"` // unix timestamp
+}
+
+// save txs which look like HTLC txs but are not valid ones, so that protocol bugs
+// or malicious probes are visible rather than silently dropped
+func (bot *MarketMakerBot) saveSuspectBchTxs(h uint64, suspects []*htlcbch.SuspectTx) bool {
+	for _, suspect := range suspects {
+		log.Warnf("suspect tx: %s, reason: %s", suspect.TxHash, suspect.Reason)
+		err := bot.db.addSuspectBchTx(&SuspectBchTx{
+			TxHash: suspect.TxHash,
+			Height: h,
+			Reason: suspect.Reason,
+			RawTx:  suspect.RawTx,
+		})
+		if err != nil {
+			bot.logError("DB error, failed to save suspect tx: ", err)
+			return false
+		}
+	}
+	return true
+}
+
+// return the latest suspect txs
+func (bot *MarketMakerBot) handleSuspects(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleReader, false) {
+		return
+	}
+	n := getIntQueryParam(r, "n", 100)
+	if n <= 0 || n > bot.dbQueryLimit {
+		n = bot.dbQueryLimit
+	}
+	suspects, err := bot.db.getSuspectBchTxs(n)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	infos := make([]*SuspectTxInfo, len(suspects))
+	for i, suspect := range suspects {
+		infos[i] = &SuspectTxInfo{
+			TxHash:  suspect.TxHash,
+			Height:  suspect.Height,
+			Reason:  suspect.Reason,
+			RawTx:   suspect.RawTx,
+			FoundAt: suspect.CreatedAt.Unix(),
+		}
+	}
+	NewOkResp(infos).WriteTo(w)
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestSuspectBchTxs(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{
+		db:           _db,
+		adminToken:   "secret",
+		dbQueryLimit: 100,
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	require.True(t, _bot.saveSuspectBchTxs(123, []*htlcbch.SuspectTx{
+		{TxHash: "tx1", Reason: "output#0 is not P2SH", RawTx: "raw1"},
+		{TxHash: "tx2", Reason: "malformed SBAS OP_RETURN", RawTx: "raw2"},
+	}))
+	// found again
+	require.True(t, _bot.saveSuspectBchTxs(123, []*htlcbch.SuspectTx{
+		{TxHash: "tx1", Reason: "output#0 is not P2SH", RawTx: "raw1"},
+	}))
+
+	getSuspects := func(token, query string) (infos []*SuspectTxInfo, errMsg string) {
+		req := httptest.NewRequest("GET", "/admin/suspects"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		_bot.handleSuspects(w, req)
+		var resp struct {
+			Success bool             `json:"success"`
+			Error   string           `json:"error"`
+			Result  []*SuspectTxInfo `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Result, resp.Error
+	}
+
+	_, errMsg := getSuspects("wrong", "")
+	require.Contains(t, errMsg, "unauthorized")
+	infos, errMsg := getSuspects("secret", "")
+	require.Empty(t, errMsg)
+	require.Len(t, infos, 2)
+	require.Equal(t, "tx2", infos[0].TxHash)
+	require.Equal(t, "malformed SBAS OP_RETURN", infos[0].Reason)
+	require.Equal(t, uint64(123), infos[1].Height)
+	require.Equal(t, "raw1", infos[1].RawTx)
+	infos, _ = getSuspects("secret", "?n=1")
+	require.Len(t, infos, 1)
+
+	n, err := _db.pruneSuspectBchTxs(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+}
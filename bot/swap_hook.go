@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"plugin"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	HookOnDepositDetected = "OnDepositDetected"
+	HookBeforeLock        = "BeforeLock"
+	HookBeforeRedeem      = "BeforeRedeem"
+	HookBeforeRefund      = "BeforeRefund"
+
+	// the exported variable of type SwapHook looked up in plugins
+	swapHookPluginSymbol = "SwapHook"
+)
+
+// ErrSwapHookRetry can be wrapped by hooks which can not decide now (e.g. a service is down),
+// the action is retried next round instead of being vetoed for good
+var ErrSwapHookRetry = errors.New("retry later")
+
+// SwapAction describes the swap an action is about to be taken on
+type SwapAction struct {
+	Direction   string // bch2sbch|sbch2bch
+	HashLock    string // hex
+	Value       uint64 // in sats, deposited by user
+	Price       uint64 // 8 decimals, BCH price for bch2sbch or sBCH price for sbch2bch
+	Token       string // SEP20 token symbol, empty means sBCH
+	UserBchPkh  string // hex
+	UserEvmAddr string // hex
+	DepositTx   string // hex, BCH lock tx for bch2sbch or sBCH lock tx for sbch2bch
+}
+
+// SwapHook lets custom policies (AML checks, dynamic pricing, etc.) veto or annotate
+// what the bot is about to do, without forking it. Each method returns a non-nil error
+// to veto the action, and a note to annotate it (logged). Hooks are called from the bot
+// loop one at a time, so they should return quickly.
+//
+//	OnDepositDetected : user deposit found on chain, vetoed deposits are ignored
+//	BeforeLock        : bot is about to lock BCH|sBCH, vetoed swaps are marked as Rejected (unless ErrSwapHookRetry)
+//	BeforeRedeem      : bot is about to unlock user deposit with revealed secret, retried next round if vetoed
+//	BeforeRefund      : bot is about to refund its own lock, retried next round if vetoed
+type SwapHook interface {
+	OnDepositDetected(action *SwapAction) (note string, err error)
+	BeforeLock(action *SwapAction) (note string, err error)
+	BeforeRedeem(action *SwapAction) (note string, err error)
+	BeforeRefund(action *SwapAction) (note string, err error)
+}
+
+// NopSwapHook can be embedded by hooks which only care about some actions
+type NopSwapHook struct{}
+
+func (NopSwapHook) OnDepositDetected(*SwapAction) (string, error) { return "", nil }
+func (NopSwapHook) BeforeLock(*SwapAction) (string, error)        { return "", nil }
+func (NopSwapHook) BeforeRedeem(*SwapAction) (string, error)      { return "", nil }
+func (NopSwapHook) BeforeRefund(*SwapAction) (string, error)      { return "", nil }
+
+// LoadSwapHookPlugin opens a Go plugin (built with -buildmode=plugin against the same
+// version of this module) which exports a variable named SwapHook
+func LoadSwapHookPlugin(path string) (SwapHook, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(swapHookPluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	hook, ok := sym.(*SwapHook)
+	if !ok || *hook == nil {
+		return nil, fmt.Errorf("%s: %s is not a bot.SwapHook", path, swapHookPluginSymbol)
+	}
+	return *hook, nil
+}
+
+// AddSwapHook registers a hook, hooks are called in the order they are added.
+// It must be called before Loop().
+func (bot *MarketMakerBot) AddSwapHook(hook SwapHook) {
+	bot.swapHooks = append(bot.swapHooks, hook)
+}
+
+// call hooks in order, returns the error of the first hook which vetoes the action
+func (bot *MarketMakerBot) callSwapHooks(hookName string, action *SwapAction) error {
+	for _, hook := range bot.swapHooks {
+		var note string
+		var err error
+		switch hookName {
+		case HookOnDepositDetected:
+			note, err = hook.OnDepositDetected(action)
+		case HookBeforeLock:
+			note, err = hook.BeforeLock(action)
+		case HookBeforeRedeem:
+			note, err = hook.BeforeRedeem(action)
+		case HookBeforeRefund:
+			note, err = hook.BeforeRefund(action)
+		}
+		if note != "" {
+			log.Infof("%s annotated by hook, hashLock: %s, note: %s", hookName, action.HashLock, note)
+		}
+		if err != nil {
+			vetoKey := hookName + ":" + action.HashLock
+			if !bot.hookVetoed[vetoKey] {
+				// retried actions are only alerted once
+				if bot.hookVetoed == nil {
+					bot.hookVetoed = map[string]bool{}
+				}
+				bot.hookVetoed[vetoKey] = true
+				bot.logWarnf("%s vetoed by hook, direction: %s, hashLock: %s, reason: %s",
+					hookName, action.Direction, action.HashLock, err)
+			}
+			return err
+		}
+	}
+	delete(bot.hookVetoed, hookName+":"+action.HashLock)
+	return nil
+}
+
+func newBch2SbchAction(record *Bch2SbchRecord) *SwapAction {
+	return &SwapAction{
+		Direction:   DirectionBch2Sbch,
+		HashLock:    record.HashLock,
+		Value:       record.Value,
+		Price:       record.BchPrice,
+		Token:       record.Token,
+		UserBchPkh:  record.SenderPkh,
+		UserEvmAddr: record.SenderEvmAddr,
+		DepositTx:   record.BchLockTxHash,
+	}
+}
+
+func newSbch2BchAction(record *Sbch2BchRecord) *SwapAction {
+	return &SwapAction{
+		Direction:   DirectionSbch2Bch,
+		HashLock:    record.HashLock,
+		Value:       record.Value,
+		Price:       record.SbchPrice,
+		Token:       record.Token,
+		UserBchPkh:  record.BchRecipientPkh,
+		UserEvmAddr: record.SbchSenderAddr,
+		DepositTx:   record.SbchLockTxHash,
+	}
+}
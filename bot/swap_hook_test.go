@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSwapHook struct {
+	NopSwapHook
+	deniedEvmAddr string
+	calls         []string
+}
+
+func (h *testSwapHook) BeforeLock(action *SwapAction) (string, error) {
+	h.calls = append(h.calls, HookBeforeLock+":"+action.HashLock)
+	if action.UserEvmAddr == h.deniedEvmAddr {
+		return "", errors.New("flagged by AML check")
+	}
+	return "checked", nil
+}
+
+func TestSwapHooks_vetoLock(t *testing.T) {
+	_val := uint64(12345678)
+	_botPkh := gethAddrBytes("bot")
+	_userPkh := gethAddrBytes("user")
+	_evmAddr := gethAddrBytes("evm")
+	_scriptHash := gethAddrBytes("htlc")
+
+	_db := initDB(t, 123, 456)
+	for _, name := range []string{"a", "b"} {
+		evmAddr := _evmAddr
+		if name == "b" {
+			evmAddr = gethAddrBytes("evm2")
+		}
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockHeight:  123,
+			BchLockTxHash:  toHex(gethHash32Bytes("bchlock" + name)),
+			Value:          _val,
+			BchPrice:       1e8,
+			RecipientPkh:   toHex(_botPkh),
+			SenderPkh:      toHex(_userPkh),
+			HashLock:       toHex(gethHash32Bytes("hash" + name)),
+			TimeLock:       100,
+			SenderEvmAddr:  toHex(evmAddr),
+			HtlcScriptHash: toHex(_scriptHash),
+			Status:         Bch2SbchStatusNew,
+		}))
+	}
+
+	hook := &testSwapHook{deniedEvmAddr: toHex(_evmAddr)}
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       newMockBchClient(124, 125),
+		sbchCli:      newMockSbchClient(457, 999, 0),
+		bchPkh:       _botPkh,
+		bchTimeLock:  72,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+		errLogQueue:  newErrLogQueue(100),
+	}
+	_bot.AddSwapHook(hook)
+	_bot.handleBchUserDeposits()
+	require.Equal(t, []string{
+		HookBeforeLock + ":" + toHex(gethHash32Bytes("hasha")),
+		HookBeforeLock + ":" + toHex(gethHash32Bytes("hashb")),
+	}, hook.calls)
+
+	rejected, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusRejected, 100)
+	require.NoError(t, err)
+	require.Len(t, rejected, 1)
+	require.Equal(t, toHex(_evmAddr), rejected[0].SenderEvmAddr)
+	require.Equal(t, "Rejected", Bch2SbchStatusRejected.String())
+
+	locked, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusSbchLocked, 100)
+	require.NoError(t, err)
+	require.Len(t, locked, 1)
+
+	errLogs := _bot.errLogQueue.removeErrLogs(100)
+	require.Len(t, errLogs, 1)
+	require.Contains(t, errLogs[0].Msg, "BeforeLock vetoed by hook")
+}
+
+func TestSwapHooks_vetoAlertedOnce(t *testing.T) {
+	hook := &testSwapHook{deniedEvmAddr: "0xabcd"}
+	_bot := &MarketMakerBot{errLogQueue: newErrLogQueue(100)}
+	_bot.AddSwapHook(NopSwapHook{})
+	_bot.AddSwapHook(hook)
+
+	action := &SwapAction{Direction: DirectionSbch2Bch, HashLock: "0x1234", UserEvmAddr: "0xabcd"}
+	require.NoError(t, _bot.callSwapHooks(HookBeforeRedeem, action))
+	require.Error(t, _bot.callSwapHooks(HookBeforeLock, action))
+	require.Error(t, _bot.callSwapHooks(HookBeforeLock, action))
+	require.Len(t, _bot.errLogQueue.removeErrLogs(100), 1)
+
+	action.UserEvmAddr = "0x5678"
+	require.NoError(t, _bot.callSwapHooks(HookBeforeLock, action))
+	require.Len(t, _bot.hookVetoed, 0)
+}
+
+func TestLoadSwapHookPlugin(t *testing.T) {
+	_, err := LoadSwapHookPlugin("not-exist.so")
+	require.Error(t, err)
+}
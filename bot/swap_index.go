@@ -0,0 +1,161 @@
+package bot
+
+import (
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gcash/bchd/btcjson"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+// In bootstrap mode the scanners of a fresh DB start from the first block the SBAS protocol
+// was used instead of the chain tips, and every HTLC found is indexed whoever locked it, so
+// that the DB becomes a complete historical swap index for explorers and analytics. Explorer
+// mode indexes HTLCs the same way and serves them, see explorer.go. Both modes require observer
+// mode, the swaps of the bot itself are still tracked as usual.
+
+// max number of lock txs looked up by one query, below the variable limit of SQLite
+const swapIndexQueryBatch = 500
+
+// the last BCH height of a fresh DB, so that the scanner starts at FirstSbasHeight
+func (bot *MarketMakerBot) getBootstrapLastBchHeight() uint64 {
+	if h := bot.getBchNet().FirstSbasHeight; h > 0 {
+		return uint64(h - 1)
+	}
+	return 0
+}
+
+// the last sBCH height of a fresh DB, so that the scanner starts at bootstrapSbchHeight
+func (bot *MarketMakerBot) getBootstrapLastSbchHeight() uint64 {
+	if bot.bootstrapSbchHeight > 0 {
+		return bot.bootstrapSbchHeight - 1
+	}
+	return 0
+}
+
+// index deposits, receipts and refunds found in BCH block#h
+func (bot *MarketMakerBot) indexBchBlock(h uint64, block *btcjson.GetBlockVerboseTxResult,
+	scan *htlcbch.BlockScan) error {
+
+	for _, deposit := range scan.Deposits {
+		err := bot.db.addIndexedBchHtlc(&IndexedBchHtlc{
+			LockTxHash:    deposit.TxHash,
+			LockVout:      deposit.Vout,
+			LockHeight:    h,
+			HashLock:      toHex(deposit.HashLock),
+			SenderPkh:     toHex(deposit.SenderPkh),
+			RecipientPkh:  toHex(deposit.RecipientPkh),
+			SenderEvmAddr: toHex(deposit.SenderEvmAddr),
+			ScriptHash:    toHex(deposit.ScriptHash),
+			Value:         deposit.Value,
+			Expiration:    uint32(deposit.Expiration),
+			PenaltyBPS:    deposit.PenaltyBPS,
+			ExpectedPrice: deposit.ExpectedPrice,
+			Template:      deposit.Template,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	receiptTxs := map[string]bool{}
+	for _, receipt := range scan.Receipts {
+		receiptTxs[receipt.TxHash] = true
+		err := bot.db.setIndexedBchHtlcUnlocked(receipt.PrevTxHash, receipt.TxHash, receipt.Secret, h)
+		if err != nil {
+			return err
+		}
+	}
+
+	// other txs spending covenants are refunds
+	spentBy := map[string]map[uint32]string{} // prev tx hash => vout => spending tx hash
+	var prevTxHashes []string
+	for _, tx := range block.Tx {
+		if receiptTxs[tx.Txid] {
+			continue
+		}
+		for _, vin := range tx.Vin {
+			if vin.Txid == "" { // coinbase
+				continue
+			}
+			if spentBy[vin.Txid] == nil {
+				spentBy[vin.Txid] = map[uint32]string{}
+				prevTxHashes = append(prevTxHashes, vin.Txid)
+			}
+			spentBy[vin.Txid][vin.Vout] = tx.Txid
+		}
+	}
+	for len(prevTxHashes) > 0 {
+		n := len(prevTxHashes)
+		if n > swapIndexQueryBatch {
+			n = swapIndexQueryBatch
+		}
+		htlcs, err := bot.db.getUnspentIndexedBchHtlcs(prevTxHashes[:n])
+		if err != nil {
+			return err
+		}
+		prevTxHashes = prevTxHashes[n:]
+		for _, htlc := range htlcs {
+			refundTxHash, ok := spentBy[htlc.LockTxHash][htlc.LockVout]
+			if !ok {
+				continue // other outputs of the lock tx are spent
+			}
+			log.Info("indexed BCH refund: ", refundTxHash, ", lock tx: ", htlc.LockTxHash)
+			if err = bot.db.setIndexedBchHtlcRefunded(htlc.LockTxHash, refundTxHash, h); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// index lock, unlock and refund events of all HTLC contracts
+func (bot *MarketMakerBot) indexSbchLogs(logs []gethtypes.Log) error {
+	for _, ethLog := range logs {
+		if len(ethLog.Topics) == 0 {
+			continue
+		}
+		htlcAddr := toHex(ethLog.Address.Bytes())
+		var err error
+		switch ethLog.Topics[0] {
+		case htlcsbch.LockEventId:
+			lockLog := htlcsbch.ParseHtlcLockLog(ethLog)
+			if lockLog == nil {
+				continue
+			}
+			err = bot.db.addIndexedSbchHtlc(&IndexedSbchHtlc{
+				HtlcAddr:        htlcAddr,
+				LockTxHash:      toHex(ethLog.TxHash.Bytes()),
+				LockHeight:      ethLog.BlockNumber,
+				HashLock:        toHex(lockLog.HashLock.Bytes()),
+				LockerAddr:      toHex(lockLog.LockerAddr.Bytes()),
+				UnlockerAddr:    toHex(lockLog.UnlockerAddr.Bytes()),
+				BchRecipientPkh: toHex(lockLog.BchRecipientPkh.Bytes()),
+				Value:           lockLog.Value.String(),
+				UnlockTime:      lockLog.UnlockTime,
+				CreatedTime:     lockLog.CreatedTime,
+				PenaltyBPS:      lockLog.PenaltyBPS,
+				ExpectedPrice:   lockLog.ExpectedPrice.String(),
+			})
+		case htlcsbch.UnlockEventId:
+			unlockLog := htlcsbch.ParseHtlcUnlockLog(ethLog)
+			if unlockLog == nil {
+				continue
+			}
+			err = bot.db.setIndexedSbchHtlcUnlocked(htlcAddr, toHex(unlockLog.HashLock.Bytes()),
+				toHex(unlockLog.TxHash.Bytes()), toHex(unlockLog.Secret.Bytes()), ethLog.BlockNumber)
+		case htlcsbch.RefundEventId:
+			refundLog := htlcsbch.ParseHtlcRefundLog(ethLog)
+			if refundLog == nil {
+				continue
+			}
+			err = bot.db.setIndexedSbchHtlcRefunded(htlcAddr, toHex(refundLog.HashLock.Bytes()),
+				toHex(refundLog.TxHash.Bytes()), ethLog.BlockNumber)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
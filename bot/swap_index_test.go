@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+func TestBootstrap_bch(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_otherBotPkh := gethAddrBytes("otherbot")
+	_hashLock := gethHash32Bytes("hash")
+	_timeLock := uint16(100)
+	_penaltyBPS := uint16(500)
+	_evmAddr := gethAddrBytes("evm")
+
+	covenant, err := htlcbch.NewMainnetCovenant(_userPkh, _otherBotPkh, _hashLock, _timeLock, _penaltyBPS)
+	require.NoError(t, err)
+	scriptHash, err := covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+
+	// deposit to another bot
+	lockTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{{PreviousOutPoint: wire.OutPoint{Hash: bchHash32("utxo")}}},
+		TxOut: []*wire.TxOut{
+			{Value: 12345678, PkScript: newP2SHPkScript(scriptHash)},
+			{PkScript: newHtlcDepositOpRet(_otherBotPkh, _userPkh, _hashLock, _timeLock, _penaltyBPS, _evmAddr, 1e8)},
+			{Value: 5000, PkScript: []byte{0x76}}, // change
+		},
+	}
+	_bchCli := newMockBchClient(1, 8)
+	_bchCli.blocks[3] = &wire.MsgBlock{Transactions: []*wire.MsgTx{lockTx}}
+	// spends the change
+	_bchCli.blocks[4] = &wire.MsgBlock{Transactions: []*wire.MsgTx{{
+		TxIn:  []*wire.TxIn{{PreviousOutPoint: wire.OutPoint{Hash: lockTx.TxHash(), Index: 2}}},
+		TxOut: []*wire.TxOut{{Value: 4000, PkScript: []byte{0x76}}},
+	}}}
+	// refunds the covenant
+	refundTx := &wire.MsgTx{
+		TxIn:  []*wire.TxIn{{PreviousOutPoint: wire.OutPoint{Hash: lockTx.TxHash(), Index: 0}}},
+		TxOut: []*wire.TxOut{{Value: 12340000, PkScript: []byte{0x76}}},
+	}
+	_bchCli.blocks[6] = &wire.MsgBlock{Transactions: []*wire.MsgTx{refundTx}}
+
+	bchNet := *htlcbch.MainNet
+	bchNet.FirstSbasHeight = 3
+	_db := initDB(t, 0, 0)
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		bchNet:       &bchNet,
+		bchPkh:       testBchPkh,
+		bchTimeLock:  _timeLock,
+		penaltyRatio: _penaltyBPS,
+		isSlaveMode:  true,
+		errLogQueue:  newErrLogQueue(100),
+		bootstrap:    true,
+	}
+	_bot.scanBchBlocks()
+	lastH, err := _db.getLastBchHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), lastH)
+	require.Equal(t, 6, _bchCli.getBlockCalls) // from block#3
+
+	var htlcs []*IndexedBchHtlc
+	require.NoError(t, _db.db.Find(&htlcs).Error)
+	require.Len(t, htlcs, 1)
+	require.Equal(t, lockTx.TxHash().String(), htlcs[0].LockTxHash)
+	require.Equal(t, uint64(3), htlcs[0].LockHeight)
+	require.Equal(t, toHex(_hashLock), htlcs[0].HashLock)
+	require.Equal(t, toHex(_userPkh), htlcs[0].SenderPkh)
+	require.Equal(t, toHex(_otherBotPkh), htlcs[0].RecipientPkh)
+	require.Equal(t, toHex(_evmAddr), htlcs[0].SenderEvmAddr)
+	require.Equal(t, uint64(12345678), htlcs[0].Value)
+	require.Equal(t, refundTx.TxHash().String(), htlcs[0].RefundTxHash)
+	require.Equal(t, uint64(6), htlcs[0].UnlockHeight)
+	require.Equal(t, "", htlcs[0].UnlockTxHash)
+
+	// not the swap of this bot
+	records, err := _db.getBch2SbchRecordsByStatus(Bch2SbchStatusNew, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 0)
+}
+
+func TestBootstrap_sbch(t *testing.T) {
+	_secret := gethHash32("secret")
+	_hashLock := gethcmn.Hash(sha256.Sum256(_secret[:]))
+	_hashLock2 := gethHash32("hash2")
+	_htlcAddr := gethAddr("htlc")
+	_lockerAddr := gethAddr("locker")
+	_unlockerAddr := gethAddr("unlocker")
+
+	newLockLog := func(h uint64, txHash, hashLock gethcmn.Hash) gethtypes.Log {
+		return gethtypes.Log{
+			Address:     _htlcAddr,
+			BlockNumber: h,
+			TxHash:      txHash,
+			Topics: []gethcmn.Hash{
+				htlcsbch.LockEventId,
+				gethAddrToHash32(_lockerAddr),
+				gethAddrToHash32(_unlockerAddr),
+			},
+			Data: joinBytes(
+				hashLock[:],
+				int64ToBytes32(1700000000),
+				satsToWeiBytes32(12345678),
+				rightPad0(gethAddrBytes("pkh"), 12),
+				int64ToBytes32(1690000000),
+				int64ToBytes32(500),
+				int64ToBytes32(1e8),
+			),
+		}
+	}
+
+	_sbchCli := newMockSbchClient(1, 999, 0)
+	_sbchCli.logs[100] = []gethtypes.Log{
+		newLockLog(100, gethHash32("lock1"), _hashLock),
+		newLockLog(100, gethHash32("lock2"), _hashLock2),
+	}
+	_sbchCli.logs[300] = []gethtypes.Log{{
+		Address:     _htlcAddr,
+		BlockNumber: 300,
+		TxHash:      gethHash32("unlock1"),
+		Topics:      []gethcmn.Hash{htlcsbch.UnlockEventId, _hashLock, _secret},
+	}}
+	_sbchCli.logs[900] = []gethtypes.Log{{
+		Address:     _htlcAddr,
+		BlockNumber: 900,
+		TxHash:      gethHash32("refund2"),
+		Topics:      []gethcmn.Hash{htlcsbch.RefundEventId, _hashLock2},
+	}}
+
+	_db := initDB(t, 0, 0)
+	_bot := &MarketMakerBot{
+		db:                  _db,
+		dbQueryLimit:        100,
+		sbchCli:             _sbchCli,
+		sbchAddr:            gethAddr("bot"),
+		isSlaveMode:         true,
+		errLogQueue:         newErrLogQueue(100),
+		bootstrap:           true,
+		bootstrapSbchHeight: 50,
+	}
+	_bot.scanSbchEvents()
+	lastH, err := _db.getLastSbchHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(999), lastH)
+
+	var htlcs []*IndexedSbchHtlc
+	require.NoError(t, _db.db.Order("id").Find(&htlcs).Error)
+	require.Len(t, htlcs, 2)
+	require.Equal(t, toHex(_htlcAddr[:]), htlcs[0].HtlcAddr)
+	require.Equal(t, toHex(_hashLock[:]), htlcs[0].HashLock)
+	require.Equal(t, toHex(_lockerAddr[:]), htlcs[0].LockerAddr)
+	require.Equal(t, toHex(_unlockerAddr[:]), htlcs[0].UnlockerAddr)
+	require.Equal(t, satsToWei(12345678).String(), htlcs[0].Value)
+	require.Equal(t, uint64(1700000000), htlcs[0].UnlockTime)
+	require.Equal(t, uint16(500), htlcs[0].PenaltyBPS)
+	require.Equal(t, "100000000", htlcs[0].ExpectedPrice)
+	require.Equal(t, toHex(gethHash32Bytes("unlock1")), htlcs[0].UnlockTxHash)
+	require.Equal(t, toHex(_secret[:]), htlcs[0].Secret)
+	require.Equal(t, uint64(300), htlcs[0].UnlockHeight)
+	require.Equal(t, "", htlcs[0].RefundTxHash)
+	require.Equal(t, toHex(gethHash32Bytes("refund2")), htlcs[1].RefundTxHash)
+	require.Equal(t, uint64(900), htlcs[1].UnlockHeight)
+	require.Equal(t, "", htlcs[1].UnlockTxHash)
+}
@@ -0,0 +1,248 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultSwapListLimit = 50
+	maxSwapListLimit     = 500
+)
+
+var (
+	bch2SbchStatusNames = []string{"New", "SbchLocked", "SecretRevealed", "BchUnlocked", "SbchRefunded",
+		"TooLateToLockSbch", "PriceChanged", "Unprofitable", "RemainderRefunded", "Rejected", "Cancelled"}
+	sbch2BchStatusNames = []string{"New", "BchLocked", "SecretRevealed", "SbchUnlocked", "BchRefunded",
+		"TooLateToLockBch", "PriceChanged", "Unprofitable", "Rejected", "Cancelled"}
+)
+
+func (s Bch2SbchStatus) String() string {
+	if int(s) < len(bch2SbchStatusNames) {
+		return bch2SbchStatusNames[s]
+	}
+	return fmt.Sprintf("Unknown(%d)", int(s))
+}
+
+func (s Sbch2BchStatus) String() string {
+	if int(s) < len(sbch2BchStatusNames) {
+		return sbch2BchStatusNames[s]
+	}
+	return fmt.Sprintf("Unknown(%d)", int(s))
+}
+
+// SwapFilter selects a page of swap records, ordered by ID (creation order)
+type SwapFilter struct {
+	Statuses []int     // empty means all
+	Sender   string    // hex, BCH PKH or EVM address of user, empty means all
+	HashLock string    // hex, empty means all
+	From     time.Time // created at or after, zero means unbounded
+	To       time.Time // created before, zero means unbounded
+	Cursor   uint      // ID of the last record of previous page, 0 means first page
+	Desc     bool      // newest first
+	Limit    int
+	Archived bool // query archive tables
+}
+
+type SwapListItem struct {
+	Id        uint    `json:"id"`
+	Direction string  `json:"direction"`
+	HashLock  string  `json:"hash_lock"`
+	Value     float64 `json:"value"`
+	Price     uint64  `json:"price"` // 8 decimals
+	Status    string  `json:"status"`
+	StatusMsg string  `json:"status_msg"`      // translated by Accept-Language, see status_msg.go
+	Token     string  `json:"token,omitempty"` // SEP20 token symbol
+	Memo      string  `json:"memo,omitempty"`  // user reference ID carried by the deposit
+	Sender    string  `json:"sender"`          // BCH PKH for bch2sbch, EVM address for sbch2bch
+	Recipient string  `json:"recipient"`       // EVM address for bch2sbch, BCH PKH for sbch2bch
+	CreatedAt int64   `json:"created_at"`      // unix timestamp
+	UpdatedAt int64   `json:"updated_at"`      // unix timestamp
+}
+
+type SwapList struct {
+	Swaps      []SwapListItem `json:"swaps"`
+	NextCursor uint           `json:"next_cursor,omitempty"` // absent on the last page
+}
+
+func (f *SwapFilter) apply(q *gorm.DB, senderCols ...string) *gorm.DB {
+	if len(f.Statuses) > 0 {
+		q = q.Where("status IN ?", f.Statuses)
+	}
+	if f.Sender != "" {
+		conds := make([]string, len(senderCols))
+		args := make([]any, len(senderCols))
+		for i, col := range senderCols {
+			conds[i] = col + " = ?"
+			args[i] = f.Sender
+		}
+		q = q.Where(strings.Join(conds, " OR "), args...)
+	}
+	if f.HashLock != "" {
+		q = q.Where("hash_lock = ?", f.HashLock)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+	if f.Cursor > 0 {
+		if f.Desc {
+			q = q.Where("id < ?", f.Cursor)
+		} else {
+			q = q.Where("id > ?", f.Cursor)
+		}
+	}
+	return q.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: f.Desc}).
+		Limit(f.Limit)
+}
+
+func (db DB) listBch2SbchRecords(f *SwapFilter) (records []*Bch2SbchRecord, err error) {
+	q := db.db.Model(&Bch2SbchRecord{})
+	if f.Archived {
+		q = db.db.Model(&ArchivedBch2SbchRecord{})
+	}
+	result := f.apply(q, "sender_pkh", "sender_evm_addr").Find(&records)
+	err = result.Error
+	return
+}
+
+func (db DB) listSbch2BchRecords(f *SwapFilter) (records []*Sbch2BchRecord, err error) {
+	q := db.db.Model(&Sbch2BchRecord{})
+	if f.Archived {
+		q = db.db.Model(&ArchivedSbch2BchRecord{})
+	}
+	result := f.apply(q, "sbch_sender_addr", "bch_recipient_pkh").Find(&records)
+	err = result.Error
+	return
+}
+
+func parseStatusNames(s string, names []string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var statuses []int
+	for _, name := range strings.Split(s, ",") {
+		status := -1
+		for i, x := range names {
+			if strings.EqualFold(x, name) {
+				status = i
+			}
+		}
+		if status < 0 {
+			return nil, fmt.Errorf("invalid status: %s", name)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (bot *MarketMakerBot) listSwaps(direction, status, lang string, f *SwapFilter) (*SwapList, error) {
+	if f.Limit <= 0 || f.Limit > maxSwapListLimit {
+		return nil, fmt.Errorf("limit must be in [1, %d]", maxSwapListLimit)
+	}
+	heights, err := bot.db.getLastHeights()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+
+	list := &SwapList{Swaps: []SwapListItem{}}
+	switch direction {
+	case DirectionBch2Sbch:
+		statuses, err := parseStatusNames(status, bch2SbchStatusNames)
+		if err != nil {
+			return nil, err
+		}
+		f.Statuses = statuses
+		records, err := bot.db.listBch2SbchRecords(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			list.Swaps = append(list.Swaps, SwapListItem{
+				Id:        record.ID,
+				Direction: DirectionBch2Sbch,
+				HashLock:  record.HashLock,
+				Value:     satsToUtxoAmt(record.Value),
+				Price:     record.BchPrice,
+				Status:    record.Status.String(),
+				StatusMsg: getBch2SbchStatusMsg(lang, record, heights.LastBchHeight, now),
+				Token:     record.Token,
+				Memo:      record.Memo,
+				Sender:    record.SenderPkh,
+				Recipient: record.SenderEvmAddr,
+				CreatedAt: record.CreatedAt.Unix(),
+				UpdatedAt: record.UpdatedAt.Unix(),
+			})
+		}
+	case DirectionSbch2Bch:
+		statuses, err := parseStatusNames(status, sbch2BchStatusNames)
+		if err != nil {
+			return nil, err
+		}
+		f.Statuses = statuses
+		records, err := bot.db.listSbch2BchRecords(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			list.Swaps = append(list.Swaps, SwapListItem{
+				Id:        record.ID,
+				Direction: DirectionSbch2Bch,
+				HashLock:  record.HashLock,
+				Value:     satsToUtxoAmt(record.Value),
+				Price:     record.SbchPrice,
+				Status:    record.Status.String(),
+				StatusMsg: getSbch2BchStatusMsg(lang, record, now),
+				Token:     record.Token,
+				Memo:      record.Memo,
+				Sender:    record.SbchSenderAddr,
+				Recipient: record.BchRecipientPkh,
+				CreatedAt: record.CreatedAt.Unix(),
+				UpdatedAt: record.UpdatedAt.Unix(),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("invalid direction: %s", direction)
+	}
+
+	if len(list.Swaps) == f.Limit {
+		list.NextCursor = list.Swaps[len(list.Swaps)-1].Id
+	}
+	return list, nil
+}
+
+// return a page of swaps, filtered by status, sender and creation time
+func (bot *MarketMakerBot) handleListSwaps(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	f := &SwapFilter{
+		Cursor:   uint(getIntQueryParam(r, "cursor", 0)),
+		Desc:     params.Get("order") != "asc",
+		Limit:    getIntQueryParam(r, "limit", defaultSwapListLimit),
+		Archived: params.Get("archived") == "true",
+	}
+	if sender := params.Get("sender"); sender != "" {
+		f.Sender = toHex(gethcmn.FromHex(sender))
+	}
+	if from := getIntQueryParam(r, "from", 0); from > 0 {
+		f.From = time.Unix(int64(from), 0)
+	}
+	if to := getIntQueryParam(r, "to", 0); to > 0 {
+		f.To = time.Unix(int64(to), 0)
+	}
+
+	list, err := bot.listSwaps(params.Get("direction"), params.Get("status"), getRequestLang(r), f)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(list).WriteTo(w)
+}
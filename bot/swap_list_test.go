@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSwaps(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	for i := 0; i < 5; i++ {
+		sender := "user"
+		status := Bch2SbchStatusBchUnlocked
+		if i%2 == 1 {
+			sender = "user2"
+			status = Bch2SbchStatusSbchLocked
+		}
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockHeight:  122,
+			BchLockTxHash:  toHex(gethHash32Bytes("bchlock" + string(rune('0'+i)))),
+			Value:          uint64(i+1) * 1e8,
+			BchPrice:       0.99e8,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(gethAddrBytes(sender)),
+			HashLock:       toHex(gethHash32Bytes("b2s" + string(rune('0'+i)))),
+			TimeLock:       100,
+			SenderEvmAddr:  toHex(gethAddrBytes("evm")),
+			HtlcScriptHash: toHex(gethAddrBytes("htlc")),
+			Status:         status,
+		}))
+	}
+	require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+		SbchLockTime:    1234567890,
+		SbchLockTxHash:  toHex(gethHash32Bytes("sbchlock")),
+		Value:           2e8,
+		SbchPrice:       0.98e8,
+		SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+		BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+		HashLock:        toHex(gethHash32Bytes("s2b")),
+		TimeLock:        72000,
+		HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+		Status:          Sbch2BchStatusBchRefunded,
+	}))
+	require.NoError(t, _db.db.Model(&Bch2SbchRecord{}).Where("id = ?", 1).
+		UpdateColumn("created_at", time.Now().AddDate(0, 0, -10)).Error)
+
+	_bot := &MarketMakerBot{db: _db, dbQueryLimit: 100}
+
+	// newest first, 2 per page
+	list, err := _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Desc: true, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 2)
+	require.Equal(t, uint(5), list.Swaps[0].Id)
+	require.Equal(t, 5.0, list.Swaps[0].Value)
+	require.Equal(t, "BchUnlocked", list.Swaps[0].Status)
+	require.Equal(t, uint(4), list.NextCursor)
+	list, err = _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Desc: true, Limit: 2, Cursor: 4})
+	require.NoError(t, err)
+	require.Equal(t, uint(3), list.Swaps[0].Id)
+	require.Equal(t, uint(2), list.NextCursor)
+	list, err = _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Desc: true, Limit: 2, Cursor: 2})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 1)
+	require.Zero(t, list.NextCursor)
+
+	// filters
+	list, err = _bot.listSwaps(DirectionBch2Sbch, "sbchlocked", "en", &SwapFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 2)
+	require.Equal(t, uint(2), list.Swaps[0].Id)
+	list, err = _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Limit: 10, Sender: toHex(gethAddrBytes("user"))})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 3)
+	list, err = _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Limit: 10, From: time.Now().AddDate(0, 0, -1)})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 4)
+	list, err = _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Limit: 10, To: time.Now().AddDate(0, 0, -1)})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 1)
+	list, err = _bot.listSwaps(DirectionSbch2Bch, "BchRefunded", "en", &SwapFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 1)
+	require.Equal(t, toHex(gethAddrBytes("uevm")), list.Swaps[0].Sender)
+
+	_, err = _bot.listSwaps(DirectionBch2Sbch, "Locked", "en", &SwapFilter{Limit: 10})
+	require.ErrorContains(t, err, "invalid status: Locked")
+	_, err = _bot.listSwaps("b2s", "", "en", &SwapFilter{Limit: 10})
+	require.ErrorContains(t, err, "invalid direction")
+	_, err = _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Limit: 1000})
+	require.ErrorContains(t, err, "limit must be in")
+
+	// archived swaps
+	_bot.retentionDays = 1
+	require.NoError(t, _db.db.Model(&Bch2SbchRecord{}).Where("1 = 1").
+		UpdateColumn("updated_at", time.Now().AddDate(0, 0, -2)).Error)
+	_bot.archiveSwaps()
+	list, err = _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 2)
+	list, err = _bot.listSwaps(DirectionBch2Sbch, "", "en", &SwapFilter{Limit: 10, Archived: true})
+	require.NoError(t, err)
+	require.Len(t, list.Swaps, 3)
+	require.Equal(t, uint(1), list.Swaps[0].Id)
+
+	// HTTP
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/v1/swaps?direction=bch2sbch&order=asc&limit=1&sender=0x"+
+		toHex(gethAddrBytes("user2")), nil)
+	_bot.handleListSwaps(w, r)
+	var resp struct {
+		Success bool
+		Result  SwapList
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(t, resp.Success)
+	require.Len(t, resp.Result.Swaps, 1)
+	require.Equal(t, uint(2), resp.Result.Swaps[0].Id)
+	require.Equal(t, uint(2), resp.Result.NextCursor)
+	require.Equal(t, "sBCH is locked by the bot, unlock it with your secret", resp.Result.Swaps[0].StatusMsg)
+
+	// translated
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/api/v1/swaps?direction=sbch2bch", nil)
+	r.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	_bot.handleListSwaps(w, r)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Result.Swaps, 1)
+	require.Equal(t, "未按时公开密钥，BCH已退还给机器人；现在可退款", resp.Result.Swaps[0].StatusMsg)
+}
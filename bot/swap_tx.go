@@ -0,0 +1,68 @@
+package bot
+
+import (
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gcash/bchd/wire"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+// swap legs, the raw tx of each leg is saved to DB
+const (
+	SwapLegBchLock    = "bch_lock"
+	SwapLegBchUnlock  = "bch_unlock"
+	SwapLegBchRefund  = "bch_refund"
+	SwapLegSbchLock   = "sbch_lock"
+	SwapLegSbchUnlock = "sbch_unlock"
+	SwapLegSbchRefund = "sbch_refund"
+)
+
+// save BCH tx observed in blocks
+func (bot *MarketMakerBot) saveBchSwapTx(hashLock, leg, txHash, rawTxHex string) {
+	if rawTxHex == "" {
+		return
+	}
+	bot.saveSwapTx(hashLock, leg, txHash, rawTxHex)
+}
+
+// save BCH tx created by bot
+func (bot *MarketMakerBot) saveBchSwapMsgTx(hashLock, leg string, tx *wire.MsgTx) {
+	bot.saveSwapTx(hashLock, leg, tx.TxHash().String(), htlcbch.MsgTxToHex(tx))
+}
+
+// save sBCH tx observed in logs or sent by bot
+func (bot *MarketMakerBot) saveSbchSwapTx(hashLock, leg string, txHash gethcmn.Hash) {
+	rawTx, err := bot.sbchCli.getRawTx(txHash)
+	if err != nil {
+		bot.logError("RPC error, failed to get raw sBCH tx: ", err)
+		return
+	}
+	bot.saveSwapTx(hashLock, leg, toHex(txHash[:]), toHex(rawTx))
+}
+
+// return the memo appended to the calldata of sBCH lock tx, invalid ones are ignored
+func getSbchLockMemo(rawTx []byte) string {
+	tx := &gethtypes.Transaction{}
+	if err := tx.UnmarshalBinary(rawTx); err != nil {
+		return ""
+	}
+	memo := string(htlcsbch.GetLockMemo(tx.Data()))
+	if !htlcbch.IsValidMemo(memo) {
+		return ""
+	}
+	return memo
+}
+
+func (bot *MarketMakerBot) saveSwapTx(hashLock, leg, txHash, rawTxHex string) {
+	err := bot.db.addSwapTx(&SwapTx{
+		HashLock: hashLock,
+		Leg:      leg,
+		TxHash:   txHash,
+		RawTx:    rawTxHex,
+	})
+	if err != nil {
+		bot.logError("DB error, failed to save swap tx: ", err)
+	}
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"sync"
+)
+
+// a step of the engine taken for one swap
+type swapJob struct {
+	hashLock string
+	action   func()
+}
+
+// SwapWorkers runs the jobs of a loop step with a pool of workers. Jobs of independent
+// swaps are run concurrently, jobs of the same swap (same hash lock) are run in order by
+// one worker. The step returns after all its jobs are done, so steps never overlap and
+// all actions of a swap are serialized. Shared resources are guarded where they are used:
+// sBCH txs by NonceManager, UTXOs of bot by bchWalletMu.
+type SwapWorkers struct {
+	n int // 1 or less means jobs are run one by one in the calling goroutine
+}
+
+func newSwapWorkers(n int) *SwapWorkers {
+	return &SwapWorkers{n: n}
+}
+
+// run jobs and wait until all of them are done
+func (w *SwapWorkers) run(jobs []swapJob) {
+	if w == nil || w.n <= 1 || len(jobs) <= 1 {
+		for _, job := range jobs {
+			job.action()
+		}
+		return
+	}
+
+	// group jobs by swap, keeping their order
+	var groups [][]swapJob
+	groupIdx := map[string]int{}
+	for _, job := range jobs {
+		idx, ok := groupIdx[job.hashLock]
+		if !ok {
+			idx = len(groups)
+			groupIdx[job.hashLock] = idx
+			groups = append(groups, nil)
+		}
+		groups[idx] = append(groups[idx], job)
+	}
+
+	groupCh := make(chan []swapJob, len(groups))
+	for _, group := range groups {
+		groupCh <- group
+	}
+	close(groupCh)
+
+	nWorkers := w.n
+	if nWorkers > len(groups) {
+		nWorkers = len(groups)
+	}
+	var wg sync.WaitGroup
+	wg.Add(nWorkers)
+	for i := 0; i < nWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			for group := range groupCh {
+				for _, job := range group {
+					job.action()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
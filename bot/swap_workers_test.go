@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapWorkers(t *testing.T) {
+	var mu sync.Mutex
+	done := map[string][]int{}
+	var running, maxRunning int32
+
+	newJob := func(hashLock string, i int) swapJob {
+		return swapJob{hashLock: hashLock, action: func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+
+			mu.Lock()
+			defer mu.Unlock()
+			done[hashLock] = append(done[hashLock], i)
+		}}
+	}
+
+	jobs := []swapJob{
+		newJob("h1", 1), newJob("h2", 1), newJob("h1", 2), newJob("h3", 1),
+		newJob("h1", 3), newJob("h2", 2), newJob("h4", 1),
+	}
+	newSwapWorkers(3).run(jobs)
+	require.Equal(t, map[string][]int{"h1": {1, 2, 3}, "h2": {1, 2}, "h3": {1}, "h4": {1}}, done)
+	require.Equal(t, int32(3), maxRunning)
+
+	// one by one
+	done = map[string][]int{}
+	maxRunning = 0
+	newSwapWorkers(1).run(jobs)
+	require.Equal(t, map[string][]int{"h1": {1, 2, 3}, "h2": {1, 2}, "h3": {1}, "h4": {1}}, done)
+	require.Equal(t, int32(1), maxRunning)
+}
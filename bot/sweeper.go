@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchutil"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+const (
+	sweepInterval = 600 // 10m
+
+	// do not sweep dust-like excess, it costs more fee than it saves risk
+	minSweepVal = 100000 // in sats
+)
+
+// ColdWallets are where the hot balances above ceilings are swept to
+type ColdWallets struct {
+	bchPkh         []byte                    // P2PKH, nil means BCH is swept to the treasury if any
+	bchTreasury    *htlcbch.MultisigTreasury // nil means BCH is not swept if bchPkh is nil too
+	sbchAddr       gethcmn.Address           // EOA or contract, zero means sBCH is not swept
+	bchHotCeiling  uint64                    // in sats
+	sbchHotCeiling uint64                    // in sats
+}
+
+func newColdWallets(cfg *Config, bchNet *htlcbch.ChainParams,
+	treasury *htlcbch.MultisigTreasury) (*ColdWallets, error) {
+
+	wallets := &ColdWallets{
+		bchHotCeiling:  cfg.BchHotCeiling,
+		sbchHotCeiling: cfg.SbchHotCeiling,
+	}
+	if cfg.ColdBchAddr != "" {
+		addr, err := bchNet.DecodeP2PKHAddress(cfg.ColdBchAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cold BCH address: %w", err)
+		}
+		wallets.bchPkh = addr.ScriptAddress()
+	} else if cfg.BchHotCeiling > 0 {
+		wallets.bchTreasury = treasury
+	}
+	if cfg.ColdSbchAddr != "" {
+		if !gethcmn.IsHexAddress(cfg.ColdSbchAddr) {
+			return nil, fmt.Errorf("invalid cold sBCH address: %s", cfg.ColdSbchAddr)
+		}
+		wallets.sbchAddr = gethcmn.HexToAddress(cfg.ColdSbchAddr)
+	}
+	if (cfg.BchHotCeiling > 0) != (wallets.bchPkh != nil || wallets.bchTreasury != nil) {
+		return nil, fmt.Errorf("both cold BCH address (or treasury) and BCH hot ceiling are required")
+	}
+	if (cfg.SbchHotCeiling > 0) != (wallets.sbchAddr != gethcmn.Address{}) {
+		return nil, fmt.Errorf("both cold sBCH address and sBCH hot ceiling are required")
+	}
+	return wallets, nil
+}
+
+// the cold address, or the P2SH address of the treasury
+func (wallets *ColdWallets) getBchAddr(bchNet *htlcbch.ChainParams) (bchutil.Address, error) {
+	if wallets.bchPkh != nil {
+		return bchNet.NewP2PKHAddress(wallets.bchPkh)
+	}
+	return wallets.bchTreasury.GetAddress()
+}
+
+// periodically sweep hot BCH & sBCH above ceilings to cold wallets,
+// to keep the exposure of hot wallets bounded
+func (bot *MarketMakerBot) sweepToCold() {
+	if bot.coldWallets == nil || bot.isSlaveMode || !bot.canSign() {
+		return
+	}
+	now := time.Now().Unix()
+	if now-bot.lastSweptAt < sweepInterval {
+		return
+	}
+	bot.lastSweptAt = now
+
+	if bot.coldWallets.bchPkh != nil || bot.coldWallets.bchTreasury != nil {
+		bot.sweepBchToCold()
+	}
+	if bot.coldWallets.sbchAddr != (gethcmn.Address{}) {
+		bot.sweepSbchToCold()
+	}
+}
+
+func (bot *MarketMakerBot) sweepBchToCold() {
+	utxos, err := bot.bchCli.GetAllUTXOs()
+	if err != nil {
+		bot.logError("RPC error, failed to query UTXOs: ", err)
+		return
+	}
+	var balance int64
+	for _, utxo := range utxos {
+		balance += utxoAmtToSats(utxo.Amount)
+	}
+	excess := balance - int64(bot.coldWallets.bchHotCeiling)
+	if excess < minSweepVal {
+		return
+	}
+
+	log.Infof("sweep BCH to cold wallet, balance: %d, ceiling: %d, excess: %d",
+		balance, bot.coldWallets.bchHotCeiling, excess)
+	utxos, err = bot.bchCli.GetUTXOs(excess, 10)
+	if err != nil {
+		bot.logError("failed to get UTXOs: ", err)
+		return
+	}
+	inputs := make([]htlcbch.InputInfo, len(utxos))
+	for i, utxo := range utxos {
+		inputs[i] = htlcbch.InputInfo{
+			TxID:   gethcmn.FromHex(utxo.TxID),
+			Vout:   utxo.Vout,
+			Amount: utxoAmtToSats(utxo.Amount),
+		}
+	}
+
+	toAddr, err := bot.coldWallets.getBchAddr(bot.getBchNet())
+	if err != nil {
+		bot.logError("failed to get cold BCH address: ", err)
+		return
+	}
+	// miner fee is deducted from the swept value
+	tx, err := htlcbch.MakePayToAddrTx(bot.bchPrivKey, inputs,
+		toAddr, excess, bot.bchLockMinerFeeRate, bot.bchSigType, bot.getBchTxLockTime(), bot.getBchNet().Net)
+	if err != nil {
+		bot.logError("failed to create BCH tx: ", err)
+		return
+	}
+	log.Info("BCH tx hex: ", htlcbch.MsgTxToHex(tx))
+
+	txHash, err := bot.bchCli.SendTx(tx)
+	if err != nil {
+		bot.logError("failed to send BCH tx: ", err)
+		return
+	}
+	bot.logWarnf("swept %d sats BCH to cold wallet %s, tx hash: %s",
+		excess, toAddr.String(), txHash.String())
+}
+
+func (bot *MarketMakerBot) sweepSbchToCold() {
+	balance, err := bot.sbchCli.getBalance()
+	if err != nil {
+		bot.logError("RPC error, failed to query sBCH balance: ", err)
+		return
+	}
+	balanceSats := weiToSats(balance)
+	if balanceSats < bot.coldWallets.sbchHotCeiling+minSweepVal {
+		return
+	}
+	excess := balanceSats - bot.coldWallets.sbchHotCeiling
+
+	log.Infof("sweep sBCH to cold wallet, balance: %d, ceiling: %d, excess: %d",
+		balanceSats, bot.coldWallets.sbchHotCeiling, excess)
+	txHash, err := bot.sbchCli.transferSbch(bot.coldWallets.sbchAddr, satsToWei(excess))
+	if err != nil {
+		bot.logError("failed to send sBCH tx: ", err)
+		return
+	}
+	bot.logWarnf("swept %d sats sBCH to cold wallet %s, tx hash: %s",
+		excess, bot.coldWallets.sbchAddr.String(), txHash.String())
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"math/big"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestNewColdWallets(t *testing.T) {
+	coldBchAddr, err := htlcbch.MainNet.NewP2PKHAddress(gethAddrBytes("cold"))
+	require.NoError(t, err)
+
+	_, err = newColdWallets(&Config{BchHotCeiling: 1e8}, htlcbch.MainNet, nil)
+	require.ErrorContains(t, err, "both cold BCH address (or treasury) and BCH hot ceiling are required")
+	_, err = newColdWallets(&Config{ColdSbchAddr: "0x1234"}, htlcbch.MainNet, nil)
+	require.ErrorContains(t, err, "invalid cold sBCH address")
+	_, err = newColdWallets(&Config{ColdBchAddr: coldBchAddr.String()}, htlcbch.TestNet3, nil)
+	require.ErrorContains(t, err, "invalid cold BCH address")
+
+	wallets, err := newColdWallets(&Config{ColdBchAddr: coldBchAddr.String(), BchHotCeiling: 1e8}, htlcbch.MainNet, nil)
+	require.NoError(t, err)
+	require.Equal(t, gethAddrBytes("cold"), wallets.bchPkh)
+	require.Equal(t, gethcmn.Address{}, wallets.sbchAddr)
+
+	// BCH is swept to the treasury if cold address is not set
+	treasury, err := newTreasury(&Config{BchTreasuryM: 1,
+		BchTreasuryPubKeys: []string{toHex(testBchPrivKey.PubKey().SerializeCompressed())}}, htlcbch.MainNet)
+	require.NoError(t, err)
+	wallets, err = newColdWallets(&Config{BchHotCeiling: 1e8}, htlcbch.MainNet, treasury)
+	require.NoError(t, err)
+	require.Nil(t, wallets.bchPkh)
+	treasuryAddr, err := treasury.GetAddress()
+	require.NoError(t, err)
+	addr, err := wallets.getBchAddr(htlcbch.MainNet)
+	require.NoError(t, err)
+	require.Equal(t, treasuryAddr.String(), addr.String())
+	wallets, err = newColdWallets(&Config{ColdBchAddr: coldBchAddr.String(), BchHotCeiling: 1e8}, htlcbch.MainNet, treasury)
+	require.NoError(t, err)
+	require.Nil(t, wallets.bchTreasury)
+}
+
+func TestSweepToCold(t *testing.T) {
+	_bchCli := newMockBchClient(124, 125)
+	_bchCli.utxos = []btcjson.ListUnspentResult{
+		{TxID: gethcmn.Hash{'u', '1'}.String(), Vout: 0, Amount: 1.5},
+		{TxID: gethcmn.Hash{'u', '2'}.String(), Vout: 1, Amount: 0.7},
+	}
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_sbchCli.balance = satsToWei(3e8)
+	coldSbchAddr := gethAddr("coldsbch")
+	_bot := &MarketMakerBot{
+		bchCli:              _bchCli,
+		sbchCli:             _sbchCli,
+		bchPrivKey:          testBchPrivKey,
+		bchPkh:              testBchPkh,
+		bchLockMinerFeeRate: 2,
+		coldWallets: &ColdWallets{
+			bchPkh:         gethAddrBytes("coldbch"),
+			sbchAddr:       coldSbchAddr,
+			bchHotCeiling:  1e8,
+			sbchHotCeiling: 2e8,
+		},
+		errLogQueue: newErrLogQueue(100),
+	}
+
+	_bot.sweepToCold()
+	require.Len(t, _bchCli.sentTxs, 1)
+	sweepTx := _bchCli.sentTxs[0]
+	require.Len(t, sweepTx.TxIn, 1) // 1.5 BCH covers the excess
+	coldOut := findTxOut(sweepTx, gethAddrBytes("coldbch"))
+	require.NotNil(t, coldOut)
+	require.Less(t, coldOut.Value, int64(1.2e8))
+	require.Greater(t, coldOut.Value, int64(1.2e8-1000))
+	require.Equal(t, satsToWei(1e8), _sbchCli.sent[coldSbchAddr])
+	require.Len(t, _bot.errLogQueue.removeErrLogs(10), 2) // alerts
+
+	// not swept again within the interval, nor if the excess is too small
+	_bot.sweepToCold()
+	require.Len(t, _bchCli.sentTxs, 1)
+	_bot.lastSweptAt = 0
+	_sbchCli.balance = satsToWei(2e8 + minSweepVal - 1)
+	_bchCli.utxos = _bchCli.utxos[:1]
+	_bchCli.utxos[0].Amount = 1.0009
+	_sbchCli.sent = map[gethcmn.Address]*big.Int{}
+	_bot.sweepToCold()
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Empty(t, _sbchCli.sent)
+
+	// observer never sweeps
+	_bot.lastSweptAt = 0
+	_bot.isObserverMode = true
+	_bchCli.utxos[0].Amount = 5
+	_bot.sweepToCold()
+	require.Len(t, _bchCli.sentTxs, 1)
+}
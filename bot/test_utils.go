@@ -7,6 +7,7 @@ import (
 	gethcmn "github.com/ethereum/go-ethereum/common"
 	"github.com/gcash/bchd/chaincfg/chainhash"
 	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
 
 	"github.com/smartbch/atomic-swap-bot/htlcbch"
 )
@@ -105,3 +106,13 @@ func int64ToBytes32(n int64) []byte {
 func satsToWeiBytes32(amt uint64) []byte {
 	return satsToWei(amt).FillBytes(make([]byte, 32))
 }
+
+// findTxOut returns the P2PKH output paying to pkh, outputs of pay txs are sorted so their positions are not fixed
+func findTxOut(tx *wire.MsgTx, pkh []byte) *wire.TxOut {
+	for _, out := range tx.TxOut {
+		if len(out.PkScript) == 25 && bytes.Equal(out.PkScript[3:23], pkh) {
+			return out
+		}
+	}
+	return nil
+}
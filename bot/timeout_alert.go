@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// warn the operator when an in-flight swap is getting close to a refund window
+// while the expected action has not happened yet:
+//
+//	BCH2SBCH SbchLocked     : user has not revealed secret, bot's sBCH becomes refundable
+//	BCH2SBCH SecretRevealed : bot has not unlocked BCH, user's BCH becomes refundable (value at risk)
+//	SBCH2BCH BchLocked      : user has not revealed secret, bot's BCH becomes refundable
+//	SBCH2BCH SecretRevealed : bot has not unlocked sBCH, user's sBCH becomes refundable (value at risk)
+//
+// each swap is alerted once, value at risk is alerted as critical
+func (bot *MarketMakerBot) checkSwapTimeouts() {
+	if bot.refundAlertBlocks == 0 {
+		return
+	}
+	log.Info("check swap timeouts ...")
+
+	sbchNow, err := bot.sbchCli.getBlockTimeLatest()
+	if err != nil {
+		bot.logError("RPC error, failed to get sBCH time: ", err)
+		return
+	}
+	alertSeconds := bchTimeLockToSeconds(uint32(bot.refundAlertBlocks))
+
+	inFlight := map[string]bool{}
+	alert := func(logf func(string, ...any), hashLock string, format string, args ...any) {
+		inFlight[hashLock] = true
+		if !bot.refundAlerted[hashLock] {
+			logf(format, args...)
+		}
+	}
+
+	for _, status := range []Bch2SbchStatus{Bch2SbchStatusSbchLocked, Bch2SbchStatusSecretRevealed} {
+		records, err := bot.db.getBch2SbchRecordsByStatus(status, bot.dbQueryLimit)
+		if err != nil {
+			bot.logError("DB error, failed to get BCH2SBCH records: ", err)
+			return
+		}
+		for _, record := range records {
+			if status == Bch2SbchStatusSbchLocked {
+				refundableTime := record.SbchLockTxTime + uint64(bchTimeLockToSeconds(record.TimeLock)/2)
+				if sbchNow+uint64(alertSeconds) >= refundableTime {
+					alert(bot.logWarnf, record.HashLock, "BCH2SBCH swap is about to time out, secret not revealed, "+
+						"hash lock: %s, sBCH refundable at: %d", record.HashLock, refundableTime)
+				}
+				continue
+			}
+			if record.BchExpiresAt != 0 {
+				if time.Now().Unix()+int64(alertSeconds) >= int64(record.BchExpiresAt) {
+					alert(bot.logCriticalf, record.HashLock, "BCH2SBCH swap is about to time out, BCH not unlocked, "+
+						"hash lock: %s, BCH refundable at: %d", record.HashLock, record.BchExpiresAt)
+				}
+				continue
+			}
+			confirmations, err := bot.bchCli.GetTxConfirmations(record.BchLockTxHash)
+			if err != nil {
+				bot.logError("RPC error, failed to get tx confirmations: ", err)
+				continue
+			}
+			if confirmations+int64(bot.refundAlertBlocks) >= int64(record.TimeLock) {
+				alert(bot.logCriticalf, record.HashLock, "BCH2SBCH swap is about to time out, BCH not unlocked, "+
+					"hash lock: %s, confirmations: %d, BCH time lock: %d", record.HashLock, confirmations, record.TimeLock)
+			}
+		}
+	}
+
+	for _, status := range []Sbch2BchStatus{Sbch2BchStatusBchLocked, Sbch2BchStatusSecretRevealed} {
+		records, err := bot.db.getSbch2BchRecordsByStatus(status, bot.dbQueryLimit)
+		if err != nil {
+			bot.logError("DB error, failed to get SBCH2BCH records: ", err)
+			return
+		}
+		for _, record := range records {
+			if status == Sbch2BchStatusSecretRevealed {
+				refundableTime := record.SbchLockTime + uint64(record.TimeLock)
+				if sbchNow+uint64(alertSeconds) >= refundableTime {
+					alert(bot.logCriticalf, record.HashLock, "SBCH2BCH swap is about to time out, sBCH not unlocked, "+
+						"hash lock: %s, sBCH refundable at: %d", record.HashLock, refundableTime)
+				}
+				continue
+			}
+			confirmations, err := bot.bchCli.GetTxConfirmations(record.BchLockTxHash)
+			if err != nil {
+				bot.logError("RPC error, failed to get tx confirmations: ", err)
+				continue
+			}
+			bchTimeLock := sbchTimeLockToBlocks(record.TimeLock) / 2
+			if confirmations+int64(bot.refundAlertBlocks) >= int64(bchTimeLock) {
+				alert(bot.logWarnf, record.HashLock, "SBCH2BCH swap is about to time out, secret not revealed, "+
+					"hash lock: %s, confirmations: %d, BCH time lock: %d", record.HashLock, confirmations, bchTimeLock)
+			}
+		}
+	}
+
+	// forget swaps which are not in-flight or not close to timeout any more
+	bot.refundAlerted = inFlight
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSwapTimeouts(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_now := uint64(1700000000)
+	for i, status := range []Bch2SbchStatus{
+		Bch2SbchStatusSbchLocked,        // sBCH refundable in 4 blocks
+		Bch2SbchStatusSbchLocked,        // sBCH refundable in 10 blocks
+		Bch2SbchStatusSecretRevealed,    // BCH refundable in 5 blocks
+		Bch2SbchStatusBchUnlocked,       // finished
+		Bch2SbchStatusSecretRevealed,    // BCH refundable in 6 blocks
+		Bch2SbchStatusTooLateToLockSbch, // finished
+	} {
+		require.NoError(t, _db.addBch2SbchRecord(&Bch2SbchRecord{
+			BchLockHeight:  100,
+			BchLockTxHash:  bchHash32("b2slock" + string(rune('0'+i))).String(),
+			Value:          1e8,
+			RecipientPkh:   toHex(testBchPkh),
+			SenderPkh:      toHex(gethAddrBytes("user")),
+			HashLock:       toHex(gethHash32Bytes("b2s" + string(rune('0'+i)))),
+			TimeLock:       72,
+			SenderEvmAddr:  gethAddr("evm").String(),
+			HtlcScriptHash: "1234",
+			SbchLockTxTime: _now - 36*600 + []uint64{4, 10, 0, 0, 0, 0}[i]*600,
+			Status:         status,
+		}))
+	}
+	for i, status := range []Sbch2BchStatus{
+		Sbch2BchStatusBchLocked,      // BCH refundable in 3 blocks
+		Sbch2BchStatusSecretRevealed, // sBCH refundable in 5 blocks
+		Sbch2BchStatusSecretRevealed, // sBCH refundable in 50 blocks
+	} {
+		require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+			SbchLockTime:    _now - 72*600 + []uint64{0, 5, 50}[i]*600,
+			SbchLockTxHash:  toHex(gethHash32Bytes("s2block" + string(rune('0'+i)))),
+			Value:           1e8,
+			SbchPrice:       1e8,
+			SbchSenderAddr:  gethAddr("evm").String(),
+			BchRecipientPkh: toHex(gethAddrBytes("user")),
+			HashLock:        toHex(gethHash32Bytes("s2b" + string(rune('0'+i)))),
+			TimeLock:        72 * 600,
+			HtlcScriptHash:  "1234",
+			BchLockTxHash:   bchHash32("s2bbchlock" + string(rune('0'+i))).String(),
+			Status:          status,
+		}))
+	}
+
+	_bchCli := newMockBchClient(100, 200)
+	_bchCli.confirmations[bchHash32("b2slock2").String()] = 67
+	_bchCli.confirmations[bchHash32("b2slock4").String()] = 66
+	_bchCli.confirmations[bchHash32("s2bbchlock0").String()] = 33
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		bchCli:       _bchCli,
+		sbchCli:      newMockSbchClient(456, 999, _now),
+		errLogQueue:  newErrLogQueue(100),
+	}
+
+	// disabled
+	_bot.checkSwapTimeouts()
+	require.Empty(t, _bot.errLogQueue.removeErrLogs(10))
+
+	_bot.refundAlertBlocks = 5
+	_bot.checkSwapTimeouts()
+	logs := _bot.errLogQueue.removeErrLogs(10)
+	require.Len(t, logs, 4)
+	require.Contains(t, toJSON(logs), toHex(gethHash32Bytes("b2s0")))
+	require.Contains(t, toJSON(logs), toHex(gethHash32Bytes("b2s2")))
+	require.Contains(t, toJSON(logs), toHex(gethHash32Bytes("s2b0")))
+	require.Contains(t, toJSON(logs), toHex(gethHash32Bytes("s2b1")))
+	require.Contains(t, toJSON(logs), "BCH not unlocked")
+	require.Contains(t, toJSON(logs), "sBCH not unlocked")
+
+	// alerted once
+	_bot.checkSwapTimeouts()
+	require.Empty(t, _bot.errLogQueue.removeErrLogs(10))
+
+	// the swap gets closer
+	_bchCli.confirmations[bchHash32("b2slock4").String()] = 67
+	_bot.checkSwapTimeouts()
+	logs = _bot.errLogQueue.removeErrLogs(10)
+	require.Len(t, logs, 1)
+	require.Contains(t, toJSON(logs), toHex(gethHash32Bytes("b2s4")))
+}
@@ -0,0 +1,254 @@
+package bot
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+)
+
+const maxTokenDecimals = 36
+
+// Token is a SEP20 token which can be swapped with BCH.
+// Token amounts in DB records are scaled to 8 decimals, just like sats.
+type Token struct {
+	Symbol     string
+	Addr       gethcmn.Address
+	HtlcAddr   gethcmn.Address
+	Decimals   uint8
+	BchPrice   uint64 // BCH price in token, 8 decimals
+	TokenPrice uint64 // token price in BCH, 8 decimals
+
+	oracle         *OracleConfig // optional
+	priceUpdatedAt int64         // unix timestamp, oracle only
+}
+
+type TokenInfo struct {
+	Symbol       string  `json:"symbol"`
+	Addr         string  `json:"addr"`
+	HtlcAddr     string  `json:"htlc_addr"`
+	Decimals     uint8   `json:"decimals"`
+	BchPrice     uint64  `json:"bch_price"`
+	TokenPrice   uint64  `json:"token_price"`
+	PriceStale   bool    `json:"price_stale"` // quoting is paused
+	Free         float64 `json:"free"`
+	Locked       float64 `json:"locked"`         // locked by bot for bch2sbch swaps
+	ToBeUnlocked float64 `json:"to_be_unlocked"` // locked by users for sbch2bch swaps
+}
+
+func newTokens(cfgs []TokenConfig) (map[string]*Token, error) {
+	tokens := map[string]*Token{}
+	for _, cfg := range cfgs {
+		if cfg.Symbol == "" {
+			return nil, fmt.Errorf("missing token symbol")
+		}
+		if tokens[cfg.Symbol] != nil {
+			return nil, fmt.Errorf("duplicated token: %s", cfg.Symbol)
+		}
+		if !gethcmn.IsHexAddress(cfg.Addr) || !gethcmn.IsHexAddress(cfg.HtlcAddr) {
+			return nil, fmt.Errorf("invalid addr or htlc_addr of token: %s", cfg.Symbol)
+		}
+		if cfg.Decimals > maxTokenDecimals {
+			return nil, fmt.Errorf("too many decimals of token: %s", cfg.Symbol)
+		}
+		if cfg.Oracle != nil {
+			if err := checkOracleConfig(cfg.Oracle); err != nil {
+				return nil, fmt.Errorf("invalid oracle of token %s: %w", cfg.Symbol, err)
+			}
+		} else if cfg.BchPrice == 0 || cfg.TokenPrice == 0 {
+			return nil, fmt.Errorf("missing prices of token: %s", cfg.Symbol)
+		}
+		tokens[cfg.Symbol] = &Token{
+			Symbol:     cfg.Symbol,
+			Addr:       gethcmn.HexToAddress(cfg.Addr),
+			HtlcAddr:   gethcmn.HexToAddress(cfg.HtlcAddr),
+			Decimals:   cfg.Decimals,
+			BchPrice:   cfg.BchPrice,
+			TokenPrice: cfg.TokenPrice,
+			oracle:     cfg.Oracle,
+		}
+	}
+	return tokens, nil
+}
+
+// tokens can not be added, removed or moved by hot reload
+func checkTokenChanges(oldCfgs, newCfgs []TokenConfig) error {
+	if len(oldCfgs) != len(newCfgs) {
+		return fmt.Errorf("tokens can not be added or removed without restart")
+	}
+	for i, oldCfg := range oldCfgs {
+		newCfg := newCfgs[i]
+		if oldCfg.Symbol != newCfg.Symbol ||
+			oldCfg.Addr != newCfg.Addr ||
+			oldCfg.HtlcAddr != newCfg.HtlcAddr ||
+			oldCfg.Decimals != newCfg.Decimals {
+
+			return fmt.Errorf("only prices of token %s can be changed without restart", oldCfg.Symbol)
+		}
+	}
+	return nil
+}
+
+func (t *Token) getSymbol() string {
+	if t == nil {
+		return ""
+	}
+	return t.Symbol
+}
+
+// amt * 10^decimals / 1e8
+func (t *Token) toBaseUnits(amt uint64) *big.Int {
+	n := new(big.Int).Mul(big.NewInt(int64(amt)), pow10(t.Decimals))
+	return n.Div(n, big.NewInt(1e8))
+}
+
+// amt * 1e8 / 10^decimals
+func (t *Token) fromBaseUnits(amt *big.Int) uint64 {
+	n := new(big.Int).Mul(amt, big.NewInt(1e8))
+	return n.Div(n, pow10(t.Decimals)).Uint64()
+}
+
+// the fee earned by bot in sats, tokens are valued in BCH at bot's prices
+func (t *Token) getServiceFee(direction string, value, price uint64) uint64 {
+	if direction == DirectionBch2Sbch {
+		// BCH in, tokens out
+		return getServiceFee(value, mulByPrice(price, t.TokenPrice))
+	}
+	// tokens in, BCH out
+	return getServiceFee(divByPrice(value, t.BchPrice), mulByPrice(t.BchPrice, price))
+}
+
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func (bot *MarketMakerBot) getTokenByHtlc(htlcAddr gethcmn.Address) *Token {
+	for _, token := range bot.tokens {
+		if token.HtlcAddr == htlcAddr {
+			return token
+		}
+	}
+	return nil
+}
+
+// return the token which is asked for by the quote of a BCH2SBCH swap, nil means sBCH
+func (bot *MarketMakerBot) getQuotedToken(hashLock string) (*Token, error) {
+	quote, err := bot.db.getQuoteByHashLock(hashLock)
+	if err != nil || quote.Token == "" {
+		return nil, nil
+	}
+	return bot.getToken(quote.Token)
+}
+
+// empty symbol means sBCH
+func (bot *MarketMakerBot) getToken(symbol string) (*Token, error) {
+	if symbol == "" {
+		return nil, nil
+	}
+	token := bot.tokens[symbol]
+	if token == nil {
+		return nil, fmt.Errorf("unknown token: %s", symbol)
+	}
+	return token, nil
+}
+
+// the client of the HTLC contract which holds the token, empty symbol means sBCH
+func (bot *MarketMakerBot) sbchCliFor(symbol string) (ISbchClient, error) {
+	token, err := bot.getToken(symbol)
+	if err != nil || token == nil {
+		return bot.sbchCli, err
+	}
+	return bot.sbchCli.forHtlc(token.HtlcAddr), nil
+}
+
+// lock sBCH or tokens to the user, amt has 8 decimals
+func (bot *MarketMakerBot) lockToHtlc(token *Token, userEvmAddr gethcmn.Address, hashLock gethcmn.Hash,
+	timeLock uint32, amt uint64) (*gethcmn.Hash, error) {
+
+	if token == nil {
+		return bot.sbchCli.lockSbchToHtlc(userEvmAddr, hashLock, timeLock, satsToWei(amt))
+	}
+	return bot.sbchCli.forHtlc(token.HtlcAddr).lockTokenToHtlc(token.Addr,
+		userEvmAddr, hashLock, timeLock, token.toBaseUnits(amt))
+}
+
+// value of sBCH HTLC logs is in wei, value of token HTLC logs is in base units
+func (bot *MarketMakerBot) getLogValue(htlcAddr gethcmn.Address, val *big.Int) (*Token, uint64) {
+	if token := bot.getTokenByHtlc(htlcAddr); token != nil {
+		return token, token.fromBaseUnits(val)
+	}
+	return nil, weiToSats(val)
+}
+
+// the service fee of a swap in sats
+func (bot *MarketMakerBot) getSwapServiceFee(symbol, direction string, value, price uint64) uint64 {
+	if token := bot.tokens[symbol]; token != nil {
+		return token.getServiceFee(direction, value, price)
+	}
+	return getServiceFee(value, price)
+}
+
+func (bot *MarketMakerBot) getTokenInfos() ([]*TokenInfo, error) {
+	infos := map[string]*TokenInfo{}
+	var result []*TokenInfo
+	for _, cfg := range bot.cfg.Tokens {
+		token := bot.tokens[cfg.Symbol]
+		if token == nil {
+			continue
+		}
+		bal, err := bot.sbchCli.getTokenBalance(token.Addr, bot.sbchAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query balance of %s: %w", token.Symbol, err)
+		}
+		info := &TokenInfo{
+			Symbol:     token.Symbol,
+			Addr:       token.Addr.String(),
+			HtlcAddr:   token.HtlcAddr.String(),
+			Decimals:   token.Decimals,
+			BchPrice:   token.BchPrice,
+			TokenPrice: token.TokenPrice,
+			PriceStale: token.isPriceStale(time.Now().Unix()),
+			Free:       satsToUtxoAmt(token.fromBaseUnits(bal)),
+		}
+		infos[token.Symbol] = info
+		result = append(result, info)
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	for _, status := range []Bch2SbchStatus{Bch2SbchStatusSbchLocked, Bch2SbchStatusSecretRevealed} {
+		records, err := bot.db.getBch2SbchRecordsByStatus(status, 500)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if info := infos[record.Token]; info != nil {
+				info.Locked += satsToUtxoAmt(mulByPrice(record.GetFilledValue(), record.BchPrice))
+			}
+		}
+	}
+	for _, status := range []Sbch2BchStatus{Sbch2BchStatusNew, Sbch2BchStatusBchLocked, Sbch2BchStatusSecretRevealed} {
+		records, err := bot.db.getSbch2BchRecordsByStatus(status, 500)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if info := infos[record.Token]; info != nil {
+				info.ToBeUnlocked += satsToUtxoAmt(record.Value)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (bot *MarketMakerBot) handleTokens(w http.ResponseWriter, r *http.Request) {
+	infos, err := bot.getTokenInfos()
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(infos).WriteTo(w)
+}
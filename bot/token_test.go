@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"math/big"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+var testTokenCfg = TokenConfig{
+	Symbol:     "USDT",
+	Addr:       gethAddr("usdt").String(),
+	HtlcAddr:   gethAddr("usdthtlc").String(),
+	Decimals:   6,
+	BchPrice:   250e8,  // 1 BCH = 250 USDT
+	TokenPrice: 0.39e6, // 1 USDT = 0.0039 BCH
+}
+
+func TestTokenAmounts(t *testing.T) {
+	tokens, err := newTokens([]TokenConfig{testTokenCfg})
+	require.NoError(t, err)
+	usdt := tokens["USDT"]
+	require.Equal(t, big.NewInt(1234567), usdt.toBaseUnits(123456789))
+	require.Equal(t, uint64(123456700), usdt.fromBaseUnits(big.NewInt(1234567)))
+
+	usdt.Decimals = 18
+	require.Equal(t, satsToWei(123456789), usdt.toBaseUnits(123456789))
+	require.Equal(t, uint64(123456789), usdt.fromBaseUnits(satsToWei(123456789)))
+
+	// 1 BCH => 250 USDT => 0.975 BCH
+	require.Equal(t, uint64(0.025e8), usdt.getServiceFee(DirectionBch2Sbch, 1e8, 250e8))
+	// 250 USDT => 0.975 BCH
+	require.Equal(t, uint64(0.025e8), usdt.getServiceFee(DirectionSbch2Bch, 250e8, 0.39e6))
+}
+
+func TestNewTokens(t *testing.T) {
+	_, err := newTokens([]TokenConfig{testTokenCfg, testTokenCfg})
+	require.ErrorContains(t, err, "duplicated token")
+
+	cfg := testTokenCfg
+	cfg.HtlcAddr = "0x"
+	_, err = newTokens([]TokenConfig{cfg})
+	require.ErrorContains(t, err, "invalid addr")
+
+	cfg = testTokenCfg
+	cfg.TokenPrice = 0
+	_, err = newTokens([]TokenConfig{cfg})
+	require.ErrorContains(t, err, "missing prices")
+
+	cfg = testTokenCfg
+	cfg.BchPrice = 240e8
+	require.NoError(t, checkTokenChanges([]TokenConfig{testTokenCfg}, []TokenConfig{cfg}))
+	cfg.Decimals = 18
+	require.ErrorContains(t, checkTokenChanges([]TokenConfig{testTokenCfg}, []TokenConfig{cfg}),
+		"only prices of token USDT")
+	require.ErrorContains(t, checkTokenChanges([]TokenConfig{testTokenCfg}, nil),
+		"can not be added or removed")
+}
+
+func TestTokenSwap_bch2sbch(t *testing.T) {
+	_sbchKey, err := gethcrypto.GenerateKey()
+	require.NoError(t, err)
+	_userPkh := gethAddrBytes("user")
+	_userEvmAddr := gethAddrBytes("evm")
+	_hashLock := gethHash32Bytes("hash")
+
+	tokens, err := newTokens([]TokenConfig{testTokenCfg})
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 125)
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_bot := &MarketMakerBot{
+		db:            _db,
+		dbQueryLimit:  100,
+		bchCli:        _bchCli,
+		sbchCli:       _sbchCli,
+		bchPkh:        testBchPkh,
+		sbchPrivKey:   _sbchKey,
+		sbchAddr:      gethcrypto.PubkeyToAddress(_sbchKey.PublicKey),
+		bchTimeLock:   72,
+		sbchTimeLock:  36000,
+		bchPrice:      0.99e8,
+		sbchPrice:     0.98e8,
+		minSwapVal:    1e6,
+		quoteValidity: 600,
+		tokens:        tokens,
+		cfg:           &Config{Tokens: []TokenConfig{testTokenCfg}},
+		errLogQueue:   newErrLogQueue(100),
+	}
+
+	quote, err := _bot.makeQuote(&QuoteReq{
+		Direction:     DirectionBch2Sbch,
+		Value:         1e8,
+		HashLock:      toHex(_hashLock),
+		SenderPkh:     toHex(_userPkh),
+		SenderEvmAddr: toHex(_userEvmAddr),
+		Token:         "USDT",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "USDT", quote.Token)
+	require.Equal(t, uint64(250e8), quote.Price)
+	require.Equal(t, uint64(250e8), quote.CounterValue)
+	require.Equal(t, uint64(0.025e8), quote.Fee)
+
+	_bot.handleBchDepositTxB2S(124, &htlcbch.HtlcLockInfo{
+		TxHash:        toHex(gethHash32Bytes("bchlock")),
+		RecipientPkh:  testBchPkh,
+		SenderPkh:     _userPkh,
+		HashLock:      _hashLock,
+		Expiration:    72,
+		Value:         1e8,
+		SenderEvmAddr: _userEvmAddr,
+		ExpectedPrice: 250e8,
+		ScriptHash:    gethAddrBytes("htlc"),
+	})
+	record, err := _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, "USDT", record.Token)
+
+	_bot.handleBchUserDeposits()
+	record, err = _db.getBch2SbchRecordByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Equal(t, Bch2SbchStatusSbchLocked, record.Status)
+	require.Equal(t, big.NewInt(250e6), _sbchCli.locked[gethcmn.BytesToHash(_hashLock)])
+
+	infos, err := _bot.getTokenInfos()
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	require.Equal(t, 250.0, infos[0].Locked)
+	_, lockedSbch, _, err := _bot.getBch2SbchInfo()
+	require.NoError(t, err)
+	require.Equal(t, 0.0, lockedSbch)
+}
+
+func TestTokenSwap_sbch2bch(t *testing.T) {
+	_hashLock := gethHash32("hash")
+	_botEvmAddr := gethAddr("botevm")
+	_userEvmAddr := gethAddr("uevm")
+	_userBchPkh := gethAddrBytes("ubch")
+	_sbchLockTxHash := gethHash32("sbchlocktx")
+
+	tokens, err := newTokens([]TokenConfig{testTokenCfg})
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_sbchCli := newMockSbchClient(457, 999, 0)
+	_sbchCli.logs[459] = []gethtypes.Log{
+		{
+			Address:     gethcmn.HexToAddress(testTokenCfg.HtlcAddr),
+			BlockNumber: 459,
+			TxHash:      _sbchLockTxHash,
+			Topics: []gethcmn.Hash{
+				htlcsbch.LockEventId,
+				gethAddrToHash32(_userEvmAddr),
+				gethAddrToHash32(_botEvmAddr),
+			},
+			Data: joinBytes(
+				_hashLock[:],
+				int64ToBytes32(987600000+36000),
+				int64ToBytes32(100e6), // 100 USDT
+				rightPad0(_userBchPkh, 12),
+				int64ToBytes32(987600000),
+				int64ToBytes32(500),
+				int64ToBytes32(0.39e16),
+			),
+		},
+	}
+	_sbchCli.tokens[gethcmn.HexToAddress(testTokenCfg.Addr)] = big.NewInt(1000e6)
+
+	_bot := &MarketMakerBot{
+		db:           _db,
+		dbQueryLimit: 100,
+		sbchCli:      _sbchCli,
+		sbchAddr:     _botEvmAddr,
+		bchPkh:       testBchPkh,
+		sbchTimeLock: 36000,
+		penaltyRatio: 500,
+		bchPrice:     1e8,
+		sbchPrice:    1e8,
+		minSwapVal:   1e6,
+		tokens:       tokens,
+		cfg:          &Config{Tokens: []TokenConfig{testTokenCfg}},
+	}
+	_bot.handleSbchEvents(457, 500)
+
+	record, err := _db.getSbch2BchRecordByHashLock(toHex(_hashLock[:]))
+	require.NoError(t, err)
+	require.Equal(t, "USDT", record.Token)
+	require.Equal(t, uint64(100e8), record.Value)
+	require.Equal(t, uint64(0.39e6), record.SbchPrice)
+
+	infos, err := _bot.getTokenInfos()
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	require.Equal(t, 1000.0, infos[0].Free)
+	require.Equal(t, 100.0, infos[0].ToBeUnlocked)
+	toBeUnlockedSbch, _, _, err := _bot.getSbch2BchInfo()
+	require.NoError(t, err)
+	require.Equal(t, 0.0, toBeUnlockedSbch)
+}
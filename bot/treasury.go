@@ -0,0 +1,466 @@
+package bot
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+const (
+	TreasuryProposalPending   = "Pending"
+	TreasuryProposalBroadcast = "Broadcast"
+	TreasuryProposalCancelled = "Cancelled"
+
+	maxTreasuryProposals = 100
+
+	SwapLegBchForward = "bch_forward"
+)
+
+type TreasuryInfo struct {
+	Addr      string                  `json:"addr"`
+	M         int                     `json:"m"`
+	PubKeys   []string                `json:"pubkeys"`
+	Proposals []*TreasuryProposalInfo `json:"proposals"` // latest first
+}
+
+type TreasuryProposalInfo struct {
+	Id         uint     `json:"id"`
+	ToAddr     string   `json:"to_addr"`
+	Value      int64    `json:"value"`
+	UnsignedTx string   `json:"unsigned_tx"` // hex
+	InAmounts  []int64  `json:"in_amounts"`  // in tx order
+	SigHashes  []string `json:"sig_hashes"`  // hex, to be signed with ALL|FORKID by co-signers
+	Signers    []string `json:"signers"`     // hex pubkeys
+	Status     string   `json:"status"`
+	TxHash     string   `json:"tx_hash,omitempty"`
+	CreatedAt  int64    `json:"created_at"`
+}
+
+type TreasuryInput struct {
+	TxID  string `json:"txid"`
+	Vout  uint32 `json:"vout"`
+	Value int64  `json:"value"` // in sats
+}
+
+type TreasuryProposeReq struct {
+	ToAddr       string          `json:"to_addr"`
+	Value        int64           `json:"value"` // in sats, miner fee deducted
+	Inputs       []TreasuryInput `json:"inputs"`
+	MinerFeeRate uint64          `json:"miner_fee_rate"` // sats/byte, default is the lock fee rate
+}
+
+type TreasurySignReq struct {
+	Id     uint     `json:"id"`
+	PubKey string   `json:"pubkey"` // hex
+	Sigs   []string `json:"sigs"`   // hex, one per input in tx order
+}
+
+type TreasuryCancelReq struct {
+	Id uint `json:"id"`
+}
+
+// treasuryProposalLocks serializes read-modify-writes of each proposal, so that concurrent
+// signs do not drop each other's signatures and a proposal is not broadcast twice,
+// the zero value is ready to use
+type treasuryProposalLocks struct {
+	mu    sync.Mutex
+	locks map[uint]*sync.Mutex // proposal ID => lock
+}
+
+// returns the unlock function
+func (l *treasuryProposalLocks) lock(id uint) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[uint]*sync.Mutex{}
+	}
+	lock := l.locks[id]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		l.locks[id] = lock
+	}
+	l.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// nil if not configured
+func newTreasury(cfg *Config, bchNet *htlcbch.ChainParams) (*htlcbch.MultisigTreasury, error) {
+	if cfg.BchTreasuryM == 0 && len(cfg.BchTreasuryPubKeys) == 0 {
+		return nil, nil
+	}
+	pubKeys := make([][]byte, len(cfg.BchTreasuryPubKeys))
+	for i, pk := range cfg.BchTreasuryPubKeys {
+		pubKeys[i] = gethcmn.FromHex(pk)
+	}
+	return htlcbch.NewMultisigTreasury(int(cfg.BchTreasuryM), pubKeys, bchNet.Net)
+}
+
+// the change of lock txs goes to the treasury if it is configured, nil means back to the bot key
+func (bot *MarketMakerBot) getBchChangeAddr() (bchutil.Address, error) {
+	if bot.bchTreasury == nil {
+		return nil, nil
+	}
+	return bot.bchTreasury.GetAddress()
+}
+
+// claim|refund outputs of HTLC covenants can only be paid to the bot key,
+// so they are forwarded to the treasury by a child tx, which also pays for the parent (CPFP).
+// Returns nil if there is no treasury, or the output is left to the bot key on errors.
+func (bot *MarketMakerBot) forwardBchToTreasury(hashLock string, tx *wire.MsgTx, minerFeeRate uint64) *wire.MsgTx {
+	if bot.bchTreasury == nil {
+		return nil
+	}
+	if bot.bchPrivKey == nil {
+		log.Info("no BCH private key, can not forward BCH to the treasury")
+		return nil
+	}
+	toAddr, err := bot.bchTreasury.GetAddress()
+	if err != nil {
+		bot.logError("failed to get treasury address: ", err)
+		return nil
+	}
+	fwdTx, err := htlcbch.MakeForwardTx(tx, bot.bchPrivKey, toAddr, minerFeeRate, bot.bchSigType,
+		bot.getBchTxLockTime())
+	if err != nil {
+		bot.logError("failed to create BCH forward tx: ", err)
+		return nil
+	}
+	log.Info("forward tx: ", htlcbch.MsgTxToHex(fwdTx))
+	txHash, err := bot.bchCli.SendTx(fwdTx)
+	if err != nil {
+		bot.logError("failed to send BCH forward tx: ", err)
+		return nil
+	}
+	log.Info("BCH forward tx sent, hash: ", txHash.String())
+	bot.saveBchSwapMsgTx(hashLock, SwapLegBchForward, fwdTx)
+	return fwdTx
+}
+
+func (bot *MarketMakerBot) getTreasuryInfo() (*TreasuryInfo, error) {
+	addr, err := bot.bchTreasury.GetAddress()
+	if err != nil {
+		return nil, err
+	}
+	info := &TreasuryInfo{
+		Addr:      addr.String(),
+		M:         bot.bchTreasury.M(),
+		Proposals: []*TreasuryProposalInfo{},
+	}
+	for _, pk := range bot.bchTreasury.PubKeys() {
+		info.PubKeys = append(info.PubKeys, hex.EncodeToString(pk))
+	}
+	proposals, err := bot.db.getTreasuryProposals(maxTreasuryProposals)
+	if err != nil {
+		return nil, err
+	}
+	for _, proposal := range proposals {
+		proposalInfo, err := bot.toTreasuryProposalInfo(proposal)
+		if err != nil {
+			return nil, err
+		}
+		info.Proposals = append(info.Proposals, proposalInfo)
+	}
+	return info, nil
+}
+
+func (bot *MarketMakerBot) toTreasuryProposalInfo(proposal *TreasuryProposal) (*TreasuryProposalInfo, error) {
+	tx, inAmounts, sigs, err := decodeTreasuryProposal(proposal)
+	if err != nil {
+		return nil, err
+	}
+	info := &TreasuryProposalInfo{
+		Id:         proposal.ID,
+		ToAddr:     proposal.ToAddr,
+		Value:      proposal.Value,
+		UnsignedTx: proposal.UnsignedTx,
+		InAmounts:  inAmounts,
+		Signers:    []string{},
+		Status:     proposal.Status,
+		TxHash:     proposal.TxHash,
+		CreatedAt:  proposal.CreatedAt.Unix(),
+	}
+	for i, inAmt := range inAmounts {
+		sigHash, err := bot.bchTreasury.GetSigHash(tx, i, inAmt)
+		if err != nil {
+			return nil, err
+		}
+		info.SigHashes = append(info.SigHashes, hex.EncodeToString(sigHash))
+	}
+	for pk := range sigs {
+		info.Signers = append(info.Signers, pk)
+	}
+	sort.Strings(info.Signers)
+	return info, nil
+}
+
+func decodeTreasuryProposal(proposal *TreasuryProposal) (*wire.MsgTx, []int64, map[string][]string, error) {
+	tx, err := htlcbch.MsgTxFromBytes(gethcmn.FromHex(proposal.UnsignedTx))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid unsigned tx: %w", err)
+	}
+	var inAmounts []int64
+	if err = json.Unmarshal([]byte(proposal.InAmounts), &inAmounts); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid input amounts: %w", err)
+	}
+	sigs := map[string][]string{}
+	if err = json.Unmarshal([]byte(proposal.Sigs), &sigs); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid signatures: %w", err)
+	}
+	if len(inAmounts) != len(tx.TxIn) {
+		return nil, nil, nil, fmt.Errorf("%d input amounts for %d inputs", len(inAmounts), len(tx.TxIn))
+	}
+	return tx, inAmounts, sigs, nil
+}
+
+// build an unsigned spend tx of the treasury, the bot signs it if it is a co-signer
+func (bot *MarketMakerBot) proposeTreasurySpend(req *TreasuryProposeReq) (*TreasuryProposal, error) {
+	toAddr, err := bchutil.DecodeAddress(req.ToAddr, bot.getBchNet().Net)
+	if err != nil || !toAddr.IsForNet(bot.getBchNet().Net) {
+		return nil, fmt.Errorf("invalid to_addr: %s", req.ToAddr)
+	}
+	if len(req.Inputs) == 0 {
+		return nil, fmt.Errorf("no inputs")
+	}
+	inputs := make([]htlcbch.InputInfo, len(req.Inputs))
+	for i, input := range req.Inputs {
+		txid := gethcmn.FromHex(input.TxID)
+		if len(txid) != 32 {
+			return nil, fmt.Errorf("invalid txid of input#%d: %s", i, input.TxID)
+		}
+		inputs[i] = htlcbch.InputInfo{TxID: txid, Vout: input.Vout, Amount: input.Value}
+	}
+	minerFeeRate := req.MinerFeeRate
+	if minerFeeRate == 0 {
+		minerFeeRate = bot.bchLockMinerFeeRate
+	}
+	tx, err := bot.bchTreasury.MakeSpendTx(inputs, toAddr, req.Value, minerFeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	// inputs are sorted in tx
+	amounts := map[string]int64{}
+	for _, input := range inputs {
+		amounts[fmt.Sprintf("%s:%d", hex.EncodeToString(input.TxID), input.Vout)] = input.Amount
+	}
+	inAmounts := make([]int64, len(tx.TxIn))
+	for i, txIn := range tx.TxIn {
+		inAmounts[i] = amounts[txIn.PreviousOutPoint.String()]
+	}
+
+	sigs := map[string][]string{}
+	if bot.bchPrivKey != nil {
+		botPk := bot.bchPrivKey.PubKey().SerializeCompressed()
+		if bot.bchTreasury.HasPubKey(botPk) {
+			botSigs := make([]string, len(tx.TxIn))
+			for i, inAmt := range inAmounts {
+				sig, err := bot.bchTreasury.SignInput(tx, i, inAmt, bot.bchPrivKey)
+				if err != nil {
+					return nil, err
+				}
+				botSigs[i] = hex.EncodeToString(sig)
+			}
+			sigs[hex.EncodeToString(botPk)] = botSigs
+		}
+	}
+
+	proposal := &TreasuryProposal{
+		ToAddr:     toAddr.String(),
+		Value:      req.Value,
+		UnsignedTx: htlcbch.MsgTxToHex(tx),
+		InAmounts:  toJSON(inAmounts),
+		Sigs:       toJSON(sigs),
+		Status:     TreasuryProposalPending,
+	}
+	if err = bot.db.addTreasuryProposal(proposal); err != nil {
+		return nil, err
+	}
+	bot.logWarnf("treasury spend #%d is proposed, %d sats to %s", proposal.ID, req.Value, proposal.ToAddr)
+	defer bot.treasuryLocks.lock(proposal.ID)()
+	return proposal, bot.tryBroadcastTreasurySpend(proposal)
+}
+
+// add signatures of a co-signer, the tx is broadcast once m co-signers have signed
+func (bot *MarketMakerBot) signTreasurySpend(req *TreasurySignReq) (*TreasuryProposal, error) {
+	defer bot.treasuryLocks.lock(req.Id)()
+	proposal, err := bot.db.getTreasuryProposal(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("proposal not found: %d", req.Id)
+	}
+	if proposal.Status != TreasuryProposalPending {
+		return nil, fmt.Errorf("proposal is %s", proposal.Status)
+	}
+	tx, inAmounts, sigs, err := decodeTreasuryProposal(proposal)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Sigs) != len(tx.TxIn) {
+		return nil, fmt.Errorf("%d signatures are required, got %d", len(tx.TxIn), len(req.Sigs))
+	}
+	pubKey := gethcmn.FromHex(req.PubKey)
+	for i, sig := range req.Sigs {
+		err = bot.bchTreasury.VerifySig(tx, i, inAmounts[i], pubKey, gethcmn.FromHex(sig))
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature of input#%d: %w", i, err)
+		}
+	}
+
+	sigs[hex.EncodeToString(pubKey)] = req.Sigs
+	proposal.Sigs = toJSON(sigs)
+	if err = bot.db.updateTreasuryProposal(proposal); err != nil {
+		return nil, err
+	}
+	return proposal, bot.tryBroadcastTreasurySpend(proposal)
+}
+
+// the caller holds the lock of the proposal
+func (bot *MarketMakerBot) tryBroadcastTreasurySpend(proposal *TreasuryProposal) error {
+	tx, _, sigs, err := decodeTreasuryProposal(proposal)
+	if err != nil {
+		return err
+	}
+	if len(sigs) < bot.bchTreasury.M() {
+		return nil
+	}
+	if bot.isSlaveMode || !bot.canSign() {
+		return fmt.Errorf("enough signatures are collected, but this instance can not broadcast")
+	}
+
+	inputSigs := make([]map[string][]byte, len(tx.TxIn))
+	for i := range inputSigs {
+		inputSigs[i] = map[string][]byte{}
+		for pk, pkSigs := range sigs {
+			inputSigs[i][pk] = gethcmn.FromHex(pkSigs[i])
+		}
+	}
+	if err = bot.bchTreasury.FinalizeTx(tx, inputSigs); err != nil {
+		return err
+	}
+	txHash, err := bot.bchCli.SendTx(tx)
+	if err != nil {
+		return fmt.Errorf("failed to send BCH tx: %w", err)
+	}
+
+	proposal.Status = TreasuryProposalBroadcast
+	proposal.TxHash = txHash.String()
+	if err = bot.db.updateTreasuryProposal(proposal); err != nil {
+		return err
+	}
+	bot.logWarnf("treasury spend #%d is broadcast, tx hash: %s", proposal.ID, proposal.TxHash)
+	return nil
+}
+
+func (bot *MarketMakerBot) cancelTreasurySpend(id uint) (*TreasuryProposal, error) {
+	defer bot.treasuryLocks.lock(id)()
+	proposal, err := bot.db.getTreasuryProposal(id)
+	if err != nil {
+		return nil, fmt.Errorf("proposal not found: %d", id)
+	}
+	if proposal.Status != TreasuryProposalPending {
+		return nil, fmt.Errorf("proposal is %s", proposal.Status)
+	}
+	proposal.Status = TreasuryProposalCancelled
+	if err = bot.db.updateTreasuryProposal(proposal); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// GET: return treasury info and latest proposals (reader)
+func (bot *MarketMakerBot) handleTreasury(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleReader, false) {
+		return
+	}
+	if bot.bchTreasury == nil {
+		NewErrResp("treasury is not enabled").WriteTo(w)
+		return
+	}
+	info, err := bot.getTreasuryInfo()
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	NewOkResp(info).WriteTo(w)
+}
+
+// POST: propose a spend of the treasury (admin, signed)
+func (bot *MarketMakerBot) handleTreasuryPropose(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleAdmin, true) {
+		return
+	}
+	var req TreasuryProposeReq
+	if !bot.decodeTreasuryReq(w, r, &req) {
+		return
+	}
+	proposal, err := bot.proposeTreasurySpend(&req)
+	bot.writeTreasuryProposal(w, proposal, err)
+}
+
+// POST: add signatures of a co-signer (operator), they are verified against the pubkey
+func (bot *MarketMakerBot) handleTreasurySign(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleOperator, false) {
+		return
+	}
+	var req TreasurySignReq
+	if !bot.decodeTreasuryReq(w, r, &req) {
+		return
+	}
+	proposal, err := bot.signTreasurySpend(&req)
+	bot.writeTreasuryProposal(w, proposal, err)
+}
+
+// POST: cancel a pending proposal (admin, signed)
+func (bot *MarketMakerBot) handleTreasuryCancel(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleAdmin, true) {
+		return
+	}
+	var req TreasuryCancelReq
+	if !bot.decodeTreasuryReq(w, r, &req) {
+		return
+	}
+	proposal, err := bot.cancelTreasurySpend(req.Id)
+	bot.writeTreasuryProposal(w, proposal, err)
+}
+
+func (bot *MarketMakerBot) decodeTreasuryReq(w http.ResponseWriter, r *http.Request, req any) bool {
+	if bot.bchTreasury == nil {
+		NewErrResp("treasury is not enabled").WriteTo(w)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		NewErrResp("invalid request: " + err.Error()).WriteTo(w)
+		return false
+	}
+	return true
+}
+
+// the proposal is returned with the error if it is saved but not broadcast
+func (bot *MarketMakerBot) writeTreasuryProposal(w http.ResponseWriter, proposal *TreasuryProposal, err error) {
+	if proposal == nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	info, err2 := bot.toTreasuryProposalInfo(proposal)
+	if err2 != nil {
+		NewErrResp(err2.Error()).WriteTo(w)
+		return
+	}
+	if err != nil {
+		resp := NewErrResp(err.Error())
+		resp.Result = info
+		resp.WriteTo(w)
+		return
+	}
+	NewOkResp(info).WriteTo(w)
+}
@@ -0,0 +1,282 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/txscript"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestNewTreasury(t *testing.T) {
+	treasury, err := newTreasury(&Config{}, htlcbch.MainNet)
+	require.NoError(t, err)
+	require.Nil(t, treasury)
+
+	_, err = newTreasury(&Config{BchTreasuryM: 2}, htlcbch.MainNet)
+	require.ErrorContains(t, err, "number of pubkeys must be in [1, 15]: 0")
+	_, err = newTreasury(&Config{BchTreasuryM: 1, BchTreasuryPubKeys: []string{"0x1234"}}, htlcbch.MainNet)
+	require.ErrorContains(t, err, "pubkey#0 is not 33 bytes compressed")
+}
+
+func TestTreasurySpend(t *testing.T) {
+	key2, _ := bchec.PrivKeyFromBytes(bchec.S256(), gethHash32Bytes("cosigner2"))
+	key3, _ := bchec.PrivKeyFromBytes(bchec.S256(), gethHash32Bytes("cosigner3"))
+	treasury, err := newTreasury(&Config{BchTreasuryM: 2, BchTreasuryPubKeys: []string{
+		toHex(testBchPrivKey.PubKey().SerializeCompressed()),
+		toHex(key2.PubKey().SerializeCompressed()),
+		toHex(key3.PubKey().SerializeCompressed()),
+	}}, htlcbch.MainNet)
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(123, 200)
+	_bot := &MarketMakerBot{
+		db:                  _db,
+		bchCli:              _bchCli,
+		bchPrivKey:          testBchPrivKey,
+		bchPkh:              testBchPkh,
+		bchTreasury:         treasury,
+		bchLockMinerFeeRate: 2,
+		adminToken:          "secret",
+		errLogQueue:         newErrLogQueue(100),
+	}
+
+	toAddr, err := htlcbch.MainNet.NewP2PKHAddress(gethAddrBytes("to"))
+	require.NoError(t, err)
+	req := &TreasuryProposeReq{
+		ToAddr: toAddr.String(),
+		Value:  1e8,
+		Inputs: []TreasuryInput{
+			{TxID: toHex(gethHash32Bytes("utxo2")), Vout: 0, Value: 0.5e8},
+			{TxID: toHex(gethHash32Bytes("utxo1")), Vout: 1, Value: 0.8e8},
+		},
+	}
+	_, err = _bot.proposeTreasurySpend(&TreasuryProposeReq{ToAddr: "bitcoincash:qq", Value: 1e8, Inputs: req.Inputs})
+	require.ErrorContains(t, err, "invalid to_addr")
+	_, err = _bot.proposeTreasurySpend(&TreasuryProposeReq{ToAddr: toAddr.String(), Value: 1e8})
+	require.ErrorContains(t, err, "no inputs")
+
+	// the bot signs as the first co-signer
+	proposal, err := _bot.proposeTreasurySpend(req)
+	require.NoError(t, err)
+	require.Equal(t, TreasuryProposalPending, proposal.Status)
+	info, err := _bot.toTreasuryProposalInfo(proposal)
+	require.NoError(t, err)
+	require.Equal(t, []int64{0.8e8, 0.5e8}, info.InAmounts) // inputs are sorted
+	require.Len(t, info.SigHashes, 2)
+	require.Equal(t, []string{hex.EncodeToString(testBchPrivKey.PubKey().SerializeCompressed())}, info.Signers)
+	require.Len(t, _bchCli.sentTxs, 0)
+
+	// the second co-signer signs the sighashes
+	tx, err := htlcbch.MsgTxFromBytes(gethcmn.FromHex(info.UnsignedTx))
+	require.NoError(t, err)
+	signReq := &TreasurySignReq{Id: proposal.ID, PubKey: toHex(key2.PubKey().SerializeCompressed())}
+	for i, inAmt := range info.InAmounts {
+		sig, err := treasury.SignInput(tx, i, inAmt, key2)
+		require.NoError(t, err)
+		signReq.Sigs = append(signReq.Sigs, toHex(sig))
+	}
+	_, err = _bot.signTreasurySpend(&TreasurySignReq{Id: proposal.ID, PubKey: signReq.PubKey, Sigs: signReq.Sigs[:1]})
+	require.ErrorContains(t, err, "2 signatures are required, got 1")
+	_, err = _bot.signTreasurySpend(&TreasurySignReq{Id: proposal.ID, PubKey: signReq.PubKey,
+		Sigs: []string{signReq.Sigs[1], signReq.Sigs[0]}})
+	require.ErrorContains(t, err, "invalid signature of input#0: signature verification failed")
+	_, err = _bot.signTreasurySpend(&TreasurySignReq{Id: proposal.ID,
+		PubKey: toHex(key3.PubKey().SerializeCompressed()), Sigs: signReq.Sigs})
+	require.ErrorContains(t, err, "invalid signature of input#0")
+
+	proposal, err = _bot.signTreasurySpend(signReq)
+	require.NoError(t, err)
+	require.Equal(t, TreasuryProposalBroadcast, proposal.Status)
+	require.Len(t, _bchCli.sentTxs, 1)
+	signedTx := _bchCli.sentTxs[0]
+	require.Equal(t, signedTx.TxHash().String(), proposal.TxHash)
+	treasuryAddr, err := treasury.GetAddress()
+	require.NoError(t, err)
+	prevPkScript, err := txscript.PayToAddrScript(treasuryAddr)
+	require.NoError(t, err)
+	for i, inAmt := range info.InAmounts {
+		vm, err := txscript.NewEngine(prevPkScript, signedTx, i,
+			txscript.StandardVerifyFlags, nil, nil, nil, inAmt)
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute())
+	}
+	toOut := findTxOut(signedTx, gethAddrBytes("to"))
+	require.NotNil(t, toOut)
+	require.Greater(t, toOut.Value, int64(1e8-2000))
+
+	// not pending anymore
+	_, err = _bot.signTreasurySpend(signReq)
+	require.ErrorContains(t, err, "proposal is Broadcast")
+	_, err = _bot.cancelTreasurySpend(proposal.ID)
+	require.ErrorContains(t, err, "proposal is Broadcast")
+
+	// admin API
+	mux := _bot.createHttpHandlers()
+	call := func(method, uri, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, uri, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		mux.ServeHTTP(w, r)
+		return w
+	}
+	w := call(http.MethodPost, "/admin/treasury/propose", `{"to_addr":"`+toAddr.String()+`","value":1000}`)
+	require.Contains(t, w.Body.String(), "missing inputs")
+	w = call(http.MethodPost, "/admin/treasury/propose", `{"to_addr":"`+toAddr.String()+`","value":50000000,`+
+		`"inputs":[{"txid":"`+toHex(gethHash32Bytes("utxo3"))+`","vout":0,"value":80000000}]}`)
+	require.Contains(t, w.Body.String(), `"status":"Pending"`)
+	w = call(http.MethodPost, "/admin/treasury/cancel", `{"id":2}`)
+	require.Contains(t, w.Body.String(), `"status":"Cancelled"`)
+	w = call(http.MethodGet, "/admin/treasury", "")
+	require.Contains(t, w.Body.String(), `"m":2`)
+	require.Contains(t, w.Body.String(), `"addr":"`+treasuryAddr.String()+`"`)
+	require.Contains(t, w.Body.String(), `"tx_hash":"`+proposal.TxHash+`"`)
+}
+
+func TestTreasurySpend_concurrentSigns(t *testing.T) {
+	key2, _ := bchec.PrivKeyFromBytes(bchec.S256(), gethHash32Bytes("cosigner2"))
+	key3, _ := bchec.PrivKeyFromBytes(bchec.S256(), gethHash32Bytes("cosigner3"))
+	treasury, err := newTreasury(&Config{BchTreasuryM: 3, BchTreasuryPubKeys: []string{
+		toHex(testBchPrivKey.PubKey().SerializeCompressed()),
+		toHex(key2.PubKey().SerializeCompressed()),
+		toHex(key3.PubKey().SerializeCompressed()),
+	}}, htlcbch.MainNet)
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(123, 200)
+	_bot := &MarketMakerBot{
+		db:                  _db,
+		bchCli:              _bchCli,
+		bchPrivKey:          testBchPrivKey,
+		bchPkh:              testBchPkh,
+		bchTreasury:         treasury,
+		bchLockMinerFeeRate: 2,
+		errLogQueue:         newErrLogQueue(100),
+	}
+	toAddr, err := htlcbch.MainNet.NewP2PKHAddress(gethAddrBytes("to"))
+	require.NoError(t, err)
+	proposal, err := _bot.proposeTreasurySpend(&TreasuryProposeReq{
+		ToAddr: toAddr.String(),
+		Value:  1e8,
+		Inputs: []TreasuryInput{{TxID: toHex(gethHash32Bytes("utxo1")), Vout: 1, Value: 2e8}},
+	})
+	require.NoError(t, err)
+	info, err := _bot.toTreasuryProposalInfo(proposal)
+	require.NoError(t, err)
+	tx, err := htlcbch.MsgTxFromBytes(gethcmn.FromHex(info.UnsignedTx))
+	require.NoError(t, err)
+
+	// the last two co-signers sign at the same time, no signature is lost
+	var wg sync.WaitGroup
+	for _, key := range []*bchec.PrivateKey{key2, key3} {
+		sig, err := treasury.SignInput(tx, 0, info.InAmounts[0], key)
+		require.NoError(t, err)
+		req := &TreasurySignReq{Id: proposal.ID, PubKey: toHex(key.PubKey().SerializeCompressed()),
+			Sigs: []string{toHex(sig)}}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = _bot.signTreasurySpend(req)
+		}()
+	}
+	wg.Wait()
+	proposal, err = _db.getTreasuryProposal(proposal.ID)
+	require.NoError(t, err)
+	require.Equal(t, TreasuryProposalBroadcast, proposal.Status)
+	require.Len(t, _bchCli.sentTxs, 1)
+}
+
+func TestForwardBchToTreasury(t *testing.T) {
+	key2, _ := bchec.PrivKeyFromBytes(bchec.S256(), gethHash32Bytes("cosigner2"))
+	treasury, err := newTreasury(&Config{BchTreasuryM: 1, BchTreasuryPubKeys: []string{
+		toHex(key2.PubKey().SerializeCompressed()),
+	}}, htlcbch.MainNet)
+	require.NoError(t, err)
+	treasuryAddr, err := treasury.GetAddress()
+	require.NoError(t, err)
+	treasuryPkScript, err := txscript.PayToAddrScript(treasuryAddr)
+	require.NoError(t, err)
+
+	_hashLock := gethHash32Bytes("hash")
+	covenant, err := htlcbch.NewMainnetCovenant(testBchPkh, gethAddrBytes("user"), _hashLock, 100, 0)
+	require.NoError(t, err)
+	refundTx, err := covenant.MakeRefundTx(gethHash32Bytes("utxo"), 0, 100000, 1)
+	require.NoError(t, err)
+
+	_db := initDB(t, 123, 456)
+	_bchCli := newMockBchClient(124, 128)
+	_bot := &MarketMakerBot{
+		db:              _db,
+		bchCli:          _bchCli,
+		bchPrivKey:      testBchPrivKey,
+		bchPkh:          testBchPkh,
+		dbQueryLimit:    100,
+		stuckTxBlocks:   3,
+		stuckTxStrategy: StuckTxStrategyCpfp,
+		errLogQueue:     newErrLogQueue(100),
+	}
+
+	// no treasury
+	require.Nil(t, _bot.forwardBchToTreasury(toHex(_hashLock), refundTx, 2))
+	changeAddr, err := _bot.getBchChangeAddr()
+	require.NoError(t, err)
+	require.Nil(t, changeAddr)
+
+	// refund output is forwarded to the treasury, so is the change of lock txs
+	_bot.bchTreasury = treasury
+	changeAddr, err = _bot.getBchChangeAddr()
+	require.NoError(t, err)
+	require.Equal(t, treasuryAddr.String(), changeAddr.String())
+	fwdTx := _bot.forwardBchToTreasury(toHex(_hashLock), refundTx, 2)
+	require.NotNil(t, fwdTx)
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Equal(t, refundTx.TxHash(), fwdTx.TxIn[0].PreviousOutPoint.Hash)
+	require.Equal(t, treasuryPkScript, fwdTx.TxOut[0].PkScript)
+	swapTxs, err := _db.getSwapTxsByHashLock(toHex(_hashLock))
+	require.NoError(t, err)
+	require.Len(t, swapTxs, 1)
+	require.Equal(t, SwapLegBchForward, swapTxs[0].Leg)
+
+	// stuck, both are rebroadcast, no CPFP tx can be chained off the forward tx
+	_bot.watchBchTx(toHex(_hashLock), SwapLegBchRefund, refundTx, fwdTx)
+	txs, err := _db.getUnconfirmedBchTxs(100)
+	require.NoError(t, err)
+	require.Equal(t, htlcbch.MsgTxToHex(fwdTx), txs[0].CpfpRawTx)
+	require.Equal(t, refundTx.TxOut[0].Value-fwdTx.TxOut[0].Value, txs[0].CpfpFee)
+	_bchCli.hTo = 131
+	_bot.checkStuckBchTxs()
+	require.Len(t, _bchCli.sentTxs, 3)
+	require.Equal(t, refundTx.TxHash(), _bchCli.sentTxs[1].TxHash())
+	require.Equal(t, fwdTx.TxHash(), _bchCli.sentTxs[2].TxHash())
+
+	// all outputs of a batch unlock tx are forwarded, they all pay the CPFP fee
+	var receipts []htlcbch.BatchReceipt
+	for i, secret := range [][]byte{gethHash32Bytes("secret1"), gethHash32Bytes("secret2")} {
+		hashLock := sha256.Sum256(secret)
+		c, err := htlcbch.NewMainnetCovenant(gethAddrBytes("user"), testBchPkh, hashLock[:], 100, 0)
+		require.NoError(t, err)
+		receipts = append(receipts, htlcbch.BatchReceipt{Covenant: c.WithBatchable(),
+			TxID: gethHash32Bytes("utxo"), Vout: uint32(i + 1), InAmt: 100000, Secret: secret})
+	}
+	batchTx, err := htlcbch.MakeBatchUnlockTx(receipts, 1, 0)
+	require.NoError(t, err)
+	fwdTx = _bot.forwardBchToTreasury(toHex(_hashLock), batchTx, 2)
+	require.NotNil(t, fwdTx)
+	require.Len(t, fwdTx.TxIn, 2)
+	_bot.watchBchTx(toHex(_hashLock), SwapLegBchUnlock, batchTx, fwdTx)
+	txs, err = _db.getUnconfirmedBchTxs(100)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	require.Equal(t, batchTx.TxOut[0].Value+batchTx.TxOut[1].Value-fwdTx.TxOut[0].Value, txs[1].CpfpFee)
+}
@@ -0,0 +1,422 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+	"github.com/smartbch/atomic-swap-bot/htlcsbch"
+)
+
+// statuses of swaps tracked by UserWatcher
+const (
+	UserSwapUserLocked     = "user_locked"     // user locked BCH (bch2sbch) or sBCH (sbch2bch)
+	UserSwapBotLocked      = "bot_locked"      // bot locked the counter-asset
+	UserSwapSecretRevealed = "secret_revealed" // user unlocked the counter-asset
+	UserSwapCompleted      = "completed"       // bot unlocked what user locked
+	UserSwapRefunded       = "refunded"        // what user locked is refunded
+)
+
+// UserWatchConfig identifies a user and the public bot they swap with
+type UserWatchConfig struct {
+	BchNet        *htlcbch.ChainParams
+	UserPkh       []byte          // BCH
+	UserEvmAddr   gethcmn.Address // optional, sbch2bch locks paying to UserPkh are also tracked
+	BotPkh        []byte          // BCH
+	BotEvmAddr    gethcmn.Address //
+	AutoRefund    bool            // send refund txs of expired BCH deposits, which need no signature
+	RefundFeeRate uint64          // sats/byte
+}
+
+// UserSwap is what UserWatcher knows about a swap of the user
+type UserSwap struct {
+	Direction string `json:"direction"` // bch2sbch|sbch2bch
+	HashLock  string `json:"hash_lock"` // hex
+	Status    string `json:"status"`    // see UserSwapXxx
+
+	// BCH covenant, locked by user (bch2sbch) or bot (sbch2bch)
+	BchLockTxHash string `json:"bch_lock_tx_hash,omitempty"`
+	BchLockHeight int64  `json:"bch_lock_height,omitempty"`
+	BchRefundTx   string `json:"bch_refund_tx_hash,omitempty"`
+	bchDeposit    *htlcbch.HtlcLockInfo
+
+	// sBCH HTLC, locked by bot (bch2sbch) or user (sbch2bch)
+	SbchLockTxHash string `json:"sbch_lock_tx_hash,omitempty"`
+	SbchLockTime   uint64 `json:"sbch_lock_time,omitempty"`   // unix seconds
+	SbchUnlockTime uint64 `json:"sbch_unlock_time,omitempty"` // unix seconds, refundable after it
+
+	Alerts  []string `json:"alerts,omitempty"`
+	alerted map[string]bool
+}
+
+func (swap *UserSwap) isFinal() bool {
+	return swap.Status == UserSwapCompleted || swap.Status == UserSwapRefunded
+}
+
+// UserWatcher follows swaps of a user against a public bot, alerts if the bot misbehaves
+// (does not lock in time, locks wrong params, lets the swap expire), and refunds expired BCH
+// deposits of the user. It needs no DB: swaps are rebuilt by scanning from given heights.
+type UserWatcher struct {
+	cfg     *UserWatchConfig
+	bchCli  IBchClient
+	sbchCli ISbchClient
+	onAlert func(swap *UserSwap, alert string)
+
+	lastBchHeight  int64
+	lastSbchHeight uint64
+	swaps          map[string]*UserSwap // hashLock => swap
+
+	// BCH locks of bot paying to user, seen before the sBCH locks of user (e.g. when catching up)
+	botBchDeposits map[string]*userWatchDeposit // hashLock => deposit
+}
+
+type userWatchDeposit struct {
+	height  int64
+	deposit *htlcbch.HtlcLockInfo
+}
+
+func NewUserWatcher(cfg *UserWatchConfig, bchRpcUrl, sbchRpcUrl string, sbchHtlcAddr gethcmn.Address,
+	onAlert func(swap *UserSwap, alert string)) (*UserWatcher, error) {
+
+	bchCli, err := NewBchClient(bchRpcUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BCH RPC client: %w", err)
+	}
+	sbchCli, err := newSbchClient(sbchRpcUrl, 10*time.Second, nil, sbchHtlcAddr, nil, &EvmChain{}, DB{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sBCH RPC client: %w", err)
+	}
+	return newUserWatcher(cfg, bchCli, sbchCli, onAlert), nil
+}
+
+func newUserWatcher(cfg *UserWatchConfig, bchCli IBchClient, sbchCli ISbchClient,
+	onAlert func(swap *UserSwap, alert string)) *UserWatcher {
+
+	return &UserWatcher{
+		cfg:     cfg,
+		bchCli:  bchCli,
+		sbchCli: sbchCli,
+		onAlert: onAlert,
+		swaps:   map[string]*UserSwap{},
+
+		botBchDeposits: map[string]*userWatchDeposit{},
+	}
+}
+
+// Run scans both chains from the given heights, then follows them until stopped
+func (w *UserWatcher) Run(fromBchHeight int64, fromSbchHeight uint64, interval time.Duration) {
+	w.lastBchHeight, w.lastSbchHeight = fromBchHeight-1, fromSbchHeight-1
+	for {
+		if err := w.round(); err != nil {
+			log.Error("failed to watch swaps: ", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (w *UserWatcher) Swaps() []*UserSwap {
+	swaps := make([]*UserSwap, 0, len(w.swaps))
+	for _, swap := range w.swaps {
+		swaps = append(swaps, swap)
+	}
+	return swaps
+}
+
+func (w *UserWatcher) round() error {
+	if err := w.scanBch(); err != nil {
+		return err
+	}
+	if err := w.scanSbch(); err != nil {
+		return err
+	}
+	return w.checkSwaps()
+}
+
+func (w *UserWatcher) scanBch() error {
+	tip, err := w.bchCli.GetBlockCount()
+	if err != nil {
+		return err
+	}
+	for h := w.lastBchHeight + 1; h <= tip; h++ {
+		block, err := w.bchCli.GetBlock(h)
+		if err != nil {
+			return err
+		}
+		scan := w.cfg.BchNet.ScanBlock(block, nil)
+		for _, deposit := range scan.Deposits {
+			w.handleBchDeposit(h, deposit)
+		}
+		for _, receipt := range scan.Receipts {
+			w.handleBchReceipt(receipt)
+		}
+		w.lastBchHeight = h
+	}
+	return nil
+}
+
+func (w *UserWatcher) handleBchDeposit(h int64, deposit *htlcbch.HtlcLockInfo) {
+	var direction string
+	switch {
+	case bytes.Equal(deposit.SenderPkh, w.cfg.UserPkh) && bytes.Equal(deposit.RecipientPkh, w.cfg.BotPkh):
+		direction = DirectionBch2Sbch
+	case bytes.Equal(deposit.SenderPkh, w.cfg.BotPkh):
+		direction = DirectionSbch2Bch // checked against the sBCH lock of user
+	default:
+		return
+	}
+
+	hashLock := toHex(deposit.HashLock)
+	swap := w.swaps[hashLock]
+	if swap == nil {
+		if direction == DirectionSbch2Bch {
+			if bytes.Equal(deposit.RecipientPkh, w.cfg.UserPkh) && w.botBchDeposits[hashLock] == nil {
+				w.botBchDeposits[hashLock] = &userWatchDeposit{height: h, deposit: deposit}
+			}
+			return
+		}
+		swap = w.newSwap(direction, hashLock)
+	}
+	w.setBchDeposit(swap, h, deposit)
+}
+
+func (w *UserWatcher) setBchDeposit(swap *UserSwap, h int64, deposit *htlcbch.HtlcLockInfo) {
+	if swap.BchLockTxHash != "" {
+		return
+	}
+	swap.BchLockTxHash = deposit.TxHash
+	swap.BchLockHeight = h
+	swap.bchDeposit = deposit
+
+	if swap.Direction == DirectionSbch2Bch {
+		swap.Status = UserSwapBotLocked
+		if !bytes.Equal(deposit.RecipientPkh, w.cfg.UserPkh) {
+			w.alert(swap, "bot locked BCH to another recipient: "+toHex(deposit.RecipientPkh))
+		}
+	}
+	log.Infof("%s swap %s: BCH locked by tx %s", swap.Direction, swap.HashLock, deposit.TxHash)
+}
+
+func (w *UserWatcher) handleBchReceipt(receipt *htlcbch.HtlcUnlockInfo) {
+	for _, swap := range w.swaps {
+		if swap.BchLockTxHash != receipt.PrevTxHash {
+			continue
+		}
+		if swap.Direction == DirectionBch2Sbch {
+			swap.Status = UserSwapCompleted // bot unlocked BCH
+		} else if swap.Status != UserSwapCompleted {
+			swap.Status = UserSwapSecretRevealed // user unlocked BCH
+		}
+		log.Infof("%s swap %s: BCH unlocked by tx %s", swap.Direction, swap.HashLock, receipt.TxHash)
+	}
+}
+
+func (w *UserWatcher) scanSbch() error {
+	tip, err := w.sbchCli.getBlockNumber()
+	if err != nil {
+		return err
+	}
+	for fromH := w.lastSbchHeight + 1; fromH <= tip; fromH += 200 {
+		toH := fromH + 199
+		if toH > tip {
+			toH = tip
+		}
+		logs, err := w.sbchCli.getHtlcLogs(fromH, toH)
+		if err != nil {
+			return err
+		}
+		for _, ethLog := range logs {
+			w.handleSbchLog(ethLog)
+		}
+		w.lastSbchHeight = toH
+	}
+	return nil
+}
+
+func (w *UserWatcher) handleSbchLog(ethLog gethtypes.Log) {
+	if len(ethLog.Topics) == 0 {
+		return
+	}
+	switch ethLog.Topics[0] {
+	case htlcsbch.LockEventId:
+		lockLog := htlcsbch.ParseHtlcLockLog(ethLog)
+		if lockLog == nil {
+			return
+		}
+		hashLock := toHex(lockLog.HashLock[:])
+		if lockLog.LockerAddr == w.cfg.BotEvmAddr {
+			// bch2sbch, bot locked sBCH for the BCH deposit of user
+			swap := w.swaps[hashLock]
+			if swap == nil || swap.Direction != DirectionBch2Sbch || swap.SbchLockTxHash != "" {
+				return
+			}
+			swap.SbchLockTxHash = ethLog.TxHash.Hex()
+			swap.SbchLockTime = lockLog.CreatedTime
+			swap.SbchUnlockTime = lockLog.UnlockTime
+			swap.Status = UserSwapBotLocked
+			if evmAddr := gethcmn.BytesToAddress(swap.bchDeposit.SenderEvmAddr); lockLog.UnlockerAddr != evmAddr {
+				w.alert(swap, "bot locked sBCH to another address: "+lockLog.UnlockerAddr.Hex())
+			}
+			log.Infof("%s swap %s: sBCH locked by tx %s", swap.Direction, hashLock, swap.SbchLockTxHash)
+			return
+		}
+		if lockLog.UnlockerAddr != w.cfg.BotEvmAddr {
+			return
+		}
+		isUser := lockLog.LockerAddr == w.cfg.UserEvmAddr && w.cfg.UserEvmAddr != (gethcmn.Address{})
+		if !isUser && !bytes.Equal(lockLog.BchRecipientPkh[:], w.cfg.UserPkh) {
+			return
+		}
+		// sbch2bch, user locked sBCH
+		if w.swaps[hashLock] != nil {
+			return
+		}
+		swap := w.newSwap(DirectionSbch2Bch, hashLock)
+		swap.SbchLockTxHash = ethLog.TxHash.Hex()
+		swap.SbchLockTime = lockLog.CreatedTime
+		swap.SbchUnlockTime = lockLog.UnlockTime
+		log.Infof("%s swap %s: sBCH locked by tx %s", swap.Direction, hashLock, swap.SbchLockTxHash)
+		if botDeposit := w.botBchDeposits[hashLock]; botDeposit != nil {
+			delete(w.botBchDeposits, hashLock)
+			w.setBchDeposit(swap, botDeposit.height, botDeposit.deposit)
+		}
+
+	case htlcsbch.UnlockEventId:
+		unlockLog := htlcsbch.ParseHtlcUnlockLog(ethLog)
+		if unlockLog == nil {
+			return
+		}
+		swap := w.swaps[toHex(unlockLog.HashLock[:])]
+		if swap == nil || swap.isFinal() {
+			return
+		}
+		if swap.Direction == DirectionBch2Sbch {
+			swap.Status = UserSwapSecretRevealed // user unlocked sBCH
+		} else {
+			swap.Status = UserSwapCompleted // bot unlocked sBCH
+		}
+		log.Infof("%s swap %s: sBCH unlocked by tx %s", swap.Direction, swap.HashLock, ethLog.TxHash.Hex())
+
+	case htlcsbch.RefundEventId:
+		refundLog := htlcsbch.ParseHtlcRefundLog(ethLog)
+		if refundLog == nil {
+			return
+		}
+		swap := w.swaps[toHex(refundLog.HashLock[:])]
+		if swap == nil || swap.isFinal() {
+			return
+		}
+		if swap.Direction == DirectionSbch2Bch {
+			swap.Status = UserSwapRefunded
+		}
+		log.Infof("%s swap %s: sBCH refunded by tx %s", swap.Direction, swap.HashLock, ethLog.TxHash.Hex())
+	}
+}
+
+func (w *UserWatcher) newSwap(direction, hashLock string) *UserSwap {
+	swap := &UserSwap{
+		Direction: direction,
+		HashLock:  hashLock,
+		Status:    UserSwapUserLocked,
+		alerted:   map[string]bool{},
+	}
+	w.swaps[hashLock] = swap
+	return swap
+}
+
+// the same deadlines as the bot, see handleBchUserDeposit and handleSbchUserDeposit
+func (w *UserWatcher) checkSwaps() error {
+	now, err := w.sbchCli.getBlockTimeLatest()
+	if err != nil {
+		return err
+	}
+	for _, swap := range w.swaps {
+		if swap.isFinal() {
+			continue
+		}
+		if swap.Direction == DirectionBch2Sbch {
+			err = w.checkBch2SbchSwap(swap)
+		} else {
+			w.checkSbch2BchSwap(swap, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *UserWatcher) checkBch2SbchSwap(swap *UserSwap) error {
+	deposit := swap.bchDeposit
+	confirmations := w.lastBchHeight - swap.BchLockHeight + 1
+	if swap.Status == UserSwapUserLocked && confirmations > int64(deposit.Expiration)/3 {
+		w.alert(swap, "bot did not lock sBCH in time, BCH can be refunded after expiration")
+	}
+	if confirmations <= int64(deposit.Expiration) {
+		return nil
+	}
+
+	// expired
+	txOut, err := w.bchCli.GetTxOut(swap.BchLockTxHash, 0)
+	if err != nil {
+		return err
+	}
+	if txOut == nil {
+		swap.Status = UserSwapRefunded // not unlocked by bot, so refunded by someone
+		return nil
+	}
+	if swap.Status == UserSwapSecretRevealed {
+		w.alert(swap, "bot did not unlock BCH before expiration")
+	}
+	if !w.cfg.AutoRefund {
+		w.alert(swap, "BCH deposit is expired and can be refunded")
+		return nil
+	}
+	c, err := w.cfg.BchNet.NewCovenantOfDeposit(deposit)
+	if err != nil {
+		return err
+	}
+	refundTx, err := c.MakeRefundTx(gethcmn.FromHex(deposit.TxHash), deposit.Vout, int64(deposit.Value), w.cfg.RefundFeeRate)
+	if err != nil {
+		return fmt.Errorf("failed to make refund tx: %w", err)
+	}
+	txHash, err := w.bchCli.SendTx(refundTx)
+	if err != nil {
+		return fmt.Errorf("failed to send refund tx: %w", err)
+	}
+	swap.BchRefundTx = txHash.String()
+	swap.Status = UserSwapRefunded
+	log.Infof("%s swap %s: BCH refunded by tx %s", swap.Direction, swap.HashLock, swap.BchRefundTx)
+	return nil
+}
+
+func (w *UserWatcher) checkSbch2BchSwap(swap *UserSwap, now uint64) {
+	timeLock := swap.SbchUnlockTime - swap.SbchLockTime
+	if swap.Status == UserSwapUserLocked && now-swap.SbchLockTime > timeLock/3 {
+		w.alert(swap, "bot did not lock BCH in time, sBCH can be refunded after expiration")
+	}
+	if now > swap.SbchUnlockTime {
+		if swap.Status == UserSwapSecretRevealed {
+			w.alert(swap, "bot did not unlock sBCH before expiration")
+		} else {
+			w.alert(swap, "sBCH lock is expired and can be refunded by calling refund() of the HTLC contract")
+		}
+	}
+}
+
+// each alert is raised once per swap
+func (w *UserWatcher) alert(swap *UserSwap, alert string) {
+	if swap.alerted[alert] {
+		return
+	}
+	swap.alerted[alert] = true
+	swap.Alerts = append(swap.Alerts, alert)
+	log.Warnf("%s swap %s: %s", swap.Direction, swap.HashLock, alert)
+	if w.onAlert != nil {
+		w.onAlert(swap, alert)
+	}
+}
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestUserWatcher_bch2sbch(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_hashLock := gethHash32Bytes("hash")
+	_hashLock2 := gethHash32Bytes("hash2")
+	_expiration := uint16(12)
+	_penaltyBPS := uint16(500)
+
+	_depositTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{},
+		TxOut: []*wire.TxOut{
+			{
+				Value:    100000,
+				PkScript: getHtlcP2shPkScript(_userPkh, testBchPkh, _hashLock, _expiration, _penaltyBPS),
+			},
+			{
+				PkScript: newHtlcDepositOpRet(testBchPkh, _userPkh, _hashLock, _expiration, _penaltyBPS, testEvmAddr[:], 1e8),
+			},
+		},
+	}
+	_depositTx2 := &wire.MsgTx{
+		TxIn: []*wire.TxIn{},
+		TxOut: []*wire.TxOut{
+			{
+				Value:    200000,
+				PkScript: getHtlcP2shPkScript(_userPkh, testBchPkh, _hashLock2, _expiration, _penaltyBPS),
+			},
+			{
+				PkScript: newHtlcDepositOpRet(testBchPkh, _userPkh, _hashLock2, _expiration, _penaltyBPS, gethAddrBytes("evm"), 1e8),
+			},
+		},
+	}
+	_bchCli := newMockBchClient(100, 120)
+	_bchCli.blocks[101] = &wire.MsgBlock{Transactions: []*wire.MsgTx{_depositTx, _depositTx2}}
+	_bchCli.txs[_depositTx.TxHash().String()] = _depositTx
+	_bchCli.hTo = 106
+
+	// bot locks sBCH for the second deposit only, to another address
+	_sbchCli := newMockSbchClient(1, 10, 2000)
+	_sbchCli.logs[5] = []gethtypes.Log{
+		newTestEvmLockLog(gethAddr("htlc"), 5, gethHash32("locktx"), testEvmAddr, gethAddr("any"),
+			gethcmn.BytesToHash(_hashLock2), 1000, 3000, 200000),
+	}
+
+	var alerts []string
+	w := newUserWatcher(&UserWatchConfig{
+		BchNet:        htlcbch.MainNet,
+		UserPkh:       _userPkh,
+		BotPkh:        testBchPkh,
+		BotEvmAddr:    testEvmAddr,
+		AutoRefund:    true,
+		RefundFeeRate: 2,
+	}, _bchCli, _sbchCli, func(swap *UserSwap, alert string) {
+		alerts = append(alerts, swap.HashLock+": "+alert)
+	})
+	w.lastBchHeight, w.lastSbchHeight = 99, 0
+	require.NoError(t, w.round())
+	require.Len(t, w.Swaps(), 2)
+	swap := w.swaps[toHex(_hashLock)]
+	require.Equal(t, UserSwapUserLocked, swap.Status)
+	require.Equal(t, int64(101), swap.BchLockHeight)
+	swap2 := w.swaps[toHex(_hashLock2)]
+	require.Equal(t, UserSwapBotLocked, swap2.Status)
+	require.Equal(t, []string{
+		toHex(_hashLock2) + ": bot locked sBCH to another address: " + testEvmAddr.Hex(),
+		toHex(_hashLock) + ": bot did not lock sBCH in time, BCH can be refunded after expiration",
+	}, alerts)
+
+	// alerts are raised once
+	require.NoError(t, w.round())
+	require.Len(t, alerts, 2)
+
+	// expired, refunded
+	_bchCli.hTo = 113
+	require.NoError(t, w.round())
+	require.Equal(t, UserSwapRefunded, swap.Status)
+	require.Len(t, _bchCli.sentTxs, 1)
+	require.Equal(t, _depositTx.TxHash(), _bchCli.sentTxs[0].TxIn[0].PreviousOutPoint.Hash)
+	require.Equal(t, _bchCli.sentTxs[0].TxHash().String(), swap.BchRefundTx)
+	// the second deposit is spent (not in mock txs)
+	require.Equal(t, UserSwapRefunded, swap2.Status)
+}
+
+func TestUserWatcher_sbch2bch(t *testing.T) {
+	_userPkh := gethAddrBytes("user")
+	_userEvmAddr := gethAddr("userevm")
+	_hashLock := gethHash32Bytes("hash")
+	_expiration := uint16(12)
+	_penaltyBPS := uint16(500)
+
+	// bot locks BCH, found before the sBCH lock of user
+	_bchCli := newMockBchClient(100, 106)
+	_bchCli.blocks[101] = &wire.MsgBlock{Transactions: []*wire.MsgTx{
+		{
+			TxIn: []*wire.TxIn{},
+			TxOut: []*wire.TxOut{
+				{
+					Value:    100000,
+					PkScript: getHtlcP2shPkScript(testBchPkh, _userPkh, _hashLock, _expiration, _penaltyBPS),
+				},
+				{
+					PkScript: newHtlcDepositOpRet(_userPkh, testBchPkh, _hashLock, _expiration, _penaltyBPS, _userEvmAddr[:], 1e8),
+				},
+			},
+		},
+	}}
+	_sbchCli := newMockSbchClient(1, 10, 1500)
+	_sbchCli.logs[5] = []gethtypes.Log{
+		newTestEvmLockLog(gethAddr("htlc"), 5, gethHash32("locktx"), _userEvmAddr, gethcmn.BytesToAddress(_userPkh),
+			gethcmn.BytesToHash(_hashLock), 1000, 3000, 100000),
+		// not a swap of user
+		newTestEvmLockLog(gethAddr("htlc"), 5, gethHash32("locktx2"), gethAddr("other"), gethAddr("other"),
+			gethHash32("hash2"), 1000, 3000, 100000),
+	}
+
+	var alerts []string
+	w := newUserWatcher(&UserWatchConfig{
+		BchNet:      htlcbch.MainNet,
+		UserPkh:     _userPkh,
+		UserEvmAddr: _userEvmAddr,
+		BotPkh:      testBchPkh,
+		BotEvmAddr:  testEvmAddr,
+	}, _bchCli, _sbchCli, func(swap *UserSwap, alert string) {
+		alerts = append(alerts, alert)
+	})
+	w.lastBchHeight, w.lastSbchHeight = 99, 0
+	require.NoError(t, w.round())
+	require.Len(t, w.Swaps(), 1)
+	swap := w.swaps[toHex(_hashLock)]
+	require.Equal(t, DirectionSbch2Bch, swap.Direction)
+	require.Equal(t, UserSwapBotLocked, swap.Status)
+	require.Equal(t, int64(101), swap.BchLockHeight)
+	require.Equal(t, uint64(4000), swap.SbchUnlockTime)
+	require.Empty(t, alerts)
+
+	// expired
+	_sbchCli.ts = 4001
+	require.NoError(t, w.round())
+	require.Equal(t, []string{
+		"sBCH lock is expired and can be refunded by calling refund() of the HTLC contract",
+	}, alerts)
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// set at release build time:
+//
+//	go build -ldflags "-X github.com/smartbch/atomic-swap-bot/bot.Version=v1.2.3 \
+//	  -X github.com/smartbch/atomic-swap-bot/bot.GitCommit=$(git rev-parse HEAD)" ./cmd/asbot
+var (
+	Version   = "dev"
+	GitCommit = "" // got from VCS info embedded by go build if not set
+)
+
+type VersionInfo struct {
+	Version           string                  `json:"version"`
+	GitCommit         string                  `json:"git_commit"` // with -dirty suffix if built from modified sources
+	GoVersion         string                  `json:"go_version"`
+	CovenantHash      string                  `json:"covenant_hash"`      // hash of the current HTLC redeem script template
+	CovenantTemplates []*CovenantTemplateInfo `json:"covenant_templates"` // all known versions, oldest first
+}
+
+type CovenantTemplateInfo struct {
+	Version string `json:"version"`
+	Hash    string `json:"hash"`
+	Retired bool   `json:"retired"` // not accepted for new deposits, swaps taken with it are still handled
+	OptIn   bool   `json:"opt_in"`  // only used for new covenants when asked for, e.g. the batchable one
+}
+
+type BotInfoV1 struct {
+	VersionInfo
+	BchPkh  string `json:"bch_pkh"`
+	EvmAddr string `json:"evm_addr"`
+}
+
+func GetVersionInfo() *VersionInfo {
+	info := &VersionInfo{
+		Version:      Version,
+		GitCommit:    GitCommit,
+		CovenantHash: htlcbch.TemplateHash(),
+	}
+	for _, template := range htlcbch.GetCovenantTemplates() {
+		info.CovenantTemplates = append(info.CovenantTemplates, &CovenantTemplateInfo{
+			Version: template.Version,
+			Hash:    template.Hash(),
+			Retired: template.Retired,
+			OptIn:   template.OptIn,
+		})
+	}
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = buildInfo.GoVersion
+	if info.GitCommit != "" {
+		return info
+	}
+	dirty := false
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.GitCommit = setting.Value
+		case "vcs.modified":
+			dirty = setting.Value == "true"
+		}
+	}
+	if dirty && info.GitCommit != "" {
+		info.GitCommit += "-dirty"
+	}
+	return info
+}
+
+// the hash of a covenant template version matched by the parser, saved with swaps
+func getCovenantHash(version string) string {
+	template, err := htlcbch.GetCovenantTemplate(version)
+	if err != nil {
+		return "" // never happens, version is set by the parser
+	}
+	return template.Hash()
+}
+
+// the covenant template version of a swap, swaps saved without covenant hash were taken with v1,
+// an unknown hash is returned as is, so that covenants of the swap can not be made
+func getCovenantVersion(covenantHash string) string {
+	if covenantHash == "" {
+		return "v1"
+	}
+	if template := htlcbch.GetCovenantTemplateByHash(covenantHash); template != nil {
+		return template.Version
+	}
+	return covenantHash
+}
+
+// warn if in-flight swaps were taken with covenant templates this version does not know (e.g. after
+// a downgrade, or an upgrade which dropped an old template), their BCH can not be unlocked or refunded
+func (bot *MarketMakerBot) checkCovenantUpgrade() error {
+	var knownHashes []string
+	for _, template := range htlcbch.GetCovenantTemplates() {
+		knownHashes = append(knownHashes, template.Hash())
+	}
+	n, err := bot.db.countInFlightSwapsOfOtherCovenants(knownHashes)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		bot.logWarnf("%d in-flight swaps were taken with covenant templates unknown to this version, "+
+			"their BCH can not be unlocked or refunded by this version", n)
+	}
+	return nil
+}
+
+func (bot *MarketMakerBot) handleBotInfoV1(w http.ResponseWriter, r *http.Request) {
+	NewOkResp(&BotInfoV1{
+		VersionInfo: *GetVersionInfo(),
+		BchPkh:      toHex(bot.bchPkh),
+		EvmAddr:     toHex(bot.sbchAddr[:]),
+	}).WriteTo(w)
+}
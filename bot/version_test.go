@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+func TestGetVersionInfo(t *testing.T) {
+	info := GetVersionInfo()
+	require.Equal(t, "dev", info.Version)
+	require.Equal(t, htlcbch.TemplateHash(), info.CovenantHash)
+	require.NotEmpty(t, info.GoVersion)
+
+	Version, GitCommit = "v1.2.3", "abcdef"
+	defer func() { Version, GitCommit = "dev", "" }()
+	info = GetVersionInfo()
+	require.Equal(t, "v1.2.3", info.Version)
+	require.Equal(t, "abcdef", info.GitCommit)
+
+	_bot := &MarketMakerBot{bchPkh: testBchPkh, sbchAddr: testEvmAddr}
+	w := httptest.NewRecorder()
+	_bot.handleBotInfoV1(w, httptest.NewRequest("GET", "/api/v1/bot/info", nil))
+	var resp struct {
+		Result BotInfoV1 `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "v1.2.3", resp.Result.Version)
+	require.Equal(t, "abcdef", resp.Result.GitCommit)
+	require.Equal(t, info.CovenantHash, resp.Result.CovenantHash)
+	require.Equal(t, "v1", resp.Result.CovenantTemplates[0].Version)
+	require.Equal(t, toHex(testBchPkh), resp.Result.BchPkh)
+}
+
+func TestGetCovenantVersion(t *testing.T) {
+	require.Equal(t, "v1", getCovenantVersion(""))
+	require.Equal(t, "v1", getCovenantVersion(htlcbch.TemplateHash()))
+	require.Equal(t, "1234", getCovenantVersion("1234"))
+	require.Equal(t, htlcbch.TemplateHash(), getCovenantHash("v1"))
+	require.Equal(t, htlcbch.TemplateHash(), getCovenantHash(""))
+}
+
+func TestCheckCovenantUpgrade(t *testing.T) {
+	_db := initDB(t, 123, 456)
+	_bot := &MarketMakerBot{db: _db, errLogQueue: newErrLogQueue(100)}
+	addRecord := func(name, covenantHash string, status Sbch2BchStatus) {
+		require.NoError(t, _db.addSbch2BchRecord(&Sbch2BchRecord{
+			SbchLockTime:    1,
+			SbchLockTxHash:  toHex(gethHash32Bytes(name)),
+			Value:           12345678,
+			SbchSenderAddr:  toHex(gethAddrBytes("uevm")),
+			BchRecipientPkh: toHex(gethAddrBytes("ubch")),
+			HashLock:        toHex(gethHash32Bytes(name)),
+			TimeLock:        36000,
+			HtlcScriptHash:  toHex(gethAddrBytes("htlc")),
+			CovenantHash:    covenantHash,
+			Status:          status,
+		}))
+	}
+
+	addRecord("current", htlcbch.TemplateHash(), Sbch2BchStatusBchLocked)
+	addRecord("unknown", "", Sbch2BchStatusBchLocked)
+	addRecord("finished", "1234", Sbch2BchStatusSbchUnlocked)
+	require.NoError(t, _bot.checkCovenantUpgrade())
+	require.Len(t, _bot.errLogQueue.removeErrLogs(10), 0)
+
+	addRecord("old", "1234", Sbch2BchStatusBchLocked)
+	require.NoError(t, _bot.checkCovenantUpgrade())
+	logs := _bot.errLogQueue.removeErrLogs(10)
+	require.Len(t, logs, 1)
+	require.Contains(t, logs[0].Msg, "1 in-flight swaps were taken with covenant templates unknown to this version")
+}
@@ -0,0 +1,385 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	WebhookDeliveryPending = "Pending"
+	WebhookDeliveryFailed  = "Failed" // given up after webhookMaxAttempts
+
+	webhookTimeout      = 10 * time.Second
+	webhookMaxAttempts  = 10
+	webhookBaseBackoff  = 10 // in seconds, doubled after each failed attempt
+	webhookMaxBackoff   = 3600
+	webhookBatchSize    = 100
+	webhookMaxErrLength = 256
+)
+
+// WebhookConfig is an integrator endpoint which is POSTed a WebhookEvent on each swap status change
+type WebhookConfig struct {
+	Url    string `json:"url"`
+	Secret string `json:"secret"` // HMAC-SHA256 key of X-Webhook-Signature
+}
+
+// WebhookEvent is the JSON body POSTed to webhooks, deliveries may be repeated and
+// reordered by retries, so receivers should deduplicate them by id
+type WebhookEvent struct {
+	Id          uint              `json:"id"`              // unique ID of the status change
+	Direction   string            `json:"direction"`       // bch2sbch|sbch2bch
+	HashLock    string            `json:"hash_lock"`       //
+	Status      string            `json:"status"`          // the new status
+	Value       uint64            `json:"value"`           // in sats
+	Token       string            `json:"token,omitempty"` // SEP20 token symbol
+	Memo        string            `json:"memo,omitempty"`  // user reference ID carried by the deposit
+	UserBchPkh  string            `json:"user_bch_pkh"`    //
+	UserEvmAddr string            `json:"user_evm_addr"`   //
+	Txs         map[string]string `json:"txs"`             // leg => tx hash, see SwapLegXxx
+	Time        int64             `json:"time"`            // unix seconds of the change
+}
+
+type WebhooksInfo struct {
+	Urls   []string               `json:"urls"`
+	Failed []*WebhookDeliveryInfo `json:"failed"` // oldest first
+}
+
+type WebhookDeliveryInfo struct {
+	EventId   uint   `json:"event_id"`
+	Url       string `json:"url"`
+	Payload   string `json:"payload"`
+	Attempts  uint32 `json:"attempts"`
+	LastError string `json:"last_error"`
+}
+
+// WebhookDispatcher POSTs swap status changes to integrator webhooks, so that they do not
+// need to poll the API. Status changes are saved to DB together with swap records,
+// the main loop fans them out to one delivery per webhook, and deliveries are POSTed
+// by a goroutine (slow webhooks do not block the main loop) with exponential backoff.
+// Each request is signed as:
+//
+//	X-Webhook-Id        : id of the event
+//	X-Webhook-Timestamp : unix seconds
+//	X-Webhook-Signature : hex(HMAC-SHA256(secret, timestamp + "." + body))
+type WebhookDispatcher struct {
+	db     DB
+	client *http.Client
+	wakeCh chan struct{}
+	errLog *ErrLogQueue
+
+	mu      sync.Mutex
+	secrets map[string]string // url => secret
+	urls    []string          // in config order
+}
+
+func newWebhookDispatcher(db DB, webhooks []WebhookConfig, errLog *ErrLogQueue) (*WebhookDispatcher, error) {
+	d := &WebhookDispatcher{
+		db:     db,
+		client: &http.Client{Timeout: webhookTimeout},
+		wakeCh: make(chan struct{}, 1),
+		errLog: errLog,
+	}
+	return d, d.set(webhooks)
+}
+
+func (d *WebhookDispatcher) set(webhooks []WebhookConfig) error {
+	secrets := map[string]string{}
+	var urls []string
+	for _, webhook := range webhooks {
+		u, err := url.Parse(webhook.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL: %s", webhook.Url)
+		}
+		if webhook.Secret == "" {
+			return fmt.Errorf("missing secret of webhook: %s", webhook.Url)
+		}
+		if _, ok := secrets[webhook.Url]; ok {
+			return fmt.Errorf("duplicated webhook: %s", webhook.Url)
+		}
+		secrets[webhook.Url] = webhook.Secret
+		urls = append(urls, webhook.Url)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.secrets = secrets
+	d.urls = urls
+	return nil
+}
+
+func (d *WebhookDispatcher) getUrls() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.urls
+}
+
+// removed webhooks are not POSTed anymore
+func (d *WebhookDispatcher) getSecret(url string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	secret, ok := d.secrets[url]
+	return secret, ok
+}
+
+// create one delivery per webhook and emails of subscribed users for each status change,
+// changes made while no webhook is configured are dropped
+func (d *WebhookDispatcher) fanOut(emails *EmailNotifier) error {
+	changes, err := d.db.getSwapStatusChanges(webhookBatchSize)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+	urls := d.getUrls()
+	var deliveries []*WebhookDelivery
+	var events []*WebhookEvent
+	for _, change := range changes {
+		event := &WebhookEvent{}
+		if err = json.Unmarshal([]byte(change.Payload), event); err != nil {
+			return fmt.Errorf("invalid status change#%d: %w", change.ID, err)
+		}
+		event.Id = change.ID
+		event.Time = change.CreatedAt.Unix()
+		events = append(events, event)
+		payload := toJSON(event)
+		for _, webhookUrl := range urls {
+			deliveries = append(deliveries, &WebhookDelivery{
+				EventId:       change.ID,
+				Url:           webhookUrl,
+				Payload:       payload,
+				NextAttemptAt: event.Time,
+				Status:        WebhookDeliveryPending,
+			})
+		}
+	}
+	emailDeliveries, err := emails.newDeliveries(events)
+	if err != nil {
+		return err
+	}
+	return d.db.fanOutSwapStatusChanges(changes, deliveries, emailDeliveries)
+}
+
+// POST due deliveries when woken up by the main loop, so that standby instances stay quiet
+func (d *WebhookDispatcher) run() {
+	for range d.wakeCh {
+		d.deliverDue(time.Now().Unix())
+	}
+}
+
+func (d *WebhookDispatcher) wake() {
+	select {
+	case d.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+func (d *WebhookDispatcher) deliverDue(now int64) {
+	deliveries, err := d.db.getDueWebhookDeliveries(now, webhookBatchSize)
+	if err != nil {
+		d.logError("DB error, failed to get webhook deliveries", err)
+		return
+	}
+	for _, delivery := range deliveries {
+		d.deliver(delivery, now)
+	}
+}
+
+func (d *WebhookDispatcher) deliver(delivery *WebhookDelivery, now int64) {
+	secret, ok := d.getSecret(delivery.Url)
+	if !ok {
+		log.Infof("webhook removed, drop delivery, url: %s, event: %d", delivery.Url, delivery.EventId)
+		if err := d.db.deleteWebhookDelivery(delivery); err != nil {
+			d.logError("DB error, failed to delete webhook delivery", err)
+		}
+		return
+	}
+
+	err := d.post(delivery, secret, now)
+	if err == nil {
+		log.Infof("webhook delivered, url: %s, event: %d", delivery.Url, delivery.EventId)
+		if err = d.db.deleteWebhookDelivery(delivery); err != nil {
+			d.logError("DB error, failed to delete webhook delivery", err)
+		}
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if len(delivery.LastError) > webhookMaxErrLength {
+		delivery.LastError = delivery.LastError[:webhookMaxErrLength]
+	}
+	if delivery.Attempts >= webhookMaxAttempts {
+		delivery.Status = WebhookDeliveryFailed
+		d.logError(fmt.Sprintf("webhook delivery failed, url: %s, event: %d, attempts: %d",
+			delivery.Url, delivery.EventId, delivery.Attempts), err)
+	} else {
+		delivery.NextAttemptAt = now + getWebhookBackoff(delivery.Attempts)
+		log.Infof("webhook delivery failed, url: %s, event: %d, attempts: %d, error: %s",
+			delivery.Url, delivery.EventId, delivery.Attempts, err.Error())
+	}
+	if err = d.db.updateWebhookDelivery(delivery); err != nil {
+		d.logError("DB error, failed to update webhook delivery", err)
+	}
+}
+
+func (d *WebhookDispatcher) logError(msg string, err error) {
+	log.Error(msg, ": ", err)
+	d.errLog.recordErrLog("error", fmt.Sprintf("%s: %s", msg, err))
+}
+
+func (d *WebhookDispatcher) post(delivery *WebhookDelivery, secret string, now int64) error {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(now, 10)
+	req, err := http.NewRequest(http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", strconv.FormatUint(uint64(delivery.EventId), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", signWebhookPayload(secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// 10s, 20s, 40s ... up to 1h
+func getWebhookBackoff(attempts uint32) int64 {
+	backoff := int64(webhookBaseBackoff)
+	for i := uint32(1); i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	return backoff
+}
+
+func (bot *MarketMakerBot) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !bot.checkApiAuth(w, r, RoleReader, false) {
+		return
+	}
+	if bot.webhooks == nil {
+		NewErrResp("webhooks are not enabled").WriteTo(w)
+		return
+	}
+	deliveries, err := bot.db.getWebhookDeliveriesByStatus(WebhookDeliveryFailed, bot.dbQueryLimit)
+	if err != nil {
+		NewErrResp(err.Error()).WriteTo(w)
+		return
+	}
+	info := &WebhooksInfo{Urls: bot.webhooks.getUrls(), Failed: []*WebhookDeliveryInfo{}}
+	for _, delivery := range deliveries {
+		info.Failed = append(info.Failed, &WebhookDeliveryInfo{
+			EventId:   delivery.EventId,
+			Url:       delivery.Url,
+			Payload:   delivery.Payload,
+			Attempts:  delivery.Attempts,
+			LastError: delivery.LastError,
+		})
+	}
+	NewOkResp(info).WriteTo(w)
+}
+
+// called by the main loop, only when this instance is leading
+func (bot *MarketMakerBot) dispatchWebhooks() {
+	if bot.webhooks == nil {
+		return
+	}
+	if err := bot.webhooks.fanOut(bot.emails); err != nil {
+		bot.logError("DB error, failed to fan out swap status changes: ", err)
+		return
+	}
+	bot.webhooks.wake()
+	if bot.emails != nil {
+		bot.emails.wake()
+	}
+}
+
+func newBch2SbchStatusChange(record *Bch2SbchRecord) *SwapStatusChange {
+	txs := map[string]string{}
+	addTx := func(leg, txHash string) {
+		if txHash != "" {
+			txs[leg] = txHash
+		}
+	}
+	addTx(SwapLegBchLock, record.BchLockTxHash)
+	addTx(SwapLegSbchLock, record.SbchLockTxHash)
+	addTx(SwapLegSbchUnlock, record.SbchUnlockTxHash)
+	addTx(SwapLegBchUnlock, record.BchUnlockTxHash)
+	addTx(SwapLegSbchRefund, record.SbchRefundTxHash)
+	addTx("bch_remainder", record.RemainderTxHash)
+	event := &WebhookEvent{
+		Direction:   DirectionBch2Sbch,
+		HashLock:    record.HashLock,
+		Status:      record.Status.String(),
+		Value:       record.Value,
+		Token:       record.Token,
+		Memo:        record.Memo,
+		UserBchPkh:  record.SenderPkh,
+		UserEvmAddr: record.SenderEvmAddr,
+		Txs:         txs,
+	}
+	return &SwapStatusChange{
+		Direction: event.Direction,
+		HashLock:  event.HashLock,
+		Status:    event.Status,
+		Payload:   toJSON(event),
+	}
+}
+
+func newSbch2BchStatusChange(record *Sbch2BchRecord) *SwapStatusChange {
+	txs := map[string]string{}
+	addTx := func(leg, txHash string) {
+		if txHash != "" {
+			txs[leg] = txHash
+		}
+	}
+	addTx(SwapLegSbchLock, record.SbchLockTxHash)
+	addTx(SwapLegBchLock, record.BchLockTxHash)
+	addTx(SwapLegBchUnlock, record.BchUnlockTxHash)
+	addTx(SwapLegSbchUnlock, record.SbchUnlockTxHash)
+	addTx(SwapLegBchRefund, record.BchRefundTxHash)
+	event := &WebhookEvent{
+		Direction:   DirectionSbch2Bch,
+		HashLock:    record.HashLock,
+		Status:      record.Status.String(),
+		Value:       record.Value,
+		Token:       record.Token,
+		Memo:        record.Memo,
+		UserBchPkh:  record.BchRecipientPkh,
+		UserEvmAddr: record.SbchSenderAddr,
+		Txs:         txs,
+	}
+	return &SwapStatusChange{
+		Direction: event.Direction,
+		HashLock:  event.HashLock,
+		Status:    event.Status,
+		Payload:   toJSON(event),
+	}
+}
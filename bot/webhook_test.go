@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapStatusChanges(t *testing.T) {
+	db := initDB(t, 123, 456)
+	record := &Bch2SbchRecord{
+		BchLockHeight:  11,
+		BchLockTxHash:  "22",
+		Value:          44,
+		RecipientPkh:   "55",
+		SenderPkh:      "66",
+		HashLock:       "77",
+		TimeLock:       88,
+		HtlcScriptHash: "99",
+		SenderEvmAddr:  "aa",
+	}
+	require.NoError(t, db.addBch2SbchRecord(record))
+	require.NoError(t, db.updateBch2SbchRecord(record.UpdateStatusToSbchLocked("bb", 1234)))
+	record.SbchLockTxTime = 1235 // status not changed
+	require.NoError(t, db.updateBch2SbchRecord(record))
+
+	record2 := &Sbch2BchRecord{
+		SbchLockTime:    11,
+		SbchLockTxHash:  "cc",
+		Value:           33,
+		SbchSenderAddr:  "44",
+		BchRecipientPkh: "55",
+		HashLock:        "66",
+		TimeLock:        77,
+		HtlcScriptHash:  "88",
+	}
+	require.NoError(t, db.addSbch2BchRecord(record2))
+	require.NoError(t, db.updateSbch2BchRecord(record2.UpdateStatusToBchLocked("dd")))
+
+	changes, err := db.getSwapStatusChanges(100)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	require.Equal(t, []string{"77:New", "77:SbchLocked", "66:New", "66:BchLocked"}, []string{
+		changes[0].HashLock + ":" + changes[0].Status,
+		changes[1].HashLock + ":" + changes[1].Status,
+		changes[2].HashLock + ":" + changes[2].Status,
+		changes[3].HashLock + ":" + changes[3].Status,
+	})
+	require.Equal(t, `{"id":0,"direction":"bch2sbch","hash_lock":"77","status":"SbchLocked","value":44,`+
+		`"user_bch_pkh":"66","user_evm_addr":"aa","txs":{"bch_lock":"22","sbch_lock":"bb"},"time":0}`,
+		changes[1].Payload)
+	require.Equal(t, `{"id":0,"direction":"sbch2bch","hash_lock":"66","status":"BchLocked","value":33,`+
+		`"user_bch_pkh":"55","user_evm_addr":"44","txs":{"bch_lock":"dd","sbch_lock":"cc"},"time":0}`,
+		changes[3].Payload)
+}
+
+func TestNewWebhookDispatcher(t *testing.T) {
+	_, err := newWebhookDispatcher(DB{}, []WebhookConfig{{Url: "ftp://a.io/hook", Secret: "s"}}, nil)
+	require.ErrorContains(t, err, "invalid webhook URL: ftp://a.io/hook")
+	_, err = newWebhookDispatcher(DB{}, []WebhookConfig{{Url: "https://a.io/hook"}}, nil)
+	require.ErrorContains(t, err, "missing secret of webhook: https://a.io/hook")
+	_, err = newWebhookDispatcher(DB{}, []WebhookConfig{
+		{Url: "https://a.io/hook", Secret: "s1"},
+		{Url: "https://a.io/hook", Secret: "s2"},
+	}, nil)
+	require.ErrorContains(t, err, "duplicated webhook: https://a.io/hook")
+
+	d, err := newWebhookDispatcher(DB{}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, d.getUrls(), 0)
+}
+
+func TestGetWebhookBackoff(t *testing.T) {
+	require.Equal(t, int64(10), getWebhookBackoff(1))
+	require.Equal(t, int64(20), getWebhookBackoff(2))
+	require.Equal(t, int64(2560), getWebhookBackoff(9))
+	require.Equal(t, int64(3600), getWebhookBackoff(10))
+	require.Equal(t, int64(3600), getWebhookBackoff(100))
+}
+
+func TestDispatchWebhooks(t *testing.T) {
+	var bodies []string
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts := r.Header.Get("X-Webhook-Timestamp")
+		require.Equal(t, signWebhookPayload("secret", ts, body), r.Header.Get("X-Webhook-Signature"))
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		bodies = append(bodies, r.Header.Get("X-Webhook-Id")+":"+string(body))
+	}))
+	defer server.Close()
+
+	_db := initDB(t, 123, 456)
+	errLogQueue := newErrLogQueue(100)
+	webhooks, err := newWebhookDispatcher(_db, []WebhookConfig{{Url: server.URL, Secret: "secret"}}, errLogQueue)
+	require.NoError(t, err)
+	_bot := &MarketMakerBot{
+		db:           _db,
+		webhooks:     webhooks,
+		dbQueryLimit: 100,
+		adminToken:   "secret",
+		errLogQueue:  errLogQueue,
+	}
+
+	record := &Sbch2BchRecord{
+		SbchLockTime:    11,
+		SbchLockTxHash:  "cc",
+		Value:           33,
+		SbchSenderAddr:  "44",
+		BchRecipientPkh: "55",
+		HashLock:        "66",
+		TimeLock:        77,
+		HtlcScriptHash:  "88",
+	}
+	require.NoError(t, _db.addSbch2BchRecord(record))
+	_bot.dispatchWebhooks()
+	changes, err := _db.getSwapStatusChanges(100)
+	require.NoError(t, err)
+	require.Len(t, changes, 0)
+
+	// failed attempts are retried with backoff
+	deliveries, err := _db.getDueWebhookDeliveries(1<<40, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	now := deliveries[0].NextAttemptAt
+	webhooks.deliverDue(now)
+	deliveries, err = _db.getDueWebhookDeliveries(now+9, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 0)
+	deliveries, err = _db.getDueWebhookDeliveries(now+10, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, uint32(1), deliveries[0].Attempts)
+	require.Equal(t, "unexpected status: 500 Internal Server Error", deliveries[0].LastError)
+
+	failing = false
+	webhooks.deliverDue(now + 10)
+	require.Len(t, bodies, 1)
+	require.True(t, strings.HasPrefix(bodies[0], "1:{\"id\":1,\"direction\":\"sbch2bch\",\"hash_lock\":\"66\",\"status\":\"New\""))
+	event := &WebhookEvent{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(bodies[0], "1:")), event))
+	require.Equal(t, now, event.Time)
+	deliveries, err = _db.getDueWebhookDeliveries(1<<40, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 0)
+
+	// give up after max attempts
+	failing = true
+	require.NoError(t, _db.updateSbch2BchRecord(record.UpdateStatusToBchLocked("dd")))
+	_bot.dispatchWebhooks()
+	for i := 0; i < webhookMaxAttempts; i++ {
+		webhooks.deliverDue(1<<40 + int64(i)*webhookMaxBackoff)
+	}
+	deliveries, err = _db.getWebhookDeliveriesByStatus(WebhookDeliveryFailed, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, uint32(webhookMaxAttempts), deliveries[0].Attempts)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	_bot.createHttpHandlers().ServeHTTP(w, r)
+	require.Contains(t, w.Body.String(), `"urls":["`+server.URL+`"]`)
+	require.Contains(t, w.Body.String(), `"event_id":2`)
+	require.Contains(t, w.Body.String(), `"attempts":10`)
+
+	// removed webhooks are dropped
+	require.NoError(t, webhooks.set(nil))
+	require.NoError(t, _db.updateSbch2BchRecord(record.UpdateStatusToSecretRevealed("ee", "ff")))
+	_bot.dispatchWebhooks()
+	deliveries, err = _db.getDueWebhookDeliveries(1<<40, 100)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 0)
+}
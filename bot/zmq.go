@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	zmqTopicHashBlock = "hashblock"
+	zmqTopicRawBlock  = "rawblock"
+
+	zmqDialTimeout    = 5 * time.Second
+	zmqMaxRetryDelay  = time.Minute
+	zmqMaxFrameSize   = 64 << 20 // rawblock may be big
+	zmqFlagMore       = 0x01
+	zmqFlagLong       = 0x02
+	zmqFlagCommand    = 0x04
+	zmqGreetingLength = 64
+)
+
+// ZmqListener subscribes block notifications published by BCH node over ZMQ
+// (e.g. BCHN -zmqpubhashblock=tcp://127.0.0.1:28332), so that new blocks are scanned
+// as soon as they arrive. Only ZMTP 3.0 with NULL security is supported.
+// The loop keeps polling, so nothing is missed while ZMQ is unavailable.
+type ZmqListener struct {
+	addr      string // host:port
+	topic     string // hashblock|rawblock
+	newBlocks chan struct{}
+}
+
+func newZmqListener(zmqUrl string) (*ZmqListener, error) {
+	u, err := url.Parse(zmqUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ZMQ URL: %w", err)
+	}
+	if u.Scheme != "tcp" || u.Host == "" {
+		return nil, fmt.Errorf("invalid ZMQ URL, expected tcp://host:port: %s", zmqUrl)
+	}
+	topic := zmqTopicHashBlock
+	if u.Path == "/"+zmqTopicRawBlock {
+		topic = zmqTopicRawBlock
+	}
+	return &ZmqListener{
+		addr:      u.Host,
+		topic:     topic,
+		newBlocks: make(chan struct{}, 1),
+	}, nil
+}
+
+// NewBlocks is signaled (at most one pending signal) when a block is published
+func (zl *ZmqListener) NewBlocks() <-chan struct{} {
+	return zl.newBlocks
+}
+
+// Run connects and reconnects forever
+func (zl *ZmqListener) Run() {
+	retryDelay := time.Second
+	for {
+		connectedAt := time.Now()
+		err := zl.listen()
+		log.Warn("ZMQ listener disconnected, fallback to polling: ", err)
+		if time.Since(connectedAt) > zmqMaxRetryDelay {
+			retryDelay = time.Second
+		}
+		time.Sleep(retryDelay)
+		if retryDelay *= 2; retryDelay > zmqMaxRetryDelay {
+			retryDelay = zmqMaxRetryDelay
+		}
+	}
+}
+
+func (zl *ZmqListener) listen() error {
+	conn, err := net.DialTimeout("tcp", zl.addr, zmqDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	if err = zmqHandshake(conn, r, zl.topic); err != nil {
+		return err
+	}
+	log.Info("ZMQ listener subscribed ", zl.topic, " from ", zl.addr)
+
+	for {
+		msg, err := zmqReadMsg(r)
+		if err != nil {
+			return err
+		}
+		if len(msg) > 0 && string(msg[0]) == zl.topic {
+			zl.notify()
+		}
+	}
+}
+
+func (zl *ZmqListener) notify() {
+	select {
+	case zl.newBlocks <- struct{}{}:
+	default: // a signal is already pending
+	}
+}
+
+// exchange greetings and READY commands as a SUB socket, then subscribe the topic
+func zmqHandshake(w io.Writer, r *bufio.Reader, topic string) error {
+	greeting := make([]byte, zmqGreetingLength)
+	greeting[0] = 0xFF
+	greeting[9] = 0x7F
+	greeting[10] = 3 // version 3.0
+	copy(greeting[12:], "NULL")
+	if _, err := w.Write(greeting); err != nil {
+		return err
+	}
+	peerGreeting := make([]byte, zmqGreetingLength)
+	if _, err := io.ReadFull(r, peerGreeting); err != nil {
+		return err
+	}
+	if peerGreeting[0] != 0xFF || peerGreeting[9] != 0x7F || peerGreeting[10] < 3 {
+		return fmt.Errorf("unsupported ZMTP greeting: %x", peerGreeting[:11])
+	}
+
+	ready := zmqCommand("READY", "Socket-Type", "SUB")
+	if err := zmqWriteFrame(w, zmqFlagCommand, ready); err != nil {
+		return err
+	}
+	flags, body, err := zmqReadFrame(r)
+	if err != nil {
+		return err
+	}
+	if flags&zmqFlagCommand == 0 || len(body) < 6 || string(body[1:6]) != "READY" {
+		return fmt.Errorf("unexpected ZMTP command: %q", body)
+	}
+
+	return zmqWriteFrame(w, 0, append([]byte{0x01}, topic...))
+}
+
+func zmqCommand(name string, props ...string) []byte {
+	body := append([]byte{byte(len(name))}, name...)
+	for i := 0; i+1 < len(props); i += 2 {
+		body = append(body, byte(len(props[i])))
+		body = append(body, props[i]...)
+		body = binary.BigEndian.AppendUint32(body, uint32(len(props[i+1])))
+		body = append(body, props[i+1]...)
+	}
+	return body
+}
+
+func zmqWriteFrame(w io.Writer, flags byte, body []byte) error {
+	var header []byte
+	if len(body) > 255 {
+		header = binary.BigEndian.AppendUint64([]byte{flags | zmqFlagLong}, uint64(len(body)))
+	} else {
+		header = []byte{flags, byte(len(body))}
+	}
+	_, err := w.Write(append(header, body...))
+	return err
+}
+
+func zmqReadFrame(r *bufio.Reader) (flags byte, body []byte, err error) {
+	flags, err = r.ReadByte()
+	if err != nil {
+		return
+	}
+	var size uint64
+	if flags&zmqFlagLong != 0 {
+		var buf [8]byte
+		if _, err = io.ReadFull(r, buf[:]); err != nil {
+			return
+		}
+		size = binary.BigEndian.Uint64(buf[:])
+	} else {
+		var b byte
+		if b, err = r.ReadByte(); err != nil {
+			return
+		}
+		size = uint64(b)
+	}
+	if size > zmqMaxFrameSize {
+		err = fmt.Errorf("ZMTP frame too big: %d", size)
+		return
+	}
+	body = make([]byte, size)
+	_, err = io.ReadFull(r, body)
+	return
+}
+
+// read a multipart message, commands (e.g. PING) are skipped
+func zmqReadMsg(r *bufio.Reader) ([][]byte, error) {
+	var msg [][]byte
+	for {
+		flags, body, err := zmqReadFrame(r)
+		if err != nil {
+			return nil, err
+		}
+		if flags&zmqFlagCommand != 0 {
+			continue
+		}
+		msg = append(msg, body)
+		if flags&zmqFlagMore == 0 {
+			return msg, nil
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewZmqListener(t *testing.T) {
+	_, err := newZmqListener("http://127.0.0.1:28332")
+	require.ErrorContains(t, err, "expected tcp://host:port")
+
+	zl, err := newZmqListener("tcp://127.0.0.1:28332")
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:28332", zl.addr)
+	require.Equal(t, zmqTopicHashBlock, zl.topic)
+
+	zl, err = newZmqListener("tcp://127.0.0.1:28332/rawblock")
+	require.NoError(t, err)
+	require.Equal(t, zmqTopicRawBlock, zl.topic)
+}
+
+func TestZmqListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	subscribed := make(chan []byte, 1)
+	go func() {
+		// a minimal ZMTP 3.0 PUB socket
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		greeting := make([]byte, zmqGreetingLength)
+		greeting[0], greeting[9], greeting[10] = 0xFF, 0x7F, 3
+		copy(greeting[12:], "NULL")
+		_, _ = conn.Write(greeting)
+		_, _ = io.ReadFull(r, make([]byte, zmqGreetingLength))
+		_, _, _ = zmqReadFrame(r) // READY
+		_ = zmqWriteFrame(conn, zmqFlagCommand, zmqCommand("READY", "Socket-Type", "PUB"))
+		_, sub, _ := zmqReadFrame(r)
+		subscribed <- sub
+
+		_ = zmqWriteFrame(conn, zmqFlagCommand, zmqCommand("PING"))
+		_ = zmqWriteFrame(conn, zmqFlagMore, []byte(zmqTopicHashBlock))
+		_ = zmqWriteFrame(conn, zmqFlagMore, make([]byte, 32))
+		_ = zmqWriteFrame(conn, 0, []byte{1, 0, 0, 0})
+		time.Sleep(time.Second)
+	}()
+
+	zl, err := newZmqListener("tcp://" + ln.Addr().String())
+	require.NoError(t, err)
+	go func() { _ = zl.listen() }()
+
+	select {
+	case sub := <-subscribed:
+		require.Equal(t, append([]byte{0x01}, zmqTopicHashBlock...), sub)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not subscribed")
+	}
+	select {
+	case <-zl.NewBlocks():
+	case <-time.After(5 * time.Second):
+		t.Fatal("new block not notified")
+	}
+
+	// wake up early, or fallback to polling
+	_bot := &MarketMakerBot{bchZmq: zl}
+	zl.notify()
+	zl.notify() // at most one pending signal
+	start := time.Now()
+	_bot.waitForNextRound()
+	require.Less(t, time.Since(start), time.Second)
+	_bot.waitForNextRound()
+	require.GreaterOrEqual(t, time.Since(start), 2*time.Second)
+}
+
+func TestZmqFrame(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		_ = zmqWriteFrame(pw, zmqFlagMore, []byte("rawblock"))
+		_ = zmqWriteFrame(pw, 0, make([]byte, 1000))
+	}()
+	msg, err := zmqReadMsg(bufio.NewReader(pr))
+	require.NoError(t, err)
+	require.Len(t, msg, 2)
+	require.Equal(t, "rawblock", string(msg[0]))
+	require.Len(t, msg[1], 1000)
+}
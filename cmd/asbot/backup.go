@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+)
+
+// backup --out=FILE [bot options]
+func backup(args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	registerFlags(fs)
+	outFile := fs.String("out", "", "snapshot file to write")
+	_ = fs.Parse(args)
+
+	if *outFile == "" {
+		log.Fatal("missing --out")
+	}
+	cfg := makeConfig(fs)
+	f, err := os.OpenFile(*outFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Fatal(err)
+	}
+	stats, err := bot.BackupDB(cfg.DbFile, f)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		_ = os.Remove(*outFile)
+		log.Fatal("failed to back up DB: ", err)
+	}
+	fmt.Println("snapshot is written to", *outFile)
+	printSnapshotStats(stats)
+}
+
+// restore --in=FILE [bot options], DB file of bot options must be new or empty
+func restore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	registerFlags(fs)
+	inFile := fs.String("in", "", "snapshot file to read")
+	_ = fs.Parse(args)
+
+	if *inFile == "" {
+		log.Fatal("missing --in")
+	}
+	cfg := makeConfig(fs)
+	f, err := os.Open(*inFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	stats, err := bot.RestoreDB(f, cfg.DbFile)
+	if err != nil {
+		log.Fatal("failed to restore DB: ", err)
+	}
+	fmt.Println("DB is restored to", cfg.DbFile)
+	printSnapshotStats(stats)
+}
+
+func printSnapshotStats(stats *bot.SnapshotStats) {
+	fmt.Println("created at      :", time.Unix(stats.Header.CreatedAt, 0).Format(time.RFC3339))
+	fmt.Println("last BCH height :", stats.Header.LastBchHeight)
+	fmt.Println("last sBCH height:", stats.Header.LastSbchHeight)
+	tables := make([]string, 0, len(stats.Rows))
+	for table := range stats.Rows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Printf("  %-24s %d\n", table, stats.Rows[table])
+	}
+}
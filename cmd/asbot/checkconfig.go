@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+)
+
+// check-config [--offline] [bot options]
+func checkConfig(args []string) {
+	fs := flag.NewFlagSet("check-config", flag.ExitOnError)
+	registerFlags(fs)
+	offline := fs.Bool("offline", false, "do not connect to RPC endpoints")
+	_ = fs.Parse(args)
+
+	problems := bot.CheckConfig(makeConfig(fs), !*offline)
+	printConfigProblems(problems)
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printConfigProblems(problems []*bot.ConfigProblem) {
+	if len(problems) == 0 {
+		fmt.Println("config is OK")
+		return
+	}
+	fmt.Println("config problems:", len(problems))
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"option", "problem", "hint"})
+	table.SetAutoWrapText(false)
+	for _, problem := range problems {
+		table.Append([]string{problem.Field, problem.Problem, problem.Hint})
+	}
+	table.Render()
+}
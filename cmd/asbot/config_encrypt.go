@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+)
+
+// the passphrase is taken from env, or read from stdin if config files have encrypted values
+func readConfigPassphrase() string {
+	if passphrase := os.Getenv(bot.ConfigPassphraseEnv); passphrase != "" {
+		return passphrase
+	}
+	if configFile == "" {
+		return ""
+	}
+	files := []string{configFile}
+	if configProfile != "" {
+		files = append(files, bot.GetProfileConfigFile(configFile, configProfile))
+	}
+	for _, file := range files {
+		if encrypted, _ := bot.IsConfigFileEncrypted(file); encrypted {
+			return readLine("Enter the config passphrase: ")
+		}
+	}
+	return ""
+}
+
+// encrypt-config-value, prints the value to put into config file
+func encryptConfigValue() {
+	passphrase := os.Getenv(bot.ConfigPassphraseEnv)
+	if passphrase == "" {
+		passphrase = readLine("Enter the config passphrase: ")
+	}
+	value := readLine("Enter the value to encrypt: ")
+	encrypted, err := bot.EncryptConfigValue(value, passphrase)
+	if err != nil {
+		log.Fatal("failed to encrypt: ", err)
+	}
+	fmt.Println(encrypted)
+}
+
+func readLine(prompt string) string {
+	var line string
+	fmt.Print(prompt)
+	_, _ = fmt.Scanln(&line)
+	if line == "" {
+		log.Fatal("empty input")
+	}
+	return line
+}
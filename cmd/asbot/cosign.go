@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchutil"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// cosign --treasury-m=M --treasury-pubkeys=PK1,PK2,... --unsigned-tx=HEX --in-amounts=SATS1,SATS2,... --wif=WIF
+// prints outputs of the proposal for review, and the signatures to be posted to /admin/treasury/sign
+func cosign(args []string) {
+	fs := flag.NewFlagSet("cosign", flag.ExitOnError)
+	net := fs.String("bch-net", "mainnet", "BCH network: mainnet|testnet3|testnet4|chipnet|regtest")
+	m := fs.Uint("treasury-m", 0, "m of the m-of-n multisig treasury")
+	pubKeys := fs.String("treasury-pubkeys", "", "comma separated hex compressed pubkeys of co-signers")
+	unsignedTx := fs.String("unsigned-tx", "", "unsigned_tx of the proposal, in hex")
+	inAmounts := fs.String("in-amounts", "", "comma separated in_amounts of the proposal, in sats")
+	wifStr := fs.String("wif", "", "WIF of the co-signer")
+	_ = fs.Parse(args)
+
+	params, err := htlcbch.GetChainParams(*net)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var pks [][]byte
+	for _, pk := range splitList(*pubKeys) {
+		pks = append(pks, gethcmn.FromHex(pk))
+	}
+	treasury, err := htlcbch.NewMultisigTreasury(int(*m), pks, params.Net)
+	if err != nil {
+		log.Fatal("invalid treasury: ", err)
+	}
+	wif, err := bchutil.DecodeWIF(*wifStr)
+	if err != nil {
+		log.Fatal("invalid WIF: ", err)
+	}
+	tx, err := htlcbch.MsgTxFromBytes(gethcmn.FromHex(*unsignedTx))
+	if err != nil {
+		log.Fatal("invalid unsigned tx: ", err)
+	}
+	amounts := splitList(*inAmounts)
+	if len(amounts) != len(tx.TxIn) {
+		log.Fatalf("%d input amounts are required, got %d", len(tx.TxIn), len(amounts))
+	}
+
+	// review what is signed
+	for i, out := range tx.TxOut {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(out.PkScript, params.Net)
+		if err != nil || len(addrs) != 1 {
+			fmt.Printf("output#%d : %d sats to non-standard script\n", i, out.Value)
+			continue
+		}
+		fmt.Printf("output#%d : %d sats to %s\n", i, out.Value, addrs[0].String())
+	}
+
+	fmt.Println("pubkey:", gethcmn.Bytes2Hex(wif.PrivKey.PubKey().SerializeCompressed()))
+	fmt.Println("signatures:")
+	for i := range tx.TxIn {
+		inAmt, err := strconv.ParseInt(amounts[i], 10, 64)
+		if err != nil {
+			log.Fatal("invalid input amount: ", err)
+		}
+		sig, err := treasury.SignInput(tx, i, inAmt, wif.PrivKey)
+		if err != nil {
+			log.Fatal("failed to sign: ", err)
+		}
+		fmt.Println(gethcmn.Bytes2Hex(sig))
+	}
+}
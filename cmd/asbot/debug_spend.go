@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// debug-spend --tx=HEX|--tx-hash=TXID [--input=N] --rpc-url=URL
+func debugSpend(args []string) {
+	fs := flag.NewFlagSet("debug-spend", flag.ExitOnError)
+	txHex := fs.String("tx", "", "raw spending tx in hex, e.g. one rejected by the node")
+	txHash := fs.String("tx-hash", "", "hash of the spending tx, queried from the node if --tx is not given")
+	inIdx := fs.Int("input", 0, "index of the input to debug")
+	rpcUrl := fs.String("rpc-url", "", "BCH RPC URL to query the spending tx and the outputs it spends")
+	_ = fs.Parse(args)
+
+	bchCli, err := bot.NewBchClient(*rpcUrl, nil)
+	if err != nil {
+		log.Fatal("failed to create BCH client: ", err)
+	}
+	if *txHex == "" {
+		tx, err := bchCli.GetTx(*txHash)
+		if err != nil {
+			log.Fatal("failed to get tx: ", err)
+		}
+		*txHex = tx.Hex
+	}
+	tx, err := htlcbch.MsgTxFromBytes(gethcmn.FromHex(*txHex))
+	if err != nil {
+		log.Fatal("invalid tx: ", err)
+	}
+
+	// introspection opcodes of HTLC covenants need the outputs spent by all inputs
+	var prevOuts []*wire.TxOut
+	for _, txIn := range tx.TxIn {
+		prevTx, err := bchCli.GetTx(txIn.PreviousOutPoint.Hash.String())
+		if err != nil {
+			log.Fatal("failed to get prev tx: ", err)
+		}
+		vout := txIn.PreviousOutPoint.Index
+		if int(vout) >= len(prevTx.Vout) {
+			log.Fatalf("prev output not found: %s", txIn.PreviousOutPoint)
+		}
+		value, err := bchutil.NewAmount(prevTx.Vout[vout].Value)
+		if err != nil {
+			log.Fatal(err)
+		}
+		prevOuts = append(prevOuts,
+			wire.NewTxOut(int64(value), gethcmn.FromHex(prevTx.Vout[vout].ScriptPubKey.Hex)))
+	}
+
+	steps, err := htlcbch.DebugSpend(tx, *inIdx, prevOuts)
+	htlcbch.PrintScriptSteps(os.Stdout, steps)
+	if err != nil {
+		fmt.Println("FAILED:", err)
+		os.Exit(1)
+	}
+	fmt.Println("OK")
+}
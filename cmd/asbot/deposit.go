@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// deposit --amount=SATS --recipient-pkh=PKH --sbch-addr=ADDR --expected-price=PRICE --utxo=TXID:VOUT:SATS
+// [--memo=REF] [--expiration-type=blocks|time|timestamp [--expires-at=UNIX]] [--wif=WIF | --sender-pkh=PKH] [--secret=HEX | --hash-lock=HEX | --secret-seed=HEX --secret-index=N] [--send --rpc-url=URL]
+func deposit(args []string) {
+	fs := flag.NewFlagSet("deposit", flag.ExitOnError)
+	net := fs.String("bch-net", "mainnet", "BCH network: mainnet|testnet3|testnet4|chipnet|regtest")
+	amount := fs.Uint64("amount", 0, "value to lock, in sats")
+	recipientPkh := fs.String("recipient-pkh", "", "PKH of the bot, in hex")
+	senderPkh := fs.String("sender-pkh", "", "PKH of the sender, in hex (not needed if --wif is given)")
+	wifStr := fs.String("wif", "", "WIF of the sender, the tx is left unsigned if not given")
+	sbchAddr := fs.String("sbch-addr", "", "sBCH address to receive the swapped coins")
+	expectedPrice := fs.Uint64("expected-price", 0, "expected BCH price, 8 decimals")
+	memo := fs.String("memo", "", "optional reference ID echoed by the bot, e.g. an order ID")
+	hashTypeStr := fs.String("hash-type", "sha256", "sha256|hash160")
+	secretHex := fs.String("secret", "", "32 bytes secret in hex, random if neither it nor --hash-lock is given")
+	hashLockHex := fs.String("hash-lock", "", "hash lock in hex")
+	secretSeed := fs.String("secret-seed", "", "master seed in hex, the secret is derived from it and --secret-index")
+	secretIndex := fs.Uint("secret-index", 0, "index of the swap, use a new one for each deposit")
+	expiration := fs.Uint("expiration", 72, "expiration of the covenant, in BCH blocks (or 512 seconds if time based)")
+	expirationTypeStr := fs.String("expiration-type", "blocks", "blocks|time|timestamp, only blocks are accepted by the bot")
+	expiresAt := fs.Uint("expires-at", 0, "unix timestamp the covenant expires at, replaces --expiration if --expiration-type=timestamp")
+	penaltyBPS := fs.Uint("penalty-bps", 500, "penalty of refund, in basis points")
+	utxos := fs.String("utxo", "", "comma separated UTXOs to spend: txid:vout:sats")
+	minerFeeRate := fs.Uint64("miner-fee-rate", 2, "miner fee rate, in sats/byte")
+	rpcUrl := fs.String("rpc-url", "", "BCH RPC URL to send the tx")
+	send := fs.Bool("send", false, "send the signed tx via --rpc-url")
+	_ = fs.Parse(args)
+
+	if *secretSeed != "" {
+		secret, err := htlcbch.DeriveSecret(gethcmn.FromHex(*secretSeed), uint32(*secretIndex))
+		if err != nil {
+			log.Fatal(err)
+		}
+		*secretHex = hex.EncodeToString(secret)
+		fmt.Println("secret index:", *secretIndex, "(keep it to recover the secret from the seed)")
+	}
+
+	tx, err := makeDepositTx(*net, *amount, *recipientPkh, *senderPkh, *wifStr, *sbchAddr, *expectedPrice, *memo,
+		*hashTypeStr, *secretHex, *hashLockHex, uint16(*expiration), *expirationTypeStr, uint32(*expiresAt), uint16(*penaltyBPS), *utxos, *minerFeeRate)
+	if err != nil {
+		log.Fatal("failed to make deposit tx: ", err)
+	}
+	fmt.Println("deposit tx:", htlcbch.MsgTxToHex(tx))
+	if !*send {
+		return
+	}
+	if *wifStr == "" {
+		log.Fatal("only signed tx can be sent, please provide --wif")
+	}
+
+	bchCli, err := bot.NewBchClient(*rpcUrl, nil)
+	if err != nil {
+		log.Fatal("failed to create BCH client: ", err)
+	}
+	txHash, err := bchCli.SendTx(tx)
+	if err != nil {
+		log.Fatal("failed to send deposit tx: ", err)
+	}
+	fmt.Println("tx hash:", txHash.String())
+}
+
+func makeDepositTx(netName string, amount uint64, recipientPkhHex, senderPkhHex, wifStr, sbchAddr string,
+	expectedPrice uint64, memo, hashTypeStr, secretHex, hashLockHex string, expiration uint16, expirationTypeStr string,
+	expiresAt uint32, penaltyBPS uint16,
+	utxos string, minerFeeRate uint64,
+) (*wire.MsgTx, error) {
+	net, err := htlcbch.GetChainParams(netName)
+	if err != nil {
+		return nil, err
+	}
+	hashType, err := htlcbch.ParseHashType(hashTypeStr)
+	if err != nil {
+		return nil, err
+	}
+	expirationType, err := htlcbch.ParseExpirationType(expirationTypeStr)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		return nil, fmt.Errorf("missing --amount")
+	}
+	if expectedPrice == 0 {
+		return nil, fmt.Errorf("missing --expected-price")
+	}
+	if memo != "" && !htlcbch.IsValidMemo(memo) {
+		return nil, fmt.Errorf("invalid --memo: at most %d printable ASCII chars", htlcbch.MaxMemoLen)
+	}
+	if !gethcmn.IsHexAddress(sbchAddr) {
+		return nil, fmt.Errorf("invalid --sbch-addr: %s", sbchAddr)
+	}
+	recipientPkh := gethcmn.FromHex(recipientPkhHex)
+	if len(recipientPkh) != 20 {
+		return nil, fmt.Errorf("invalid --recipient-pkh: %s", recipientPkhHex)
+	}
+
+	var privKey *bchec.PrivateKey
+	senderPkh := gethcmn.FromHex(senderPkhHex)
+	if wifStr != "" {
+		wif, err := bchutil.DecodeWIF(wifStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --wif: %w", err)
+		}
+		wif.CompressPubKey = true
+		privKey = wif.PrivKey
+		senderPkh = bchutil.Hash160(wif.SerializePubKey())
+	}
+	if len(senderPkh) != 20 {
+		return nil, fmt.Errorf("invalid --sender-pkh: %s", senderPkhHex)
+	}
+
+	hashLock := gethcmn.FromHex(hashLockHex)
+	if hashLockHex == "" {
+		secret := gethcmn.FromHex(secretHex)
+		if secretHex == "" {
+			secret = make([]byte, 32)
+			if _, err = rand.Read(secret); err != nil {
+				return nil, err
+			}
+			fmt.Println("secret   :", hex.EncodeToString(secret), "(keep it to unlock the coins)")
+		}
+		if len(secret) != 32 {
+			return nil, fmt.Errorf("invalid --secret: %s", secretHex)
+		}
+		hashLock = hashType.HashSecret(secret)
+	}
+	if len(hashLock) != hashType.HashLockLen() {
+		return nil, fmt.Errorf("invalid --hash-lock: %s", hashLockHex)
+	}
+
+	inputs, err := parseUTXOs(utxos)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := net.NewCovenantWithHashType(senderPkh, recipientPkh, hashLock, hashType, expiration, penaltyBPS)
+	if err != nil {
+		return nil, err
+	}
+	if expirationType == htlcbch.ExpirationTypeTimestamp {
+		c, err = c.WithExpiresAt(expiresAt)
+	} else {
+		c, err = c.WithExpirationType(expirationType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cP2SH, err := c.GetRedeemScriptHash()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("sender pkh:", hex.EncodeToString(senderPkh))
+	fmt.Println("hash lock:", hex.EncodeToString(hashLock))
+	fmt.Println("htlc p2sh:", hex.EncodeToString(cP2SH))
+
+	return c.MakeDepositTx(privKey, inputs, int64(amount),
+		gethcmn.HexToAddress(sbchAddr).Bytes(), expectedPrice, memo, minerFeeRate)
+}
+
+// txid:vout:sats,txid:vout:sats,...
+func parseUTXOs(s string) ([]htlcbch.InputInfo, error) {
+	var inputs []htlcbch.InputInfo
+	for _, utxo := range splitList(s) {
+		ss := strings.Split(strings.TrimSpace(utxo), ":")
+		if len(ss) != 3 {
+			return nil, fmt.Errorf("invalid utxo: %s", utxo)
+		}
+		txid := gethcmn.FromHex(ss[0])
+		if len(txid) != 32 {
+			return nil, fmt.Errorf("invalid utxo txid: %s", ss[0])
+		}
+		vout, err := strconv.ParseUint(ss[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid utxo vout: %s", ss[1])
+		}
+		val, err := strconv.ParseUint(ss[2], 10, 63)
+		if err != nil {
+			return nil, fmt.Errorf("invalid utxo value: %s", ss[2])
+		}
+		inputs = append(inputs, htlcbch.InputInfo{TxID: txid, Vout: uint32(vout), Amount: int64(val)})
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("missing --utxo")
+	}
+	return inputs, nil
+}
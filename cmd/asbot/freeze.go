@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+)
+
+// freeze|thaw [--reason=TEXT] [bot options], takes effect on the running bot within a round
+func setFrozen(name string, frozen bool, args []string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	registerFlags(fs)
+	reason := fs.String("reason", "", "why bot is "+name+"ed, kept in DB")
+	_ = fs.Parse(args)
+
+	cfg := makeConfig(fs)
+	if err := bot.SetFrozen(cfg.DbFile, frozen, *reason); err != nil {
+		log.Fatal("failed to ", name, ": ", err)
+	}
+	if frozen {
+		fmt.Println("frozen, nothing will be signed or broadcast until thawed")
+	} else {
+		fmt.Println("thawed")
+	}
+}
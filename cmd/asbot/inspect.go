@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// inspect --deposit-tx=TXID --rpc-url=URL
+func inspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	net := fs.String("bch-net", "mainnet", "BCH network: mainnet|testnet3|testnet4|chipnet|regtest")
+	depositTxHash := fs.String("deposit-tx", "", "hash of the deposit tx")
+	rpcUrl := fs.String("rpc-url", "", "BCH RPC URL to query the deposit tx and its UTXO")
+	_ = fs.Parse(args)
+
+	params, err := htlcbch.GetChainParams(*net)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bchCli, err := bot.NewBchClient(*rpcUrl, nil)
+	if err != nil {
+		log.Fatal("failed to create BCH client: ", err)
+	}
+	info, err := bot.InspectCovenant(bchCli, params, *depositTxHash)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bz, _ := json.MarshalIndent(info, "", "  ")
+	fmt.Println(string(bz))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// redeem --deposit-tx=TXID (--secret=HEX | --secret-seed=HEX [--secret-index=N]) [--to-address=ADDR] --rpc-url=URL [--send]
+func redeem(args []string) {
+	fs := flag.NewFlagSet("redeem", flag.ExitOnError)
+	net := fs.String("bch-net", "mainnet", "BCH network: mainnet|testnet3|testnet4|chipnet|regtest")
+	depositTxHash := fs.String("deposit-tx", "", "hash of the deposit tx")
+	secretHex := fs.String("secret", "", "32 bytes secret in hex")
+	secretSeed := fs.String("secret-seed", "", "master seed in hex to derive the secret if --secret is not given")
+	secretIndex := fs.Int64("secret-index", -1, "index of the swap, searched if not given")
+	maxSecretIndex := fs.Uint("max-secret-index", 100000, "how many indices to search")
+	toAddr := fs.String("to-address", "", "the covenant only pays to its recipient, it is checked if given")
+	minerFeeRate := fs.Uint64("miner-fee-rate", 2, "miner fee rate, in sats/byte")
+	rpcUrl := fs.String("rpc-url", "", "BCH RPC URL to query the deposit tx and send the redeem tx")
+	send := fs.Bool("send", false, "send the redeem tx")
+	_ = fs.Parse(args)
+
+	params, err := htlcbch.GetChainParams(*net)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bchCli, err := bot.NewBchClient(*rpcUrl, nil)
+	if err != nil {
+		log.Fatal("failed to create BCH client: ", err)
+	}
+	tx, deposit, err := loadDeposit(bchCli, params, *depositTxHash)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *toAddr != "" {
+		addr, err := params.DecodeP2PKHAddress(*toAddr)
+		if err != nil {
+			log.Fatal("invalid --to-address: ", err)
+		}
+		if !bytes.Equal(addr.ScriptAddress(), deposit.RecipientPkh) {
+			log.Fatalf("the covenant can only be redeemed to its recipient: %s",
+				hex.EncodeToString(deposit.RecipientPkh))
+		}
+	}
+
+	secret := gethcmn.FromHex(*secretHex)
+	if *secretHex == "" && *secretSeed != "" {
+		secret, err = deriveSecret(gethcmn.FromHex(*secretSeed), *secretIndex, uint32(*maxSecretIndex), deposit)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(secret) != 32 || !bytes.Equal(deposit.HashType.HashSecret(secret), deposit.HashLock) {
+		log.Fatal("the secret does not match the hash lock: ", hex.EncodeToString(deposit.HashLock))
+	}
+
+	c, err := params.NewCovenantOfDeposit(deposit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	redeemTx, err := c.MakeUnlockTx(gethcmn.FromHex(tx.Txid), deposit.Vout, int64(deposit.Value), *minerFeeRate, secret)
+	if err != nil {
+		log.Fatal("failed to make redeem tx: ", err)
+	}
+	fmt.Println("redeem tx:", htlcbch.MsgTxToHex(redeemTx))
+	if !*send {
+		return
+	}
+
+	txHash, err := bchCli.SendTx(redeemTx)
+	if err != nil {
+		log.Fatal("failed to send redeem tx: ", err)
+	}
+	fmt.Println("tx hash:", txHash.String())
+}
+
+// query the deposit tx and reconstruct the covenant info from its OP_RETURN
+func loadDeposit(bchCli *bot.BchClient, params *htlcbch.ChainParams, txHash string,
+) (*btcjson.TxRawResult, *htlcbch.HtlcLockInfo, error) {
+	tx, err := bchCli.GetTx(txHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get deposit tx: %w", err)
+	}
+	deposit := params.GetHtlcLockInfo(*tx)
+	if deposit == nil {
+		return nil, nil, fmt.Errorf("not a valid deposit tx: %s", txHash)
+	}
+	fmt.Println("sender pkh   :", hex.EncodeToString(deposit.SenderPkh))
+	fmt.Println("recipient pkh:", hex.EncodeToString(deposit.RecipientPkh))
+	fmt.Println("hash lock    :", hex.EncodeToString(deposit.HashLock))
+	if deposit.ExpirationType == htlcbch.ExpirationTypeTimestamp {
+		fmt.Println("expires at   :", time.Unix(int64(deposit.ExpiresAt), 0).UTC())
+	} else {
+		fmt.Println("expiration   :", deposit.Expiration, deposit.ExpirationType)
+	}
+	fmt.Println("penalty bps  :", deposit.PenaltyBPS)
+	fmt.Println("value        :", deposit.Value)
+	return tx, deposit, nil
+}
+
+// derive the secret of the deposit from the seed, search the index if it is lost
+func deriveSecret(seed []byte, index int64, maxIndex uint32, deposit *htlcbch.HtlcLockInfo) ([]byte, error) {
+	if index >= 0 {
+		return htlcbch.DeriveSecret(seed, uint32(index))
+	}
+	idx, secret, err := htlcbch.FindSecretIndex(seed, deposit.HashLock, deposit.HashType, maxIndex)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("secret index :", idx)
+	return secret, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// refund --deposit-tx=TXID --rpc-url=URL [--send]
+func refund(args []string) {
+	fs := flag.NewFlagSet("refund", flag.ExitOnError)
+	net := fs.String("bch-net", "mainnet", "BCH network: mainnet|testnet3|testnet4|chipnet|regtest")
+	depositTxHash := fs.String("deposit-tx", "", "hash of the deposit tx")
+	minerFeeRate := fs.Uint64("miner-fee-rate", 2, "miner fee rate, in sats/byte")
+	rpcUrl := fs.String("rpc-url", "", "BCH RPC URL to query the deposit tx and send the refund tx")
+	send := fs.Bool("send", false, "send the refund tx")
+	_ = fs.Parse(args)
+
+	params, err := htlcbch.GetChainParams(*net)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bchCli, err := bot.NewBchClient(*rpcUrl, nil)
+	if err != nil {
+		log.Fatal("failed to create BCH client: ", err)
+	}
+	tx, deposit, err := loadDeposit(bchCli, params, *depositTxHash)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// same as the bot, see refundLockedBCH()
+	if n := deposit.RefundableIn(tx.Confirmations, tx.Blocktime, time.Now().Unix()); n > 0 {
+		log.Fatalf("the covenant is not expired yet, confirmations: %d, refundable after %d more %s",
+			tx.Confirmations, n, deposit.ExpirationType.Unit())
+	}
+
+	c, err := params.NewCovenantOfDeposit(deposit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	refundTx, err := c.MakeRefundTx(gethcmn.FromHex(tx.Txid), deposit.Vout, int64(deposit.Value), *minerFeeRate)
+	if err != nil {
+		log.Fatal("failed to make refund tx: ", err)
+	}
+	fmt.Println("refund tx:", htlcbch.MsgTxToHex(refundTx))
+	for i, out := range refundTx.TxOut {
+		fmt.Printf("output#%d : %d sats\n", i, out.Value)
+	}
+	if !*send {
+		return
+	}
+
+	txHash, err := bchCli.SendTx(refundTx)
+	if err != nil {
+		log.Fatal("failed to send refund tx: ", err)
+	}
+	fmt.Println("tx hash:", txHash.String())
+}
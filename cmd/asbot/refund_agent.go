@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// refund-agent --deposit-tx=TXID[,TXID...] --rpc-url=URL [--interval=DURATION]
+func refundAgent(args []string) {
+	fs := flag.NewFlagSet("refund-agent", flag.ExitOnError)
+	net := fs.String("bch-net", "mainnet", "BCH network: mainnet|testnet3|testnet4|chipnet|regtest")
+	depositTxHashes := fs.String("deposit-tx", "", "hashes of the deposit txs, separated by commas")
+	minerFeeRate := fs.Uint64("miner-fee-rate", 2, "miner fee rate, in sats/byte")
+	rpcUrl := fs.String("rpc-url", "", "BCH RPC URL to follow the deposits and send the refund txs")
+	interval := fs.Duration("interval", time.Minute, "how often to check the deposits")
+	_ = fs.Parse(args)
+
+	params, err := htlcbch.GetChainParams(*net)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *depositTxHashes == "" {
+		log.Fatal("missing --deposit-tx")
+	}
+	bchCli, err := bot.NewBchClient(*rpcUrl, nil)
+	if err != nil {
+		log.Fatal("failed to create BCH client: ", err)
+	}
+	agent := bot.NewRefundAgent(bchCli, params, strings.Split(*depositTxHashes, ","), *minerFeeRate)
+	agent.Run(*interval)
+	log.Info("all deposits are spent")
+}
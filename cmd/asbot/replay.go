@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+)
+
+// replay --blocks=DIR [--update] [bot options]
+func replay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	registerFlags(fs)
+	blocksDir := fs.String("blocks", "", "dir of recorded blocks")
+	update := fs.Bool("update", false, "write resulting swap states to expected.json")
+	_ = fs.Parse(args)
+
+	if *blocksDir == "" {
+		log.Fatal("missing --blocks")
+	}
+	result, err := bot.Replay(makeConfig(fs), *blocksDir)
+	if err != nil {
+		log.Fatal("failed to replay: ", err)
+	}
+	bz, _ := json.MarshalIndent(result.States, "", "  ")
+	fmt.Printf("replayed %d BCH blocks and %d sBCH blocks, swap states:\n%s\n",
+		result.BchBlocks, result.SbchBlocks, bz)
+
+	if *update {
+		file := filepath.Join(*blocksDir, bot.ReplayExpectedFile)
+		if err = os.WriteFile(file, append(bz, '\n'), 0644); err != nil {
+			log.Fatal("failed to write expected states: ", err)
+		}
+		fmt.Println("expected states are written to", file)
+		return
+	}
+	if len(result.Mismatches) > 0 {
+		fmt.Println("mismatches:", len(result.Mismatches))
+		for _, mismatch := range result.Mismatches {
+			fmt.Println(" ", mismatch)
+		}
+		os.Exit(1)
+	}
+}
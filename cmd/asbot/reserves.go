@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+)
+
+// reserves [--out=FILE] [bot options]
+// reserves --verify=FILE
+func reserves(args []string) {
+	fs := flag.NewFlagSet("reserves", flag.ExitOnError)
+	registerFlags(fs)
+	outFile := fs.String("out", "", "file to write the signed report to, printed if not given")
+	verifyFile := fs.String("verify", "", "verify the signatures of a report instead of making one")
+	_ = fs.Parse(args)
+
+	if *verifyFile != "" {
+		verifyReserves(*verifyFile)
+		return
+	}
+
+	cfg := makeConfig(fs)
+	if cfg.BchPrivKeyWIF == "" || cfg.SbchPrivKeyHex == "" {
+		cfg.BchPrivKeyWIF, cfg.SbchPrivKeyHex = readKeys(cfg.SlaveMode)
+	}
+	_bot, err := bot.NewBot(cfg)
+	if err != nil {
+		log.Fatal("failed to create bot: ", err)
+	}
+	report, err := _bot.MakeReservesReport()
+	if err != nil {
+		log.Fatal("failed to make report: ", err)
+	}
+	bz, _ := json.MarshalIndent(report, "", "  ")
+	if *outFile == "" {
+		fmt.Println(string(bz))
+	} else if err = os.WriteFile(*outFile, append(bz, '\n'), 0644); err != nil {
+		log.Fatal("failed to write report: ", err)
+	}
+	printReservesSummary(report)
+}
+
+func verifyReserves(file string) {
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report := &bot.ReservesReport{}
+	if err = json.Unmarshal(bz, report); err != nil {
+		log.Fatal("failed to parse report: ", err)
+	}
+	if err = bot.VerifyReservesReport(report); err != nil {
+		log.Fatal("invalid report: ", err)
+	}
+	fmt.Println("signatures are valid")
+	printReservesSummary(report)
+}
+
+func printReservesSummary(report *bot.ReservesReport) {
+	fmt.Printf("BCH : %d sats in %d UTXOs of %s, %d pending, %d locked\n",
+		report.BchBalance, len(report.BchUtxos), report.BchAddr,
+		report.Liabilities.PendingBch, report.Liabilities.LockedBch)
+	fmt.Printf("sBCH: %d sats of %s, %d pending, %d locked\n",
+		report.SbchBalance, report.SbchAddr,
+		report.Liabilities.PendingSbch, report.Liabilities.LockedSbch)
+	fmt.Println("solvent:", report.IsSolvent())
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"time"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smartbch/atomic-swap-bot/bot"
+	"github.com/smartbch/atomic-swap-bot/htlcbch"
+)
+
+// watch --user-pkh=PKH [--user-evm-addr=ADDR] --bot-pkh=PKH --bot-evm-addr=ADDR --from-bch=H --from-sbch=H
+// --bch-rpc-url=URL --sbch-rpc-url=URL --sbch-htlc-addr=ADDR [--auto-refund] [--webhook-url=URL]
+func watch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	net := fs.String("bch-net", "mainnet", "BCH network: mainnet|testnet3|testnet4|chipnet|regtest")
+	bchRpcUrl := fs.String("bch-rpc-url", "", "BCH RPC URL")
+	sbchRpcUrl := fs.String("sbch-rpc-url", "", "sBCH RPC URL")
+	sbchHtlcAddr := fs.String("sbch-htlc-addr", "", "address of the sBCH HTLC contract")
+	userPkh := fs.String("user-pkh", "", "your BCH PKH, in hex")
+	userEvmAddr := fs.String("user-evm-addr", "", "your sBCH address (optional)")
+	botPkh := fs.String("bot-pkh", "", "BCH PKH of the bot, in hex")
+	botEvmAddr := fs.String("bot-evm-addr", "", "sBCH address of the bot")
+	fromBch := fs.Int64("from-bch", 0, "BCH height to start scanning from")
+	fromSbch := fs.Uint64("from-sbch", 0, "sBCH height to start scanning from")
+	interval := fs.Duration("interval", time.Minute, "how often to check the chains")
+	autoRefund := fs.Bool("auto-refund", false, "send refund txs of your expired BCH deposits")
+	refundFeeRate := fs.Uint64("refund-fee-rate", 2, "miner fee rate of refund txs, in sats/byte")
+	webhookUrl := fs.String("webhook-url", "", "alerts are POSTed to this URL as JSON (optional)")
+	_ = fs.Parse(args)
+
+	params, err := htlcbch.GetChainParams(*net)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg := &bot.UserWatchConfig{
+		BchNet:        params,
+		UserPkh:       gethcmn.FromHex(*userPkh),
+		BotPkh:        gethcmn.FromHex(*botPkh),
+		BotEvmAddr:    gethcmn.HexToAddress(*botEvmAddr),
+		AutoRefund:    *autoRefund,
+		RefundFeeRate: *refundFeeRate,
+	}
+	if len(cfg.UserPkh) != 20 {
+		log.Fatal("invalid --user-pkh: ", *userPkh)
+	}
+	if len(cfg.BotPkh) != 20 {
+		log.Fatal("invalid --bot-pkh: ", *botPkh)
+	}
+	if !gethcmn.IsHexAddress(*botEvmAddr) {
+		log.Fatal("invalid --bot-evm-addr: ", *botEvmAddr)
+	}
+	if *userEvmAddr != "" {
+		if !gethcmn.IsHexAddress(*userEvmAddr) {
+			log.Fatal("invalid --user-evm-addr: ", *userEvmAddr)
+		}
+		cfg.UserEvmAddr = gethcmn.HexToAddress(*userEvmAddr)
+	}
+	if *fromBch <= 0 || *fromSbch == 0 {
+		log.Fatal("missing --from-bch or --from-sbch")
+	}
+
+	watcher, err := bot.NewUserWatcher(cfg, *bchRpcUrl, *sbchRpcUrl, gethcmn.HexToAddress(*sbchHtlcAddr),
+		func(swap *bot.UserSwap, alert string) {
+			if *webhookUrl != "" {
+				postWatchAlert(*webhookUrl, swap, alert)
+			}
+		})
+	if err != nil {
+		log.Fatal(err)
+	}
+	watcher.Run(*fromBch, *fromSbch, *interval)
+}
+
+func postWatchAlert(url string, swap *bot.UserSwap, alert string) {
+	bz, _ := json.Marshal(map[string]any{"alert": alert, "swap": swap})
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(bz))
+	if err != nil {
+		log.Error("failed to post alert: ", err)
+		return
+	}
+	_ = resp.Body.Close()
+}
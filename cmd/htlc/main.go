@@ -1,7 +1,6 @@
 package main
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"os"
@@ -9,7 +8,6 @@ import (
 	"strings"
 
 	gethcmn "github.com/ethereum/go-ethereum/common"
-	"github.com/gcash/bchd/chaincfg"
 	"github.com/gcash/bchutil"
 	"github.com/urfave/cli/v2"
 
@@ -18,35 +16,42 @@ import (
 )
 
 const (
+	flagNameNet          = "net"
 	flagNameRpcUrl       = "rpc-url"
 	flagNameWIF          = "wif"
 	flagNameFromAddr     = "from-addr"
 	flagNameToAddr       = "to-addr"
 	flagNameSecret       = "secret"
+	flagNameHashType     = "hash-type"
 	flagNameExpiration   = "expiration"
 	flagNamePenaltyBPS   = "penalty-bps"
 	flagNameUTXO         = "utxo"
 	flagNameAmt          = "amt"
 	flagNameMinerFeeRate = "miner-fee-rate"
 	flagNameDryRun       = "dry-run"
+	flagNameBatchable    = "batchable"
 )
 
 var (
+	flagNet          = &cli.StringFlag{Name: flagNameNet, Required: false, Value: "testnet3", Usage: "mainnet|testnet3|testnet4|chipnet|regtest"}
 	flagRpcUrl       = &cli.StringFlag{Name: flagNameRpcUrl, Required: false}
 	flagWIF          = &cli.StringFlag{Name: flagNameWIF, Required: true}
 	flagFromAddr     = &cli.StringFlag{Name: flagNameFromAddr, Required: false}
 	flagToAddr       = &cli.StringFlag{Name: flagNameToAddr, Required: false}
 	flagSecret       = &cli.StringFlag{Name: flagNameSecret, Required: false, DefaultText: "123"}
+	flagHashType     = &cli.StringFlag{Name: flagNameHashType, Required: false, Value: "sha256", Usage: "sha256|hash160"}
 	flagExpiration   = &cli.Uint64Flag{Name: flagNameExpiration, Required: false, DefaultText: "36"}
 	flagPenaltyBPS   = &cli.Uint64Flag{Name: flagNamePenaltyBPS, Required: false, DefaultText: "500"}
 	flagUTXO         = &cli.StringFlag{Name: flagNameUTXO, Required: true, Usage: "txid:vout:val"}
 	flagAmt          = &cli.Uint64Flag{Name: flagNameAmt, Required: true}
 	flagMinerFeeRate = &cli.Uint64Flag{Name: flagNameMinerFeeRate, Required: false, DefaultText: "2"}
 	flagDryRun       = &cli.BoolFlag{Name: flagNameDryRun, Required: false, DefaultText: "true"}
+	flagBatchable    = &cli.BoolFlag{Name: flagNameBatchable, Required: false, Usage: "the batchable variant of the covenant, as quoted by bots with --bch-batch-receipts"}
 )
 
 func main() {
 	app := &cli.App{
+		Flags: []cli.Flag{flagNet},
 		Commands: []*cli.Command{
 			cmdLock(),
 			cmdUnlock(),
@@ -62,28 +67,39 @@ func cmdLock() *cli.Command {
 	return &cli.Command{
 		Name: "lock",
 		Flags: []cli.Flag{
-			flagWIF, flagToAddr, flagSecret, flagExpiration, flagPenaltyBPS,
-			flagUTXO, flagAmt, flagMinerFeeRate, flagDryRun, flagRpcUrl,
+			flagWIF, flagToAddr, flagSecret, flagHashType, flagExpiration, flagPenaltyBPS,
+			flagUTXO, flagAmt, flagMinerFeeRate, flagDryRun, flagRpcUrl, flagBatchable,
 		},
 		Action: func(ctx *cli.Context) error {
-			wif, pkh, addr, err := decodeWIF(ctx.String(flagNameWIF))
+			net, err := htlcbch.GetChainParams(ctx.String(flagNameNet))
 			if err != nil {
 				return err
 			}
-			_, toPkh, err := decodeAddr(ctx.String(flagNameToAddr))
+			hashType, err := htlcbch.ParseHashType(ctx.String(flagNameHashType))
 			if err != nil {
 				return err
 			}
-			_, hashLock := secretToHashLock(ctx.String(flagNameSecret))
+			wif, pkh, addr, err := decodeWIF(ctx.String(flagNameWIF), net)
+			if err != nil {
+				return err
+			}
+			_, toPkh, err := decodeAddr(ctx.String(flagNameToAddr), net)
+			if err != nil {
+				return err
+			}
+			_, hashLock := secretToHashLock(ctx.String(flagNameSecret), hashType)
 
-			c, err := htlcbch.NewTestnet3Covenant(
-				pkh, toPkh, hashLock,
+			c, err := net.NewCovenantWithHashType(
+				pkh, toPkh, hashLock, hashType,
 				uint16(ctx.Uint64(flagNameExpiration)),
 				uint16(ctx.Uint64(flagNamePenaltyBPS)),
 			)
 			if err != nil {
 				return err
 			}
+			if ctx.Bool(flagNameBatchable) {
+				c = c.WithBatchable()
+			}
 
 			cP2SH, err := c.GetRedeemScriptHash()
 			if err != nil {
@@ -133,28 +149,39 @@ func cmdUnlock() *cli.Command {
 	return &cli.Command{
 		Name: "unlock",
 		Flags: []cli.Flag{
-			flagWIF, flagFromAddr, flagSecret, flagExpiration, flagPenaltyBPS,
-			flagUTXO, flagMinerFeeRate, flagDryRun, flagRpcUrl,
+			flagWIF, flagFromAddr, flagSecret, flagHashType, flagExpiration, flagPenaltyBPS,
+			flagUTXO, flagMinerFeeRate, flagDryRun, flagRpcUrl, flagBatchable,
 		},
 		Action: func(ctx *cli.Context) error {
-			_, pkh, addr, err := decodeWIF(ctx.String(flagNameWIF))
+			net, err := htlcbch.GetChainParams(ctx.String(flagNameNet))
+			if err != nil {
+				return err
+			}
+			hashType, err := htlcbch.ParseHashType(ctx.String(flagNameHashType))
+			if err != nil {
+				return err
+			}
+			_, pkh, addr, err := decodeWIF(ctx.String(flagNameWIF), net)
 			if err != nil {
 				return err
 			}
-			_, fromPkh, err := decodeAddr(ctx.String(flagNameFromAddr))
+			_, fromPkh, err := decodeAddr(ctx.String(flagNameFromAddr), net)
 			if err != nil {
 				return err
 			}
-			secret, hashLock := secretToHashLock(ctx.String(flagNameSecret))
+			secret, hashLock := secretToHashLock(ctx.String(flagNameSecret), hashType)
 
-			c, err := htlcbch.NewTestnet3Covenant(
-				fromPkh, pkh, hashLock,
+			c, err := net.NewCovenantWithHashType(
+				fromPkh, pkh, hashLock, hashType,
 				uint16(ctx.Uint64(flagNameExpiration)),
 				uint16(ctx.Uint64(flagNamePenaltyBPS)),
 			)
 			if err != nil {
 				return err
 			}
+			if ctx.Bool(flagNameBatchable) {
+				c = c.WithBatchable()
+			}
 
 			cP2SH, err := c.GetRedeemScriptHash()
 			if err != nil {
@@ -202,28 +229,39 @@ func cmdRefund() *cli.Command {
 	return &cli.Command{
 		Name: "refund",
 		Flags: []cli.Flag{
-			flagWIF, flagToAddr, flagSecret, flagExpiration, flagPenaltyBPS,
-			flagUTXO, flagMinerFeeRate, flagDryRun, flagRpcUrl,
+			flagWIF, flagToAddr, flagSecret, flagHashType, flagExpiration, flagPenaltyBPS,
+			flagUTXO, flagMinerFeeRate, flagDryRun, flagRpcUrl, flagBatchable,
 		},
 		Action: func(ctx *cli.Context) error {
-			_, pkh, addr, err := decodeWIF(ctx.String(flagNameWIF))
+			net, err := htlcbch.GetChainParams(ctx.String(flagNameNet))
+			if err != nil {
+				return err
+			}
+			hashType, err := htlcbch.ParseHashType(ctx.String(flagNameHashType))
 			if err != nil {
 				return err
 			}
-			_, toPkh, err := decodeAddr(ctx.String(flagNameToAddr))
+			_, pkh, addr, err := decodeWIF(ctx.String(flagNameWIF), net)
 			if err != nil {
 				return err
 			}
-			_, hashLock := secretToHashLock(ctx.String(flagNameSecret))
+			_, toPkh, err := decodeAddr(ctx.String(flagNameToAddr), net)
+			if err != nil {
+				return err
+			}
+			_, hashLock := secretToHashLock(ctx.String(flagNameSecret), hashType)
 
-			c, err := htlcbch.NewTestnet3Covenant(
-				pkh, toPkh, hashLock,
+			c, err := net.NewCovenantWithHashType(
+				pkh, toPkh, hashLock, hashType,
 				uint16(ctx.Uint64(flagNameExpiration)),
 				uint16(ctx.Uint64(flagNamePenaltyBPS)),
 			)
 			if err != nil {
 				return err
 			}
+			if ctx.Bool(flagNameBatchable) {
+				c = c.WithBatchable()
+			}
 
 			cP2SH, err := c.GetRedeemScriptHash()
 			if err != nil {
@@ -267,7 +305,8 @@ func cmdRefund() *cli.Command {
 	}
 }
 
-func decodeWIF(wifStr string) (wif *bchutil.WIF, pkh []byte, addr *bchutil.AddressPubKeyHash, err error) {
+func decodeWIF(wifStr string, net *htlcbch.ChainParams,
+) (wif *bchutil.WIF, pkh []byte, addr *bchutil.AddressPubKeyHash, err error) {
 	wif, err = bchutil.DecodeWIF(wifStr)
 	if err != nil {
 		return
@@ -275,28 +314,23 @@ func decodeWIF(wifStr string) (wif *bchutil.WIF, pkh []byte, addr *bchutil.Addre
 
 	wif.CompressPubKey = true
 	pkh = bchutil.Hash160(wif.SerializePubKey())
-	addr, err = bchutil.NewAddressPubKeyHash(pkh, &chaincfg.TestNet3Params)
+	addr, err = net.NewP2PKHAddress(pkh)
 	return
 }
 
-func decodeAddr(addrStr string) (bchutil.Address, []byte, error) {
-	addr, err := bchutil.DecodeAddress(addrStr, &chaincfg.TestNet3Params)
+func decodeAddr(addrStr string, net *htlcbch.ChainParams) (bchutil.Address, []byte, error) {
+	addrPkh, err := net.DecodeP2PKHAddress(addrStr)
 	if err != nil {
 		return nil, nil, err
 	}
-	addrPkh, ok := addr.(*bchutil.AddressPubKeyHash)
-	if !ok {
-		return nil, nil, fmt.Errorf("not P2PKH addr: %s", addrStr)
-	}
 	pkh := addrPkh.Hash160()
-	return addr, (*pkh)[:], nil
+	return addrPkh, (*pkh)[:], nil
 }
 
-func secretToHashLock(secret string) ([]byte, []byte) {
+func secretToHashLock(secret string, hashType htlcbch.HashType) ([]byte, []byte) {
 	var secret32 [32]byte
 	copy(secret32[:], secret)
-	hashLock := sha256.Sum256(secret32[:])
-	return secret32[:], hashLock[:]
+	return secret32[:], hashType.HashSecret(secret32[:])
 }
 
 func parseUTXO(utxo string) (txid []byte, vout uint64, val uint64, err error) {
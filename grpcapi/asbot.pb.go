@@ -0,0 +1,2927 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.31.0
+// 	protoc        (unknown)
+// source: grpcapi/asbot.proto
+
+// gRPC API of atomic swap bot, it mirrors the HTTP API.
+// Generate Go code with:
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative grpcapi/asbot.proto
+
+package grpcapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PingReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *PingReq) Reset() {
+	*x = PingReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PingReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingReq) ProtoMessage() {}
+
+func (x *PingReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingReq.ProtoReflect.Descriptor instead.
+func (*PingReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{0}
+}
+
+type PingResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Msg string `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"` // "pong"
+}
+
+func (x *PingResp) Reset() {
+	*x = PingResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PingResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResp) ProtoMessage() {}
+
+func (x *PingResp) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResp.ProtoReflect.Descriptor instead.
+func (*PingResp) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PingResp) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+type GetInfoReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetInfoReq) Reset() {
+	*x = GetInfoReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetInfoReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInfoReq) ProtoMessage() {}
+
+func (x *GetInfoReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInfoReq.ProtoReflect.Descriptor instead.
+func (*GetInfoReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{2}
+}
+
+type Info struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FreeBch          float64     `protobuf:"fixed64,1,opt,name=free_bch,json=freeBch,proto3" json:"free_bch,omitempty"`
+	FreeSbch         float64     `protobuf:"fixed64,2,opt,name=free_sbch,json=freeSbch,proto3" json:"free_sbch,omitempty"`
+	LockedBch        float64     `protobuf:"fixed64,3,opt,name=locked_bch,json=lockedBch,proto3" json:"locked_bch,omitempty"`
+	LockedSbch       float64     `protobuf:"fixed64,4,opt,name=locked_sbch,json=lockedSbch,proto3" json:"locked_sbch,omitempty"`
+	ToBeUnlockedBch  float64     `protobuf:"fixed64,5,opt,name=to_be_unlocked_bch,json=toBeUnlockedBch,proto3" json:"to_be_unlocked_bch,omitempty"`
+	ToBeUnlockedSbch float64     `protobuf:"fixed64,6,opt,name=to_be_unlocked_sbch,json=toBeUnlockedSbch,proto3" json:"to_be_unlocked_sbch,omitempty"`
+	S2BSwaps         []*SwapInfo `protobuf:"bytes,7,rep,name=s2b_swaps,json=s2bSwaps,proto3" json:"s2b_swaps,omitempty"`
+	B2SSwaps         []*SwapInfo `protobuf:"bytes,8,rep,name=b2s_swaps,json=b2sSwaps,proto3" json:"b2s_swaps,omitempty"`
+}
+
+func (x *Info) Reset() {
+	*x = Info{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Info) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Info) ProtoMessage() {}
+
+func (x *Info) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Info.ProtoReflect.Descriptor instead.
+func (*Info) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Info) GetFreeBch() float64 {
+	if x != nil {
+		return x.FreeBch
+	}
+	return 0
+}
+
+func (x *Info) GetFreeSbch() float64 {
+	if x != nil {
+		return x.FreeSbch
+	}
+	return 0
+}
+
+func (x *Info) GetLockedBch() float64 {
+	if x != nil {
+		return x.LockedBch
+	}
+	return 0
+}
+
+func (x *Info) GetLockedSbch() float64 {
+	if x != nil {
+		return x.LockedSbch
+	}
+	return 0
+}
+
+func (x *Info) GetToBeUnlockedBch() float64 {
+	if x != nil {
+		return x.ToBeUnlockedBch
+	}
+	return 0
+}
+
+func (x *Info) GetToBeUnlockedSbch() float64 {
+	if x != nil {
+		return x.ToBeUnlockedSbch
+	}
+	return 0
+}
+
+func (x *Info) GetS2BSwaps() []*SwapInfo {
+	if x != nil {
+		return x.S2BSwaps
+	}
+	return nil
+}
+
+func (x *Info) GetB2SSwaps() []*SwapInfo {
+	if x != nil {
+		return x.B2SSwaps
+	}
+	return nil
+}
+
+type SwapInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HashLock string  `protobuf:"bytes,1,opt,name=hash_lock,json=hashLock,proto3" json:"hash_lock,omitempty"`
+	Value    float64 `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	Status   string  `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Token    string  `protobuf:"bytes,4,opt,name=token,proto3" json:"token,omitempty"` // SEP20 token symbol, empty means sBCH
+}
+
+func (x *SwapInfo) Reset() {
+	*x = SwapInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SwapInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwapInfo) ProtoMessage() {}
+
+func (x *SwapInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwapInfo.ProtoReflect.Descriptor instead.
+func (*SwapInfo) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SwapInfo) GetHashLock() string {
+	if x != nil {
+		return x.HashLock
+	}
+	return ""
+}
+
+func (x *SwapInfo) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *SwapInfo) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SwapInfo) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type GetReceivePkhReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetReceivePkhReq) Reset() {
+	*x = GetReceivePkhReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetReceivePkhReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReceivePkhReq) ProtoMessage() {}
+
+func (x *GetReceivePkhReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReceivePkhReq.ProtoReflect.Descriptor instead.
+func (*GetReceivePkhReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{5}
+}
+
+type ReceivePkh struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pkh string `protobuf:"bytes,1,opt,name=pkh,proto3" json:"pkh,omitempty"`
+	Idx int64  `protobuf:"varint,2,opt,name=idx,proto3" json:"idx,omitempty"` // -1 means the static PKH
+}
+
+func (x *ReceivePkh) Reset() {
+	*x = ReceivePkh{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReceivePkh) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceivePkh) ProtoMessage() {}
+
+func (x *ReceivePkh) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceivePkh.ProtoReflect.Descriptor instead.
+func (*ReceivePkh) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ReceivePkh) GetPkh() string {
+	if x != nil {
+		return x.Pkh
+	}
+	return ""
+}
+
+func (x *ReceivePkh) GetIdx() int64 {
+	if x != nil {
+		return x.Idx
+	}
+	return 0
+}
+
+type GetTokensReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetTokensReq) Reset() {
+	*x = GetTokensReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetTokensReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTokensReq) ProtoMessage() {}
+
+func (x *GetTokensReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTokensReq.ProtoReflect.Descriptor instead.
+func (*GetTokensReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{7}
+}
+
+type TokenList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Tokens []*Token `protobuf:"bytes,1,rep,name=tokens,proto3" json:"tokens,omitempty"`
+}
+
+func (x *TokenList) Reset() {
+	*x = TokenList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TokenList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenList) ProtoMessage() {}
+
+func (x *TokenList) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenList.ProtoReflect.Descriptor instead.
+func (*TokenList) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TokenList) GetTokens() []*Token {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+type Token struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Symbol       string  `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Addr         string  `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	HtlcAddr     string  `protobuf:"bytes,3,opt,name=htlc_addr,json=htlcAddr,proto3" json:"htlc_addr,omitempty"`
+	Decimals     uint32  `protobuf:"varint,4,opt,name=decimals,proto3" json:"decimals,omitempty"`
+	BchPrice     uint64  `protobuf:"varint,5,opt,name=bch_price,json=bchPrice,proto3" json:"bch_price,omitempty"`
+	TokenPrice   uint64  `protobuf:"varint,6,opt,name=token_price,json=tokenPrice,proto3" json:"token_price,omitempty"`
+	PriceStale   bool    `protobuf:"varint,7,opt,name=price_stale,json=priceStale,proto3" json:"price_stale,omitempty"`
+	Free         float64 `protobuf:"fixed64,8,opt,name=free,proto3" json:"free,omitempty"`
+	Locked       float64 `protobuf:"fixed64,9,opt,name=locked,proto3" json:"locked,omitempty"`
+	ToBeUnlocked float64 `protobuf:"fixed64,10,opt,name=to_be_unlocked,json=toBeUnlocked,proto3" json:"to_be_unlocked,omitempty"`
+}
+
+func (x *Token) Reset() {
+	*x = Token{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Token) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Token) ProtoMessage() {}
+
+func (x *Token) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Token.ProtoReflect.Descriptor instead.
+func (*Token) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Token) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Token) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *Token) GetHtlcAddr() string {
+	if x != nil {
+		return x.HtlcAddr
+	}
+	return ""
+}
+
+func (x *Token) GetDecimals() uint32 {
+	if x != nil {
+		return x.Decimals
+	}
+	return 0
+}
+
+func (x *Token) GetBchPrice() uint64 {
+	if x != nil {
+		return x.BchPrice
+	}
+	return 0
+}
+
+func (x *Token) GetTokenPrice() uint64 {
+	if x != nil {
+		return x.TokenPrice
+	}
+	return 0
+}
+
+func (x *Token) GetPriceStale() bool {
+	if x != nil {
+		return x.PriceStale
+	}
+	return false
+}
+
+func (x *Token) GetFree() float64 {
+	if x != nil {
+		return x.Free
+	}
+	return 0
+}
+
+func (x *Token) GetLocked() float64 {
+	if x != nil {
+		return x.Locked
+	}
+	return 0
+}
+
+func (x *Token) GetToBeUnlocked() float64 {
+	if x != nil {
+		return x.ToBeUnlocked
+	}
+	return 0
+}
+
+type QuoteReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Direction     string `protobuf:"bytes,1,opt,name=direction,proto3" json:"direction,omitempty"` // bch2sbch|sbch2bch
+	Value         uint64 `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`        // in sats
+	HashLock      string `protobuf:"bytes,3,opt,name=hash_lock,json=hashLock,proto3" json:"hash_lock,omitempty"`
+	Expiration    uint32 `protobuf:"varint,4,opt,name=expiration,proto3" json:"expiration,omitempty"` // optional
+	SenderPkh     string `protobuf:"bytes,5,opt,name=sender_pkh,json=senderPkh,proto3" json:"sender_pkh,omitempty"`
+	SenderEvmAddr string `protobuf:"bytes,6,opt,name=sender_evm_addr,json=senderEvmAddr,proto3" json:"sender_evm_addr,omitempty"`
+	RecipientPkh  string `protobuf:"bytes,7,opt,name=recipient_pkh,json=recipientPkh,proto3" json:"recipient_pkh,omitempty"`
+	Token         string `protobuf:"bytes,8,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (x *QuoteReq) Reset() {
+	*x = QuoteReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QuoteReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteReq) ProtoMessage() {}
+
+func (x *QuoteReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteReq.ProtoReflect.Descriptor instead.
+func (*QuoteReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *QuoteReq) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *QuoteReq) GetValue() uint64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *QuoteReq) GetHashLock() string {
+	if x != nil {
+		return x.HashLock
+	}
+	return ""
+}
+
+func (x *QuoteReq) GetExpiration() uint32 {
+	if x != nil {
+		return x.Expiration
+	}
+	return 0
+}
+
+func (x *QuoteReq) GetSenderPkh() string {
+	if x != nil {
+		return x.SenderPkh
+	}
+	return ""
+}
+
+func (x *QuoteReq) GetSenderEvmAddr() string {
+	if x != nil {
+		return x.SenderEvmAddr
+	}
+	return ""
+}
+
+func (x *QuoteReq) GetRecipientPkh() string {
+	if x != nil {
+		return x.RecipientPkh
+	}
+	return ""
+}
+
+func (x *QuoteReq) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type Quote struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Direction    string `protobuf:"bytes,1,opt,name=direction,proto3" json:"direction,omitempty"`
+	Token        string `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Value        uint64 `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	CounterValue uint64 `protobuf:"varint,4,opt,name=counter_value,json=counterValue,proto3" json:"counter_value,omitempty"`
+	Fee          uint64 `protobuf:"varint,5,opt,name=fee,proto3" json:"fee,omitempty"`
+	Price        uint64 `protobuf:"varint,6,opt,name=price,proto3" json:"price,omitempty"`
+	HashLock     string `protobuf:"bytes,7,opt,name=hash_lock,json=hashLock,proto3" json:"hash_lock,omitempty"`
+	CovenantAddr string `protobuf:"bytes,8,opt,name=covenant_addr,json=covenantAddr,proto3" json:"covenant_addr,omitempty"`
+	OpReturn     string `protobuf:"bytes,9,opt,name=op_return,json=opReturn,proto3" json:"op_return,omitempty"`
+	BchTimeLock  uint32 `protobuf:"varint,10,opt,name=bch_time_lock,json=bchTimeLock,proto3" json:"bch_time_lock,omitempty"`
+	SbchTimeLock uint32 `protobuf:"varint,11,opt,name=sbch_time_lock,json=sbchTimeLock,proto3" json:"sbch_time_lock,omitempty"`
+	PenaltyBps   uint32 `protobuf:"varint,12,opt,name=penalty_bps,json=penaltyBps,proto3" json:"penalty_bps,omitempty"`
+	ValidUntil   int64  `protobuf:"varint,13,opt,name=valid_until,json=validUntil,proto3" json:"valid_until,omitempty"`
+	Signer       string `protobuf:"bytes,14,opt,name=signer,proto3" json:"signer,omitempty"`
+	Signature    string `protobuf:"bytes,15,opt,name=signature,proto3" json:"signature,omitempty"`
+	PaymentUri   string `protobuf:"bytes,16,opt,name=payment_uri,json=paymentUri,proto3" json:"payment_uri,omitempty"`
+}
+
+func (x *Quote) Reset() {
+	*x = Quote{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Quote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quote) ProtoMessage() {}
+
+func (x *Quote) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quote.ProtoReflect.Descriptor instead.
+func (*Quote) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Quote) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *Quote) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *Quote) GetValue() uint64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Quote) GetCounterValue() uint64 {
+	if x != nil {
+		return x.CounterValue
+	}
+	return 0
+}
+
+func (x *Quote) GetFee() uint64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *Quote) GetPrice() uint64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Quote) GetHashLock() string {
+	if x != nil {
+		return x.HashLock
+	}
+	return ""
+}
+
+func (x *Quote) GetCovenantAddr() string {
+	if x != nil {
+		return x.CovenantAddr
+	}
+	return ""
+}
+
+func (x *Quote) GetOpReturn() string {
+	if x != nil {
+		return x.OpReturn
+	}
+	return ""
+}
+
+func (x *Quote) GetBchTimeLock() uint32 {
+	if x != nil {
+		return x.BchTimeLock
+	}
+	return 0
+}
+
+func (x *Quote) GetSbchTimeLock() uint32 {
+	if x != nil {
+		return x.SbchTimeLock
+	}
+	return 0
+}
+
+func (x *Quote) GetPenaltyBps() uint32 {
+	if x != nil {
+		return x.PenaltyBps
+	}
+	return 0
+}
+
+func (x *Quote) GetValidUntil() int64 {
+	if x != nil {
+		return x.ValidUntil
+	}
+	return 0
+}
+
+func (x *Quote) GetSigner() string {
+	if x != nil {
+		return x.Signer
+	}
+	return ""
+}
+
+func (x *Quote) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *Quote) GetPaymentUri() string {
+	if x != nil {
+		return x.PaymentUri
+	}
+	return ""
+}
+
+type ListSwapsReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Direction string `protobuf:"bytes,1,opt,name=direction,proto3" json:"direction,omitempty"` // bch2sbch|sbch2bch
+	Status    string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`       // comma separated status names, empty means all
+	Sender    string `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`       // BCH PKH or EVM address of user
+	From      int64  `protobuf:"varint,4,opt,name=from,proto3" json:"from,omitempty"`          // unix timestamp, inclusive
+	To        int64  `protobuf:"varint,5,opt,name=to,proto3" json:"to,omitempty"`              // unix timestamp, exclusive
+	Cursor    uint64 `protobuf:"varint,6,opt,name=cursor,proto3" json:"cursor,omitempty"`      // next_cursor of previous page
+	Asc       bool   `protobuf:"varint,7,opt,name=asc,proto3" json:"asc,omitempty"`            // oldest first
+	Limit     uint32 `protobuf:"varint,8,opt,name=limit,proto3" json:"limit,omitempty"`        // default 50, max 500
+	Archived  bool   `protobuf:"varint,9,opt,name=archived,proto3" json:"archived,omitempty"`
+}
+
+func (x *ListSwapsReq) Reset() {
+	*x = ListSwapsReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListSwapsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSwapsReq) ProtoMessage() {}
+
+func (x *ListSwapsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSwapsReq.ProtoReflect.Descriptor instead.
+func (*ListSwapsReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListSwapsReq) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *ListSwapsReq) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListSwapsReq) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *ListSwapsReq) GetFrom() int64 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *ListSwapsReq) GetTo() int64 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *ListSwapsReq) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *ListSwapsReq) GetAsc() bool {
+	if x != nil {
+		return x.Asc
+	}
+	return false
+}
+
+func (x *ListSwapsReq) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListSwapsReq) GetArchived() bool {
+	if x != nil {
+		return x.Archived
+	}
+	return false
+}
+
+type SwapList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Swaps      []*SwapListItem `protobuf:"bytes,1,rep,name=swaps,proto3" json:"swaps,omitempty"`
+	NextCursor uint64          `protobuf:"varint,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // 0 on the last page
+}
+
+func (x *SwapList) Reset() {
+	*x = SwapList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SwapList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwapList) ProtoMessage() {}
+
+func (x *SwapList) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwapList.ProtoReflect.Descriptor instead.
+func (*SwapList) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SwapList) GetSwaps() []*SwapListItem {
+	if x != nil {
+		return x.Swaps
+	}
+	return nil
+}
+
+func (x *SwapList) GetNextCursor() uint64 {
+	if x != nil {
+		return x.NextCursor
+	}
+	return 0
+}
+
+type SwapListItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id        uint64  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Direction string  `protobuf:"bytes,2,opt,name=direction,proto3" json:"direction,omitempty"`
+	HashLock  string  `protobuf:"bytes,3,opt,name=hash_lock,json=hashLock,proto3" json:"hash_lock,omitempty"`
+	Value     float64 `protobuf:"fixed64,4,opt,name=value,proto3" json:"value,omitempty"`
+	Price     uint64  `protobuf:"varint,5,opt,name=price,proto3" json:"price,omitempty"`
+	Status    string  `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	Token     string  `protobuf:"bytes,7,opt,name=token,proto3" json:"token,omitempty"`
+	Sender    string  `protobuf:"bytes,8,opt,name=sender,proto3" json:"sender,omitempty"`
+	Recipient string  `protobuf:"bytes,9,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	CreatedAt int64   `protobuf:"varint,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt int64   `protobuf:"varint,11,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+}
+
+func (x *SwapListItem) Reset() {
+	*x = SwapListItem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SwapListItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwapListItem) ProtoMessage() {}
+
+func (x *SwapListItem) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwapListItem.ProtoReflect.Descriptor instead.
+func (*SwapListItem) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SwapListItem) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SwapListItem) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *SwapListItem) GetHashLock() string {
+	if x != nil {
+		return x.HashLock
+	}
+	return ""
+}
+
+func (x *SwapListItem) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *SwapListItem) GetPrice() uint64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *SwapListItem) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SwapListItem) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *SwapListItem) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *SwapListItem) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *SwapListItem) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *SwapListItem) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type GetSwapTxsReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	HashLock string `protobuf:"bytes,1,opt,name=hash_lock,json=hashLock,proto3" json:"hash_lock,omitempty"`
+}
+
+func (x *GetSwapTxsReq) Reset() {
+	*x = GetSwapTxsReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetSwapTxsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSwapTxsReq) ProtoMessage() {}
+
+func (x *GetSwapTxsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSwapTxsReq.ProtoReflect.Descriptor instead.
+func (*GetSwapTxsReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetSwapTxsReq) GetHashLock() string {
+	if x != nil {
+		return x.HashLock
+	}
+	return ""
+}
+
+type SwapTxList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Txs []*SwapTx `protobuf:"bytes,1,rep,name=txs,proto3" json:"txs,omitempty"`
+}
+
+func (x *SwapTxList) Reset() {
+	*x = SwapTxList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SwapTxList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwapTxList) ProtoMessage() {}
+
+func (x *SwapTxList) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwapTxList.ProtoReflect.Descriptor instead.
+func (*SwapTxList) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SwapTxList) GetTxs() []*SwapTx {
+	if x != nil {
+		return x.Txs
+	}
+	return nil
+}
+
+type SwapTx struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Leg    string `protobuf:"bytes,1,opt,name=leg,proto3" json:"leg,omitempty"`
+	TxHash string `protobuf:"bytes,2,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	RawTx  string `protobuf:"bytes,3,opt,name=raw_tx,json=rawTx,proto3" json:"raw_tx,omitempty"`
+}
+
+func (x *SwapTx) Reset() {
+	*x = SwapTx{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SwapTx) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwapTx) ProtoMessage() {}
+
+func (x *SwapTx) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwapTx.ProtoReflect.Descriptor instead.
+func (*SwapTx) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SwapTx) GetLeg() string {
+	if x != nil {
+		return x.Leg
+	}
+	return ""
+}
+
+func (x *SwapTx) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+func (x *SwapTx) GetRawTx() string {
+	if x != nil {
+		return x.RawTx
+	}
+	return ""
+}
+
+type GetStatsReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetStatsReq) Reset() {
+	*x = GetStatsReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetStatsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsReq) ProtoMessage() {}
+
+func (x *GetStatsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsReq.ProtoReflect.Descriptor instead.
+func (*GetStatsReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{18}
+}
+
+type Stats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Swaps         int64 `protobuf:"varint,1,opt,name=swaps,proto3" json:"swaps,omitempty"`
+	ServiceFee    int64 `protobuf:"varint,2,opt,name=service_fee,json=serviceFee,proto3" json:"service_fee,omitempty"`
+	EstimatedCost int64 `protobuf:"varint,3,opt,name=estimated_cost,json=estimatedCost,proto3" json:"estimated_cost,omitempty"`
+	BchMinerFee   int64 `protobuf:"varint,4,opt,name=bch_miner_fee,json=bchMinerFee,proto3" json:"bch_miner_fee,omitempty"`
+	SbchGasFee    int64 `protobuf:"varint,5,opt,name=sbch_gas_fee,json=sbchGasFee,proto3" json:"sbch_gas_fee,omitempty"`
+	Profit        int64 `protobuf:"varint,6,opt,name=profit,proto3" json:"profit,omitempty"`
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Stats) GetSwaps() int64 {
+	if x != nil {
+		return x.Swaps
+	}
+	return 0
+}
+
+func (x *Stats) GetServiceFee() int64 {
+	if x != nil {
+		return x.ServiceFee
+	}
+	return 0
+}
+
+func (x *Stats) GetEstimatedCost() int64 {
+	if x != nil {
+		return x.EstimatedCost
+	}
+	return 0
+}
+
+func (x *Stats) GetBchMinerFee() int64 {
+	if x != nil {
+		return x.BchMinerFee
+	}
+	return 0
+}
+
+func (x *Stats) GetSbchGasFee() int64 {
+	if x != nil {
+		return x.SbchGasFee
+	}
+	return 0
+}
+
+func (x *Stats) GetProfit() int64 {
+	if x != nil {
+		return x.Profit
+	}
+	return 0
+}
+
+type GetSwapStatsReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Days uint32 `protobuf:"varint,1,opt,name=days,proto3" json:"days,omitempty"` // default 30, max 365
+}
+
+func (x *GetSwapStatsReq) Reset() {
+	*x = GetSwapStatsReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetSwapStatsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSwapStatsReq) ProtoMessage() {}
+
+func (x *GetSwapStatsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSwapStatsReq.ProtoReflect.Descriptor instead.
+func (*GetSwapStatsReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetSwapStatsReq) GetDays() uint32 {
+	if x != nil {
+		return x.Days
+	}
+	return 0
+}
+
+type SwapStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	GeneratedAt int64             `protobuf:"varint,1,opt,name=generated_at,json=generatedAt,proto3" json:"generated_at,omitempty"`
+	Days        uint32            `protobuf:"varint,2,opt,name=days,proto3" json:"days,omitempty"`
+	Daily       []*DailySwapStats `protobuf:"bytes,3,rep,name=daily,proto3" json:"daily,omitempty"`
+	Bch2Sbch    *DirectionStats   `protobuf:"bytes,4,opt,name=bch2sbch,proto3" json:"bch2sbch,omitempty"`
+	Sbch2Bch    *DirectionStats   `protobuf:"bytes,5,opt,name=sbch2bch,proto3" json:"sbch2bch,omitempty"`
+	FeesEarned  uint64            `protobuf:"varint,6,opt,name=fees_earned,json=feesEarned,proto3" json:"fees_earned,omitempty"`
+	LargestSwap uint64            `protobuf:"varint,7,opt,name=largest_swap,json=largestSwap,proto3" json:"largest_swap,omitempty"`
+}
+
+func (x *SwapStats) Reset() {
+	*x = SwapStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SwapStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwapStats) ProtoMessage() {}
+
+func (x *SwapStats) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwapStats.ProtoReflect.Descriptor instead.
+func (*SwapStats) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SwapStats) GetGeneratedAt() int64 {
+	if x != nil {
+		return x.GeneratedAt
+	}
+	return 0
+}
+
+func (x *SwapStats) GetDays() uint32 {
+	if x != nil {
+		return x.Days
+	}
+	return 0
+}
+
+func (x *SwapStats) GetDaily() []*DailySwapStats {
+	if x != nil {
+		return x.Daily
+	}
+	return nil
+}
+
+func (x *SwapStats) GetBch2Sbch() *DirectionStats {
+	if x != nil {
+		return x.Bch2Sbch
+	}
+	return nil
+}
+
+func (x *SwapStats) GetSbch2Bch() *DirectionStats {
+	if x != nil {
+		return x.Sbch2Bch
+	}
+	return nil
+}
+
+func (x *SwapStats) GetFeesEarned() uint64 {
+	if x != nil {
+		return x.FeesEarned
+	}
+	return 0
+}
+
+func (x *SwapStats) GetLargestSwap() uint64 {
+	if x != nil {
+		return x.LargestSwap
+	}
+	return 0
+}
+
+type DailySwapStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Date     string `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Bch2Sbch int64  `protobuf:"varint,2,opt,name=bch2sbch,proto3" json:"bch2sbch,omitempty"`
+	Sbch2Bch int64  `protobuf:"varint,3,opt,name=sbch2bch,proto3" json:"sbch2bch,omitempty"`
+}
+
+func (x *DailySwapStats) Reset() {
+	*x = DailySwapStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DailySwapStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DailySwapStats) ProtoMessage() {}
+
+func (x *DailySwapStats) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DailySwapStats.ProtoReflect.Descriptor instead.
+func (*DailySwapStats) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DailySwapStats) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *DailySwapStats) GetBch2Sbch() int64 {
+	if x != nil {
+		return x.Bch2Sbch
+	}
+	return 0
+}
+
+func (x *DailySwapStats) GetSbch2Bch() int64 {
+	if x != nil {
+		return x.Sbch2Bch
+	}
+	return 0
+}
+
+type DirectionStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Swaps             int64   `protobuf:"varint,1,opt,name=swaps,proto3" json:"swaps,omitempty"`
+	Completed         int64   `protobuf:"varint,2,opt,name=completed,proto3" json:"completed,omitempty"`
+	Refunded          int64   `protobuf:"varint,3,opt,name=refunded,proto3" json:"refunded,omitempty"`
+	Volume            uint64  `protobuf:"varint,4,opt,name=volume,proto3" json:"volume,omitempty"`
+	LargestSwap       uint64  `protobuf:"varint,5,opt,name=largest_swap,json=largestSwap,proto3" json:"largest_swap,omitempty"`
+	AvgCompletionTime int64   `protobuf:"varint,6,opt,name=avg_completion_time,json=avgCompletionTime,proto3" json:"avg_completion_time,omitempty"`
+	RefundRate        float64 `protobuf:"fixed64,7,opt,name=refund_rate,json=refundRate,proto3" json:"refund_rate,omitempty"`
+	FeesEarned        uint64  `protobuf:"varint,8,opt,name=fees_earned,json=feesEarned,proto3" json:"fees_earned,omitempty"`
+}
+
+func (x *DirectionStats) Reset() {
+	*x = DirectionStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DirectionStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DirectionStats) ProtoMessage() {}
+
+func (x *DirectionStats) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DirectionStats.ProtoReflect.Descriptor instead.
+func (*DirectionStats) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DirectionStats) GetSwaps() int64 {
+	if x != nil {
+		return x.Swaps
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetCompleted() int64 {
+	if x != nil {
+		return x.Completed
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetRefunded() int64 {
+	if x != nil {
+		return x.Refunded
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetVolume() uint64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetLargestSwap() uint64 {
+	if x != nil {
+		return x.LargestSwap
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetAvgCompletionTime() int64 {
+	if x != nil {
+		return x.AvgCompletionTime
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetRefundRate() float64 {
+	if x != nil {
+		return x.RefundRate
+	}
+	return 0
+}
+
+func (x *DirectionStats) GetFeesEarned() uint64 {
+	if x != nil {
+		return x.FeesEarned
+	}
+	return 0
+}
+
+type StreamSwapEventsReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	AfterId uint64 `protobuf:"varint,1,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"` // only events with larger IDs are sent, 0 means from the oldest kept
+}
+
+func (x *StreamSwapEventsReq) Reset() {
+	*x = StreamSwapEventsReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamSwapEventsReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamSwapEventsReq) ProtoMessage() {}
+
+func (x *StreamSwapEventsReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamSwapEventsReq.ProtoReflect.Descriptor instead.
+func (*StreamSwapEventsReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *StreamSwapEventsReq) GetAfterId() uint64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+type SwapEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id        uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Kind      string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"` // bch_deposit|bch_receipt|sbch_lock|sbch_unlock
+	Key       string `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Height    uint64 `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`
+	Payload   string `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"` // JSON
+	CreatedAt int64  `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+}
+
+func (x *SwapEvent) Reset() {
+	*x = SwapEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SwapEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwapEvent) ProtoMessage() {}
+
+func (x *SwapEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwapEvent.ProtoReflect.Descriptor instead.
+func (*SwapEvent) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *SwapEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SwapEvent) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *SwapEvent) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SwapEvent) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *SwapEvent) GetPayload() string {
+	if x != nil {
+		return x.Payload
+	}
+	return ""
+}
+
+func (x *SwapEvent) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type GetAccessListReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetAccessListReq) Reset() {
+	*x = GetAccessListReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetAccessListReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAccessListReq) ProtoMessage() {}
+
+func (x *GetAccessListReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAccessListReq.ProtoReflect.Descriptor instead.
+func (*GetAccessListReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{26}
+}
+
+type ReloadAccessListReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReloadAccessListReq) Reset() {
+	*x = ReloadAccessListReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReloadAccessListReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadAccessListReq) ProtoMessage() {}
+
+func (x *ReloadAccessListReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadAccessListReq.ProtoReflect.Descriptor instead.
+func (*ReloadAccessListReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{27}
+}
+
+type AccessList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	WhitelistPkhs     []string `protobuf:"bytes,1,rep,name=whitelist_pkhs,json=whitelistPkhs,proto3" json:"whitelist_pkhs,omitempty"`
+	WhitelistEvmAddrs []string `protobuf:"bytes,2,rep,name=whitelist_evm_addrs,json=whitelistEvmAddrs,proto3" json:"whitelist_evm_addrs,omitempty"`
+	BlacklistPkhs     []string `protobuf:"bytes,3,rep,name=blacklist_pkhs,json=blacklistPkhs,proto3" json:"blacklist_pkhs,omitempty"`
+	BlacklistEvmAddrs []string `protobuf:"bytes,4,rep,name=blacklist_evm_addrs,json=blacklistEvmAddrs,proto3" json:"blacklist_evm_addrs,omitempty"`
+}
+
+func (x *AccessList) Reset() {
+	*x = AccessList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AccessList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccessList) ProtoMessage() {}
+
+func (x *AccessList) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccessList.ProtoReflect.Descriptor instead.
+func (*AccessList) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *AccessList) GetWhitelistPkhs() []string {
+	if x != nil {
+		return x.WhitelistPkhs
+	}
+	return nil
+}
+
+func (x *AccessList) GetWhitelistEvmAddrs() []string {
+	if x != nil {
+		return x.WhitelistEvmAddrs
+	}
+	return nil
+}
+
+func (x *AccessList) GetBlacklistPkhs() []string {
+	if x != nil {
+		return x.BlacklistPkhs
+	}
+	return nil
+}
+
+func (x *AccessList) GetBlacklistEvmAddrs() []string {
+	if x != nil {
+		return x.BlacklistEvmAddrs
+	}
+	return nil
+}
+
+type ReloadConfigReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReloadConfigReq) Reset() {
+	*x = ReloadConfigReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReloadConfigReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadConfigReq) ProtoMessage() {}
+
+func (x *ReloadConfigReq) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadConfigReq.ProtoReflect.Descriptor instead.
+func (*ReloadConfigReq) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{29}
+}
+
+type ReloadConfigResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Msg string `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"` // "scheduled"
+}
+
+func (x *ReloadConfigResp) Reset() {
+	*x = ReloadConfigResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_grpcapi_asbot_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReloadConfigResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadConfigResp) ProtoMessage() {}
+
+func (x *ReloadConfigResp) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcapi_asbot_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadConfigResp.ProtoReflect.Descriptor instead.
+func (*ReloadConfigResp) Descriptor() ([]byte, []int) {
+	return file_grpcapi_asbot_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ReloadConfigResp) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+var File_grpcapi_asbot_proto protoreflect.FileDescriptor
+
+var file_grpcapi_asbot_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x22,
+	0x09, 0x0a, 0x07, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x22, 0x1c, 0x0a, 0x08, 0x50, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x22, 0x0c, 0x0a, 0x0a, 0x47, 0x65, 0x74, 0x49,
+	0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x22, 0xbc, 0x02, 0x0a, 0x04, 0x49, 0x6e, 0x66, 0x6f, 0x12,
+	0x19, 0x0a, 0x08, 0x66, 0x72, 0x65, 0x65, 0x5f, 0x62, 0x63, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x07, 0x66, 0x72, 0x65, 0x65, 0x42, 0x63, 0x68, 0x12, 0x1b, 0x0a, 0x09, 0x66, 0x72,
+	0x65, 0x65, 0x5f, 0x73, 0x62, 0x63, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x66,
+	0x72, 0x65, 0x65, 0x53, 0x62, 0x63, 0x68, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x6f, 0x63, 0x6b, 0x65,
+	0x64, 0x5f, 0x62, 0x63, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x6c, 0x6f, 0x63,
+	0x6b, 0x65, 0x64, 0x42, 0x63, 0x68, 0x12, 0x1f, 0x0a, 0x0b, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64,
+	0x5f, 0x73, 0x62, 0x63, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0a, 0x6c, 0x6f, 0x63,
+	0x6b, 0x65, 0x64, 0x53, 0x62, 0x63, 0x68, 0x12, 0x2b, 0x0a, 0x12, 0x74, 0x6f, 0x5f, 0x62, 0x65,
+	0x5f, 0x75, 0x6e, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x5f, 0x62, 0x63, 0x68, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x0f, 0x74, 0x6f, 0x42, 0x65, 0x55, 0x6e, 0x6c, 0x6f, 0x63, 0x6b, 0x65,
+	0x64, 0x42, 0x63, 0x68, 0x12, 0x2d, 0x0a, 0x13, 0x74, 0x6f, 0x5f, 0x62, 0x65, 0x5f, 0x75, 0x6e,
+	0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x5f, 0x73, 0x62, 0x63, 0x68, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x10, 0x74, 0x6f, 0x42, 0x65, 0x55, 0x6e, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x53,
+	0x62, 0x63, 0x68, 0x12, 0x2f, 0x0a, 0x09, 0x73, 0x32, 0x62, 0x5f, 0x73, 0x77, 0x61, 0x70, 0x73,
+	0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x77, 0x61, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x73, 0x32, 0x62, 0x53,
+	0x77, 0x61, 0x70, 0x73, 0x12, 0x2f, 0x0a, 0x09, 0x62, 0x32, 0x73, 0x5f, 0x73, 0x77, 0x61, 0x70,
+	0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e,
+	0x76, 0x31, 0x2e, 0x53, 0x77, 0x61, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x62, 0x32, 0x73,
+	0x53, 0x77, 0x61, 0x70, 0x73, 0x22, 0x6b, 0x0a, 0x08, 0x53, 0x77, 0x61, 0x70, 0x49, 0x6e, 0x66,
+	0x6f, 0x12, 0x1b, 0x0a, 0x09, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x68, 0x61, 0x73, 0x68, 0x4c, 0x6f, 0x63, 0x6b, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x22, 0x12, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65,
+	0x50, 0x6b, 0x68, 0x52, 0x65, 0x71, 0x22, 0x30, 0x0a, 0x0a, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76,
+	0x65, 0x50, 0x6b, 0x68, 0x12, 0x10, 0x0a, 0x03, 0x70, 0x6b, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x70, 0x6b, 0x68, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x64, 0x78, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x03, 0x69, 0x64, 0x78, 0x22, 0x0e, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x54,
+	0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x22, 0x34, 0x0a, 0x09, 0x54, 0x6f, 0x6b, 0x65,
+	0x6e, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x27, 0x0a, 0x06, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x06, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x22, 0x9d,
+	0x02, 0x0a, 0x05, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x79, 0x6d, 0x62,
+	0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x79, 0x6d, 0x62, 0x6f, 0x6c,
+	0x12, 0x12, 0x0a, 0x04, 0x61, 0x64, 0x64, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x61, 0x64, 0x64, 0x72, 0x12, 0x1b, 0x0a, 0x09, 0x68, 0x74, 0x6c, 0x63, 0x5f, 0x61, 0x64, 0x64,
+	0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x68, 0x74, 0x6c, 0x63, 0x41, 0x64, 0x64,
+	0x72, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x65, 0x63, 0x69, 0x6d, 0x61, 0x6c, 0x73, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x08, 0x64, 0x65, 0x63, 0x69, 0x6d, 0x61, 0x6c, 0x73, 0x12, 0x1b, 0x0a,
+	0x09, 0x62, 0x63, 0x68, 0x5f, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x08, 0x62, 0x63, 0x68, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x6f,
+	0x6b, 0x65, 0x6e, 0x5f, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0a, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x70,
+	0x72, 0x69, 0x63, 0x65, 0x5f, 0x73, 0x74, 0x61, 0x6c, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x66, 0x72, 0x65, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x66, 0x72, 0x65, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x06, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x12, 0x24, 0x0a, 0x0e, 0x74, 0x6f, 0x5f, 0x62,
+	0x65, 0x5f, 0x75, 0x6e, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x0c, 0x74, 0x6f, 0x42, 0x65, 0x55, 0x6e, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x22, 0xfd,
+	0x01, 0x0a, 0x08, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x52, 0x65, 0x71, 0x12, 0x1c, 0x0a, 0x09, 0x64,
+	0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12,
+	0x1b, 0x0a, 0x09, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x68, 0x61, 0x73, 0x68, 0x4c, 0x6f, 0x63, 0x6b, 0x12, 0x1e, 0x0a, 0x0a,
+	0x65, 0x78, 0x70, 0x69, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a,
+	0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x5f, 0x70, 0x6b, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x50, 0x6b, 0x68, 0x12, 0x26, 0x0a, 0x0f, 0x73,
+	0x65, 0x6e, 0x64, 0x65, 0x72, 0x5f, 0x65, 0x76, 0x6d, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x45, 0x76, 0x6d, 0x41,
+	0x64, 0x64, 0x72, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74,
+	0x5f, 0x70, 0x6b, 0x68, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x72, 0x65, 0x63, 0x69,
+	0x70, 0x69, 0x65, 0x6e, 0x74, 0x50, 0x6b, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65,
+	0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0xe0,
+	0x03, 0x0a, 0x05, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x64, 0x69, 0x72, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x64, 0x69, 0x72,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x5f, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x65, 0x72, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x66, 0x65, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x66, 0x65, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69,
+	0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12,
+	0x1b, 0x0a, 0x09, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x68, 0x61, 0x73, 0x68, 0x4c, 0x6f, 0x63, 0x6b, 0x12, 0x23, 0x0a, 0x0d,
+	0x63, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x41, 0x64, 0x64,
+	0x72, 0x12, 0x1b, 0x0a, 0x09, 0x6f, 0x70, 0x5f, 0x72, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6f, 0x70, 0x52, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x12, 0x22,
+	0x0a, 0x0d, 0x62, 0x63, 0x68, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x6c, 0x6f, 0x63, 0x6b, 0x18,
+	0x0a, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x62, 0x63, 0x68, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x6f,
+	0x63, 0x6b, 0x12, 0x24, 0x0a, 0x0e, 0x73, 0x62, 0x63, 0x68, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x5f,
+	0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x73, 0x62, 0x63, 0x68,
+	0x54, 0x69, 0x6d, 0x65, 0x4c, 0x6f, 0x63, 0x6b, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x65, 0x6e, 0x61,
+	0x6c, 0x74, 0x79, 0x5f, 0x62, 0x70, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x70,
+	0x65, 0x6e, 0x61, 0x6c, 0x74, 0x79, 0x42, 0x70, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x76, 0x61, 0x6c,
+	0x69, 0x64, 0x5f, 0x75, 0x6e, 0x74, 0x69, 0x6c, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a,
+	0x76, 0x61, 0x6c, 0x69, 0x64, 0x55, 0x6e, 0x74, 0x69, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x69,
+	0x67, 0x6e, 0x65, 0x72, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x69, 0x67, 0x6e,
+	0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18,
+	0x0f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x75, 0x72, 0x69, 0x18,
+	0x10, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x55, 0x72,
+	0x69, 0x22, 0xdc, 0x01, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x77, 0x61, 0x70, 0x73, 0x52,
+	0x65, 0x71, 0x12, 0x1c, 0x0a, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x6e, 0x64,
+	0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72,
+	0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04,
+	0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x02, 0x74, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x10, 0x0a, 0x03,
+	0x61, 0x73, 0x63, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x61, 0x73, 0x63, 0x12, 0x14,
+	0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x72, 0x63, 0x68, 0x69, 0x76, 0x65, 0x64,
+	0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x61, 0x72, 0x63, 0x68, 0x69, 0x76, 0x65, 0x64,
+	0x22, 0x59, 0x0a, 0x08, 0x53, 0x77, 0x61, 0x70, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x2c, 0x0a, 0x05,
+	0x73, 0x77, 0x61, 0x70, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x61, 0x73,
+	0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x77, 0x61, 0x70, 0x4c, 0x69, 0x73, 0x74, 0x49,
+	0x74, 0x65, 0x6d, 0x52, 0x05, 0x73, 0x77, 0x61, 0x70, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x65,
+	0x78, 0x74, 0x5f, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0a, 0x6e, 0x65, 0x78, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22, 0xa7, 0x02, 0x0a, 0x0c,
+	0x53, 0x77, 0x61, 0x70, 0x4c, 0x69, 0x73, 0x74, 0x49, 0x74, 0x65, 0x6d, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1c, 0x0a, 0x09,
+	0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x68, 0x61,
+	0x73, 0x68, 0x5f, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x68,
+	0x61, 0x73, 0x68, 0x4c, 0x6f, 0x63, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x70, 0x72,
+	0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x74,
+	0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65,
+	0x6e, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x63,
+	0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65,
+	0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x61, 0x74,
+	0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x64, 0x5f, 0x61, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x75, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x64, 0x41, 0x74, 0x22, 0x2c, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x53, 0x77, 0x61, 0x70,
+	0x54, 0x78, 0x73, 0x52, 0x65, 0x71, 0x12, 0x1b, 0x0a, 0x09, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x6c,
+	0x6f, 0x63, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x68, 0x61, 0x73, 0x68, 0x4c,
+	0x6f, 0x63, 0x6b, 0x22, 0x30, 0x0a, 0x0a, 0x53, 0x77, 0x61, 0x70, 0x54, 0x78, 0x4c, 0x69, 0x73,
+	0x74, 0x12, 0x22, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10,
+	0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x77, 0x61, 0x70, 0x54, 0x78,
+	0x52, 0x03, 0x74, 0x78, 0x73, 0x22, 0x4a, 0x0a, 0x06, 0x53, 0x77, 0x61, 0x70, 0x54, 0x78, 0x12,
+	0x10, 0x0a, 0x03, 0x6c, 0x65, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6c, 0x65,
+	0x67, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x78, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x74, 0x78, 0x48, 0x61, 0x73, 0x68, 0x12, 0x15, 0x0a, 0x06, 0x72, 0x61,
+	0x77, 0x5f, 0x74, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x72, 0x61, 0x77, 0x54,
+	0x78, 0x22, 0x0d, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71,
+	0x22, 0xc3, 0x01, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x77,
+	0x61, 0x70, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x73, 0x77, 0x61, 0x70, 0x73,
+	0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x66, 0x65, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x46, 0x65,
+	0x65, 0x12, 0x25, 0x0a, 0x0e, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x63,
+	0x6f, 0x73, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x65, 0x73, 0x74, 0x69, 0x6d,
+	0x61, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x0d, 0x62, 0x63, 0x68, 0x5f,
+	0x6d, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x66, 0x65, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0b, 0x62, 0x63, 0x68, 0x4d, 0x69, 0x6e, 0x65, 0x72, 0x46, 0x65, 0x65, 0x12, 0x20, 0x0a, 0x0c,
+	0x73, 0x62, 0x63, 0x68, 0x5f, 0x67, 0x61, 0x73, 0x5f, 0x66, 0x65, 0x65, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0a, 0x73, 0x62, 0x63, 0x68, 0x47, 0x61, 0x73, 0x46, 0x65, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06,
+	0x70, 0x72, 0x6f, 0x66, 0x69, 0x74, 0x22, 0x25, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x53, 0x77, 0x61,
+	0x70, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x79,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x64, 0x61, 0x79, 0x73, 0x22, 0xa2, 0x02,
+	0x0a, 0x09, 0x53, 0x77, 0x61, 0x70, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x67,
+	0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0b, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x64, 0x61, 0x79, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x64, 0x61,
+	0x79, 0x73, 0x12, 0x2e, 0x0a, 0x05, 0x64, 0x61, 0x69, 0x6c, 0x79, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x18, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x61, 0x69,
+	0x6c, 0x79, 0x53, 0x77, 0x61, 0x70, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x05, 0x64, 0x61, 0x69,
+	0x6c, 0x79, 0x12, 0x34, 0x0a, 0x08, 0x62, 0x63, 0x68, 0x32, 0x73, 0x62, 0x63, 0x68, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e,
+	0x44, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x08,
+	0x62, 0x63, 0x68, 0x32, 0x73, 0x62, 0x63, 0x68, 0x12, 0x34, 0x0a, 0x08, 0x73, 0x62, 0x63, 0x68,
+	0x32, 0x62, 0x63, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x73, 0x62,
+	0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53,
+	0x74, 0x61, 0x74, 0x73, 0x52, 0x08, 0x73, 0x62, 0x63, 0x68, 0x32, 0x62, 0x63, 0x68, 0x12, 0x1f,
+	0x0a, 0x0b, 0x66, 0x65, 0x65, 0x73, 0x5f, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x64, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x0a, 0x66, 0x65, 0x65, 0x73, 0x45, 0x61, 0x72, 0x6e, 0x65, 0x64, 0x12,
+	0x21, 0x0a, 0x0c, 0x6c, 0x61, 0x72, 0x67, 0x65, 0x73, 0x74, 0x5f, 0x73, 0x77, 0x61, 0x70, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6c, 0x61, 0x72, 0x67, 0x65, 0x73, 0x74, 0x53, 0x77,
+	0x61, 0x70, 0x22, 0x5c, 0x0a, 0x0e, 0x44, 0x61, 0x69, 0x6c, 0x79, 0x53, 0x77, 0x61, 0x70, 0x53,
+	0x74, 0x61, 0x74, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x63, 0x68, 0x32,
+	0x73, 0x62, 0x63, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x62, 0x63, 0x68, 0x32,
+	0x73, 0x62, 0x63, 0x68, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x62, 0x63, 0x68, 0x32, 0x62, 0x63, 0x68,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x73, 0x62, 0x63, 0x68, 0x32, 0x62, 0x63, 0x68,
+	0x22, 0x8d, 0x02, 0x0a, 0x0e, 0x44, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74,
+	0x61, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x77, 0x61, 0x70, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x05, 0x73, 0x77, 0x61, 0x70, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6d,
+	0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x6f,
+	0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x66, 0x75, 0x6e,
+	0x64, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x72, 0x65, 0x66, 0x75, 0x6e,
+	0x64, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x06, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x6c,
+	0x61, 0x72, 0x67, 0x65, 0x73, 0x74, 0x5f, 0x73, 0x77, 0x61, 0x70, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x0b, 0x6c, 0x61, 0x72, 0x67, 0x65, 0x73, 0x74, 0x53, 0x77, 0x61, 0x70, 0x12, 0x2e,
+	0x0a, 0x13, 0x61, 0x76, 0x67, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x69, 0x6f, 0x6e,
+	0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x61, 0x76, 0x67,
+	0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1f,
+	0x0a, 0x0b, 0x72, 0x65, 0x66, 0x75, 0x6e, 0x64, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x0a, 0x72, 0x65, 0x66, 0x75, 0x6e, 0x64, 0x52, 0x61, 0x74, 0x65, 0x12,
+	0x1f, 0x0a, 0x0b, 0x66, 0x65, 0x65, 0x73, 0x5f, 0x65, 0x61, 0x72, 0x6e, 0x65, 0x64, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x66, 0x65, 0x65, 0x73, 0x45, 0x61, 0x72, 0x6e, 0x65, 0x64,
+	0x22, 0x30, 0x0a, 0x13, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x77, 0x61, 0x70, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x12, 0x19, 0x0a, 0x08, 0x61, 0x66, 0x74, 0x65, 0x72,
+	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x61, 0x66, 0x74, 0x65, 0x72,
+	0x49, 0x64, 0x22, 0x92, 0x01, 0x0a, 0x09, 0x53, 0x77, 0x61, 0x70, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64,
+	0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6b, 0x69, 0x6e, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x18,
+	0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x22, 0x12, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x41, 0x63,
+	0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x22, 0x15, 0x0a, 0x13, 0x52,
+	0x65, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x52,
+	0x65, 0x71, 0x22, 0xba, 0x01, 0x0a, 0x0a, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73,
+	0x74, 0x12, 0x25, 0x0a, 0x0e, 0x77, 0x68, 0x69, 0x74, 0x65, 0x6c, 0x69, 0x73, 0x74, 0x5f, 0x70,
+	0x6b, 0x68, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x77, 0x68, 0x69, 0x74, 0x65,
+	0x6c, 0x69, 0x73, 0x74, 0x50, 0x6b, 0x68, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x77, 0x68, 0x69, 0x74,
+	0x65, 0x6c, 0x69, 0x73, 0x74, 0x5f, 0x65, 0x76, 0x6d, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x11, 0x77, 0x68, 0x69, 0x74, 0x65, 0x6c, 0x69, 0x73, 0x74,
+	0x45, 0x76, 0x6d, 0x41, 0x64, 0x64, 0x72, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x62, 0x6c, 0x61, 0x63,
+	0x6b, 0x6c, 0x69, 0x73, 0x74, 0x5f, 0x70, 0x6b, 0x68, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x0d, 0x62, 0x6c, 0x61, 0x63, 0x6b, 0x6c, 0x69, 0x73, 0x74, 0x50, 0x6b, 0x68, 0x73, 0x12,
+	0x2e, 0x0a, 0x13, 0x62, 0x6c, 0x61, 0x63, 0x6b, 0x6c, 0x69, 0x73, 0x74, 0x5f, 0x65, 0x76, 0x6d,
+	0x5f, 0x61, 0x64, 0x64, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x11, 0x62, 0x6c,
+	0x61, 0x63, 0x6b, 0x6c, 0x69, 0x73, 0x74, 0x45, 0x76, 0x6d, 0x41, 0x64, 0x64, 0x72, 0x73, 0x22,
+	0x11, 0x0a, 0x0f, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52,
+	0x65, 0x71, 0x22, 0x24, 0x0a, 0x10, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x52, 0x65, 0x73, 0x70, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x32, 0xa4, 0x06, 0x0a, 0x0d, 0x41, 0x74, 0x6f,
+	0x6d, 0x69, 0x63, 0x53, 0x77, 0x61, 0x70, 0x42, 0x6f, 0x74, 0x12, 0x2d, 0x0a, 0x04, 0x50, 0x69,
+	0x6e, 0x67, 0x12, 0x11, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x71, 0x1a, 0x12, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x12, 0x2f, 0x0a, 0x07, 0x47, 0x65, 0x74,
+	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x14, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e,
+	0x47, 0x65, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x1a, 0x0e, 0x2e, 0x61, 0x73, 0x62,
+	0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x41, 0x0a, 0x0d, 0x47, 0x65,
+	0x74, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x50, 0x6b, 0x68, 0x12, 0x1a, 0x2e, 0x61, 0x73,
+	0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76,
+	0x65, 0x50, 0x6b, 0x68, 0x52, 0x65, 0x71, 0x1a, 0x14, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e,
+	0x76, 0x31, 0x2e, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x50, 0x6b, 0x68, 0x12, 0x38, 0x0a,
+	0x09, 0x47, 0x65, 0x74, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x12, 0x16, 0x2e, 0x61, 0x73, 0x62,
+	0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x73, 0x52,
+	0x65, 0x71, 0x1a, 0x13, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x6f,
+	0x6b, 0x65, 0x6e, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x2f, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x51, 0x75,
+	0x6f, 0x74, 0x65, 0x12, 0x12, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x51,
+	0x75, 0x6f, 0x74, 0x65, 0x52, 0x65, 0x71, 0x1a, 0x0f, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e,
+	0x76, 0x31, 0x2e, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x12, 0x37, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74,
+	0x53, 0x77, 0x61, 0x70, 0x73, 0x12, 0x16, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x77, 0x61, 0x70, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x12, 0x2e,
+	0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x77, 0x61, 0x70, 0x4c, 0x69, 0x73,
+	0x74, 0x12, 0x3b, 0x0a, 0x0a, 0x47, 0x65, 0x74, 0x53, 0x77, 0x61, 0x70, 0x54, 0x78, 0x73, 0x12,
+	0x17, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x77,
+	0x61, 0x70, 0x54, 0x78, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x14, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74,
+	0x2e, 0x76, 0x31, 0x2e, 0x53, 0x77, 0x61, 0x70, 0x54, 0x78, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x32,
+	0x0a, 0x08, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x15, 0x2e, 0x61, 0x73, 0x62,
+	0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65,
+	0x71, 0x1a, 0x0f, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x12, 0x3e, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x53, 0x77, 0x61, 0x70, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x12, 0x19, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65,
+	0x74, 0x53, 0x77, 0x61, 0x70, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x13, 0x2e,
+	0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x77, 0x61, 0x70, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x12, 0x48, 0x0a, 0x10, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x77, 0x61, 0x70,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1d, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x77, 0x61, 0x70, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x13, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x53, 0x77, 0x61, 0x70, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12, 0x41, 0x0a, 0x0d,
+	0x47, 0x65, 0x74, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x1a, 0x2e,
+	0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x41, 0x63, 0x63, 0x65,
+	0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x14, 0x2e, 0x61, 0x73, 0x62, 0x6f,
+	0x74, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x12,
+	0x47, 0x0a, 0x10, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x4c,
+	0x69, 0x73, 0x74, 0x12, 0x1d, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x6c, 0x6f, 0x61, 0x64, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x52,
+	0x65, 0x71, 0x1a, 0x14, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x63,
+	0x63, 0x65, 0x73, 0x73, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x45, 0x0a, 0x0c, 0x52, 0x65, 0x6c, 0x6f,
+	0x61, 0x64, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x19, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74,
+	0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x52, 0x65, 0x71, 0x1a, 0x1a, 0x2e, 0x61, 0x73, 0x62, 0x6f, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x73, 0x70, 0x42,
+	0x2d, 0x5a, 0x2b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x6d,
+	0x61, 0x72, 0x74, 0x62, 0x63, 0x68, 0x2f, 0x61, 0x74, 0x6f, 0x6d, 0x69, 0x63, 0x2d, 0x73, 0x77,
+	0x61, 0x70, 0x2d, 0x62, 0x6f, 0x74, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_grpcapi_asbot_proto_rawDescOnce sync.Once
+	file_grpcapi_asbot_proto_rawDescData = file_grpcapi_asbot_proto_rawDesc
+)
+
+func file_grpcapi_asbot_proto_rawDescGZIP() []byte {
+	file_grpcapi_asbot_proto_rawDescOnce.Do(func() {
+		file_grpcapi_asbot_proto_rawDescData = protoimpl.X.CompressGZIP(file_grpcapi_asbot_proto_rawDescData)
+	})
+	return file_grpcapi_asbot_proto_rawDescData
+}
+
+var file_grpcapi_asbot_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
+var file_grpcapi_asbot_proto_goTypes = []interface{}{
+	(*PingReq)(nil),             // 0: asbot.v1.PingReq
+	(*PingResp)(nil),            // 1: asbot.v1.PingResp
+	(*GetInfoReq)(nil),          // 2: asbot.v1.GetInfoReq
+	(*Info)(nil),                // 3: asbot.v1.Info
+	(*SwapInfo)(nil),            // 4: asbot.v1.SwapInfo
+	(*GetReceivePkhReq)(nil),    // 5: asbot.v1.GetReceivePkhReq
+	(*ReceivePkh)(nil),          // 6: asbot.v1.ReceivePkh
+	(*GetTokensReq)(nil),        // 7: asbot.v1.GetTokensReq
+	(*TokenList)(nil),           // 8: asbot.v1.TokenList
+	(*Token)(nil),               // 9: asbot.v1.Token
+	(*QuoteReq)(nil),            // 10: asbot.v1.QuoteReq
+	(*Quote)(nil),               // 11: asbot.v1.Quote
+	(*ListSwapsReq)(nil),        // 12: asbot.v1.ListSwapsReq
+	(*SwapList)(nil),            // 13: asbot.v1.SwapList
+	(*SwapListItem)(nil),        // 14: asbot.v1.SwapListItem
+	(*GetSwapTxsReq)(nil),       // 15: asbot.v1.GetSwapTxsReq
+	(*SwapTxList)(nil),          // 16: asbot.v1.SwapTxList
+	(*SwapTx)(nil),              // 17: asbot.v1.SwapTx
+	(*GetStatsReq)(nil),         // 18: asbot.v1.GetStatsReq
+	(*Stats)(nil),               // 19: asbot.v1.Stats
+	(*GetSwapStatsReq)(nil),     // 20: asbot.v1.GetSwapStatsReq
+	(*SwapStats)(nil),           // 21: asbot.v1.SwapStats
+	(*DailySwapStats)(nil),      // 22: asbot.v1.DailySwapStats
+	(*DirectionStats)(nil),      // 23: asbot.v1.DirectionStats
+	(*StreamSwapEventsReq)(nil), // 24: asbot.v1.StreamSwapEventsReq
+	(*SwapEvent)(nil),           // 25: asbot.v1.SwapEvent
+	(*GetAccessListReq)(nil),    // 26: asbot.v1.GetAccessListReq
+	(*ReloadAccessListReq)(nil), // 27: asbot.v1.ReloadAccessListReq
+	(*AccessList)(nil),          // 28: asbot.v1.AccessList
+	(*ReloadConfigReq)(nil),     // 29: asbot.v1.ReloadConfigReq
+	(*ReloadConfigResp)(nil),    // 30: asbot.v1.ReloadConfigResp
+}
+var file_grpcapi_asbot_proto_depIdxs = []int32{
+	4,  // 0: asbot.v1.Info.s2b_swaps:type_name -> asbot.v1.SwapInfo
+	4,  // 1: asbot.v1.Info.b2s_swaps:type_name -> asbot.v1.SwapInfo
+	9,  // 2: asbot.v1.TokenList.tokens:type_name -> asbot.v1.Token
+	14, // 3: asbot.v1.SwapList.swaps:type_name -> asbot.v1.SwapListItem
+	17, // 4: asbot.v1.SwapTxList.txs:type_name -> asbot.v1.SwapTx
+	22, // 5: asbot.v1.SwapStats.daily:type_name -> asbot.v1.DailySwapStats
+	23, // 6: asbot.v1.SwapStats.bch2sbch:type_name -> asbot.v1.DirectionStats
+	23, // 7: asbot.v1.SwapStats.sbch2bch:type_name -> asbot.v1.DirectionStats
+	0,  // 8: asbot.v1.AtomicSwapBot.Ping:input_type -> asbot.v1.PingReq
+	2,  // 9: asbot.v1.AtomicSwapBot.GetInfo:input_type -> asbot.v1.GetInfoReq
+	5,  // 10: asbot.v1.AtomicSwapBot.GetReceivePkh:input_type -> asbot.v1.GetReceivePkhReq
+	7,  // 11: asbot.v1.AtomicSwapBot.GetTokens:input_type -> asbot.v1.GetTokensReq
+	10, // 12: asbot.v1.AtomicSwapBot.GetQuote:input_type -> asbot.v1.QuoteReq
+	12, // 13: asbot.v1.AtomicSwapBot.ListSwaps:input_type -> asbot.v1.ListSwapsReq
+	15, // 14: asbot.v1.AtomicSwapBot.GetSwapTxs:input_type -> asbot.v1.GetSwapTxsReq
+	18, // 15: asbot.v1.AtomicSwapBot.GetStats:input_type -> asbot.v1.GetStatsReq
+	20, // 16: asbot.v1.AtomicSwapBot.GetSwapStats:input_type -> asbot.v1.GetSwapStatsReq
+	24, // 17: asbot.v1.AtomicSwapBot.StreamSwapEvents:input_type -> asbot.v1.StreamSwapEventsReq
+	26, // 18: asbot.v1.AtomicSwapBot.GetAccessList:input_type -> asbot.v1.GetAccessListReq
+	27, // 19: asbot.v1.AtomicSwapBot.ReloadAccessList:input_type -> asbot.v1.ReloadAccessListReq
+	29, // 20: asbot.v1.AtomicSwapBot.ReloadConfig:input_type -> asbot.v1.ReloadConfigReq
+	1,  // 21: asbot.v1.AtomicSwapBot.Ping:output_type -> asbot.v1.PingResp
+	3,  // 22: asbot.v1.AtomicSwapBot.GetInfo:output_type -> asbot.v1.Info
+	6,  // 23: asbot.v1.AtomicSwapBot.GetReceivePkh:output_type -> asbot.v1.ReceivePkh
+	8,  // 24: asbot.v1.AtomicSwapBot.GetTokens:output_type -> asbot.v1.TokenList
+	11, // 25: asbot.v1.AtomicSwapBot.GetQuote:output_type -> asbot.v1.Quote
+	13, // 26: asbot.v1.AtomicSwapBot.ListSwaps:output_type -> asbot.v1.SwapList
+	16, // 27: asbot.v1.AtomicSwapBot.GetSwapTxs:output_type -> asbot.v1.SwapTxList
+	19, // 28: asbot.v1.AtomicSwapBot.GetStats:output_type -> asbot.v1.Stats
+	21, // 29: asbot.v1.AtomicSwapBot.GetSwapStats:output_type -> asbot.v1.SwapStats
+	25, // 30: asbot.v1.AtomicSwapBot.StreamSwapEvents:output_type -> asbot.v1.SwapEvent
+	28, // 31: asbot.v1.AtomicSwapBot.GetAccessList:output_type -> asbot.v1.AccessList
+	28, // 32: asbot.v1.AtomicSwapBot.ReloadAccessList:output_type -> asbot.v1.AccessList
+	30, // 33: asbot.v1.AtomicSwapBot.ReloadConfig:output_type -> asbot.v1.ReloadConfigResp
+	21, // [21:34] is the sub-list for method output_type
+	8,  // [8:21] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_grpcapi_asbot_proto_init() }
+func file_grpcapi_asbot_proto_init() {
+	if File_grpcapi_asbot_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_grpcapi_asbot_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PingReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PingResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetInfoReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Info); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SwapInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetReceivePkhReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReceivePkh); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetTokensReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TokenList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Token); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QuoteReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Quote); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListSwapsReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SwapList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SwapListItem); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetSwapTxsReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SwapTxList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SwapTx); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetStatsReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Stats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetSwapStatsReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SwapStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DailySwapStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DirectionStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamSwapEventsReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SwapEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetAccessListReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[27].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReloadAccessListReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[28].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AccessList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[29].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReloadConfigReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_grpcapi_asbot_proto_msgTypes[30].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReloadConfigResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_grpcapi_asbot_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   31,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_grpcapi_asbot_proto_goTypes,
+		DependencyIndexes: file_grpcapi_asbot_proto_depIdxs,
+		MessageInfos:      file_grpcapi_asbot_proto_msgTypes,
+	}.Build()
+	File_grpcapi_asbot_proto = out.File
+	file_grpcapi_asbot_proto_rawDesc = nil
+	file_grpcapi_asbot_proto_goTypes = nil
+	file_grpcapi_asbot_proto_depIdxs = nil
+}
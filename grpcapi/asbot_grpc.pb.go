@@ -0,0 +1,590 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: grpcapi/asbot.proto
+
+// gRPC API of atomic swap bot, it mirrors the HTTP API.
+// Generate Go code with:
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative grpcapi/asbot.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	AtomicSwapBot_Ping_FullMethodName             = "/asbot.v1.AtomicSwapBot/Ping"
+	AtomicSwapBot_GetInfo_FullMethodName          = "/asbot.v1.AtomicSwapBot/GetInfo"
+	AtomicSwapBot_GetReceivePkh_FullMethodName    = "/asbot.v1.AtomicSwapBot/GetReceivePkh"
+	AtomicSwapBot_GetTokens_FullMethodName        = "/asbot.v1.AtomicSwapBot/GetTokens"
+	AtomicSwapBot_GetQuote_FullMethodName         = "/asbot.v1.AtomicSwapBot/GetQuote"
+	AtomicSwapBot_ListSwaps_FullMethodName        = "/asbot.v1.AtomicSwapBot/ListSwaps"
+	AtomicSwapBot_GetSwapTxs_FullMethodName       = "/asbot.v1.AtomicSwapBot/GetSwapTxs"
+	AtomicSwapBot_GetStats_FullMethodName         = "/asbot.v1.AtomicSwapBot/GetStats"
+	AtomicSwapBot_GetSwapStats_FullMethodName     = "/asbot.v1.AtomicSwapBot/GetSwapStats"
+	AtomicSwapBot_StreamSwapEvents_FullMethodName = "/asbot.v1.AtomicSwapBot/StreamSwapEvents"
+	AtomicSwapBot_GetAccessList_FullMethodName    = "/asbot.v1.AtomicSwapBot/GetAccessList"
+	AtomicSwapBot_ReloadAccessList_FullMethodName = "/asbot.v1.AtomicSwapBot/ReloadAccessList"
+	AtomicSwapBot_ReloadConfig_FullMethodName     = "/asbot.v1.AtomicSwapBot/ReloadConfig"
+)
+
+// AtomicSwapBotClient is the client API for AtomicSwapBot service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AtomicSwapBotClient interface {
+	Ping(ctx context.Context, in *PingReq, opts ...grpc.CallOption) (*PingResp, error)
+	GetInfo(ctx context.Context, in *GetInfoReq, opts ...grpc.CallOption) (*Info, error)
+	GetReceivePkh(ctx context.Context, in *GetReceivePkhReq, opts ...grpc.CallOption) (*ReceivePkh, error)
+	GetTokens(ctx context.Context, in *GetTokensReq, opts ...grpc.CallOption) (*TokenList, error)
+	GetQuote(ctx context.Context, in *QuoteReq, opts ...grpc.CallOption) (*Quote, error)
+	ListSwaps(ctx context.Context, in *ListSwapsReq, opts ...grpc.CallOption) (*SwapList, error)
+	GetSwapTxs(ctx context.Context, in *GetSwapTxsReq, opts ...grpc.CallOption) (*SwapTxList, error)
+	GetStats(ctx context.Context, in *GetStatsReq, opts ...grpc.CallOption) (*Stats, error)
+	GetSwapStats(ctx context.Context, in *GetSwapStatsReq, opts ...grpc.CallOption) (*SwapStats, error)
+	// streams events found by chain watchers, see EventXxx of package bot
+	StreamSwapEvents(ctx context.Context, in *StreamSwapEventsReq, opts ...grpc.CallOption) (AtomicSwapBot_StreamSwapEventsClient, error)
+	// admin, "authorization: Bearer <admin token|API key|JWT>" metadata is required
+	GetAccessList(ctx context.Context, in *GetAccessListReq, opts ...grpc.CallOption) (*AccessList, error)
+	ReloadAccessList(ctx context.Context, in *ReloadAccessListReq, opts ...grpc.CallOption) (*AccessList, error)
+	ReloadConfig(ctx context.Context, in *ReloadConfigReq, opts ...grpc.CallOption) (*ReloadConfigResp, error)
+}
+
+type atomicSwapBotClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAtomicSwapBotClient(cc grpc.ClientConnInterface) AtomicSwapBotClient {
+	return &atomicSwapBotClient{cc}
+}
+
+func (c *atomicSwapBotClient) Ping(ctx context.Context, in *PingReq, opts ...grpc.CallOption) (*PingResp, error) {
+	out := new(PingResp)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_Ping_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) GetInfo(ctx context.Context, in *GetInfoReq, opts ...grpc.CallOption) (*Info, error) {
+	out := new(Info)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_GetInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) GetReceivePkh(ctx context.Context, in *GetReceivePkhReq, opts ...grpc.CallOption) (*ReceivePkh, error) {
+	out := new(ReceivePkh)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_GetReceivePkh_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) GetTokens(ctx context.Context, in *GetTokensReq, opts ...grpc.CallOption) (*TokenList, error) {
+	out := new(TokenList)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_GetTokens_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) GetQuote(ctx context.Context, in *QuoteReq, opts ...grpc.CallOption) (*Quote, error) {
+	out := new(Quote)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_GetQuote_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) ListSwaps(ctx context.Context, in *ListSwapsReq, opts ...grpc.CallOption) (*SwapList, error) {
+	out := new(SwapList)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_ListSwaps_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) GetSwapTxs(ctx context.Context, in *GetSwapTxsReq, opts ...grpc.CallOption) (*SwapTxList, error) {
+	out := new(SwapTxList)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_GetSwapTxs_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) GetStats(ctx context.Context, in *GetStatsReq, opts ...grpc.CallOption) (*Stats, error) {
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_GetStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) GetSwapStats(ctx context.Context, in *GetSwapStatsReq, opts ...grpc.CallOption) (*SwapStats, error) {
+	out := new(SwapStats)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_GetSwapStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) StreamSwapEvents(ctx context.Context, in *StreamSwapEventsReq, opts ...grpc.CallOption) (AtomicSwapBot_StreamSwapEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AtomicSwapBot_ServiceDesc.Streams[0], AtomicSwapBot_StreamSwapEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &atomicSwapBotStreamSwapEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AtomicSwapBot_StreamSwapEventsClient interface {
+	Recv() (*SwapEvent, error)
+	grpc.ClientStream
+}
+
+type atomicSwapBotStreamSwapEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *atomicSwapBotStreamSwapEventsClient) Recv() (*SwapEvent, error) {
+	m := new(SwapEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *atomicSwapBotClient) GetAccessList(ctx context.Context, in *GetAccessListReq, opts ...grpc.CallOption) (*AccessList, error) {
+	out := new(AccessList)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_GetAccessList_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) ReloadAccessList(ctx context.Context, in *ReloadAccessListReq, opts ...grpc.CallOption) (*AccessList, error) {
+	out := new(AccessList)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_ReloadAccessList_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *atomicSwapBotClient) ReloadConfig(ctx context.Context, in *ReloadConfigReq, opts ...grpc.CallOption) (*ReloadConfigResp, error) {
+	out := new(ReloadConfigResp)
+	err := c.cc.Invoke(ctx, AtomicSwapBot_ReloadConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AtomicSwapBotServer is the server API for AtomicSwapBot service.
+// All implementations must embed UnimplementedAtomicSwapBotServer
+// for forward compatibility
+type AtomicSwapBotServer interface {
+	Ping(context.Context, *PingReq) (*PingResp, error)
+	GetInfo(context.Context, *GetInfoReq) (*Info, error)
+	GetReceivePkh(context.Context, *GetReceivePkhReq) (*ReceivePkh, error)
+	GetTokens(context.Context, *GetTokensReq) (*TokenList, error)
+	GetQuote(context.Context, *QuoteReq) (*Quote, error)
+	ListSwaps(context.Context, *ListSwapsReq) (*SwapList, error)
+	GetSwapTxs(context.Context, *GetSwapTxsReq) (*SwapTxList, error)
+	GetStats(context.Context, *GetStatsReq) (*Stats, error)
+	GetSwapStats(context.Context, *GetSwapStatsReq) (*SwapStats, error)
+	// streams events found by chain watchers, see EventXxx of package bot
+	StreamSwapEvents(*StreamSwapEventsReq, AtomicSwapBot_StreamSwapEventsServer) error
+	// admin, "authorization: Bearer <admin token|API key|JWT>" metadata is required
+	GetAccessList(context.Context, *GetAccessListReq) (*AccessList, error)
+	ReloadAccessList(context.Context, *ReloadAccessListReq) (*AccessList, error)
+	ReloadConfig(context.Context, *ReloadConfigReq) (*ReloadConfigResp, error)
+	mustEmbedUnimplementedAtomicSwapBotServer()
+}
+
+// UnimplementedAtomicSwapBotServer must be embedded to have forward compatible implementations.
+type UnimplementedAtomicSwapBotServer struct {
+}
+
+func (UnimplementedAtomicSwapBotServer) Ping(context.Context, *PingReq) (*PingResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) GetInfo(context.Context, *GetInfoReq) (*Info, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInfo not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) GetReceivePkh(context.Context, *GetReceivePkhReq) (*ReceivePkh, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReceivePkh not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) GetTokens(context.Context, *GetTokensReq) (*TokenList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTokens not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) GetQuote(context.Context, *QuoteReq) (*Quote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuote not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) ListSwaps(context.Context, *ListSwapsReq) (*SwapList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSwaps not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) GetSwapTxs(context.Context, *GetSwapTxsReq) (*SwapTxList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSwapTxs not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) GetStats(context.Context, *GetStatsReq) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) GetSwapStats(context.Context, *GetSwapStatsReq) (*SwapStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSwapStats not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) StreamSwapEvents(*StreamSwapEventsReq, AtomicSwapBot_StreamSwapEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamSwapEvents not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) GetAccessList(context.Context, *GetAccessListReq) (*AccessList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccessList not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) ReloadAccessList(context.Context, *ReloadAccessListReq) (*AccessList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadAccessList not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) ReloadConfig(context.Context, *ReloadConfigReq) (*ReloadConfigResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadConfig not implemented")
+}
+func (UnimplementedAtomicSwapBotServer) mustEmbedUnimplementedAtomicSwapBotServer() {}
+
+// UnsafeAtomicSwapBotServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AtomicSwapBotServer will
+// result in compilation errors.
+type UnsafeAtomicSwapBotServer interface {
+	mustEmbedUnimplementedAtomicSwapBotServer()
+}
+
+func RegisterAtomicSwapBotServer(s grpc.ServiceRegistrar, srv AtomicSwapBotServer) {
+	s.RegisterService(&AtomicSwapBot_ServiceDesc, srv)
+}
+
+func _AtomicSwapBot_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).Ping(ctx, req.(*PingReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_GetInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInfoReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).GetInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_GetInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).GetInfo(ctx, req.(*GetInfoReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_GetReceivePkh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReceivePkhReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).GetReceivePkh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_GetReceivePkh_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).GetReceivePkh(ctx, req.(*GetReceivePkhReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_GetTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTokensReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).GetTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_GetTokens_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).GetTokens(ctx, req.(*GetTokensReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_GetQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuoteReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).GetQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_GetQuote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).GetQuote(ctx, req.(*QuoteReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_ListSwaps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSwapsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).ListSwaps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_ListSwaps_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).ListSwaps(ctx, req.(*ListSwapsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_GetSwapTxs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSwapTxsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).GetSwapTxs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_GetSwapTxs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).GetSwapTxs(ctx, req.(*GetSwapTxsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).GetStats(ctx, req.(*GetStatsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_GetSwapStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSwapStatsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).GetSwapStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_GetSwapStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).GetSwapStats(ctx, req.(*GetSwapStatsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_StreamSwapEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamSwapEventsReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AtomicSwapBotServer).StreamSwapEvents(m, &atomicSwapBotStreamSwapEventsServer{stream})
+}
+
+type AtomicSwapBot_StreamSwapEventsServer interface {
+	Send(*SwapEvent) error
+	grpc.ServerStream
+}
+
+type atomicSwapBotStreamSwapEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *atomicSwapBotStreamSwapEventsServer) Send(m *SwapEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AtomicSwapBot_GetAccessList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccessListReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).GetAccessList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_GetAccessList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).GetAccessList(ctx, req.(*GetAccessListReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_ReloadAccessList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadAccessListReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).ReloadAccessList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_ReloadAccessList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).ReloadAccessList(ctx, req.(*ReloadAccessListReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AtomicSwapBot_ReloadConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadConfigReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicSwapBotServer).ReloadConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AtomicSwapBot_ReloadConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicSwapBotServer).ReloadConfig(ctx, req.(*ReloadConfigReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AtomicSwapBot_ServiceDesc is the grpc.ServiceDesc for AtomicSwapBot service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AtomicSwapBot_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "asbot.v1.AtomicSwapBot",
+	HandlerType: (*AtomicSwapBotServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ping",
+			Handler:    _AtomicSwapBot_Ping_Handler,
+		},
+		{
+			MethodName: "GetInfo",
+			Handler:    _AtomicSwapBot_GetInfo_Handler,
+		},
+		{
+			MethodName: "GetReceivePkh",
+			Handler:    _AtomicSwapBot_GetReceivePkh_Handler,
+		},
+		{
+			MethodName: "GetTokens",
+			Handler:    _AtomicSwapBot_GetTokens_Handler,
+		},
+		{
+			MethodName: "GetQuote",
+			Handler:    _AtomicSwapBot_GetQuote_Handler,
+		},
+		{
+			MethodName: "ListSwaps",
+			Handler:    _AtomicSwapBot_ListSwaps_Handler,
+		},
+		{
+			MethodName: "GetSwapTxs",
+			Handler:    _AtomicSwapBot_GetSwapTxs_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _AtomicSwapBot_GetStats_Handler,
+		},
+		{
+			MethodName: "GetSwapStats",
+			Handler:    _AtomicSwapBot_GetSwapStats_Handler,
+		},
+		{
+			MethodName: "GetAccessList",
+			Handler:    _AtomicSwapBot_GetAccessList_Handler,
+		},
+		{
+			MethodName: "ReloadAccessList",
+			Handler:    _AtomicSwapBot_ReloadAccessList_Handler,
+		},
+		{
+			MethodName: "ReloadConfig",
+			Handler:    _AtomicSwapBot_ReloadConfig_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSwapEvents",
+			Handler:       _AtomicSwapBot_StreamSwapEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "grpcapi/asbot.proto",
+}
@@ -0,0 +1,84 @@
+package htlcbch
+
+import (
+	"fmt"
+
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+
+	"github.com/smartbch/atomic-swap-bot/htlcmath"
+)
+
+// BatchReceipt is a covenant output to be claimed by MakeBatchUnlockTx
+type BatchReceipt struct {
+	Covenant *HtlcCovenant
+	TxID     []byte
+	Vout     uint32
+	InAmt    int64
+	Secret   []byte
+}
+
+// MakeBatchUnlockTx claims several covenants in one tx, input#i pays output#i to the recipient of
+// receipts[i], and the miner fee is shared by all inputs. All covenants must be batchable,
+// see HtlcCovenant.CanBeBatched(). lockTime is for anti-fee-sniping, 0 means none.
+func MakeBatchUnlockTx(
+	receipts []BatchReceipt,
+	minerFeeRate uint64,
+	lockTime uint32,
+) (*wire.MsgTx, error) {
+	// estimate miner fee, tx size does not depend on it
+	tx, err := makeBatchUnlockTx(receipts, 0, lockTime)
+	if err != nil {
+		return nil, err
+	}
+	// make tx
+	minerFee, err := htlcmath.MinerFee(len(MsgTxToBytes(tx)), minerFeeRate)
+	if err != nil {
+		return nil, err
+	}
+	n := uint64(len(receipts))
+	return makeBatchUnlockTx(receipts, (minerFee+n-1)/n, lockTime)
+}
+
+func makeBatchUnlockTx(
+	receipts []BatchReceipt,
+	feePerInput uint64,
+	lockTime uint32,
+) (*wire.MsgTx, error) {
+
+	if len(receipts) == 0 {
+		return nil, fmt.Errorf("no receipts")
+	}
+
+	builder := newMsgTxBuilder().useLockTime(lockTime)
+	for i, receipt := range receipts {
+		c := receipt.Covenant
+		if !c.CanBeBatched() {
+			return nil, fmt.Errorf("receipt#%d can not be batched: %s", i, c.String())
+		}
+		if len(receipt.Secret) != 32 {
+			return nil, fmt.Errorf("secret of receipt#%d is not 32 bytes", i)
+		}
+
+		sigScript, err := c.BuildUnlockSigScript(receipt.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		toAddr, err := bchutil.NewAddressPubKeyHash(c.recipientPkh, c.net)
+		if err != nil {
+			return nil, err
+		}
+
+		split, err := getReceiptSplit(receipt.InAmt, func(value uint64) (htlcmath.ReceiptSplit, error) {
+			return htlcmath.UnlockSplit(value, feePerInput)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("receipt#%d: %w", i, err)
+		}
+
+		builder.addInput(receipt.TxID, receipt.Vout, 0, sigScript).
+			addOutput(toAddr, int64(split.ToRecipient))
+	}
+	return builder.build()
+}
@@ -0,0 +1,197 @@
+package htlcbch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+)
+
+// HtlcSpec describes an HTLC output regardless of the chain it is on
+type HtlcSpec struct {
+	SenderPkh    []byte // 20 bytes, refunded to
+	RecipientPkh []byte // 20 bytes, claimed by
+	HashLock     []byte // 32 bytes, sha256
+	Expiration   uint16 // in blocks
+	ExpiresAt    uint32 // unix timestamp of absolute expiration, used instead of Expiration if not 0
+	PenaltyBPS   uint16 // paid to recipient on refund
+	ReferralPkh  []byte // 20 bytes, optional, paid ReferralBPS of the value on claim
+	ReferralBPS  uint16 // 0 means no referral
+	Template     string // version of the covenant template, empty means the current one
+}
+
+// HtlcClaim is an HTLC output to be claimed with the secret, see ChainAdapter.BuildBatchClaim
+type HtlcClaim struct {
+	Htlc   *HtlcSpec
+	TxID   []byte
+	Vout   uint32
+	InAmt  int64
+	Secret []byte
+}
+
+// BlockScan holds the HTLC txs found in a block
+type BlockScan struct {
+	Deposits []*HtlcLockInfo
+	Receipts []*HtlcUnlockInfo
+	Suspects []*SuspectTx // txs which look like HTLC txs but are not valid ones
+	SbasTxs  []*SbasTx    // all txs carrying the SBAS protocol ID, only with DepositFilter.Diagnose
+}
+
+// ChainAdapter is what the swap engine needs from a UTXO chain with HTLC covenants.
+// ChainParams implements it for BCH networks, other chains with compatible script
+// capabilities can be supported by implementing it, without touching the engine.
+type ChainAdapter interface {
+	ChainName() string
+
+	// ScanBlock finds HTLC deposits (those not passing filter are dropped, nil means the defaults) and receipts
+	ScanBlock(block *btcjson.GetBlockVerboseTxResult, filter *DepositFilter) *BlockScan
+
+	// ScriptHash returns the hash of the HTLC redeem script, which is recorded with swaps
+	ScriptHash(htlc *HtlcSpec) ([]byte, error)
+
+	// BuildLock funds the HTLC with outAmt from P2PKH inputs of fromKey,
+	// the change goes to changeAddr, or back to fromKey if it is nil
+	BuildLock(htlc *HtlcSpec, fromKey *bchec.PrivateKey, inputs []InputInfo, outAmt int64, changeAddr bchutil.Address,
+		minerFeeRate uint64, sigType SigType, lockTime uint32) (*wire.MsgTx, error)
+
+	// BuildClaim spends the HTLC output to its recipient with the secret
+	BuildClaim(htlc *HtlcSpec, txid []byte, vout uint32, inAmt int64,
+		minerFeeRate uint64, secret []byte, lockTime uint32) (*wire.MsgTx, error)
+
+	// CanBatchClaim returns true if the HTLC output can be claimed with others by BuildBatchClaim
+	CanBatchClaim(htlc *HtlcSpec) bool
+
+	// BuildBatchClaim spends several HTLC outputs in one tx, each to its recipient, sharing the miner fee
+	BuildBatchClaim(claims []HtlcClaim, minerFeeRate uint64, lockTime uint32) (*wire.MsgTx, error)
+
+	// BuildRefund spends the expired HTLC output back to its sender, minus the penalty
+	BuildRefund(htlc *HtlcSpec, txid []byte, vout uint32, inAmt int64,
+		minerFeeRate uint64, lockTime uint32) (*wire.MsgTx, error)
+
+	// lockTime of the Build methods is for anti-fee-sniping, usually the current height, 0 means none
+
+	// VerifyLock checks that a deposit found by ScanBlock funds the HTLC with value
+	VerifyLock(deposit *HtlcLockInfo, htlc *HtlcSpec, value uint64) error
+}
+
+var _ ChainAdapter = (*ChainParams)(nil)
+
+func (p *ChainParams) ChainName() string {
+	return "bch-" + p.Name
+}
+
+func (p *ChainParams) ScanBlock(block *btcjson.GetBlockVerboseTxResult, filter *DepositFilter) *BlockScan {
+	deposits, lockSuspects := getHtlcLocksInfo(block, p, filter)
+	receipts, unlockSuspects := getHtlcUnlocksInfo(block)
+	scan := &BlockScan{
+		Deposits: deposits,
+		Receipts: receipts,
+		Suspects: append(lockSuspects, unlockSuspects...),
+	}
+	if filter.diagnose() {
+		scan.SbasTxs = getSbasTxs(block, p, filter)
+	}
+	return scan
+}
+
+func (p *ChainParams) newCovenantOf(htlc *HtlcSpec) (*HtlcCovenant, error) {
+	template, err := GetCovenantTemplate(htlc.Template)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.NewCovenant(htlc.SenderPkh, htlc.RecipientPkh, htlc.HashLock, htlc.Expiration, htlc.PenaltyBPS)
+	if err != nil {
+		return nil, err
+	}
+	c = c.WithTemplate(template)
+	if htlc.ExpiresAt != 0 {
+		if c, err = c.WithExpiresAt(htlc.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+	if htlc.ReferralBPS == 0 {
+		return c, nil
+	}
+	return c.WithReferral(htlc.ReferralPkh, htlc.ReferralBPS)
+}
+
+func (p *ChainParams) ScriptHash(htlc *HtlcSpec) ([]byte, error) {
+	covenant, err := p.newCovenantOf(htlc)
+	if err != nil {
+		return nil, err
+	}
+	return covenant.GetRedeemScriptHash()
+}
+
+func (p *ChainParams) BuildLock(htlc *HtlcSpec, fromKey *bchec.PrivateKey, inputs []InputInfo, outAmt int64,
+	changeAddr bchutil.Address, minerFeeRate uint64, sigType SigType, lockTime uint32) (*wire.MsgTx, error) {
+
+	covenant, err := p.newCovenantOf(htlc)
+	if err != nil {
+		return nil, err
+	}
+	return covenant.WithSigType(sigType).WithLockTime(lockTime).WithChangeAddr(changeAddr).
+		MakeLockTx(fromKey, inputs, outAmt, minerFeeRate)
+}
+
+func (p *ChainParams) BuildClaim(htlc *HtlcSpec, txid []byte, vout uint32, inAmt int64,
+	minerFeeRate uint64, secret []byte, lockTime uint32) (*wire.MsgTx, error) {
+
+	covenant, err := p.newCovenantOf(htlc)
+	if err != nil {
+		return nil, err
+	}
+	return covenant.WithLockTime(lockTime).MakeUnlockTx(txid, vout, inAmt, minerFeeRate, secret)
+}
+
+func (p *ChainParams) CanBatchClaim(htlc *HtlcSpec) bool {
+	covenant, err := p.newCovenantOf(htlc)
+	return err == nil && covenant.CanBeBatched()
+}
+
+func (p *ChainParams) BuildBatchClaim(claims []HtlcClaim, minerFeeRate uint64, lockTime uint32) (*wire.MsgTx, error) {
+	receipts := make([]BatchReceipt, len(claims))
+	for i, claim := range claims {
+		covenant, err := p.newCovenantOf(claim.Htlc)
+		if err != nil {
+			return nil, err
+		}
+		receipts[i] = BatchReceipt{
+			Covenant: covenant,
+			TxID:     claim.TxID,
+			Vout:     claim.Vout,
+			InAmt:    claim.InAmt,
+			Secret:   claim.Secret,
+		}
+	}
+	return MakeBatchUnlockTx(receipts, minerFeeRate, lockTime)
+}
+
+func (p *ChainParams) BuildRefund(htlc *HtlcSpec, txid []byte, vout uint32, inAmt int64,
+	minerFeeRate uint64, lockTime uint32) (*wire.MsgTx, error) {
+
+	covenant, err := p.newCovenantOf(htlc)
+	if err != nil {
+		return nil, err
+	}
+	return covenant.WithLockTime(lockTime).MakeRefundTx(txid, vout, inAmt, minerFeeRate)
+}
+
+func (p *ChainParams) VerifyLock(deposit *HtlcLockInfo, htlc *HtlcSpec, value uint64) error {
+	scriptHash, err := p.ScriptHash(htlc)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(deposit.ScriptHash, scriptHash) {
+		return fmt.Errorf("script hash not match: %s != %s",
+			hex.EncodeToString(deposit.ScriptHash), hex.EncodeToString(scriptHash))
+	}
+	if deposit.Value != value {
+		return fmt.Errorf("value not match: %d != %d", deposit.Value, value)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package htlcbch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/wire"
+)
+
+func TestChainAdapter(t *testing.T) {
+	var adapter ChainAdapter = TestNet3
+	require.Equal(t, "bch-"+TestNet3.Name, adapter.ChainName())
+
+	htlc := &HtlcSpec{
+		SenderPkh:    testSenderPkh,
+		RecipientPkh: testRecipientPkh,
+		HashLock:     testSecretHash,
+		Expiration:   testExpiration,
+		PenaltyBPS:   testPenaltyBPS,
+	}
+	c, err := NewTestnet3Covenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+
+	scriptHash, err := adapter.ScriptHash(htlc)
+	require.NoError(t, err)
+	scriptHash2, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	require.Equal(t, scriptHash2, scriptHash)
+
+	_, err = adapter.ScriptHash(&HtlcSpec{SenderPkh: testSenderPkh})
+	require.Error(t, err)
+
+	// absolute expiration
+	c2, err := c.WithExpiresAt(1700000000)
+	require.NoError(t, err)
+	absScriptHash2, err := c2.GetRedeemScriptHash()
+	require.NoError(t, err)
+	htlc2 := *htlc
+	htlc2.ExpiresAt = 1700000000
+	absScriptHash, err := adapter.ScriptHash(&htlc2)
+	require.NoError(t, err)
+	require.Equal(t, absScriptHash2, absScriptHash)
+	require.NotEqual(t, scriptHash, absScriptHash)
+	htlc2.ExpiresAt = 1700
+	_, err = adapter.ScriptHash(&htlc2)
+	require.Error(t, err)
+
+	inputs := []InputInfo{
+		{
+			TxID:   gethcmn.Hash{'t', 'x', 'i', 'd'}.Bytes(),
+			Vout:   uint32(1),
+			Amount: int64(20000),
+		},
+	}
+	tx, err := adapter.BuildLock(htlc, testSenderWIF.PrivKey, inputs, 10000, nil, 2, SigTypeECDSA, 0)
+	require.NoError(t, err)
+	tx2, err := c.MakeLockTx(testSenderWIF.PrivKey, inputs, 10000, 2)
+	require.NoError(t, err)
+	require.Equal(t, MsgTxToHex(tx2), MsgTxToHex(tx))
+
+	txid := gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes()
+	tx, err = adapter.BuildClaim(htlc, txid, 1, 100000000, 2, testSecretKey, 0)
+	require.NoError(t, err)
+	tx2, err = c.MakeUnlockTx(txid, 1, 100000000, 2, testSecretKey)
+	require.NoError(t, err)
+	require.Equal(t, MsgTxToHex(tx2), MsgTxToHex(tx))
+
+	tx, err = adapter.BuildRefund(htlc, txid, 1, 100000000, 3, 0)
+	require.NoError(t, err)
+	tx2, err = c.MakeRefundTx(txid, 1, 100000000, 3)
+	require.NoError(t, err)
+	require.Equal(t, MsgTxToHex(tx2), MsgTxToHex(tx))
+
+	// batch claims
+	require.False(t, adapter.CanBatchClaim(htlc))
+	batchable := *htlc
+	batchable.Template = "v2"
+	require.True(t, adapter.CanBatchClaim(&batchable))
+	scriptHash2, err = adapter.ScriptHash(&batchable)
+	require.NoError(t, err)
+	require.NotEqual(t, scriptHash, scriptHash2)
+	claims := []HtlcClaim{{Htlc: &batchable, TxID: txid, Vout: 1, InAmt: 100000000, Secret: testSecretKey}}
+	tx, err = adapter.BuildBatchClaim(claims, 2, 800000)
+	require.NoError(t, err)
+	tx2, err = MakeBatchUnlockTx([]BatchReceipt{{Covenant: c.WithBatchable(), TxID: txid, Vout: 1, InAmt: 100000000,
+		Secret: testSecretKey}}, 2, 800000)
+	require.NoError(t, err)
+	require.Equal(t, MsgTxToHex(tx2), MsgTxToHex(tx))
+	require.Equal(t, uint32(800000), tx.LockTime)
+	claims[0].Htlc = htlc
+	_, err = adapter.BuildBatchClaim(claims, 2, 0)
+	require.ErrorContains(t, err, "can not be batched")
+	// anti-fee-sniping
+	tx, err = adapter.BuildRefund(htlc, txid, 1, 100000000, 3, 800000)
+	require.NoError(t, err)
+	require.Equal(t, uint32(800000), tx.LockTime)
+	require.Equal(t, uint32(htlc.Expiration), tx.TxIn[0].Sequence)
+	tx, err = adapter.BuildClaim(htlc, txid, 1, 100000000, 2, testSecretKey, 800000)
+	require.NoError(t, err)
+	require.Equal(t, uint32(800000), tx.LockTime)
+	require.Equal(t, uint32(wire.MaxTxInSequenceNum-1), tx.TxIn[0].Sequence)
+
+	deposit := &HtlcLockInfo{ScriptHash: scriptHash, Value: 10000}
+	require.NoError(t, adapter.VerifyLock(deposit, htlc, 10000))
+	require.ErrorContains(t, adapter.VerifyLock(deposit, htlc, 10001), "value not match")
+	deposit.ScriptHash = testSenderPkh
+	require.ErrorContains(t, adapter.VerifyLock(deposit, htlc, 10000), "script hash not match")
+
+	scan := adapter.ScanBlock(&btcjson.GetBlockVerboseTxResult{}, nil)
+	require.Len(t, scan.Deposits, 0)
+	require.Len(t, scan.Receipts, 0)
+}
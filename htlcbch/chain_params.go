@@ -0,0 +1,154 @@
+package htlcbch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+)
+
+// ChainParams selects the BCH network used to build covenants, encode addresses and parse txs.
+type ChainParams struct {
+	Name string
+	Net  *chaincfg.Params
+
+	// no SBAS tx was mined before this block, bootstrap scans start here, 0 means from genesis
+	FirstSbasHeight int64
+}
+
+var (
+	// a conservative lower bound (end of 2022), starting earlier only costs scanning time
+	MainNet  = &ChainParams{Name: "mainnet", Net: &chaincfg.MainNetParams, FirstSbasHeight: 770000}
+	TestNet3 = &ChainParams{Name: "testnet3", Net: &chaincfg.TestNet3Params}
+	TestNet4 = &ChainParams{Name: "testnet4", Net: &chaincfg.TestNet4Params}
+	ChipNet  = &ChainParams{Name: "chipnet", Net: newChipNetParams()}
+	RegTest  = &ChainParams{Name: "regtest", Net: &chaincfg.RegressionNetParams}
+
+	allChainParams = []*ChainParams{MainNet, TestNet3, TestNet4, ChipNet, RegTest}
+)
+
+// chipnet shares address encoding with testnet4
+func newChipNetParams() *chaincfg.Params {
+	params := chaincfg.TestNet4Params
+	params.Name = "chipnet"
+	return &params
+}
+
+func GetChainParams(name string) (*ChainParams, error) {
+	for _, params := range allChainParams {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+
+	names := make([]string, len(allChainParams))
+	for i, params := range allChainParams {
+		names[i] = params.Name
+	}
+	return nil, fmt.Errorf("unknown BCH network: %s, supported networks: %s",
+		name, strings.Join(names, ", "))
+}
+
+func (p *ChainParams) NewCovenant(
+	senderPkh, recipientPkh, hashLock []byte, expiration, penaltyBPS uint16,
+) (*HtlcCovenant, error) {
+
+	return NewCovenant(senderPkh, recipientPkh, hashLock, expiration, penaltyBPS, p.Net)
+}
+
+func (p *ChainParams) NewCovenantWithHashType(
+	senderPkh, recipientPkh, hashLock []byte, hashType HashType, expiration, penaltyBPS uint16,
+) (*HtlcCovenant, error) {
+
+	return NewCovenantWithHashType(senderPkh, recipientPkh, hashLock, hashType, expiration, penaltyBPS, p.Net)
+}
+
+// NewCovenantOfDeposit makes the covenant described by the OP_RETURN of a deposit, split if it has a referral,
+// with the template version matched by the parser (the current one if not parsed yet)
+func (p *ChainParams) NewCovenantOfDeposit(deposit *HtlcLockInfo) (*HtlcCovenant, error) {
+	template, err := GetCovenantTemplate(deposit.Template)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.NewCovenantWithHashType(deposit.SenderPkh, deposit.RecipientPkh,
+		deposit.HashLock, deposit.HashType, deposit.Expiration, deposit.PenaltyBPS)
+	if err != nil {
+		return nil, err
+	}
+	c = c.WithTemplate(template)
+	if deposit.ExpirationType == ExpirationTypeTimestamp {
+		c, err = c.WithExpiresAt(deposit.ExpiresAt)
+	} else {
+		c, err = c.WithExpirationType(deposit.ExpirationType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(deposit.ReferralPkh) == 0 {
+		return c, nil
+	}
+	return c.WithReferral(deposit.ReferralPkh, deposit.ReferralBPS)
+}
+
+func (p *ChainParams) MakePayTx(
+	fromKey *bchec.PrivateKey, inputs []InputInfo, toPkh []byte, outAmt int64, minerFeeRate uint64,
+) (*wire.MsgTx, error) {
+
+	return MakePayTx(fromKey, inputs, toPkh, outAmt, minerFeeRate, p.Net)
+}
+
+func (p *ChainParams) MakePayTxWithSigType(
+	fromKey *bchec.PrivateKey, inputs []InputInfo, toPkh []byte, outAmt int64, minerFeeRate uint64, sigType SigType,
+	lockTime uint32,
+) (*wire.MsgTx, error) {
+
+	return MakePayTxWithSigType(fromKey, inputs, toPkh, outAmt, minerFeeRate, sigType, lockTime, p.Net)
+}
+
+func (p *ChainParams) NewP2PKHAddress(pkh []byte) (*bchutil.AddressPubKeyHash, error) {
+	return bchutil.NewAddressPubKeyHash(pkh, p.Net)
+}
+
+// decode a cash address (with or without prefix) and make sure it is P2PKH
+func (p *ChainParams) DecodeP2PKHAddress(addr string) (*bchutil.AddressPubKeyHash, error) {
+	decoded, err := bchutil.DecodeAddress(addr, p.Net)
+	if err != nil {
+		return nil, err
+	}
+	if !decoded.IsForNet(p.Net) {
+		return nil, fmt.Errorf("address is not for %s: %s", p.Name, addr)
+	}
+	p2pkh, ok := decoded.(*bchutil.AddressPubKeyHash)
+	if !ok {
+		return nil, fmt.Errorf("not P2PKH address: %s", addr)
+	}
+	return p2pkh, nil
+}
+
+func (p *ChainParams) GetHtlcLocksInfo(block *btcjson.GetBlockVerboseTxResult) []*HtlcLockInfo {
+	deposits, _ := getHtlcLocksInfo(block, p, nil)
+	return deposits
+}
+
+// GetHtlcLocksInfoAboveFloor drops deposits below the floor, see DepositFloor
+func (p *ChainParams) GetHtlcLocksInfoAboveFloor(
+	block *btcjson.GetBlockVerboseTxResult, floor *DepositFloor,
+) []*HtlcLockInfo {
+
+	deposits, _ := getHtlcLocksInfo(block, p, &DepositFilter{Floor: floor})
+	return deposits
+}
+
+// GetHtlcLockInfo returns nil if tx is not a valid HTLC lock tx
+func (p *ChainParams) GetHtlcLockInfo(tx btcjson.TxRawResult) *HtlcLockInfo {
+	return isHtlcLockTx(tx, p, nil)
+}
+
+// GetHtlcLockInfoWithFilter is GetHtlcLockInfo with a DepositFilter, e.g. to find deposits in any output order
+func (p *ChainParams) GetHtlcLockInfoWithFilter(tx btcjson.TxRawResult, filter *DepositFilter) *HtlcLockInfo {
+	return isHtlcLockTx(tx, p, filter)
+}
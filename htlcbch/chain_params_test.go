@@ -0,0 +1,45 @@
+package htlcbch
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetChainParams(t *testing.T) {
+	for _, name := range []string{"mainnet", "testnet3", "testnet4", "chipnet", "regtest"} {
+		params, err := GetChainParams(name)
+		require.NoError(t, err)
+		require.Equal(t, name, params.Name)
+	}
+
+	_, err := GetChainParams("simnet")
+	require.ErrorContains(t, err, "unknown BCH network: simnet")
+}
+
+func TestChainParamsAddress(t *testing.T) {
+	for _, params := range []*ChainParams{MainNet, ChipNet, RegTest} {
+		c, err := params.NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+			testExpiration, testPenaltyBPS)
+		require.NoError(t, err)
+
+		// script hash does not depend on network
+		p2sh, err := c.GetRedeemScriptHash()
+		require.NoError(t, err)
+		require.Equal(t, "521f6c114336d78a7afb478faed6822b2dc68ba0", hex.EncodeToString(p2sh))
+
+		addr, err := c.GetP2SHAddress()
+		require.NoError(t, err)
+		require.Contains(t, addr, params.Net.CashAddressPrefix+":")
+	}
+
+	addr, err := ChipNet.DecodeP2PKHAddress(testSenderAddr.String())
+	require.NoError(t, err)
+	require.Equal(t, testSenderPkh, addr.Hash160()[:])
+
+	_, err = MainNet.DecodeP2PKHAddress("bchtest:" + testSenderAddr.String())
+	require.Error(t, err)
+	_, err = TestNet3.DecodeP2PKHAddress("bchtest:ppfp7mq3gvmd0zn6ldrcltkksg4jm35t5qm0z8273e")
+	require.ErrorContains(t, err, "not P2PKH address")
+}
@@ -1,6 +1,8 @@
 package htlcbch
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -11,6 +13,7 @@ import (
 	"github.com/gcash/bchd/txscript"
 	"github.com/gcash/bchd/wire"
 	"github.com/gcash/bchutil"
+	"github.com/smartbch/atomic-swap-bot/htlcmath"
 )
 
 const (
@@ -20,8 +23,238 @@ const (
 
 var (
 	redeemScriptWithoutConstructorArgs = gethcmn.FromHex(RedeemScriptWithoutConstructorArgsHex)
+
+	// same as HTLC4 but the secret is checked by OP_HASH160 instead of OP_SHA256
+	hash160RedeemScriptWithoutConstructorArgs = makeHash160RedeemScript()
+
+	// opt-in variants whose receipts can be batched, see makeBatchableRedeemScript()
+	batchableRedeemScriptWithoutConstructorArgs        = makeBatchableRedeemScript(redeemScriptWithoutConstructorArgs)
+	hash160BatchableRedeemScriptWithoutConstructorArgs = makeBatchableRedeemScript(hash160RedeemScriptWithoutConstructorArgs)
+
+	// split variants of the above, see makeSplitRedeemScript()
+	splitRedeemScriptWithoutConstructorArgs        = makeSplitRedeemScript(txscript.OP_SHA256)
+	hash160SplitRedeemScriptWithoutConstructorArgs = makeSplitRedeemScript(txscript.OP_HASH160)
+)
+
+// HashType is the hash function used to derive hash lock from secret
+type HashType uint8
+
+const (
+	HashTypeSha256  HashType = 0 // 32 bytes hash lock, the default
+	HashTypeHash160 HashType = 1 // 20 bytes hash lock, RIPEMD160(SHA256(secret))
+)
+
+// offset of OP_SHA256 which hashes the secret in HTLC4
+const secretHashOpOffset = 10
+
+func makeHash160RedeemScript() []byte {
+	script := gethcmn.CopyBytes(redeemScriptWithoutConstructorArgs)
+	if script[secretHashOpOffset] != txscript.OP_SHA256 {
+		panic("unexpected HTLC redeem script")
+	}
+	script[secretHashOpOffset] = txscript.OP_HASH160
+	return script
+}
+
+// makeBatchableRedeemScript makes a variant of HTLC4 whose receipt path checks the active input index
+// instead of input 0, and pays the output of the same index, so that receipts of several covenants
+// can be batched into one tx, input#i paying output#i (see MakeBatchUnlockTx):
+//
+//	INPUTINDEX 0 NUMEQUALVERIFY => (removed)
+//	0 OUTPUTBYTECODE => INPUTINDEX OUTPUTBYTECODE
+//	0 OUTPUTVALUE 0 UTXOVALUE => INPUTINDEX OUTPUTVALUE INPUTINDEX UTXOVALUE
+func makeBatchableRedeemScript(script []byte) []byte {
+	elseIdx := bytes.Index(script,
+		[]byte{txscript.OP_2DROP, txscript.OP_2DROP, txscript.OP_1, txscript.OP_ELSE}) + 3
+	if elseIdx < 3 {
+		panic("unexpected HTLC redeem script")
+	}
+	receiptPath := gethcmn.CopyBytes(script[:elseIdx])
+	replace := func(old, new []byte) {
+		if bytes.Count(receiptPath, old) != 1 {
+			panic("unexpected HTLC redeem script")
+		}
+		receiptPath = bytes.Replace(receiptPath, old, new, 1)
+	}
+	replace([]byte{txscript.OP_INPUTINDEX, txscript.OP_0, txscript.OP_NUMEQUALVERIFY}, nil)
+	replace([]byte{txscript.OP_0, txscript.OP_OUTPUTBYTECODE}, []byte{txscript.OP_INPUTINDEX, txscript.OP_OUTPUTBYTECODE})
+	replace([]byte{txscript.OP_0, txscript.OP_OUTPUTVALUE}, []byte{txscript.OP_INPUTINDEX, txscript.OP_OUTPUTVALUE})
+	replace([]byte{txscript.OP_0, txscript.OP_UTXOVALUE}, []byte{txscript.OP_INPUTINDEX, txscript.OP_UTXOVALUE})
+	return append(receiptPath, script[elseIdx:]...)
+}
+
+// makeSplitRedeemScript makes a variant of HTLC4 with two more constructor args, referralBPS and
+// referralPkh, pushed after senderPkh. Its receipt path pays output#1 to referralPkh with at least
+// utxoValue * referralBPS / 10000, and output#0 to recipientPkh with the rest minus at most 2000
+// sats of miner fee. Its refund path drops the referral args and works as HTLC4's.
+//
+//	// stack: referralBPS referralPkh senderPkh recipientPkh hashLock expiration penaltyBPS selector [secret]
+//	7 PICK 0 NUMEQUAL IF
+//	  INPUTINDEX 0 NUMEQUALVERIFY
+//	  8 ROLL <hashOp> 5 ROLL EQUALVERIFY
+//	  0 UTXOVALUE MUL 10000 DIV                                      // referral fee
+//	  76a914 2 PICK CAT 88ac CAT 1 OUTPUTBYTECODE EQUALVERIFY
+//	  1 OUTPUTVALUE OVER GREATERTHANOREQUAL VERIFY
+//	  76a914 4 PICK CAT 88ac CAT 0 OUTPUTBYTECODE EQUALVERIFY
+//	  0 OUTPUTVALUE 0 UTXOVALUE 2 ROLL SUB 2000 SUB GREATERTHANOREQUAL VERIFY
+//	  2DROP 2DROP 2DROP 1
+//	ELSE
+//	  2DROP <refund path of HTLC4>
+func makeSplitRedeemScript(hashOp byte) []byte {
+	p2pkhPrefix, p2pkhSuffix := []byte{txscript.OP_DUP, txscript.OP_HASH160, txscript.OP_DATA_20},
+		[]byte{txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG}
+	receiptPath, err := txscript.NewScriptBuilder().
+		AddOps([]byte{txscript.OP_7, txscript.OP_PICK, txscript.OP_0, txscript.OP_NUMEQUAL, txscript.OP_IF}).
+		AddOps([]byte{txscript.OP_INPUTINDEX, txscript.OP_0, txscript.OP_NUMEQUALVERIFY}).
+		AddOps([]byte{txscript.OP_8, txscript.OP_ROLL, hashOp, txscript.OP_5, txscript.OP_ROLL, txscript.OP_EQUALVERIFY}).
+		AddOps([]byte{txscript.OP_0, txscript.OP_UTXOVALUE, txscript.OP_MUL}).AddInt64(10000).AddOp(txscript.OP_DIV).
+		AddData(p2pkhPrefix).AddOps([]byte{txscript.OP_2, txscript.OP_PICK, txscript.OP_CAT}).
+		AddData(p2pkhSuffix).AddOps([]byte{txscript.OP_CAT, txscript.OP_1, txscript.OP_OUTPUTBYTECODE, txscript.OP_EQUALVERIFY}).
+		AddOps([]byte{txscript.OP_1, txscript.OP_OUTPUTVALUE, txscript.OP_OVER, txscript.OP_GREATERTHANOREQUAL, txscript.OP_VERIFY}).
+		AddData(p2pkhPrefix).AddOps([]byte{txscript.OP_4, txscript.OP_PICK, txscript.OP_CAT}).
+		AddData(p2pkhSuffix).AddOps([]byte{txscript.OP_CAT, txscript.OP_0, txscript.OP_OUTPUTBYTECODE, txscript.OP_EQUALVERIFY}).
+		AddOps([]byte{txscript.OP_0, txscript.OP_OUTPUTVALUE, txscript.OP_0, txscript.OP_UTXOVALUE, txscript.OP_2, txscript.OP_ROLL, txscript.OP_SUB}).
+		AddInt64(2000).AddOps([]byte{txscript.OP_SUB, txscript.OP_GREATERTHANOREQUAL, txscript.OP_VERIFY}).
+		AddOps([]byte{txscript.OP_2DROP, txscript.OP_2DROP, txscript.OP_2DROP, txscript.OP_1}).
+		Script()
+	if err != nil {
+		panic(err)
+	}
+
+	// the receipt path of HTLC4 ends with "2DROP 2DROP 1 ELSE"
+	elseIdx := bytes.Index(redeemScriptWithoutConstructorArgs,
+		[]byte{txscript.OP_2DROP, txscript.OP_2DROP, txscript.OP_1, txscript.OP_ELSE}) + 3
+	if elseIdx < 3 {
+		panic("unexpected HTLC redeem script")
+	}
+	refundPath := redeemScriptWithoutConstructorArgs[elseIdx+1:]
+	script := append(receiptPath, txscript.OP_ELSE, txscript.OP_2DROP)
+	return append(script, refundPath...)
+}
+
+// makeAbsoluteRedeemScript makes a variant of a redeem script whose refund path checks the expiration
+// by OP_CHECKLOCKTIMEVERIFY instead of OP_CHECKSEQUENCEVERIFY, so it is an absolute timestamp:
+//
+//	3 ROLL CHECKSEQUENCEVERIFY DROP => 3 ROLL CHECKLOCKTIMEVERIFY DROP
+func makeAbsoluteRedeemScript(script []byte) []byte {
+	csv := []byte{txscript.OP_3, txscript.OP_ROLL, txscript.OP_CHECKSEQUENCEVERIFY, txscript.OP_DROP}
+	idx := bytes.Index(script, csv)
+	if idx < 0 || bytes.Count(script, csv) != 1 {
+		panic("unexpected HTLC redeem script")
+	}
+	script = gethcmn.CopyBytes(script)
+	script[idx+2] = txscript.OP_CHECKLOCKTIMEVERIFY
+	return script
+}
+
+func (t HashType) IsValid() bool {
+	return t == HashTypeSha256 || t == HashTypeHash160
+}
+
+func (t HashType) HashLockLen() int {
+	if t == HashTypeHash160 {
+		return 20
+	}
+	return 32
+}
+
+func (t HashType) HashSecret(secret []byte) []byte {
+	if t == HashTypeHash160 {
+		return bchutil.Hash160(secret)
+	}
+	hash := sha256.Sum256(secret)
+	return hash[:]
+}
+
+func (t HashType) String() string {
+	switch t {
+	case HashTypeSha256:
+		return "sha256"
+	case HashTypeHash160:
+		return "hash160"
+	default:
+		return fmt.Sprintf("unknown(%d)", t)
+	}
+}
+
+func ParseHashType(s string) (HashType, error) {
+	switch s {
+	case "", "sha256":
+		return HashTypeSha256, nil
+	case "hash160":
+		return HashTypeHash160, nil
+	default:
+		return 0, fmt.Errorf("unknown hash type: %s", s)
+	}
+}
+
+// ExpirationType is the unit of the expiration, which is the relative lock time (BIP68) checked by
+// OP_CHECKSEQUENCEVERIFY in the refund path. Block counts depend on how fast blocks are mined, while
+// time based expirations give the sender a fixed refund window after the deposit is confirmed.
+// Timestamp expirations are absolute instead, checked by OP_CHECKLOCKTIMEVERIFY against median time
+// past, so they are in the same unit as the time locks of EVM HTLCs.
+type ExpirationType uint8
+
+const (
+	ExpirationTypeBlocks    ExpirationType = 0 // in blocks, the default
+	ExpirationTypeTime      ExpirationType = 1 // in units of 512 seconds, measured by median time past
+	ExpirationTypeTimestamp ExpirationType = 2 // unix timestamp, see WithExpiresAt()
 )
 
+// ExpirationTimeUnit is the granularity of time based relative lock times, in seconds
+const ExpirationTimeUnit = 1 << wire.SequenceLockTimeGranularity
+
+func (t ExpirationType) IsValid() bool {
+	return t == ExpirationTypeBlocks || t == ExpirationTypeTime || t == ExpirationTypeTimestamp
+}
+
+// Sequence is the relative lock time of expiration, as the CSV arg and the sequence of refund inputs,
+// 0 for absolute expirations, whose refund inputs are non-final to enforce the lock time instead
+func (t ExpirationType) Sequence(expiration uint16) uint32 {
+	switch t {
+	case ExpirationTypeTime:
+		return wire.SequenceLockTimeIsSeconds | uint32(expiration)
+	case ExpirationTypeTimestamp:
+		return 0
+	default:
+		return uint32(expiration)
+	}
+}
+
+func (t ExpirationType) String() string {
+	switch t {
+	case ExpirationTypeBlocks:
+		return "blocks"
+	case ExpirationTypeTime:
+		return "time"
+	case ExpirationTypeTimestamp:
+		return "timestamp"
+	default:
+		return fmt.Sprintf("unknown(%d)", t)
+	}
+}
+
+// Unit is the unit of RefundableIn
+func (t ExpirationType) Unit() string {
+	if t == ExpirationTypeBlocks {
+		return "blocks"
+	}
+	return "seconds"
+}
+
+func ParseExpirationType(s string) (ExpirationType, error) {
+	switch s {
+	case "", "blocks":
+		return ExpirationTypeBlocks, nil
+	case "time":
+		return ExpirationTypeTime, nil
+	case "timestamp":
+		return ExpirationTypeTimestamp, nil
+	default:
+		return 0, fmt.Errorf("unknown expiration type: %s", s)
+	}
+}
+
 type InputInfo struct {
 	TxID   []byte
 	Vout   uint32
@@ -29,12 +262,21 @@ type InputInfo struct {
 }
 
 type HtlcCovenant struct {
-	senderPkh    []byte // 20 bytes
-	recipientPkh []byte // 20 bytes
-	hashLock     []byte // 32 bytes
-	expiration   uint16
-	penaltyBPS   uint16
-	net          *chaincfg.Params
+	senderPkh      []byte // 20 bytes
+	recipientPkh   []byte // 20 bytes
+	hashLock       []byte // 32 bytes for SHA256, 20 bytes for HASH160
+	hashType       HashType
+	expiration     uint16
+	expirationType ExpirationType // unit of expiration, see WithExpirationType()
+	expiresAt      uint32         // unix timestamp, replaces expiration if expirationType is timestamp
+	penaltyBPS     uint16
+	referralPkh    []byte // 20 bytes, empty means not a split covenant, see WithReferral()
+	referralBPS    uint16
+	template       *CovenantTemplate // nil means the current one, see WithTemplate()
+	net            *chaincfg.Params
+	sigType        SigType         // used to sign P2PKH inputs of lock txs
+	lockTime       uint32          // anti-fee-sniping lock time of txs made by it, see useLockTime()
+	changeAddr     bchutil.Address // change of lock txs, nil means back to the sender, see WithChangeAddr()
 }
 
 func NewMainnetCovenant(
@@ -58,20 +300,33 @@ func NewCovenant(
 	net *chaincfg.Params,
 ) (*HtlcCovenant, error) {
 
+	return NewCovenantWithHashType(senderPkh, recipientPkh, hashLock, HashTypeSha256,
+		expiration, penaltyBPS, net)
+}
+
+func NewCovenantWithHashType(
+	senderPkh, recipientPkh, hashLock []byte, hashType HashType, expiration, penaltyBPS uint16,
+	net *chaincfg.Params,
+) (*HtlcCovenant, error) {
+
+	if !hashType.IsValid() {
+		return nil, fmt.Errorf("invalid hash type: %d", hashType)
+	}
 	if len(senderPkh) != 20 {
 		return nil, fmt.Errorf("senderPkh is not 20 bytes")
 	}
 	if len(recipientPkh) != 20 {
 		return nil, fmt.Errorf("recipientPkh is not 20 bytes")
 	}
-	if len(hashLock) != 32 {
-		return nil, fmt.Errorf("hashLock is not 32 bytes")
+	if len(hashLock) != hashType.HashLockLen() {
+		return nil, fmt.Errorf("hashLock is not %d bytes", hashType.HashLockLen())
 	}
 
 	return &HtlcCovenant{
 		senderPkh:    senderPkh,
 		recipientPkh: recipientPkh,
 		hashLock:     hashLock,
+		hashType:     hashType,
 		expiration:   expiration,
 		penaltyBPS:   penaltyBPS,
 		net:          net,
@@ -83,11 +338,117 @@ func (c *HtlcCovenant) String() string {
 		"senderPkh: " + hex.EncodeToString(c.senderPkh) +
 		", recipientPkh: " + hex.EncodeToString(c.recipientPkh) +
 		", hashLock: " + hex.EncodeToString(c.hashLock) +
+		", hashType: " + c.hashType.String() +
 		", expiration: " + fmt.Sprintf("%d", c.expiration) +
+		", expirationType: " + c.expirationType.String() +
+		", expiresAt: " + fmt.Sprintf("%d", c.expiresAt) +
 		", penaltyBPS: " + fmt.Sprintf("%d", c.penaltyBPS) +
+		", referralPkh: " + hex.EncodeToString(c.referralPkh) +
+		", referralBPS: " + fmt.Sprintf("%d", c.referralBPS) +
+		", template: " + c.Template().Version +
 		"}"
 }
 
+func (c *HtlcCovenant) HashType() HashType {
+	return c.hashType
+}
+
+func (c *HtlcCovenant) ExpirationType() ExpirationType {
+	return c.expirationType
+}
+
+// WithExpirationType returns a copy of the covenant whose expiration is in another unit,
+// the redeem script is not changed but the relative lock time checked by it
+func (c *HtlcCovenant) WithExpirationType(expirationType ExpirationType) (*HtlcCovenant, error) {
+	if !expirationType.IsValid() {
+		return nil, fmt.Errorf("invalid expiration type: %d", expirationType)
+	}
+	if expirationType == ExpirationTypeTimestamp {
+		return nil, fmt.Errorf("timestamp expiration needs a timestamp, see WithExpiresAt()")
+	}
+	c2 := *c
+	c2.expirationType = expirationType
+	c2.expiresAt = 0
+	return &c2, nil
+}
+
+// WithExpiresAt returns a copy of the covenant which can be refunded once the median time past
+// reaches expiresAt (unix timestamp), instead of a relative lock time after the deposit
+func (c *HtlcCovenant) WithExpiresAt(expiresAt uint32) (*HtlcCovenant, error) {
+	if expiresAt < txscript.LockTimeThreshold {
+		return nil, fmt.Errorf("expiresAt is not a timestamp: %d", expiresAt)
+	}
+	c2 := *c
+	c2.expirationType = ExpirationTypeTimestamp
+	c2.expiration = 0
+	c2.expiresAt = expiresAt
+	return &c2, nil
+}
+
+func (c *HtlcCovenant) ExpiresAt() uint32 {
+	return c.expiresAt
+}
+
+// the arg checked by CSV, or by CLTV if the expiration is absolute
+func (c *HtlcCovenant) expirationArg() int64 {
+	if c.expirationType == ExpirationTypeTimestamp {
+		return int64(c.expiresAt)
+	}
+	return int64(c.expirationType.Sequence(c.expiration))
+}
+
+// refund txs of absolute expirations use the expiration as lock time, instead of anti-fee-sniping
+func (c *HtlcCovenant) newRefundTxBuilder() *msgTxBuilder {
+	if c.expirationType == ExpirationTypeTimestamp {
+		return newMsgTxBuilder().useExpiresAt(c.expiresAt)
+	}
+	return newMsgTxBuilder().useLockTime(c.lockTime)
+}
+
+// WithReferral returns a split copy of the covenant, whose unlock tx pays referralBPS of the value
+// to referralPkh (e.g. an integrator), and the rest to recipient. Refund txs are not affected.
+func (c *HtlcCovenant) WithReferral(referralPkh []byte, referralBPS uint16) (*HtlcCovenant, error) {
+	if len(referralPkh) != 20 {
+		return nil, fmt.Errorf("referralPkh is not 20 bytes")
+	}
+	if referralBPS == 0 || referralBPS >= htlcmath.MaxBPS {
+		return nil, fmt.Errorf("referralBPS not in (0, %d): %d", htlcmath.MaxBPS, referralBPS)
+	}
+	c2 := *c
+	c2.referralPkh = referralPkh
+	c2.referralBPS = referralBPS
+	return &c2, nil
+}
+
+// WithTemplate returns a copy of the covenant made by another version of the template
+func (c *HtlcCovenant) WithTemplate(template *CovenantTemplate) *HtlcCovenant {
+	c2 := *c
+	c2.template = template
+	return &c2
+}
+
+func (c *HtlcCovenant) Template() *CovenantTemplate {
+	if c.template == nil {
+		return CurrentCovenantTemplate()
+	}
+	return c.template
+}
+
+func (c *HtlcCovenant) IsSplit() bool {
+	return len(c.referralPkh) > 0
+}
+
+// WithBatchable returns a copy of the covenant made by the batchable template, which has another address.
+// Split covenants pay output#0 and output#1 on receipt, so they can not be batched anyway.
+func (c *HtlcCovenant) WithBatchable() *HtlcCovenant {
+	return c.WithTemplate(BatchableCovenantTemplate())
+}
+
+// CanBeBatched returns true if the receipt of the covenant can be batched with others, see MakeBatchUnlockTx
+func (c *HtlcCovenant) CanBeBatched() bool {
+	return c.Template().Batchable() && !c.IsSplit()
+}
+
 func (c *HtlcCovenant) GetRedeemScriptHash() ([]byte, error) {
 	redeemScript, err := c.BuildFullRedeemScript()
 	if err != nil {
@@ -116,13 +477,16 @@ func (c *HtlcCovenant) MakeUnlockTx(
 	minerFeeRate uint64,
 	secret []byte,
 ) (*wire.MsgTx, error) {
-	// estimate miner fee
-	tx, err := c.makeUnlockTx(txid, vout, inAmt, secret, 1000)
+	// estimate miner fee, tx size does not depend on it
+	tx, err := c.makeUnlockTx(txid, vout, inAmt, secret, 0)
 	if err != nil {
 		return nil, err
 	}
 	// make tx
-	minerFee := int64(len(MsgTxToBytes(tx))) * int64(minerFeeRate)
+	minerFee, err := htlcmath.MinerFee(len(MsgTxToBytes(tx)), minerFeeRate)
+	if err != nil {
+		return nil, err
+	}
 	return c.makeUnlockTx(txid, vout, inAmt, secret, minerFee)
 }
 
@@ -130,20 +494,23 @@ func (c *HtlcCovenant) MakeRefundTx(
 	txid []byte, vout uint32, inAmt int64, // input info
 	minerFeeRate uint64,
 ) (*wire.MsgTx, error) {
-	// estimate miner fee
-	tx, err := c.makeRefundTx(txid, vout, inAmt, 1000)
+	// estimate miner fee, tx size does not depend on it
+	tx, err := c.makeRefundTx(txid, vout, inAmt, 0)
 	if err != nil {
 		return nil, err
 	}
 	// make tx
-	minerFee := int64(len(MsgTxToBytes(tx))) * int64(minerFeeRate)
+	minerFee, err := htlcmath.MinerFee(len(MsgTxToBytes(tx)), minerFeeRate)
+	if err != nil {
+		return nil, err
+	}
 	return c.makeRefundTx(txid, vout, inAmt, minerFee)
 }
 
 func (c *HtlcCovenant) makeUnlockTx(
 	txid []byte, vout uint32, inAmt int64, // input info
 	secret []byte,
-	minerFee int64,
+	minerFee uint64,
 ) (*wire.MsgTx, error) {
 
 	if len(secret) != 32 {
@@ -162,18 +529,49 @@ func (c *HtlcCovenant) makeUnlockTx(
 		return nil, err
 	}
 
+	split, err := getReceiptSplit(inAmt, func(value uint64) (htlcmath.ReceiptSplit, error) {
+		return c.unlockSplit(value, minerFee)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.IsSplit() {
+		return newMsgTxBuilder().
+			useLockTime(c.lockTime).
+			addInput(txid, vout, seq, sigScript).
+			addOutput(toAddr, int64(split.ToRecipient)).
+			build()
+	}
+
+	// split covenant: the referral fee goes to output#1
+
+	referralAddr, err := bchutil.NewAddressPubKeyHash(c.referralPkh, c.net)
+	if err != nil {
+		return nil, err
+	}
+
 	return newMsgTxBuilder().
+		useLockTime(c.lockTime).
 		addInput(txid, vout, seq, sigScript).
-		addOutput(toAddr, inAmt-minerFee).
+		addOutput(toAddr, int64(split.ToRecipient)).
+		addOutput(referralAddr, int64(split.ToReferral)).
 		build()
 }
 
+func (c *HtlcCovenant) unlockSplit(value, minerFee uint64) (htlcmath.ReceiptSplit, error) {
+	if !c.IsSplit() {
+		return htlcmath.UnlockSplit(value, minerFee)
+	}
+	return htlcmath.UnlockSplitWithReferral(value, minerFee, c.referralBPS)
+}
+
 func (c *HtlcCovenant) makeRefundTx(
 	txid []byte, vout uint32, inAmt int64, // input info
-	minerFee int64,
+	minerFee uint64,
 ) (*wire.MsgTx, error) {
 
-	seq := uint32(c.expiration)
+	seq := c.expirationType.Sequence(c.expiration)
 
 	sigScript, err := c.BuildRefundSigScript()
 	if err != nil {
@@ -185,11 +583,18 @@ func (c *HtlcCovenant) makeRefundTx(
 		return nil, err
 	}
 
+	split, err := getReceiptSplit(inAmt, func(value uint64) (htlcmath.ReceiptSplit, error) {
+		return htlcmath.RefundSplit(value, minerFee, c.penaltyBPS)
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// no penalty
 	if c.penaltyBPS == 0 {
-		return newMsgTxBuilder().
+		return c.newRefundTxBuilder().
 			addInput(txid, vout, seq, sigScript).
-			addOutput(senderAddr, inAmt-minerFee).
+			addOutput(senderAddr, int64(split.ToSender)).
 			build()
 	}
 
@@ -200,42 +605,129 @@ func (c *HtlcCovenant) makeRefundTx(
 		return nil, err
 	}
 
-	penaltyVal := inAmt * int64(c.penaltyBPS) / 10000
-	if penaltyVal < 546 {
-		penaltyVal = 546
-	}
-
-	return newMsgTxBuilder().
+	return c.newRefundTxBuilder().
 		addInput(txid, vout, seq, sigScript).
-		addOutput(senderAddr, inAmt-penaltyVal-minerFee).
-		addOutput(recipientAddr, penaltyVal).
+		addOutput(senderAddr, int64(split.ToSender)).
+		addOutput(recipientAddr, int64(split.ToRecipient)).
 		build()
 }
 
+// outputs of a split never exceed inAmt, so they fit in int64
+func getReceiptSplit(inAmt int64, splitFn func(value uint64) (htlcmath.ReceiptSplit, error),
+) (htlcmath.ReceiptSplit, error) {
+
+	value, err := htlcmath.FromInt64(inAmt)
+	if err != nil {
+		return htlcmath.ReceiptSplit{}, err
+	}
+	return splitFn(value)
+}
+
+// CanBeSpent returns false if the unlock tx or the refund tx of a deposit of value sats,
+// after paying miner fee at the given rate, would have an output below dust limit
+func (c *HtlcCovenant) CanBeSpent(value uint64, unlockFeeRate, refundFeeRate uint64) bool {
+	inAmt, err := htlcmath.ToInt64(value)
+	if err != nil {
+		return false
+	}
+	txid := make([]byte, 32)
+	unlockTx, err := c.makeUnlockTx(txid, 0, inAmt, make([]byte, 32), 0)
+	if err != nil {
+		return false
+	}
+	unlockFee, err := htlcmath.MinerFee(len(MsgTxToBytes(unlockTx)), unlockFeeRate)
+	if err != nil {
+		return false
+	}
+	if _, err = c.unlockSplit(value, unlockFee); err != nil {
+		return false
+	}
+
+	refundTx, err := c.makeRefundTx(txid, 0, inAmt, 0)
+	if err != nil {
+		return false
+	}
+	refundFee, err := htlcmath.MinerFee(len(MsgTxToBytes(refundTx)), refundFeeRate)
+	if err != nil {
+		return false
+	}
+	_, err = htlcmath.RefundSplit(value, refundFee, c.penaltyBPS)
+	return err == nil
+}
+
+// WithSigType returns a copy of the covenant whose lock txs are signed with sigType,
+// unlock and refund txs are not affected since they carry no signatures
+func (c *HtlcCovenant) WithSigType(sigType SigType) *HtlcCovenant {
+	c2 := *c
+	c2.sigType = sigType
+	return &c2
+}
+
+// WithLockTime returns a copy of covenant which makes txs with the lock time, usually the current
+// BCH height. Refunds still wait for the expiration, whose relative lock time is in the sequence.
+func (c *HtlcCovenant) WithLockTime(lockTime uint32) *HtlcCovenant {
+	c2 := *c
+	c2.lockTime = lockTime
+	return &c2
+}
+
+// WithChangeAddr returns a copy of covenant whose lock txs send the change to changeAddr,
+// e.g. a multisig treasury, instead of the sender. Nil means the sender.
+func (c *HtlcCovenant) WithChangeAddr(changeAddr bchutil.Address) *HtlcCovenant {
+	c2 := *c
+	c2.changeAddr = changeAddr
+	return &c2
+}
+
 func (c *HtlcCovenant) MakeLockTx(
 	fromKey *bchec.PrivateKey,
 	inputs []InputInfo, // inputs info
 	outAmt int64, // output info
 	minerFeeRate uint64,
 ) (*wire.MsgTx, error) {
+	return c.MakeDepositTx(fromKey, inputs, outAmt, make([]byte, 20), 1e8, "", minerFeeRate)
+}
+
+// MakeDepositTx makes a lock tx with the OP_RETURN payload recognized by the bot,
+// the tx is left unsigned if fromKey is nil, and the change goes back to the sender
+// unless WithChangeAddr() is used
+func (c *HtlcCovenant) MakeDepositTx(
+	fromKey *bchec.PrivateKey,
+	inputs []InputInfo, // inputs info
+	outAmt int64, // output info
+	sbchUserAddr []byte,
+	expectedPrice uint64,
+	memo string, // optional reference ID echoed by the bot, see IsValidMemo
+	minerFeeRate uint64,
+) (*wire.MsgTx, error) {
+	opRetScript, err := c.BuildOpRetPkScriptWithMemo(sbchUserAddr, expectedPrice, memo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OP_RETURN: %w", err)
+	}
+
 	// estimate miner fee
-	tx, err := c.makeLockTx(fromKey, inputs, outAmt, 1000)
+	tx, err := c.makeLockTx(fromKey, inputs, outAmt, opRetScript, 1000)
 	if err != nil {
 		return nil, err
 	}
 	// make tx
-	minerFee := int64(len(MsgTxToBytes(tx))) * int64(minerFeeRate)
-	return c.makeLockTx(fromKey, inputs, outAmt, minerFee)
+	minerFee := int64(getStableTxSize(tx, c.sigType)) * int64(minerFeeRate)
+	return c.makeLockTx(fromKey, inputs, outAmt, opRetScript, minerFee)
 }
 
 func (c *HtlcCovenant) makeLockTx(
 	fromKey *bchec.PrivateKey,
 	inputs []InputInfo, // inputs info
 	outAmt int64, // output info
+	opRetScript []byte,
 	minerFee int64,
 ) (*wire.MsgTx, error) {
-	fromPk := fromKey.PubKey().SerializeCompressed()
-	fromPkh := bchutil.Hash160(fromPk)
+	fromPkh := c.senderPkh
+	var fromPk []byte
+	if fromKey != nil {
+		fromPk = fromKey.PubKey().SerializeCompressed()
+		fromPkh = bchutil.Hash160(fromPk)
+	}
 
 	script, err := c.BuildFullRedeemScript()
 	if err != nil {
@@ -247,9 +739,12 @@ func (c *HtlcCovenant) makeLockTx(
 		return nil, fmt.Errorf("failed to calc p2sh address: %d", err)
 	}
 
-	changeAddr, err := bchutil.NewAddressPubKeyHash(fromPkh, c.net)
-	if err != nil {
-		return nil, fmt.Errorf("failed to calc p2pkh address: %w", err)
+	changeAddr := c.changeAddr
+	if changeAddr == nil {
+		changeAddr, err = bchutil.NewAddressPubKeyHash(fromPkh, c.net)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calc p2pkh address: %w", err)
+		}
 	}
 
 	prevPkScript, err := payToPubKeyHashPkScript(fromPkh)
@@ -257,16 +752,13 @@ func (c *HtlcCovenant) makeLockTx(
 		return nil, fmt.Errorf("failed to creatte pkScript: %w", err)
 	}
 
-	opRetScript, err := c.BuildOpRetPkScript(make([]byte, 20), 1e8)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build OP_RETURN: %w", err)
-	}
-
 	sigScriptFn := func(sig []byte) ([]byte, error) {
 		return payToPubKeyHashSigScript(sig, fromPk)
 	}
 
-	builder := newMsgTxBuilder()
+	// inputs are sorted as BIP-69, outputs are not: the deposit and OP_RETURN must come first
+	inputs = sortInputs(inputs)
+	builder := newMsgTxBuilder().useSigType(c.sigType).useLockTime(c.lockTime)
 	var totalInAmt int64
 	for _, input := range inputs {
 		builder.addInput(input.TxID, input.Vout, 0, nil)
@@ -279,6 +771,9 @@ func (c *HtlcCovenant) makeLockTx(
 	builder.addOutput(toAddr, outAmt)
 	builder.addOpRet(opRetScript)
 	builder.addChange(changeAddr, changeAmt)
+	if fromKey == nil {
+		return builder.build()
+	}
 	for i, utxo := range inputs {
 		builder.sign(i, utxo.Amount, prevPkScript, fromKey, sigScriptFn)
 	}
@@ -286,13 +781,20 @@ func (c *HtlcCovenant) makeLockTx(
 }
 
 func (c *HtlcCovenant) BuildFullRedeemScript() ([]byte, error) {
-	return txscript.NewScriptBuilder().
+	builder := txscript.NewScriptBuilder().
 		AddInt64(int64(c.penaltyBPS)).
-		AddInt64(int64(c.expiration)).
+		AddInt64(c.expirationArg()).
 		AddData(c.hashLock).
 		AddData(c.recipientPkh).
-		AddData(c.senderPkh).
-		AddOps(redeemScriptWithoutConstructorArgs).
+		AddData(c.senderPkh)
+	code := c.Template().redeemScript(c.hashType, c.IsSplit(), c.expirationType == ExpirationTypeTimestamp)
+	if !c.IsSplit() {
+		return builder.AddOps(code).Script()
+	}
+	return builder.
+		AddData(c.referralPkh).
+		AddInt64(int64(c.referralBPS)).
+		AddOps(code).
 		Script()
 }
 
@@ -322,19 +824,54 @@ func (c *HtlcCovenant) BuildRefundSigScript() ([]byte, error) {
 }
 
 // OP_RETURN "SBAS" <recipient pkh> <sender pkh> <hash lock> <expiration> <penalty bps> <sbch user address> <expected price>
+// [<hash type> [<memo> [<referral pkh> <referral bps>]]]
+// hash type is omitted for SHA256 to keep compatible with old parsers, expiration is 2 bytes
+// in blocks, or 4 bytes of the relative lock time (with the BIP68 type flag) if it is time based,
+// or 4 bytes of the timestamp (not below 500000000, see txscript.LockTimeThreshold) if it is absolute
 func (c *HtlcCovenant) BuildOpRetPkScript(sbchUserAddr []byte,
 	expectedPrice uint64) ([]byte, error) {
-	return txscript.NewScriptBuilder().
+	return c.BuildOpRetPkScriptWithMemo(sbchUserAddr, expectedPrice, "")
+}
+
+// BuildOpRetPkScriptWithMemo appends the memo if it is not empty, hash type is always pushed before it.
+// The referral args of split covenants are appended after the memo, which is pushed even if empty.
+func (c *HtlcCovenant) BuildOpRetPkScriptWithMemo(sbchUserAddr []byte,
+	expectedPrice uint64, memo string) ([]byte, error) {
+	if memo != "" && !IsValidMemo(memo) {
+		return nil, fmt.Errorf("invalid memo: %q", memo)
+	}
+	builder := txscript.NewScriptBuilder().
 		AddOp(txscript.OP_RETURN).
 		AddData([]byte(protoID)).
 		AddData(c.recipientPkh).
 		AddData(c.senderPkh).
 		AddData(c.hashLock).
-		AddData(encodeBE16(c.expiration)).
+		AddData(c.encodeExpiration()).
 		AddData(encodeBE16(c.penaltyBPS)).
 		AddData(sbchUserAddr).
-		AddData(encodeBE64(expectedPrice)).
-		Script()
+		AddData(encodeBE64(expectedPrice))
+	if c.hashType != HashTypeSha256 || memo != "" || c.IsSplit() {
+		// AddData would encode small numbers as OP_N which are not treated as pushed data
+		builder.AddOps([]byte{txscript.OP_DATA_1, byte(c.hashType)})
+	}
+	if memo != "" || c.IsSplit() {
+		builder.AddData([]byte(memo))
+	}
+	if c.IsSplit() {
+		builder.AddData(c.referralPkh).AddData(encodeBE16(c.referralBPS))
+	}
+	return builder.Script()
+}
+
+func (c *HtlcCovenant) encodeExpiration() []byte {
+	switch c.expirationType {
+	case ExpirationTypeBlocks:
+		return encodeBE16(c.expiration)
+	case ExpirationTypeTimestamp:
+		return encodeBE32(c.expiresAt)
+	default:
+		return encodeBE32(c.expirationType.Sequence(c.expiration))
+	}
 }
 
 func encodeBE16(n uint16) []byte {
@@ -342,6 +879,11 @@ func encodeBE16(n uint16) []byte {
 	binary.BigEndian.PutUint16(buf[:], n)
 	return buf[:]
 }
+func encodeBE32(n uint32) []byte {
+	buf := [4]byte{}
+	binary.BigEndian.PutUint32(buf[:], n)
+	return buf[:]
+}
 func encodeBE64(n uint64) []byte {
 	buf := [8]byte{}
 	binary.BigEndian.PutUint64(buf[:], n)
@@ -0,0 +1,66 @@
+package htlcbch
+
+import (
+	"encoding/hex"
+
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchutil"
+)
+
+// CovenantArgs are the constructor args of an HTLC covenant, pushed before its code
+type CovenantArgs struct {
+	SenderPkh      string `json:"sender_pkh"`             // hex, refunded to
+	RecipientPkh   string `json:"recipient_pkh"`          // hex, unlocked to
+	HashLock       string `json:"hash_lock"`              // hex
+	HashType       string `json:"hash_type"`              // sha256|hash160
+	Expiration     uint16 `json:"expiration"`             // in blocks, or 512 seconds if time based
+	ExpirationType string `json:"expiration_type"`        // blocks|time|timestamp
+	ExpiresAt      uint32 `json:"expires_at,omitempty"`   // unix timestamp, replaces expiration if absolute
+	PenaltyBPS     uint16 `json:"penalty_bps"`            // paid to recipient on refund
+	ReferralPkh    string `json:"referral_pkh,omitempty"` // hex, split covenants only, paid on unlock
+	ReferralBPS    uint16 `json:"referral_bps,omitempty"` // split covenants only
+	Template       string `json:"template"`               // version of the covenant template
+}
+
+// CovenantDump describes an HTLC covenant in a human-readable way, for support and debugging
+type CovenantDump struct {
+	Args         CovenantArgs `json:"args"`
+	RedeemScript string       `json:"redeem_script"` // hex
+	Disassembly  string       `json:"disassembly"`   //
+	ScriptHash   string       `json:"script_hash"`   // hex, hash160 of redeem script
+	P2SHAddress  string       `json:"p2sh_address"`  //
+}
+
+func (c *HtlcCovenant) Dump() (*CovenantDump, error) {
+	redeemScript, err := c.BuildFullRedeemScript()
+	if err != nil {
+		return nil, err
+	}
+	disasm, err := txscript.DisasmString(redeemScript)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := c.GetP2SHAddress()
+	if err != nil {
+		return nil, err
+	}
+	return &CovenantDump{
+		Args: CovenantArgs{
+			SenderPkh:      hex.EncodeToString(c.senderPkh),
+			RecipientPkh:   hex.EncodeToString(c.recipientPkh),
+			HashLock:       hex.EncodeToString(c.hashLock),
+			HashType:       c.hashType.String(),
+			Expiration:     c.expiration,
+			ExpirationType: c.expirationType.String(),
+			ExpiresAt:      c.expiresAt,
+			PenaltyBPS:     c.penaltyBPS,
+			ReferralPkh:    hex.EncodeToString(c.referralPkh),
+			ReferralBPS:    c.referralBPS,
+			Template:       c.Template().Version,
+		},
+		RedeemScript: hex.EncodeToString(redeemScript),
+		Disassembly:  disasm,
+		ScriptHash:   hex.EncodeToString(bchutil.Hash160(redeemScript)),
+		P2SHAddress:  addr,
+	}, nil
+}
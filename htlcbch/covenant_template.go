@@ -0,0 +1,178 @@
+package htlcbch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// CovenantTemplate is a version of the HTLC redeem script templates (the code after constructor args),
+// with a variant for each hash type, plain or split. A covenant upgrade adds a new version to
+// covenantTemplates instead of changing an old one, so that swaps deposited to old versions can
+// still be found, claimed and refunded. Retired versions are not accepted for new deposits.
+// Opt-in versions are accepted, but only used for new covenants when asked for explicitly.
+type CovenantTemplate struct {
+	Version   string
+	Retired   bool
+	OptIn     bool
+	batchable bool      // receipts of plain covenants can be batched, see MakeBatchUnlockTx()
+	plain     [2][]byte // indexed by HashType
+	split     [2][]byte // indexed by HashType, see makeSplitRedeemScript()
+}
+
+// all known versions, the last active one which is not opt-in is used for new covenants
+var covenantTemplates = []*CovenantTemplate{
+	{
+		Version: "v1", // HTLC4
+		plain:   [2][]byte{redeemScriptWithoutConstructorArgs, hash160RedeemScriptWithoutConstructorArgs},
+		split:   [2][]byte{splitRedeemScriptWithoutConstructorArgs, hash160SplitRedeemScriptWithoutConstructorArgs},
+	},
+	{
+		Version:   "v2", // HTLC4 with batchable receipts, see makeBatchableRedeemScript()
+		OptIn:     true,
+		batchable: true,
+		plain:     [2][]byte{batchableRedeemScriptWithoutConstructorArgs, hash160BatchableRedeemScriptWithoutConstructorArgs},
+		split:     [2][]byte{splitRedeemScriptWithoutConstructorArgs, hash160SplitRedeemScriptWithoutConstructorArgs},
+	},
+}
+
+// hex of redeem scripts which end sig scripts spending HTLC covenants of all versions
+var htlcRedeemScriptHexes = getRedeemScriptHexes()
+
+func getRedeemScriptHexes() (hexes []string) {
+	for _, t := range covenantTemplates {
+		for _, script := range t.allScripts() {
+			hexes = append(hexes, hex.EncodeToString(script))
+		}
+	}
+	return
+}
+
+// Batchable returns true if receipts of plain (not split) covenants of the template can be batched
+func (t *CovenantTemplate) Batchable() bool {
+	return t.batchable
+}
+
+func (t *CovenantTemplate) scripts() [][]byte {
+	return [][]byte{t.plain[0], t.plain[1], t.split[0], t.split[1]}
+}
+
+// absolute variants are derived from the others, see makeAbsoluteRedeemScript()
+func (t *CovenantTemplate) redeemScript(hashType HashType, split, absolute bool) []byte {
+	script := t.plain[hashType]
+	if split {
+		script = t.split[hashType]
+	}
+	if absolute {
+		return makeAbsoluteRedeemScript(script)
+	}
+	return script
+}
+
+// scripts() and their absolute variants
+func (t *CovenantTemplate) allScripts() (scripts [][]byte) {
+	for _, absolute := range []bool{false, true} {
+		for _, split := range []bool{false, true} {
+			for _, hashType := range []HashType{HashTypeSha256, HashTypeHash160} {
+				scripts = append(scripts, t.redeemScript(hashType, split, absolute))
+			}
+		}
+	}
+	return
+}
+
+// Hash identifies the template (all variants), in hex
+func (t *CovenantTemplate) Hash() string {
+	h := sha256.New()
+	for _, script := range t.scripts() {
+		h.Write(encodeBE16(uint16(len(script))))
+		h.Write(script)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GetCovenantTemplates returns all known versions, oldest first
+func GetCovenantTemplates() []*CovenantTemplate {
+	return covenantTemplates
+}
+
+// CurrentCovenantTemplate is the version of new covenants
+func CurrentCovenantTemplate() *CovenantTemplate {
+	for i := len(covenantTemplates) - 1; i >= 0; i-- {
+		if !covenantTemplates[i].Retired && !covenantTemplates[i].OptIn {
+			return covenantTemplates[i]
+		}
+	}
+	panic("no active covenant template")
+}
+
+// BatchableCovenantTemplate is the version of new covenants whose receipts can be batched
+func BatchableCovenantTemplate() *CovenantTemplate {
+	for i := len(covenantTemplates) - 1; i >= 0; i-- {
+		if !covenantTemplates[i].Retired && covenantTemplates[i].batchable {
+			return covenantTemplates[i]
+		}
+	}
+	panic("no active batchable covenant template")
+}
+
+// GetCovenantTemplate returns the template of version, empty version means the current one
+func GetCovenantTemplate(version string) (*CovenantTemplate, error) {
+	if version == "" {
+		return CurrentCovenantTemplate(), nil
+	}
+	for _, t := range covenantTemplates {
+		if t.Version == version {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown covenant template: %s", version)
+}
+
+// GetCovenantTemplateByHash returns nil if no known version has the hash
+func GetCovenantTemplateByHash(hash string) *CovenantTemplate {
+	for _, t := range covenantTemplates {
+		if strings.EqualFold(t.Hash(), hash) {
+			return t
+		}
+	}
+	return nil
+}
+
+// TemplateHash is the hash of the current template, in hex
+func TemplateHash() string {
+	return CurrentCovenantTemplate().Hash()
+}
+
+// versions accepted for new deposits, the current one first
+func activeCovenantTemplates() (templates []*CovenantTemplate) {
+	current := CurrentCovenantTemplate()
+	templates = append(templates, current)
+	for i := len(covenantTemplates) - 1; i >= 0; i-- {
+		if !covenantTemplates[i].Retired && covenantTemplates[i] != current {
+			templates = append(templates, covenantTemplates[i])
+		}
+	}
+	return
+}
+
+// the template, hash type and split flag of the redeem script which ends sigScript, nil if none,
+// the longest match wins in case the code of a version ends with the code of another
+func findTemplateOfSigScript(sigScript []byte) (template *CovenantTemplate, hashType HashType, isSplit bool) {
+	matchedLen := 0
+	for _, t := range covenantTemplates {
+		for _, ht := range []HashType{HashTypeSha256, HashTypeHash160} {
+			for _, split := range []bool{false, true} {
+				for _, absolute := range []bool{false, true} {
+					code := t.redeemScript(ht, split, absolute)
+					if len(code) > matchedLen && bytes.HasSuffix(sigScript, code) {
+						template, hashType, isSplit, matchedLen = t, ht, split, len(code)
+					}
+				}
+			}
+		}
+	}
+	return
+}
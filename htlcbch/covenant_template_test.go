@@ -0,0 +1,130 @@
+package htlcbch
+
+import (
+	"encoding/hex"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/txscript"
+	"github.com/stretchr/testify/require"
+)
+
+// register a v3 template, which is v1 with an OP_NOP before the code, so v1 ends v3
+func withTestTemplateV3(t *testing.T) (v1, v3 *CovenantTemplate) {
+	v1 = covenantTemplates[0]
+	v3 = &CovenantTemplate{Version: "v3"}
+	for i := range v1.plain {
+		v3.plain[i] = append([]byte{txscript.OP_NOP}, v1.plain[i]...)
+		v3.split[i] = append([]byte{txscript.OP_NOP}, v1.split[i]...)
+	}
+	oldTemplates, oldHexes := covenantTemplates, htlcRedeemScriptHexes
+	covenantTemplates = append(append([]*CovenantTemplate{}, oldTemplates...), v3)
+	htlcRedeemScriptHexes = getRedeemScriptHexes()
+	t.Cleanup(func() {
+		v1.Retired = false
+		covenantTemplates, htlcRedeemScriptHexes = oldTemplates, oldHexes
+	})
+	return
+}
+
+func TestCovenantTemplateRegistry(t *testing.T) {
+	require.Equal(t, "v1", CurrentCovenantTemplate().Version)
+	require.Equal(t, TemplateHash(), CurrentCovenantTemplate().Hash())
+	_, err := GetCovenantTemplate("v3")
+	require.ErrorContains(t, err, "unknown covenant template: v3")
+
+	// the batchable v2 is opt-in, it is accepted but not used for new covenants by default
+	v2, err := GetCovenantTemplate("v2")
+	require.NoError(t, err)
+	require.True(t, v2.OptIn)
+	require.True(t, v2.Batchable())
+	require.False(t, CurrentCovenantTemplate().Batchable())
+	require.Equal(t, v2, BatchableCovenantTemplate())
+	require.Equal(t, []*CovenantTemplate{covenantTemplates[0], v2}, activeCovenantTemplates())
+
+	v1, v3 := withTestTemplateV3(t)
+	require.Equal(t, v3, CurrentCovenantTemplate())
+	template, err := GetCovenantTemplate("")
+	require.NoError(t, err)
+	require.Equal(t, v3, template)
+	require.Equal(t, v1, GetCovenantTemplateByHash(v1.Hash()))
+	require.Equal(t, v3, GetCovenantTemplateByHash(v3.Hash()))
+	require.Nil(t, GetCovenantTemplateByHash(hex.EncodeToString(make([]byte, 32))))
+	require.NotEqual(t, v1.Hash(), v3.Hash())
+	require.Equal(t, []*CovenantTemplate{v3, v2, v1}, activeCovenantTemplates())
+
+	v3.Retired = true
+	require.Equal(t, v1, CurrentCovenantTemplate())
+	v3.Retired = false
+}
+
+func TestParseDepositsOfAllTemplates(t *testing.T) {
+	v1, v3 := withTestTemplateV3(t)
+	v2 := BatchableCovenantTemplate()
+
+	c, err := NewTestnet3Covenant(testSenderPkh, testRecipientPkh, testSecretHash, testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+	opRet, err := c.BuildOpRetPkScript(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8)
+	require.NoError(t, err)
+	makeLockTx := func(template *CovenantTemplate) btcjson.TxRawResult {
+		scriptHash, err := c.WithTemplate(template).GetRedeemScriptHash()
+		require.NoError(t, err)
+		p2sh := append(append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, scriptHash...), txscript.OP_EQUAL)
+		return btcjson.TxRawResult{Txid: "lock", Vout: []btcjson.Vout{
+			{Value: 0.0001, ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(p2sh)}},
+			{ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(opRet)}},
+		}}
+	}
+	makeUnlockTx := func(template *CovenantTemplate) btcjson.TxRawResult {
+		sigScript, err := c.WithTemplate(template).BuildUnlockSigScript(testSecretKey)
+		require.NoError(t, err)
+		return btcjson.TxRawResult{Txid: "unlock", Vin: []btcjson.Vin{
+			{Txid: "lock", ScriptSig: &btcjson.ScriptSig{Hex: hex.EncodeToString(sigScript)}},
+		}}
+	}
+
+	// old swaps are not stranded by the upgrade
+	for _, template := range []*CovenantTemplate{v1, v2, v3} {
+		deposit, reason := parseHtlcLockTx(makeLockTx(template), TestNet3, nil)
+		require.Empty(t, reason)
+		require.Equal(t, template.Version, deposit.Template)
+		c2, err := TestNet3.NewCovenantOfDeposit(deposit)
+		require.NoError(t, err)
+		scriptHash, err := c2.GetRedeemScriptHash()
+		require.NoError(t, err)
+		require.Equal(t, scriptHash, []byte(deposit.ScriptHash))
+
+		deposit, reason = parseHtlcLockTx(makeLockTx(template), TestNet3, &DepositFilter{AnyOutputOrder: true})
+		require.Empty(t, reason)
+		require.Equal(t, template.Version, deposit.Template)
+
+		receipt := isHtlcUnlockTx(makeUnlockTx(template))
+		require.NotNil(t, receipt)
+		require.Equal(t, template.Version, receipt.TemplateVersion)
+	}
+
+	// retired templates are not accepted for new deposits, but their receipts are still found
+	v1.Retired = true
+	_, reason := parseHtlcLockTx(makeLockTx(v1), TestNet3, nil)
+	require.Contains(t, reason, "script hash mismatch")
+	require.NotNil(t, isHtlcUnlockTx(makeUnlockTx(v1)))
+
+	// covenants of a spec are made by its template
+	htlc := &HtlcSpec{
+		SenderPkh:    testSenderPkh,
+		RecipientPkh: testRecipientPkh,
+		HashLock:     testSecretHash,
+		Expiration:   testExpiration,
+		PenaltyBPS:   testPenaltyBPS,
+		Template:     "v1",
+	}
+	scriptHash, err := TestNet3.ScriptHash(htlc)
+	require.NoError(t, err)
+	v1Hash, err := c.WithTemplate(v1).GetRedeemScriptHash()
+	require.NoError(t, err)
+	require.Equal(t, v1Hash, scriptHash)
+	htlc.Template = "v9"
+	_, err = TestNet3.ScriptHash(htlc)
+	require.ErrorContains(t, err, "unknown covenant template: v9")
+}
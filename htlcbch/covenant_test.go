@@ -3,13 +3,17 @@ package htlcbch
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	gethcmn "github.com/ethereum/go-ethereum/common"
 	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
 	"github.com/gcash/bchutil"
+	"github.com/smartbch/atomic-swap-bot/htlcmath"
 )
 
 const (
@@ -49,6 +53,11 @@ func TestP2SHAddr(t *testing.T) {
 	require.Equal(t, "bchtest:ppfp7mq3gvmd0zn6ldrcltkksg4jm35t5qm0z8273e", addr)
 }
 
+// changes of any template must be released as a new covenant version, see TemplateHash()
+func TestTemplateHash(t *testing.T) {
+	require.Equal(t, "b41d58206cbe17ba4b41b4eb19f87b00dcd5d65c62eda88e79018d20e3ca8eaf", TemplateHash())
+}
+
 func TestBuildFullRedeemScript(t *testing.T) {
 	c, err := NewCovenant(
 		testSenderPkh,
@@ -145,6 +154,123 @@ func TestMakeRefundTx(t *testing.T) {
 	//require.Equal(t, "?", MsgTxToHex(tx))
 }
 
+func TestMakeReceiptTx_dust(t *testing.T) {
+	c, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	txid := gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes()
+
+	// unlock: 330 bytes
+	_, err = c.MakeUnlockTx(txid, 1, 330*2+546, 2, testSecretKey)
+	require.NoError(t, err)
+	_, err = c.MakeUnlockTx(txid, 1, 330*2+545, 2, testSecretKey)
+	require.ErrorIs(t, err, htlcmath.ErrDustOutput)
+	_, err = c.MakeUnlockTx(txid, 1, 100, 2, testSecretKey)
+	require.ErrorIs(t, err, htlcmath.ErrDustOutput)
+	_, err = c.MakeUnlockTx(txid, 1, -1, 2, testSecretKey)
+	require.ErrorIs(t, err, htlcmath.ErrInsufficient)
+
+	// refund: 331 bytes, penalty is rounded up to 546
+	_, err = c.MakeRefundTx(txid, 1, 331*3+546*2, 3)
+	require.NoError(t, err)
+	_, err = c.MakeRefundTx(txid, 1, 331*3+546*2-1, 3)
+	require.ErrorIs(t, err, htlcmath.ErrDustOutput)
+
+	require.True(t, c.CanBeSpent(331*3+546*2, 2, 3))
+	require.False(t, c.CanBeSpent(331*3+546*2-1, 2, 3))
+	require.False(t, c.CanBeSpent(1<<63, 2, 3))
+}
+
+// the receipt path of HTLC4 checks that the covenant is spent by input 0 and pays output 0,
+// so receipts of several covenants can not be batched into one tx, the batchable variant checks the active input
+func TestMakeBatchUnlockTx(t *testing.T) {
+	secret2 := gethcmn.Hash{'4', '5', '6'}.Bytes()
+	c1, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	c2, err := NewCovenant(testSenderPkh, testRecipientPkh, HashTypeSha256.HashSecret(secret2),
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.False(t, c1.CanBeBatched())
+	require.True(t, c1.WithBatchable().CanBeBatched())
+
+	inAmt := int64(100000)
+	execInput := func(tx *wire.MsgTx, idx int, prevPkScripts [][]byte) error {
+		utxoCache := txscript.NewUtxoCache()
+		for i, pkScript := range prevPkScripts {
+			utxoCache.AddEntry(i, *wire.NewTxOut(inAmt, pkScript))
+		}
+		vm, err := txscript.NewEngine(prevPkScripts[idx], tx, idx,
+			txscript.StandardVerifyFlags, nil, nil, utxoCache, inAmt)
+		if err != nil {
+			return err
+		}
+		return vm.Execute()
+	}
+	p2shPkScript := func(c *HtlcCovenant) []byte {
+		scriptHash, err := c.GetRedeemScriptHash()
+		require.NoError(t, err)
+		addr, err := bchutil.NewAddressScriptHashFromHash(scriptHash, &chaincfg.TestNet3Params)
+		require.NoError(t, err)
+		pkScript, err := txscript.PayToAddrScript(addr)
+		require.NoError(t, err)
+		return pkScript
+	}
+	txid1, txid2 := gethcmn.Hash{'u', 't', 'x', 'o', '1'}.Bytes(), gethcmn.Hash{'u', 't', 'x', 'o', '2'}.Bytes()
+
+	// HTLC4: two covenants in one tx, each input paying its own output
+	tx, err := c1.MakeUnlockTx(txid1, 0, inAmt, 2, testSecretKey)
+	require.NoError(t, err)
+	tx2, err := c2.MakeUnlockTx(txid2, 0, inAmt, 2, secret2)
+	require.NoError(t, err)
+	require.NoError(t, execInput(tx, 0, [][]byte{p2shPkScript(c1)}))
+	batchTx := tx.Copy()
+	batchTx.AddTxIn(tx2.TxIn[0])
+	batchTx.AddTxOut(tx2.TxOut[0])
+	prevPkScripts := [][]byte{p2shPkScript(c1), p2shPkScript(c2)}
+	require.NoError(t, execInput(batchTx, 0, prevPkScripts))
+	require.ErrorContains(t, execInput(batchTx, 1, prevPkScripts), "OP_NUMEQUALVERIFY") // OP_INPUTINDEX 0 OP_NUMEQUALVERIFY
+
+	// batchable variant, which has another address and can still be claimed alone
+	b1, b2 := c1.WithBatchable(), c2.WithBatchable()
+	require.NotEqual(t, p2shPkScript(c1), p2shPkScript(b1))
+	tx, err = b1.MakeUnlockTx(txid1, 0, inAmt, 2, testSecretKey)
+	require.NoError(t, err)
+	require.Len(t, MsgTxToBytes(tx), 327)
+	require.NoError(t, execInput(tx, 0, [][]byte{p2shPkScript(b1)}))
+
+	receipts := []BatchReceipt{
+		{Covenant: b1, TxID: txid1, Vout: 0, InAmt: inAmt, Secret: testSecretKey},
+		{Covenant: b2, TxID: txid2, Vout: 1, InAmt: inAmt, Secret: secret2},
+	}
+	batchTx, err = MakeBatchUnlockTx(receipts, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, batchTx.TxIn, 2)
+	require.Len(t, batchTx.TxOut, 2)
+	size := int64(len(MsgTxToBytes(batchTx)))
+	require.Equal(t, inAmt-(size*2+1)/2, batchTx.TxOut[0].Value)
+	require.Equal(t, batchTx.TxOut[0].Value, batchTx.TxOut[1].Value)
+	require.Less(t, size, int64(327*2)) // cheaper than two unlock txs
+	prevPkScripts = [][]byte{p2shPkScript(b1), p2shPkScript(b2)}
+	require.NoError(t, execInput(batchTx, 0, prevPkScripts))
+	require.NoError(t, execInput(batchTx, 1, prevPkScripts))
+
+	// input#1 can not pay output#0
+	swappedTx := batchTx.Copy()
+	swappedTx.TxOut[0].Value, swappedTx.TxOut[1].Value = 546, batchTx.TxOut[0].Value*2-546
+	require.Error(t, execInput(swappedTx, 0, prevPkScripts))
+
+	_, err = MakeBatchUnlockTx(nil, 2, 0)
+	require.ErrorContains(t, err, "no receipts")
+	receipts[1].Covenant = c2
+	_, err = MakeBatchUnlockTx(receipts, 2, 0)
+	require.ErrorContains(t, err, "receipt#1 can not be batched")
+	receipts[1].Covenant = b2
+	receipts[1].InAmt = 1000
+	_, err = MakeBatchUnlockTx(receipts, 2, 0)
+	require.ErrorIs(t, err, htlcmath.ErrDustOutput)
+}
+
 func TestMakeLockTx(t *testing.T) {
 	c, err := NewCovenant(
 		testSenderPkh,
@@ -168,6 +294,427 @@ func TestMakeLockTx(t *testing.T) {
 	feeRate := uint64(2)
 	tx, err := c.MakeLockTx(testSenderWIF.PrivKey, inputs, outAmt, feeRate)
 	require.NoError(t, err)
-	require.Len(t, MsgTxToBytes(tx), 350)
+	require.Len(t, MsgTxToBytes(tx), 349)
 	//require.Equal(t, "?", MsgTxToHex(tx))
+
+	// signed with Schnorr
+	tx, err = c.WithSigType(SigTypeSchnorr).MakeLockTx(testSenderWIF.PrivKey, inputs, outAmt, feeRate)
+	require.NoError(t, err)
+	require.Len(t, MsgTxToBytes(tx), getStableTxSize(tx, SigTypeSchnorr))
+	require.Equal(t, SigTypeECDSA, c.sigType)
+	prevPkScript, err := payToPubKeyHashPkScript(testSenderPkh)
+	require.NoError(t, err)
+	vm, err := txscript.NewEngine(prevPkScript, tx, 0,
+		txscript.StandardVerifyFlags, nil, nil, nil, 20000)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+
+	// change goes to a P2SH address
+	changeAddr, err := bchutil.NewAddressScriptHashFromHash(testRecipientPkh, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	tx, err = c.WithChangeAddr(changeAddr).MakeLockTx(testSenderWIF.PrivKey, inputs, outAmt, feeRate)
+	require.NoError(t, err)
+	require.Len(t, tx.TxOut, 3)
+	changePkScript, err := txscript.PayToAddrScript(changeAddr)
+	require.NoError(t, err)
+	require.Equal(t, changePkScript, tx.TxOut[2].PkScript)
+	require.Equal(t, int64(20000-10000-getStableTxSize(tx, SigTypeECDSA)*2), tx.TxOut[2].Value)
+}
+
+func TestMakeDepositTx(t *testing.T) {
+	c, err := NewCovenant(
+		testSenderPkh,
+		testRecipientPkh,
+		testSecretHash,
+		testExpiration,
+		testPenaltyBPS,
+		&chaincfg.TestNet3Params,
+	)
+	require.NoError(t, err)
+
+	inputs := []InputInfo{
+		{
+			TxID:   gethcmn.Hash{'t', 'x', 'i', 'd'}.Bytes(),
+			Vout:   uint32(1),
+			Amount: int64(20000),
+		},
+	}
+	sbchUserAddr := gethcmn.Address{'u', 's', 'e', 'r'}.Bytes()
+
+	signedTx, err := c.MakeDepositTx(testSenderWIF.PrivKey, inputs, 10000, sbchUserAddr, 99000000, "", 2)
+	require.NoError(t, err)
+	unsignedTx, err := c.MakeDepositTx(nil, inputs, 10000, sbchUserAddr, 99000000, "", 2)
+	require.NoError(t, err)
+	require.Empty(t, unsignedTx.TxIn[0].SignatureScript)
+	require.InDelta(t, signedTx.TxOut[2].Value, unsignedTx.TxOut[2].Value, 2*2) // DER signatures may be 1 byte shorter
+
+	// the deposit can be recognized by the parser
+	info := getHtlcLockInfo(signedTx.TxOut[1].PkScript)
+	require.NotNil(t, info)
+	require.Equal(t, testRecipientPkh, []byte(info.RecipientPkh))
+	require.Equal(t, testSenderPkh, []byte(info.SenderPkh))
+	require.Equal(t, testSecretHash, []byte(info.HashLock))
+	require.Equal(t, uint16(testExpiration), info.Expiration)
+	require.Equal(t, uint16(testPenaltyBPS), info.PenaltyBPS)
+	require.Equal(t, sbchUserAddr, []byte(info.SenderEvmAddr))
+	require.Equal(t, uint64(99000000), info.ExpectedPrice)
+	scriptHash, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	require.Equal(t, scriptHash, getP2SHash(signedTx.TxOut[0].PkScript))
+	require.Equal(t, int64(10000), signedTx.TxOut[0].Value)
+}
+
+func TestHash160Covenant(t *testing.T) {
+	_, err := NewCovenantWithHashType(testSenderPkh, testRecipientPkh, testSecretHash,
+		HashTypeHash160, testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "hashLock is not 20 bytes")
+	_, err = NewCovenantWithHashType(testSenderPkh, testRecipientPkh, testSecretHash,
+		HashType(2), testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "invalid hash type")
+
+	hashLock := HashTypeHash160.HashSecret(testSecretKey)
+	require.Equal(t, bchutil.Hash160(testSecretKey), hashLock)
+	c, err := NewCovenantWithHashType(testSenderPkh, testRecipientPkh, hashLock,
+		HashTypeHash160, testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Equal(t, HashTypeHash160, c.HashType())
+
+	script, err := c.BuildFullRedeemScript()
+	require.NoError(t, err)
+	require.Contains(t, hex.EncodeToString(script), "5579009c63c0009d567aa9537a88")
+
+	sha256Covenant, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	p2sh1, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	p2sh2, err := sha256Covenant.GetRedeemScriptHash()
+	require.NoError(t, err)
+	require.NotEqual(t, p2sh1, p2sh2)
+
+	sigScript, err := c.BuildUnlockSigScript(testSecretKey)
+	require.NoError(t, err)
+	unlockInfo := getHtlcUnlockInfo(sigScript)
+	require.NotNil(t, unlockInfo)
+	require.Equal(t, HashTypeHash160, unlockInfo.HashType)
+	require.Equal(t, hex.EncodeToString(testSecretKey), unlockInfo.Secret)
+
+	tx, err := c.MakeUnlockTx(gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes(), 1, 100000000, 2, testSecretKey)
+	require.NoError(t, err)
+	require.Len(t, MsgTxToBytes(tx), 318)
+}
+
+func TestParseHashType(t *testing.T) {
+	hashType, err := ParseHashType("")
+	require.NoError(t, err)
+	require.Equal(t, HashTypeSha256, hashType)
+	hashType, err = ParseHashType("hash160")
+	require.NoError(t, err)
+	require.Equal(t, HashTypeHash160, hashType)
+	require.Equal(t, "hash160", hashType.String())
+	_, err = ParseHashType("md5")
+	require.ErrorContains(t, err, "unknown hash type")
+}
+
+func TestCovenantDump(t *testing.T) {
+	c, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+
+	dump, err := c.Dump()
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(testSenderPkh), dump.Args.SenderPkh)
+	require.Equal(t, hex.EncodeToString(testRecipientPkh), dump.Args.RecipientPkh)
+	require.Equal(t, hex.EncodeToString(testSecretHash), dump.Args.HashLock)
+	require.Equal(t, "sha256", dump.Args.HashType)
+	require.Equal(t, uint16(testExpiration), dump.Args.Expiration)
+	require.Equal(t, uint16(testPenaltyBPS), dump.Args.PenaltyBPS)
+	require.Equal(t, "521f6c114336d78a7afb478faed6822b2dc68ba0", dump.ScriptHash)
+	require.Equal(t, "bchtest:ppfp7mq3gvmd0zn6ldrcltkksg4jm35t5qm0z8273e", dump.P2SHAddress)
+	require.True(t, strings.HasPrefix(dump.RedeemScript, "02f4010124"))
+	require.True(t, strings.HasPrefix(dump.Disassembly, "f401 24 "+dump.Args.HashLock+" "+
+		dump.Args.RecipientPkh+" "+dump.Args.SenderPkh+" 5 OP_PICK 0 OP_NUMEQUAL OP_IF OP_INPUTINDEX"))
+}
+
+func TestSplitCovenant(t *testing.T) {
+	referralPkh := bchutil.Hash160([]byte("referral"))
+	c, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	_, err = c.WithReferral(referralPkh[:19], 100)
+	require.ErrorContains(t, err, "referralPkh is not 20 bytes")
+	_, err = c.WithReferral(referralPkh, 0)
+	require.ErrorContains(t, err, "referralBPS not in")
+	_, err = c.WithReferral(referralPkh, 10000)
+	require.ErrorContains(t, err, "referralBPS not in")
+
+	sc, err := c.WithReferral(referralPkh, 100)
+	require.NoError(t, err)
+	require.True(t, sc.IsSplit())
+	require.False(t, c.IsSplit())
+	p2sh1, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	p2sh2, err := sc.GetRedeemScriptHash()
+	require.NoError(t, err)
+	require.NotEqual(t, p2sh1, p2sh2)
+	require.False(t, sc.WithBatchable().CanBeBatched())
+
+	inAmt := int64(1000000)
+	p2shPkScript := append(append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, p2sh2...), txscript.OP_EQUAL)
+	execTx := func(tx *wire.MsgTx) error {
+		utxoCache := txscript.NewUtxoCache()
+		utxoCache.AddEntry(0, *wire.NewTxOut(inAmt, p2shPkScript))
+		vm, err := txscript.NewEngine(p2shPkScript, tx, 0,
+			txscript.StandardVerifyFlags, nil, nil, utxoCache, inAmt)
+		if err != nil {
+			return err
+		}
+		return vm.Execute()
+	}
+
+	// unlock: output#0 to recipient, output#1 to referral
+	txid := gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes()
+	tx, err := sc.MakeUnlockTx(txid, 0, inAmt, 2, testSecretKey)
+	require.NoError(t, err)
+	require.Len(t, tx.TxOut, 2)
+	referralPkScript, err := payToPubKeyHashPkScript(referralPkh)
+	require.NoError(t, err)
+	require.Equal(t, referralPkScript, tx.TxOut[1].PkScript)
+	require.Equal(t, int64(10000), tx.TxOut[1].Value)
+	require.Equal(t, inAmt-10000-int64(len(MsgTxToBytes(tx))*2), tx.TxOut[0].Value)
+	require.NoError(t, execTx(tx))
+
+	unlockInfo := getHtlcUnlockInfo(tx.TxIn[0].SignatureScript)
+	require.NotNil(t, unlockInfo)
+	require.Equal(t, hex.EncodeToString(testSecretKey), unlockInfo.Secret)
+	require.True(t, mayBeHtlcSigScriptHex(hex.EncodeToString(tx.TxIn[0].SignatureScript)))
+
+	// the referral can not be underpaid
+	tx2 := tx.Copy()
+	tx2.TxOut[1].Value--
+	require.Error(t, execTx(tx2))
+	tx2 = tx.Copy()
+	tx2.TxOut[1].PkScript, _ = payToPubKeyHashPkScript(testSenderPkh)
+	require.Error(t, execTx(tx2))
+	// nor the recipient
+	tx2 = tx.Copy()
+	tx2.TxOut[0].Value -= 2000
+	require.Error(t, execTx(tx2))
+	// the secret must match
+	tx2, err = sc.MakeUnlockTx(txid, 0, inAmt, 2, gethcmn.Hash{'4', '5', '6'}.Bytes())
+	require.NoError(t, err)
+	require.Error(t, execTx(tx2))
+
+	// refund is not affected
+	tx, err = sc.MakeRefundTx(txid, 0, inAmt, 2)
+	require.NoError(t, err)
+	require.Len(t, tx.TxOut, 2)
+	require.Equal(t, int64(50000), tx.TxOut[1].Value)
+	require.NoError(t, execTx(tx))
+
+	// hash160 variant
+	hc, err := NewCovenantWithHashType(testSenderPkh, testRecipientPkh, HashTypeHash160.HashSecret(testSecretKey),
+		HashTypeHash160, testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	hc, err = hc.WithReferral(referralPkh, 100)
+	require.NoError(t, err)
+	scriptHash, err := hc.GetRedeemScriptHash()
+	require.NoError(t, err)
+	p2shPkScript = append(append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, scriptHash...), txscript.OP_EQUAL)
+	tx, err = hc.MakeUnlockTx(txid, 0, inAmt, 2, testSecretKey)
+	require.NoError(t, err)
+	require.NoError(t, execTx(tx))
+	unlockInfo = getHtlcUnlockInfo(tx.TxIn[0].SignatureScript)
+	require.NotNil(t, unlockInfo)
+	require.Equal(t, HashTypeHash160, unlockInfo.HashType)
+
+	// dust: referral fee is rounded up to 546
+	require.True(t, sc.CanBeSpent(20000, 2, 2))
+	require.False(t, sc.CanBeSpent(1800, 2, 0))
+	require.True(t, c.CanBeSpent(1800, 2, 0))
+}
+
+func TestSplitCovenantOpRet(t *testing.T) {
+	referralPkh := bchutil.Hash160([]byte("referral"))
+	c, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	c, err = c.WithReferral(referralPkh, 250)
+	require.NoError(t, err)
+	scriptHash, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+
+	for _, memo := range []string{"", "order-123"} {
+		opRet, err := c.BuildOpRetPkScriptWithMemo(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8, memo)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(opRet), 223)
+		info := getHtlcLockInfo(opRet)
+		require.NotNil(t, info)
+		require.Equal(t, memo, info.Memo)
+		require.Equal(t, referralPkh, []byte(info.ReferralPkh))
+		require.Equal(t, uint16(250), info.ReferralBPS)
+
+		c2, err := TestNet3.NewCovenantOfDeposit(info)
+		require.NoError(t, err)
+		scriptHash2, err := c2.GetRedeemScriptHash()
+		require.NoError(t, err)
+		require.Equal(t, scriptHash, scriptHash2)
+	}
+
+	dump, err := c.Dump()
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(referralPkh), dump.Args.ReferralPkh)
+	require.Equal(t, uint16(250), dump.Args.ReferralBPS)
+}
+
+func TestTimeBasedExpiration(t *testing.T) {
+	c, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	_, err = c.WithExpirationType(ExpirationType(3))
+	require.ErrorContains(t, err, "invalid expiration type")
+	tc, err := c.WithExpirationType(ExpirationTypeTime)
+	require.NoError(t, err)
+	require.Equal(t, ExpirationTypeBlocks, c.ExpirationType())
+	require.Equal(t, ExpirationTypeTime, tc.ExpirationType())
+	p2sh1, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	p2sh2, err := tc.GetRedeemScriptHash()
+	require.NoError(t, err)
+	require.NotEqual(t, p2sh1, p2sh2)
+
+	// the OP_RETURN carries the relative lock time with the type flag
+	opRet, err := tc.BuildOpRetPkScript(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8)
+	require.NoError(t, err)
+	retData, err := txscript.PushedData(opRet)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x00, 0x40, 0x00, testExpiration}, retData[4])
+	info := getHtlcLockInfo(opRet)
+	require.NotNil(t, info)
+	require.Equal(t, uint16(testExpiration), info.Expiration)
+	require.Equal(t, ExpirationTypeTime, info.ExpirationType)
+	c2, err := TestNet3.NewCovenantOfDeposit(info)
+	require.NoError(t, err)
+	scriptHash, err := c2.GetRedeemScriptHash()
+	require.NoError(t, err)
+	require.Equal(t, p2sh2, scriptHash)
+
+	retData[4] = []byte{0x00, 0x00, 0x00, testExpiration} // 4 bytes without the type flag
+	builder := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN)
+	for _, data := range retData {
+		builder.AddData(data)
+	}
+	opRet, err = builder.Script()
+	require.NoError(t, err)
+	_, err = decodeHtlcLockInfo(opRet)
+	require.ErrorContains(t, err, "invalid expiration: 0x24")
+
+	// the refund input waits for the relative lock time in seconds
+	inAmt := int64(1000000)
+	p2shPkScript := append(append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, p2sh2...), txscript.OP_EQUAL)
+	execTx := func(tx *wire.MsgTx) error {
+		utxoCache := txscript.NewUtxoCache()
+		utxoCache.AddEntry(0, *wire.NewTxOut(inAmt, p2shPkScript))
+		vm, err := txscript.NewEngine(p2shPkScript, tx, 0,
+			txscript.StandardVerifyFlags, nil, nil, utxoCache, inAmt)
+		if err != nil {
+			return err
+		}
+		return vm.Execute()
+	}
+	tx, err := tc.MakeRefundTx(gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes(), 0, inAmt, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint32(wire.SequenceLockTimeIsSeconds|testExpiration), tx.TxIn[0].Sequence)
+	require.NoError(t, execTx(tx))
+	tx.TxIn[0].Sequence = testExpiration
+	require.Error(t, execTx(tx))
+
+	dump, err := tc.Dump()
+	require.NoError(t, err)
+	require.Equal(t, "time", dump.Args.ExpirationType)
+}
+
+func TestRefundableIn(t *testing.T) {
+	deposit := &HtlcLockInfo{Expiration: 36}
+	require.Equal(t, int64(37), deposit.RefundableIn(0, 0, 1000))
+	require.Equal(t, int64(27), deposit.RefundableIn(10, 0, 1000))
+	require.Equal(t, int64(0), deposit.RefundableIn(37, 0, 1000))
+
+	deposit.ExpirationType = ExpirationTypeTime
+	require.Equal(t, int64(36*512), deposit.RefundableIn(0, 0, 1000))
+	require.Equal(t, int64(36*512-600), deposit.RefundableIn(1, 1000, 1600))
+	require.Equal(t, int64(0), deposit.RefundableIn(100, 1000, 1000+36*512))
+}
+
+func TestTimestampExpiration(t *testing.T) {
+	expiresAt := uint32(1700000000)
+	c, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	_, err = c.WithExpiresAt(testExpiration)
+	require.ErrorContains(t, err, "expiresAt is not a timestamp")
+	_, err = c.WithExpirationType(ExpirationTypeTimestamp)
+	require.ErrorContains(t, err, "timestamp expiration needs a timestamp")
+	ac, err := c.WithExpiresAt(expiresAt)
+	require.NoError(t, err)
+	require.Equal(t, ExpirationTypeTimestamp, ac.ExpirationType())
+	require.Equal(t, expiresAt, ac.ExpiresAt())
+
+	script, err := ac.BuildFullRedeemScript()
+	require.NoError(t, err)
+	disasm, err := txscript.DisasmString(script)
+	require.NoError(t, err)
+	require.Contains(t, disasm, "OP_CHECKLOCKTIMEVERIFY")
+	require.NotContains(t, disasm, "OP_CHECKSEQUENCEVERIFY")
+
+	// the OP_RETURN carries the timestamp
+	opRet, err := ac.BuildOpRetPkScript(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8)
+	require.NoError(t, err)
+	info := getHtlcLockInfo(opRet)
+	require.NotNil(t, info)
+	require.Equal(t, ExpirationTypeTimestamp, info.ExpirationType)
+	require.Equal(t, expiresAt, info.ExpiresAt)
+	require.Equal(t, uint16(0), info.Expiration)
+	c2, err := TestNet3.NewCovenantOfDeposit(info)
+	require.NoError(t, err)
+	p2sh, err := ac.GetRedeemScriptHash()
+	require.NoError(t, err)
+	scriptHash, err := c2.GetRedeemScriptHash()
+	require.NoError(t, err)
+	require.Equal(t, p2sh, scriptHash)
+
+	// the refund tx uses the timestamp as lock time
+	inAmt := int64(1000000)
+	p2shPkScript := append(append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, p2sh...), txscript.OP_EQUAL)
+	execTx := func(tx *wire.MsgTx) error {
+		utxoCache := txscript.NewUtxoCache()
+		utxoCache.AddEntry(0, *wire.NewTxOut(inAmt, p2shPkScript))
+		vm, err := txscript.NewEngine(p2shPkScript, tx, 0,
+			txscript.StandardVerifyFlags, nil, nil, utxoCache, inAmt)
+		if err != nil {
+			return err
+		}
+		return vm.Execute()
+	}
+	txid := gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes()
+	tx, err := ac.WithLockTime(800000).MakeRefundTx(txid, 0, inAmt, 2)
+	require.NoError(t, err)
+	require.Equal(t, expiresAt, tx.LockTime)
+	require.Equal(t, wire.MaxTxInSequenceNum-1, tx.TxIn[0].Sequence)
+	require.NoError(t, execTx(tx))
+	tx.LockTime = expiresAt - 1
+	require.Error(t, execTx(tx))
+
+	// receipts are found as those of relative expirations
+	tx, err = ac.MakeUnlockTx(txid, 0, inAmt, 2, testSecretKey)
+	require.NoError(t, err)
+	require.NoError(t, execTx(tx))
+	unlockInfo := getHtlcUnlockInfo(tx.TxIn[0].SignatureScript)
+	require.NotNil(t, unlockInfo)
+	require.Equal(t, "v1", unlockInfo.TemplateVersion)
+
+	deposit := &HtlcLockInfo{ExpirationType: ExpirationTypeTimestamp, ExpiresAt: expiresAt}
+	require.Equal(t, int64(100), deposit.RefundableIn(1, 0, int64(expiresAt)-100))
+	require.Equal(t, int64(0), deposit.RefundableIn(1, 0, int64(expiresAt)))
+	require.Equal(t, "seconds", deposit.ExpirationType.Unit())
 }
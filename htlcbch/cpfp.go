@@ -0,0 +1,156 @@
+package htlcbch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+)
+
+// MakeCpfpTx creates a child tx which spends the P2PKH outputs of parentTx
+// that are paid to fromKey, the miner fee is large enough to pay for
+// both parent and child at the given fee rate (child-pays-for-parent).
+func MakeCpfpTx(
+	parentTx *wire.MsgTx,
+	fromKey *bchec.PrivateKey,
+	minerFeeRate uint64,
+) (*wire.MsgTx, error) {
+	return MakeCpfpTxWithSigType(parentTx, fromKey, minerFeeRate, SigTypeECDSA, 0)
+}
+
+// MakeCpfpTxWithSigType is like MakeCpfpTx, but the input is signed with sigType,
+// and lockTime (anti-fee-sniping, see useLockTime) is set if it is not 0
+func MakeCpfpTxWithSigType(
+	parentTx *wire.MsgTx,
+	fromKey *bchec.PrivateKey,
+	minerFeeRate uint64,
+	sigType SigType,
+	lockTime uint32,
+) (*wire.MsgTx, error) {
+	return MakePackageCpfpTx(parentTx, 0, 0, fromKey, minerFeeRate, sigType, lockTime)
+}
+
+// MakePackageCpfpTx is like MakeCpfpTxWithSigType, but parentTx may be a CPFP tx itself,
+// whose unconfirmed ancestors take ancestorSize bytes, and ancestorFee is already paid
+// by parentTx and its ancestors (fees of txs not made by CPFP are not counted).
+// BCH has no RBF, so a stuck tx is bumped again by chaining a new child off the last one,
+// and the new child pays for the whole package: parentTx, its ancestors and itself.
+func MakePackageCpfpTx(
+	parentTx *wire.MsgTx,
+	ancestorSize int64,
+	ancestorFee int64,
+	fromKey *bchec.PrivateKey,
+	minerFeeRate uint64,
+	sigType SigType,
+	lockTime uint32,
+) (*wire.MsgTx, error) {
+	return makePackageCpfpTx(parentTx, ancestorSize, ancestorFee, fromKey, nil, minerFeeRate, sigType, lockTime)
+}
+
+// MakeForwardTx is like MakeCpfpTxWithSigType, but the output is paid to toAddr, e.g. a multisig
+// treasury, so that outputs which can only be paid to fromKey (e.g. by HTLC covenants) are forwarded
+func MakeForwardTx(
+	parentTx *wire.MsgTx,
+	fromKey *bchec.PrivateKey,
+	toAddr bchutil.Address,
+	minerFeeRate uint64,
+	sigType SigType,
+	lockTime uint32,
+) (*wire.MsgTx, error) {
+	toPkScript, err := txscript.PayToAddrScript(toAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pkScript: %w", err)
+	}
+	return makePackageCpfpTx(parentTx, 0, 0, fromKey, toPkScript, minerFeeRate, sigType, lockTime)
+}
+
+// the output is paid to toPkScript, or back to fromKey if it is nil
+func makePackageCpfpTx(
+	parentTx *wire.MsgTx,
+	ancestorSize int64,
+	ancestorFee int64,
+	fromKey *bchec.PrivateKey,
+	toPkScript []byte,
+	minerFeeRate uint64,
+	sigType SigType,
+	lockTime uint32,
+) (*wire.MsgTx, error) {
+	fromPk := fromKey.PubKey().SerializeCompressed()
+	fromPkh := bchutil.Hash160(fromPk)
+	pkScript, err := payToPubKeyHashPkScript(fromPkh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pkScript: %w", err)
+	}
+	if toPkScript == nil {
+		toPkScript = pkScript
+	}
+
+	// all outputs paid to fromKey are spent, e.g. those of a batch receipt tx
+	var vouts []uint32
+	var inAmt int64
+	for i, txOut := range parentTx.TxOut {
+		if bytes.Equal(txOut.PkScript, pkScript) {
+			vouts = append(vouts, uint32(i))
+			inAmt += txOut.Value
+		}
+	}
+	if len(vouts) == 0 {
+		return nil, fmt.Errorf("no output is paid to %s", hex.EncodeToString(fromPkh))
+	}
+
+	parentSize := int64(len(MsgTxToBytes(parentTx)))
+
+	// estimate miner fee
+	tx, err := makeCpfpTx(parentTx, vouts, pkScript, toPkScript, fromKey, 1000, sigType, lockTime)
+	if err != nil {
+		return nil, err
+	}
+	// make tx
+	childSize := int64(getStableTxSize(tx, sigType))
+	minerFee := (ancestorSize+parentSize+childSize)*int64(minerFeeRate) - ancestorFee
+	if minerFee < childSize { // min relay fee of the child itself
+		minerFee = childSize
+	}
+	if inAmt-minerFee <= dustAmt {
+		return nil, fmt.Errorf("insufficient input value: %d < %d", inAmt, minerFee+dustAmt)
+	}
+	return makeCpfpTx(parentTx, vouts, pkScript, toPkScript, fromKey, minerFee, sigType, lockTime)
+}
+
+func makeCpfpTx(
+	parentTx *wire.MsgTx, vouts []uint32, // input info
+	pkScript []byte,
+	toPkScript []byte,
+	fromKey *bchec.PrivateKey,
+	minerFee int64,
+	sigType SigType,
+	lockTime uint32,
+) (*wire.MsgTx, error) {
+	fromPk := fromKey.PubKey().SerializeCompressed()
+	parentTxid, err := hex.DecodeString(parentTx.TxHash().String())
+	if err != nil {
+		return nil, err
+	}
+
+	sigScriptFn := func(sig []byte) ([]byte, error) {
+		return payToPubKeyHashSigScript(sig, fromPk)
+	}
+
+	builder := newMsgTxBuilder().
+		useSigType(sigType).
+		useLockTime(lockTime)
+	inAmt := int64(0)
+	for _, vout := range vouts {
+		builder.addInput(parentTxid, vout, 0, nil)
+		inAmt += parentTx.TxOut[vout].Value
+	}
+	builder.addPkScriptOutput(toPkScript, inAmt-minerFee)
+	for i, vout := range vouts {
+		builder.sign(i, parentTx.TxOut[vout].Value, pkScript, fromKey, sigScriptFn)
+	}
+	return builder.build()
+}
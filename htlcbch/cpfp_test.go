@@ -0,0 +1,162 @@
+package htlcbch
+
+import (
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeCpfpTx(t *testing.T) {
+	c, err := NewCovenant(
+		testSenderPkh,
+		testRecipientPkh,
+		testSecretHash,
+		testExpiration,
+		testPenaltyBPS,
+		&chaincfg.TestNet3Params,
+	)
+	require.NoError(t, err)
+	parentTx, err := c.MakeRefundTx(
+		gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes(),
+		1,
+		100000000,
+		1,
+	)
+	require.NoError(t, err)
+
+	childTx, err := MakeCpfpTx(parentTx, testSenderWIF.PrivKey, 3)
+	require.NoError(t, err)
+	require.Len(t, childTx.TxIn, 1)
+	require.Len(t, childTx.TxOut, 1)
+	require.Equal(t, parentTx.TxHash(), childTx.TxIn[0].PreviousOutPoint.Hash)
+	require.Equal(t, uint32(0), childTx.TxIn[0].PreviousOutPoint.Index)
+
+	inAmt := parentTx.TxOut[0].Value
+	minerFee := inAmt - childTx.TxOut[0].Value
+	packageSize := int64(len(MsgTxToBytes(parentTx)) + len(MsgTxToBytes(childTx)))
+	require.InDelta(t, packageSize*3, minerFee, 6) // signature size may vary
+
+	// check signature
+	vm, err := txscript.NewEngine(parentTx.TxOut[0].PkScript, childTx, 0,
+		txscript.StandardVerifyFlags, nil, nil, nil, inAmt)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+
+	// output is not paid to key
+	otherKey, err := bchec.NewPrivateKey(bchec.S256())
+	require.NoError(t, err)
+	_, err = MakeCpfpTx(parentTx, otherKey, 3)
+	require.ErrorContains(t, err, "no output is paid to")
+
+	// all outputs of a batch receipt tx are spent
+	secret2 := gethcmn.Hash{'4', '5', '6'}.Bytes()
+	c1, err := NewTestnet3Covenant(testRecipientPkh, testSenderPkh, testSecretHash, testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+	c2, err := NewTestnet3Covenant(testRecipientPkh, testSenderPkh, HashTypeSha256.HashSecret(secret2),
+		testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+	parentTx, err = MakeBatchUnlockTx([]BatchReceipt{
+		{Covenant: c1.WithBatchable(), TxID: gethcmn.Hash{'u', 't', 'x', 'o', '1'}.Bytes(), InAmt: 100000, Secret: testSecretKey},
+		{Covenant: c2.WithBatchable(), TxID: gethcmn.Hash{'u', 't', 'x', 'o', '2'}.Bytes(), InAmt: 200000, Secret: secret2},
+	}, 1, 0)
+	require.NoError(t, err)
+	childTx, err = MakeCpfpTx(parentTx, testSenderWIF.PrivKey, 1)
+	require.NoError(t, err)
+	require.Len(t, childTx.TxIn, 2)
+	require.Len(t, childTx.TxOut, 1)
+	inAmt = parentTx.TxOut[0].Value + parentTx.TxOut[1].Value
+	packageSize = int64(len(MsgTxToBytes(parentTx)) + len(MsgTxToBytes(childTx)))
+	require.InDelta(t, packageSize, inAmt-childTx.TxOut[0].Value, 4)
+
+	utxoCache := txscript.NewUtxoCache()
+	for i, txOut := range parentTx.TxOut {
+		utxoCache.AddEntry(i, *txOut)
+	}
+	for i, txOut := range parentTx.TxOut {
+		vm, err := txscript.NewEngine(txOut.PkScript, childTx, i,
+			txscript.StandardVerifyFlags, nil, nil, utxoCache, txOut.Value)
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute())
+	}
+}
+
+func TestMakePackageCpfpTx(t *testing.T) {
+	c, err := NewCovenant(
+		testSenderPkh,
+		testRecipientPkh,
+		testSecretHash,
+		testExpiration,
+		testPenaltyBPS,
+		&chaincfg.TestNet3Params,
+	)
+	require.NoError(t, err)
+	parentTx, err := c.MakeRefundTx(
+		gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes(),
+		1,
+		100000000,
+		1,
+	)
+	require.NoError(t, err)
+	childTx, err := MakeCpfpTx(parentTx, testSenderWIF.PrivKey, 2)
+	require.NoError(t, err)
+	parentSize := int64(len(MsgTxToBytes(parentTx)))
+	childSize := int64(len(MsgTxToBytes(childTx)))
+	childFee := parentTx.TxOut[0].Value - childTx.TxOut[0].Value
+
+	// chained off the first child, pays for the whole package at the new fee rate
+	grandchildTx, err := MakePackageCpfpTx(childTx, parentSize, childFee, testSenderWIF.PrivKey, 4, SigTypeECDSA, 0)
+	require.NoError(t, err)
+	require.Equal(t, childTx.TxHash(), grandchildTx.TxIn[0].PreviousOutPoint.Hash)
+	grandchildFee := childTx.TxOut[0].Value - grandchildTx.TxOut[0].Value
+	packageSize := parentSize + childSize + int64(len(MsgTxToBytes(grandchildTx)))
+	require.InDelta(t, packageSize*4, childFee+grandchildFee, 12) // signature size may vary
+
+	// ancestors already paid enough, the min relay fee is paid
+	grandchildTx, err = MakePackageCpfpTx(childTx, parentSize, childFee, testSenderWIF.PrivKey, 1, SigTypeECDSA, 0)
+	require.NoError(t, err)
+	grandchildFee = childTx.TxOut[0].Value - grandchildTx.TxOut[0].Value
+	require.InDelta(t, len(MsgTxToBytes(grandchildTx)), grandchildFee, 2)
+}
+
+func TestMakeForwardTx(t *testing.T) {
+	c, err := NewCovenant(
+		testSenderPkh,
+		testRecipientPkh,
+		testSecretHash,
+		testExpiration,
+		testPenaltyBPS,
+		&chaincfg.TestNet3Params,
+	)
+	require.NoError(t, err)
+	parentTx, err := c.MakeRefundTx(
+		gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes(),
+		1,
+		100000000,
+		1,
+	)
+	require.NoError(t, err)
+
+	toAddr, err := bchutil.NewAddressScriptHashFromHash(testRecipientPkh, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	childTx, err := MakeForwardTx(parentTx, testSenderWIF.PrivKey, toAddr, 3, SigTypeECDSA, 0)
+	require.NoError(t, err)
+	require.Len(t, childTx.TxOut, 1)
+	require.Equal(t, parentTx.TxHash(), childTx.TxIn[0].PreviousOutPoint.Hash)
+	toPkScript, err := txscript.PayToAddrScript(toAddr)
+	require.NoError(t, err)
+	require.Equal(t, toPkScript, childTx.TxOut[0].PkScript)
+
+	inAmt := parentTx.TxOut[0].Value
+	packageSize := int64(len(MsgTxToBytes(parentTx)) + len(MsgTxToBytes(childTx)))
+	require.InDelta(t, packageSize*3, inAmt-childTx.TxOut[0].Value, 6)
+
+	vm, err := txscript.NewEngine(parentTx.TxOut[0].PkScript, childTx, 0,
+		txscript.StandardVerifyFlags, nil, nil, nil, inAmt)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+}
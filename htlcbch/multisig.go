@@ -0,0 +1,242 @@
+package htlcbch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// 3 + 34 * n bytes redeem script must not exceed the 520 bytes push limit
+	maxMultisigKeys = 15
+)
+
+// MultisigTreasury is an m-of-n P2SH multisig address, pubkeys are sorted as BIP-67.
+// Its inputs are signed with ECDSA only, since Schnorr CHECKMULTISIG needs a signer bitfield.
+type MultisigTreasury struct {
+	m            int
+	pubKeys      [][]byte // 33 bytes compressed pubkeys
+	redeemScript []byte
+	net          *chaincfg.Params
+}
+
+func NewMultisigTreasury(m int, pubKeys [][]byte, net *chaincfg.Params) (*MultisigTreasury, error) {
+	n := len(pubKeys)
+	if n == 0 || n > maxMultisigKeys {
+		return nil, fmt.Errorf("number of pubkeys must be in [1, %d]: %d", maxMultisigKeys, n)
+	}
+	if m < 1 || m > n {
+		return nil, fmt.Errorf("m must be in [1, %d]: %d", n, m)
+	}
+
+	sorted := make([][]byte, n)
+	for i, pk := range pubKeys {
+		if len(pk) != 33 {
+			return nil, fmt.Errorf("pubkey#%d is not 33 bytes compressed", i)
+		}
+		if _, err := bchec.ParsePubKey(pk, bchec.S256()); err != nil {
+			return nil, fmt.Errorf("invalid pubkey#%d: %w", i, err)
+		}
+		sorted[i] = pk
+	}
+	slices.SortFunc(sorted, func(a, b []byte) bool {
+		return bytes.Compare(a, b) < 0
+	})
+	for i := 1; i < n; i++ {
+		if bytes.Equal(sorted[i-1], sorted[i]) {
+			return nil, fmt.Errorf("duplicated pubkey: %s", hex.EncodeToString(sorted[i]))
+		}
+	}
+
+	// OP_m <pubkey>... OP_n OP_CHECKMULTISIG
+	builder := txscript.NewScriptBuilder().AddInt64(int64(m))
+	for _, pk := range sorted {
+		builder.AddData(pk)
+	}
+	redeemScript, err := builder.AddInt64(int64(n)).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MultisigTreasury{
+		m:            m,
+		pubKeys:      sorted,
+		redeemScript: redeemScript,
+		net:          net,
+	}, nil
+}
+
+func (t *MultisigTreasury) M() int {
+	return t.m
+}
+
+func (t *MultisigTreasury) PubKeys() [][]byte {
+	return t.pubKeys
+}
+
+func (t *MultisigTreasury) RedeemScript() []byte {
+	return t.redeemScript
+}
+
+func (t *MultisigTreasury) GetAddress() (*bchutil.AddressScriptHash, error) {
+	return bchutil.NewAddressScriptHash(t.redeemScript, t.net)
+}
+
+func (t *MultisigTreasury) HasPubKey(pk []byte) bool {
+	for _, pubKey := range t.pubKeys {
+		if bytes.Equal(pubKey, pk) {
+			return true
+		}
+	}
+	return false
+}
+
+// <OP_0><push 72><DER sig + sighash type>...<push redeem script><redeem script>
+func (t *MultisigTreasury) sigScriptLen() int {
+	n := len(t.redeemScript)
+	pushLen := 2 // OP_PUSHDATA1 <len>
+	if n > 0xff {
+		pushLen = 3 // OP_PUSHDATA2 <len>
+	}
+	return 1 + t.m*(1+72) + pushLen + n
+}
+
+// MakeSpendTx creates an unsigned tx which pays outAmt to toAddr from inputs of the treasury,
+// the miner fee is deducted from outAmt and the change is sent back to the treasury.
+// Inputs and outputs are sorted as BIP-69, so all co-signers sign the same tx.
+func (t *MultisigTreasury) MakeSpendTx(
+	inputs []InputInfo, // inputs info
+	toAddr bchutil.Address, outAmt int64, // output info
+	minerFeeRate uint64,
+) (*wire.MsgTx, error) {
+	// estimate miner fee
+	tx, err := t.makeSpendTx(inputs, toAddr, outAmt, 0)
+	if err != nil {
+		return nil, err
+	}
+	// make tx
+	txSize := len(MsgTxToBytes(tx)) + len(tx.TxIn)*t.sigScriptLen()
+	minerFee := int64(txSize) * int64(minerFeeRate)
+	if outAmt-minerFee <= dustAmt {
+		return nil, fmt.Errorf("insufficient output value: %d < %d", outAmt, minerFee+dustAmt)
+	}
+	return t.makeSpendTx(inputs, toAddr, outAmt, minerFee)
+}
+
+func (t *MultisigTreasury) makeSpendTx(
+	inputs []InputInfo, // inputs info
+	toAddr bchutil.Address, outAmt int64, // output info
+	minerFee int64,
+) (*wire.MsgTx, error) {
+	changeAddr, err := t.GetAddress()
+	if err != nil {
+		return nil, fmt.Errorf("failed to calc p2sh address: %w", err)
+	}
+
+	inputs = sortInputs(inputs)
+	builder := newMsgTxBuilder()
+	var totalInAmt int64
+	for _, input := range inputs {
+		builder.addInput(input.TxID, input.Vout, 0, nil)
+		totalInAmt += input.Amount
+	}
+	changeAmt := totalInAmt - outAmt
+	if changeAmt < 0 {
+		return nil, fmt.Errorf("insufficient input value: %d < %d", totalInAmt, outAmt)
+	}
+	builder.addOutput(toAddr, outAmt-minerFee)
+	builder.addChange(changeAddr, changeAmt)
+	builder.sortOutputs(0)
+	return builder.build()
+}
+
+// GetSigHash returns the hash signed by co-signers for the input idx of tx
+func (t *MultisigTreasury) GetSigHash(tx *wire.MsgTx, idx int, inAmt int64) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, fmt.Errorf("input index out of range: %d", idx)
+	}
+	hashType := txscript.SigHashAll | txscript.SigHashForkID
+	return txscript.CalcSignatureHash(t.redeemScript, txscript.NewTxSigHashes(tx),
+		hashType, tx, idx, inAmt, true)
+}
+
+// SignInput returns the ECDSA signature (with sighash type) of privKey for the input idx of tx
+func (t *MultisigTreasury) SignInput(tx *wire.MsgTx, idx int, inAmt int64,
+	privKey *bchec.PrivateKey) ([]byte, error) {
+
+	if !t.HasPubKey(privKey.PubKey().SerializeCompressed()) {
+		return nil, fmt.Errorf("key is not a co-signer of the treasury")
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, fmt.Errorf("input index out of range: %d", idx)
+	}
+	hashType := txscript.SigHashAll | txscript.SigHashForkID
+	return txscript.RawTxInECDSASignature(tx, idx, t.redeemScript, hashType, privKey, inAmt)
+}
+
+// VerifySig checks the signature (with sighash type) of pubKey for the input idx of tx
+func (t *MultisigTreasury) VerifySig(tx *wire.MsgTx, idx int, inAmt int64, pubKey, sig []byte) error {
+	if !t.HasPubKey(pubKey) {
+		return fmt.Errorf("not a co-signer: %s", hex.EncodeToString(pubKey))
+	}
+	hashType := txscript.SigHashAll | txscript.SigHashForkID
+	if len(sig) < 2 || sig[len(sig)-1] != byte(hashType) {
+		return fmt.Errorf("sighash type must be ALL|FORKID")
+	}
+	parsedSig, err := bchec.ParseDERSignature(sig[:len(sig)-1], bchec.S256())
+	if err != nil {
+		return fmt.Errorf("invalid DER signature: %w", err)
+	}
+	parsedPk, err := bchec.ParsePubKey(pubKey, bchec.S256())
+	if err != nil {
+		return err
+	}
+	sigHash, err := t.GetSigHash(tx, idx, inAmt)
+	if err != nil {
+		return err
+	}
+	if !parsedSig.Verify(sigHash, parsedPk) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// FinalizeTx fills sig scripts of tx, sigs[i] maps hex pubkeys to their signatures of the input i,
+// the signatures are expected to be verified by VerifySig, m of them are used in pubkey order
+func (t *MultisigTreasury) FinalizeTx(tx *wire.MsgTx, sigs []map[string][]byte) error {
+	if len(sigs) != len(tx.TxIn) {
+		return fmt.Errorf("signatures of %d inputs are required, got %d", len(tx.TxIn), len(sigs))
+	}
+	for i, inputSigs := range sigs {
+		// OP_CHECKMULTISIG bug: one extra item is popped
+		builder := txscript.NewScriptBuilder().AddOp(txscript.OP_0)
+		nSigs := 0
+		for _, pk := range t.pubKeys {
+			sig, ok := inputSigs[hex.EncodeToString(pk)]
+			if !ok {
+				continue
+			}
+			builder.AddData(sig)
+			nSigs++
+			if nSigs == t.m {
+				break
+			}
+		}
+		if nSigs < t.m {
+			return fmt.Errorf("input#%d has %d signatures, %d are required", i, nSigs, t.m)
+		}
+		sigScript, err := builder.AddData(t.redeemScript).Script()
+		if err != nil {
+			return err
+		}
+		tx.TxIn[i].SignatureScript = sigScript
+	}
+	return nil
+}
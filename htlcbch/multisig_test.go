@@ -0,0 +1,109 @@
+package htlcbch
+
+import (
+	"encoding/hex"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/txscript"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMultisigTreasury(t *testing.T) {
+	pk1 := testSenderWIF.PrivKey.PubKey().SerializeCompressed()
+	pk2 := testRecipientWIF.PrivKey.PubKey().SerializeCompressed()
+
+	_, err := NewMultisigTreasury(1, nil, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "number of pubkeys must be in [1, 15]: 0")
+	_, err = NewMultisigTreasury(3, [][]byte{pk1, pk2}, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "m must be in [1, 2]: 3")
+	_, err = NewMultisigTreasury(1, [][]byte{pk1, pk2[1:]}, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "pubkey#1 is not 33 bytes compressed")
+	_, err = NewMultisigTreasury(1, [][]byte{pk1, pk1}, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "duplicated pubkey")
+
+	// pubkey order does not matter
+	t1, err := NewMultisigTreasury(2, [][]byte{pk1, pk2}, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	t2, err := NewMultisigTreasury(2, [][]byte{pk2, pk1}, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Equal(t, t1.RedeemScript(), t2.RedeemScript())
+	addr, err := t1.GetAddress()
+	require.NoError(t, err)
+	require.True(t, addr.IsForNet(&chaincfg.TestNet3Params))
+	require.True(t, t1.HasPubKey(pk1))
+}
+
+func TestMultisigTreasury_spend(t *testing.T) {
+	key3, _ := bchec.PrivKeyFromBytes(bchec.S256(), gethcmn.Hash{'k', 'e', 'y', '3'}.Bytes())
+	keys := []*bchec.PrivateKey{testSenderWIF.PrivKey, testRecipientWIF.PrivKey, key3}
+	pubKeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		pubKeys[i] = key.PubKey().SerializeCompressed()
+	}
+	treasury, err := NewMultisigTreasury(2, pubKeys, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+
+	inputs := []InputInfo{
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd', '2'}.Bytes(), Vout: 0, Amount: 30000},
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd', '1'}.Bytes(), Vout: 1, Amount: 20000},
+	}
+	toAddr, err := TestNet3.NewP2PKHAddress(testRecipientPkh)
+	require.NoError(t, err)
+	tx, err := treasury.MakeSpendTx(inputs, toAddr, 40000, 2)
+	require.NoError(t, err)
+	require.Len(t, tx.TxIn, 2)
+	require.Len(t, tx.TxOut, 2)
+	inAmts := []int64{20000, 30000} // sorted by txid
+
+	// only 2 of 3 co-signers sign
+	otherKey, _ := bchec.PrivKeyFromBytes(bchec.S256(), gethcmn.Hash{'k', 'e', 'y', '4'}.Bytes())
+	_, err = treasury.SignInput(tx, 0, inAmts[0], otherKey)
+	require.ErrorContains(t, err, "key is not a co-signer of the treasury")
+	sigs := make([]map[string][]byte, len(tx.TxIn))
+	for i := range tx.TxIn {
+		sigs[i] = map[string][]byte{}
+		for _, key := range keys[1:] {
+			sig, err := treasury.SignInput(tx, i, inAmts[i], key)
+			require.NoError(t, err)
+			pk := key.PubKey().SerializeCompressed()
+			require.NoError(t, treasury.VerifySig(tx, i, inAmts[i], pk, sig))
+			sigs[i][hex.EncodeToString(pk)] = sig
+		}
+	}
+	sig0 := sigs[0][hex.EncodeToString(pubKeys[1])]
+	require.ErrorContains(t, treasury.VerifySig(tx, 1, inAmts[1], pubKeys[1], sig0),
+		"signature verification failed")
+	require.ErrorContains(t, treasury.VerifySig(tx, 0, inAmts[0], pubKeys[2], sig0),
+		"signature verification failed")
+	require.ErrorContains(t, treasury.VerifySig(tx, 0, inAmts[0], pubKeys[1], sig0[:len(sig0)-1]),
+		"sighash type must be ALL|FORKID")
+
+	require.ErrorContains(t, treasury.FinalizeTx(tx, sigs[:1]), "signatures of 2 inputs are required, got 1")
+	require.ErrorContains(t, treasury.FinalizeTx(tx, []map[string][]byte{sigs[0], {}}),
+		"input#1 has 0 signatures, 2 are required")
+	require.NoError(t, treasury.FinalizeTx(tx, sigs))
+
+	// miner fee is deducted from output
+	toPkScript, err := payToPubKeyHashPkScript(testRecipientPkh)
+	require.NoError(t, err)
+	require.Equal(t, toPkScript, tx.TxOut[1].PkScript)
+	require.Equal(t, int64(10000), tx.TxOut[0].Value)
+	minerFee := 40000 - tx.TxOut[1].Value
+	require.InDelta(t, len(MsgTxToBytes(tx))*2, minerFee, 10)
+
+	// check signatures
+	addr, err := treasury.GetAddress()
+	require.NoError(t, err)
+	prevPkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	require.Equal(t, prevPkScript, tx.TxOut[0].PkScript) // change
+	for i := range tx.TxIn {
+		vm, err := txscript.NewEngine(prevPkScript, tx, i,
+			txscript.StandardVerifyFlags, nil, nil, nil, inAmts[i])
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute())
+	}
+}
@@ -0,0 +1,110 @@
+package htlcbch
+
+import (
+	"fmt"
+
+	"github.com/gcash/bchd/bchec"
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+)
+
+// MakePayTx creates a tx which pays outAmt to toPkh from P2PKH inputs of fromKey,
+// the miner fee is deducted from outAmt and the change is sent back to fromKey.
+// Inputs and outputs are sorted as BIP-69, so the tx is deterministic.
+func MakePayTx(
+	fromKey *bchec.PrivateKey,
+	inputs []InputInfo, // inputs info
+	toPkh []byte, outAmt int64, // output info
+	minerFeeRate uint64,
+	net *chaincfg.Params,
+) (*wire.MsgTx, error) {
+	return MakePayTxWithSigType(fromKey, inputs, toPkh, outAmt, minerFeeRate, SigTypeECDSA, 0, net)
+}
+
+// MakePayTxWithSigType is like MakePayTx, but inputs are signed with sigType,
+// and lockTime (anti-fee-sniping, see useLockTime) is set if it is not 0
+func MakePayTxWithSigType(
+	fromKey *bchec.PrivateKey,
+	inputs []InputInfo, // inputs info
+	toPkh []byte, outAmt int64, // output info
+	minerFeeRate uint64,
+	sigType SigType,
+	lockTime uint32,
+	net *chaincfg.Params,
+) (*wire.MsgTx, error) {
+	toAddr, err := bchutil.NewAddressPubKeyHash(toPkh, net)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calc p2pkh address: %w", err)
+	}
+	return MakePayToAddrTx(fromKey, inputs, toAddr, outAmt, minerFeeRate, sigType, lockTime, net)
+}
+
+// MakePayToAddrTx is like MakePayTxWithSigType, but toAddr can be P2SH
+func MakePayToAddrTx(
+	fromKey *bchec.PrivateKey,
+	inputs []InputInfo, // inputs info
+	toAddr bchutil.Address, outAmt int64, // output info
+	minerFeeRate uint64,
+	sigType SigType,
+	lockTime uint32,
+	net *chaincfg.Params,
+) (*wire.MsgTx, error) {
+	// estimate miner fee
+	tx, err := makePayTx(fromKey, inputs, toAddr, outAmt, 1000, sigType, lockTime, net)
+	if err != nil {
+		return nil, err
+	}
+	// make tx
+	minerFee := int64(getStableTxSize(tx, sigType)) * int64(minerFeeRate)
+	if outAmt-minerFee <= dustAmt {
+		return nil, fmt.Errorf("insufficient output value: %d < %d", outAmt, minerFee+dustAmt)
+	}
+	return makePayTx(fromKey, inputs, toAddr, outAmt, minerFee, sigType, lockTime, net)
+}
+
+func makePayTx(
+	fromKey *bchec.PrivateKey,
+	inputs []InputInfo, // inputs info
+	toAddr bchutil.Address, outAmt int64, // output info
+	minerFee int64,
+	sigType SigType,
+	lockTime uint32,
+	net *chaincfg.Params,
+) (*wire.MsgTx, error) {
+	fromPk := fromKey.PubKey().SerializeCompressed()
+	fromPkh := bchutil.Hash160(fromPk)
+
+	changeAddr, err := bchutil.NewAddressPubKeyHash(fromPkh, net)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calc p2pkh address: %w", err)
+	}
+
+	prevPkScript, err := payToPubKeyHashPkScript(fromPkh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pkScript: %w", err)
+	}
+
+	sigScriptFn := func(sig []byte) ([]byte, error) {
+		return payToPubKeyHashSigScript(sig, fromPk)
+	}
+
+	inputs = sortInputs(inputs)
+	builder := newMsgTxBuilder().useSigType(sigType).useLockTime(lockTime)
+	var totalInAmt int64
+	for _, input := range inputs {
+		builder.addInput(input.TxID, input.Vout, 0, nil)
+		totalInAmt += input.Amount
+	}
+	changeAmt := totalInAmt - outAmt
+	if changeAmt < 0 {
+		return nil, fmt.Errorf("insufficient input value: %d < %d", totalInAmt, outAmt)
+	}
+	builder.addOutput(toAddr, outAmt-minerFee)
+	builder.addChange(changeAddr, changeAmt)
+	builder.sortOutputs(0)
+	for i, utxo := range inputs {
+		builder.sign(i, utxo.Amount, prevPkScript, fromKey, sigScriptFn)
+	}
+	return builder.build()
+}
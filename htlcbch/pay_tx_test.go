@@ -0,0 +1,204 @@
+package htlcbch
+
+import (
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/chaincfg/chainhash"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakePayTx(t *testing.T) {
+	inputs := []InputInfo{
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd'}.Bytes(), Vout: 1, Amount: 20000},
+	}
+	tx, err := MakePayTx(testSenderWIF.PrivKey, inputs, testRecipientPkh, 10000, 2, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Len(t, tx.TxIn, 1)
+	require.Len(t, tx.TxOut, 2)
+
+	// miner fee is deducted from output
+	minerFee := 10000 - tx.TxOut[0].Value
+	require.InDelta(t, len(MsgTxToBytes(tx))*2, minerFee, 2)
+	require.Equal(t, int64(10000), tx.TxOut[1].Value)
+
+	toPkScript, err := payToPubKeyHashPkScript(testRecipientPkh)
+	require.NoError(t, err)
+	require.Equal(t, toPkScript, tx.TxOut[0].PkScript)
+
+	// check signature
+	prevPkScript, err := payToPubKeyHashPkScript(testSenderPkh)
+	require.NoError(t, err)
+	vm, err := txscript.NewEngine(prevPkScript, tx, 0,
+		txscript.StandardVerifyFlags, nil, nil, nil, 20000)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+
+	_, err = MakePayTx(testSenderWIF.PrivKey, inputs, testRecipientPkh, 30000, 2, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "insufficient input value")
+	_, err = MakePayTx(testSenderWIF.PrivKey, inputs, testRecipientPkh, 600, 2, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "insufficient output value")
+}
+
+func TestMakePayTx_deterministic(t *testing.T) {
+	inputs := []InputInfo{
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd', '2'}.Bytes(), Vout: 0, Amount: 20000},
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd', '1'}.Bytes(), Vout: 3, Amount: 30000},
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd', '1'}.Bytes(), Vout: 1, Amount: 40000},
+	}
+	tx1, err := MakePayTx(testSenderWIF.PrivKey, inputs, testRecipientPkh, 60000, 2, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	reversed := []InputInfo{inputs[2], inputs[1], inputs[0]}
+	tx2, err := MakePayTx(testSenderWIF.PrivKey, reversed, testRecipientPkh, 60000, 2, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Equal(t, MsgTxToBytes(tx1), MsgTxToBytes(tx2))
+	require.Equal(t, inputs[2], reversed[0]) // not sorted in place
+
+	// inputs are sorted by txid and vout
+	require.Len(t, tx1.TxIn, 3)
+	require.Equal(t, uint32(1), tx1.TxIn[0].PreviousOutPoint.Index)
+	require.Equal(t, uint32(3), tx1.TxIn[1].PreviousOutPoint.Index)
+	require.Equal(t, uint32(0), tx1.TxIn[2].PreviousOutPoint.Index)
+
+	// outputs are sorted by value, the change is smaller than the payment
+	require.Len(t, tx1.TxOut, 2)
+	require.Equal(t, int64(30000), tx1.TxOut[0].Value)
+	toPkScript, err := payToPubKeyHashPkScript(testRecipientPkh)
+	require.NoError(t, err)
+	require.Equal(t, toPkScript, tx1.TxOut[1].PkScript)
+
+	// the miner fee does not depend on the length of signatures
+	require.Equal(t, int64(60000-getStableTxSize(tx1, SigTypeECDSA)*2), tx1.TxOut[1].Value)
+	require.LessOrEqual(t, len(MsgTxToBytes(tx1)), getStableTxSize(tx1, SigTypeECDSA))
+}
+
+func TestMakePayTx_schnorr(t *testing.T) {
+	inputs := []InputInfo{
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd'}.Bytes(), Vout: 1, Amount: 20000},
+	}
+	tx, err := MakePayTxWithSigType(testSenderWIF.PrivKey, inputs, testRecipientPkh, 10000, 2,
+		SigTypeSchnorr, 0, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Len(t, tx.TxIn[0].SignatureScript, p2pkhSchnorrSigScriptLen)
+	require.Len(t, MsgTxToBytes(tx), getStableTxSize(tx, SigTypeSchnorr))
+
+	// check signature
+	prevPkScript, err := payToPubKeyHashPkScript(testSenderPkh)
+	require.NoError(t, err)
+	vm, err := txscript.NewEngine(prevPkScript, tx, 0,
+		txscript.StandardVerifyFlags, nil, nil, nil, 20000)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+
+	// smaller than ECDSA signed tx, and so is the miner fee
+	tx2, err := MakePayTxWithSigType(testSenderWIF.PrivKey, inputs, testRecipientPkh, 10000, 2,
+		SigTypeECDSA, 0, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Less(t, len(MsgTxToBytes(tx)), len(MsgTxToBytes(tx2)))
+	require.Greater(t, tx.TxOut[0].Value, tx2.TxOut[0].Value)
+
+	_, err = MakePayTxWithSigType(testSenderWIF.PrivKey, inputs, testRecipientPkh, 10000, 2,
+		SigType(2), 0, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "invalid signature type: 2")
+}
+
+func TestMakePayTx_lockTime(t *testing.T) {
+	inputs := []InputInfo{
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd'}.Bytes(), Vout: 1, Amount: 20000},
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd', '2'}.Bytes(), Vout: 0, Amount: 30000},
+	}
+	tx, err := MakePayTxWithSigType(testSenderWIF.PrivKey, inputs, testRecipientPkh, 10000, 2,
+		SigTypeECDSA, 800000, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Equal(t, uint32(800000), tx.LockTime)
+	prevPkScript, err := payToPubKeyHashPkScript(testSenderPkh)
+	require.NoError(t, err)
+	for i, txIn := range tx.TxIn {
+		require.Equal(t, uint32(wire.MaxTxInSequenceNum-1), txIn.Sequence)
+		vm, err := txscript.NewEngine(prevPkScript, tx, i,
+			txscript.StandardVerifyFlags, nil, nil, nil, sortInputs(inputs)[i].Amount)
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute())
+	}
+
+	// same size and miner fee as without lock time
+	tx2, err := MakePayTxWithSigType(testSenderWIF.PrivKey, inputs, testRecipientPkh, 10000, 2,
+		SigTypeECDSA, 0, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), tx2.LockTime)
+	require.Equal(t, uint32(wire.MaxTxInSequenceNum), tx2.TxIn[0].Sequence)
+	require.Equal(t, tx2.TxOut[0].Value, tx.TxOut[0].Value)
+
+	_, err = MakePayTxWithSigType(testSenderWIF.PrivKey, inputs, testRecipientPkh, 10000, 2,
+		SigTypeECDSA, txscript.LockTimeThreshold, &chaincfg.TestNet3Params)
+	require.ErrorContains(t, err, "lock time is not a block height")
+}
+
+func TestParseSigType(t *testing.T) {
+	for _, s := range []string{"", "ecdsa", "schnorr"} {
+		sigType, err := ParseSigType(s)
+		require.NoError(t, err)
+		if s != "" {
+			require.Equal(t, s, sigType.String())
+		}
+	}
+	_, err := ParseSigType("ed25519")
+	require.ErrorContains(t, err, "unknown signature type: ed25519")
+}
+
+func TestMakePayTx_anyoneCanPay(t *testing.T) {
+	inputs := []InputInfo{
+		{TxID: gethcmn.Hash{'t', 'x', 'i', 'd'}.Bytes(), Vout: 1, Amount: 20000},
+	}
+	sigType, err := SigTypeSchnorr.WithSigHashType(
+		txscript.SigHashAll | txscript.SigHashForkID | txscript.SigHashAnyOneCanPay)
+	require.NoError(t, err)
+	require.Equal(t, "schnorr+anyonecanpay", sigType.String())
+	tx, err := MakePayTxWithSigType(testSenderWIF.PrivKey, inputs, testRecipientPkh, 10000, 2,
+		sigType, 0, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Len(t, MsgTxToBytes(tx), getStableTxSize(tx, sigType))
+	pushes, err := txscript.PushedData(tx.TxIn[0].SignatureScript)
+	require.NoError(t, err)
+	require.Equal(t, byte(0xc1), pushes[0][len(pushes[0])-1]) // ALL|FORKID|ANYONECANPAY
+
+	// the signature is still valid after another input is added
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{'x'}, 0), nil))
+	prevPkScript, err := payToPubKeyHashPkScript(testSenderPkh)
+	require.NoError(t, err)
+	vm, err := txscript.NewEngine(prevPkScript, tx, 0,
+		txscript.StandardVerifyFlags, nil, nil, nil, 20000)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+}
+
+func TestSigHashType(t *testing.T) {
+	hashType, err := ParseSigHashType("")
+	require.NoError(t, err)
+	require.Equal(t, txscript.SigHashAll|txscript.SigHashForkID, hashType)
+	require.Equal(t, hashType, SigTypeECDSA.SigHashType())
+	hashType, err = ParseSigHashType("all+anyonecanpay")
+	require.NoError(t, err)
+	sigType, err := SigTypeECDSA.WithSigHashType(hashType)
+	require.NoError(t, err)
+	require.Equal(t, hashType, sigType.SigHashType())
+	require.Equal(t, SigTypeECDSA, sigType.Algorithm())
+	sigType, err = sigType.WithSigHashType(txscript.SigHashAll | txscript.SigHashForkID)
+	require.NoError(t, err)
+	require.Equal(t, SigTypeECDSA, sigType)
+	_, err = ParseSigHashType("single")
+	require.ErrorContains(t, err, "unknown sighash type: single")
+
+	for _, hashType := range []txscript.SigHashType{
+		txscript.SigHashNone | txscript.SigHashForkID,
+		txscript.SigHashSingle | txscript.SigHashForkID | txscript.SigHashAnyOneCanPay,
+	} {
+		_, err = SigTypeECDSA.WithSigHashType(hashType)
+		require.ErrorContains(t, err, "does not commit to all outputs")
+	}
+	_, err = SigTypeECDSA.WithSigHashType(txscript.SigHashAll)
+	require.ErrorContains(t, err, "without FORKID")
+}
@@ -0,0 +1,80 @@
+package htlcbch
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
+)
+
+// ScriptStep is the state of the script engine after an opcode is executed
+type ScriptStep struct {
+	Opcode   string   // with script index and offset, e.g. "02:0012: OP_CHECKSEQUENCEVERIFY", P2SH redeem scripts are #2
+	Stack    []string // hex, top last
+	AltStack []string // hex, top last
+}
+
+// DebugSpend executes the input inIdx of tx opcode by opcode, to diagnose why a spend fails validation.
+// prevOuts are the outputs spent by all inputs of tx, in order, they are used by introspection opcodes
+// of HTLC covenants. It returns the executed steps and the error failing the input, nil if it is valid.
+// Lock times are checked against the tx only, not against the chain.
+func DebugSpend(tx *wire.MsgTx, inIdx int, prevOuts []*wire.TxOut) ([]*ScriptStep, error) {
+	if inIdx < 0 || inIdx >= len(tx.TxIn) {
+		return nil, fmt.Errorf("input index out of range: %d", inIdx)
+	}
+	if len(prevOuts) != len(tx.TxIn) {
+		return nil, fmt.Errorf("expected %d prev outputs, got %d", len(tx.TxIn), len(prevOuts))
+	}
+	utxoCache := txscript.NewUtxoCache()
+	for i, prevOut := range prevOuts {
+		utxoCache.AddEntry(i, *prevOut)
+	}
+	prevOut := prevOuts[inIdx]
+	vm, err := txscript.NewEngine(prevOut.PkScript, tx, inIdx,
+		txscript.StandardVerifyFlags, nil, nil, utxoCache, prevOut.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []*ScriptStep
+	for {
+		opcode, err := vm.DisasmPC()
+		if err != nil {
+			return steps, err
+		}
+		done, err := vm.Step()
+		steps = append(steps, &ScriptStep{
+			Opcode:   opcode,
+			Stack:    hexStack(vm.GetStack()),
+			AltStack: hexStack(vm.GetAltStack()),
+		})
+		if err != nil {
+			return steps, err
+		}
+		if done {
+			return steps, vm.CheckErrorCondition(true)
+		}
+	}
+}
+
+// PrintScriptSteps prints an opcode and the stacks after it per line, stack items are separated by spaces
+func PrintScriptSteps(w io.Writer, steps []*ScriptStep) {
+	for _, step := range steps {
+		_, _ = fmt.Fprintf(w, "%-48s [%s]", step.Opcode, strings.Join(step.Stack, " "))
+		if len(step.AltStack) > 0 {
+			_, _ = fmt.Fprintf(w, " alt: [%s]", strings.Join(step.AltStack, " "))
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+}
+
+func hexStack(stack [][]byte) []string {
+	items := make([]string, len(stack))
+	for i, item := range stack {
+		items[i] = hex.EncodeToString(item)
+	}
+	return items
+}
@@ -0,0 +1,56 @@
+package htlcbch
+
+import (
+	"bytes"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugSpend(t *testing.T) {
+	c, err := NewCovenant(testSenderPkh, testRecipientPkh, testSecretHash,
+		testExpiration, testPenaltyBPS, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	scriptHash, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	inAmt := int64(1000000)
+	p2shPkScript := append(append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, scriptHash...), txscript.OP_EQUAL)
+	prevOuts := []*wire.TxOut{wire.NewTxOut(inAmt, p2shPkScript)}
+	txid := gethcmn.Hash{'u', 't', 'x', 'o'}.Bytes()
+
+	tx, err := c.MakeUnlockTx(txid, 0, inAmt, 2, testSecretKey)
+	require.NoError(t, err)
+	steps, err := DebugSpend(tx, 0, prevOuts)
+	require.NoError(t, err)
+	require.Contains(t, steps[len(steps)-1].Opcode, "02:") // sig script, P2SH pkScript, then redeem script
+	var buf bytes.Buffer
+	PrintScriptSteps(&buf, steps)
+	require.Contains(t, buf.String(), "OP_SHA256")
+	require.Equal(t, len(steps), bytes.Count(buf.Bytes(), []byte("\n")))
+
+	// wrong secret
+	tx, err = c.MakeUnlockTx(txid, 0, inAmt, 2, gethcmn.Hash{'x'}.Bytes())
+	require.NoError(t, err)
+	steps, err = DebugSpend(tx, 0, prevOuts)
+	require.Error(t, err)
+	require.Contains(t, steps[len(steps)-1].Opcode, "OP_EQUALVERIFY")
+
+	// refund before expiration
+	tx, err = c.MakeRefundTx(txid, 0, inAmt, 2)
+	require.NoError(t, err)
+	_, err = DebugSpend(tx, 0, prevOuts)
+	require.NoError(t, err)
+	tx.TxIn[0].Sequence = testExpiration - 1
+	steps, err = DebugSpend(tx, 0, prevOuts)
+	require.Error(t, err)
+	require.Contains(t, steps[len(steps)-1].Opcode, "OP_CHECKSEQUENCEVERIFY")
+
+	_, err = DebugSpend(tx, 1, prevOuts)
+	require.ErrorContains(t, err, "input index out of range: 1")
+	_, err = DebugSpend(tx, 0, nil)
+	require.ErrorContains(t, err, "expected 1 prev outputs, got 0")
+}
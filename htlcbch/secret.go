@@ -0,0 +1,51 @@
+package htlcbch
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	minSecretSeedLen = 16
+	secretDomain     = "SBAS secret"
+)
+
+// DeriveSecret derives the secret of the index-th swap from the master seed:
+// HMAC-SHA256(seed, "SBAS secret" || index as uint32 big endian),
+// so only the index of a swap needs to be persisted, the secret can always be recovered from the seed
+func DeriveSecret(seed []byte, index uint32) ([]byte, error) {
+	if len(seed) < minSecretSeedLen {
+		return nil, fmt.Errorf("secret seed must be at least %d bytes", minSecretSeedLen)
+	}
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte(secretDomain))
+	mac.Write(binary.BigEndian.AppendUint32(nil, index))
+	return mac.Sum(nil), nil
+}
+
+// DeriveHashLock returns the hash lock of the index-th secret derived from the seed
+func DeriveHashLock(seed []byte, index uint32, hashType HashType) ([]byte, error) {
+	secret, err := DeriveSecret(seed, index)
+	if err != nil {
+		return nil, err
+	}
+	return hashType.HashSecret(secret), nil
+}
+
+// FindSecretIndex searches indices [0, maxIndex) for the secret of the hash lock,
+// it is used to recover in-flight swaps if their indices are lost
+func FindSecretIndex(seed, hashLock []byte, hashType HashType, maxIndex uint32) (uint32, []byte, error) {
+	for i := uint32(0); i < maxIndex; i++ {
+		secret, err := DeriveSecret(seed, i)
+		if err != nil {
+			return 0, nil, err
+		}
+		if bytes.Equal(hashType.HashSecret(secret), hashLock) {
+			return i, secret, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("secret not found in the first %d indices", maxIndex)
+}
@@ -0,0 +1,34 @@
+package htlcbch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveSecret(t *testing.T) {
+	_, err := DeriveSecret([]byte("short"), 0)
+	require.ErrorContains(t, err, "at least 16 bytes")
+
+	seed := []byte("0123456789abcdef0123456789abcdef")
+	s0, err := DeriveSecret(seed, 0)
+	require.NoError(t, err)
+	require.Len(t, s0, 32)
+	s0again, err := DeriveSecret(seed, 0)
+	require.NoError(t, err)
+	require.Equal(t, s0, s0again)
+	s1, err := DeriveSecret(seed, 1)
+	require.NoError(t, err)
+	require.NotEqual(t, s0, s1)
+
+	hashLock, err := DeriveHashLock(seed, 7, HashTypeHash160)
+	require.NoError(t, err)
+	require.Len(t, hashLock, 20)
+	idx, secret, err := FindSecretIndex(seed, hashLock, HashTypeHash160, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(7), idx)
+	require.Equal(t, hashLock, HashTypeHash160.HashSecret(secret))
+
+	_, _, err = FindSecretIndex(seed, hashLock, HashTypeSha256, 100)
+	require.ErrorContains(t, err, "secret not found in the first 100 indices")
+}
@@ -1,22 +1,137 @@
 package htlcbch
 
 import (
+	"bytes"
 	"encoding/hex"
+	"fmt"
 
 	"github.com/gcash/bchd/bchec"
 	"github.com/gcash/bchd/chaincfg/chainhash"
 	"github.com/gcash/bchd/txscript"
 	"github.com/gcash/bchd/wire"
 	"github.com/gcash/bchutil"
+	"github.com/smartbch/atomic-swap-bot/htlcmath"
+	"golang.org/x/exp/slices"
 )
 
 const (
-	dustAmt = 546
+	dustAmt = htlcmath.DustLimit
+
+	// <push 72><DER sig + sighash type><push 33><compressed pubkey>
+	p2pkhSigScriptLen = 1 + 72 + 1 + 33
+	// <push 65><Schnorr sig + sighash type><push 33><compressed pubkey>
+	p2pkhSchnorrSigScriptLen = 1 + 65 + 1 + 33
+)
+
+// SigType selects the signature algorithm used to sign P2PKH inputs,
+// BCH nodes accept both since the 2019 May upgrade.
+// It may carry the SigTypeAnyoneCanPay flag, see WithSigHashType().
+type SigType uint8
+
+const (
+	SigTypeECDSA   SigType = 0
+	SigTypeSchnorr SigType = 1
+
+	// signatures commit to their own inputs only (SIGHASH_ANYONECANPAY), so that inputs can be added
+	// to signed txs, e.g. to batch or bump them, while outputs are still committed to by SIGHASH_ALL
+	SigTypeAnyoneCanPay SigType = 0x80
 )
 
+func (t SigType) IsValid() bool {
+	return t.Algorithm() == SigTypeECDSA || t.Algorithm() == SigTypeSchnorr
+}
+
+// Algorithm drops the sighash flags
+func (t SigType) Algorithm() SigType {
+	return t &^ SigTypeAnyoneCanPay
+}
+
+// SigHashType is always SIGHASH_ALL|SIGHASH_FORKID, with SIGHASH_ANYONECANPAY if flagged
+func (t SigType) SigHashType() txscript.SigHashType {
+	hashType := txscript.SigHashAll | txscript.SigHashForkID
+	if t&SigTypeAnyoneCanPay != 0 {
+		hashType |= txscript.SigHashAnyOneCanPay
+	}
+	return hashType
+}
+
+// WithSigHashType returns the algorithm flagged to sign with hashType, which must be accepted by
+// the P2PKH inputs signed by the bot, see ValidateSigHashType()
+func (t SigType) WithSigHashType(hashType txscript.SigHashType) (SigType, error) {
+	if err := ValidateSigHashType(hashType); err != nil {
+		return 0, err
+	}
+	if hashType&txscript.SigHashAnyOneCanPay != 0 {
+		return t.Algorithm() | SigTypeAnyoneCanPay, nil
+	}
+	return t.Algorithm(), nil
+}
+
+func (t SigType) String() string {
+	var s string
+	switch t.Algorithm() {
+	case SigTypeECDSA:
+		s = "ecdsa"
+	case SigTypeSchnorr:
+		s = "schnorr"
+	default:
+		return fmt.Sprintf("unknown(%d)", t)
+	}
+	if t&SigTypeAnyoneCanPay != 0 {
+		s += "+anyonecanpay"
+	}
+	return s
+}
+
+func ParseSigType(s string) (SigType, error) {
+	switch s {
+	case "", "ecdsa":
+		return SigTypeECDSA, nil
+	case "schnorr":
+		return SigTypeSchnorr, nil
+	default:
+		return 0, fmt.Errorf("unknown signature type: %s", s)
+	}
+}
+
+// ParseSigHashType parses all|all+anyonecanpay, FORKID is implied
+func ParseSigHashType(s string) (txscript.SigHashType, error) {
+	switch s {
+	case "", "all":
+		return txscript.SigHashAll | txscript.SigHashForkID, nil
+	case "all+anyonecanpay":
+		return txscript.SigHashAll | txscript.SigHashForkID | txscript.SigHashAnyOneCanPay, nil
+	default:
+		return 0, fmt.Errorf("unknown sighash type: %s", s)
+	}
+}
+
+// ValidateSigHashType checks hashType can sign the inputs spent by the bot. They are all P2PKH, whose
+// OP_CHECKSIG accepts any hash type with FORKID after the 2017 Aug fork, but SIGHASH_NONE and
+// SIGHASH_SINGLE leave outputs uncommitted, so that anyone could redirect the coins. HTLC covenants
+// have no OP_CHECKSIG, and treasury inputs are always signed with ALL|FORKID by all co-signers.
+func ValidateSigHashType(hashType txscript.SigHashType) error {
+	if hashType&txscript.SigHashForkID == 0 {
+		return fmt.Errorf("sighash type without FORKID: %#x", uint32(hashType))
+	}
+	if hashType&^(txscript.SigHashForkID|txscript.SigHashAnyOneCanPay) != txscript.SigHashAll {
+		return fmt.Errorf("sighash type does not commit to all outputs: %#x", uint32(hashType))
+	}
+	return nil
+}
+
+// Schnorr sigs are always 64 bytes, DER encoded ECDSA sigs are up to 72 bytes
+func (t SigType) p2pkhSigScriptLen() int {
+	if t.Algorithm() == SigTypeSchnorr {
+		return p2pkhSchnorrSigScriptLen
+	}
+	return p2pkhSigScriptLen
+}
+
 type msgTxBuilder struct {
-	msgTx *wire.MsgTx
-	err   error
+	msgTx   *wire.MsgTx
+	sigType SigType
+	err     error
 }
 
 func newMsgTxBuilder() *msgTxBuilder {
@@ -25,6 +140,47 @@ func newMsgTxBuilder() *msgTxBuilder {
 	}
 }
 
+func (builder *msgTxBuilder) useSigType(sigType SigType) *msgTxBuilder {
+	if builder.err != nil {
+		return builder
+	}
+	if !sigType.IsValid() {
+		builder.err = fmt.Errorf("invalid signature type: %d", sigType)
+		return builder
+	}
+	builder.sigType = sigType
+	return builder
+}
+
+// anti-fee-sniping: with the current tip height as lock time, the tx can only be mined in the next
+// block, so miners gain nothing by reorging the tip to take its fee. 0 means no lock time.
+// It must be called before inputs are added.
+func (builder *msgTxBuilder) useLockTime(lockTime uint32) *msgTxBuilder {
+	if builder.err != nil {
+		return builder
+	}
+	if lockTime >= txscript.LockTimeThreshold {
+		builder.err = fmt.Errorf("lock time is not a block height: %d", lockTime)
+		return builder
+	}
+	builder.msgTx.LockTime = lockTime
+	return builder
+}
+
+// refunds of covenants with absolute expirations use the expiration as lock time, so that
+// OP_CHECKLOCKTIMEVERIFY passes. It must be called before inputs are added.
+func (builder *msgTxBuilder) useExpiresAt(expiresAt uint32) *msgTxBuilder {
+	if builder.err != nil {
+		return builder
+	}
+	if expiresAt < txscript.LockTimeThreshold {
+		builder.err = fmt.Errorf("lock time is not a timestamp: %d", expiresAt)
+		return builder
+	}
+	builder.msgTx.LockTime = expiresAt
+	return builder
+}
+
 func (builder *msgTxBuilder) addInput(txid []byte, vout uint32, seq uint32, sigScript []byte) *msgTxBuilder {
 	if builder.err != nil {
 		return builder
@@ -41,6 +197,8 @@ func (builder *msgTxBuilder) addInput(txid []byte, vout uint32, seq uint32, sigS
 	txIn := wire.NewTxIn(outPoint, nil)
 	if seq > 0 {
 		txIn.Sequence = seq
+	} else if builder.msgTx.LockTime > 0 {
+		txIn.Sequence = wire.MaxTxInSequenceNum - 1 // non-final, so that the lock time is enforced
 	}
 	if len(sigScript) > 0 {
 		txIn.SignatureScript = sigScript
@@ -65,6 +223,16 @@ func (builder *msgTxBuilder) addOutput(toAddr bchutil.Address, outAmt int64) *ms
 	return builder
 }
 
+func (builder *msgTxBuilder) addPkScriptOutput(pkScript []byte, outAmt int64) *msgTxBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	txOut := wire.NewTxOut(outAmt, pkScript)
+	builder.msgTx.AddTxOut(txOut)
+	return builder
+}
+
 func (builder *msgTxBuilder) addOpRet(pkScript []byte) *msgTxBuilder {
 	txOut := wire.NewTxOut(0, pkScript)
 	builder.msgTx.AddTxOut(txOut)
@@ -89,9 +257,12 @@ func (builder *msgTxBuilder) sign(
 		return builder
 	}
 
-	hashType := txscript.SigHashAll | txscript.SigHashForkID
-	sig, err := txscript.RawTxInECDSASignature(builder.msgTx,
-		inIdx, subScript, hashType, privKey, inAmt)
+	hashType := builder.sigType.SigHashType()
+	signFn := txscript.RawTxInECDSASignature
+	if builder.sigType.Algorithm() == SigTypeSchnorr {
+		signFn = txscript.RawTxInSchnorrSignature
+	}
+	sig, err := signFn(builder.msgTx, inIdx, subScript, hashType, privKey, inAmt)
 	if err != nil {
 		builder.err = err
 		return builder
@@ -110,3 +281,42 @@ func (builder *msgTxBuilder) sign(
 func (builder *msgTxBuilder) build() (*wire.MsgTx, error) {
 	return builder.msgTx, builder.err
 }
+
+// BIP-69: inputs are sorted by prev txid (in the byte order shown by explorers) and vout,
+// so replicas spending the same UTXOs build identical txs whatever order the node lists them in
+func sortInputs(inputs []InputInfo) []InputInfo {
+	sorted := make([]InputInfo, len(inputs))
+	copy(sorted, inputs)
+	slices.SortFunc(sorted, func(a, b InputInfo) bool {
+		if c := bytes.Compare(a.TxID, b.TxID); c != 0 {
+			return c < 0
+		}
+		return a.Vout < b.Vout
+	})
+	return sorted
+}
+
+// BIP-69: outputs from index start are sorted by value and pkScript,
+// outputs whose positions are required by covenants or the OP_RETURN protocol must be before start
+func (builder *msgTxBuilder) sortOutputs(start int) *msgTxBuilder {
+	if builder.err != nil || start >= len(builder.msgTx.TxOut) {
+		return builder
+	}
+	slices.SortFunc(builder.msgTx.TxOut[start:], func(a, b *wire.TxOut) bool {
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		return bytes.Compare(a.PkScript, b.PkScript) < 0
+	})
+	return builder
+}
+
+// size of tx with P2PKH sig scripts counted at their max length, so the miner fee
+// does not depend on the length of DER signatures, which varies by a byte or two
+func getStableTxSize(tx *wire.MsgTx, sigType SigType) int {
+	unsigned := tx.Copy()
+	for _, txIn := range unsigned.TxIn {
+		txIn.SignatureScript = nil
+	}
+	return len(MsgTxToBytes(unsigned)) + len(unsigned.TxIn)*sigType.p2pkhSigScriptLen()
+}
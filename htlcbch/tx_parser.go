@@ -4,44 +4,139 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"math"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gcash/bchd/btcjson"
 	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
 )
 
 const (
 	protoID = "SBAS" // SmartBCH AtomicSwap
+
+	MaxMemoLen = 64 // the OP_RETURN stays within the 223 bytes standard limit
 )
 
 type HtlcLockInfo struct {
 	//BlockNum      uint64
-	TxHash        string        // 32 bytes, hex
-	RecipientPkh  hexutil.Bytes // 20 bytes
-	SenderPkh     hexutil.Bytes // 20 bytes
-	HashLock      hexutil.Bytes // 32 bytes, sha256
-	Expiration    uint16        //  2 bytes, big endian
-	PenaltyBPS    uint16        //  2 bytes, big endian
-	SenderEvmAddr hexutil.Bytes // 20 bytes
-	ScriptHash    hexutil.Bytes // 20 bytes, hash160
-	Value         uint64        // in sats
-	ExpectedPrice uint64        // 8 decimals
+	TxHash         string         // 32 bytes, hex
+	Vout           uint32         // index of the deposit output, 0 unless found by DepositFilter.AnyOutputOrder
+	RecipientPkh   hexutil.Bytes  // 20 bytes
+	SenderPkh      hexutil.Bytes  // 20 bytes
+	HashLock       hexutil.Bytes  // 32 bytes for sha256, 20 bytes for hash160
+	HashType       HashType       //  1 byte, optional, sha256 if omitted
+	Expiration     uint16         //  2 bytes, big endian, or 4 bytes with the BIP68 type flag if time based
+	ExpirationType ExpirationType // unit of Expiration
+	ExpiresAt      uint32         //  4 bytes, big endian, unix timestamp, replaces Expiration if it is absolute
+	PenaltyBPS     uint16         //  2 bytes, big endian
+	SenderEvmAddr  hexutil.Bytes  // 20 bytes
+	ScriptHash     hexutil.Bytes  // 20 bytes, hash160
+	Value          uint64         // in sats
+	ExpectedPrice  uint64         // 8 decimals
+	Memo           string         // optional, user-supplied reference ID
+	ReferralPkh    hexutil.Bytes  // 20 bytes, optional, referral of split covenants
+	ReferralBPS    uint16         //  2 bytes, big endian, optional
+	Template       string         // version of the matched covenant template, see CovenantTemplate
+	RawTx          string         // hex
 }
 
 type HtlcUnlockInfo struct {
-	PrevTxHash string // 32 bytes, hex
-	TxHash     string // 32 bytes, hex
-	Secret     string // 32 bytes, hex
+	PrevTxHash      string // 32 bytes, hex
+	TxHash          string // 32 bytes, hex
+	Secret          string // 32 bytes, hex
+	HashType        HashType
+	TemplateVersion string // of the covenant spent, see CovenantTemplate
+	RawTx           string // hex
+}
+
+// SuspectTx matches some but not all HTLC heuristics, e.g. a valid OP_RETURN with a
+// mismatched script hash. It is neither a deposit nor a receipt, but may reveal protocol
+// bugs or malicious probes.
+type SuspectTx struct {
+	TxHash string // 32 bytes, hex
+	Reason string //
+	RawTx  string // hex
+}
+
+// DepositFloor drops deposits which are not worth tracking: the value must be at least MinValue,
+// and must cover the miner fee of the future unlock|refund tx (plus penalty of the refund tx)
+// without leaving a dust output, otherwise nobody can ever spend the covenant.
+type DepositFloor struct {
+	MinValue      uint64 // in sats
+	UnlockFeeRate uint64 // sats/byte
+	RefundFeeRate uint64 // sats/byte
+}
+
+func (f *DepositFloor) allows(c *HtlcCovenant, value uint64) bool {
+	return f == nil || value >= f.MinValue && c.CanBeSpent(value, f.UnlockFeeRate, f.RefundFeeRate)
+}
+
+// DepositFilter decides which txs are recognized as deposits, nil means the strict defaults.
+// Deposits made by the deposit tool and the bot always put the covenant at output#0 and the
+// OP_RETURN at output#1, but some wallets reorder outputs or add extra ones. With AnyOutputOrder,
+// the first SBAS OP_RETURN and the first P2SH output matching it are searched in all outputs.
+type DepositFilter struct {
+	Floor          *DepositFloor // nil means no floor
+	AnyOutputOrder bool
+	Diagnose       bool // collect all SBAS txs, see SbasTx
+}
+
+func (f *DepositFilter) getFloor() *DepositFloor {
+	if f == nil {
+		return nil
+	}
+	return f.Floor
+}
+
+func (f *DepositFilter) anyOutputOrder() bool {
+	return f != nil && f.AnyOutputOrder
+}
+
+func (f *DepositFilter) diagnose() bool {
+	return f != nil && f.Diagnose
+}
+
+// SbasTx is a tx carrying the SBAS protocol ID in an OP_RETURN output, valid or not.
+// Txs made by third-party tooling which fail validation would otherwise vanish silently,
+// so they are collected with the exact reason for diagnostics.
+type SbasTx struct {
+	TxHash  string          // 32 bytes, hex
+	Vout    uint32          // index of the SBAS OP_RETURN output
+	Payload []hexutil.Bytes // pushes after the protocol ID
+	Deposit *HtlcLockInfo   // nil if tx is not a valid deposit
+	Reason  string          // why tx is not a valid deposit
+	RawTx   string          // hex
 }
 
 // === Lock ===
 
-func GetHtlcLocksInfo(block *btcjson.GetBlockVerboseTxResult) (deposits []*HtlcLockInfo) {
+func GetHtlcLocksInfo(block *btcjson.GetBlockVerboseTxResult) []*HtlcLockInfo {
+	deposits, _ := getHtlcLocksInfo(block, MainNet, nil)
+	return deposits
+}
+
+// floor is optional, nil means all deposits are returned
+func getHtlcLocksInfo(block *btcjson.GetBlockVerboseTxResult, params *ChainParams, filter *DepositFilter,
+) (deposits []*HtlcLockInfo, suspects []*SuspectTx) {
 	for _, tx := range block.Tx {
-		depositInfo := isHtlcLockTx(tx)
+		depositInfo, suspectReason := parseHtlcLockTx(tx, params, filter)
 		if depositInfo != nil {
 			deposits = append(deposits, depositInfo)
+		} else if suspectReason != "" {
+			suspects = append(suspects, &SuspectTx{TxHash: tx.Txid, Reason: suspectReason, RawTx: tx.Hex})
+		}
+	}
+	return
+}
+
+func getSbasTxs(block *btcjson.GetBlockVerboseTxResult, params *ChainParams, filter *DepositFilter,
+) (sbasTxs []*SbasTx) {
+	for _, tx := range block.Tx {
+		if sbasTx := diagnoseSbasTx(tx, params, filter); sbasTx != nil {
+			sbasTxs = append(sbasTxs, sbasTx)
 		}
 	}
 	return
@@ -56,75 +151,317 @@ func decodeHex(s string) []byte {
 }
 
 // output#0: deposit, output#1: op_return
-func isHtlcLockTx(tx btcjson.TxRawResult) *HtlcLockInfo {
+func isHtlcLockTx(tx btcjson.TxRawResult, params *ChainParams, filter *DepositFilter) *HtlcLockInfo {
+	depositInfo, _ := parseHtlcLockTx(tx, params, filter)
+	return depositInfo
+}
+
+// suspectReason is set if the OP_RETURN output carries the SBAS protocol ID but tx is not a valid deposit
+func parseHtlcLockTx(tx btcjson.TxRawResult, params *ChainParams, filter *DepositFilter,
+) (depositInfo *HtlcLockInfo, suspectReason string) {
 	if len(tx.Vout) < 2 {
-		return nil
+		return nil, ""
 	}
 
-	// output#0 must be locked by P2SH script
-	scriptHash := getP2SHash(decodeHex(tx.Vout[0].ScriptPubKey.Hex))
-	if scriptHash == nil {
-		return nil
+	retIdx := 1
+	if filter.anyOutputOrder() {
+		if retIdx = findProtoIDOutput(tx); retIdx < 0 {
+			return nil, ""
+		}
 	}
 
-	// output#1 must be NULL DATA that contains the HTLC info
-	depositInfo := getHtlcLockInfo(decodeHex(tx.Vout[1].ScriptPubKey.Hex))
+	// output#1 (or the SBAS one) must be NULL DATA that contains the HTLC info,
+	// most txs are not, so check it before decoding
+	if !isNullDataHex(tx.Vout[retIdx].ScriptPubKey.Hex) {
+		return nil, ""
+	}
+	retPkScript := decodeHex(tx.Vout[retIdx].ScriptPubKey.Hex)
+	depositInfo = getHtlcLockInfo(retPkScript)
 	if depositInfo == nil {
-		return nil
+		if hasProtoID(retPkScript) {
+			return nil, "malformed SBAS OP_RETURN"
+		}
+		return nil, ""
 	}
 
-	c, err := NewMainnetCovenant(depositInfo.SenderPkh,
-		depositInfo.RecipientPkh, depositInfo.HashLock,
-		depositInfo.Expiration, depositInfo.PenaltyBPS)
-	if err != nil {
-		return nil
+	// output#0 must be locked by P2SH script
+	var scriptHash []byte
+	if !filter.anyOutputOrder() {
+		if scriptHash = getP2SHash(decodeHex(tx.Vout[0].ScriptPubKey.Hex)); scriptHash == nil {
+			return nil, "output#0 is not P2SH"
+		}
 	}
-	cScriptHash, err := c.GetRedeemScriptHash()
+
+	c, err := params.NewCovenantOfDeposit(depositInfo)
 	if err != nil {
-		return nil
+		return nil, "invalid covenant: " + err.Error()
 	}
-	if !bytes.Equal(cScriptHash, scriptHash) {
-		return nil
+
+	// match all active versions of the template, the current one first,
+	// the mismatch is reported against the current one
+	var cScriptHash0 []byte
+	depositIdx := -1
+	for _, template := range activeCovenantTemplates() {
+		cScriptHash, err := c.WithTemplate(template).GetRedeemScriptHash()
+		if err != nil {
+			return nil, "invalid covenant: " + err.Error()
+		}
+		if cScriptHash0 == nil {
+			cScriptHash0 = cScriptHash
+		}
+		if filter.anyOutputOrder() {
+			depositIdx = findP2SHOutput(tx, cScriptHash)
+		} else if bytes.Equal(cScriptHash, scriptHash) {
+			depositIdx = 0
+		}
+		if depositIdx >= 0 {
+			c = c.WithTemplate(template)
+			scriptHash = cScriptHash
+			depositInfo.Template = template.Version
+			break
+		}
+	}
+	if depositIdx < 0 {
+		if filter.anyOutputOrder() {
+			return nil, fmt.Sprintf("no P2SH output matches script hash %x", cScriptHash0)
+		}
+		return nil, fmt.Sprintf("script hash mismatch: %x != %x", scriptHash, cScriptHash0)
+	}
+	value := utxoAmtToSats(tx.Vout[depositIdx].Value)
+	if !filter.getFloor().allows(c, value) {
+		return nil, ""
 	}
 
 	depositInfo.TxHash = tx.Txid
+	depositInfo.Vout = uint32(depositIdx)
 	depositInfo.ScriptHash = scriptHash
-	depositInfo.Value = utxoAmtToSats(tx.Vout[0].Value)
-	return depositInfo
+	depositInfo.Value = value
+	depositInfo.RawTx = tx.Hex
+	return depositInfo, ""
+}
+
+// returns nil if tx has no SBAS OP_RETURN, otherwise tells why it is not accepted by parseHtlcLockTx
+func diagnoseSbasTx(tx btcjson.TxRawResult, params *ChainParams, filter *DepositFilter) *SbasTx {
+	// in strict mode, output#1 is the one parsed even if an earlier output also carries the protocol ID
+	retIdx := findProtoIDOutput(tx)
+	if !filter.anyOutputOrder() && len(tx.Vout) >= 2 &&
+		isNullDataHex(tx.Vout[1].ScriptPubKey.Hex) && hasProtoID(decodeHex(tx.Vout[1].ScriptPubKey.Hex)) {
+		retIdx = 1
+	}
+	if retIdx < 0 {
+		return nil
+	}
+
+	retPkScript := decodeHex(tx.Vout[retIdx].ScriptPubKey.Hex)
+	retData, _ := txscript.PushedData(retPkScript) // checked by hasProtoID
+	sbasTx := &SbasTx{
+		TxHash: tx.Txid,
+		Vout:   uint32(retIdx),
+		RawTx:  tx.Hex,
+	}
+	for _, data := range retData[1:] {
+		sbasTx.Payload = append(sbasTx.Payload, data)
+	}
+
+	depositInfo, suspectReason := parseHtlcLockTx(tx, params, filter)
+	if depositInfo != nil {
+		sbasTx.Deposit = depositInfo
+		return sbasTx
+	}
+	if _, err := decodeHtlcLockInfo(retPkScript); err != nil {
+		sbasTx.Reason = "malformed SBAS OP_RETURN: " + err.Error()
+	} else if !filter.anyOutputOrder() && retIdx != 1 {
+		sbasTx.Reason = fmt.Sprintf("SBAS OP_RETURN is output#%d, not output#1", retIdx)
+	} else if suspectReason != "" {
+		sbasTx.Reason = suspectReason
+	} else if len(tx.Vout) < 2 {
+		sbasTx.Reason = "no output besides the SBAS OP_RETURN"
+	} else {
+		sbasTx.Reason = "deposit below the floor"
+	}
+	return sbasTx
+}
+
+// returns the index of the first NULL DATA output with the SBAS protocol ID, or -1
+func findProtoIDOutput(tx btcjson.TxRawResult) int {
+	for i, vout := range tx.Vout {
+		if isNullDataHex(vout.ScriptPubKey.Hex) && hasProtoID(decodeHex(vout.ScriptPubKey.Hex)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// returns the index of the first P2SH output locked by scriptHash, or -1
+func findP2SHOutput(tx btcjson.TxRawResult, scriptHash []byte) int {
+	for i, vout := range tx.Vout {
+		if bytes.Equal(getP2SHash(decodeHex(vout.ScriptPubKey.Hex)), scriptHash) {
+			return i
+		}
+	}
+	return -1
+}
+
+// OP_RETURN "SBAS" ...
+func hasProtoID(pkScript []byte) bool {
+	if len(pkScript) == 0 || pkScript[0] != txscript.OP_RETURN {
+		return false
+	}
+	retData, err := txscript.PushedData(pkScript)
+	return err == nil && len(retData) > 0 && string(retData[0]) == protoID
 }
 
 // https://github.com/bitcoincashorg/bitcoincash.org/blob/master/spec/op_return-prefix-guideline.md
 // OP_RETURN "SBAS" <recipient pkh> <sender pkh> <hash lock> <expiration> <penalty bps> <sbch user address> <expected price>
+// [<hash type> [<memo> [<referral pkh> <referral bps>]]]
 func getHtlcLockInfo(pkScript []byte) *HtlcLockInfo {
+	depositInfo, _ := decodeHtlcLockInfo(pkScript)
+	return depositInfo
+}
+
+// decodeHtlcLockInfo is getHtlcLockInfo which tells why pkScript is not a valid SBAS OP_RETURN
+func decodeHtlcLockInfo(pkScript []byte) (*HtlcLockInfo, error) {
 	if len(pkScript) == 0 ||
 		pkScript[0] != txscript.OP_RETURN {
-		return nil
+		return nil, fmt.Errorf("not OP_RETURN")
 	}
 
 	retData, err := txscript.PushedData(pkScript)
-	if err != nil ||
-		len(retData) != 8 ||
-		string(retData[0]) != protoID || // "SBAS"
-		len(retData[1]) != 20 || // recipient pkh
-		len(retData[2]) != 20 || // sender pkh
-		len(retData[3]) != 32 || // hash lock
-		len(retData[4]) != 2 || // expiration
-		len(retData[5]) != 2 || // penalty bps
-		len(retData[6]) != 20 || // sender evm addr
-		len(retData[7]) != 8 { // expected price
+	if err != nil {
+		return nil, fmt.Errorf("invalid pushes: %w", err)
+	}
+	if len(retData) < 8 || len(retData) > 12 || len(retData) == 11 {
+		return nil, fmt.Errorf("invalid number of pushes: %d", len(retData))
+	}
 
-		return nil
+	hashType := HashTypeSha256
+	if len(retData) >= 9 {
+		if len(retData[8]) != 1 {
+			return nil, fmt.Errorf("invalid length of hash type: %d", len(retData[8]))
+		}
+		hashType = HashType(retData[8][0])
+		if !hashType.IsValid() {
+			return nil, fmt.Errorf("invalid hash type: %d", hashType)
+		}
+	}
+	var memo string
+	if len(retData) >= 10 {
+		memo = string(retData[9])
+		// the memo may be empty only if it is followed by the referral args
+		if !IsValidMemo(memo) && !(memo == "" && len(retData) == 12) {
+			return nil, fmt.Errorf("invalid memo: %q", memo)
+		}
+	}
+	var referralPkh []byte
+	var referralBPS uint16
+	if len(retData) == 12 {
+		if len(retData[10]) != 20 {
+			return nil, fmt.Errorf("invalid length of referral pkh: %d", len(retData[10]))
+		}
+		if len(retData[11]) != 2 {
+			return nil, fmt.Errorf("invalid length of referral bps: %d", len(retData[11]))
+		}
+		referralPkh = retData[10]
+		referralBPS = binary.BigEndian.Uint16(retData[11])
+	}
+
+	if string(retData[0]) != protoID { // "SBAS"
+		return nil, fmt.Errorf("invalid protocol ID: %q", retData[0])
+	}
+	expirationLen := 2
+	if len(retData[4]) == 4 {
+		expirationLen = 4
+	}
+	for i, field := range []struct {
+		name string
+		len  int
+	}{
+		{"recipient pkh", 20},
+		{"sender pkh", 20},
+		{"hash lock", hashType.HashLockLen()},
+		{"expiration", expirationLen},
+		{"penalty bps", 2},
+		{"sender evm addr", 20},
+		{"expected price", 8},
+	} {
+		if len(retData[i+1]) != field.len {
+			return nil, fmt.Errorf("invalid length of %s: %d", field.name, len(retData[i+1]))
+		}
+	}
+	expiration, expiresAt, expirationType, err := decodeExpiration(retData[4])
+	if err != nil {
+		return nil, err
 	}
 
 	return &HtlcLockInfo{
-		RecipientPkh:  retData[1],
-		SenderPkh:     retData[2],
-		HashLock:      retData[3],
-		Expiration:    binary.BigEndian.Uint16(retData[4]),
-		PenaltyBPS:    binary.BigEndian.Uint16(retData[5]),
-		SenderEvmAddr: retData[6],
-		ExpectedPrice: binary.BigEndian.Uint64(retData[7]),
+		RecipientPkh:   retData[1],
+		SenderPkh:      retData[2],
+		HashLock:       retData[3],
+		HashType:       hashType,
+		Expiration:     expiration,
+		ExpirationType: expirationType,
+		ExpiresAt:      expiresAt,
+		PenaltyBPS:     binary.BigEndian.Uint16(retData[5]),
+		SenderEvmAddr:  retData[6],
+		ExpectedPrice:  binary.BigEndian.Uint64(retData[7]),
+		Memo:           memo,
+		ReferralPkh:    referralPkh,
+		ReferralBPS:    referralBPS,
+	}, nil
+}
+
+// RefundableIn returns how long the sender has to wait to refund a deposit which has the confirmations
+// and was mined at blockTime (unix seconds): in blocks, or in seconds if its expiration is time based or
+// absolute, 0 means now. Time based lock times are measured by nodes against median time past, which
+// lags block times by about an hour, so they are estimated by block times (and now for timestamps) and
+// a refund tx may still be rejected as non-final for a while.
+func (info *HtlcLockInfo) RefundableIn(confirmations uint64, blockTime, now int64) int64 {
+	var n int64
+	switch info.ExpirationType {
+	case ExpirationTypeTime:
+		n = int64(info.Expiration)*ExpirationTimeUnit - (now - blockTime)
+		if confirmations == 0 {
+			n = int64(info.Expiration) * ExpirationTimeUnit
+		}
+	case ExpirationTypeTimestamp:
+		n = int64(info.ExpiresAt) - now
+	default:
+		n = int64(info.Expiration) - int64(confirmations) + 1
 	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// 2 bytes in blocks, or 4 bytes of a time based relative lock time or a timestamp, see BuildOpRetPkScript()
+func decodeExpiration(data []byte) (expiration uint16, expiresAt uint32, expirationType ExpirationType, err error) {
+	if len(data) == 2 {
+		return binary.BigEndian.Uint16(data), 0, ExpirationTypeBlocks, nil
+	}
+	seq := binary.BigEndian.Uint32(data)
+	if seq >= txscript.LockTimeThreshold {
+		return 0, seq, ExpirationTypeTimestamp, nil
+	}
+	if seq&^(wire.SequenceLockTimeIsSeconds|wire.SequenceLockTimeMask) != 0 ||
+		seq&wire.SequenceLockTimeIsSeconds == 0 {
+		return 0, 0, 0, fmt.Errorf("invalid expiration: %#x", seq)
+	}
+	return uint16(seq), 0, ExpirationTypeTime, nil
+}
+
+// IsValidMemo tells if memo can be carried by a deposit: 1~MaxMemoLen printable ASCII chars,
+// so it can be echoed as is by APIs and webhooks
+func IsValidMemo(memo string) bool {
+	if len(memo) == 0 || len(memo) > MaxMemoLen {
+		return false
+	}
+	for i := 0; i < len(memo); i++ {
+		if memo[i] < 0x20 || memo[i] > 0x7e {
+			return false
+		}
+	}
+	return true
 }
 
 // OP_HASH160 <20 bytes script hash> OP_EQUAL
@@ -141,20 +478,42 @@ func getP2SHash(pkScript []byte) (scriptHash []byte) {
 // === Unlock ===
 
 func GetHtlcUnlocksInfo(block *btcjson.GetBlockVerboseTxResult) (receipts []*HtlcUnlockInfo) {
+	receipts, _ = getHtlcUnlocksInfo(block)
+	return
+}
+
+func getHtlcUnlocksInfo(block *btcjson.GetBlockVerboseTxResult) (receipts []*HtlcUnlockInfo, suspects []*SuspectTx) {
 	for _, tx := range block.Tx {
 		receiptInfo := isHtlcUnlockTx(tx)
 		if receiptInfo != nil {
 			receipts = append(receipts, receiptInfo)
+		} else if batchReceipts := isHtlcBatchUnlockTx(tx); len(batchReceipts) > 0 {
+			receipts = append(receipts, batchReceipts...)
+		} else if suspectReason := getHtlcSpendSuspectReason(tx); suspectReason != "" {
+			suspects = append(suspects, &SuspectTx{TxHash: tx.Txid, Reason: suspectReason, RawTx: tx.Hex})
 		}
 	}
 	return
 }
 
+// GetHtlcUnlockInfo returns nil if tx is not an HTLC unlock tx
+func GetHtlcUnlockInfo(tx btcjson.TxRawResult) *HtlcUnlockInfo {
+	return isHtlcUnlockTx(tx)
+}
+
+// GetHtlcUnlocksInfoOfTx returns the HTLC receipts of tx, which may be a batch one, see MakeBatchUnlockTx
+func GetHtlcUnlocksInfoOfTx(tx btcjson.TxRawResult) []*HtlcUnlockInfo {
+	if receiptInfo := isHtlcUnlockTx(tx); receiptInfo != nil {
+		return []*HtlcUnlockInfo{receiptInfo}
+	}
+	return isHtlcBatchUnlockTx(tx)
+}
+
 func isHtlcUnlockTx(tx btcjson.TxRawResult) *HtlcUnlockInfo {
 	if len(tx.Vin) != 1 {
 		return nil
 	}
-	if tx.Vin[0].ScriptSig == nil {
+	if tx.Vin[0].ScriptSig == nil || !mayBeHtlcSigScriptHex(tx.Vin[0].ScriptSig.Hex) {
 		return nil
 	}
 	sigScript := decodeHex(tx.Vin[0].ScriptSig.Hex)
@@ -162,14 +521,45 @@ func isHtlcUnlockTx(tx btcjson.TxRawResult) *HtlcUnlockInfo {
 	if receiptInfo != nil {
 		receiptInfo.PrevTxHash = tx.Vin[0].Txid
 		receiptInfo.TxHash = tx.Txid
+		receiptInfo.RawTx = tx.Hex
 	}
 	return receiptInfo
 }
 
+// inputs of a tx with several inputs which claim batchable covenants, other inputs are ignored
+func isHtlcBatchUnlockTx(tx btcjson.TxRawResult) (receipts []*HtlcUnlockInfo) {
+	if len(tx.Vin) < 2 {
+		return nil
+	}
+	for _, vin := range tx.Vin {
+		if vin.ScriptSig == nil || !mayBeHtlcSigScriptHex(vin.ScriptSig.Hex) {
+			continue
+		}
+		sigScript := decodeHex(vin.ScriptSig.Hex)
+		template, hashType, isSplit := findTemplateOfSigScript(sigScript)
+		if template == nil || !template.Batchable() || isSplit {
+			continue
+		}
+		receiptInfo := parseHtlcUnlockSigScript(sigScript, template, hashType)
+		if receiptInfo != nil {
+			receiptInfo.PrevTxHash = vin.Txid
+			receiptInfo.TxHash = tx.Txid
+			receiptInfo.RawTx = tx.Hex
+			receipts = append(receipts, receiptInfo)
+		}
+	}
+	return
+}
+
 func getHtlcUnlockInfo(sigScript []byte) *HtlcUnlockInfo {
-	if !bytes.HasSuffix(sigScript, redeemScriptWithoutConstructorArgs) {
+	template, hashType, _ := findTemplateOfSigScript(sigScript)
+	if template == nil {
 		return nil
 	}
+	return parseHtlcUnlockSigScript(sigScript, template, hashType)
+}
+
+func parseHtlcUnlockSigScript(sigScript []byte, template *CovenantTemplate, hashType HashType) *HtlcUnlockInfo {
 	pushes, err := txscript.PushedData(sigScript)
 	if err != nil {
 		return nil
@@ -182,12 +572,51 @@ func getHtlcUnlockInfo(sigScript []byte) *HtlcUnlockInfo {
 	}
 
 	return &HtlcUnlockInfo{
-		Secret: hex.EncodeToString(pushes[0]),
+		Secret:          hex.EncodeToString(pushes[0]),
+		HashType:        hashType,
+		TemplateVersion: template.Version,
+	}
+}
+
+// an input spends an HTLC covenant, but tx is neither a receipt nor a refund which can be parsed
+func getHtlcSpendSuspectReason(tx btcjson.TxRawResult) string {
+	for i, vin := range tx.Vin {
+		if vin.ScriptSig == nil || !mayBeHtlcSigScriptHex(vin.ScriptSig.Hex) {
+			continue
+		}
+		sigScript := decodeHex(vin.ScriptSig.Hex)
+		if len(tx.Vin) != 1 {
+			return fmt.Sprintf("HTLC spent by input#%d of %d inputs", i, len(tx.Vin))
+		}
+		pushes, err := txscript.PushedData(sigScript)
+		if err != nil {
+			return "unparseable HTLC sig script"
+		}
+		if len(pushes) != 1 { // <selector=1> <redeem script> of refund
+			return "malformed HTLC sig script"
+		}
 	}
+	return ""
 }
 
 // utils
 
+// checked on hex to avoid decoding sig scripts of all txs in a block
+func mayBeHtlcSigScriptHex(sigScriptHex string) bool {
+	for _, suffix := range htlcRedeemScriptHexes {
+		if len(sigScriptHex) >= len(suffix) &&
+			strings.EqualFold(sigScriptHex[len(sigScriptHex)-len(suffix):], suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// OP_RETURN ...
+func isNullDataHex(pkScriptHex string) bool {
+	return len(pkScriptHex) >= 2 && strings.EqualFold(pkScriptHex[:2], "6a")
+}
+
 func utxoAmtToSats(amt float64) uint64 {
 	return uint64(math.Round(amt * 1e8))
 }
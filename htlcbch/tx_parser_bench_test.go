@@ -0,0 +1,100 @@
+package htlcbch
+
+import (
+	"crypto/rand"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/chaincfg/chainhash"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// a block of nTxs P2PKH payments with an HTLC deposit, receipt and refund
+func newBenchBlock(b *testing.B, nTxs int) *btcjson.GetBlockVerboseTxResult {
+	randBytes := func(n int) []byte {
+		bz := make([]byte, n)
+		_, _ = rand.Read(bz)
+		return bz
+	}
+	p2pkh := func() []byte {
+		script, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+			AddData(randBytes(20)).AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+		return script
+	}
+
+	block := &btcjson.GetBlockVerboseTxResult{}
+	for i := 0; i < nTxs; i++ {
+		tx := wire.NewMsgTx(2)
+		for j := 0; j <= i%3; j++ {
+			sigScript, _ := txscript.NewScriptBuilder().AddData(randBytes(72)).AddData(randBytes(33)).Script()
+			tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{byte(i), byte(j)}, 0), sigScript))
+		}
+		tx.AddTxOut(wire.NewTxOut(100000, p2pkh()))
+		tx.AddTxOut(wire.NewTxOut(200000, p2pkh()))
+		block.Tx = append(block.Tx, msgTxToRawResult(tx))
+	}
+
+	c := newHashTypeCovenant(b, HashTypeSha256)
+	opRet, err := c.BuildOpRetPkScript(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8)
+	require.NoError(b, err)
+	scriptHash, err := c.GetRedeemScriptHash()
+	require.NoError(b, err)
+	p2sh, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_HASH160).AddData(scriptHash).
+		AddOp(txscript.OP_EQUAL).Script()
+	deposit := wire.NewMsgTx(2)
+	deposit.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0), randBytes(107)))
+	deposit.AddTxOut(wire.NewTxOut(100000, p2sh))
+	deposit.AddTxOut(wire.NewTxOut(0, opRet))
+
+	unlockSigScript, err := c.BuildUnlockSigScript(testSecretKey)
+	require.NoError(b, err)
+	refundSigScript, err := c.BuildRefundSigScript()
+	require.NoError(b, err)
+	receipt := wire.NewMsgTx(2)
+	receipt.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), unlockSigScript))
+	receipt.AddTxOut(wire.NewTxOut(90000, p2pkh()))
+	refund := wire.NewMsgTx(2)
+	refund.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 0), refundSigScript))
+	refund.AddTxOut(wire.NewTxOut(90000, p2pkh()))
+	block.Tx = append(block.Tx, msgTxToRawResult(deposit), msgTxToRawResult(receipt), msgTxToRawResult(refund))
+	return block
+}
+
+func benchmarkScanBlock(b *testing.B, nTxs int) {
+	block := newBenchBlock(b, nTxs)
+	scan := TestNet3.ScanBlock(block, nil)
+	require.Len(b, scan.Deposits, 1)
+	require.Len(b, scan.Receipts, 1)
+	require.Len(b, scan.Suspects, 0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		TestNet3.ScanBlock(block, nil)
+	}
+}
+
+func BenchmarkScanBlock_100Txs(b *testing.B)   { benchmarkScanBlock(b, 100) }
+func BenchmarkScanBlock_2000Txs(b *testing.B)  { benchmarkScanBlock(b, 2000) }
+func BenchmarkScanBlock_20000Txs(b *testing.B) { benchmarkScanBlock(b, 20000) }
+
+func BenchmarkGetHtlcLocksInfo_2000Txs(b *testing.B) {
+	block := newBenchBlock(b, 2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		TestNet3.GetHtlcLocksInfo(block)
+	}
+}
+
+func BenchmarkGetHtlcUnlocksInfo_2000Txs(b *testing.B) {
+	block := newBenchBlock(b, 2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		GetHtlcUnlocksInfo(block)
+	}
+}
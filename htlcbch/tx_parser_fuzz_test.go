@@ -0,0 +1,180 @@
+package htlcbch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/gcash/bchd/btcjson"
+	"github.com/gcash/bchd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// Seeds of FuzzParseHtlcTx are in testdata/fuzz, more can be added from blocks recorded by
+// a bot (see --record-dir), e.g. on mainnet:
+//
+//	HTLC_FUZZ_BLOCKS=/path/to/record/bch go test -fuzz=FuzzParseHtlcTx ./htlcbch
+const fuzzBlocksEnv = "HTLC_FUZZ_BLOCKS"
+
+func FuzzGetHtlcLockInfo(f *testing.F) {
+	for _, hashType := range []HashType{HashTypeSha256, HashTypeHash160} {
+		c := newHashTypeCovenant(f, hashType)
+		opRet, err := c.BuildOpRetPkScript(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8)
+		require.NoError(f, err)
+		f.Add(opRet)
+		opRet, err = c.BuildOpRetPkScriptWithMemo(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8, "order-1")
+		require.NoError(f, err)
+		f.Add(opRet)
+	}
+
+	f.Fuzz(func(t *testing.T, pkScript []byte) {
+		info := getHtlcLockInfo(pkScript)
+		if info == nil {
+			return
+		}
+		require.True(t, hasProtoID(pkScript))
+		require.True(t, info.HashType.IsValid())
+		require.Len(t, info.RecipientPkh, 20)
+		require.Len(t, info.SenderPkh, 20)
+		require.Len(t, info.HashLock, info.HashType.HashLockLen())
+		require.Len(t, info.SenderEvmAddr, 20)
+
+		// the canonical encoding of what is accepted must be parsed to the same info
+		c, err := TestNet3.NewCovenantWithHashType(info.SenderPkh, info.RecipientPkh,
+			info.HashLock, info.HashType, info.Expiration, info.PenaltyBPS)
+		if err != nil {
+			return
+		}
+		opRet, err := c.BuildOpRetPkScriptWithMemo(info.SenderEvmAddr, info.ExpectedPrice, info.Memo)
+		require.NoError(t, err)
+		require.Equal(t, info, getHtlcLockInfo(opRet))
+	})
+}
+
+func FuzzGetHtlcUnlockInfo(f *testing.F) {
+	for _, hashType := range []HashType{HashTypeSha256, HashTypeHash160} {
+		c := newHashTypeCovenant(f, hashType)
+		unlockSigScript, err := c.BuildUnlockSigScript(testSecretKey)
+		require.NoError(f, err)
+		refundSigScript, err := c.BuildRefundSigScript()
+		require.NoError(f, err)
+		f.Add(unlockSigScript)
+		f.Add(refundSigScript)
+	}
+
+	f.Fuzz(func(t *testing.T, sigScript []byte) {
+		tx := btcjson.TxRawResult{Vin: []btcjson.Vin{{ScriptSig: &btcjson.ScriptSig{Hex: hex.EncodeToString(sigScript)}}}}
+		_ = getHtlcSpendSuspectReason(tx)
+
+		info := getHtlcUnlockInfo(sigScript)
+		if info == nil {
+			return
+		}
+		secret, err := hex.DecodeString(info.Secret)
+		require.NoError(t, err)
+		require.Len(t, secret, 32)
+		require.True(t, info.HashType.IsValid())
+		require.Equal(t, info, isHtlcUnlockTx(tx))
+	})
+}
+
+// whole txs through deposit, receipt and suspect parsers
+func FuzzParseHtlcTx(f *testing.F) {
+	addRecordedTxs(f)
+
+	f.Fuzz(func(t *testing.T, rawTx []byte) {
+		msgTx, err := MsgTxFromBytes(rawTx)
+		if err != nil {
+			return
+		}
+		tx := msgTxToRawResult(msgTx)
+
+		for _, params := range []*ChainParams{MainNet, TestNet3} {
+			deposit, suspectReason := parseHtlcLockTx(tx, params, nil)
+			if deposit == nil {
+				continue
+			}
+			require.Empty(t, suspectReason)
+			c, err := params.NewCovenantWithHashType(deposit.SenderPkh, deposit.RecipientPkh,
+				deposit.HashLock, deposit.HashType, deposit.Expiration, deposit.PenaltyBPS)
+			require.NoError(t, err)
+			scriptHash, err := c.GetRedeemScriptHash()
+			require.NoError(t, err)
+			require.Equal(t, scriptHash, []byte(deposit.ScriptHash))
+			require.Equal(t, scriptHash, getP2SHash(msgTx.TxOut[0].PkScript))
+			if val := msgTx.TxOut[0].Value; val >= 0 && val <= 21e14 {
+				require.Equal(t, uint64(val), deposit.Value)
+			}
+		}
+
+		if receipt := isHtlcUnlockTx(tx); receipt != nil {
+			require.Len(t, msgTx.TxIn, 1)
+			require.Equal(t, msgTx.TxIn[0].PreviousOutPoint.Hash.String(), receipt.PrevTxHash)
+			require.Len(t, receipt.Secret, 64)
+		} else {
+			_ = getHtlcSpendSuspectReason(tx)
+		}
+	})
+}
+
+func newHashTypeCovenant(t require.TestingT, hashType HashType) *HtlcCovenant {
+	hashLock := testSecretHash
+	if hashType == HashTypeHash160 {
+		hashLock = hashLock[:20]
+	}
+	c, err := TestNet3.NewCovenantWithHashType(testSenderPkh, testRecipientPkh, hashLock, hashType,
+		testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+	return c
+}
+
+// raw txs of BCH blocks recorded by a bot
+func addRecordedTxs(f *testing.F) {
+	dir := os.Getenv(fuzzBlocksEnv)
+	if dir == "" {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(f, err)
+	for _, file := range files {
+		bz, err := os.ReadFile(file)
+		require.NoError(f, err)
+		var block struct {
+			Txs []string `json:"txs"`
+		}
+		require.NoError(f, json.Unmarshal(bz, &block))
+		for _, rawTx := range block.Txs {
+			f.Add(gethcmn.FromHex(rawTx))
+		}
+	}
+}
+
+// what a BCH node returns for the tx
+func msgTxToRawResult(tx *wire.MsgTx) btcjson.TxRawResult {
+	var buf bytes.Buffer
+	_ = tx.Serialize(&buf)
+	result := btcjson.TxRawResult{
+		Txid: tx.TxHash().String(),
+		Hex:  hex.EncodeToString(buf.Bytes()),
+	}
+	for _, in := range tx.TxIn {
+		result.Vin = append(result.Vin, btcjson.Vin{
+			Txid:      in.PreviousOutPoint.Hash.String(),
+			Vout:      in.PreviousOutPoint.Index,
+			ScriptSig: &btcjson.ScriptSig{Hex: hex.EncodeToString(in.SignatureScript)},
+			Sequence:  in.Sequence,
+		})
+	}
+	for i, out := range tx.TxOut {
+		result.Vout = append(result.Vout, btcjson.Vout{
+			Value:        float64(out.Value) / 1e8,
+			N:            uint32(i),
+			ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(out.PkScript)},
+		})
+	}
+	return result
+}
@@ -3,6 +3,7 @@ package htlcbch
 import (
 	"encoding/hex"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -10,6 +11,7 @@ import (
 	gethcmn "github.com/ethereum/go-ethereum/common"
 	"github.com/gcash/bchd/btcjson"
 	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
 )
 
 func TestIsP2SH(t *testing.T) {
@@ -60,6 +62,40 @@ func TestGetHtlcLockInfo(t *testing.T) {
 	require.Equal(t, uint64(1e8), depositInfo.ExpectedPrice)
 }
 
+func TestGetHtlcLockInfoMemo(t *testing.T) {
+	recipientPkh := gethcmn.FromHex("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	senderPkh := gethcmn.FromHex("eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee")
+	hashLock := gethcmn.FromHex("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	sbchAddr := gethcmn.FromHex("ffffffffffffffffffffffffffffffffffffffff")
+	c, err := NewTestnet3Covenant(senderPkh, recipientPkh, hashLock, 72, 500)
+	require.NoError(t, err)
+
+	memo := strings.Repeat("x", MaxMemoLen)
+	pkScript, err := c.BuildOpRetPkScriptWithMemo(sbchAddr, 1e8, memo)
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(pkScript), 223)
+	depositInfo := getHtlcLockInfo(pkScript)
+	require.NotNil(t, depositInfo)
+	require.Equal(t, HashTypeSha256, depositInfo.HashType)
+	require.Equal(t, memo, depositInfo.Memo)
+
+	pkScript, err = c.BuildOpRetPkScriptWithMemo(sbchAddr, 1e8, "")
+	require.NoError(t, err)
+	require.Equal(t, "", getHtlcLockInfo(pkScript).Memo)
+
+	_, err = c.BuildOpRetPkScriptWithMemo(sbchAddr, 1e8, memo+"x")
+	require.ErrorContains(t, err, "invalid memo")
+	_, err = c.BuildOpRetPkScriptWithMemo(sbchAddr, 1e8, "order\n1")
+	require.ErrorContains(t, err, "invalid memo")
+
+	// memo pushed without hash type
+	pkScript, err = c.BuildOpRetPkScript(sbchAddr, 1e8)
+	require.NoError(t, err)
+	pkScript, err = txscript.NewScriptBuilder().AddOps(pkScript).AddData([]byte("x")).AddData([]byte("y")).Script()
+	require.NoError(t, err)
+	require.Nil(t, getHtlcLockInfo(pkScript))
+}
+
 func TestGetHtlcLockInfo2(t *testing.T) {
 	pkScript := "0x6a0453424153144d027fdd0585302264922bed58b8a84d38776ccb14a47165ef477c99a53cdeb846a7687a069d7df27c20ed88bb4d5991f2f91939d37277c0f988bbf461c889cafbdd5384ecb881ce6bf302002402050014765fd1f0e3d125b36de29b5f88295a247814276e080000000005f5e100"
 	depositInfo := getHtlcLockInfo(gethcmn.FromHex(pkScript))
@@ -138,8 +174,9 @@ func TestIsHtlcLockTx(t *testing.T) {
 	require.NoError(t, json.Unmarshal([]byte(txJSON), &tx))
 
 	//recipientPkh := gethcmn.FromHex("0x104f3f29055f1b2b6debeb6e69a6f0d534f01585")
-	result := isHtlcLockTx(tx)
+	result := isHtlcLockTx(tx, TestNet3, nil)
 	require.NotNil(t, result)
+	require.Equal(t, result, TestNet3.GetHtlcLockInfo(tx))
 	require.Equal(t, "7e6343c8ccdc0ef7504931fb80b61414c1eee4bab287879cbf1f3deb63222b4f", result.TxHash)
 	require.Equal(t, "92a9a3f7f0bbd5b6a66b95db86957de6277bc491", hex.EncodeToString(result.RecipientPkh))
 	require.Equal(t, "8b79ea99e6c418776a9c9d2c5dc074b4404c8a57", hex.EncodeToString(result.SenderPkh))
@@ -149,6 +186,12 @@ func TestIsHtlcLockTx(t *testing.T) {
 	require.Equal(t, "a8afaf6b99a5d5dfd359aa1bc0ef9a0bef0886c8", hex.EncodeToString(result.ScriptHash))
 	require.Equal(t, uint64(5000), result.Value)
 	require.Equal(t, uint64(1e8), result.ExpectedPrice)
+
+	// deposit floor
+	require.NotNil(t, isHtlcLockTx(tx, TestNet3, &DepositFilter{Floor: &DepositFloor{MinValue: 5000, UnlockFeeRate: 1, RefundFeeRate: 1}}))
+	require.Nil(t, isHtlcLockTx(tx, TestNet3, &DepositFilter{Floor: &DepositFloor{MinValue: 5001}}))
+	require.Nil(t, isHtlcLockTx(tx, TestNet3, &DepositFilter{Floor: &DepositFloor{UnlockFeeRate: 20, RefundFeeRate: 1}}))
+	require.Nil(t, isHtlcLockTx(tx, TestNet3, &DepositFilter{Floor: &DepositFloor{UnlockFeeRate: 1, RefundFeeRate: 20}}))
 }
 
 func TestGetHtlcUnlockInfo(t *testing.T) {
@@ -206,3 +249,284 @@ func TestIsUnlockTx(t *testing.T) {
 	require.Equal(t, "c748992bb1d40087c6976099e70c4fbf7124ab17359e5337baeb8e96589db15f", result.TxHash)
 	require.Equal(t, "3132330000000000000000000000000000000000000000000000000000000000", result.Secret)
 }
+
+func TestGetHtlcLockInfoHash160(t *testing.T) {
+	recipientPkh := gethcmn.FromHex("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	senderPkh := gethcmn.FromHex("eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee")
+	hashLock := gethcmn.FromHex("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	sbchAddr := gethcmn.FromHex("ffffffffffffffffffffffffffffffffffffffff")
+
+	c, err := NewTestnet3Covenant(senderPkh, recipientPkh, hashLock, 0x1234, 0x5555)
+	require.ErrorContains(t, err, "hashLock is not 32 bytes")
+	c, err = TestNet3.NewCovenantWithHashType(senderPkh, recipientPkh, hashLock, HashTypeHash160, 0x1234, 0x5555)
+	require.NoError(t, err)
+
+	pkScript, err := c.BuildOpRetPkScript(sbchAddr, 1e8)
+	require.NoError(t, err)
+	depositInfo := getHtlcLockInfo(pkScript)
+	require.NotNil(t, depositInfo)
+	require.Equal(t, HashTypeHash160, depositInfo.HashType)
+	require.Equal(t, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", hex.EncodeToString(depositInfo.HashLock))
+	require.Equal(t, uint16(0x1234), depositInfo.Expiration)
+	require.Equal(t, uint64(1e8), depositInfo.ExpectedPrice)
+
+	// hash lock length must match hash type
+	makeOpRet := func(hashLock []byte, hashType ...byte) []byte {
+		builder := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_RETURN).
+			AddData([]byte(protoID)).
+			AddData(recipientPkh).
+			AddData(senderPkh).
+			AddData(hashLock).
+			AddData(gethcmn.FromHex("1234")).
+			AddData(gethcmn.FromHex("5555")).
+			AddData(sbchAddr).
+			AddData(gethcmn.FromHex("0000000005f5e100"))
+		if len(hashType) > 0 {
+			builder.AddOps(append([]byte{byte(len(hashType))}, hashType...))
+		}
+		script, _ := builder.Script()
+		return script
+	}
+	hashLock32 := gethcmn.FromHex("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	require.Equal(t, pkScript, makeOpRet(hashLock, 1))
+	require.Nil(t, getHtlcLockInfo(makeOpRet(hashLock)))
+	require.Nil(t, getHtlcLockInfo(makeOpRet(hashLock32, 1)))
+	require.Nil(t, getHtlcLockInfo(makeOpRet(hashLock, 2)))
+	require.Nil(t, getHtlcLockInfo(makeOpRet(hashLock, 1, 1)))
+	require.NotNil(t, getHtlcLockInfo(makeOpRet(hashLock32, 0)))
+	require.Equal(t, HashTypeSha256, getHtlcLockInfo(makeOpRet(hashLock32)).HashType)
+}
+
+func TestBatchableCovenantTxs(t *testing.T) {
+	secret2 := gethcmn.Hash{'4', '5', '6'}.Bytes()
+	sbchAddr := gethcmn.FromHex("ffffffffffffffffffffffffffffffffffffffff")
+	c1, err := NewTestnet3Covenant(testSenderPkh, testRecipientPkh, testSecretHash, testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+	c2, err := NewTestnet3Covenant(testSenderPkh, testRecipientPkh, HashTypeSha256.HashSecret(secret2),
+		testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+	b1, b2 := c1.WithBatchable(), c2.WithBatchable()
+
+	toTxResult := func(tx *wire.MsgTx) btcjson.TxRawResult {
+		result := btcjson.TxRawResult{Txid: tx.TxHash().String(), Hex: MsgTxToHex(tx)}
+		for _, txIn := range tx.TxIn {
+			result.Vin = append(result.Vin, btcjson.Vin{
+				Txid:      txIn.PreviousOutPoint.Hash.String(),
+				Vout:      txIn.PreviousOutPoint.Index,
+				ScriptSig: &btcjson.ScriptSig{Hex: hex.EncodeToString(txIn.SignatureScript)},
+			})
+		}
+		for i, txOut := range tx.TxOut {
+			result.Vout = append(result.Vout, btcjson.Vout{
+				Value:        float64(txOut.Value) / 1e8,
+				N:            uint32(i),
+				ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(txOut.PkScript)},
+			})
+		}
+		return result
+	}
+
+	// deposits to both variants are found, the OP_RETURN is the same
+	inputs := []InputInfo{{TxID: gethcmn.Hash{'t', 'x', 'i', 'd'}.Bytes(), Vout: 1, Amount: 200000}}
+	for _, c := range []*HtlcCovenant{c1, b1} {
+		tx, err := c.MakeDepositTx(testSenderWIF.PrivKey, inputs, 100000, sbchAddr, 1e8, "", 2)
+		require.NoError(t, err)
+		deposit := isHtlcLockTx(toTxResult(tx), TestNet3, nil)
+		require.NotNil(t, deposit)
+		require.Equal(t, c.Template().Version, deposit.Template)
+		scriptHash, err := c.GetRedeemScriptHash()
+		require.NoError(t, err)
+		require.Equal(t, scriptHash, []byte(deposit.ScriptHash))
+	}
+
+	// single receipts of both variants
+	txid1, txid2 := gethcmn.Hash{'u', 't', 'x', 'o', '1'}.Bytes(), gethcmn.Hash{'u', 't', 'x', 'o', '2'}.Bytes()
+	for _, c := range []*HtlcCovenant{c1, b1} {
+		tx, err := c.MakeUnlockTx(txid1, 0, 100000, 2, testSecretKey)
+		require.NoError(t, err)
+		receipts := GetHtlcUnlocksInfoOfTx(toTxResult(tx))
+		require.Len(t, receipts, 1)
+		require.Equal(t, hex.EncodeToString(testSecretKey), receipts[0].Secret)
+		require.Equal(t, c.Template().Version, receipts[0].TemplateVersion)
+	}
+
+	// batch receipts
+	batchTx, err := MakeBatchUnlockTx([]BatchReceipt{
+		{Covenant: b1, TxID: txid1, InAmt: 100000, Secret: testSecretKey},
+		{Covenant: b2, TxID: txid2, InAmt: 100000, Secret: secret2},
+	}, 2, 0)
+	require.NoError(t, err)
+	result := toTxResult(batchTx)
+	require.Nil(t, GetHtlcUnlockInfo(result))
+	receipts := GetHtlcUnlocksInfoOfTx(result)
+	require.Len(t, receipts, 2)
+	require.Equal(t, batchTx.TxIn[1].PreviousOutPoint.Hash.String(), receipts[1].PrevTxHash)
+	require.Equal(t, hex.EncodeToString(secret2), receipts[1].Secret)
+	require.Equal(t, batchTx.TxHash().String(), receipts[1].TxHash)
+	require.Len(t, GetHtlcUnlocksInfo(&btcjson.GetBlockVerboseTxResult{Tx: []btcjson.TxRawResult{result}}), 2)
+}
+
+func TestParseSuspectTxs(t *testing.T) {
+	c, err := NewTestnet3Covenant(testSenderPkh, testRecipientPkh, testSecretHash, testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+	opRet, err := c.BuildOpRetPkScript(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8)
+	require.NoError(t, err)
+	scriptHash, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	p2sh := append(append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, scriptHash...), txscript.OP_EQUAL)
+
+	makeLockTx := func(out0, out1 []byte) btcjson.TxRawResult {
+		return btcjson.TxRawResult{Txid: "lock", Vout: []btcjson.Vout{
+			{Value: 0.0001, ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(out0)}},
+			{ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(out1)}},
+		}}
+	}
+	parseLock := func(tx btcjson.TxRawResult) (*HtlcLockInfo, string) {
+		return parseHtlcLockTx(tx, TestNet3, nil)
+	}
+
+	deposit, reason := parseLock(makeLockTx(p2sh, opRet))
+	require.NotNil(t, deposit)
+	require.Empty(t, reason)
+
+	// not an HTLC tx
+	otherOpRet, _ := txscript.NullDataScript([]byte("hello"))
+	deposit, reason = parseLock(makeLockTx(p2sh, otherOpRet))
+	require.Nil(t, deposit)
+	require.Empty(t, reason)
+
+	// suspects
+	_, reason = parseLock(makeLockTx(p2sh, opRet[:len(opRet)-9]))
+	require.Equal(t, "malformed SBAS OP_RETURN", reason)
+	_, reason = parseLock(makeLockTx(opRet, opRet))
+	require.Equal(t, "output#0 is not P2SH", reason)
+	wrongP2sh := gethcmn.CopyBytes(p2sh)
+	wrongP2sh[2] ^= 0xff
+	_, reason = parseLock(makeLockTx(wrongP2sh, opRet))
+	require.Contains(t, reason, "script hash mismatch")
+
+	// any output order
+	anyOrder := &DepositFilter{AnyOutputOrder: true}
+	reorderedTx := btcjson.TxRawResult{Txid: "lock", Vout: []btcjson.Vout{
+		{Value: 0.5, ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(wrongP2sh)}},
+		{ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(otherOpRet)}},
+		{ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(opRet)}},
+		{Value: 0.0001, ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(p2sh)}},
+	}}
+	deposit, _ = parseLock(reorderedTx)
+	require.Nil(t, deposit)
+	deposit, reason = parseHtlcLockTx(reorderedTx, TestNet3, anyOrder)
+	require.Empty(t, reason)
+	require.Equal(t, uint32(3), deposit.Vout)
+	require.Equal(t, uint64(10000), deposit.Value)
+	deposit, _ = parseHtlcLockTx(makeLockTx(p2sh, opRet), TestNet3, anyOrder)
+	require.Equal(t, uint32(0), deposit.Vout)
+	deposit, _ = parseHtlcLockTx(reorderedTx, TestNet3, &DepositFilter{Floor: &DepositFloor{MinValue: 10001}, AnyOutputOrder: true})
+	require.Nil(t, deposit)
+	reorderedTx.Vout = reorderedTx.Vout[:3]
+	_, reason = parseHtlcLockTx(reorderedTx, TestNet3, anyOrder)
+	require.Contains(t, reason, "no P2SH output matches script hash")
+
+	makeSpendTx := func(sigScripts ...[]byte) btcjson.TxRawResult {
+		tx := btcjson.TxRawResult{Txid: "spend"}
+		for _, sigScript := range sigScripts {
+			tx.Vin = append(tx.Vin, btcjson.Vin{ScriptSig: &btcjson.ScriptSig{Hex: hex.EncodeToString(sigScript)}})
+		}
+		return tx
+	}
+	unlockSigScript, err := c.BuildUnlockSigScript(testSecretKey)
+	require.NoError(t, err)
+	refundSigScript, err := c.BuildRefundSigScript()
+	require.NoError(t, err)
+	redeemScript, err := c.BuildFullRedeemScript()
+	require.NoError(t, err)
+	badSigScript, err := txscript.NewScriptBuilder().
+		AddData(testSecretKey[:31]).AddInt64(0).AddData(redeemScript).Script()
+	require.NoError(t, err)
+
+	block := &btcjson.GetBlockVerboseTxResult{Tx: []btcjson.TxRawResult{
+		makeSpendTx(unlockSigScript),
+		makeSpendTx(refundSigScript),
+		makeSpendTx([]byte{txscript.OP_TRUE}),
+		makeSpendTx([]byte{txscript.OP_TRUE}, unlockSigScript),
+		makeSpendTx(badSigScript),
+	}}
+	receipts, suspects := getHtlcUnlocksInfo(block)
+	require.Len(t, receipts, 1)
+	require.Len(t, suspects, 2)
+	require.Equal(t, "HTLC spent by input#1 of 2 inputs", suspects[0].Reason)
+	require.Equal(t, "malformed HTLC sig script", suspects[1].Reason)
+
+	// found by ScanBlock
+	block.Tx = append(block.Tx, makeLockTx(wrongP2sh, opRet))
+	scan := TestNet3.ScanBlock(block, nil)
+	require.Len(t, scan.Suspects, 3)
+	require.Equal(t, "lock", scan.Suspects[0].TxHash)
+}
+
+func TestDiagnoseSbasTxs(t *testing.T) {
+	c, err := NewTestnet3Covenant(testSenderPkh, testRecipientPkh, testSecretHash, testExpiration, testPenaltyBPS)
+	require.NoError(t, err)
+	opRet, err := c.BuildOpRetPkScript(gethcmn.Address{'u', 's', 'e', 'r'}.Bytes(), 1e8)
+	require.NoError(t, err)
+	scriptHash, err := c.GetRedeemScriptHash()
+	require.NoError(t, err)
+	p2sh := append(append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, scriptHash...), txscript.OP_EQUAL)
+	otherOpRet, _ := txscript.NullDataScript([]byte("hello"))
+
+	makeTx := func(txid string, outs ...[]byte) btcjson.TxRawResult {
+		tx := btcjson.TxRawResult{Txid: txid}
+		for _, out := range outs {
+			tx.Vout = append(tx.Vout, btcjson.Vout{Value: 0.0001,
+				ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(out)}})
+		}
+		return tx
+	}
+	diagnose := func(filter *DepositFilter, outs ...[]byte) *SbasTx {
+		return diagnoseSbasTx(makeTx("tx", outs...), TestNet3, filter)
+	}
+
+	// not SBAS txs
+	require.Nil(t, diagnose(nil, p2sh, otherOpRet))
+	require.Nil(t, diagnose(nil, p2sh))
+
+	sbasTx := diagnose(nil, p2sh, opRet)
+	require.NotNil(t, sbasTx.Deposit)
+	require.Empty(t, sbasTx.Reason)
+	require.Equal(t, uint32(1), sbasTx.Vout)
+	require.Len(t, sbasTx.Payload, 7)
+	require.Equal(t, []byte(testRecipientPkh), []byte(sbasTx.Payload[0]))
+
+	// exact reasons
+	sbasTx = diagnose(nil, p2sh, opRet[:len(opRet)-9])
+	require.Nil(t, sbasTx.Deposit)
+	require.Equal(t, "malformed SBAS OP_RETURN: invalid number of pushes: 7", sbasTx.Reason)
+	require.Len(t, sbasTx.Payload, 6)
+	badPrice := gethcmn.CopyBytes(opRet)
+	badPrice[len(badPrice)-9] = 7
+	require.Equal(t, "malformed SBAS OP_RETURN: invalid length of expected price: 7",
+		diagnose(nil, p2sh, badPrice[:len(badPrice)-1]).Reason)
+	require.Equal(t, "output#0 is not P2SH", diagnose(nil, opRet, opRet).Reason)
+	require.Equal(t, "SBAS OP_RETURN is output#2, not output#1", diagnose(nil, p2sh, otherOpRet, opRet).Reason)
+	require.Equal(t, "SBAS OP_RETURN is output#0, not output#1", diagnose(nil, opRet).Reason)
+	anyOrder := &DepositFilter{AnyOutputOrder: true}
+	require.NotNil(t, diagnose(anyOrder, otherOpRet, opRet, p2sh).Deposit)
+	require.Equal(t, "no output besides the SBAS OP_RETURN", diagnose(anyOrder, opRet).Reason)
+	require.Contains(t, diagnose(anyOrder, otherOpRet, opRet).Reason, "no P2SH output matches script hash")
+	floor := &DepositFilter{Floor: &DepositFloor{MinValue: 10001}}
+	require.Equal(t, "deposit below the floor", diagnose(floor, p2sh, opRet).Reason)
+
+	// collected by ScanBlock only with Diagnose
+	block := &btcjson.GetBlockVerboseTxResult{Tx: []btcjson.TxRawResult{
+		makeTx("lock", p2sh, opRet),
+		makeTx("other", p2sh, otherOpRet),
+		makeTx("bad", p2sh, opRet[:len(opRet)-9]),
+	}}
+	require.Empty(t, TestNet3.ScanBlock(block, nil).SbasTxs)
+	scan := TestNet3.ScanBlock(block, &DepositFilter{Diagnose: true})
+	require.Len(t, scan.Deposits, 1)
+	require.Len(t, scan.SbasTxs, 2)
+	require.Equal(t, "lock", scan.SbasTxs[0].TxHash)
+	require.Equal(t, "bad", scan.SbasTxs[1].TxHash)
+}
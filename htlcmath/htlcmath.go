@@ -0,0 +1,168 @@
+// Package htlcmath computes how the value of a BCH HTLC covenant is split by its unlock|refund txs.
+// All arithmetic is checked, results never wrap around silently.
+package htlcmath
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	DustLimit = 546   // min value of P2PKH outputs relayed by nodes, in sats
+	MaxBPS    = 10000 // 100%
+)
+
+var (
+	ErrOverflow     = errors.New("integer overflow")
+	ErrInsufficient = errors.New("insufficient value")
+	ErrDustOutput   = errors.New("output below dust limit")
+	ErrInvalidBPS   = errors.New("BPS must not exceed 10000")
+	ErrDivideByZero = errors.New("divide by zero")
+)
+
+// ReceiptSplit tells where the value of a covenant goes when it is spent
+type ReceiptSplit struct {
+	ToSender    uint64 // refund tx only
+	ToRecipient uint64 // unlocked value, or penalty of refund tx
+	ToReferral  uint64 // unlock tx of split covenants only
+	MinerFee    uint64
+}
+
+func Add(a, b uint64) (uint64, error) {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return 0, ErrOverflow
+	}
+	return sum, nil
+}
+
+func Sub(a, b uint64) (uint64, error) {
+	diff, borrow := bits.Sub64(a, b, 0)
+	if borrow != 0 {
+		return 0, fmt.Errorf("%w: %d < %d", ErrInsufficient, a, b)
+	}
+	return diff, nil
+}
+
+func Mul(a, b uint64) (uint64, error) {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return 0, ErrOverflow
+	}
+	return lo, nil
+}
+
+// MulDiv returns a * b / c rounded down, the 128-bit product does not overflow
+func MulDiv(a, b, c uint64) (uint64, error) {
+	if c == 0 {
+		return 0, ErrDivideByZero
+	}
+	hi, lo := bits.Mul64(a, b)
+	if hi >= c {
+		return 0, ErrOverflow
+	}
+	q, _ := bits.Div64(hi, lo, c)
+	return q, nil
+}
+
+// ToInt64 converts sats to the type of wire.TxOut.Value
+func ToInt64(n uint64) (int64, error) {
+	if n > math.MaxInt64 {
+		return 0, ErrOverflow
+	}
+	return int64(n), nil
+}
+
+// FromInt64 converts wire.TxOut.Value to sats
+func FromInt64(n int64) (uint64, error) {
+	if n < 0 {
+		return 0, fmt.Errorf("%w: negative value %d", ErrInsufficient, n)
+	}
+	return uint64(n), nil
+}
+
+// ApplyBPS returns value * bps / 10000 rounded down
+func ApplyBPS(value uint64, bps uint16) (uint64, error) {
+	if bps > MaxBPS {
+		return 0, ErrInvalidBPS
+	}
+	return MulDiv(value, uint64(bps), MaxBPS)
+}
+
+// Penalty is paid to recipient by refund tx, it is rounded up to dust limit, 0 if penaltyBPS is 0
+func Penalty(value uint64, penaltyBPS uint16) (uint64, error) {
+	if penaltyBPS == 0 {
+		return 0, nil
+	}
+	penalty, err := ApplyBPS(value, penaltyBPS)
+	if err != nil {
+		return 0, err
+	}
+	if penalty < DustLimit {
+		penalty = DustLimit
+	}
+	return penalty, nil
+}
+
+// UnlockSplit pays value minus miner fee to recipient
+func UnlockSplit(value, minerFee uint64) (ReceiptSplit, error) {
+	out, err := Sub(value, minerFee)
+	if err != nil {
+		return ReceiptSplit{}, err
+	}
+	if out < DustLimit {
+		return ReceiptSplit{}, fmt.Errorf("%w: %d", ErrDustOutput, out)
+	}
+	return ReceiptSplit{ToRecipient: out, MinerFee: minerFee}, nil
+}
+
+// UnlockSplitWithReferral pays the referral fee to referral, and value minus referral fee and
+// miner fee to recipient. Like penalty, the referral fee is rounded up to dust limit.
+func UnlockSplitWithReferral(value, minerFee uint64, referralBPS uint16) (ReceiptSplit, error) {
+	referralFee, err := Penalty(value, referralBPS)
+	if err != nil {
+		return ReceiptSplit{}, err
+	}
+	cost, err := Add(referralFee, minerFee)
+	if err != nil {
+		return ReceiptSplit{}, err
+	}
+	out, err := Sub(value, cost)
+	if err != nil {
+		return ReceiptSplit{}, err
+	}
+	if out < DustLimit {
+		return ReceiptSplit{}, fmt.Errorf("%w: %d", ErrDustOutput, out)
+	}
+	return ReceiptSplit{ToRecipient: out, ToReferral: referralFee, MinerFee: minerFee}, nil
+}
+
+// RefundSplit pays penalty to recipient, and value minus penalty and miner fee back to sender
+func RefundSplit(value, minerFee uint64, penaltyBPS uint16) (ReceiptSplit, error) {
+	penalty, err := Penalty(value, penaltyBPS)
+	if err != nil {
+		return ReceiptSplit{}, err
+	}
+	cost, err := Add(penalty, minerFee)
+	if err != nil {
+		return ReceiptSplit{}, err
+	}
+	out, err := Sub(value, cost)
+	if err != nil {
+		return ReceiptSplit{}, err
+	}
+	if out < DustLimit {
+		return ReceiptSplit{}, fmt.Errorf("%w: %d", ErrDustOutput, out)
+	}
+	return ReceiptSplit{ToSender: out, ToRecipient: penalty, MinerFee: minerFee}, nil
+}
+
+// MinerFee returns size * feeRate
+func MinerFee(size int, feeRate uint64) (uint64, error) {
+	if size < 0 {
+		return 0, ErrOverflow
+	}
+	return Mul(uint64(size), feeRate)
+}
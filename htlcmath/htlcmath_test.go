@@ -0,0 +1,195 @@
+package htlcmath
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddSubMul(t *testing.T) {
+	testCases := []struct {
+		a, b            uint64
+		sum, diff, prod uint64
+		sumErr, diffErr error
+		prodErr         error
+	}{
+		{a: 0, b: 0, sum: 0, diff: 0, prod: 0},
+		{a: 5, b: 3, sum: 8, diff: 2, prod: 15},
+		{a: 3, b: 5, sum: 8, diffErr: ErrInsufficient, prod: 15},
+		{a: math.MaxUint64, b: 0, sum: math.MaxUint64, diff: math.MaxUint64, prod: 0},
+		{a: math.MaxUint64, b: 1, sumErr: ErrOverflow, diff: math.MaxUint64 - 1, prod: math.MaxUint64},
+		{a: math.MaxUint64, b: 2, sumErr: ErrOverflow, diff: math.MaxUint64 - 2, prodErr: ErrOverflow},
+		{a: 1 << 32, b: 1 << 32, sum: 1 << 33, diff: 0, prodErr: ErrOverflow},
+		{a: 1 << 32, b: 1<<32 - 1, sum: 1<<33 - 1, diff: 1, prod: 1<<64 - 1<<32},
+	}
+	for i, tc := range testCases {
+		sum, err := Add(tc.a, tc.b)
+		require.ErrorIs(t, err, tc.sumErr, i)
+		require.Equal(t, tc.sum, sum, i)
+
+		diff, err := Sub(tc.a, tc.b)
+		require.ErrorIs(t, err, tc.diffErr, i)
+		require.Equal(t, tc.diff, diff, i)
+
+		prod, err := Mul(tc.a, tc.b)
+		require.ErrorIs(t, err, tc.prodErr, i)
+		require.Equal(t, tc.prod, prod, i)
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	testCases := []struct {
+		a, b, c uint64
+		result  uint64
+		err     error
+	}{
+		{a: 10, b: 3, c: 4, result: 7},
+		{a: 0, b: 3, c: 4, result: 0},
+		{a: 10, b: 3, c: 0, err: ErrDivideByZero},
+		{a: math.MaxUint64, b: math.MaxUint64, c: math.MaxUint64, result: math.MaxUint64},
+		{a: math.MaxUint64, b: 10000, c: 10000, result: math.MaxUint64},
+		{a: math.MaxUint64, b: 9999, c: 10000, result: 18444899399302180659},
+		{a: math.MaxUint64, b: 2, c: 1, err: ErrOverflow},
+		{a: 1 << 63, b: 2, c: 2, result: 1 << 63},
+	}
+	for i, tc := range testCases {
+		result, err := MulDiv(tc.a, tc.b, tc.c)
+		require.ErrorIs(t, err, tc.err, i)
+		require.Equal(t, tc.result, result, i)
+	}
+}
+
+func TestIntConversions(t *testing.T) {
+	n, err := ToInt64(math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), n)
+	_, err = ToInt64(math.MaxInt64 + 1)
+	require.ErrorIs(t, err, ErrOverflow)
+
+	u, err := FromInt64(0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), u)
+	_, err = FromInt64(-1)
+	require.ErrorIs(t, err, ErrInsufficient)
+}
+
+func TestPenalty(t *testing.T) {
+	testCases := []struct {
+		value   uint64
+		bps     uint16
+		penalty uint64
+		err     error
+	}{
+		{value: 1e8, bps: 0, penalty: 0},
+		{value: 0, bps: 0, penalty: 0},
+		{value: 1e8, bps: 500, penalty: 5e6},
+		{value: 1e8, bps: 1, penalty: 1e4},
+		{value: 1e8, bps: 10000, penalty: 1e8},
+		{value: 1e8, bps: 10001, err: ErrInvalidBPS},
+		{value: 10000, bps: 500, penalty: DustLimit}, // 500 rounded up
+		{value: 10920, bps: 500, penalty: DustLimit}, // 546
+		{value: 10939, bps: 500, penalty: DustLimit}, // 546.95 rounded down
+		{value: 10940, bps: 500, penalty: 547},       // 547
+		{value: 0, bps: 500, penalty: DustLimit},     // rounded up
+		{value: math.MaxUint64, bps: 10000, penalty: math.MaxUint64},
+		{value: math.MaxUint64, bps: 5000, penalty: math.MaxUint64 / 2},
+	}
+	for i, tc := range testCases {
+		penalty, err := Penalty(tc.value, tc.bps)
+		require.ErrorIs(t, err, tc.err, i)
+		require.Equal(t, tc.penalty, penalty, i)
+	}
+}
+
+func TestUnlockSplit(t *testing.T) {
+	testCases := []struct {
+		value, fee uint64
+		split      ReceiptSplit
+		err        error
+	}{
+		{value: 1e8, fee: 660, split: ReceiptSplit{ToRecipient: 1e8 - 660, MinerFee: 660}},
+		{value: 1e8, fee: 0, split: ReceiptSplit{ToRecipient: 1e8}},
+		{value: 1206, fee: 660, split: ReceiptSplit{ToRecipient: DustLimit, MinerFee: 660}},
+		{value: 1205, fee: 660, err: ErrDustOutput},
+		{value: 660, fee: 660, err: ErrDustOutput},
+		{value: 659, fee: 660, err: ErrInsufficient},
+		{value: 0, fee: math.MaxUint64, err: ErrInsufficient},
+		{value: math.MaxUint64, fee: 1, split: ReceiptSplit{ToRecipient: math.MaxUint64 - 1, MinerFee: 1}},
+	}
+	for i, tc := range testCases {
+		split, err := UnlockSplit(tc.value, tc.fee)
+		require.ErrorIs(t, err, tc.err, i)
+		require.Equal(t, tc.split, split, i)
+		if err == nil {
+			require.Equal(t, tc.value, split.ToRecipient+split.MinerFee, i)
+		}
+	}
+}
+
+func TestUnlockSplitWithReferral(t *testing.T) {
+	testCases := []struct {
+		value, fee uint64
+		bps        uint16
+		split      ReceiptSplit
+		err        error
+	}{
+		{value: 1e8, fee: 700, bps: 100, split: ReceiptSplit{ToRecipient: 1e8 - 1e6 - 700, ToReferral: 1e6, MinerFee: 700}},
+		{value: 1e8, fee: 700, bps: 0, split: ReceiptSplit{ToRecipient: 1e8 - 700, MinerFee: 700}},
+		{value: 10000, fee: 700, bps: 100, split: ReceiptSplit{ToRecipient: 10000 - DustLimit - 700, ToReferral: DustLimit, MinerFee: 700}},
+		{value: 1791, fee: 700, bps: 100, err: ErrDustOutput},
+		{value: 1e8, fee: 700, bps: 10000, err: ErrInsufficient},
+		{value: 1e8, fee: 700, bps: 10001, err: ErrInvalidBPS},
+		{value: math.MaxUint64, fee: math.MaxUint64, bps: 1, err: ErrOverflow},
+	}
+	for i, tc := range testCases {
+		split, err := UnlockSplitWithReferral(tc.value, tc.fee, tc.bps)
+		require.ErrorIs(t, err, tc.err, i)
+		require.Equal(t, tc.split, split, i)
+		if err == nil {
+			require.Equal(t, tc.value, split.ToRecipient+split.ToReferral+split.MinerFee, i)
+		}
+	}
+}
+
+func TestRefundSplit(t *testing.T) {
+	testCases := []struct {
+		value, fee uint64
+		bps        uint16
+		split      ReceiptSplit
+		err        error
+	}{
+		{value: 1e8, fee: 662, bps: 0, split: ReceiptSplit{ToSender: 1e8 - 662, MinerFee: 662}},
+		{value: 1e8, fee: 662, bps: 500, split: ReceiptSplit{ToSender: 1e8 - 5e6 - 662, ToRecipient: 5e6, MinerFee: 662}},
+		{value: 1e8, fee: 662, bps: 10000, err: ErrInsufficient},
+		{value: 1e8, fee: 0, bps: 10000, err: ErrDustOutput},
+		{value: 1e8, fee: 662, bps: 10001, err: ErrInvalidBPS},
+		{value: 5000, fee: 662, bps: 500, split: ReceiptSplit{ToSender: 5000 - DustLimit - 662, ToRecipient: DustLimit, MinerFee: 662}},
+		{value: 1754, fee: 662, bps: 500, split: ReceiptSplit{ToSender: DustLimit, ToRecipient: DustLimit, MinerFee: 662}},
+		{value: 1753, fee: 662, bps: 500, err: ErrDustOutput},
+		{value: 1208, fee: 662, bps: 0, split: ReceiptSplit{ToSender: DustLimit, MinerFee: 662}},
+		{value: 1207, fee: 662, bps: 0, err: ErrDustOutput},
+		{value: 1000, fee: 662, bps: 500, err: ErrInsufficient},
+		{value: 1e8, fee: math.MaxUint64, bps: 500, err: ErrOverflow},
+		{value: math.MaxUint64, fee: 1, bps: 5000,
+			split: ReceiptSplit{ToSender: math.MaxUint64 - math.MaxUint64/2 - 1, ToRecipient: math.MaxUint64 / 2, MinerFee: 1}},
+	}
+	for i, tc := range testCases {
+		split, err := RefundSplit(tc.value, tc.fee, tc.bps)
+		require.ErrorIs(t, err, tc.err, i)
+		require.Equal(t, tc.split, split, i)
+		if err == nil {
+			require.Equal(t, tc.value, split.ToSender+split.ToRecipient+split.MinerFee, i)
+		}
+	}
+}
+
+func TestMinerFee(t *testing.T) {
+	fee, err := MinerFee(330, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(660), fee)
+	_, err = MinerFee(-1, 2)
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = MinerFee(2, math.MaxUint64)
+	require.ErrorIs(t, err, ErrOverflow)
+}
@@ -1,6 +1,7 @@
 package htlcsbch
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"strings"
@@ -702,6 +703,18 @@ func PackLock(
 		penaltyBPS, receiverIsMM, expectedPrice)
 }
 
+// GetLockMemo returns the bytes appended to the calldata of a lock call of sBCH or token HTLC,
+// they are ignored by the contract and carry the optional memo of the swap
+func GetLockMemo(calldata []byte) []byte {
+	for _, method := range []abi.Method{htlcAbi.Methods["lock"], tokenHtlcAbi.Methods["lock"]} {
+		n := 4 + 32*len(method.Inputs) // all inputs are static
+		if len(calldata) > n && bytes.Equal(calldata[:4], method.ID) {
+			return calldata[n:]
+		}
+	}
+	return nil
+}
+
 func PackUnlock(sender common.Address, hashLock, secret common.Hash) ([]byte, error) {
 	// function unlock(address sender, bytes32 _secretLock, bytes32 _secretKey) public
 	return htlcAbi.Pack("unlock", sender, hashLock, secret)
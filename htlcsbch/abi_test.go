@@ -43,6 +43,26 @@ func TestPackLock(t *testing.T) {
 `, "\n", ""), hex.EncodeToString(data))
 }
 
+func TestGetLockMemo(t *testing.T) {
+	recipient := common.Address{'b', 'o', 't'}
+	hashLock := common.Hash{'s', 'e', 'c', 'r', 'e', 't'}
+	bchAddr := common.Address{'u', 's', 'e', 'r'}
+
+	data, err := PackLock(recipient, hashLock, 100, bchAddr)
+	require.NoError(t, err)
+	require.Nil(t, GetLockMemo(data))
+	require.Equal(t, []byte("order-123"), GetLockMemo(append(data, "order-123"...)))
+
+	data, err = PackLockToken(recipient, hashLock, 100, bchAddr, big.NewInt(1))
+	require.NoError(t, err)
+	require.Nil(t, GetLockMemo(data))
+	require.Equal(t, []byte("order-456"), GetLockMemo(append(data, "order-456"...)))
+
+	data, err = PackRefund(recipient, hashLock)
+	require.NoError(t, err)
+	require.Nil(t, GetLockMemo(append(data, make([]byte, 300)...)))
+}
+
 func TestPackUnlock(t *testing.T) {
 	sender := common.Address{'s', 'e', 'n', 'd', 'e', 'r'}
 	hashLock := common.Hash{'h', 'a', 's', 'h', 'l', 'o', 'c', 'k', 0xaa}
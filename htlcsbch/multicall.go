@@ -0,0 +1,106 @@
+package htlcsbch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// only aggregate3() of Multicall3, which is deployed at the same address on most EVM chains
+	_multicallAbiJsonStr = `[
+    {
+      "inputs": [
+        {
+          "components": [
+            {
+              "internalType": "address",
+              "name": "target",
+              "type": "address"
+            },
+            {
+              "internalType": "bool",
+              "name": "allowFailure",
+              "type": "bool"
+            },
+            {
+              "internalType": "bytes",
+              "name": "callData",
+              "type": "bytes"
+            }
+          ],
+          "internalType": "struct Multicall3.Call3[]",
+          "name": "calls",
+          "type": "tuple[]"
+        }
+      ],
+      "name": "aggregate3",
+      "outputs": [
+        {
+          "components": [
+            {
+              "internalType": "bool",
+              "name": "success",
+              "type": "bool"
+            },
+            {
+              "internalType": "bytes",
+              "name": "returnData",
+              "type": "bytes"
+            }
+          ],
+          "internalType": "struct Multicall3.Result[]",
+          "name": "returnData",
+          "type": "tuple[]"
+        }
+      ],
+      "stateMutability": "payable",
+      "type": "function"
+    }
+  ]`
+)
+
+var multicallAbi abi.ABI
+
+func init() {
+	var err error
+	multicallAbi, err = abi.JSON(strings.NewReader(_multicallAbiJsonStr))
+	if err != nil {
+		panic("failed to parse Multicall3 ABI")
+	}
+}
+
+// Call3 is a call aggregated by Multicall3
+type Call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// Call3Result is the result of a Call3
+type Call3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+func PackAggregate3(calls []Call3) ([]byte, error) {
+	// function aggregate3(Call3[] calldata calls) public payable returns (Result[] memory returnData)
+	return multicallAbi.Pack("aggregate3", calls)
+}
+
+func UnpackAggregate3(data []byte) ([]Call3Result, error) {
+	result, err := multicallAbi.Unpack("aggregate3", data)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != 1 {
+		return nil, fmt.Errorf("no or too many results: %d", len(result))
+	}
+	var results []Call3Result
+	if err = multicallAbi.Methods["aggregate3"].Outputs.Copy(&results, result); err != nil {
+		return nil, fmt.Errorf("failed to cast results: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,37 @@
+package htlcsbch
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMulticallABI(t *testing.T) {
+	require.Equal(t, "82ad56cb", hex.EncodeToString(multicallAbi.Methods["aggregate3"].ID))
+
+	calls := []Call3{
+		{Target: common.Address{'h', 't', 'l', 'c'}, CallData: []byte{1, 2, 3}},
+		{Target: common.Address{'h', 't', 'l', 'c', '2'}, AllowFailure: true, CallData: []byte{4, 5}},
+	}
+	data, err := PackAggregate3(calls)
+	require.NoError(t, err)
+	args, err := multicallAbi.Methods["aggregate3"].Inputs.Unpack(data[4:])
+	require.NoError(t, err)
+	require.Len(t, args, 1)
+
+	results := []Call3Result{
+		{Success: true, ReturnData: common.LeftPadBytes([]byte{2}, 32)},
+		{Success: false, ReturnData: []byte{}},
+	}
+	data, err = multicallAbi.Methods["aggregate3"].Outputs.Pack(results)
+	require.NoError(t, err)
+	results2, err := UnpackAggregate3(data)
+	require.NoError(t, err)
+	require.Equal(t, results, results2)
+
+	state, err := UnpackGetSwapState(results2[0].ReturnData)
+	require.NoError(t, err)
+	require.Equal(t, uint8(2), state)
+}
@@ -0,0 +1,96 @@
+package htlcsbch
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+const (
+	// only the Chainlink aggregator functions used by bot
+	_aggregatorAbiJsonStr = `[
+    {
+      "inputs": [],
+      "name": "latestRoundData",
+      "outputs": [
+        {
+          "internalType": "uint80",
+          "name": "roundId",
+          "type": "uint80"
+        },
+        {
+          "internalType": "int256",
+          "name": "answer",
+          "type": "int256"
+        },
+        {
+          "internalType": "uint256",
+          "name": "startedAt",
+          "type": "uint256"
+        },
+        {
+          "internalType": "uint256",
+          "name": "updatedAt",
+          "type": "uint256"
+        },
+        {
+          "internalType": "uint80",
+          "name": "answeredInRound",
+          "type": "uint80"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    }
+  ]`
+)
+
+var aggregatorAbi abi.ABI
+
+type RoundData struct {
+	RoundId         *big.Int
+	Answer          *big.Int
+	StartedAt       *big.Int
+	UpdatedAt       *big.Int
+	AnsweredInRound *big.Int
+}
+
+func init() {
+	var err error
+	aggregatorAbi, err = abi.JSON(strings.NewReader(_aggregatorAbiJsonStr))
+	if err != nil {
+		panic("failed to parse aggregator ABI")
+	}
+}
+
+func PackLatestRoundData() ([]byte, error) {
+	// function latestRoundData() external view returns (uint80, int256, uint256, uint256, uint80)
+	return aggregatorAbi.Pack("latestRoundData")
+}
+func UnpackLatestRoundData(data []byte) (*RoundData, error) {
+	result, err := aggregatorAbi.Unpack("latestRoundData", data)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != 5 {
+		return nil, fmt.Errorf("expected fields: 5, got: %d", len(result))
+	}
+
+	fields := make([]*big.Int, 5)
+	for i, x := range result {
+		n, ok := x.(*big.Int)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast field#%d", i)
+		}
+		fields[i] = n
+	}
+	return &RoundData{
+		RoundId:         fields[0],
+		Answer:          fields[1],
+		StartedAt:       fields[2],
+		UpdatedAt:       fields[3],
+		AnsweredInRound: fields[4],
+	}, nil
+}
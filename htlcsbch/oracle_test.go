@@ -0,0 +1,34 @@
+package htlcsbch
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackLatestRoundData(t *testing.T) {
+	data, err := PackLatestRoundData()
+	require.NoError(t, err)
+	require.Equal(t, "feaf968c", hex.EncodeToString(data))
+}
+
+func TestUnpackLatestRoundData(t *testing.T) {
+	data := common.FromHex(strings.ReplaceAll(`
+0000000000000000000000000000000000000000000000000000000000000007
+00000000000000000000000000000000000000000000000000000005d21dba00
+0000000000000000000000000000000000000000000000000000000063ae1f00
+0000000000000000000000000000000000000000000000000000000063ae1f2a
+0000000000000000000000000000000000000000000000000000000000000007
+`, "\n", ""))
+	rd, err := UnpackLatestRoundData(data)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(7), rd.RoundId)
+	require.Equal(t, big.NewInt(250e8), rd.Answer)
+	require.Equal(t, big.NewInt(0x63ae1f00), rd.StartedAt)
+	require.Equal(t, big.NewInt(0x63ae1f2a), rd.UpdatedAt)
+	require.Equal(t, big.NewInt(7), rd.AnsweredInRound)
+}
@@ -0,0 +1,246 @@
+package htlcsbch
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// only the SEP20 (ERC20) functions used by bot
+	_sep20AbiJsonStr = `[
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "owner",
+          "type": "address"
+        },
+        {
+          "internalType": "address",
+          "name": "spender",
+          "type": "address"
+        }
+      ],
+      "name": "allowance",
+      "outputs": [
+        {
+          "internalType": "uint256",
+          "name": "",
+          "type": "uint256"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    },
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "spender",
+          "type": "address"
+        },
+        {
+          "internalType": "uint256",
+          "name": "amount",
+          "type": "uint256"
+        }
+      ],
+      "name": "approve",
+      "outputs": [
+        {
+          "internalType": "bool",
+          "name": "",
+          "type": "bool"
+        }
+      ],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    },
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "account",
+          "type": "address"
+        }
+      ],
+      "name": "balanceOf",
+      "outputs": [
+        {
+          "internalType": "uint256",
+          "name": "",
+          "type": "uint256"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    },
+    {
+      "inputs": [],
+      "name": "decimals",
+      "outputs": [
+        {
+          "internalType": "uint8",
+          "name": "",
+          "type": "uint8"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    }
+  ]`
+
+	// the token HTLC has the same events and unlock/refund/getSwapState functions as
+	// the sBCH HTLC, but lock() is not payable and pulls tokens with transferFrom()
+	_tokenHtlcAbiJsonStr = `[
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "_receiver",
+          "type": "address"
+        },
+        {
+          "internalType": "bytes32",
+          "name": "_secretLock",
+          "type": "bytes32"
+        },
+        {
+          "internalType": "uint256",
+          "name": "_validPeriod",
+          "type": "uint256"
+        },
+        {
+          "internalType": "bytes20",
+          "name": "_receiverBchPkh",
+          "type": "bytes20"
+        },
+        {
+          "internalType": "uint16",
+          "name": "_penaltyBPS",
+          "type": "uint16"
+        },
+        {
+          "internalType": "bool",
+          "name": "_receiverIsMM",
+          "type": "bool"
+        },
+        {
+          "internalType": "uint256",
+          "name": "_expectedPrice",
+          "type": "uint256"
+        },
+        {
+          "internalType": "uint256",
+          "name": "_amount",
+          "type": "uint256"
+        }
+      ],
+      "name": "lock",
+      "outputs": [],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    }
+  ]`
+)
+
+var (
+	sep20Abi     abi.ABI
+	tokenHtlcAbi abi.ABI
+)
+
+func init() {
+	var err error
+	sep20Abi, err = abi.JSON(strings.NewReader(_sep20AbiJsonStr))
+	if err != nil {
+		panic("failed to parse SEP20 ABI")
+	}
+	tokenHtlcAbi, err = abi.JSON(strings.NewReader(_tokenHtlcAbiJsonStr))
+	if err != nil {
+		panic("failed to parse token HTLC ABI")
+	}
+}
+
+func PackLockToken(
+	recipient common.Address,
+	hashLock common.Hash,
+	timeLock uint32,
+	bchAddr common.Address,
+	amount *big.Int,
+) ([]byte, error) {
+	/*
+	   function lock(address _receiver,
+	                 bytes32 _secretLock,
+	                 uint256 _validPeriod,
+	                 bytes20 _receiverBchPkh,
+	                 uint16  _penaltyBPS,
+	                 bool    _receiverIsMM,
+	                 uint256 _expectedPrice,
+	                 uint256 _amount) public {
+	*/
+	var penaltyBPS uint16 = 0
+	var receiverIsMM = false
+	var expectedPrice = big.NewInt(1e18)
+	return tokenHtlcAbi.Pack("lock",
+		recipient, hashLock, big.NewInt(int64(timeLock)), bchAddr,
+		penaltyBPS, receiverIsMM, expectedPrice, amount)
+}
+
+func PackApprove(spender common.Address, amount *big.Int) ([]byte, error) {
+	// function approve(address spender, uint256 amount) external returns (bool)
+	return sep20Abi.Pack("approve", spender, amount)
+}
+
+func PackAllowance(owner, spender common.Address) ([]byte, error) {
+	// function allowance(address owner, address spender) external view returns (uint256)
+	return sep20Abi.Pack("allowance", owner, spender)
+}
+func UnpackAllowance(data []byte) (*big.Int, error) {
+	return unpackSep20Uint256("allowance", data)
+}
+
+func PackBalanceOf(owner common.Address) ([]byte, error) {
+	// function balanceOf(address account) external view returns (uint256)
+	return sep20Abi.Pack("balanceOf", owner)
+}
+func UnpackBalanceOf(data []byte) (*big.Int, error) {
+	return unpackSep20Uint256("balanceOf", data)
+}
+
+func PackDecimals() ([]byte, error) {
+	// function decimals() external view returns (uint8)
+	return sep20Abi.Pack("decimals")
+}
+func UnpackDecimals(data []byte) (uint8, error) {
+	result, err := sep20Abi.Unpack("decimals", data)
+	if err != nil {
+		return 0, err
+	}
+	if len(result) != 1 {
+		return 0, fmt.Errorf("no or too many results: %d", len(result))
+	}
+	n, ok := result[0].(uint8)
+	if !ok {
+		return 0, fmt.Errorf("failed to cast result to uint8")
+	}
+	return n, nil
+}
+
+func unpackSep20Uint256(method string, data []byte) (*big.Int, error) {
+	result, err := sep20Abi.Unpack(method, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != 1 {
+		return nil, fmt.Errorf("no or too many results: %d", len(result))
+	}
+	n, ok := result[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast result to *big.Int")
+	}
+	return n, nil
+}
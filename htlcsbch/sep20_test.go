@@ -0,0 +1,65 @@
+package htlcsbch
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSep20ABI(t *testing.T) {
+	require.Equal(t, "dd62ed3e", hex.EncodeToString(sep20Abi.Methods["allowance"].ID))
+	require.Equal(t, "095ea7b3", hex.EncodeToString(sep20Abi.Methods["approve"].ID))
+	require.Equal(t, "70a08231", hex.EncodeToString(sep20Abi.Methods["balanceOf"].ID))
+	require.Equal(t, "313ce567", hex.EncodeToString(sep20Abi.Methods["decimals"].ID))
+	require.Equal(t, "a5e99fb3", hex.EncodeToString(tokenHtlcAbi.Methods["lock"].ID))
+}
+
+func TestPackLockToken(t *testing.T) {
+	recipient := common.Address{'b', 'o', 't', 0xbb}
+	hashLock := common.Hash{'s', 'e', 'c', 'r', 'e', 't', 0xcc}
+	timeLock := uint32(0x12345)
+	bchAddr := common.Address{'u', 's', 'e', 'r', 0xdd}
+
+	data, err := PackLockToken(recipient, hashLock, timeLock, bchAddr, big.NewInt(0x1234567890))
+	require.NoError(t, err)
+	require.Equal(t, strings.ReplaceAll(`a5e99fb3
+000000000000000000000000626f74bb00000000000000000000000000000000
+736563726574cc00000000000000000000000000000000000000000000000000
+0000000000000000000000000000000000000000000000000000000000012345
+75736572dd000000000000000000000000000000000000000000000000000000
+0000000000000000000000000000000000000000000000000000000000000000
+0000000000000000000000000000000000000000000000000000000000000000
+0000000000000000000000000000000000000000000000000de0b6b3a7640000
+0000000000000000000000000000000000000000000000000000001234567890
+`, "\n", ""), hex.EncodeToString(data))
+}
+
+func TestPackApprove(t *testing.T) {
+	spender := common.Address{'h', 't', 'l', 'c'}
+	data, err := PackApprove(spender, big.NewInt(1e18))
+	require.NoError(t, err)
+	require.Equal(t, strings.ReplaceAll(`095ea7b3
+00000000000000000000000068746c6300000000000000000000000000000000
+0000000000000000000000000000000000000000000000000de0b6b3a7640000
+`, "\n", ""), hex.EncodeToString(data))
+}
+
+func TestUnpackSep20(t *testing.T) {
+	data := common.FromHex("0x0000000000000000000000000000000000000000000000000de0b6b3a7640000")
+	n, err := UnpackBalanceOf(data)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1e18), n)
+
+	n, err = UnpackAllowance(data)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1e18), n)
+
+	data = common.FromHex("0x0000000000000000000000000000000000000000000000000000000000000012")
+	d, err := UnpackDecimals(data)
+	require.NoError(t, err)
+	require.Equal(t, uint8(18), d)
+}
@@ -0,0 +1,490 @@
+// Package qrcode encodes bytes into QR codes (byte mode, error correction level M),
+// which is all that payment URIs need.
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+const quietZone = 4 // in modules
+
+// error correction codewords per block, number of blocks and data codewords per block
+// of the 2 block groups, level M, indexed by version-1
+var blockTable = [40][5]int{
+	{10, 1, 16, 0, 0}, {16, 1, 28, 0, 0}, {26, 1, 44, 0, 0}, {18, 2, 32, 0, 0},
+	{24, 2, 43, 0, 0}, {16, 4, 27, 0, 0}, {18, 4, 31, 0, 0}, {22, 2, 38, 2, 39},
+	{22, 3, 36, 2, 37}, {26, 4, 43, 1, 44}, {30, 1, 50, 4, 51}, {22, 6, 36, 2, 37},
+	{22, 8, 37, 1, 38}, {24, 4, 40, 5, 41}, {24, 5, 41, 5, 42}, {28, 7, 45, 3, 46},
+	{28, 10, 46, 1, 47}, {26, 9, 43, 4, 44}, {26, 3, 44, 11, 45}, {26, 3, 41, 13, 42},
+	{26, 17, 42, 0, 0}, {28, 17, 46, 0, 0}, {28, 4, 47, 14, 48}, {28, 6, 45, 14, 46},
+	{28, 8, 47, 13, 48}, {28, 19, 46, 4, 47}, {28, 22, 45, 3, 46}, {28, 3, 45, 23, 46},
+	{28, 21, 45, 7, 46}, {28, 19, 47, 10, 48}, {28, 2, 46, 29, 47}, {28, 10, 46, 23, 47},
+	{28, 14, 46, 21, 47}, {28, 14, 46, 23, 47}, {28, 12, 47, 26, 48}, {28, 6, 47, 34, 48},
+	{28, 29, 46, 14, 47}, {28, 13, 46, 32, 47}, {28, 40, 47, 7, 48}, {28, 18, 47, 31, 48},
+}
+
+// centers of alignment patterns, indexed by version-1
+var alignmentTable = [40][]int{
+	{}, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34}, {6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+	{6, 30, 54}, {6, 32, 58}, {6, 34, 62}, {6, 26, 46, 66}, {6, 26, 48, 70}, {6, 26, 50, 74},
+	{6, 30, 54, 78}, {6, 30, 56, 82}, {6, 30, 58, 86}, {6, 34, 62, 90}, {6, 28, 50, 72, 94},
+	{6, 26, 50, 74, 98}, {6, 30, 54, 78, 102}, {6, 28, 54, 80, 106}, {6, 32, 58, 84, 110},
+	{6, 30, 58, 86, 114}, {6, 34, 62, 90, 118}, {6, 26, 50, 74, 98, 122}, {6, 30, 54, 78, 102, 126},
+	{6, 26, 52, 78, 104, 130}, {6, 30, 56, 82, 108, 134}, {6, 34, 60, 86, 112, 138},
+	{6, 30, 58, 86, 114, 142}, {6, 34, 62, 90, 118, 146}, {6, 30, 54, 78, 102, 126, 150},
+	{6, 24, 50, 76, 102, 128, 154}, {6, 28, 54, 80, 106, 132, 158}, {6, 32, 58, 84, 110, 136, 162},
+	{6, 26, 54, 82, 110, 138, 166}, {6, 30, 58, 86, 114, 142, 170},
+}
+
+// QRCode is a square of dark and light modules
+type QRCode struct {
+	Version int
+	Size    int
+
+	modules    [][]bool // [y][x], true means dark
+	isFunction [][]bool // finder, timing, alignment, format and version modules
+}
+
+// Encode encodes data with the smallest version which fits and the mask of the lowest penalty
+func Encode(data []byte) (*QRCode, error) {
+	return encode(data, -1)
+}
+
+func encode(data []byte, mask int) (*QRCode, error) {
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if 4+charCountBits(v)+len(data)*8 <= dataCodewords(v)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("data too long: %d bytes", len(data))
+	}
+
+	size := version*4 + 17
+	qr := &QRCode{
+		Version:    version,
+		Size:       size,
+		modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+	}
+	for y := 0; y < size; y++ {
+		qr.modules[y] = make([]bool, size)
+		qr.isFunction[y] = make([]bool, size)
+	}
+	qr.drawFunctionPatterns()
+	qr.drawCodewords(addErrorCorrection(version, encodeData(version, data)))
+
+	if mask < 0 {
+		minPenalty := -1
+		for m := 0; m < 8; m++ {
+			qr.applyMask(m)
+			qr.drawFormatBits(m)
+			if penalty := qr.penalty(); minPenalty < 0 || penalty < minPenalty {
+				minPenalty = penalty
+				mask = m
+			}
+			qr.applyMask(m) // undo
+		}
+	}
+	qr.applyMask(mask)
+	qr.drawFormatBits(mask)
+	return qr, nil
+}
+
+// Dark returns true if the module at column x and row y is dark
+func (qr *QRCode) Dark(x, y int) bool {
+	return qr.modules[y][x]
+}
+
+// PNG renders the code with a quiet zone, each module takes scale*scale pixels
+func (qr *QRCode) PNG(scale int) ([]byte, error) {
+	if scale <= 0 {
+		return nil, fmt.Errorf("invalid scale: %d", scale)
+	}
+	width := (qr.Size + quietZone*2) * scale
+	img := image.NewPaletted(image.Rect(0, 0, width, width),
+		color.Palette{color.White, color.Black})
+	for y := 0; y < qr.Size; y++ {
+		for x := 0; x < qr.Size; x++ {
+			if !qr.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+quietZone)*scale+dx, (y+quietZone)*scale+dy, 1)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func dataCodewords(version int) int {
+	b := blockTable[version-1]
+	return b[1]*b[2] + b[3]*b[4]
+}
+
+// mode indicator, char count, data, terminator and pad bytes
+func encodeData(version int, data []byte) []byte {
+	var bits bitBuffer
+	bits.append(0b0100, 4) // byte mode
+	bits.append(uint32(len(data)), charCountBits(version))
+	for _, b := range data {
+		bits.append(uint32(b), 8)
+	}
+
+	capacity := dataCodewords(version) * 8
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := uint32(0xEC); len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	return bits.bytes()
+}
+
+// split data into blocks, compute error correction codewords and interleave them
+func addErrorCorrection(version int, data []byte) []byte {
+	b := blockTable[version-1]
+	ecLen := b[0]
+	divisor := rsDivisor(ecLen)
+
+	var dataBlocks, ecBlocks [][]byte
+	for g := 0; g < 2; g++ {
+		n, blockLen := b[1+g*2], b[2+g*2]
+		for i := 0; i < n; i++ {
+			block := data[:blockLen]
+			data = data[blockLen:]
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+		}
+	}
+
+	var result []byte
+	for i := 0; i < b[4] || i < b[2]; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < ecLen; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+func (qr *QRCode) setFunction(x, y int, dark bool) {
+	qr.modules[y][x] = dark
+	qr.isFunction[y][x] = true
+}
+
+func (qr *QRCode) drawFunctionPatterns() {
+	size := qr.Size
+	for i := 0; i < size; i++ {
+		qr.setFunction(6, i, i%2 == 0)
+		qr.setFunction(i, 6, i%2 == 0)
+	}
+
+	qr.drawFinder(3, 3)
+	qr.drawFinder(size-4, 3)
+	qr.drawFinder(3, size-4)
+
+	pos := alignmentTable[qr.Version-1]
+	n := len(pos)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if (i == 0 && j == 0) || (i == 0 && j == n-1) || (i == n-1 && j == 0) {
+				continue // overlaps finders
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					qr.setFunction(pos[i]+dx, pos[j]+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	qr.drawFormatBits(0) // reserved, overwritten after masking
+	qr.drawVersion()
+}
+
+// finder pattern with its separator
+func (qr *QRCode) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= qr.Size || y < 0 || y >= qr.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			qr.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (qr *QRCode) drawFormatBits(mask int) {
+	data := uint32(mask) // level M is 0b00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	size := qr.Size
+	for i := 0; i <= 5; i++ {
+		qr.setFunction(8, i, getBit(bits, i))
+	}
+	qr.setFunction(8, 7, getBit(bits, 6))
+	qr.setFunction(8, 8, getBit(bits, 7))
+	qr.setFunction(7, 8, getBit(bits, 8))
+	for i := 9; i < 15; i++ {
+		qr.setFunction(14-i, 8, getBit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		qr.setFunction(size-1-i, 8, getBit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.setFunction(8, size-15+i, getBit(bits, i))
+	}
+	qr.setFunction(8, size-8, true) // dark module
+}
+
+func (qr *QRCode) drawVersion() {
+	if qr.Version < 7 {
+		return
+	}
+	rem := uint32(qr.Version)
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := uint32(qr.Version)<<12 | rem
+	for i := 0; i < 18; i++ {
+		a, b := qr.Size-11+i%3, i/3
+		qr.setFunction(a, b, getBit(bits, i))
+		qr.setFunction(b, a, getBit(bits, i))
+	}
+}
+
+// place codewords in the zigzag order, from the bottom right corner
+func (qr *QRCode) drawCodewords(codewords []byte) {
+	i := 0
+	for right := qr.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		for vert := 0; vert < qr.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 { // upward
+					y = qr.Size - 1 - vert
+				}
+				if qr.isFunction[y][x] || i >= len(codewords)*8 {
+					continue // remainder bits are light
+				}
+				qr.modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+func (qr *QRCode) applyMask(mask int) {
+	for y := 0; y < qr.Size; y++ {
+		for x := 0; x < qr.Size; x++ {
+			if qr.isFunction[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			qr.modules[y][x] = qr.modules[y][x] != invert
+		}
+	}
+}
+
+// penalty rules of the spec: runs, 2x2 blocks, finder-like patterns and dark/light balance
+func (qr *QRCode) penalty() int {
+	size := qr.Size
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return qr.modules[x][y]
+		}
+		return qr.modules[y][x]
+	}
+
+	penalty := 0
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < size; y++ {
+			run := 1
+			for x := 1; x < size; x++ {
+				if at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					if run == 5 {
+						penalty += 3
+					} else if run > 5 {
+						penalty++
+					}
+				} else {
+					run = 1
+				}
+			}
+			for x := 0; x+11 <= size; x++ {
+				if matchFinderLike(func(i int) bool { return at(x+i, y, vertical) }) {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if qr.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				c := qr.modules[y][x]
+				if c == qr.modules[y][x+1] && c == qr.modules[y+1][x] && c == qr.modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+	percent := dark * 100 / (size * size)
+	penalty += abs(percent-50) / 5 * 10
+	return penalty
+}
+
+// 1:1:3:1:1 dark/light pattern with 4 light modules on one side
+func matchFinderLike(at func(i int) bool) bool {
+	const a, b = "10111010000", "00001011101"
+	matchA, matchB := true, true
+	for i := 0; i < 11; i++ {
+		if at(i) != (a[i] == '1') {
+			matchA = false
+		}
+		if at(i) != (b[i] == '1') {
+			matchB = false
+		}
+	}
+	return matchA || matchB
+}
+
+// === Reed-Solomon over GF(2^8) with polynomial 0x11D ===
+
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = gfMul(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMul(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// === utils ===
+
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(val uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*bb = append(*bb, (val>>i)&1 == 1)
+	}
+}
+
+func (bb bitBuffer) bytes() []byte {
+	result := make([]byte, (len(bb)+7)/8)
+	for i, bit := range bb {
+		if bit {
+			result[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return result
+}
+
+func getBit(x uint32, i int) bool {
+	return (x>>i)&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
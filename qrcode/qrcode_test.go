@@ -0,0 +1,76 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	// generated by another implementation, mask 3
+	expected := []string{
+		"#######.#..#..#######",
+		"#.....#.#...#.#.....#",
+		"#.###.#..#.##.#.###.#",
+		"#.###.#.#..##.#.###.#",
+		"#.###.#...#...#.###.#",
+		"#.....#.......#.....#",
+		"#######.#.#.#.#######",
+		"........###..........",
+		"#.##.###.##...#..#.##",
+		"....#..##...#..##...#",
+		"###..###..#.##.#...##",
+		"..##.#..#...#....#.##",
+		"###..####.##..#.##...",
+		"........##.#..#.#....",
+		"#######.##.....##....",
+		"#.....#.#....#...##..",
+		"#.###.#..#####..####.",
+		"#.###.#.#..###.....#.",
+		"#.###.#.#.#.###..#...",
+		"#.....#....#.####...#",
+		"#######.#...####..#..",
+	}
+	qr, err := encode([]byte("bitcoincash:"), 3)
+	require.NoError(t, err)
+	require.Equal(t, 1, qr.Version)
+	require.Equal(t, 21, qr.Size)
+	for y, row := range expected {
+		for x, c := range row {
+			require.Equal(t, c == '#', qr.Dark(x, y), "x=%d, y=%d", x, y)
+		}
+	}
+}
+
+func TestEncode_versions(t *testing.T) {
+	for _, tc := range []struct{ n, version int }{
+		{14, 1}, {15, 2}, {213, 10}, {412, 15}, {2331, 40},
+	} {
+		qr, err := Encode([]byte(strings.Repeat("a", tc.n)))
+		require.NoError(t, err)
+		require.Equal(t, tc.version, qr.Version, tc.n)
+		require.Equal(t, tc.version*4+17, qr.Size)
+	}
+	_, err := Encode(make([]byte, 2332))
+	require.ErrorContains(t, err, "data too long")
+}
+
+func TestPNG(t *testing.T) {
+	qr, err := Encode([]byte("bitcoincash:"))
+	require.NoError(t, err)
+	_, err = qr.PNG(0)
+	require.ErrorContains(t, err, "invalid scale")
+
+	bz, err := qr.PNG(4)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(bz))
+	require.NoError(t, err)
+	require.Equal(t, (21+8)*4, img.Bounds().Dx())
+	r, _, _, _ := img.At(4*4, 4*4).RGBA() // top left of the finder
+	require.Zero(t, r)
+	r, _, _, _ = img.At(0, 0).RGBA() // quiet zone
+	require.NotZero(t, r)
+}